@@ -23,12 +23,14 @@ import (
 	"log"
 	"os"
 
+	"github.com/googleapis/librarian/internal/failure"
 	"github.com/googleapis/librarian/internal/librarian"
 )
 
 func main() {
 	ctx := context.Background()
 	if err := librarian.Run(ctx, os.Args[1:]...); err != nil {
-		log.Fatal(err)
+		log.Print(err)
+		os.Exit(failure.ExitCode(err))
 	}
 }
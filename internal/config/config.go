@@ -16,15 +16,21 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"os/user"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/googleapis/librarian/internal/gitrepo"
 )
 
 const (
@@ -38,6 +44,10 @@ const (
 	// ConfigureResponse is a JSON file that describes which library to change
 	// after initial configuration.
 	ConfigureResponse = "configure-response.json"
+	// ContainerResults is a JSON file in which a container reports warnings,
+	// generated files, skipped items, metrics and fatal errors back to
+	// librarian, after any container command.
+	ContainerResults = "librarian-results.json"
 	// GeneratorInputDir is the default directory to store files that generator
 	// needs to regenerate libraries from an empty directory.
 	GeneratorInputDir = ".librarian/generator-input"
@@ -51,9 +61,81 @@ const (
 	LibrarianDir = ".librarian"
 	// ReleaseInitRequest is a JSON file that describes which library to release.
 	ReleaseInitRequest = "release-init-request.json"
+	// TestRequest is a JSON file that describes which library to test.
+	TestRequest = "test-request.json"
+	// TestResponse is a JSON file that describes the result of testing a
+	// library.
+	TestResponse = "test-response.json"
+
+	// WorkRootPrefix is the prefix of the names of the work roots which
+	// librarian creates in the temporary directory.
+	WorkRootPrefix = "librarian-"
+
+	// ArchiveFormatTarGz is the default -archive-format, gzipped tar.
+	ArchiveFormatTarGz = "tar.gz"
+	// ArchiveFormatZip is the -archive-format of zip archives.
+	ArchiveFormatZip = "zip"
+
+	// ChannelCanary is the -channel of prerelease versions, which are
+	// tracked separately from the stable versions of the libraries.
+	ChannelCanary = "canary"
+	// ChannelStable is the default -channel.
+	ChannelStable = "stable"
+
+	// ContainerBackendDocker is the default -container-backend, which runs
+	// the containers with the local Docker daemon.
+	ContainerBackendDocker = "docker"
+	// ContainerBackendKubernetes is the -container-backend which runs each
+	// container as a Kubernetes Job, as configured in the kubernetes section
+	// of config.yaml.
+	ContainerBackendKubernetes = "kubernetes"
+
+	// DebugShellInteractive is the -debug-shell which opens an interactive
+	// shell in the container of a failed phase.
+	DebugShellInteractive = "interactive"
+	// DebugShellPrint is the -debug-shell which prints the docker commands to
+	// rerun the failed phase and to open a shell in its container.
+	DebugShellPrint = "print"
+
+	// ErrorFormatJSON is the -error-format which writes errors as JSON.
+	ErrorFormatJSON = "json"
+	// ErrorFormatText is the default -error-format.
+	ErrorFormatText = "text"
+
+	// OutputFormatJSON is the -output-format which writes the result of a
+	// command as JSON.
+	OutputFormatJSON = "json"
+	// OutputFormatText is the default -output-format, which writes the result
+	// of a command as lines of text.
+	OutputFormatText = "text"
+
+	// PhaseBuild is the -phases entry which runs the build container command.
+	PhaseBuild = "build"
+	// PhaseConfigure is the -phases entry which runs the configure container
+	// command for a library which is not configured yet.
+	PhaseConfigure = "configure"
+	// PhaseGenerate is the -phases entry which runs the generate container
+	// command and copies the generated code into the repository.
+	PhaseGenerate = "generate"
+	// PhaseTest is the -phases entry which runs the test container command.
+	PhaseTest = "test"
+
+	// ProfileCPU is the -profile which collects a CPU profile.
+	ProfileCPU = "cpu"
+	// ProfileMem is the -profile which collects a heap profile at the end of
+	// the run.
+	ProfileMem = "mem"
+	// ProfileTrace is the -profile which collects a runtime execution trace.
+	ProfileTrace = "trace"
 
-	pipelineStateFile = "state.yaml"
-	versionCmdName    = "version"
+	cleanCmdName       = "clean"
+	defaultGitHubHost  = "github.com"
+	pipelineStateFile  = "state.yaml"
+	printConfigCmdName = "print-config"
+	selfUpdateCmdName  = "self-update"
+	serveCmdName       = "serve"
+	statsCmdName       = "stats"
+	versionCmdName     = "version"
 )
 
 // are variables so it can be replaced during testing.
@@ -65,13 +147,27 @@ var (
 
 var (
 	// pullRequestRegexp is regular expression that describes a uri of a pull request.
-	pullRequestRegexp = regexp.MustCompile(`^https://github\.com/([a-zA-Z0-9-._]+)/([a-zA-Z0-9-._]+)/pull/([0-9]+)$`)
+	pullRequestRegexp = regexp.MustCompile(`^https://([a-zA-Z0-9-.:]+)/([a-zA-Z0-9-._]+)/([a-zA-Z0-9-._]+)/pull/([0-9]+)$`)
+
+	// bugReferencePattern matches the references of bugs, e.g. "b/12345",
+	// "#123" or the URL of an issue.
+	bugReferencePattern = regexp.MustCompile(`^(b/[0-9]+|#[0-9]+|https?://\S+)$`)
+
+	// imagePlatformPattern matches the platforms of images, e.g. "linux/arm64" or "linux/arm/v7".
+	imagePlatformPattern = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(/[a-z0-9]+)?$`)
 )
 
 // Config holds all configuration values parsed from flags or environment
 // variables. When adding members to this struct, please keep them in
 // alphabetical order.
 type Config struct {
+	// AllowedRepos is a comma-separated list of the repositories which the
+	// serve command runs work requests on, as accepted by -repo. Work
+	// requests for other repositories are rejected.
+	//
+	// AllowedRepos is specified with the -allowed-repos flag.
+	AllowedRepos string
+
 	// API is the path to the API to be configured or generated,
 	// relative to the root of the googleapis repository. It is a directory
 	// name as far as (and including) the version (v1, v2, v1alpha etc). It
@@ -83,49 +179,340 @@ type Config struct {
 	// API Path is specified with the -api flag.
 	API string
 
+	// APIPathFilter is a comma-separated list of globs of API paths which
+	// selects the libraries regenerated by the generate command when neither
+	// API nor Library is specified. A glob prefixed with "!" excludes the API
+	// paths it matches. "*" matches within a path segment and "**" across
+	// segments, e.g. "google/cloud/aiplatform/**,!**/v1beta1".
+	//
+	// APIPathFilter is specified with the -api-path-filter flag.
+	APIPathFilter string
+
 	// APISource is the path to the root of the googleapis repository.
 	// When this is not specified, the googleapis repository is cloned
 	// automatically.
 	//
-	// APISource is used by generate, update-apis and configure
+	// APISource is used by generate, sync-apis, update-apis and configure
 	// commands.
 	//
 	// APISource is specified with the -api-source flag.
 	APISource string
 
+	// APISourceFullClone checks out every file of the API source repository
+	// when generate clones it, instead of only the directories of the APIs of
+	// the libraries and of their dependencies. It is needed when the APIs
+	// import protos which are not in the known dependencies.
+	//
+	// APISourceFullClone is specified with the -api-source-full-clone flag.
+	APISourceFullClone bool
+
+	// APISourceSSHKey is the path of the private key, e.g. a deploy key, used
+	// to authenticate with the API source repository when APISource is an SSH
+	// URL such as git@github.com:googleapis/googleapis.git. If empty, the keys
+	// of the SSH agent are used.
+	//
+	// APISourceSSHKey is specified with the -api-source-ssh-key flag.
+	APISourceSSHKey string
+
+	// APIRef is a commit hash, tag or branch of the API source repository to
+	// generate from, instead of its HEAD. The API source repository is checked
+	// out at APIRef in WorkRoot, so a local APISource is left untouched.
+	//
+	// APIRef is only used by the generate and sync-apis commands.
+	//
+	// APIRef is specified with the -api-ref flag, or its alias -api-commit.
+	APIRef string
+
+	// APIRootAllowDirty allows generation from a local API source repository
+	// with uncommitted changes. The working tree is snapshotted into WorkRoot
+	// and the generation is recorded as coming from a dirty API source, so
+	// that API producers can test proto changes before merging them.
+	//
+	// APIRootAllowDirty is only used by the generate command, and requires
+	// APISource to be a local directory.
+	//
+	// APIRootAllowDirty is specified with the -api-root-allow-dirty flag.
+	APIRootAllowDirty bool
+
+	// ArchiveFormat is the format of the archives written by the export and
+	// bundle-debug commands: "tar.gz", the default, or "zip".
+	//
+	// ArchiveFormat is specified with the -archive-format flag.
+	ArchiveFormat string
+
+	// ArtifactsInclude is a comma-separated list of globs, relative to
+	// WorkRoot, of the artifacts uploaded to ArtifactsURL. "*" matches within
+	// a path segment and "**" across segments. If empty, the files at the
+	// root of WorkRoot and the output and logs directories are uploaded.
+	//
+	// ArtifactsInclude is specified with the -artifacts-include flag.
+	ArtifactsInclude string
+
+	// ArtifactsRetention is how long artifacts are kept under ArtifactsURL.
+	// The artifacts of older runs, that is the {prefix}/{run-id} directories
+	// which contain the artifacts.json manifest written by librarian, are
+	// deleted after the artifacts of the current run are uploaded. Other
+	// objects are never deleted. Zero keeps the artifacts forever.
+	//
+	// ArtifactsRetention is specified with the -artifacts-retention flag.
+	ArtifactsRetention time.Duration
+
+	// ArtifactsURL is a Cloud Storage location, gs://bucket[/prefix], to
+	// which the artifacts of the run in WorkRoot, such as the generated code,
+	// the container logs and the reports, are uploaded at the end of the run,
+	// whether it succeeded or not. The artifacts of a run are stored under
+	// {prefix}/{run-id}/{library-id}, where the run ID is the release ID of a
+	// release run, and the command and start time of the run otherwise.
+	// Artifacts which belong to no library are stored under "_run" instead
+	// of a library ID.
+	//
+	// ArtifactsURL is specified with the -artifacts-url flag.
+	ArtifactsURL string
+
+	// AttachSBOM determines whether to attach an SBOM of each released
+	// library to its GitHub release. The SBOM records the generator image and
+	// the API source commit from the state, not the dependencies of the
+	// library.
+	//
+	// AttachSBOM is only used by the tag-and-release command.
+	//
+	// AttachSBOM is specified with the -attach-sbom flag.
+	AttachSBOM bool
+
+	// AuditLog is where every mutating action of the run, such as a created
+	// commit, an opened pull request, a created release or a modified state
+	// file, is recorded for compliance review, with the actor, time, command
+	// and flags of the run. It is either a file, which is appended to, or a
+	// Cloud Storage location, gs://bucket[/prefix], under which the records of
+	// each run are uploaded as a new object at the end of the run.
+	//
+	// AuditLog is specified with the -audit-log flag.
+	AuditLog string
+
+	// Bug is the optional URL or ID of the bug tracking why the generation of
+	// a library is blocked, and is only used in the block-library command.
+	//
+	// Bug is specified with the -bug flag.
+	Bug string
+
 	// Build determines whether to build the generated library, and is only
 	// used in the generate command.
 	//
 	// Build is specified with the -build flag.
 	Build bool
 
+	// Channel is the release channel of release init: "stable", the default,
+	// or "canary". A canary release gives the released libraries a prerelease
+	// version, e.g. 1.5.0-beta.3, which is recorded as their canary_version in
+	// the state, leaving their stable version unchanged. Canary releases are
+	// tagged with the canary tag format of config.yaml, and promoted to stable
+	// releases with the promote-release command.
+	//
+	// Channel is specified with the -channel flag.
+	Channel string
+
+	// Check determines whether the version command checks the release
+	// manifest for a newer version of librarian.
+	//
+	// Check is specified with the -check flag. No value is required.
+	Check bool
+
 	// CI is the type of Continuous Integration (CI) environment in which
 	// the tool is executing.
 	CI string
 
+	// CleanWorkRoot determines whether to remove the work root at the end of
+	// a successful run. Only work roots created by librarian are removed, not
+	// ones specified with the -output flag.
+	//
+	// CleanWorkRoot is specified with the -clean-work-root flag.
+	CleanWorkRoot bool
+
 	// CommandName is the name of the command being executed.
 	//
 	// commandName is populated automatically after flag parsing. No user setup is
 	// expected.
 	CommandName string
 
+	// Comment is the body of the pull request comment which handle-comment
+	// handles, e.g. "/librarian exclude secretmanager".
+	//
+	// Comment is specified with the -comment flag.
+	Comment string
+
+	// CommentAuthor is the GitHub login of the author of Comment, who must be
+	// a release approver or an owner of the libraries which the comment
+	// changes.
+	//
+	// CommentAuthor is specified with the -comment-author flag.
+	CommentAuthor string
+
 	// Commit determines whether to creat a commit for the release but not create
 	// a pull request.
 	//
 	// This flag is ignored if Push is set to true.
 	Commit bool
 
+	// ContainerBackend is where the language containers run: "docker", the
+	// default, runs them with the local Docker daemon, and "kubernetes" runs
+	// each of them as a Kubernetes Job with kubectl.
+	//
+	// ContainerBackend is specified with the -container-backend flag.
+	ContainerBackend string
+
+	// ContainerLogLevel is the slog level, e.g. "info" or "debug", at which
+	// the output of containers is logged. The output is also written to log
+	// files in the "logs" directory of the work root, regardless of the level.
+	//
+	// ContainerLogLevel is specified with the -container-log-level flag.
+	ContainerLogLevel string
+
+	// ContainerMounts is a comma-separated list of extra directories of the
+	// host to bind mount into language containers, such as local Maven, Gradle
+	// or npm caches. Each mount has the format
+	// "{host-dir}:{container-dir}[:ro|:rw]", and is read-write by default. A
+	// leading "~" of the host directory is expanded to the home directory.
+	// The container directories must not overlap with the directories that
+	// librarian mounts itself.
+	//
+	// ContainerMounts is specified with the -container-mounts flag.
+	ContainerMounts string
+
+	// ContainerRecord is the fixture directory into which every container run
+	// is recorded: its arguments, environment and mounted inputs, together with
+	// the files it changed in the mounted directories. The runs of each
+	// library are recorded in a subdirectory named after it, so that the
+	// libraries generated with -parallel are replayed in any order. The
+	// bundle-debug command includes the invocation records of the directory
+	// in the bundle.
+	//
+	// ContainerRecord is specified with the -container-record flag.
+	ContainerRecord string
+
+	// ContainerReplay is a fixture directory recorded with -container-record.
+	// When set, containers are not run. Instead, each container run is checked
+	// against the recording, and the recorded changes are applied to the
+	// mounted directories.
+	//
+	// ContainerReplay is specified with the -container-replay flag.
+	ContainerReplay string
+
+	// DebugShell determines what happens when a container phase fails, so
+	// that generator failures can be debugged in place: "interactive" opens a
+	// shell in a container with the same image, mounts and environment as the
+	// failed one, and "print" prints the docker commands to do so. Nothing
+	// happens if empty. "interactive" falls back to "print" if the standard
+	// input is not a terminal.
+	//
+	// DebugShell is specified with the -debug-shell flag.
+	DebugShell string
+
+	// DryRun determines whether to only report what the clean command would
+	// remove, or what the reconcile-mirrors command would push, without
+	// changing anything.
+	//
+	// DryRun is specified with the -dry-run flag.
+	DryRun bool
+
+	// EmitStubs determines whether discover-apis prints onboarding stubs,
+	// entries of state.yaml, for the APIs which are not covered by any
+	// library, instead of their paths.
+	//
+	// EmitStubs is specified with the -emit-stubs flag.
+	EmitStubs bool
+
+	// ErrorFormat is the format in which the error of a failed run is written
+	// to standard output, in addition to the log: "text", the default, writes
+	// nothing, and "json" writes the failure category, exit code and message
+	// as a JSON object.
+	//
+	// ErrorFormat is specified with the -error-format flag.
+	ErrorFormat string
+
+	// FileIssues determines whether check-freshness files a GitHub issue for
+	// each stale library, and generate for each library whose APIs were
+	// removed from the API source, unless an open issue for the library
+	// exists.
+	//
+	// FileIssues is specified with the -file-issues flag.
+	FileIssues bool
+
+	// Fix determines whether verify-releases reconciles the drift it finds:
+	// the versions of libraries in the state are updated to their latest
+	// tags, and missing GitHub releases are created for existing tags.
+	//
+	// Fix is specified with the -fix flag.
+	Fix bool
+
+	// ForceRegenerate determines whether generate commits the regeneration
+	// of the libraries even if it changes nothing meaningful, e.g. to pick up
+	// a generator fix which is only recorded in metadata. The regeneration
+	// marker of each generated library is updated, and a regeneration which
+	// changes nothing else is committed with the "chore: regenerate" message.
+	//
+	// ForceRegenerate is specified with the -force-regenerate flag.
+	ForceRegenerate bool
+
+	// GeneratorSource is a local directory of the source of the generator,
+	// mounted read-only into language containers at /generator. It lets
+	// generator developers run a local build of the generator from source,
+	// e.g. with ImageLocal.
+	//
+	// GeneratorSource is specified with the -generator-source flag.
+	GeneratorSource string
+
+	// GerritPassword is the HTTP password of the Gerrit account of librarian,
+	// used to push changes for review and to vote on them when the repository
+	// configures Gerrit as its review backend in config.yaml.
+	//
+	// GerritPassword is not specified by a flag, as flags are logged and the
+	// password is sensitive information. Instead, it is fetched from the
+	// LIBRARIAN_GERRIT_PASSWORD environment variable.
+	GerritPassword string
+
+	// GerritUser is the username of the Gerrit account of librarian, which
+	// GerritPassword belongs to. It is fetched from the LIBRARIAN_GERRIT_USER
+	// environment variable.
+	GerritUser string
+
+	// GitHubAPIURL is the base URL of the REST API of a GitHub Enterprise
+	// Server instance, e.g. https://github.example.com/api/v3/. When empty,
+	// github.com is used. It defaults to the value of the
+	// LIBRARIAN_GITHUB_API_URL environment variable.
+	//
+	// GitHubAPIURL is specified with the -github-api-url flag.
+	GitHubAPIURL string
+
 	// GitHubToken is the access token to use for all operations involving
 	// GitHub.
 	//
 	// GitHubToken is used by to configure, update-apis and update-image-tag commands,
-	// when Push is true.
+	// when Push is true. Before doing any work, commands check that it
+	// grants the permissions they need, such as contents:write and
+	// pull_requests:write to push and create pull requests.
 	//
 	// GitHubToken is not specified by a flag, as flags are logged and the
 	// access token is sensitive information. Instead, it is fetched from the
 	// LIBRARIAN_GITHUB_TOKEN environment variable.
 	GitHubToken string
 
+	// GitHubUploadURL is the base URL for uploads of a GitHub Enterprise
+	// Server instance, e.g. https://github.example.com/api/uploads/. When
+	// empty, GitHubAPIURL is used. It defaults to the value of the
+	// LIBRARIAN_GITHUB_UPLOAD_URL environment variable.
+	//
+	// GitHubUploadURL is specified with the -github-upload-url flag.
+	GitHubUploadURL string
+
+	// GitTuning configures the performance of the clones of remote
+	// repositories: "off", which clones with go-git, or a comma-separated
+	// list of the settings of gitrepo.ParseTuningOptions, e.g.
+	// "checkout-workers=16,fsmonitor=true". When empty, the default tuning
+	// is used.
+	//
+	// GitTuning is specified with the -git-tuning flag.
+	GitTuning string
+
 	// HostMount is used to remap Docker mount paths when running in environments
 	// where Docker containers are siblings (e.g., Kokoro).
 	// It specifies a mount point from the Docker host into the Docker container.
@@ -145,6 +532,44 @@ type Config struct {
 	// Image is specified with the -image flag.
 	Image string
 
+	// ImageLocal determines whether the language container images are local,
+	// unreleased builds, e.g. of a generator under development. Local images
+	// are never pulled, must exist in the local Docker daemon, and are
+	// recorded with the "local-dev" provenance in the SBOM and pull request.
+	//
+	// ImageLocal is specified with the -image-local flag. No value is
+	// required.
+	ImageLocal bool
+
+	// ImageArchive is the path of a tarball of container images, as written by
+	// "docker save", which is loaded into the local Docker daemon before the
+	// containers run. It provides the images in environments where they
+	// cannot be pulled, such as with Offline.
+	//
+	// ImageArchive is specified with the -image-archive flag.
+	ImageArchive string
+
+	// ImagePlatform is the platform, e.g. "linux/amd64", which the language
+	// container images run as. By default, the platform of the machine is
+	// selected for the images which are available for it, and the images which
+	// are not run under emulation, e.g. amd64 images on Apple Silicon.
+	//
+	// ImagePlatform is specified with the -image-platform flag.
+	ImagePlatform string
+
+	// KeepLast is the number of most recent work roots which the clean command
+	// keeps, regardless of their age.
+	//
+	// KeepLast is specified with the -keep-last flag.
+	KeepLast int
+
+	// Language is the language of the repository scaffolded by the init-repo
+	// command, e.g. "go" or "python". It determines the default layout and
+	// config of the repository.
+	//
+	// Language is specified with the -language flag.
+	Language string
+
 	// Library is the library ID to generate (e.g. google-cloud-secretmanager-v1 ).
 	// This usually corresponds to a releasable language unit -- for Go this would
 	// be a Go module or for dotnet the name of a NuGet package. If neither this nor
@@ -161,9 +586,167 @@ type Config struct {
 	// Requires the --library flag to be specified.
 	LibraryVersion string
 
+	// Listen is the TCP address on which the serve command accepts work
+	// requests, "localhost:8080" by default.
+	//
+	// Listen is specified with the -listen flag.
+	Listen string
+
+	// LogCommands determines whether every external command which librarian
+	// runs, such as git, docker and kubectl, is logged with its arguments and
+	// the environment variables it sets, with secrets redacted.
+	//
+	// LogCommands is specified with the -log-commands flag.
+	LogCommands bool
+
+	// LogURL is a link to the logs of the current run, such as the Cloud Build
+	// log page. It is included in failure reports when ReportFailures is set.
+	//
+	// LogURL is not specified by a flag. Instead, it is fetched from the
+	// LIBRARIAN_LOG_URL environment variable, which is expected to be set by
+	// the environment running librarian unattended.
+	LogURL string
+
+	// MaxConcurrency is the maximum number of work requests which the serve
+	// command runs at the same time, across all repositories.
+	//
+	// MaxConcurrency is specified with the -max-concurrency flag.
+	MaxConcurrency int
+
+	// MaxReleaseFiles is the maximum number of files changed by a single
+	// release pull request. Releases changing more files are split into
+	// multiple pull requests.
+	//
+	// MaxReleaseFiles is specified with the -max-release-files flag.
+	MaxReleaseFiles int
+
+	// MaxReleaseLibraries is the maximum number of libraries released by a
+	// single release pull request. Releases of more libraries are split into
+	// multiple pull requests.
+	//
+	// MaxReleaseLibraries is specified with the -max-release-libraries flag.
+	MaxReleaseLibraries int
+
+	// MergeQueueTimeout is how long the tag-and-release command waits for a
+	// release pull request queued in the merge queue of its base branch to
+	// be merged. Zero means not waiting.
+	//
+	// MergeQueueTimeout is specified with the -merge-queue-timeout flag.
+	MergeQueueTimeout time.Duration
+
+	// MetricsDir is the directory into which the metrics of each run, such
+	// as the duration of its phases, the number of libraries processed and
+	// the size of the changes, are written as a JSON file. The stats command
+	// summarizes the runs in it. The directory can be a mounted bucket, to
+	// keep the history of runs across machines.
+	//
+	// MetricsDir is specified with the -metrics-dir flag.
+	MetricsDir string
+
+	// Mirror is the name of the mirror, among the mirrors of config.yaml,
+	// which the reconcile-mirrors command reconciles. If empty, all the
+	// mirrors are reconciled.
+	//
+	// Mirror is specified with the -mirror flag.
+	Mirror string
+
+	// Parallel is the number of libraries which the generate command
+	// regenerates at the same time. With more than one, each library is
+	// generated, built and committed in its own git worktree of the language
+	// repository, on its own branch, and the commits are applied to the
+	// language repository in the order of the libraries at the end.
+	//
+	// Parallel is specified with the -parallel flag.
+	Parallel int
+
+	// Phases is a comma-separated list of the container phases which the
+	// generate command runs for each library: "configure", "generate",
+	// "build" and "test". It allows e.g. building or testing the code in the
+	// repository without regenerating it. When empty, the configure and
+	// generate phases run, and the build phase runs if Build is true.
+	//
+	// Phases is specified with the -phases flag.
+	Phases string
+
+	// Profile is a comma-separated list of the profiles of the librarian
+	// process to collect into WorkRoot: "cpu" and "mem" for pprof profiles,
+	// and "trace" for a runtime execution trace. It helps diagnosing slow
+	// runs, e.g. on very large repositories.
+	//
+	// Profile is specified with the -profile flag.
+	Profile string
+
+	// PubSubAudience is the audience of the OIDC tokens which the Pub/Sub
+	// push subscription sends with its messages to the serve command, e.g.
+	// the URL of the push endpoint. If empty, the serve command does not
+	// accept Pub/Sub messages.
+	//
+	// PubSubAudience is specified with the -pubsub-audience flag.
+	PubSubAudience string
+
+	// PubSubServiceAccount is the email of the service account of the OIDC
+	// tokens of the Pub/Sub push subscription. If empty, the tokens of any
+	// service account with PubSubAudience are accepted.
+	//
+	// PubSubServiceAccount is specified with the -pubsub-service-account
+	// flag.
+	PubSubServiceAccount string
+
+	// NativeImage fails the run if a language container image would run
+	// under emulation, because it is not available for the platform of the
+	// machine or ImagePlatform is another platform, rather than only warning.
+	//
+	// NativeImage is specified with the -native-image flag.
+	NativeImage bool
+
+	// NewLibraryID is the ID which the rename-library command renames the
+	// library specified with -library to.
+	//
+	// NewLibraryID is specified with the -new-library-id flag.
+	NewLibraryID string
+
+	// NewSourceRoots is a comma-separated list of the source roots which the
+	// rename-library command moves the source roots of the library to, in the
+	// same order as the source roots in the state.
+	//
+	// NewSourceRoots is specified with the -new-source-roots flag.
+	NewSourceRoots string
+
+	// OlderThan is the minimum age of the work roots which the clean command
+	// removes.
+	//
+	// OlderThan is specified with the -older-than flag.
+	OlderThan time.Duration
+
+	// Offline determines whether librarian runs without network access, for
+	// restricted build environments. All inputs are local files: the
+	// language repository and API source are directories or git bundles, and
+	// the container images exist locally or are loaded from ImageArchive.
+	// Images are never pulled, GitHub is not contacted, and the commits are
+	// written to git bundles and patches in the work root for manual
+	// transfer instead of being pushed.
+	//
+	// Offline is specified with the -offline flag. No value is required.
+	Offline bool
+
+	// OutputFormat is the format in which check-freshness and status write
+	// their result to standard output: "text", the default, or "json".
+	//
+	// OutputFormat is specified with the -output-format flag.
+	OutputFormat string
+
+	// OverrideFreeze is the bug, e.g. "b/12345", justifying a release during
+	// an active freeze of the release policy, which blocks the creation of
+	// release pull requests and the tagging of releases otherwise. The
+	// override is noted in the release pull request.
+	//
+	// OverrideFreeze is specified with the -override-freeze flag.
+	OverrideFreeze string
+
 	// PullRequest to target and operate one in the context of a release.
 	//
-	// The pull request should be in the format `https://github.com/{owner}/{repo}/pull/{number}`.
+	// The pull request should be in the format `https://github.com/{owner}/{repo}/pull/{number}`,
+	// with the host of GitHubAPIURL in place of github.com if it is specified.
 	// Setting this field for `tag-and-release` means librarian will only attempt
 	// to process this exact pull request and not search for other pull requests
 	// that may be ready for tagging and releasing.
@@ -180,11 +763,71 @@ type Config struct {
 	// pull request that would have been created is displayed in the output of
 	// the command.
 	//
-	// When Push is true, GitHubToken must also be specified.
+	// When Push is true, GitHubToken must also be specified, or
+	// GerritPassword if the repository uses Gerrit for reviews.
 	//
 	// Push is specified with the -push flag. No value is required.
 	Push bool
 
+	// Reason is why the generation of a library is blocked, and is only used
+	// in the block-library command.
+	//
+	// Reason is specified with the -reason flag.
+	Reason string
+
+	// RegistryMirror is a registry which container images are pulled through
+	// instead of their own registry, e.g. a pull-through cache. It replaces the
+	// registry host of the image, and may include a path prefix.
+	//
+	// RegistryMirror is specified with the -registry-mirror flag.
+	RegistryMirror string
+
+	// ReleaseArtifacts is the artifact root: a directory holding the built
+	// artifacts of the released libraries, in a subdirectory named after the
+	// ID of each library, e.g. "dist/google-cloud-foo/foo-1.2.0.tar.gz". After
+	// the build, the sign-artifacts command signs the artifacts as configured
+	// by the artifact signing of the repository, with the signatures written
+	// next to them. The tag-and-release command uploads the artifacts of a
+	// released library, with their signatures, to its GitHub release.
+	//
+	// ReleaseArtifacts is used by the sign-artifacts and tag-and-release
+	// commands.
+	//
+	// ReleaseArtifacts is specified with the -release-artifacts flag.
+	ReleaseArtifacts string
+
+	// ReleaseManifest is the URL, or the path, of the manifest of the
+	// releases of librarian, which the version -check and self-update
+	// commands consult.
+	//
+	// ReleaseManifest is specified with the -release-manifest flag.
+	ReleaseManifest string
+
+	// ReleasePublicKey is the base64-encoded Ed25519 public key which the
+	// signatures of the librarian binaries downloaded by self-update are
+	// verified with. If empty, only their checksums are verified.
+	//
+	// ReleasePublicKey is specified with the -release-public-key flag.
+	ReleasePublicKey string
+
+	// ReportFailures determines whether to file a GitHub issue on the language
+	// repository when the command fails. Repeated occurrences of the same
+	// failure are added as comments on the existing issue, which is identified
+	// by a fingerprint label.
+	//
+	// When ReportFailures is true, GitHubToken must also be specified.
+	//
+	// ReportFailures is specified with the -report-failures flag.
+	ReportFailures bool
+
+	// Sandbox determines whether to isolate the generate container: it runs
+	// without network access, with a read-only root filesystem, without
+	// capabilities and with a tmpfs scratch directory at /tmp. This overrides
+	// any sandbox configured for the generate command in config.yaml.
+	//
+	// Sandbox is specified with the -sandbox flag.
+	Sandbox bool
+
 	// Repo specifies the language repository to use, as either a local root directory
 	// or a URL to clone from. If a local directory is specified, it can
 	// be relative to the current working directory. The repository must
@@ -203,22 +846,108 @@ type Config struct {
 	// Repo is specified with the -repo flag.
 	Repo string
 
-	// UserGID is the group ID of the current user. It is used to run Docker
-	// containers with the same user, so that created files have the correct
-	// ownership.
+	// RetryFailedFrom is the path of the generation-report.json of a previous
+	// generate run. Only the libraries which failed to generate in that run
+	// are generated again, from the same commit of the API source repository.
+	//
+	// RetryFailedFrom is specified with the -retry-failed-from flag.
+	RetryFailedFrom string
+
+	// Review prompts, in the terminal, to accept or reject each change which
+	// regeneration made to a handwritten file, or to a file edited manually
+	// since the last generation of its library, before the changes are
+	// committed. The decisions are recorded in the language repository, and
+	// applied to the same regenerated changes in the following runs.
+	//
+	// Review is specified with the -review flag.
+	Review bool
+
+	// ServiceConfig is the path of the service config YAML of an API to
+	// generate, e.g. of an API which is not in any library yet. The API path
+	// is derived from it: the directory of the service config relative to
+	// APISource if it is in the API source, and the package of its first API
+	// otherwise. If Library is empty, the library of the API is generated, or
+	// a new library is onboarded with an ID derived from the API path. The
+	// directory of the service config is mounted read-only into the language
+	// containers at /service-config.
+	//
+	// ServiceConfig is only used by the generate command.
+	//
+	// ServiceConfig is specified with the -service-config flag.
+	ServiceConfig string
+
+	// ServeToken is the shared secret which the work requests sent to the
+	// serve command carry as bearer token. If empty, the serve command only
+	// accepts Pub/Sub messages.
+	//
+	// ServeToken is not specified by a flag. Instead, it is fetched from the
+	// LIBRARIAN_SERVE_TOKEN environment variable.
+	ServeToken string
+
+	// Since is a commit hash or tag of the language repository. The
+	// plan-release command considers the commits since it for the release of
+	// every library, instead of the commits since the last release of each
+	// library.
+	//
+	// Since is specified with the -since flag.
+	Since string
+
+	// SSHKey is the path of the private key, e.g. a deploy key, used to
+	// authenticate with the language repository when its remote is an SSH URL
+	// such as git@github.com:googleapis/google-cloud-go.git, for cloning,
+	// fetching and pushing. If empty, the keys of the SSH agent are used.
+	// The choice between SSH and HTTPS is made per repository, from the URL of
+	// its remote.
+	//
+	// SSHKey is specified with the -ssh-key flag.
+	SSHKey string
+
+	// SSHKnownHosts is the path of the known_hosts file used to verify the
+	// host keys of SSH remotes. If empty, ~/.ssh/known_hosts and
+	// /etc/ssh/ssh_known_hosts are used, or the files listed in the
+	// SSH_KNOWN_HOSTS environment variable if it is set.
+	//
+	// SSHKnownHosts is specified with the -ssh-known-hosts flag.
+	SSHKnownHosts string
+
+	// StaleAfter is the age of the API source commit which a library was last
+	// generated from, after which check-freshness reports the library as
+	// stale.
+	//
+	// StaleAfter is specified with the -stale-after flag.
+	StaleAfter time.Duration
+
+	// UserGID is the group ID of the current user, who owns the files created by
+	// the Docker containers on the host. See the docker package for how
+	// rootless and rootful container runtimes are handled.
 	//
 	// This is populated automatically after flag parsing. No user setup is
 	// expected.
 	UserGID string
 
-	// UserUID is the user ID of the current user. It is used to run Docker
-	// containers with the same user, so that created files have the correct
-	// ownership.
+	// UserUID is the user ID of the current user, who owns the files created by
+	// the Docker containers on the host. See the docker package for how
+	// rootless and rootful container runtimes are handled.
 	//
 	// This is populated automatically after flag parsing. No user setup is
 	// expected.
 	UserUID string
 
+	// Verify determines whether generate-ci checks that the CI trigger
+	// definitions in the repository are up to date with config.yaml, instead
+	// of writing them.
+	//
+	// Verify is specified with the -verify flag.
+	Verify bool
+
+	// Verbosity is the verbosity of the log output: 0 by default, which logs
+	// informational messages, 1, which also logs debug messages, and 2, which
+	// also logs the source location of each message and every external
+	// command, as with LogCommands.
+	//
+	// Verbosity is 1 with the -v flag, and 2 with the -vv flag.
+	Verbosity int
+
 	// WorkRoot is the root directory used for temporary working files, including
 	// any repositories that are cloned. By default, this is created in /tmp with
 	// a timestamped directory name (e.g. /tmp/librarian-20250617T083548Z) but
@@ -231,11 +960,31 @@ type Config struct {
 // New returns a new Config populated with environment variables.
 func New(cmdName string) *Config {
 	return &Config{
-		CommandName: cmdName,
-		GitHubToken: os.Getenv("LIBRARIAN_GITHUB_TOKEN"),
+		CommandName:     cmdName,
+		GerritPassword:  os.Getenv("LIBRARIAN_GERRIT_PASSWORD"),
+		GerritUser:      os.Getenv("LIBRARIAN_GERRIT_USER"),
+		GitHubAPIURL:    os.Getenv("LIBRARIAN_GITHUB_API_URL"),
+		GitHubToken:     os.Getenv("LIBRARIAN_GITHUB_TOKEN"),
+		GitHubUploadURL: os.Getenv("LIBRARIAN_GITHUB_UPLOAD_URL"),
+		LogURL:          os.Getenv("LIBRARIAN_LOG_URL"),
+		ServeToken:      os.Getenv("LIBRARIAN_SERVE_TOKEN"),
 	}
 }
 
+// GitHubHost returns the host of the GitHub instance that librarian
+// interacts with: the host of GitHubAPIURL, or github.com if it is not
+// specified.
+func (c *Config) GitHubHost() string {
+	if c.GitHubAPIURL == "" {
+		return defaultGitHubHost
+	}
+	u, err := url.Parse(c.GitHubAPIURL)
+	if err != nil || u.Host == "" {
+		return defaultGitHubHost
+	}
+	return u.Host
+}
+
 // setupUser performs late initialization of user-specific configuration,
 // determining the current user. This is in a separate method as it
 // can fail, and is called after flag parsing.
@@ -250,15 +999,15 @@ func (c *Config) setupUser() error {
 }
 
 func (c *Config) createWorkRoot() error {
-	if c.CommandName == versionCmdName {
+	if !c.needsRepo() {
 		return nil
 	}
 	if c.WorkRoot != "" {
 		slog.Info("Using specified working directory", "dir", c.WorkRoot)
 		return nil
 	}
-	t := now()
-	path := filepath.Join(tempDir(), fmt.Sprintf("librarian-%s", formatTimestamp(t)))
+	t := now().UTC()
+	path := filepath.Join(tempDir(), WorkRootPrefix+formatTimestamp(t))
 
 	_, err := os.Stat(path)
 	switch {
@@ -277,8 +1026,40 @@ func (c *Config) createWorkRoot() error {
 	return nil
 }
 
+// RunsPhase reports whether the given phase is selected by Phases, or by
+// default if Phases is empty.
+func (c *Config) RunsPhase(phase string) bool {
+	if c.Phases == "" {
+		return phase == PhaseConfigure || phase == PhaseGenerate || (phase == PhaseBuild && c.Build)
+	}
+	return slices.Contains(strings.Split(c.Phases, ","), phase)
+}
+
+// AllowedRepoList returns the repositories listed in AllowedRepos.
+func (c *Config) AllowedRepoList() []string {
+	if c.AllowedRepos == "" {
+		return nil
+	}
+	return strings.Split(c.AllowedRepos, ",")
+}
+
+// Profiles returns the profiles listed in Profile.
+func (c *Config) Profiles() []string {
+	if c.Profile == "" {
+		return nil
+	}
+	return strings.Split(c.Profile, ",")
+}
+
+// needsRepo reports whether the command operates on a language repository
+// and a work root.
+func (c *Config) needsRepo() bool {
+	return c.CommandName != versionCmdName && c.CommandName != cleanCmdName && c.CommandName != statsCmdName &&
+		c.CommandName != printConfigCmdName && c.CommandName != serveCmdName && c.CommandName != selfUpdateCmdName
+}
+
 func (c *Config) deriveRepo() error {
-	if c.CommandName == versionCmdName {
+	if !c.needsRepo() {
 		return nil
 	}
 	if c.Repo != "" {
@@ -300,17 +1081,203 @@ func (c *Config) deriveRepo() error {
 
 // IsValid ensures the values contained in a Config are valid.
 func (c *Config) IsValid() (bool, error) {
-	if c.Push && c.GitHubToken == "" {
-		return false, errors.New("no GitHub token supplied for push")
+	if c.Push && c.GitHubToken == "" && c.GerritPassword == "" {
+		return false, errors.New("no GitHub token or Gerrit password supplied for push")
+	}
+
+	if c.ReportFailures && c.GitHubToken == "" {
+		return false, errors.New("no GitHub token supplied for reporting failures")
+	}
+
+	if c.FileIssues && c.GitHubToken == "" {
+		return false, errors.New("no GitHub token supplied for filing issues")
+	}
+
+	if c.MaxReleaseFiles < 0 || c.MaxReleaseLibraries < 0 {
+		return false, errors.New("release pull request limits must not be negative")
+	}
+
+	if c.ArtifactsURL != "" {
+		if bucket, _, _ := strings.Cut(strings.TrimPrefix(c.ArtifactsURL, "gs://"), "/"); !strings.HasPrefix(c.ArtifactsURL, "gs://") || bucket == "" {
+			return false, fmt.Errorf("invalid -artifacts-url %q, want gs://bucket[/prefix]", c.ArtifactsURL)
+		}
+	}
+
+	if strings.HasPrefix(c.AuditLog, "gs://") {
+		if bucket, _, _ := strings.Cut(strings.TrimPrefix(c.AuditLog, "gs://"), "/"); bucket == "" {
+			return false, fmt.Errorf("invalid -audit-log %q, want a file or gs://bucket[/prefix]", c.AuditLog)
+		}
+	}
+
+	if c.ArtifactsRetention < 0 {
+		return false, errors.New("artifacts retention must not be negative")
+	}
+
+	if c.ReleasePublicKey != "" {
+		if key, err := base64.StdEncoding.DecodeString(c.ReleasePublicKey); err != nil || len(key) != ed25519.PublicKeySize {
+			return false, fmt.Errorf("invalid -release-public-key, want a base64-encoded Ed25519 public key of %d bytes", ed25519.PublicKeySize)
+		}
+	}
+
+	if c.MergeQueueTimeout < 0 {
+		return false, errors.New("merge queue timeout must not be negative")
+	}
+
+	if c.Parallel < 0 {
+		return false, errors.New("parallel must not be negative")
+	}
+
+	if c.MaxConcurrency < 0 {
+		return false, errors.New("max concurrency must not be negative")
+	}
+
+	if c.CommandName == serveCmdName && c.AllowedRepos == "" {
+		return false, errors.New("allowed repos must be specified to serve")
+	}
+
+	if c.KeepLast < 0 || c.OlderThan < 0 {
+		return false, errors.New("clean limits must not be negative")
+	}
+
+	switch c.ArchiveFormat {
+	case "", ArchiveFormatTarGz, ArchiveFormatZip:
+	default:
+		return false, fmt.Errorf("invalid -archive-format %q, want %q or %q", c.ArchiveFormat, ArchiveFormatTarGz, ArchiveFormatZip)
+	}
+
+	switch c.Channel {
+	case "", ChannelStable, ChannelCanary:
+	default:
+		return false, fmt.Errorf("invalid -channel %q, want %q or %q", c.Channel, ChannelStable, ChannelCanary)
+	}
+
+	if _, err := gitrepo.ParseTuningOptions(c.GitTuning); err != nil {
+		return false, fmt.Errorf("invalid -git-tuning %q: %w", c.GitTuning, err)
+	}
+
+	switch c.ErrorFormat {
+	case "", ErrorFormatText, ErrorFormatJSON:
+	default:
+		return false, fmt.Errorf("invalid -error-format %q, want %q or %q", c.ErrorFormat, ErrorFormatText, ErrorFormatJSON)
+	}
+
+	switch c.OutputFormat {
+	case "", OutputFormatText, OutputFormatJSON:
+	default:
+		return false, fmt.Errorf("invalid -output-format %q, want %q or %q", c.OutputFormat, OutputFormatText, OutputFormatJSON)
+	}
+
+	if c.StaleAfter < 0 {
+		return false, errors.New("stale after must not be negative")
+	}
+
+	for _, profile := range c.Profiles() {
+		switch profile {
+		case ProfileCPU, ProfileMem, ProfileTrace:
+		default:
+			return false, fmt.Errorf("invalid -profile %q, want a comma-separated list of %q, %q and %q", profile, ProfileCPU, ProfileMem, ProfileTrace)
+		}
+	}
+
+	if c.Phases != "" {
+		if err := c.validatePhases(); err != nil {
+			return false, err
+		}
+	}
+
+	if c.APIPathFilter != "" && (c.API != "" || c.Library != "") {
+		return false, errors.New("-api-path-filter cannot be combined with -api or -library")
+	}
+
+	if c.RetryFailedFrom != "" && (c.API != "" || c.APIPathFilter != "" || c.APIRef != "" || c.Library != "") {
+		return false, errors.New("-retry-failed-from cannot be combined with -api, -api-path-filter, -api-ref or -library")
+	}
+
+	if c.APIRef != "" && c.APIRootAllowDirty {
+		return false, errors.New("-api-ref and -api-root-allow-dirty are mutually exclusive")
+	}
+
+	if c.ContainerLogLevel != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(c.ContainerLogLevel)); err != nil {
+			return false, fmt.Errorf("invalid -container-log-level %q: %w", c.ContainerLogLevel, err)
+		}
+	}
+
+	if _, err := ParseContainerMounts(c.ContainerMounts); err != nil {
+		return false, err
+	}
+
+	switch c.DebugShell {
+	case "", DebugShellInteractive, DebugShellPrint:
+	default:
+		return false, fmt.Errorf("invalid -debug-shell %q, want %q or %q", c.DebugShell, DebugShellInteractive, DebugShellPrint)
+	}
+
+	switch c.ContainerBackend {
+	case "", ContainerBackendDocker:
+	case ContainerBackendKubernetes:
+		if c.DebugShell != "" || c.Offline {
+			return false, errors.New("-container-backend=kubernetes cannot be combined with -debug-shell or -offline")
+		}
+	default:
+		return false, fmt.Errorf("invalid -container-backend %q, want %q or %q", c.ContainerBackend, ContainerBackendDocker, ContainerBackendKubernetes)
+	}
+
+	if c.ContainerRecord != "" && c.ContainerReplay != "" {
+		return false, errors.New("-container-record and -container-replay are mutually exclusive")
+	}
+
+	if c.ImagePlatform != "" && !imagePlatformPattern.MatchString(c.ImagePlatform) {
+		return false, fmt.Errorf("invalid -image-platform %q, want os/arch[/variant], e.g. linux/amd64", c.ImagePlatform)
+	}
+
+	if c.OverrideFreeze != "" && !bugReferencePattern.MatchString(c.OverrideFreeze) {
+		return false, fmt.Errorf("invalid -override-freeze %q, want a bug, e.g. b/12345", c.OverrideFreeze)
+	}
+
+	if c.ImageLocal && c.RegistryMirror != "" {
+		return false, errors.New("-image-local and -registry-mirror are mutually exclusive")
+	}
+
+	if c.Offline {
+		for _, flag := range []struct {
+			name string
+			set  bool
+		}{
+			{"-artifacts-url", c.ArtifactsURL != ""},
+			{"-audit-log in Cloud Storage", strings.HasPrefix(c.AuditLog, "gs://")},
+			{"-push", c.Push},
+			{"-registry-mirror", c.RegistryMirror != ""},
+			{"-report-failures", c.ReportFailures},
+		} {
+			if flag.set {
+				return false, fmt.Errorf("-offline cannot be combined with %s, which needs network access", flag.name)
+			}
+		}
+	}
+
+	if c.Library == "" && (c.NewLibraryID != "" || c.NewSourceRoots != "") {
+		return false, errors.New("specified new library ID or source roots without library id")
 	}
 
 	if c.Library == "" && c.LibraryVersion != "" {
 		return false, errors.New("specified library version without library id")
 	}
 
+	if c.GitHubAPIURL != "" {
+		if u, err := url.Parse(c.GitHubAPIURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return false, fmt.Errorf("invalid GitHub API URL %q", c.GitHubAPIURL)
+		}
+	}
+
+	if c.GitHubUploadURL != "" && c.GitHubAPIURL == "" {
+		return false, errors.New("specified GitHub upload URL without GitHub API URL")
+	}
+
 	if c.PullRequest != "" {
-		matched := pullRequestRegexp.MatchString(c.PullRequest)
-		if !matched {
+		matches := pullRequestRegexp.FindStringSubmatch(c.PullRequest)
+		if matches == nil || matches[1] != c.GitHubHost() {
 			return false, errors.New("pull request URL is not valid")
 		}
 	}
@@ -319,7 +1286,7 @@ func (c *Config) IsValid() (bool, error) {
 		return false, err
 	}
 
-	if c.CommandName != versionCmdName && c.Repo == "" {
+	if c.needsRepo() && c.Repo == "" {
 		return false, errors.New("language repository not specified or detected")
 	}
 
@@ -340,6 +1307,31 @@ func (c *Config) SetDefaults() error {
 	return nil
 }
 
+// validatePhases checks that Phases lists known phases, each at most once,
+// and that the phases which a phase depends on are selected too.
+func (c *Config) validatePhases() error {
+	if c.Build {
+		return errors.New("-build and -phases are mutually exclusive, add \"build\" to -phases instead")
+	}
+	phases := strings.Split(c.Phases, ",")
+	for i, phase := range phases {
+		switch phase {
+		case PhaseConfigure, PhaseGenerate, PhaseBuild, PhaseTest:
+		default:
+			return fmt.Errorf("invalid -phases entry %q, want a comma-separated list of %q, %q, %q and %q",
+				phase, PhaseConfigure, PhaseGenerate, PhaseBuild, PhaseTest)
+		}
+		if slices.Contains(phases[:i], phase) {
+			return fmt.Errorf("phase %q is listed more than once in -phases", phase)
+		}
+	}
+	// A newly configured library has no code until it is generated.
+	if slices.Contains(phases, PhaseConfigure) && !slices.Contains(phases, PhaseGenerate) {
+		return fmt.Errorf("phase %q requires phase %q", PhaseConfigure, PhaseGenerate)
+	}
+	return nil
+}
+
 func validateHostMount(hostMount, defaultValue string) (bool, error) {
 	if hostMount == defaultValue {
 		return true, nil
@@ -353,7 +1345,77 @@ func validateHostMount(hostMount, defaultValue string) (bool, error) {
 	return true, nil
 }
 
+// ContainerMount is an extra bind mount of a host directory into language
+// containers.
+type ContainerMount struct {
+	// HostDir is the absolute path of the directory on the host.
+	HostDir string
+	// ContainerDir is the absolute path at which HostDir is mounted in the
+	// container.
+	ContainerDir string
+	// ReadOnly determines whether the mount is read-only.
+	ReadOnly bool
+}
+
+// ParseContainerMounts parses a comma-separated list of mounts in the format
+// "{host-dir}:{container-dir}[:ro|:rw]", as specified with -container-mounts.
+func ParseContainerMounts(list string) ([]*ContainerMount, error) {
+	var mounts []*ContainerMount
+	for _, element := range strings.Split(list, ",") {
+		element = strings.TrimSpace(element)
+		if element == "" {
+			continue
+		}
+		parts := strings.Split(element, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid container mount %q, want {host-dir}:{container-dir}[:ro|:rw]", element)
+		}
+		mount := &ContainerMount{
+			HostDir:      parts[0],
+			ContainerDir: filepath.Clean(parts[1]),
+		}
+		if len(parts) == 3 {
+			switch parts[2] {
+			case "ro":
+				mount.ReadOnly = true
+			case "rw":
+			default:
+				return nil, fmt.Errorf("invalid mode %q of container mount %q, want \"ro\" or \"rw\"", parts[2], element)
+			}
+		}
+		if rest, ok := strings.CutPrefix(mount.HostDir, "~"); ok && (rest == "" || rest[0] == '/') {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("failed to expand host directory of container mount %q: %w", element, err)
+			}
+			mount.HostDir = home + rest
+		}
+		if !filepath.IsAbs(mount.HostDir) || !filepath.IsAbs(mount.ContainerDir) {
+			return nil, fmt.Errorf("invalid container mount %q, directories must be absolute", element)
+		}
+		mount.HostDir = filepath.Clean(mount.HostDir)
+		mounts = append(mounts, mount)
+	}
+	return mounts, nil
+}
+
+const yyyyMMddHHmmss = "20060102T150405Z" // Expected format by time library
+
 func formatTimestamp(t time.Time) string {
-	const yyyyMMddHHmmss = "20060102T150405Z" // Expected format by time library
 	return t.Format(yyyyMMddHHmmss)
 }
+
+// ParseWorkRootName returns the creation time encoded in the name of a work
+// root created by librarian. It reports false if name is not the name of such
+// a work root.
+func ParseWorkRootName(name string) (time.Time, bool) {
+	timestamp, ok := strings.CutPrefix(name, WorkRootPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(yyyyMMddHHmmss, timestamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -36,12 +36,20 @@ func TestNew(t *testing.T) {
 		{
 			name: "All environment variables set",
 			envVars: map[string]string{
-				"LIBRARIAN_GITHUB_TOKEN":    "gh_token",
-				"LIBRARIAN_SYNC_AUTH_TOKEN": "sync_token",
+				"LIBRARIAN_GITHUB_API_URL":    "https://github.example.com/api/v3/",
+				"LIBRARIAN_GITHUB_TOKEN":      "gh_token",
+				"LIBRARIAN_GITHUB_UPLOAD_URL": "https://github.example.com/api/uploads/",
+				"LIBRARIAN_SYNC_AUTH_TOKEN":   "sync_token",
+				"LIBRARIAN_LOG_URL":           "https://example.com/logs",
+				"LIBRARIAN_SERVE_TOKEN":       "serve_token",
 			},
 			want: Config{
-				GitHubToken: "gh_token",
-				CommandName: "test",
+				GitHubAPIURL:    "https://github.example.com/api/v3/",
+				GitHubToken:     "gh_token",
+				GitHubUploadURL: "https://github.example.com/api/uploads/",
+				LogURL:          "https://example.com/logs",
+				ServeToken:      "serve_token",
+				CommandName:     "test",
 			},
 		},
 		{
@@ -165,6 +173,296 @@ func TestIsValid(t *testing.T) {
 				Repo:        "/tmp/some/repo",
 			},
 		},
+		{
+			name: "Valid config - clean command without repo",
+			cfg: Config{
+				CommandName: "clean",
+				KeepLast:    3,
+				OlderThan:   time.Hour,
+			},
+		},
+		{
+			name: "Invalid config - negative keep last",
+			cfg: Config{
+				CommandName: "clean",
+				KeepLast:    -1,
+			},
+			wantErr:    true,
+			wantErrMsg: "clean limits must not be negative",
+		},
+		{
+			name: "Invalid config - negative parallel",
+			cfg: Config{
+				CommandName: "generate",
+				Parallel:    -1,
+			},
+			wantErr:    true,
+			wantErrMsg: "parallel must not be negative",
+		},
+		{
+			name: "Invalid config - negative max concurrency",
+			cfg: Config{
+				CommandName:    "serve",
+				MaxConcurrency: -1,
+			},
+			wantErr:    true,
+			wantErrMsg: "max concurrency must not be negative",
+		},
+		{
+			name: "Invalid config - serve without allowed repos",
+			cfg: Config{
+				CommandName: "serve",
+			},
+			wantErr:    true,
+			wantErrMsg: "allowed repos must be specified to serve",
+		},
+		{
+			name: "Valid config - serve",
+			cfg: Config{
+				CommandName:  "serve",
+				AllowedRepos: "https://github.com/googleapis/google-cloud-go",
+			},
+		},
+		{
+			name: "Valid config - profiles",
+			cfg: Config{
+				Profile: "cpu,mem,trace",
+				Repo:    "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - profile",
+			cfg: Config{
+				Profile: "cpu,block",
+				Repo:    "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid -profile "block"`,
+		},
+		{
+			name: "Valid config - phases",
+			cfg: Config{
+				Phases: "build,test",
+				Repo:   "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - unknown phase",
+			cfg: Config{
+				Phases: "generate,lint",
+				Repo:   "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid -phases entry "lint"`,
+		},
+		{
+			name: "Invalid config - duplicate phase",
+			cfg: Config{
+				Phases: "build,build",
+				Repo:   "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: `phase "build" is listed more than once`,
+		},
+		{
+			name: "Invalid config - phase without its dependency",
+			cfg: Config{
+				Phases: "configure,build",
+				Repo:   "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: `phase "configure" requires phase "generate"`,
+		},
+		{
+			name: "Invalid config - phases with build",
+			cfg: Config{
+				Build:  true,
+				Phases: "generate",
+				Repo:   "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "-build and -phases are mutually exclusive",
+		},
+		{
+			name: "Invalid config - container mounts",
+			cfg: Config{
+				ContainerMounts: "/home/user/.m2",
+				Repo:            "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid container mount",
+		},
+		{
+			name: "Invalid config - record and replay containers",
+			cfg: Config{
+				ContainerRecord: "/tmp/fixtures",
+				ContainerReplay: "/tmp/fixtures",
+				Repo:            "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "mutually exclusive",
+		},
+		{
+			name: "Invalid config - local image with registry mirror",
+			cfg: Config{
+				ImageLocal:     true,
+				RegistryMirror: "mirror.gcr.io",
+				Repo:           "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "-image-local and -registry-mirror are mutually exclusive",
+		},
+		{
+			name: "Invalid config - offline with push",
+			cfg: Config{
+				GitHubToken: "token",
+				Offline:     true,
+				Push:        true,
+				Repo:        "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "-offline cannot be combined with -push",
+		},
+		{
+			name: "Invalid config - offline with registry mirror",
+			cfg: Config{
+				Offline:        true,
+				RegistryMirror: "mirror.gcr.io",
+				Repo:           "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "-offline cannot be combined with -registry-mirror",
+		},
+		{
+			name: "Invalid config - error format",
+			cfg: Config{
+				ErrorFormat: "yaml",
+				Repo:        "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid -error-format",
+		},
+		{
+			name: "Invalid config - git tuning",
+			cfg: Config{
+				GitTuning: "checkout-workers=many",
+				Repo:      "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid -git-tuning",
+		},
+		{
+			name: "Invalid config - container backend",
+			cfg: Config{
+				ContainerBackend: "podman",
+				Repo:             "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid -container-backend",
+		},
+		{
+			name: "Invalid config - kubernetes with debug shell",
+			cfg: Config{
+				ContainerBackend: ContainerBackendKubernetes,
+				DebugShell:       DebugShellPrint,
+				Repo:             "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "-container-backend=kubernetes cannot be combined",
+		},
+		{
+			name: "Invalid config - output format",
+			cfg: Config{
+				OutputFormat: "yaml",
+				Repo:         "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid -output-format",
+		},
+		{
+			name: "Invalid config - override freeze",
+			cfg: Config{
+				OverrideFreeze: "holidays",
+				Repo:           "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid -override-freeze",
+		},
+		{
+			name: "Invalid config - negative stale after",
+			cfg: Config{
+				StaleAfter: -time.Hour,
+				Repo:       "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "stale after must not be negative",
+		},
+		{
+			name: "Invalid config - file issues without token",
+			cfg: Config{
+				FileIssues: true,
+				Repo:       "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "no GitHub token supplied for filing issues",
+		},
+		{
+			name: "Invalid config - api path filter with library",
+			cfg: Config{
+				APIPathFilter: "google/cloud/**",
+				Library:       "pubsub",
+				Repo:          "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "-api-path-filter cannot be combined",
+		},
+		{
+			name: "Invalid config - retry failed with api ref",
+			cfg: Config{
+				APIRef:          "main",
+				RetryFailedFrom: "generation-report.json",
+				Repo:            "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "-retry-failed-from cannot be combined",
+		},
+		{
+			name: "Invalid config - archive format",
+			cfg: Config{
+				ArchiveFormat: "rar",
+				Repo:          "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid -archive-format",
+		},
+		{
+			name: "Invalid config - channel",
+			cfg: Config{
+				Channel: "nightly",
+				Repo:    "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid -channel",
+		},
+		{
+			name: "Invalid config - debug shell",
+			cfg: Config{
+				DebugShell: "bash",
+				Repo:       "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid -debug-shell",
+		},
+		{
+			name: "Invalid config - API ref with dirty API source",
+			cfg: Config{
+				APIRef:            "v1.0.0",
+				APIRootAllowDirty: true,
+				Repo:              "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "mutually exclusive",
+		},
 		{
 			name: "Valid config - valid pull request",
 			cfg: Config{
@@ -180,7 +478,102 @@ func TestIsValid(t *testing.T) {
 				Repo:        "/tmp/some/repo",
 			},
 			wantErr:    true,
-			wantErrMsg: "no GitHub token supplied for push",
+			wantErrMsg: "no GitHub token or Gerrit password supplied for push",
+		},
+		{
+			name: "Valid config - Push true, Gerrit password",
+			cfg: Config{
+				Push:           true,
+				GerritPassword: "secret",
+				Repo:           "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - ReportFailures true, token missing",
+			cfg: Config{
+				ReportFailures: true,
+				Repo:           "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "no GitHub token supplied for reporting failures",
+		},
+		{
+			name: "Valid config - artifacts",
+			cfg: Config{
+				ArtifactsRetention: 720 * time.Hour,
+				ArtifactsURL:       "gs://bucket/librarian",
+				Repo:               "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - artifacts URL without bucket",
+			cfg: Config{
+				ArtifactsURL: "gs:///librarian",
+				Repo:         "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid -artifacts-url",
+		},
+		{
+			name: "Invalid config - artifacts URL not in Cloud Storage",
+			cfg: Config{
+				ArtifactsURL: "https://example.com/artifacts",
+				Repo:         "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid -artifacts-url",
+		},
+		{
+			name: "Valid config - audit log file",
+			cfg: Config{
+				AuditLog: "audit/librarian.jsonl",
+				Repo:     "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - audit log without bucket",
+			cfg: Config{
+				AuditLog: "gs:///audit",
+				Repo:     "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid -audit-log",
+		},
+		{
+			name: "Invalid config - negative artifacts retention",
+			cfg: Config{
+				ArtifactsRetention: -time.Hour,
+				Repo:               "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "artifacts retention must not be negative",
+		},
+		{
+			name: "Invalid config - negative merge queue timeout",
+			cfg: Config{
+				MergeQueueTimeout: -time.Minute,
+				Repo:              "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "merge queue timeout must not be negative",
+		},
+		{
+			name: "Invalid config - short release public key",
+			cfg: Config{
+				ReleasePublicKey: "c2hvcnQ=",
+				Repo:             "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid -release-public-key",
+		},
+		{
+			name: "Invalid config - negative release limit",
+			cfg: Config{
+				MaxReleaseLibraries: -1,
+				Repo:                "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "release pull request limits must not be negative",
 		},
 		{
 			name: "Invalid config - library version presents, missing library id",
@@ -193,6 +586,24 @@ func TestIsValid(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "specified library version without library id",
 		},
+		{
+			name: "Invalid config - container log level",
+			cfg: Config{
+				ContainerLogLevel: "verbose",
+				Repo:              "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid -container-log-level",
+		},
+		{
+			name: "Invalid config - new library id without library id",
+			cfg: Config{
+				NewLibraryID: "new-library",
+				Repo:         "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "specified new library ID or source roots without library id",
+		},
 		{
 			name: "Invalid config - host mount invalid, missing local-dir",
 			cfg: Config{
@@ -228,6 +639,43 @@ func TestIsValid(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "language repository not specified or detected",
 		},
+		{
+			name: "Valid config - GitHub Enterprise Server",
+			cfg: Config{
+				GitHubAPIURL:    "https://github.example.com/api/v3/",
+				GitHubUploadURL: "https://github.example.com/api/uploads/",
+				PullRequest:     "https://github.example.com/owner/repo/pull/123",
+				Repo:            "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - invalid GitHub API URL",
+			cfg: Config{
+				GitHubAPIURL: "github.example.com",
+				Repo:         "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid GitHub API URL",
+		},
+		{
+			name: "Invalid config - GitHub upload URL without API URL",
+			cfg: Config{
+				GitHubUploadURL: "https://github.example.com/api/uploads/",
+				Repo:            "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "specified GitHub upload URL without GitHub API URL",
+		},
+		{
+			name: "Invalid config - pull request url of another host",
+			cfg: Config{
+				GitHubAPIURL: "https://github.example.com/api/v3/",
+				PullRequest:  "https://github.com/owner/repo/pull/123",
+				Repo:         "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "pull request URL is not valid",
+		},
 		{
 			name: "Invalid config - invalid pull request url",
 			cfg: Config{
@@ -251,8 +699,69 @@ func TestIsValid(t *testing.T) {
 	}
 }
 
+func TestGitHubHost(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		gitHubAPIURL string
+		want         string
+	}{
+		{
+			name: "default",
+			want: "github.com",
+		},
+		{
+			name:         "enterprise",
+			gitHubAPIURL: "https://github.example.com/api/v3/",
+			want:         "github.example.com",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &Config{GitHubAPIURL: test.gitHubAPIURL}
+			if got := cfg.GitHubHost(); got != test.want {
+				t.Errorf("GitHubHost() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRunsPhase(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		cfg  *Config
+		want []string
+	}{
+		{
+			name: "default",
+			cfg:  &Config{},
+			want: []string{PhaseConfigure, PhaseGenerate},
+		},
+		{
+			name: "default with build",
+			cfg:  &Config{Build: true},
+			want: []string{PhaseConfigure, PhaseGenerate, PhaseBuild},
+		},
+		{
+			name: "selected phases",
+			cfg:  &Config{Phases: "test,build"},
+			want: []string{PhaseBuild, PhaseTest},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, phase := range []string{PhaseConfigure, PhaseGenerate, PhaseBuild, PhaseTest} {
+				if test.cfg.RunsPhase(phase) {
+					got = append(got, phase)
+				}
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("RunsPhase() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestCreateWorkRoot(t *testing.T) {
-	timestamp := time.Now()
+	timestamp := time.Now().UTC()
 	localTempDir := t.TempDir()
 	now = func() time.Time {
 		return timestamp
@@ -289,6 +798,15 @@ func TestCreateWorkRoot(t *testing.T) {
 				return "/some/path", func() {}
 			},
 		},
+		{
+			name: "clean command",
+			config: &Config{
+				CommandName: "clean",
+			},
+			setup: func(t *testing.T) (string, func()) {
+				return "", func() {}
+			},
+		},
 		{
 			name:   "without override, new dir",
 			config: &Config{},
@@ -340,6 +858,36 @@ func TestCreateWorkRoot(t *testing.T) {
 	}
 }
 
+func TestParseWorkRootName(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		want   time.Time
+		wantOK bool
+	}{
+		{
+			name:   "librarian-20250102T030405Z",
+			want:   time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+			wantOK: true,
+		},
+		{
+			name: "librarian-latest",
+		},
+		{
+			name: "other-20250102T030405Z",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := ParseWorkRootName(test.name)
+			if ok != test.wantOK {
+				t.Fatalf("ParseWorkRootName(%q) ok = %v, want %v", test.name, ok, test.wantOK)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("ParseWorkRootName(%q) = %v, want %v", test.name, got, test.want)
+			}
+		})
+	}
+}
+
 func TestDeriveRepo(t *testing.T) {
 	for _, test := range []struct {
 		name         string
@@ -347,6 +895,7 @@ func TestDeriveRepo(t *testing.T) {
 		setup        func(t *testing.T, dir string)
 		wantErr      bool
 		wantRepoPath string
+		wantNoRepo   bool
 	}{
 		{
 			name: "configured repo path",
@@ -382,6 +931,20 @@ func TestDeriveRepo(t *testing.T) {
 			},
 			wantRepoPath: "/some/path",
 		},
+		{
+			name: "serve command, no state file",
+			config: &Config{
+				CommandName: "serve",
+			},
+			wantNoRepo: true,
+		},
+		{
+			name: "self-update command, no state file",
+			config: &Config{
+				CommandName: "self-update",
+			},
+			wantNoRepo: true,
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			tmpDir := t.TempDir()
@@ -397,7 +960,7 @@ func TestDeriveRepo(t *testing.T) {
 			}
 
 			wantPath := test.wantRepoPath
-			if wantPath == "" && !test.wantErr {
+			if wantPath == "" && !test.wantErr && !test.wantNoRepo {
 				wantPath = tmpDir
 			}
 
@@ -560,3 +1123,70 @@ func TestValidateHostMount(t *testing.T) {
 		})
 	}
 }
+
+func TestParseContainerMounts(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name       string
+		list       string
+		want       []*ContainerMount
+		wantErrMsg string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "mounts",
+			list: "/home/user/.m2:/root/.m2, /cache/npm/:/npm:ro,/gradle:/gradle:rw",
+			want: []*ContainerMount{
+				{HostDir: "/home/user/.m2", ContainerDir: "/root/.m2"},
+				{HostDir: "/cache/npm", ContainerDir: "/npm", ReadOnly: true},
+				{HostDir: "/gradle", ContainerDir: "/gradle"},
+			},
+		},
+		{
+			name: "home directory",
+			list: "~/.m2:/root/.m2",
+			want: []*ContainerMount{{HostDir: filepath.Join(home, ".m2"), ContainerDir: "/root/.m2"}},
+		},
+		{
+			name:       "missing container directory",
+			list:       "/home/user/.m2",
+			wantErrMsg: "invalid container mount",
+		},
+		{
+			name:       "invalid mode",
+			list:       "/home/user/.m2:/root/.m2:rx",
+			wantErrMsg: "invalid mode",
+		},
+		{
+			name:       "relative host directory",
+			list:       "cache:/cache",
+			wantErrMsg: "must be absolute",
+		},
+		{
+			name:       "relative container directory",
+			list:       "/cache:cache",
+			wantErrMsg: "must be absolute",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseContainerMounts(test.list)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("ParseContainerMounts() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseContainerMounts() error = %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("ParseContainerMounts() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -15,7 +15,20 @@
 package config
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/kms"
+	"github.com/googleapis/librarian/internal/semver"
+	"github.com/googleapis/librarian/internal/versionfile"
 )
 
 const (
@@ -26,7 +39,93 @@ const (
 
 // LibrarianConfig defines the contract for the config.yaml file.
 type LibrarianConfig struct {
-	GlobalFilesAllowlist []*GlobalFile `yaml:"global_files_allowlist"`
+	// Extends is the location of a base config which this config overlays.
+	// It is either an HTTP(S) URL or a path relative to the file declaring it.
+	// See [LibrarianConfig.Overlay] for how the configs are merged.
+	Extends              string                 `yaml:"extends,omitempty"`
+	GlobalFilesAllowlist []*GlobalFile          `yaml:"global_files_allowlist"`
+	VersionFiles         []*VersionFile         `yaml:"version_files,omitempty"`
+	Environment          []*EnvironmentVariable `yaml:"environment,omitempty"`
+	Sandbox              *ContainerSandbox      `yaml:"sandbox,omitempty"`
+	// ContainerLimits bounds the resources and the run time of containers.
+	ContainerLimits *ContainerLimits `yaml:"container_limits,omitempty"`
+	// Containers declares the language containers which run some of the
+	// container commands in place of the image of the state, e.g. separate
+	// images for generation, building and publishing.
+	Containers     []*Container    `yaml:"containers,omitempty"`
+	ProtectedFiles *ProtectedFiles `yaml:"protected_files,omitempty"`
+	ReleasePolicy  *ReleasePolicy  `yaml:"release_policy,omitempty"`
+	// ConflictResolution defines how regeneration resolves conflicts with
+	// manual edits made to generated files since the last generation. The
+	// first rule matching a file applies; files which match no rule are
+	// overwritten with the generated content.
+	ConflictResolution []*ConflictResolution `yaml:"conflict_resolution,omitempty"`
+	// Normalization defines how the generated files are normalized before
+	// they are copied into the repository, so that generating again from
+	// unchanged inputs produces identical files.
+	Normalization *Normalization `yaml:"normalization,omitempty"`
+	// NoOp defines which generated changes are too trivial to be committed.
+	// Runs without meaningful changes create no commit or pull request.
+	NoOp *NoOp `yaml:"no_op,omitempty"`
+	// RegenerationMarker is the path of the file, relative to the root of
+	// the repository, in which "generate -force-regenerate" records the
+	// provenance of the forced regeneration of each library, so that the
+	// regeneration is committed even if it changes nothing else. "{id}" is
+	// replaced with the ID of the library, and "{source_root}" with its first
+	// source root. Defaults to DefaultRegenerationMarker.
+	RegenerationMarker string `yaml:"regeneration_marker,omitempty"`
+	// CITriggers defines the CI trigger definitions of the standard workflows
+	// of the repository, which the generate-ci command emits.
+	CITriggers *CITriggers `yaml:"ci_triggers,omitempty"`
+	// Gerrit configures Gerrit as the review backend of the repository: the
+	// changes of generate and release init are pushed for review to Gerrit
+	// instead of being sent as GitHub pull requests.
+	Gerrit *Gerrit `yaml:"gerrit,omitempty"`
+	// APISnapshot configures the snapshot of API definitions which the
+	// sync-apis command maintains in the repository.
+	APISnapshot *APISnapshot `yaml:"api_snapshot,omitempty"`
+	// CommitGrouping defines how the changes of a generation run are grouped
+	// into commits: "run", "library", "api" or "squash". Defaults to "run".
+	CommitGrouping string `yaml:"commit_grouping,omitempty"`
+	// SourceAttribution configures the attribution of the commits of the API
+	// source repository which a generation run picks up, in the messages of
+	// the commits of the run.
+	SourceAttribution *SourceAttribution `yaml:"source_attribution,omitempty"`
+	// PullRequests defines the labels and reviewers of the pull requests
+	// created by librarian.
+	PullRequests *PullRequests `yaml:"pull_requests,omitempty"`
+	// Canary configures the canary release channel, whose prerelease
+	// versions are released by "release init -channel=canary" and promoted
+	// to stable versions by "release promote-release".
+	Canary *Canary `yaml:"canary,omitempty"`
+	// Kubernetes configures the Kubernetes Jobs which run the containers
+	// with "-container-backend=kubernetes".
+	Kubernetes *Kubernetes `yaml:"kubernetes,omitempty"`
+	// BugTrackers maps the bug references in commit messages, e.g. "b/123",
+	// to the URLs of the bugs, which are linked in changelogs and release
+	// notes.
+	BugTrackers []*BugTracker `yaml:"bug_trackers,omitempty"`
+	// Mirrors are the secondary remotes to which the release commits and
+	// tags are pushed after they are created on GitHub.
+	Mirrors []*Mirror `yaml:"mirrors,omitempty"`
+	// BuildMatrix declares the variants which the libraries are built and
+	// tested for, e.g. several Java versions. The build and test commands
+	// run once per variant of a library, and once if it has none.
+	BuildMatrix []*BuildVariant `yaml:"build_matrix,omitempty"`
+	// LibrarianVersion pins the release of librarian which runs on the
+	// repository, e.g. "v0.5.0". Commands run by an older release fail, so
+	// that pipelines do not run outdated binaries.
+	LibrarianVersion string `yaml:"librarian_version,omitempty"`
+	// APISourceDependencies are the directories of the API source which the
+	// APIs of the libraries import, besides the dependencies shared by most
+	// APIs such as google/api, e.g. "google/geo/type". They are checked out
+	// with the APIs of the libraries when generate clones the API source.
+	APISourceDependencies []string `yaml:"api_source_dependencies,omitempty"`
+	// ArtifactSigning configures the signing of the built artifacts of the
+	// released libraries by "release sign-artifacts" after the build. The
+	// artifacts are uploaded with their signatures to their GitHub releases
+	// by "release tag-and-release" with -release-artifacts.
+	ArtifactSigning *ArtifactSigning `yaml:"artifact_signing,omitempty"`
 }
 
 // GlobalFile defines the global files in language repositories.
@@ -35,6 +134,809 @@ type GlobalFile struct {
 	Permissions string `yaml:"permissions"`
 }
 
+// VersionFile defines a file, relative to each source root of a library, in
+// which librarian rewrites the library version during a release.
+type VersionFile struct {
+	// Path is the path of the file relative to a library source root.
+	Path string `yaml:"path"`
+	// Kind is the kind of the file, which determines how the version is
+	// located. See [versionfile.Kinds] for the supported values.
+	Kind string `yaml:"kind"`
+	// Pattern is a regular expression with a capture group named "version".
+	// It is required when Kind is "generic", and ignored otherwise.
+	Pattern string `yaml:"pattern,omitempty"`
+}
+
+// EnvironmentVariable defines an environment variable injected into the
+// language container.
+type EnvironmentVariable struct {
+	// Name is the name of the variable within the container.
+	Name string `yaml:"name"`
+	// Value is the static value of the variable.
+	Value string `yaml:"value,omitempty"`
+	// SecretEnv is the name of an environment variable of the librarian
+	// process holding the value, e.g. one populated from a secret manager by
+	// the build environment. Secret values are never logged. Exactly one of
+	// Value and SecretEnv may be set.
+	SecretEnv string `yaml:"secret_env,omitempty"`
+	// Commands limits the variable to the given container commands, e.g.
+	// "generate". The variable is injected into all commands if empty.
+	Commands []string `yaml:"commands,omitempty"`
+	// Libraries limits the variable to container invocations for the given
+	// library IDs. The variable is injected for all libraries if empty.
+	Libraries []string `yaml:"libraries,omitempty"`
+}
+
+// ContainerSandbox defines the isolation of language container runs, so that
+// untrusted images cannot reach the network or tamper with their environment.
+type ContainerSandbox struct {
+	// Commands limits the sandbox to the given container commands, e.g.
+	// "generate". The sandbox applies to all commands if empty.
+	Commands []string `yaml:"commands,omitempty"`
+	// Network is the network of the container. The only supported value is
+	// "none", which disables networking. The default network is used if empty.
+	Network string `yaml:"network,omitempty"`
+	// ReadOnly determines whether the root filesystem of the container is
+	// mounted read-only. Mounted directories are not affected.
+	ReadOnly bool `yaml:"read_only,omitempty"`
+	// DropCapabilities determines whether all Linux capabilities are dropped
+	// and privilege escalation is disabled.
+	DropCapabilities bool `yaml:"drop_capabilities,omitempty"`
+	// Tmpfs are absolute paths in the container at which an empty tmpfs is
+	// mounted as scratch space, e.g. "/tmp" for a read-only root filesystem.
+	Tmpfs []string `yaml:"tmpfs,omitempty"`
+}
+
+// ContainerLimits bounds the resources and the run time of the containers,
+// so that a runaway generator cannot starve the rest of a run over many
+// libraries. The limits apply to all the containers of a library, and those
+// of Libraries override the default limits field by field.
+type ContainerLimits struct {
+	// ResourceLimits are the default limits of all containers.
+	ResourceLimits `yaml:",inline"`
+	// Libraries override the default limits for the containers of single
+	// libraries.
+	Libraries []*LibraryContainerLimits `yaml:"libraries,omitempty"`
+}
+
+// LibraryContainerLimits are the limits of the containers of a library.
+type LibraryContainerLimits struct {
+	// ID is the ID of the library.
+	ID             string `yaml:"id"`
+	ResourceLimits `yaml:",inline"`
+}
+
+// Container is a named language container which runs the given container
+// commands. The commands which no container declares run in the image of the
+// state, or the image specified with the -image flag.
+type Container struct {
+	// Name identifies the container, e.g. "builder".
+	Name string `yaml:"name"`
+	// Image is the image of the container, with a tag or a digest.
+	Image string `yaml:"image"`
+	// Commands are the container commands run in the container, e.g.
+	// "build" and "test".
+	Commands []string `yaml:"commands"`
+}
+
+// ResourceLimits are the limits of a container. Empty fields are unlimited.
+type ResourceLimits struct {
+	// CPUs is the number of CPUs the container may use, as accepted by the
+	// --cpus flag of docker run, e.g. "1.5".
+	CPUs string `yaml:"cpus,omitempty"`
+	// Memory is the memory limit of the container, as accepted by the
+	// --memory flag of docker run, e.g. "4g".
+	Memory string `yaml:"memory,omitempty"`
+	// Timeout is the wall-clock time after which the container is killed,
+	// as a duration such as "30m".
+	Timeout string `yaml:"timeout,omitempty"`
+}
+
+// TimeoutDuration returns the parsed Timeout, or 0 if l is nil or Timeout
+// is not set.
+func (l *ResourceLimits) TimeoutDuration() time.Duration {
+	if l == nil || l.Timeout == "" {
+		return 0
+	}
+	// Timeout is checked by Validate.
+	d, _ := time.ParseDuration(l.Timeout)
+	return d
+}
+
+var memoryLimitRegex = regexp.MustCompile(`^[0-9]+[bkmgBKMG]?$`)
+
+// validate checks the limits, which are named by what for errors.
+func (l *ResourceLimits) validate(what string) error {
+	if l.CPUs != "" {
+		if cpus, err := strconv.ParseFloat(l.CPUs, 64); err != nil || cpus <= 0 {
+			return fmt.Errorf("invalid cpus of %s: %q", what, l.CPUs)
+		}
+	}
+	if l.Memory != "" && !memoryLimitRegex.MatchString(l.Memory) {
+		return fmt.Errorf("invalid memory of %s: %q", what, l.Memory)
+	}
+	if l.Timeout != "" {
+		if d, err := time.ParseDuration(l.Timeout); err != nil || d <= 0 {
+			return fmt.Errorf("invalid timeout of %s: %q", what, l.Timeout)
+		}
+	}
+	return nil
+}
+
+// The actions taken when generation changes a protected file.
+const (
+	// ProtectedFilesFail fails the generation run.
+	ProtectedFilesFail = "fail"
+	// ProtectedFilesQuarantine commits the changes to protected files
+	// separately from the other changes, so that they can be reviewed or
+	// reverted on their own.
+	ProtectedFilesQuarantine = "quarantine"
+)
+
+// ProtectedFiles defines handwritten files of libraries which generation must
+// not change.
+type ProtectedFiles struct {
+	// OnChange is the action taken when generation changes a protected file,
+	// either "fail" or "quarantine". Defaults to "fail".
+	OnChange string `yaml:"on_change,omitempty"`
+	// Libraries lists the protected paths of each library.
+	Libraries []*LibraryProtectedFiles `yaml:"libraries"`
+}
+
+// LibraryProtectedFiles defines the protected paths of a library.
+type LibraryProtectedFiles struct {
+	// ID is the ID of the library.
+	ID string `yaml:"id"`
+	// Paths are files or directories, relative to the root of the repository.
+	// A directory protects every file within it.
+	Paths []string `yaml:"paths"`
+}
+
+// The groupings of the changes of a generation run into commits.
+const (
+	// CommitGroupingRun commits all the changes of the run at once.
+	CommitGroupingRun = "run"
+	// CommitGroupingLibrary commits the changes of each library separately.
+	CommitGroupingLibrary = "library"
+	// CommitGroupingAPI commits the changes of each API path separately.
+	CommitGroupingAPI = "api"
+	// CommitGroupingSquash commits all the changes of the run at once, with
+	// a message detailing the changes of each library.
+	CommitGroupingSquash = "squash"
+)
+
+// The strategies for resolving a conflict between regeneration and manual
+// edits of a file.
+const (
+	// ConflictPreferGenerated overwrites the manual edits with the generated
+	// content.
+	ConflictPreferGenerated = "prefer-generated"
+	// ConflictPreferManual keeps the manually edited content, discarding the
+	// generated changes to the file.
+	ConflictPreferManual = "prefer-manual"
+	// ConflictFail fails the generation run.
+	ConflictFail = "fail"
+	// ConflictThreeWayMerge merges the manual edits and the generated changes,
+	// using the content of the last generation as the common base. The
+	// generation run fails if the changes overlap.
+	ConflictThreeWayMerge = "three-way-merge"
+)
+
+// ConflictResolution defines the strategy for resolving conflicts in the
+// files matching a path pattern.
+type ConflictResolution struct {
+	// Path is a regular expression matched against paths relative to the
+	// root of the repository.
+	Path string `yaml:"path"`
+	// Strategy is one of "prefer-generated", "prefer-manual", "fail" and
+	// "three-way-merge".
+	Strategy string `yaml:"strategy"`
+}
+
+// LineEndingsLF converts the line endings of generated files to "\n".
+const LineEndingsLF = "lf"
+
+// Normalization defines how the generated files are normalized. Files
+// containing a NUL byte are considered binary, and only their permissions are
+// normalized.
+type Normalization struct {
+	// LineEndings is "lf" to convert "\r\n" line endings to "\n". Line
+	// endings are kept if empty.
+	LineEndings string `yaml:"line_endings,omitempty"`
+	// StripPatterns are regular expressions, in multi-line mode, whose
+	// matches are removed from the generated files, e.g. the line of a
+	// generation timestamp: "^// Generated on .*\n".
+	StripPatterns []string `yaml:"strip_patterns,omitempty"`
+	// SortLines are regular expressions matched against the paths of
+	// generated files, relative to the output directory of the library, whose
+	// lines are sorted, such as generated lists of files.
+	SortLines []string `yaml:"sort_lines,omitempty"`
+	// Permissions, if true, sets the permissions of the generated files to
+	// 0755 if they are executable by their owner, and to 0644 otherwise.
+	Permissions bool `yaml:"permissions,omitempty"`
+}
+
+// NoOp defines which generated changes are meaningful. Changes to the files
+// in the .librarian directory, such as the state, are never meaningful by
+// themselves.
+type NoOp struct {
+	// Ignore are regular expressions matched against the paths of changed
+	// files, relative to the root of the repository, whose changes are not
+	// meaningful, e.g. "/gapic_metadata\\.json$".
+	Ignore []string `yaml:"ignore,omitempty"`
+	// MinFiles is the minimum number of meaningfully changed files of a run
+	// which is committed. Defaults to 1.
+	MinFiles int `yaml:"min_files,omitempty"`
+}
+
+// The CI providers for which trigger definitions can be generated.
+const (
+	CIProviderGitHubActions = "github-actions"
+	CIProviderCloudBuild    = "cloud-build"
+	CIProviderKokoro        = "kokoro"
+)
+
+// The standard workflows of a language repository.
+const (
+	// CIWorkflowGenerate regenerates the libraries, nightly by default.
+	CIWorkflowGenerate = "generate"
+	// CIWorkflowReleaseInit creates a release pull request, weekly by
+	// default.
+	CIWorkflowReleaseInit = "release-init"
+	// CIWorkflowTagAndRelease tags and publishes merged release pull
+	// requests, on pushes to the default branch by default.
+	CIWorkflowTagAndRelease = "tag-and-release"
+)
+
+// CITriggers defines how the standard workflows of the repository are run by
+// a CI provider.
+type CITriggers struct {
+	// Provider is one of "github-actions", "cloud-build" and "kokoro".
+	Provider string `yaml:"provider"`
+	// LibrarianVersion is the version of the github.com/googleapis/librarian
+	// module run by GitHub Actions and Kokoro. Defaults to "latest".
+	LibrarianVersion string `yaml:"librarian_version,omitempty"`
+	// Image is the librarian container image run by Cloud Build. Required
+	// for "cloud-build".
+	Image string `yaml:"image,omitempty"`
+	// Branch is the default branch of the repository. Defaults to "main".
+	Branch string `yaml:"branch,omitempty"`
+	// TokenSecret is the name of the secret holding the GitHub token of
+	// librarian. Defaults to "LIBRARIAN_GITHUB_TOKEN".
+	TokenSecret string `yaml:"token_secret,omitempty"`
+	// Workflows customize the standard workflows, by name.
+	Workflows []*CIWorkflow `yaml:"workflows,omitempty"`
+}
+
+// CIWorkflow customizes a standard workflow.
+type CIWorkflow struct {
+	// Name is one of "generate", "release-init" and "tag-and-release".
+	Name string `yaml:"name"`
+	// Schedule is the cron schedule of the workflow, in UTC. An empty
+	// schedule keeps the default of the workflow.
+	Schedule string `yaml:"schedule,omitempty"`
+	// Disabled omits the workflow.
+	Disabled bool `yaml:"disabled,omitempty"`
+	// Flags are additional flags of the librarian command, e.g. "-build".
+	Flags []string `yaml:"flags,omitempty"`
+}
+
+// Gerrit defines how changes are sent for review to a Gerrit host.
+type Gerrit struct {
+	// URL is the base URL of the Gerrit host, e.g.
+	// https://example-review.googlesource.com. Required.
+	URL string `yaml:"url"`
+	// Project is the name of the Gerrit project of the repository, e.g.
+	// "example/google-cloud-go". Required.
+	Project string `yaml:"project"`
+	// Branch is the branch which changes are pushed for review to. Defaults
+	// to "main".
+	Branch string `yaml:"branch,omitempty"`
+	// Topic is the topic of the changes. Defaults to the name of the local
+	// branch of the run, so that the changes of a run, such as quarantined
+	// protected files, are grouped together.
+	Topic string `yaml:"topic,omitempty"`
+	// Labels are the votes cast on each change after it is pushed, e.g.
+	// {"Code-Review": 1, "Commit-Queue": 1}.
+	Labels map[string]int `yaml:"labels,omitempty"`
+}
+
+// Kubernetes defines how language containers run as Kubernetes Jobs, one Job
+// per container run, for fleets too large for a single Docker host. The
+// directories which librarian mounts into the containers are shared with the
+// Jobs either through Volume or through Storage; exactly one of them must be
+// set.
+type Kubernetes struct {
+	// Namespace is the namespace of the Jobs. The namespace of the current
+	// kubectl context is used if empty.
+	Namespace string `yaml:"namespace,omitempty"`
+	// ServiceAccount is the service account which the pods of the Jobs run
+	// as. The default service account of the namespace is used if empty.
+	ServiceAccount string `yaml:"service_account,omitempty"`
+	// Requests are the resources requested by the language containers, which
+	// the scheduler reserves for them. The limits are those of
+	// container_limits.
+	Requests *ResourceRequests `yaml:"requests,omitempty"`
+	// Volume is a persistent volume claim shared by librarian and the Jobs.
+	Volume *KubernetesVolume `yaml:"volume,omitempty"`
+	// Storage is a Cloud Storage location, gs://bucket[/prefix], through
+	// which the mounted directories are uploaded to the Jobs before they run,
+	// and the writable ones downloaded after they succeed.
+	Storage string `yaml:"storage,omitempty"`
+	// StorageImage is the image which copies the directories from and to
+	// Storage in the pods of the Jobs. It must provide gcloud, sh and tar.
+	// Defaults to the slim image of the Google Cloud CLI.
+	StorageImage string `yaml:"storage_image,omitempty"`
+}
+
+// ResourceRequests are the resources requested for a Kubernetes container.
+type ResourceRequests struct {
+	// CPU is the requested CPU, as a Kubernetes quantity, e.g. "2" or
+	// "500m".
+	CPU string `yaml:"cpu,omitempty"`
+	// Memory is the requested memory, as a Kubernetes quantity, e.g. "8Gi".
+	Memory string `yaml:"memory,omitempty"`
+}
+
+// KubernetesVolume is a persistent volume claim which is mounted both into
+// the pod of librarian and into the pods of the Jobs, e.g. a ReadWriteMany
+// volume of a network file system. The work root and the repositories of the
+// run must be on the volume, so that their directories are mounted into the
+// containers as sub-paths of the claim.
+type KubernetesVolume struct {
+	// Claim is the name of the persistent volume claim. Required.
+	Claim string `yaml:"claim"`
+	// MountPath is the absolute path at which the claim is mounted in the
+	// pod of librarian. Required.
+	MountPath string `yaml:"mount_path"`
+}
+
+// kubernetesQuantityRegex matches Kubernetes resource quantities.
+var kubernetesQuantityRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(m|k|M|G|T|Ki|Mi|Gi|Ti)?$`)
+
+// validate checks the Kubernetes config.
+func (k *Kubernetes) validate() error {
+	if (k.Volume == nil) == (k.Storage == "") {
+		return errors.New("kubernetes requires exactly one of volume and storage")
+	}
+	if k.Volume != nil {
+		if k.Volume.Claim == "" {
+			return errors.New("kubernetes volume requires a claim")
+		}
+		if !path.IsAbs(k.Volume.MountPath) {
+			return fmt.Errorf("invalid kubernetes volume mount_path %q, want an absolute path", k.Volume.MountPath)
+		}
+	}
+	if k.Storage != "" {
+		if bucket, _, _ := strings.Cut(strings.TrimPrefix(k.Storage, "gs://"), "/"); !strings.HasPrefix(k.Storage, "gs://") || bucket == "" {
+			return fmt.Errorf("invalid kubernetes storage %q, want gs://bucket[/prefix]", k.Storage)
+		}
+	}
+	if k.Requests != nil {
+		for name, quantity := range map[string]string{"cpu": k.Requests.CPU, "memory": k.Requests.Memory} {
+			if quantity != "" && !kubernetesQuantityRegex.MatchString(quantity) {
+				return fmt.Errorf("invalid kubernetes %s request: %q", name, quantity)
+			}
+		}
+	}
+	return nil
+}
+
+// TargetBranch returns the branch which changes are pushed for review to.
+func (g *Gerrit) TargetBranch() string {
+	if g.Branch == "" {
+		return "main"
+	}
+	return g.Branch
+}
+
+// The kinds of pull requests created by librarian.
+const (
+	// PullRequestKindGenerate is a pull request of generated changes.
+	PullRequestKindGenerate = "generate"
+	// PullRequestKindRelease is a release pull request.
+	PullRequestKindRelease = "release"
+)
+
+// PullRequests defines the labels and reviewers of the pull requests created
+// by librarian. The labels are added to, and the reviewers requested on, each
+// pull request in addition to the owners of its libraries.
+type PullRequests struct {
+	// Labels are added to all pull requests, e.g. "automated".
+	Labels []string `yaml:"labels,omitempty"`
+	// Reviewers are requested to review all pull requests. A reviewer is a
+	// GitHub user, e.g. "@octocat", or a team, e.g. "@googleapis/yoshi".
+	Reviewers []string `yaml:"reviewers,omitempty"`
+	// LibraryLabel is the label added for each library changed by a pull
+	// request, with "{id}" replaced by the ID of the library, e.g.
+	// "api: {id}".
+	LibraryLabel string `yaml:"library_label,omitempty"`
+	// Kinds define additional labels and reviewers of the pull requests of a
+	// kind.
+	Kinds []*PullRequestKind `yaml:"kinds,omitempty"`
+}
+
+// PullRequestKind defines the additional labels and reviewers of the pull
+// requests of a kind.
+type PullRequestKind struct {
+	// Kind is the kind of pull requests: "generate" or "release".
+	Kind string `yaml:"kind"`
+	// Labels are added to the pull requests of the kind.
+	Labels []string `yaml:"labels,omitempty"`
+	// Reviewers are requested to review the pull requests of the kind.
+	Reviewers []string `yaml:"reviewers,omitempty"`
+}
+
+// DefaultRegenerationMarker is the default regeneration marker of the
+// libraries. See [LibrarianConfig.RegenerationMarker].
+const DefaultRegenerationMarker = ".librarian/regenerations/{id}.yaml"
+
+// DefaultAPISnapshotPath is the directory of the snapshot of API definitions,
+// unless one is configured.
+const DefaultAPISnapshotPath = "third_party/googleapis"
+
+// APISnapshot defines the snapshot of the API definitions of the libraries,
+// copied from the API source repository into the repository.
+type APISnapshot struct {
+	// Path is the directory of the snapshot, relative to the root of the
+	// repository. Defaults to "third_party/googleapis".
+	Path string `yaml:"path,omitempty"`
+}
+
+const (
+	// DefaultCanaryPrerelease is the prerelease identifier of canary
+	// versions, unless one is configured.
+	DefaultCanaryPrerelease = "beta"
+	// DefaultCanaryTagFormat is the format of the tags of canary releases,
+	// unless one is configured.
+	DefaultCanaryTagFormat = "{id}-canary-{version}"
+)
+
+// Canary defines the canary release channel.
+type Canary struct {
+	// Prerelease is the prerelease identifier of canary versions, e.g.
+	// "beta" for 1.5.0-beta.3. Defaults to "beta".
+	Prerelease string `yaml:"prerelease,omitempty"`
+	// TagFormat is the format of the tags of canary releases, with the {id}
+	// and {version} placeholders like the tag_format of libraries. Defaults
+	// to "{id}-canary-{version}".
+	TagFormat string `yaml:"tag_format,omitempty"`
+}
+
+// SourceAttribution defines how the commits of the API source repository
+// which changed the generated libraries are attributed.
+type SourceAttribution struct {
+	// CoAuthors adds a Co-authored-by trailer for each author of the source
+	// commits to the commits of the libraries they changed.
+	CoAuthors bool `yaml:"co_authors,omitempty"`
+	// CommitURL is the URL of a source commit, with the {hash} placeholder,
+	// e.g. "https://github.com/googleapis/googleapis/commit/{hash}". When it
+	// is set, the source commits are linked in the commit messages.
+	CommitURL string `yaml:"commit_url,omitempty"`
+}
+
+const (
+	// ArtifactSigningGPG signs artifacts with a GPG private key.
+	ArtifactSigningGPG = "gpg"
+	// ArtifactSigningKMS signs artifacts with an asymmetric key of Cloud
+	// KMS.
+	ArtifactSigningKMS = "kms"
+)
+
+// ArtifactSigning defines how the built artifacts of releases are signed.
+// Each artifact gets a detached signature next to it in the artifact root:
+// an ASCII-armored "{artifact}.asc" with GPG, or the raw signature of its
+// SHA-256 digest in "{artifact}.sig" with Cloud KMS.
+type ArtifactSigning struct {
+	// Method is how the artifacts are signed: "gpg" or "kms".
+	Method string `yaml:"method"`
+	// KeySecretEnv is the name of the environment variable of the librarian
+	// process holding the ASCII-armored GPG private key, e.g. one populated
+	// from a secret manager by the build environment. Required for "gpg".
+	KeySecretEnv string `yaml:"key_secret_env,omitempty"`
+	// PassphraseSecretEnv is the name of the environment variable holding
+	// the passphrase of the GPG private key, if it has one.
+	PassphraseSecretEnv string `yaml:"passphrase_secret_env,omitempty"`
+	// KMSKey is the resource name of the version of the Cloud KMS key, e.g.
+	// "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1",
+	// whose algorithm must use SHA-256. Required for "kms".
+	KMSKey string `yaml:"kms_key,omitempty"`
+}
+
+// BugTracker is a bug tracker whose bugs are referenced in commit messages.
+type BugTracker struct {
+	// Prefix is the prefix of the references to the bugs of the tracker, e.g.
+	// "b/" for "b/123", or "#" for "#456".
+	Prefix string `yaml:"prefix"`
+	// URL is the URL of a bug, with the {id} placeholder for the ID of the
+	// bug, e.g. "https://issuetracker.google.com/issues/{id}".
+	URL string `yaml:"url"`
+}
+
+const (
+	// MirrorCommits pushes the released commits to the branch of a mirror.
+	MirrorCommits = "commits"
+	// MirrorTags pushes the release tags to a mirror.
+	MirrorTags = "tags"
+	// DefaultMirrorBranch is the branch of a mirror to which commits are
+	// pushed, unless one is configured.
+	DefaultMirrorBranch = "main"
+)
+
+// Mirror is a secondary remote of the repository, to which the commits and
+// tags of releases are pushed.
+type Mirror struct {
+	// Name identifies the mirror, e.g. in the -mirror flag.
+	Name string `yaml:"name"`
+	// URL is the URL of the mirror, e.g.
+	// "https://gitlab.example.com/googleapis/google-cloud-go.git".
+	URL string `yaml:"url"`
+	// Push lists what is pushed to the mirror: "commits", "tags", or both,
+	// which is the default.
+	Push []string `yaml:"push,omitempty"`
+	// Branch is the branch of the mirror to which the commits are pushed.
+	// Defaults to "main".
+	Branch string `yaml:"branch,omitempty"`
+	// Username is the username of HTTP basic auth with the mirror. Defaults
+	// to that of the repository.
+	Username string `yaml:"username,omitempty"`
+	// PasswordEnv is the environment variable holding the password, or
+	// token, of HTTP basic auth with the mirror. The password itself is never
+	// stored in config.yaml.
+	PasswordEnv string `yaml:"password_env,omitempty"`
+	// SSHKeyFile is the private key to authenticate with the mirror if it
+	// uses SSH. Defaults to the key specified with -ssh-key.
+	SSHKeyFile string `yaml:"ssh_key_file,omitempty"`
+}
+
+// Pushes reports whether what, "commits" or "tags", is pushed to m.
+func (m *Mirror) Pushes(what string) bool {
+	return len(m.Push) == 0 || slices.Contains(m.Push, what)
+}
+
+// TargetBranch returns the branch of m to which the commits are pushed.
+func (m *Mirror) TargetBranch() string {
+	return cmp.Or(m.Branch, DefaultMirrorBranch)
+}
+
+// BuildVariantEnvVar is the environment variable holding the name of the
+// build variant in the containers of the build and test commands of a variant.
+const BuildVariantEnvVar = "LIBRARIAN_BUILD_VARIANT"
+
+// BuildVariant is a variant of the build matrix, a cell of which is the build
+// and test of a library in a variant.
+type BuildVariant struct {
+	// Name identifies the variant in reports, e.g. "java8" or "graalvm".
+	Name string `yaml:"name"`
+	// Libraries limits the variant to the given library IDs. The variant
+	// applies to all libraries if empty.
+	Libraries []string `yaml:"libraries,omitempty"`
+	// Environment are the environment variables injected into the containers
+	// of the build and test commands of the variant. They replace the
+	// variables of the same name of the environment of the config.
+	Environment []*EnvironmentVariable `yaml:"environment,omitempty"`
+}
+
+// BuildVariantsFor returns the variants of the build matrix which the library
+// with the given ID is built and tested for, or nil if it has none.
+func (g *LibrarianConfig) BuildVariantsFor(libraryID string) []*BuildVariant {
+	if g == nil {
+		return nil
+	}
+	var variants []*BuildVariant
+	for _, variant := range g.BuildMatrix {
+		if len(variant.Libraries) == 0 || slices.Contains(variant.Libraries, libraryID) {
+			variants = append(variants, variant)
+		}
+	}
+	return variants
+}
+
+// BuildEnvironmentFor returns the environment variables to inject into the
+// container when running command, "build" or "test", for the library with the
+// given ID in variant. It is EnvironmentFor when variant is nil.
+func (g *LibrarianConfig) BuildEnvironmentFor(command, libraryID string, variant *BuildVariant) []*EnvironmentVariable {
+	env := g.EnvironmentFor(command, libraryID)
+	if variant == nil {
+		return env
+	}
+	variables := []*EnvironmentVariable{{Name: BuildVariantEnvVar, Value: variant.Name}}
+	for _, variable := range variant.Environment {
+		if len(variable.Commands) > 0 && !slices.Contains(variable.Commands, command) {
+			continue
+		}
+		if len(variable.Libraries) > 0 && !slices.Contains(variable.Libraries, libraryID) {
+			continue
+		}
+		variables = append(variables, variable)
+	}
+	env = slices.DeleteFunc(slices.Clone(env), func(e *EnvironmentVariable) bool {
+		return slices.ContainsFunc(variables, func(v *EnvironmentVariable) bool { return v.Name == e.Name })
+	})
+	return append(env, variables...)
+}
+
+// BugURL returns the URL of the bug referenced by ref, e.g. "b/123", in the
+// first bug tracker whose prefix ref starts with, or an empty string if
+// there is none.
+func (g *LibrarianConfig) BugURL(ref string) string {
+	if g == nil {
+		return ""
+	}
+	for _, tracker := range g.BugTrackers {
+		if id, ok := strings.CutPrefix(ref, tracker.Prefix); ok && id != "" {
+			return strings.ReplaceAll(tracker.URL, "{id}", id)
+		}
+	}
+	return ""
+}
+
+// SourceCommitURL returns the URL of the source commit hash, or an empty
+// string if no commit URL is configured.
+func (g *LibrarianConfig) SourceCommitURL(hash string) string {
+	if g == nil || g.SourceAttribution == nil || g.SourceAttribution.CommitURL == "" {
+		return ""
+	}
+	return strings.ReplaceAll(g.SourceAttribution.CommitURL, "{hash}", hash)
+}
+
+// SourceCoAuthors reports whether the authors of the source commits are
+// credited as co-authors.
+func (g *LibrarianConfig) SourceCoAuthors() bool {
+	return g != nil && g.SourceAttribution != nil && g.SourceAttribution.CoAuthors
+}
+
+// CanaryPrerelease returns the prerelease identifier of canary versions.
+func (g *LibrarianConfig) CanaryPrerelease() string {
+	if g == nil || g.Canary == nil {
+		return DefaultCanaryPrerelease
+	}
+	return cmp.Or(g.Canary.Prerelease, DefaultCanaryPrerelease)
+}
+
+// CanaryTagFormat returns the format of the tags of canary releases.
+func (g *LibrarianConfig) CanaryTagFormat() string {
+	if g == nil || g.Canary == nil {
+		return DefaultCanaryTagFormat
+	}
+	return cmp.Or(g.Canary.TagFormat, DefaultCanaryTagFormat)
+}
+
+// ReleasePolicy defines rules which gate releases. The rules are evaluated
+// by release init before a release pull request is created, except for the
+// approval, which tag-and-release requires before tagging.
+type ReleasePolicy struct {
+	// Approvers are the users, e.g. "@octocat", and teams, e.g.
+	// "@googleapis/release-team", who may approve any release, in addition to
+	// the owners of the released libraries.
+	Approvers []string `yaml:"approvers,omitempty"`
+	// BlockedDays are the days of the week, e.g. "Friday", on which no
+	// release is initiated. Days are in UTC.
+	BlockedDays []string `yaml:"blocked_days,omitempty"`
+	// FreezeFeed is the URL of an iCalendar or JSON feed of release freezes,
+	// in addition to Freezes, e.g. the freeze calendar of an organization.
+	FreezeFeed string `yaml:"freeze_feed,omitempty"`
+	// Freezes are the windows during which no release pull request is
+	// created and no release is tagged, unless the freeze is overridden with
+	// -override-freeze.
+	Freezes []*ReleaseFreeze `yaml:"freezes,omitempty"`
+	// MinChanges is the minimum number of releasable changes since the last
+	// release for a library to be released.
+	MinChanges int `yaml:"min_changes,omitempty"`
+	// MinInterval is the minimum time between two releases of a library, as
+	// a duration such as "24h".
+	MinInterval string `yaml:"min_interval,omitempty"`
+	// RequireApproval determines whether a release must be approved with
+	// approve-release before tag-and-release tags it.
+	RequireApproval bool `yaml:"require_approval,omitempty"`
+}
+
+// RequiresApproval reports whether releases must be approved before they are
+// tagged.
+func (p *ReleasePolicy) RequiresApproval() bool {
+	return p != nil && p.RequireApproval
+}
+
+// IsBlockedOn reports whether releases are blocked on the day of t.
+func (p *ReleasePolicy) IsBlockedOn(t time.Time) bool {
+	if p == nil {
+		return false
+	}
+	day := t.UTC().Weekday().String()
+	for _, blocked := range p.BlockedDays {
+		if strings.EqualFold(blocked, day) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReleaseFreeze is a window during which releases are frozen.
+type ReleaseFreeze struct {
+	// Start is the first day of the freeze, e.g. "2025-12-20", or the time it
+	// starts, e.g. "2025-12-20T17:00:00Z". Days are in UTC.
+	Start string `yaml:"start" json:"start"`
+	// End is the last day of the freeze, e.g. "2026-01-05", or the time it
+	// ends.
+	End    string `yaml:"end" json:"end"`
+	Reason string `yaml:"reason,omitempty" json:"reason,omitempty"`
+}
+
+// Window returns the start of the freeze, and its end, exclusive.
+func (f *ReleaseFreeze) Window() (time.Time, time.Time, error) {
+	start, err := parseFreezeTime(f.Start, false)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid freeze start %q", f.Start)
+	}
+	end, err := parseFreezeTime(f.End, true)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid freeze end %q", f.End)
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("freeze ends at %s before it starts at %s", f.End, f.Start)
+	}
+	return start, end, nil
+}
+
+// IsActiveAt reports whether the freeze is active at t. A freeze which is
+// not valid is never active.
+func (f *ReleaseFreeze) IsActiveAt(t time.Time) bool {
+	start, end, err := f.Window()
+	return err == nil && !t.Before(start) && t.Before(end)
+}
+
+// parseFreezeTime parses a day or a time of a freeze. The end of a freeze on
+// a day is the start of the next day.
+func parseFreezeTime(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// MinIntervalDuration returns the parsed MinInterval, or 0 if p is nil or
+// MinInterval is not set.
+func (p *ReleasePolicy) MinIntervalDuration() time.Duration {
+	if p == nil || p.MinInterval == "" {
+		return 0
+	}
+	// MinInterval is checked by Validate.
+	d, _ := time.ParseDuration(p.MinInterval)
+	return d
+}
+
+// StrictContainerSandbox returns the most restrictive sandbox: no network, a
+// read-only root filesystem, no capabilities and a tmpfs at /tmp.
+func StrictContainerSandbox() *ContainerSandbox {
+	return &ContainerSandbox{
+		Network:          "none",
+		ReadOnly:         true,
+		DropCapabilities: true,
+		Tmpfs:            []string{"/tmp"},
+	}
+}
+
+var (
+	envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// variantNameRegex matches the names of build variants, which are
+	// shown in reports and tables.
+	variantNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	// canaryPrereleaseRegex matches the prerelease identifiers which are not
+	// numeric, so that they are told apart from the prerelease number.
+	canaryPrereleaseRegex = regexp.MustCompile(`^[0-9A-Za-z-]*[A-Za-z-][0-9A-Za-z-]*$`)
+
+	validContainerCommands = map[string]bool{
+		"build":        true,
+		"configure":    true,
+		"generate":     true,
+		"release-init": true,
+		"test":         true,
+	}
+)
+
 var validPermissions = map[string]bool{
 	PermissionReadOnly:  true,
 	PermissionWriteOnly: true,
@@ -52,6 +954,645 @@ func (g *LibrarianConfig) Validate() error {
 			return fmt.Errorf("invalid global file permissions at index %d: %q", i, permissions)
 		}
 	}
+	for i, versionFile := range g.VersionFiles {
+		if !isValidDirPath(versionFile.Path) {
+			return fmt.Errorf("invalid version file path at index %d: %q", i, versionFile.Path)
+		}
+		if _, err := versionfile.New(versionFile.Kind, versionFile.Pattern); err != nil {
+			return fmt.Errorf("invalid version file at index %d: %w", i, err)
+		}
+	}
+	for i, env := range g.Environment {
+		if !envNameRegex.MatchString(env.Name) {
+			return fmt.Errorf("invalid environment variable name at index %d: %q", i, env.Name)
+		}
+		if env.Value != "" && env.SecretEnv != "" {
+			return fmt.Errorf("environment variable %s at index %d sets both value and secret_env", env.Name, i)
+		}
+		if env.SecretEnv != "" && !envNameRegex.MatchString(env.SecretEnv) {
+			return fmt.Errorf("invalid secret_env of environment variable at index %d: %q", i, env.SecretEnv)
+		}
+		for _, command := range env.Commands {
+			if !validContainerCommands[command] {
+				return fmt.Errorf("invalid command of environment variable at index %d: %q", i, command)
+			}
+		}
+	}
+	if g.Sandbox != nil {
+		for _, command := range g.Sandbox.Commands {
+			if !validContainerCommands[command] {
+				return fmt.Errorf("invalid sandbox command: %q", command)
+			}
+		}
+		if g.Sandbox.Network != "" && g.Sandbox.Network != "none" {
+			return fmt.Errorf("invalid sandbox network: %q", g.Sandbox.Network)
+		}
+		for _, path := range g.Sandbox.Tmpfs {
+			if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, ":,") {
+				return fmt.Errorf("invalid sandbox tmpfs path: %q", path)
+			}
+		}
+	}
+	if g.ProtectedFiles != nil {
+		switch g.ProtectedFiles.OnChange {
+		case "", ProtectedFilesFail, ProtectedFilesQuarantine:
+		default:
+			return fmt.Errorf("invalid protected files on_change: %q", g.ProtectedFiles.OnChange)
+		}
+		for i, library := range g.ProtectedFiles.Libraries {
+			if library.ID == "" {
+				return fmt.Errorf("protected files at index %d have no library id", i)
+			}
+			for _, path := range library.Paths {
+				if !isValidDirPath(path) {
+					return fmt.Errorf("invalid protected path of library %s: %q", library.ID, path)
+				}
+			}
+		}
+	}
+	for i, rule := range g.ConflictResolution {
+		if _, err := regexp.Compile(rule.Path); err != nil || rule.Path == "" {
+			return fmt.Errorf("invalid conflict resolution path at index %d: %q", i, rule.Path)
+		}
+		switch rule.Strategy {
+		case ConflictPreferGenerated, ConflictPreferManual, ConflictFail, ConflictThreeWayMerge:
+		default:
+			return fmt.Errorf("invalid conflict resolution strategy of %s: %q", rule.Path, rule.Strategy)
+		}
+	}
+	if g.Normalization != nil {
+		if err := g.Normalization.validate(); err != nil {
+			return err
+		}
+	}
+	if g.NoOp != nil {
+		for _, path := range g.NoOp.Ignore {
+			if _, err := regexp.Compile(path); err != nil || path == "" {
+				return fmt.Errorf("invalid no-op ignore path: %q", path)
+			}
+		}
+		if g.NoOp.MinFiles < 0 {
+			return fmt.Errorf("invalid no-op min files, must not be negative: %d", g.NoOp.MinFiles)
+		}
+	}
+	if g.RegenerationMarker != "" && !isValidDirPath(strings.NewReplacer("{id}", "id", "{source_root}", "root").Replace(g.RegenerationMarker)) {
+		return fmt.Errorf("invalid regeneration marker path: %q", g.RegenerationMarker)
+	}
+	switch g.CommitGrouping {
+	case "", CommitGroupingRun, CommitGroupingLibrary, CommitGroupingAPI, CommitGroupingSquash:
+	default:
+		return fmt.Errorf("invalid commit grouping: %q", g.CommitGrouping)
+	}
+	if g.CITriggers != nil {
+		switch g.CITriggers.Provider {
+		case CIProviderGitHubActions, CIProviderKokoro:
+		case CIProviderCloudBuild:
+			if g.CITriggers.Image == "" {
+				return errors.New("ci triggers of cloud-build require an image")
+			}
+		default:
+			return fmt.Errorf("invalid ci triggers provider: %q", g.CITriggers.Provider)
+		}
+		for _, workflow := range g.CITriggers.Workflows {
+			switch workflow.Name {
+			case CIWorkflowGenerate, CIWorkflowReleaseInit, CIWorkflowTagAndRelease:
+			default:
+				return fmt.Errorf("invalid ci workflow: %q", workflow.Name)
+			}
+			if workflow.Schedule != "" && len(strings.Fields(workflow.Schedule)) != 5 {
+				return fmt.Errorf("invalid schedule of ci workflow %s: %q", workflow.Name, workflow.Schedule)
+			}
+		}
+	}
+	if g.ContainerLimits != nil {
+		if err := g.ContainerLimits.validate("container limits"); err != nil {
+			return err
+		}
+		for _, library := range g.ContainerLimits.Libraries {
+			if library.ID == "" {
+				return errors.New("container limits of a library require an id")
+			}
+			if err := library.validate(fmt.Sprintf("container limits of %s", library.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	containerNames := make(map[string]bool)
+	containerCommands := make(map[string]string)
+	for i, container := range g.Containers {
+		if container.Name == "" {
+			return fmt.Errorf("container at index %d requires a name", i)
+		}
+		if containerNames[container.Name] {
+			return fmt.Errorf("duplicate container: %q", container.Name)
+		}
+		containerNames[container.Name] = true
+		if container.Image == "" || strings.ContainsAny(container.Image, " \t") {
+			return fmt.Errorf("invalid image of container %s: %q", container.Name, container.Image)
+		}
+		if len(container.Commands) == 0 {
+			return fmt.Errorf("container %s requires commands", container.Name)
+		}
+		for _, command := range container.Commands {
+			if !validContainerCommands[command] {
+				return fmt.Errorf("invalid command of container %s: %q", container.Name, command)
+			}
+			if other, ok := containerCommands[command]; ok {
+				return fmt.Errorf("command %q is declared by containers %s and %s", command, other, container.Name)
+			}
+			containerCommands[command] = container.Name
+		}
+	}
+	if g.Gerrit != nil {
+		if !strings.HasPrefix(g.Gerrit.URL, "https://") && !strings.HasPrefix(g.Gerrit.URL, "http://") {
+			return fmt.Errorf("invalid gerrit url: %q", g.Gerrit.URL)
+		}
+		if g.Gerrit.Project == "" {
+			return errors.New("gerrit requires a project")
+		}
+		// The topic is a push option, which ends at a comma and must not be
+		// percent-encoded.
+		if strings.ContainsAny(g.Gerrit.Topic, ",% \t\n") {
+			return fmt.Errorf("invalid gerrit topic: %q", g.Gerrit.Topic)
+		}
+	}
+	if g.Kubernetes != nil {
+		if err := g.Kubernetes.validate(); err != nil {
+			return err
+		}
+	}
+	if g.PullRequests != nil {
+		if err := g.PullRequests.validate(); err != nil {
+			return err
+		}
+	}
+	if g.APISnapshot != nil && g.APISnapshot.Path != "" && !isValidDirPath(g.APISnapshot.Path) {
+		return fmt.Errorf("invalid api snapshot path: %q", g.APISnapshot.Path)
+	}
+	for i, dependency := range g.APISourceDependencies {
+		if !isValidDirPath(dependency) {
+			return fmt.Errorf("invalid api source dependency at index %d: %q", i, dependency)
+		}
+	}
+	if g.ArtifactSigning != nil {
+		if err := g.ArtifactSigning.validate(); err != nil {
+			return err
+		}
+	}
+	if g.Canary != nil {
+		if g.Canary.Prerelease != "" && !canaryPrereleaseRegex.MatchString(g.Canary.Prerelease) {
+			return fmt.Errorf("invalid canary prerelease: %q", g.Canary.Prerelease)
+		}
+		if g.Canary.TagFormat != "" && !strings.Contains(g.Canary.TagFormat, "{version}") {
+			return fmt.Errorf("invalid canary tag_format %q, want a {version} placeholder", g.Canary.TagFormat)
+		}
+	}
+	if g.SourceAttribution != nil && g.SourceAttribution.CommitURL != "" && !strings.Contains(g.SourceAttribution.CommitURL, "{hash}") {
+		return fmt.Errorf("invalid source attribution commit_url %q, want a {hash} placeholder", g.SourceAttribution.CommitURL)
+	}
+	for i, tracker := range g.BugTrackers {
+		if tracker.Prefix == "" {
+			return fmt.Errorf("bug tracker at index %d requires a prefix", i)
+		}
+		if !strings.Contains(tracker.URL, "{id}") {
+			return fmt.Errorf("invalid url of bug tracker %s: %q, want an {id} placeholder", tracker.Prefix, tracker.URL)
+		}
+	}
+	mirrorNames := make(map[string]bool)
+	for i, mirror := range g.Mirrors {
+		if mirror.Name == "" {
+			return fmt.Errorf("mirror at index %d requires a name", i)
+		}
+		if mirrorNames[mirror.Name] {
+			return fmt.Errorf("duplicate mirror: %q", mirror.Name)
+		}
+		mirrorNames[mirror.Name] = true
+		if mirror.URL == "" || strings.ContainsAny(mirror.URL, " \t") {
+			return fmt.Errorf("invalid url of mirror %s: %q", mirror.Name, mirror.URL)
+		}
+		for _, what := range mirror.Push {
+			if what != MirrorCommits && what != MirrorTags {
+				return fmt.Errorf("invalid push of mirror %s: %q, want %q or %q", mirror.Name, what, MirrorCommits, MirrorTags)
+			}
+		}
+		if strings.ContainsAny(mirror.Branch, " \t:~^") {
+			return fmt.Errorf("invalid branch of mirror %s: %q", mirror.Name, mirror.Branch)
+		}
+		if mirror.PasswordEnv != "" && !envNameRegex.MatchString(mirror.PasswordEnv) {
+			return fmt.Errorf("invalid password_env of mirror %s: %q", mirror.Name, mirror.PasswordEnv)
+		}
+	}
+	variantNames := make(map[string]bool)
+	for i, variant := range g.BuildMatrix {
+		if !variantNameRegex.MatchString(variant.Name) {
+			return fmt.Errorf("invalid name of build variant at index %d: %q", i, variant.Name)
+		}
+		if variantNames[variant.Name] {
+			return fmt.Errorf("duplicate build variant: %q", variant.Name)
+		}
+		variantNames[variant.Name] = true
+		for j, env := range variant.Environment {
+			if !envNameRegex.MatchString(env.Name) || env.Name == BuildVariantEnvVar {
+				return fmt.Errorf("invalid environment variable name of build variant %s at index %d: %q", variant.Name, j, env.Name)
+			}
+			if env.Value != "" && env.SecretEnv != "" {
+				return fmt.Errorf("environment variable %s of build variant %s sets both value and secret_env", env.Name, variant.Name)
+			}
+			if env.SecretEnv != "" && !envNameRegex.MatchString(env.SecretEnv) {
+				return fmt.Errorf("invalid secret_env of environment variable %s of build variant %s: %q", env.Name, variant.Name, env.SecretEnv)
+			}
+			for _, command := range env.Commands {
+				if command != "build" && command != "test" {
+					return fmt.Errorf("invalid command of environment variable %s of build variant %s: %q, want \"build\" or \"test\"", env.Name, variant.Name, command)
+				}
+			}
+		}
+	}
+	if g.LibrarianVersion != "" {
+		if _, err := semver.Parse(strings.TrimPrefix(g.LibrarianVersion, "v")); err != nil {
+			return fmt.Errorf("invalid librarian_version: %q", g.LibrarianVersion)
+		}
+	}
+	if g.ReleasePolicy != nil {
+		for i, approver := range g.ReleasePolicy.Approvers {
+			if !ownerRegex.MatchString(approver) {
+				return fmt.Errorf("invalid release policy approver at index %d: %q", i, approver)
+			}
+		}
+		for _, day := range g.ReleasePolicy.BlockedDays {
+			if !isWeekday(day) {
+				return fmt.Errorf("invalid release policy blocked day: %q", day)
+			}
+		}
+		for i, freeze := range g.ReleasePolicy.Freezes {
+			if _, _, err := freeze.Window(); err != nil {
+				return fmt.Errorf("invalid release policy freeze at index %d: %w", i, err)
+			}
+		}
+		if g.ReleasePolicy.FreezeFeed != "" {
+			if u, err := url.Parse(g.ReleasePolicy.FreezeFeed); err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+				return fmt.Errorf("invalid release policy freeze_feed: %q", g.ReleasePolicy.FreezeFeed)
+			}
+		}
+		if g.ReleasePolicy.MinChanges < 0 {
+			return fmt.Errorf("invalid release policy min_changes: %d", g.ReleasePolicy.MinChanges)
+		}
+		if g.ReleasePolicy.MinInterval != "" {
+			if d, err := time.ParseDuration(g.ReleasePolicy.MinInterval); err != nil || d < 0 {
+				return fmt.Errorf("invalid release policy min_interval: %q", g.ReleasePolicy.MinInterval)
+			}
+		}
+	}
+	return nil
+}
+
+func isWeekday(day string) bool {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(day, d.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsProtected reports whether path, relative to the root of the repository,
+// is a protected file of the library with the given ID.
+func (g *LibrarianConfig) IsProtected(libraryID, path string) bool {
+	if g == nil || g.ProtectedFiles == nil {
+		return false
+	}
+	for _, library := range g.ProtectedFiles.Libraries {
+		if library.ID != libraryID {
+			continue
+		}
+		for _, protected := range library.Paths {
+			protected = strings.TrimSuffix(protected, "/")
+			if path == protected || strings.HasPrefix(path, protected+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ConflictStrategy returns the strategy for resolving a conflict between
+// regeneration and manual edits of the file at path, relative to the root of
+// the repository.
+func (g *LibrarianConfig) ConflictStrategy(path string) string {
+	if g == nil {
+		return ConflictPreferGenerated
+	}
+	for _, rule := range g.ConflictResolution {
+		if re, err := regexp.Compile(rule.Path); err == nil && re.MatchString(path) {
+			return rule.Strategy
+		}
+	}
+	return ConflictPreferGenerated
+}
+
+// ProtectedFilesAction returns the action taken when generation changes a
+// protected file.
+func (g *LibrarianConfig) ProtectedFilesAction() string {
+	if g == nil || g.ProtectedFiles == nil || g.ProtectedFiles.OnChange == "" {
+		return ProtectedFilesFail
+	}
+	return g.ProtectedFiles.OnChange
+}
+
+// CommitGroupingMode returns how the changes of a generation run are grouped
+// into commits.
+func (g *LibrarianConfig) CommitGroupingMode() string {
+	if g == nil || g.CommitGrouping == "" {
+		return CommitGroupingRun
+	}
+	return g.CommitGrouping
+}
+
+// RegenerationMarkerPath returns the path of the regeneration marker of
+// library, relative to the root of the repository.
+func (g *LibrarianConfig) RegenerationMarkerPath(library *LibraryState) string {
+	marker := DefaultRegenerationMarker
+	if g != nil && g.RegenerationMarker != "" {
+		marker = g.RegenerationMarker
+	}
+	var sourceRoot string
+	if len(library.SourceRoots) > 0 {
+		sourceRoot = library.SourceRoots[0]
+	}
+	return path.Clean(strings.NewReplacer("{id}", library.ID, "{source_root}", sourceRoot).Replace(marker))
+}
 
+// APISnapshotPath returns the directory of the snapshot of API definitions,
+// relative to the root of the repository.
+func (g *LibrarianConfig) APISnapshotPath() string {
+	if g == nil || g.APISnapshot == nil || g.APISnapshot.Path == "" {
+		return DefaultAPISnapshotPath
+	}
+	return g.APISnapshot.Path
+}
+
+// SandboxFor returns the sandbox of the container when running command, or
+// nil if the container is not sandboxed.
+func (g *LibrarianConfig) SandboxFor(command string) *ContainerSandbox {
+	if g == nil || g.Sandbox == nil {
+		return nil
+	}
+	if len(g.Sandbox.Commands) > 0 && !slices.Contains(g.Sandbox.Commands, command) {
+		return nil
+	}
+	return g.Sandbox
+}
+
+// ContainerLimitsFor returns the limits of the containers run for the
+// library with the given ID: the default limits, overridden by those of the
+// library. It returns nil if no limits are configured.
+func (g *LibrarianConfig) ContainerLimitsFor(libraryID string) *ResourceLimits {
+	if g == nil || g.ContainerLimits == nil {
+		return nil
+	}
+	limits := g.ContainerLimits.ResourceLimits
+	for _, library := range g.ContainerLimits.Libraries {
+		if libraryID != "" && library.ID == libraryID {
+			limits.CPUs = cmp.Or(library.CPUs, limits.CPUs)
+			limits.Memory = cmp.Or(library.Memory, limits.Memory)
+			limits.Timeout = cmp.Or(library.Timeout, limits.Timeout)
+		}
+	}
+	return &limits
+}
+
+// validate checks the line endings and regular expressions of normalization.
+func (n *Normalization) validate() error {
+	switch n.LineEndings {
+	case "", LineEndingsLF:
+	default:
+		return fmt.Errorf("invalid normalization line endings: %q", n.LineEndings)
+	}
+	for _, pattern := range n.StripPatterns {
+		if _, err := regexp.Compile(pattern); err != nil || pattern == "" {
+			return fmt.Errorf("invalid normalization strip pattern: %q", pattern)
+		}
+	}
+	for _, path := range n.SortLines {
+		if _, err := regexp.Compile(path); err != nil || path == "" {
+			return fmt.Errorf("invalid normalization sort lines path: %q", path)
+		}
+	}
+	return nil
+}
+
+// validate checks the method of artifact signing, and its key.
+func (s *ArtifactSigning) validate() error {
+	switch s.Method {
+	case ArtifactSigningGPG:
+		if !envNameRegex.MatchString(s.KeySecretEnv) {
+			return fmt.Errorf("invalid key_secret_env of gpg artifact signing: %q", s.KeySecretEnv)
+		}
+		if s.PassphraseSecretEnv != "" && !envNameRegex.MatchString(s.PassphraseSecretEnv) {
+			return fmt.Errorf("invalid passphrase_secret_env of gpg artifact signing: %q", s.PassphraseSecretEnv)
+		}
+		if s.KMSKey != "" {
+			return errors.New("gpg artifact signing sets kms_key")
+		}
+	case ArtifactSigningKMS:
+		if !kms.IsKeyVersion(s.KMSKey) {
+			return fmt.Errorf("invalid kms_key of kms artifact signing, want projects/{project}/locations/{location}/keyRings/{ring}/cryptoKeys/{key}/cryptoKeyVersions/{version}: %q", s.KMSKey)
+		}
+		if s.KeySecretEnv != "" || s.PassphraseSecretEnv != "" {
+			return errors.New("kms artifact signing sets key_secret_env or passphrase_secret_env")
+		}
+	default:
+		return fmt.Errorf("invalid artifact signing method %q, want %q or %q", s.Method, ArtifactSigningGPG, ArtifactSigningKMS)
+	}
 	return nil
 }
+
+// validate checks the labels and reviewers of pull requests.
+func (p *PullRequests) validate() error {
+	if err := validateLabelsAndReviewers("pull requests", p.Labels, p.Reviewers); err != nil {
+		return err
+	}
+	if p.LibraryLabel != "" && !strings.Contains(p.LibraryLabel, "{id}") {
+		return fmt.Errorf("invalid library label of pull requests, want a label containing {id}: %q", p.LibraryLabel)
+	}
+	for _, kind := range p.Kinds {
+		switch kind.Kind {
+		case PullRequestKindGenerate, PullRequestKindRelease:
+		default:
+			return fmt.Errorf("invalid pull request kind: %q", kind.Kind)
+		}
+		if err := validateLabelsAndReviewers(kind.Kind+" pull requests", kind.Labels, kind.Reviewers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateLabelsAndReviewers(what string, labels, reviewers []string) error {
+	for _, label := range labels {
+		if strings.TrimSpace(label) == "" {
+			return fmt.Errorf("empty label of %s", what)
+		}
+	}
+	for _, reviewer := range reviewers {
+		if reviewer == "" || strings.ContainsAny(reviewer, " \t,") {
+			return fmt.Errorf("invalid reviewer of %s: %q", what, reviewer)
+		}
+	}
+	return nil
+}
+
+// PullRequestLabels returns the labels of a pull request of the given kind
+// which changes the libraries with the given IDs, without duplicates.
+func (g *LibrarianConfig) PullRequestLabels(kind string, libraryIDs []string) []string {
+	if g == nil || g.PullRequests == nil {
+		return nil
+	}
+	labels := slices.Clone(g.PullRequests.Labels)
+	for _, k := range g.PullRequests.Kinds {
+		if k.Kind == kind {
+			labels = append(labels, k.Labels...)
+		}
+	}
+	if g.PullRequests.LibraryLabel != "" {
+		for _, id := range libraryIDs {
+			labels = append(labels, strings.ReplaceAll(g.PullRequests.LibraryLabel, "{id}", id))
+		}
+	}
+	return compactUnsorted(labels)
+}
+
+// PullRequestReviewers returns the reviewers of a pull request of the given
+// kind, without duplicates.
+func (g *LibrarianConfig) PullRequestReviewers(kind string) []string {
+	if g == nil || g.PullRequests == nil {
+		return nil
+	}
+	reviewers := slices.Clone(g.PullRequests.Reviewers)
+	for _, k := range g.PullRequests.Kinds {
+		if k.Kind == kind {
+			reviewers = append(reviewers, k.Reviewers...)
+		}
+	}
+	return compactUnsorted(reviewers)
+}
+
+// compactUnsorted removes the duplicates of values, keeping the first
+// occurrence of each value in place.
+func compactUnsorted(values []string) []string {
+	var compacted []string
+	for _, value := range values {
+		if !slices.Contains(compacted, value) {
+			compacted = append(compacted, value)
+		}
+	}
+	return compacted
+}
+
+// ContainerImages returns the images of the declared containers by the
+// container commands they run. It returns nil if no container is declared.
+func (g *LibrarianConfig) ContainerImages() map[string]string {
+	if g == nil || len(g.Containers) == 0 {
+		return nil
+	}
+	images := make(map[string]string)
+	for _, container := range g.Containers {
+		for _, command := range container.Commands {
+			images[command] = container.Image
+		}
+	}
+	return images
+}
+
+// EnvironmentFor returns the environment variables to inject into the
+// container when running command for the library with the given ID. An empty
+// libraryID only matches variables which are not limited to libraries.
+func (g *LibrarianConfig) EnvironmentFor(command, libraryID string) []*EnvironmentVariable {
+	if g == nil {
+		return nil
+	}
+	var env []*EnvironmentVariable
+	for _, variable := range g.Environment {
+		if len(variable.Commands) > 0 && !slices.Contains(variable.Commands, command) {
+			continue
+		}
+		if len(variable.Libraries) > 0 && !slices.Contains(variable.Libraries, libraryID) {
+			continue
+		}
+		env = append(env, variable)
+	}
+	return env
+}
+
+// Overlay returns a new config with the entries of overlay applied on top of g.
+// Entries of overlay replace the entries of g with the same path, in place; the
+// remaining entries of overlay are appended. Environment variables are matched
+// by their name, commands and libraries instead of a path. The sandbox,
+// container limits, protected files, release policy, CI triggers, Gerrit
+// config, API snapshot, commit grouping, pull requests, source attribution,
+// normalization, no-op detection, canary channel and Kubernetes config of
+// overlay, if any, replace those of g. Containers are matched by their name,
+// bug trackers by their prefix, and mirrors and build variants by their name.
+// The API source dependencies are merged. Extends is not copied, as the result
+// is fully resolved.
+func (g *LibrarianConfig) Overlay(overlay *LibrarianConfig) *LibrarianConfig {
+	return &LibrarianConfig{
+		GlobalFilesAllowlist: overlayByPath(g.GlobalFilesAllowlist, overlay.GlobalFilesAllowlist,
+			func(f *GlobalFile) string { return f.Path }),
+		VersionFiles: overlayByPath(g.VersionFiles, overlay.VersionFiles,
+			func(f *VersionFile) string { return f.Path }),
+		Environment: overlayByPath(g.Environment, overlay.Environment,
+			func(e *EnvironmentVariable) string {
+				return strings.Join([]string{e.Name, strings.Join(e.Commands, ","), strings.Join(e.Libraries, ",")}, "|")
+			}),
+		Sandbox:           cmp.Or(overlay.Sandbox, g.Sandbox),
+		ContainerLimits:   cmp.Or(overlay.ContainerLimits, g.ContainerLimits),
+		ProtectedFiles:    cmp.Or(overlay.ProtectedFiles, g.ProtectedFiles),
+		ReleasePolicy:     cmp.Or(overlay.ReleasePolicy, g.ReleasePolicy),
+		CITriggers:        cmp.Or(overlay.CITriggers, g.CITriggers),
+		Gerrit:            cmp.Or(overlay.Gerrit, g.Gerrit),
+		APISnapshot:       cmp.Or(overlay.APISnapshot, g.APISnapshot),
+		CommitGrouping:    cmp.Or(overlay.CommitGrouping, g.CommitGrouping),
+		PullRequests:      cmp.Or(overlay.PullRequests, g.PullRequests),
+		SourceAttribution: cmp.Or(overlay.SourceAttribution, g.SourceAttribution),
+		Normalization:     cmp.Or(overlay.Normalization, g.Normalization),
+		NoOp:              cmp.Or(overlay.NoOp, g.NoOp),
+		Canary:            cmp.Or(overlay.Canary, g.Canary),
+		Kubernetes:        cmp.Or(overlay.Kubernetes, g.Kubernetes),
+		ConflictResolution: overlayByPath(g.ConflictResolution, overlay.ConflictResolution,
+			func(r *ConflictResolution) string { return r.Path }),
+		Containers: overlayByPath(g.Containers, overlay.Containers,
+			func(c *Container) string { return c.Name }),
+		BugTrackers: overlayByPath(g.BugTrackers, overlay.BugTrackers,
+			func(t *BugTracker) string { return t.Prefix }),
+		Mirrors: overlayByPath(g.Mirrors, overlay.Mirrors,
+			func(m *Mirror) string { return m.Name }),
+		BuildMatrix: overlayByPath(g.BuildMatrix, overlay.BuildMatrix,
+			func(v *BuildVariant) string { return v.Name }),
+		LibrarianVersion:   cmp.Or(overlay.LibrarianVersion, g.LibrarianVersion),
+		RegenerationMarker: cmp.Or(overlay.RegenerationMarker, g.RegenerationMarker),
+		APISourceDependencies: overlayByPath(g.APISourceDependencies, overlay.APISourceDependencies,
+			func(dependency string) string { return dependency }),
+		ArtifactSigning: cmp.Or(overlay.ArtifactSigning, g.ArtifactSigning),
+	}
+}
+
+func overlayByPath[T any](base, overlay []T, path func(T) string) []T {
+	if len(base) == 0 && len(overlay) == 0 {
+		return nil
+	}
+	merged := slices.Clone(base)
+	index := make(map[string]int, len(merged))
+	for i, entry := range merged {
+		index[path(entry)] = i
+	}
+	for _, entry := range overlay {
+		if i, ok := index[path(entry)]; ok {
+			merged[i] = entry
+			continue
+		}
+		index[path(entry)] = len(merged)
+		merged = append(merged, entry)
+	}
+	return merged
+}
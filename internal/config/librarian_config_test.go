@@ -17,6 +17,9 @@ package config
 import (
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestGlobalConfig_Validate(t *testing.T) {
@@ -79,24 +82,1393 @@ func TestGlobalConfig_Validate(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "invalid global file permissions",
 		},
-	} {
-		t.Run(test.name, func(t *testing.T) {
-			err := test.config.Validate()
-			if test.wantErr {
-				if err == nil {
-					t.Errorf("GlobalConfig.Validate() should return error")
-				}
-
-				if !strings.Contains(err.Error(), test.wantErrMsg) {
-					t.Errorf("GlobalConfig.Validate() err = %v, want error containing %q", err, test.wantErrMsg)
-				}
-
-				return
-			}
-
-			if err != nil {
-				t.Errorf("GlobalConfig.Validate() error = %v, wantErr %v", err, test.wantErr)
-			}
-		})
+		{
+			name: "valid version files",
+			config: &LibrarianConfig{
+				VersionFiles: []*VersionFile{
+					{Path: "pom.xml", Kind: "pom-xml"},
+					{Path: "version.txt", Kind: "generic", Pattern: "(?P<version>.*)"},
+				},
+			},
+		},
+		{
+			name: "invalid version file path",
+			config: &LibrarianConfig{
+				VersionFiles: []*VersionFile{
+					{Path: "../pom.xml", Kind: "pom-xml"},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid version file path",
+		},
+		{
+			name: "invalid version file kind",
+			config: &LibrarianConfig{
+				VersionFiles: []*VersionFile{
+					{Path: "Cargo.toml", Kind: "cargo-toml"},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid version file at index 0",
+		},
+		{
+			name: "valid environment",
+			config: &LibrarianConfig{
+				Environment: []*EnvironmentVariable{
+					{Name: "GOFLAGS", Value: "-mod=mod", Commands: []string{"generate", "build"}},
+					{Name: "TOKEN", SecretEnv: "LIBRARIAN_TOKEN", Libraries: []string{"a"}},
+				},
+			},
+		},
+		{
+			name: "invalid environment variable name",
+			config: &LibrarianConfig{
+				Environment: []*EnvironmentVariable{
+					{Name: "NOT-VALID", Value: "x"},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid environment variable name",
+		},
+		{
+			name: "environment variable with value and secret",
+			config: &LibrarianConfig{
+				Environment: []*EnvironmentVariable{
+					{Name: "TOKEN", Value: "x", SecretEnv: "LIBRARIAN_TOKEN"},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "sets both value and secret_env",
+		},
+		{
+			name: "environment variable with invalid command",
+			config: &LibrarianConfig{
+				Environment: []*EnvironmentVariable{
+					{Name: "TOKEN", Value: "x", Commands: []string{"publish"}},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid command of environment variable",
+		},
+		{
+			name: "valid sandbox",
+			config: &LibrarianConfig{
+				Sandbox: &ContainerSandbox{
+					Commands: []string{"generate"},
+					Network:  "none",
+					ReadOnly: true,
+					Tmpfs:    []string{"/tmp"},
+				},
+			},
+		},
+		{
+			name: "sandbox with invalid command",
+			config: &LibrarianConfig{
+				Sandbox: &ContainerSandbox{Commands: []string{"publish"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid sandbox command",
+		},
+		{
+			name: "sandbox with unsupported network",
+			config: &LibrarianConfig{
+				Sandbox: &ContainerSandbox{Network: "host"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid sandbox network",
+		},
+		{
+			name: "sandbox with relative tmpfs",
+			config: &LibrarianConfig{
+				Sandbox: &ContainerSandbox{Tmpfs: []string{"tmp"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid sandbox tmpfs path",
+		},
+		{
+			name:   "valid librarian version",
+			config: &LibrarianConfig{LibrarianVersion: "v0.5.0"},
+		},
+		{
+			name:       "invalid librarian version",
+			config:     &LibrarianConfig{LibrarianVersion: "latest"},
+			wantErr:    true,
+			wantErrMsg: "invalid librarian_version",
+		},
+		{
+			name: "valid protected files",
+			config: &LibrarianConfig{
+				ProtectedFiles: &ProtectedFiles{
+					OnChange: ProtectedFilesQuarantine,
+					Libraries: []*LibraryProtectedFiles{
+						{ID: "a", Paths: []string{"a/handwritten.go", "a/docs"}},
+					},
+				},
+			},
+		},
+		{
+			name: "protected files with invalid action",
+			config: &LibrarianConfig{
+				ProtectedFiles: &ProtectedFiles{OnChange: "ignore"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid protected files on_change",
+		},
+		{
+			name: "protected files without library id",
+			config: &LibrarianConfig{
+				ProtectedFiles: &ProtectedFiles{
+					Libraries: []*LibraryProtectedFiles{{Paths: []string{"a"}}},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "have no library id",
+		},
+		{
+			name: "protected files with invalid path",
+			config: &LibrarianConfig{
+				ProtectedFiles: &ProtectedFiles{
+					Libraries: []*LibraryProtectedFiles{{ID: "a", Paths: []string{"../a"}}},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid protected path",
+		},
+		{
+			name: "valid conflict resolution",
+			config: &LibrarianConfig{
+				ConflictResolution: []*ConflictResolution{
+					{Path: `README\.md$`, Strategy: ConflictPreferManual},
+					{Path: ".*", Strategy: ConflictThreeWayMerge},
+				},
+			},
+		},
+		{
+			name: "conflict resolution with invalid path",
+			config: &LibrarianConfig{
+				ConflictResolution: []*ConflictResolution{{Path: "a(", Strategy: ConflictFail}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid conflict resolution path",
+		},
+		{
+			name: "conflict resolution with invalid strategy",
+			config: &LibrarianConfig{
+				ConflictResolution: []*ConflictResolution{{Path: ".*", Strategy: "overwrite"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid conflict resolution strategy",
+		},
+		{
+			name: "valid ci triggers",
+			config: &LibrarianConfig{
+				CITriggers: &CITriggers{
+					Provider: CIProviderGitHubActions,
+					Workflows: []*CIWorkflow{
+						{Name: CIWorkflowGenerate, Schedule: "0 2 * * *"},
+						{Name: CIWorkflowReleaseInit, Disabled: true},
+					},
+				},
+			},
+		},
+		{
+			name: "ci triggers with invalid provider",
+			config: &LibrarianConfig{
+				CITriggers: &CITriggers{Provider: "jenkins"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid ci triggers provider",
+		},
+		{
+			name: "cloud build ci triggers without image",
+			config: &LibrarianConfig{
+				CITriggers: &CITriggers{Provider: CIProviderCloudBuild},
+			},
+			wantErr:    true,
+			wantErrMsg: "require an image",
+		},
+		{
+			name:   "valid api snapshot",
+			config: &LibrarianConfig{APISnapshot: &APISnapshot{Path: "third_party/googleapis"}},
+		},
+		{
+			name:       "invalid api snapshot path",
+			config:     &LibrarianConfig{APISnapshot: &APISnapshot{Path: "../googleapis"}},
+			wantErr:    true,
+			wantErrMsg: "invalid api snapshot path",
+		},
+		{
+			name:   "valid regeneration marker",
+			config: &LibrarianConfig{RegenerationMarker: "{source_root}/.librarian-regeneration.yaml"},
+		},
+		{
+			name:       "invalid regeneration marker",
+			config:     &LibrarianConfig{RegenerationMarker: "../{id}.yaml"},
+			wantErr:    true,
+			wantErrMsg: "invalid regeneration marker path",
+		},
+		{
+			name:   "valid api source dependencies",
+			config: &LibrarianConfig{APISourceDependencies: []string{"google/geo/type", "grafeas/v1"}},
+		},
+		{
+			name:       "invalid api source dependency",
+			config:     &LibrarianConfig{APISourceDependencies: []string{"google/geo/type", "/google/api"}},
+			wantErr:    true,
+			wantErrMsg: "invalid api source dependency at index 1",
+		},
+		{
+			name:   "valid gpg artifact signing",
+			config: &LibrarianConfig{ArtifactSigning: &ArtifactSigning{Method: ArtifactSigningGPG, KeySecretEnv: "SIGNING_KEY", PassphraseSecretEnv: "SIGNING_PASSPHRASE"}},
+		},
+		{
+			name:   "valid kms artifact signing",
+			config: &LibrarianConfig{ArtifactSigning: &ArtifactSigning{Method: ArtifactSigningKMS, KMSKey: "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1"}},
+		},
+		{
+			name:       "gpg artifact signing without key",
+			config:     &LibrarianConfig{ArtifactSigning: &ArtifactSigning{Method: ArtifactSigningGPG}},
+			wantErr:    true,
+			wantErrMsg: "invalid key_secret_env of gpg artifact signing",
+		},
+		{
+			name:       "invalid kms key",
+			config:     &LibrarianConfig{ArtifactSigning: &ArtifactSigning{Method: ArtifactSigningKMS, KMSKey: "projects/p/locations/global/keyRings/r/cryptoKeys/k"}},
+			wantErr:    true,
+			wantErrMsg: "invalid kms_key of kms artifact signing",
+		},
+		{
+			name:       "kms artifact signing with gpg key",
+			config:     &LibrarianConfig{ArtifactSigning: &ArtifactSigning{Method: ArtifactSigningKMS, KMSKey: "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1", KeySecretEnv: "SIGNING_KEY"}},
+			wantErr:    true,
+			wantErrMsg: "kms artifact signing sets key_secret_env",
+		},
+		{
+			name:       "invalid artifact signing method",
+			config:     &LibrarianConfig{ArtifactSigning: &ArtifactSigning{Method: "cosign"}},
+			wantErr:    true,
+			wantErrMsg: "invalid artifact signing method",
+		},
+		{
+			name:   "valid commit grouping",
+			config: &LibrarianConfig{CommitGrouping: CommitGroupingLibrary},
+		},
+		{
+			name:       "invalid commit grouping",
+			config:     &LibrarianConfig{CommitGrouping: "file"},
+			wantErr:    true,
+			wantErrMsg: "invalid commit grouping",
+		},
+		{
+			name: "valid container limits",
+			config: &LibrarianConfig{
+				ContainerLimits: &ContainerLimits{
+					ResourceLimits: ResourceLimits{CPUs: "1.5", Memory: "512m", Timeout: "30m"},
+					Libraries:      []*LibraryContainerLimits{{ID: "a", ResourceLimits: ResourceLimits{Memory: "8g"}}},
+				},
+			},
+		},
+		{
+			name: "container limits with invalid cpus",
+			config: &LibrarianConfig{
+				ContainerLimits: &ContainerLimits{ResourceLimits: ResourceLimits{CPUs: "0"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid cpus of container limits",
+		},
+		{
+			name: "container limits with invalid memory",
+			config: &LibrarianConfig{
+				ContainerLimits: &ContainerLimits{
+					Libraries: []*LibraryContainerLimits{{ID: "a", ResourceLimits: ResourceLimits{Memory: "8 GB"}}},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid memory of container limits of a",
+		},
+		{
+			name: "container limits with invalid timeout",
+			config: &LibrarianConfig{
+				ContainerLimits: &ContainerLimits{ResourceLimits: ResourceLimits{Timeout: "forever"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid timeout of container limits",
+		},
+		{
+			name: "container limits of a library without id",
+			config: &LibrarianConfig{
+				ContainerLimits: &ContainerLimits{Libraries: []*LibraryContainerLimits{{}}},
+			},
+			wantErr:    true,
+			wantErrMsg: "require an id",
+		},
+		{
+			name: "valid containers",
+			config: &LibrarianConfig{
+				Containers: []*Container{
+					{Name: "builder", Image: "example.com/builder:1.0", Commands: []string{"build", "test"}},
+					{Name: "publisher", Image: "example.com/publisher@sha256:0123", Commands: []string{"release-init"}},
+				},
+			},
+		},
+		{
+			name: "container without name",
+			config: &LibrarianConfig{
+				Containers: []*Container{{Image: "example.com/builder:1.0", Commands: []string{"build"}}},
+			},
+			wantErr:    true,
+			wantErrMsg: "requires a name",
+		},
+		{
+			name: "duplicate container",
+			config: &LibrarianConfig{
+				Containers: []*Container{
+					{Name: "builder", Image: "example.com/builder:1.0", Commands: []string{"build"}},
+					{Name: "builder", Image: "example.com/builder:1.0", Commands: []string{"test"}},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "duplicate container",
+		},
+		{
+			name: "container without image",
+			config: &LibrarianConfig{
+				Containers: []*Container{{Name: "builder", Commands: []string{"build"}}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid image of container builder",
+		},
+		{
+			name: "container with invalid command",
+			config: &LibrarianConfig{
+				Containers: []*Container{{Name: "builder", Image: "example.com/builder:1.0", Commands: []string{"publish"}}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid command of container builder",
+		},
+		{
+			name: "command in two containers",
+			config: &LibrarianConfig{
+				Containers: []*Container{
+					{Name: "builder", Image: "example.com/builder:1.0", Commands: []string{"build"}},
+					{Name: "tester", Image: "example.com/tester:1.0", Commands: []string{"test", "build"}},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: `command "build" is declared by containers builder and tester`,
+		},
+		{
+			name: "valid no-op",
+			config: &LibrarianConfig{
+				NoOp: &NoOp{Ignore: []string{`/gapic_metadata\.json$`}, MinFiles: 2},
+			},
+		},
+		{
+			name: "no-op with invalid ignore path",
+			config: &LibrarianConfig{
+				NoOp: &NoOp{Ignore: []string{"["}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid no-op ignore path",
+		},
+		{
+			name: "no-op with negative min files",
+			config: &LibrarianConfig{
+				NoOp: &NoOp{MinFiles: -1},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid no-op min files",
+		},
+		{
+			name: "valid canary",
+			config: &LibrarianConfig{
+				Canary: &Canary{Prerelease: "rc", TagFormat: "canary/{id}/v{version}"},
+			},
+		},
+		{
+			name: "canary with numeric prerelease",
+			config: &LibrarianConfig{
+				Canary: &Canary{Prerelease: "1"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid canary prerelease",
+		},
+		{
+			name: "canary tag format without version",
+			config: &LibrarianConfig{
+				Canary: &Canary{TagFormat: "{id}-canary"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid canary tag_format",
+		},
+		{
+			name: "valid source attribution",
+			config: &LibrarianConfig{
+				SourceAttribution: &SourceAttribution{CoAuthors: true, CommitURL: "https://github.com/googleapis/googleapis/commit/{hash}"},
+			},
+		},
+		{
+			name: "source attribution commit url without hash",
+			config: &LibrarianConfig{
+				SourceAttribution: &SourceAttribution{CommitURL: "https://github.com/googleapis/googleapis/commit"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid source attribution commit_url",
+		},
+		{
+			name: "valid bug trackers",
+			config: &LibrarianConfig{
+				BugTrackers: []*BugTracker{{Prefix: "b/", URL: "https://issuetracker.google.com/issues/{id}"}},
+			},
+		},
+		{
+			name: "bug tracker without prefix",
+			config: &LibrarianConfig{
+				BugTrackers: []*BugTracker{{URL: "https://issuetracker.google.com/issues/{id}"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "requires a prefix",
+		},
+		{
+			name: "bug tracker url without id",
+			config: &LibrarianConfig{
+				BugTrackers: []*BugTracker{{Prefix: "b/", URL: "https://issuetracker.google.com/issues"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid url of bug tracker",
+		},
+		{
+			name: "valid mirrors",
+			config: &LibrarianConfig{
+				Mirrors: []*Mirror{
+					{Name: "gitlab", URL: "https://gitlab.example.com/repo.git", Push: []string{MirrorTags}, PasswordEnv: "GITLAB_TOKEN"},
+					{Name: "backup", URL: "git@example.com:repo.git", Branch: "release"},
+				},
+			},
+		},
+		{
+			name: "mirror without name",
+			config: &LibrarianConfig{
+				Mirrors: []*Mirror{{URL: "https://gitlab.example.com/repo.git"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "mirror at index 0 requires a name",
+		},
+		{
+			name: "duplicate mirror",
+			config: &LibrarianConfig{
+				Mirrors: []*Mirror{
+					{Name: "gitlab", URL: "https://gitlab.example.com/a.git"},
+					{Name: "gitlab", URL: "https://gitlab.example.com/b.git"},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "duplicate mirror",
+		},
+		{
+			name: "mirror without url",
+			config: &LibrarianConfig{
+				Mirrors: []*Mirror{{Name: "gitlab"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid url of mirror gitlab",
+		},
+		{
+			name: "invalid mirror push",
+			config: &LibrarianConfig{
+				Mirrors: []*Mirror{{Name: "gitlab", URL: "https://gitlab.example.com/repo.git", Push: []string{"branches"}}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid push of mirror gitlab",
+		},
+		{
+			name: "invalid mirror password env",
+			config: &LibrarianConfig{
+				Mirrors: []*Mirror{{Name: "gitlab", URL: "https://gitlab.example.com/repo.git", PasswordEnv: "$TOKEN"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid password_env of mirror gitlab",
+		},
+		{
+			name: "valid build matrix",
+			config: &LibrarianConfig{
+				BuildMatrix: []*BuildVariant{
+					{Name: "java8", Environment: []*EnvironmentVariable{{Name: "JAVA_VERSION", Value: "8"}}},
+					{Name: "graalvm", Libraries: []string{"a"}, Environment: []*EnvironmentVariable{{Name: "NATIVE", Value: "true", Commands: []string{"test"}}}},
+				},
+			},
+		},
+		{
+			name: "build variant without name",
+			config: &LibrarianConfig{
+				BuildMatrix: []*BuildVariant{{}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid name of build variant at index 0",
+		},
+		{
+			name: "duplicate build variant",
+			config: &LibrarianConfig{
+				BuildMatrix: []*BuildVariant{{Name: "java8"}, {Name: "java8"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "duplicate build variant",
+		},
+		{
+			name: "build variant overriding its name variable",
+			config: &LibrarianConfig{
+				BuildMatrix: []*BuildVariant{{Name: "java8", Environment: []*EnvironmentVariable{{Name: BuildVariantEnvVar, Value: "java11"}}}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid environment variable name of build variant java8",
+		},
+		{
+			name: "build variant variable of generate",
+			config: &LibrarianConfig{
+				BuildMatrix: []*BuildVariant{{Name: "java8", Environment: []*EnvironmentVariable{{Name: "JAVA_VERSION", Value: "8", Commands: []string{"generate"}}}}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid command of environment variable JAVA_VERSION of build variant java8",
+		},
+		{
+			name: "valid normalization",
+			config: &LibrarianConfig{
+				Normalization: &Normalization{
+					LineEndings:   "lf",
+					StripPatterns: []string{`^// Generated on .*\n`},
+					SortLines:     []string{`/files\.txt$`},
+					Permissions:   true,
+				},
+			},
+		},
+		{
+			name: "normalization with invalid line endings",
+			config: &LibrarianConfig{
+				Normalization: &Normalization{LineEndings: "crlf"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid normalization line endings",
+		},
+		{
+			name: "normalization with invalid strip pattern",
+			config: &LibrarianConfig{
+				Normalization: &Normalization{StripPatterns: []string{"("}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid normalization strip pattern",
+		},
+		{
+			name: "normalization with empty sort lines path",
+			config: &LibrarianConfig{
+				Normalization: &Normalization{SortLines: []string{""}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid normalization sort lines path",
+		},
+		{
+			name: "valid pull requests",
+			config: &LibrarianConfig{
+				PullRequests: &PullRequests{
+					Labels:       []string{"automated"},
+					Reviewers:    []string{"@octocat", "@googleapis/yoshi"},
+					LibraryLabel: "api: {id}",
+					Kinds:        []*PullRequestKind{{Kind: "release", Labels: []string{"release"}}},
+				},
+			},
+		},
+		{
+			name: "pull requests with empty label",
+			config: &LibrarianConfig{
+				PullRequests: &PullRequests{Labels: []string{" "}},
+			},
+			wantErr:    true,
+			wantErrMsg: "empty label of pull requests",
+		},
+		{
+			name: "pull requests with invalid reviewer",
+			config: &LibrarianConfig{
+				PullRequests: &PullRequests{Reviewers: []string{"@a, @b"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid reviewer of pull requests",
+		},
+		{
+			name: "pull requests with library label without id",
+			config: &LibrarianConfig{
+				PullRequests: &PullRequests{LibraryLabel: "api"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid library label of pull requests",
+		},
+		{
+			name: "pull requests with invalid kind",
+			config: &LibrarianConfig{
+				PullRequests: &PullRequests{Kinds: []*PullRequestKind{{Kind: "publish"}}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid pull request kind",
+		},
+		{
+			name: "valid gerrit",
+			config: &LibrarianConfig{
+				Gerrit: &Gerrit{
+					URL:     "https://example-review.googlesource.com",
+					Project: "example/repo",
+					Topic:   "librarian",
+					Labels:  map[string]int{"Code-Review": 1},
+				},
+			},
+		},
+		{
+			name: "valid kubernetes",
+			config: &LibrarianConfig{
+				Kubernetes: &Kubernetes{
+					Namespace: "librarian",
+					Requests:  &ResourceRequests{CPU: "500m", Memory: "8Gi"},
+					Volume:    &KubernetesVolume{Claim: "workspace", MountPath: "/workspace"},
+				},
+			},
+		},
+		{
+			name: "kubernetes with volume and storage",
+			config: &LibrarianConfig{
+				Kubernetes: &Kubernetes{
+					Volume:  &KubernetesVolume{Claim: "workspace", MountPath: "/workspace"},
+					Storage: "gs://bucket",
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "exactly one of volume and storage",
+		},
+		{
+			name: "kubernetes with relative mount path",
+			config: &LibrarianConfig{
+				Kubernetes: &Kubernetes{Volume: &KubernetesVolume{Claim: "workspace", MountPath: "workspace"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid kubernetes volume mount_path",
+		},
+		{
+			name: "kubernetes with invalid storage",
+			config: &LibrarianConfig{
+				Kubernetes: &Kubernetes{Storage: "s3://bucket"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid kubernetes storage",
+		},
+		{
+			name: "kubernetes with invalid request",
+			config: &LibrarianConfig{
+				Kubernetes: &Kubernetes{Storage: "gs://bucket", Requests: &ResourceRequests{Memory: "8 GB"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid kubernetes memory request",
+		},
+		{
+			name: "gerrit without url",
+			config: &LibrarianConfig{
+				Gerrit: &Gerrit{Project: "example/repo"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid gerrit url",
+		},
+		{
+			name: "gerrit without project",
+			config: &LibrarianConfig{
+				Gerrit: &Gerrit{URL: "https://example-review.googlesource.com"},
+			},
+			wantErr:    true,
+			wantErrMsg: "gerrit requires a project",
+		},
+		{
+			name: "gerrit with invalid topic",
+			config: &LibrarianConfig{
+				Gerrit: &Gerrit{URL: "https://example-review.googlesource.com", Project: "example/repo", Topic: "a,b"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid gerrit topic",
+		},
+		{
+			name: "ci triggers with invalid workflow",
+			config: &LibrarianConfig{
+				CITriggers: &CITriggers{
+					Provider:  CIProviderKokoro,
+					Workflows: []*CIWorkflow{{Name: "publish"}},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid ci workflow",
+		},
+		{
+			name: "ci triggers with invalid schedule",
+			config: &LibrarianConfig{
+				CITriggers: &CITriggers{
+					Provider:  CIProviderKokoro,
+					Workflows: []*CIWorkflow{{Name: CIWorkflowGenerate, Schedule: "daily"}},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid schedule of ci workflow",
+		},
+		{
+			name: "valid release policy",
+			config: &LibrarianConfig{
+				ReleasePolicy: &ReleasePolicy{
+					BlockedDays: []string{"Friday", "saturday"},
+					MinChanges:  2,
+					MinInterval: "24h",
+				},
+			},
+		},
+		{
+			name: "release policy with approval",
+			config: &LibrarianConfig{
+				ReleasePolicy: &ReleasePolicy{
+					RequireApproval: true,
+					Approvers:       []string{"@octocat", "@googleapis/release-team"},
+				},
+			},
+		},
+		{
+			name: "release policy with invalid approver",
+			config: &LibrarianConfig{
+				ReleasePolicy: &ReleasePolicy{Approvers: []string{"octocat"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid release policy approver",
+		},
+		{
+			name: "release policy with invalid day",
+			config: &LibrarianConfig{
+				ReleasePolicy: &ReleasePolicy{BlockedDays: []string{"Fri"}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid release policy blocked day",
+		},
+		{
+			name: "release policy with negative min changes",
+			config: &LibrarianConfig{
+				ReleasePolicy: &ReleasePolicy{MinChanges: -1},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid release policy min_changes",
+		},
+		{
+			name: "release policy with invalid min interval",
+			config: &LibrarianConfig{
+				ReleasePolicy: &ReleasePolicy{MinInterval: "1 day"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid release policy min_interval",
+		},
+		{
+			name: "release policy with freezes",
+			config: &LibrarianConfig{
+				ReleasePolicy: &ReleasePolicy{
+					Freezes: []*ReleaseFreeze{
+						{Start: "2025-12-20", End: "2026-01-05", Reason: "holidays"},
+						{Start: "2025-11-27T17:00:00Z", End: "2025-11-28T17:00:00Z"},
+					},
+					FreezeFeed: "https://example.com/freezes.ics",
+				},
+			},
+		},
+		{
+			name: "release policy with freeze ending before it starts",
+			config: &LibrarianConfig{
+				ReleasePolicy: &ReleasePolicy{Freezes: []*ReleaseFreeze{{Start: "2026-01-05", End: "2025-12-20"}}},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid release policy freeze at index 0",
+		},
+		{
+			name: "release policy with invalid freeze feed",
+			config: &LibrarianConfig{
+				ReleasePolicy: &ReleasePolicy{FreezeFeed: "freezes.ics"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid release policy freeze_feed",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.config.Validate()
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("GlobalConfig.Validate() should return error")
+				}
+
+				if !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Errorf("GlobalConfig.Validate() err = %v, want error containing %q", err, test.wantErrMsg)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Errorf("GlobalConfig.Validate() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestLibrarianConfig_Overlay(t *testing.T) {
+	base := &LibrarianConfig{
+		GlobalFilesAllowlist: []*GlobalFile{
+			{Path: "go.work", Permissions: PermissionReadOnly},
+			{Path: "README.md", Permissions: PermissionWriteOnly},
+		},
+		VersionFiles: []*VersionFile{
+			{Path: "version.go", Kind: "go-version"},
+		},
+		Sandbox:        &ContainerSandbox{Network: "none"},
+		CommitGrouping: CommitGroupingLibrary,
+		ConflictResolution: []*ConflictResolution{
+			{Path: ".*", Strategy: ConflictFail},
+		},
+		BugTrackers: []*BugTracker{
+			{Prefix: "b/", URL: "https://issuetracker.google.com/issues/{id}"},
+		},
+		APISourceDependencies: []string{"google/geo/type"},
+	}
+	overlay := &LibrarianConfig{
+		Extends: "base.yaml",
+		GlobalFilesAllowlist: []*GlobalFile{
+			{Path: "go.work", Permissions: PermissionReadWrite},
+			{Path: "CHANGES.md", Permissions: PermissionReadOnly},
+		},
+		ConflictResolution: []*ConflictResolution{
+			{Path: ".*", Strategy: ConflictThreeWayMerge},
+		},
+		BugTrackers: []*BugTracker{
+			{Prefix: "#", URL: "https://github.com/googleapis/librarian/issues/{id}"},
+		},
+		APISourceDependencies: []string{"grafeas/v1", "google/geo/type"},
+	}
+	want := &LibrarianConfig{
+		GlobalFilesAllowlist: []*GlobalFile{
+			{Path: "go.work", Permissions: PermissionReadWrite},
+			{Path: "README.md", Permissions: PermissionWriteOnly},
+			{Path: "CHANGES.md", Permissions: PermissionReadOnly},
+		},
+		VersionFiles: []*VersionFile{
+			{Path: "version.go", Kind: "go-version"},
+		},
+		Sandbox:        &ContainerSandbox{Network: "none"},
+		CommitGrouping: CommitGroupingLibrary,
+		ConflictResolution: []*ConflictResolution{
+			{Path: ".*", Strategy: ConflictThreeWayMerge},
+		},
+		BugTrackers: []*BugTracker{
+			{Prefix: "b/", URL: "https://issuetracker.google.com/issues/{id}"},
+			{Prefix: "#", URL: "https://github.com/googleapis/librarian/issues/{id}"},
+		},
+		APISourceDependencies: []string{"google/geo/type", "grafeas/v1"},
+	}
+	got := base.Overlay(overlay)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Overlay() mismatch (-want +got):\n%s", diff)
+	}
+	if base.GlobalFilesAllowlist[0].Permissions != PermissionReadOnly {
+		t.Errorf("Overlay() modified the base config")
+	}
+}
+
+func TestLibrarianConfig_EnvironmentFor(t *testing.T) {
+	all := &EnvironmentVariable{Name: "ALL", Value: "1"}
+	generate := &EnvironmentVariable{Name: "GENERATE", Value: "2", Commands: []string{"generate"}}
+	libraryA := &EnvironmentVariable{Name: "LIBRARY_A", Value: "3", Libraries: []string{"a"}}
+	cfg := &LibrarianConfig{
+		Environment: []*EnvironmentVariable{all, generate, libraryA},
+	}
+	for _, test := range []struct {
+		name      string
+		config    *LibrarianConfig
+		command   string
+		libraryID string
+		want      []*EnvironmentVariable
+	}{
+		{
+			name:      "generate library a",
+			config:    cfg,
+			command:   "generate",
+			libraryID: "a",
+			want:      []*EnvironmentVariable{all, generate, libraryA},
+		},
+		{
+			name:      "build library b",
+			config:    cfg,
+			command:   "build",
+			libraryID: "b",
+			want:      []*EnvironmentVariable{all},
+		},
+		{
+			name:    "no library",
+			config:  cfg,
+			command: "release-init",
+			want:    []*EnvironmentVariable{all},
+		},
+		{
+			name:    "nil config",
+			command: "generate",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.config.EnvironmentFor(test.command, test.libraryID)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("EnvironmentFor() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLibrarianConfig_BuildVariantsFor(t *testing.T) {
+	java8 := &BuildVariant{Name: "java8"}
+	graalvm := &BuildVariant{Name: "graalvm", Libraries: []string{"a"}}
+	cfg := &LibrarianConfig{BuildMatrix: []*BuildVariant{java8, graalvm}}
+	if diff := cmp.Diff([]*BuildVariant{java8, graalvm}, cfg.BuildVariantsFor("a")); diff != "" {
+		t.Errorf("BuildVariantsFor(a) mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]*BuildVariant{java8}, cfg.BuildVariantsFor("b")); diff != "" {
+		t.Errorf("BuildVariantsFor(b) mismatch (-want +got):\n%s", diff)
+	}
+	var nilConfig *LibrarianConfig
+	if got := nilConfig.BuildVariantsFor("a"); got != nil {
+		t.Errorf("BuildVariantsFor() of nil config = %v, want nil", got)
+	}
+}
+
+func TestLibrarianConfig_BuildEnvironmentFor(t *testing.T) {
+	all := &EnvironmentVariable{Name: "ALL", Value: "1"}
+	javaHome := &EnvironmentVariable{Name: "JAVA_HOME", Value: "/usr/lib/jvm/default"}
+	cfg := &LibrarianConfig{Environment: []*EnvironmentVariable{all, javaHome}}
+	java8Home := &EnvironmentVariable{Name: "JAVA_HOME", Value: "/usr/lib/jvm/java-8"}
+	native := &EnvironmentVariable{Name: "NATIVE", Value: "true", Commands: []string{"test"}}
+	java8 := &BuildVariant{Name: "java8", Environment: []*EnvironmentVariable{java8Home, native}}
+	for _, test := range []struct {
+		name    string
+		config  *LibrarianConfig
+		command string
+		variant *BuildVariant
+		want    []*EnvironmentVariable
+	}{
+		{
+			name:    "no variant",
+			config:  cfg,
+			command: "build",
+			want:    []*EnvironmentVariable{all, javaHome},
+		},
+		{
+			name:    "build variant",
+			config:  cfg,
+			command: "build",
+			variant: java8,
+			want:    []*EnvironmentVariable{all, {Name: BuildVariantEnvVar, Value: "java8"}, java8Home},
+		},
+		{
+			name:    "test variant",
+			config:  cfg,
+			command: "test",
+			variant: java8,
+			want:    []*EnvironmentVariable{all, {Name: BuildVariantEnvVar, Value: "java8"}, java8Home, native},
+		},
+		{
+			name:    "nil config",
+			command: "build",
+			variant: java8,
+			want:    []*EnvironmentVariable{{Name: BuildVariantEnvVar, Value: "java8"}, java8Home},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.config.BuildEnvironmentFor(test.command, "a", test.variant)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("BuildEnvironmentFor() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+	if diff := cmp.Diff([]*EnvironmentVariable{all, javaHome}, cfg.Environment); diff != "" {
+		t.Errorf("BuildEnvironmentFor() modified the environment (-want +got):\n%s", diff)
+	}
+}
+
+func TestLibrarianConfig_SandboxFor(t *testing.T) {
+	generateOnly := &ContainerSandbox{Commands: []string{"generate"}, Network: "none"}
+	all := &ContainerSandbox{ReadOnly: true}
+	for _, test := range []struct {
+		name    string
+		config  *LibrarianConfig
+		command string
+		want    *ContainerSandbox
+	}{
+		{
+			name:    "matching command",
+			config:  &LibrarianConfig{Sandbox: generateOnly},
+			command: "generate",
+			want:    generateOnly,
+		},
+		{
+			name:    "other command",
+			config:  &LibrarianConfig{Sandbox: generateOnly},
+			command: "build",
+		},
+		{
+			name:    "all commands",
+			config:  &LibrarianConfig{Sandbox: all},
+			command: "release-init",
+			want:    all,
+		},
+		{
+			name:    "no sandbox",
+			config:  &LibrarianConfig{},
+			command: "generate",
+		},
+		{
+			name:    "nil config",
+			command: "generate",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.config.SandboxFor(test.command)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("SandboxFor() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLibrarianConfig_ContainerLimitsFor(t *testing.T) {
+	limits := &ContainerLimits{
+		ResourceLimits: ResourceLimits{CPUs: "2", Memory: "4g", Timeout: "30m"},
+		Libraries: []*LibraryContainerLimits{
+			{ID: "big", ResourceLimits: ResourceLimits{Memory: "16g", Timeout: "2h"}},
+		},
+	}
+	for _, test := range []struct {
+		name      string
+		config    *LibrarianConfig
+		libraryID string
+		want      *ResourceLimits
+	}{
+		{
+			name:      "library override",
+			config:    &LibrarianConfig{ContainerLimits: limits},
+			libraryID: "big",
+			want:      &ResourceLimits{CPUs: "2", Memory: "16g", Timeout: "2h"},
+		},
+		{
+			name:      "defaults",
+			config:    &LibrarianConfig{ContainerLimits: limits},
+			libraryID: "small",
+			want:      &ResourceLimits{CPUs: "2", Memory: "4g", Timeout: "30m"},
+		},
+		{
+			name:   "all libraries",
+			config: &LibrarianConfig{ContainerLimits: limits},
+			want:   &ResourceLimits{CPUs: "2", Memory: "4g", Timeout: "30m"},
+		},
+		{
+			name:      "no limits",
+			config:    &LibrarianConfig{},
+			libraryID: "big",
+		},
+		{
+			name:      "nil config",
+			libraryID: "big",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.config.ContainerLimitsFor(test.libraryID)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("ContainerLimitsFor() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+	if got := limits.Libraries[0].TimeoutDuration(); got != 2*time.Hour {
+		t.Errorf("TimeoutDuration() = %s, want 2h", got)
+	}
+}
+
+func TestLibrarianConfig_ContainerImages(t *testing.T) {
+	lc := &LibrarianConfig{
+		Containers: []*Container{
+			{Name: "builder", Image: "example.com/builder:1.0", Commands: []string{"build", "test"}},
+			{Name: "publisher", Image: "example.com/publisher:1.0", Commands: []string{"release-init"}},
+		},
+	}
+	want := map[string]string{
+		"build":        "example.com/builder:1.0",
+		"test":         "example.com/builder:1.0",
+		"release-init": "example.com/publisher:1.0",
+	}
+	if diff := cmp.Diff(want, lc.ContainerImages()); diff != "" {
+		t.Errorf("ContainerImages() mismatch (-want +got):\n%s", diff)
+	}
+	var nilConfig *LibrarianConfig
+	if got := nilConfig.ContainerImages(); got != nil {
+		t.Errorf("ContainerImages() of nil config = %v, want nil", got)
+	}
+}
+
+func TestLibrarianConfig_RegenerationMarkerPath(t *testing.T) {
+	library := &LibraryState{ID: "lib", SourceRoots: []string{"src/lib", "src/other"}}
+	for _, test := range []struct {
+		name   string
+		config *LibrarianConfig
+		want   string
+	}{
+		{
+			name: "default",
+			want: ".librarian/regenerations/lib.yaml",
+		},
+		{
+			name:   "configured",
+			config: &LibrarianConfig{RegenerationMarker: "{source_root}/.regenerated-{id}"},
+			want:   "src/lib/.regenerated-lib",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.config.RegenerationMarkerPath(library); got != test.want {
+				t.Errorf("RegenerationMarkerPath() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLibrarianConfig_PullRequestLabelsAndReviewers(t *testing.T) {
+	lc := &LibrarianConfig{
+		PullRequests: &PullRequests{
+			Labels:       []string{"automated"},
+			Reviewers:    []string{"@octocat"},
+			LibraryLabel: "api: {id}",
+			Kinds: []*PullRequestKind{
+				{Kind: PullRequestKindRelease, Labels: []string{"release", "automated"}, Reviewers: []string{"@googleapis/releasers", "@octocat"}},
+			},
+		},
+	}
+	if diff := cmp.Diff([]string{"automated", "api: a", "api: b"}, lc.PullRequestLabels(PullRequestKindGenerate, []string{"a", "b"})); diff != "" {
+		t.Errorf("PullRequestLabels() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"automated", "release", "api: a"}, lc.PullRequestLabels(PullRequestKindRelease, []string{"a"})); diff != "" {
+		t.Errorf("PullRequestLabels() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"@octocat"}, lc.PullRequestReviewers(PullRequestKindGenerate)); diff != "" {
+		t.Errorf("PullRequestReviewers() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"@octocat", "@googleapis/releasers"}, lc.PullRequestReviewers(PullRequestKindRelease)); diff != "" {
+		t.Errorf("PullRequestReviewers() mismatch (-want +got):\n%s", diff)
+	}
+	var nilConfig *LibrarianConfig
+	if got := nilConfig.PullRequestLabels(PullRequestKindGenerate, []string{"a"}); got != nil {
+		t.Errorf("PullRequestLabels() of nil config = %v, want nil", got)
+	}
+	if got := nilConfig.PullRequestReviewers(PullRequestKindGenerate); got != nil {
+		t.Errorf("PullRequestReviewers() of nil config = %v, want nil", got)
+	}
+}
+
+func TestLibrarianConfig_IsProtected(t *testing.T) {
+	cfg := &LibrarianConfig{
+		ProtectedFiles: &ProtectedFiles{
+			Libraries: []*LibraryProtectedFiles{
+				{ID: "a", Paths: []string{"a/handwritten.go", "a/docs/"}},
+			},
+		},
+	}
+	for _, test := range []struct {
+		name      string
+		config    *LibrarianConfig
+		libraryID string
+		path      string
+		want      bool
+	}{
+		{
+			name:      "protected file",
+			config:    cfg,
+			libraryID: "a",
+			path:      "a/handwritten.go",
+			want:      true,
+		},
+		{
+			name:      "file in protected directory",
+			config:    cfg,
+			libraryID: "a",
+			path:      "a/docs/README.md",
+			want:      true,
+		},
+		{
+			name:      "file with protected prefix",
+			config:    cfg,
+			libraryID: "a",
+			path:      "a/docs.md",
+		},
+		{
+			name:      "other library",
+			config:    cfg,
+			libraryID: "b",
+			path:      "a/handwritten.go",
+		},
+		{
+			name:      "nil config",
+			libraryID: "a",
+			path:      "a/handwritten.go",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.config.IsProtected(test.libraryID, test.path); got != test.want {
+				t.Errorf("IsProtected() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLibrarianConfig_ConflictStrategy(t *testing.T) {
+	cfg := &LibrarianConfig{
+		ConflictResolution: []*ConflictResolution{
+			{Path: `README\.md$`, Strategy: ConflictPreferManual},
+			{Path: `^a/`, Strategy: ConflictThreeWayMerge},
+		},
+	}
+	for _, test := range []struct {
+		name   string
+		config *LibrarianConfig
+		path   string
+		want   string
+	}{
+		{
+			name:   "first matching rule",
+			config: cfg,
+			path:   "a/README.md",
+			want:   ConflictPreferManual,
+		},
+		{
+			name:   "second matching rule",
+			config: cfg,
+			path:   "a/client.go",
+			want:   ConflictThreeWayMerge,
+		},
+		{
+			name:   "no matching rule",
+			config: cfg,
+			path:   "b/client.go",
+			want:   ConflictPreferGenerated,
+		},
+		{
+			name: "nil config",
+			path: "a/client.go",
+			want: ConflictPreferGenerated,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.config.ConflictStrategy(test.path); got != test.want {
+				t.Errorf("ConflictStrategy() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReleasePolicy_IsBlockedOn(t *testing.T) {
+	friday := time.Date(2025, 1, 3, 12, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		name   string
+		policy *ReleasePolicy
+		t      time.Time
+		want   bool
+	}{
+		{
+			name: "nil policy",
+			t:    friday,
+		},
+		{
+			name:   "blocked day",
+			policy: &ReleasePolicy{BlockedDays: []string{"friday"}},
+			t:      friday,
+			want:   true,
+		},
+		{
+			name:   "other day",
+			policy: &ReleasePolicy{BlockedDays: []string{"Friday"}},
+			t:      friday.Add(24 * time.Hour),
+		},
+		{
+			name:   "day in utc",
+			policy: &ReleasePolicy{BlockedDays: []string{"Friday"}},
+			t:      time.Date(2025, 1, 2, 20, 0, 0, 0, time.FixedZone("PST", -8*60*60)),
+			want:   true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.policy.IsBlockedOn(test.t); got != test.want {
+				t.Errorf("IsBlockedOn() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReleaseFreeze_IsActiveAt(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		freeze *ReleaseFreeze
+		t      time.Time
+		want   bool
+	}{
+		{
+			name:   "first day",
+			freeze: &ReleaseFreeze{Start: "2025-12-20", End: "2026-01-05"},
+			t:      time.Date(2025, 12, 20, 0, 0, 0, 0, time.UTC),
+			want:   true,
+		},
+		{
+			name:   "last day",
+			freeze: &ReleaseFreeze{Start: "2025-12-20", End: "2026-01-05"},
+			t:      time.Date(2026, 1, 5, 23, 59, 0, 0, time.UTC),
+			want:   true,
+		},
+		{
+			name:   "after last day",
+			freeze: &ReleaseFreeze{Start: "2025-12-20", End: "2026-01-05"},
+			t:      time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "times",
+			freeze: &ReleaseFreeze{Start: "2025-11-27T17:00:00Z", End: "2025-11-28T17:00:00Z"},
+			t:      time.Date(2025, 11, 28, 9, 0, 0, 0, time.FixedZone("PST", -8*60*60)),
+		},
+		{
+			name:   "invalid",
+			freeze: &ReleaseFreeze{Start: "2025-12-20"},
+			t:      time.Date(2025, 12, 20, 12, 0, 0, 0, time.UTC),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.freeze.IsActiveAt(test.t); got != test.want {
+				t.Errorf("IsActiveAt() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLibrarianConfig_BugURL(t *testing.T) {
+	t.Parallel()
+	config := &LibrarianConfig{
+		BugTrackers: []*BugTracker{
+			{Prefix: "b/", URL: "https://issuetracker.google.com/issues/{id}"},
+			{Prefix: "#", URL: "https://github.com/googleapis/librarian/issues/{id}"},
+		},
+	}
+	for _, test := range []struct {
+		ref  string
+		want string
+	}{
+		{ref: "b/123", want: "https://issuetracker.google.com/issues/123"},
+		{ref: "#456", want: "https://github.com/googleapis/librarian/issues/456"},
+		{ref: "b/", want: ""},
+		{ref: "JIRA-1", want: ""},
+	} {
+		if got := config.BugURL(test.ref); got != test.want {
+			t.Errorf("BugURL(%q) = %q, want %q", test.ref, got, test.want)
+		}
+	}
+	var nilConfig *LibrarianConfig
+	if got := nilConfig.BugURL("b/123"); got != "" {
+		t.Errorf("BugURL() of nil config = %q, want empty", got)
 	}
 }
@@ -18,7 +18,9 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 )
 
 const (
@@ -53,6 +55,50 @@ func (s *LibrarianState) Validate() error {
 			return fmt.Errorf("invalid library at index %d: %w", i, err)
 		}
 	}
+	return s.validateDependencies()
+}
+
+// validateDependencies checks that the libraries depend on libraries of the
+// state, without cycles.
+func (s *LibrarianState) validateDependencies() error {
+	for _, l := range s.Libraries {
+		for _, id := range l.DependsOn {
+			if s.LibraryByID(id) == nil {
+				return fmt.Errorf("library %q depends on unknown library %q", l.ID, id)
+			}
+		}
+	}
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	marks := make(map[string]int)
+	var path []string
+	var visit func(l *LibraryState) error
+	visit = func(l *LibraryState) error {
+		switch marks[l.ID] {
+		case visiting:
+			cycle := append(path[slices.Index(path, l.ID):], l.ID)
+			return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+		case visited:
+			return nil
+		}
+		marks[l.ID] = visiting
+		path = append(path, l.ID)
+		for _, id := range l.DependsOn {
+			if err := visit(s.LibraryByID(id)); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		marks[l.ID] = visited
+		return nil
+	}
+	for _, l := range s.Libraries {
+		if err := visit(l); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -67,6 +113,23 @@ func (s *LibrarianState) ImageRefAndTag() (ref string, tag string) {
 	return parseImage(s.Image)
 }
 
+// LibraryImage returns the image which runs the commands of library: the
+// image which the library is pinned to, if any, or else the image of the
+// state.
+func (s *LibrarianState) LibraryImage(library *LibraryState) string {
+	if s == nil {
+		return ""
+	}
+	if library == nil || library.Image == "" {
+		return s.Image
+	}
+	if tag, ok := strings.CutPrefix(library.Image, ":"); ok {
+		ref, _ := s.ImageRefAndTag()
+		return ref + ":" + tag
+	}
+	return library.Image
+}
+
 // LibraryByID returns the library with the given ID, or nil if not found.
 func (s *LibrarianState) LibraryByID(id string) *LibraryState {
 	for _, lib := range s.Libraries {
@@ -102,6 +165,11 @@ type LibraryState struct {
 	ID string `yaml:"id" json:"id"`
 	// The last released version of the library, following SemVer.
 	Version string `yaml:"version" json:"version"`
+	// The version of the last canary release of the library since its last
+	// stable release, a SemVer prerelease version such as 1.5.0-beta.3. It is
+	// tracked independently of Version, and cleared when the canary release
+	// is promoted.
+	CanaryVersion string `yaml:"canary_version,omitempty" json:"canary_version,omitempty"`
 	// The commit hash from the API definition repository at which the library was last generated.
 	LastGeneratedCommit string `yaml:"last_generated_commit" json:"last_generated_commit"`
 	// The changes from the language repository since the library was last released.
@@ -125,19 +193,57 @@ type LibraryState struct {
 	// permitted to reference the values configured in the library. If not specified
 	// the assumed format is {id}-{version}. e.g., {id}/v{version}.
 	TagFormat string `yaml:"tag_format,omitempty" json:"tag_format,omitempty"`
+	// A list of GitHub users (e.g., @octocat) or teams (e.g., @googleapis/yoshi)
+	// that own the library. Owners are written to CODEOWNERS for the library's
+	// source roots and are requested to review pull requests that change it.
+	Owners []string `yaml:"owners,omitempty" json:"owners,omitempty"`
+	// The ID of the release the library was last released in. Libraries
+	// released together have the same release ID, even when the release was
+	// split into multiple pull requests.
+	ReleaseID string `yaml:"release_id,omitempty" json:"-"`
+	// The release group of the library. Libraries in the same release group,
+	// e.g. a core library and its extensions, are always released together:
+	// when any of them has changes to release, all of them are released, and
+	// their versions are bumped by the highest change among them.
+	ReleaseGroup string `yaml:"release_group,omitempty" json:"release_group,omitempty"`
+	// The IDs of the other libraries of the repository which the library
+	// depends on. Libraries are released after their dependencies, and when
+	// a dependency is released, the library is released too, so that the
+	// language container updates its manifests to the new version of the
+	// dependency. The dependencies must not form a cycle.
+	DependsOn []string `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	// The tag of the last release of the library, when it does not follow the
+	// tag format anymore because the library was renamed since. The next
+	// release looks up the changes since this tag, and then clears it.
+	PreviousReleaseTag string `yaml:"previous_release_tag,omitempty" json:"-"`
+	// The image which runs the language container commands of the library in
+	// place of the image of the repository, e.g. to keep the library on an
+	// older generator during a migration. It is a full image reference, or a
+	// tag of the image of the repository prefixed with a colon, e.g. ":1.2.0".
+	Image string `yaml:"image,omitempty" json:"-"`
+	// Whether the generation of the library is blocked, e.g. by a bug of the
+	// generator, and why. Blocked libraries are skipped when all libraries are
+	// generated.
+	Blocked *GenerationBlock `yaml:"blocked,omitempty" json:"-"`
 	// Whether including this library in a release.
 	// This field is ignored when writing to state.yaml.
 	ReleaseTriggered bool `yaml:"-" json:"release_triggered,omitempty"`
 	// An error message from the docker response.
 	// This field is ignored when writing to state.yaml.
 	ErrorMessage string `yaml:"-" json:"error,omitempty"`
+	// The dependencies of the library, optionally reported by the generate
+	// and build docker responses. They are recorded in the SBOM of the run.
+	// This field is ignored when writing to state.yaml.
+	Dependencies []*Dependency `yaml:"-" json:"dependencies,omitempty"`
 }
 
 var (
-	libraryIDRegex = regexp.MustCompile(`^[a-zA-Z0-9/._-]+$`)
-	semverRegex    = regexp.MustCompile(`^v?\d+\.\d+\.\d+$`)
-	hexRegex       = regexp.MustCompile("^[a-fA-F0-9]+$")
-	tagFormatRegex = regexp.MustCompile(`{[^{}]*}`)
+	libraryIDRegex  = regexp.MustCompile(`^[a-zA-Z0-9/._-]+$`)
+	semverRegex     = regexp.MustCompile(`^v?\d+\.\d+\.\d+$`)
+	prereleaseRegex = regexp.MustCompile(`^v?\d+\.\d+\.\d+-[0-9A-Za-z.-]+$`)
+	hexRegex        = regexp.MustCompile("^[a-fA-F0-9]+$")
+	tagFormatRegex  = regexp.MustCompile(`{[^{}]*}`)
+	ownerRegex      = regexp.MustCompile(`^@[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:/[a-zA-Z0-9._-]+)?$`)
 )
 
 // Validate checks that the Library is valid.
@@ -154,6 +260,34 @@ func (l *LibraryState) Validate() error {
 	if l.Version != "" && !semverRegex.MatchString(l.Version) {
 		return fmt.Errorf("invalid version: %q", l.Version)
 	}
+	if l.CanaryVersion != "" && !prereleaseRegex.MatchString(l.CanaryVersion) {
+		return fmt.Errorf("invalid canary_version: %q", l.CanaryVersion)
+	}
+	if l.ReleaseGroup != "" && !libraryIDRegex.MatchString(l.ReleaseGroup) {
+		return fmt.Errorf("invalid release_group: %q", l.ReleaseGroup)
+	}
+	for _, id := range l.DependsOn {
+		if id == l.ID {
+			return fmt.Errorf("library %q depends on itself", l.ID)
+		}
+		if !libraryIDRegex.MatchString(id) {
+			return fmt.Errorf("invalid depends_on: %q", id)
+		}
+	}
+	if l.Image != "" {
+		if tag, ok := strings.CutPrefix(l.Image, ":"); ok {
+			if tag == "" || strings.ContainsAny(tag, ":/@ \t\n\r") {
+				return fmt.Errorf("invalid image tag: %q", l.Image)
+			}
+		} else if !isValidImage(l.Image) {
+			return fmt.Errorf("invalid image: %q", l.Image)
+		}
+	}
+	if l.Blocked != nil {
+		if err := l.Blocked.Validate(); err != nil {
+			return fmt.Errorf("invalid blocked: %w", err)
+		}
+	}
 	if l.LastGeneratedCommit != "" {
 		if !hexRegex.MatchString(l.LastGeneratedCommit) {
 			return fmt.Errorf("last_generated_commit must be a hex string")
@@ -191,6 +325,11 @@ func (l *LibraryState) Validate() error {
 			}
 		}
 	}
+	for i, o := range l.Owners {
+		if !ownerRegex.MatchString(o) {
+			return fmt.Errorf("invalid owner at index %d: %q", i, o)
+		}
+	}
 	for i, r := range l.PreserveRegex {
 		if _, err := regexp.Compile(r); err != nil {
 			return fmt.Errorf("invalid preserve_regex at index %d: %w", i, err)
@@ -204,6 +343,29 @@ func (l *LibraryState) Validate() error {
 	return nil
 }
 
+// GenerationBlock records why the generation of a library is blocked.
+type GenerationBlock struct {
+	// Why the generation of the library is blocked.
+	Reason string `yaml:"reason" json:"reason"`
+	// The optional URL or ID of the bug tracking the block.
+	Bug string `yaml:"bug,omitempty" json:"bug,omitempty"`
+	// The date the generation was blocked, in the format "2006-01-02".
+	Since string `yaml:"since,omitempty" json:"since,omitempty"`
+}
+
+// Validate checks that the GenerationBlock is valid.
+func (b *GenerationBlock) Validate() error {
+	if strings.TrimSpace(b.Reason) == "" {
+		return fmt.Errorf("reason is required")
+	}
+	if b.Since != "" {
+		if _, err := time.Parse(time.DateOnly, b.Since); err != nil {
+			return fmt.Errorf("invalid since: %q", b.Since)
+		}
+	}
+	return nil
+}
+
 // API represents an API that is part of a library.
 type API struct {
 	// The path to the API, relative to the root of the API definition repository (e.g., "google/storage/v1").
@@ -223,6 +385,25 @@ func (a *API) Validate() error {
 	return nil
 }
 
+// Dependency is a dependency of a library, as reported by the language
+// container.
+type Dependency struct {
+	// The name of the package, e.g. "google.golang.org/grpc".
+	Name string `json:"name"`
+	// The version of the package.
+	Version string `json:"version"`
+	// The optional package URL, e.g. "pkg:golang/google.golang.org/grpc@v1.2.3".
+	PURL string `json:"purl,omitempty"`
+}
+
+// Bug is a bug referenced by a change.
+type Bug struct {
+	// The reference to the bug, e.g. "b/123", "#456" or "owner/repo#789".
+	ID string `json:"id"`
+	// The URL of the bug, if known.
+	URL string `json:"url,omitempty"`
+}
+
 // Change represents the changelog of a library.
 type Change struct {
 	// The type of the change, should be one of the conventional type.
@@ -235,6 +416,13 @@ type Change struct {
 	ClNum string `yaml:"piper_cl_number" json:"piper_cl_number"`
 	// The commit hash in the source repository associated with this change.
 	CommitHash string `yaml:"source_commit_hash" json:"source_commit_hash"`
+	// The bugs referenced by the commit, e.g. "b/123" or "#456", with their
+	// URLs when they are known, for linking them in the changelog.
+	Bugs []*Bug `yaml:"-" json:"bugs,omitempty"`
+	// Author is the author of the commit in the source repository, formatted
+	// as "Name <email>". It is used to attribute the change, and not passed
+	// to the containers.
+	Author string `yaml:"-" json:"-"`
 }
 
 // invalidPathChars contains characters that are invalid in path components,
@@ -71,6 +71,52 @@ func TestLibrarianState_Validate(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "libraries cannot be empty",
 		},
+		{
+			name: "valid dependencies",
+			state: &LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*LibraryState{
+					{ID: "a", SourceRoots: []string{"a"}, DependsOn: []string{"b", "c"}},
+					{ID: "b", SourceRoots: []string{"b"}, DependsOn: []string{"c"}},
+					{ID: "c", SourceRoots: []string{"c"}},
+				},
+			},
+		},
+		{
+			name: "dependency on unknown library",
+			state: &LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*LibraryState{
+					{ID: "a", SourceRoots: []string{"a"}, DependsOn: []string{"b"}},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: `library "a" depends on unknown library "b"`,
+		},
+		{
+			name: "dependency on itself",
+			state: &LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*LibraryState{
+					{ID: "a", SourceRoots: []string{"a"}, DependsOn: []string{"a"}},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: `library "a" depends on itself`,
+		},
+		{
+			name: "dependency cycle",
+			state: &LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*LibraryState{
+					{ID: "a", SourceRoots: []string{"a"}, DependsOn: []string{"b"}},
+					{ID: "b", SourceRoots: []string{"b"}, DependsOn: []string{"c"}},
+					{ID: "c", SourceRoots: []string{"c"}, DependsOn: []string{"a"}},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "dependency cycle: a -> b -> c -> a",
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			err := test.state.Validate()
@@ -119,6 +165,26 @@ func TestLibrary_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid canary version",
+			library: &LibraryState{
+				ID:            "a/b",
+				Version:       "1.4.2",
+				CanaryVersion: "1.5.0-beta.3",
+				SourceRoots:   []string{"src/a"},
+			},
+		},
+		{
+			name: "canary version without prerelease",
+			library: &LibraryState{
+				ID:            "a/b",
+				Version:       "1.4.2",
+				CanaryVersion: "1.5.0",
+				SourceRoots:   []string{"src/a"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid canary_version",
+		},
 		{
 			name:       "missing id",
 			library:    &LibraryState{},
@@ -175,6 +241,15 @@ func TestLibrary_Validate(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "invalid id",
 		},
+		{
+			name: "invalid release group",
+			library: &LibraryState{
+				ID:           "a/b",
+				ReleaseGroup: "core extensions",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid release_group",
+		},
 		{
 			name: "invalid last generated commit non-hex",
 			library: &LibraryState{
@@ -253,6 +328,26 @@ func TestLibrary_Validate(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "invalid release_exclude_path at index",
 		},
+		{
+			name: "valid owners",
+			library: &LibraryState{
+				ID:          "a/b",
+				SourceRoots: []string{"src/a"},
+				APIs:        []*API{{Path: "a/b/v1"}},
+				Owners:      []string{"@octocat", "@googleapis/yoshi-go"},
+			},
+		},
+		{
+			name: "invalid owner",
+			library: &LibraryState{
+				ID:          "a/b",
+				SourceRoots: []string{"src/a"},
+				APIs:        []*API{{Path: "a/b/v1"}},
+				Owners:      []string{"octocat"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid owner at index",
+		},
 		{
 			name: "valid tag_format",
 			library: &LibraryState{
@@ -284,6 +379,60 @@ func TestLibrary_Validate(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "must contain",
 		},
+		{
+			name: "valid image tag",
+			library: &LibraryState{
+				ID:          "a/b",
+				SourceRoots: []string{"src/a"},
+				Image:       ":1.2.0",
+			},
+		},
+		{
+			name: "invalid image tag",
+			library: &LibraryState{
+				ID:    "a/b",
+				Image: ":",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid image tag",
+		},
+		{
+			name: "invalid image",
+			library: &LibraryState{
+				ID:    "a/b",
+				Image: "gcr.io/test/image",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid image",
+		},
+		{
+			name: "blocked",
+			library: &LibraryState{
+				ID:          "a/b",
+				SourceRoots: []string{"src/a"},
+				Blocked:     &GenerationBlock{Reason: "generator bug", Bug: "b/123", Since: "2025-01-02"},
+			},
+		},
+		{
+			name: "blocked without reason",
+			library: &LibraryState{
+				ID:          "a/b",
+				SourceRoots: []string{"src/a"},
+				Blocked:     &GenerationBlock{Bug: "b/123"},
+			},
+			wantErr:    true,
+			wantErrMsg: "reason is required",
+		},
+		{
+			name: "blocked with invalid since",
+			library: &LibraryState{
+				ID:          "a/b",
+				SourceRoots: []string{"src/a"},
+				Blocked:     &GenerationBlock{Reason: "generator bug", Since: "yesterday"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid since",
+		},
 		{
 			name: "valid tag_format with version only",
 			library: &LibraryState{
@@ -445,3 +594,34 @@ func TestLibraryState_LibraryByID(t *testing.T) {
 		})
 	}
 }
+
+func TestLibrarianState_LibraryImage(t *testing.T) {
+	state := &LibrarianState{Image: "gcr.io/test/image:v2.0.0"}
+	for _, test := range []struct {
+		name    string
+		library *LibraryState
+		want    string
+	}{
+		{
+			name:    "default",
+			library: &LibraryState{ID: "foo"},
+			want:    "gcr.io/test/image:v2.0.0",
+		},
+		{
+			name:    "tag",
+			library: &LibraryState{ID: "foo", Image: ":v1.9.0"},
+			want:    "gcr.io/test/image:v1.9.0",
+		},
+		{
+			name:    "image",
+			library: &LibraryState{ID: "foo", Image: "gcr.io/test/legacy:v1.0.0"},
+			want:    "gcr.io/test/legacy:v1.0.0",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := state.LibraryImage(test.library); got != test.want {
+				t.Errorf("LibraryImage() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -17,7 +17,9 @@ package conventionalcommits
 import (
 	"fmt"
 	"log/slog"
+	"maps"
 	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -40,6 +42,29 @@ type ConventionalCommit struct {
 	IsNested bool
 	// SHA is the full commit hash.
 	SHA string
+	// Author is the author of the commit, formatted as "Name <email>".
+	Author string
+	// Bugs are the bugs referenced in the description, body and footers of
+	// the commit, in order of appearance.
+	Bugs []*BugReference
+}
+
+// BugReference is a reference to a bug in a commit message: "b/123", "#456",
+// or the URL of a GitHub issue or pull request.
+type BugReference struct {
+	// Prefix identifies the bug tracker, e.g. "b/" or "#", or
+	// "owner/repo#" for the URL of a GitHub issue.
+	Prefix string
+	// ID is the ID of the bug in its tracker, e.g. "123".
+	ID string
+	// URL is the URL of the bug, if the reference is one.
+	URL string
+}
+
+// String returns the reference as written in changelogs, e.g. "b/123" or
+// "owner/repo#789".
+func (b *BugReference) String() string {
+	return b.Prefix + b.ID
 }
 
 const breakingChangeKey = "BREAKING CHANGE"
@@ -52,6 +77,36 @@ var commitRegex = regexp.MustCompile(`^(?P<type>\w+)(?:\((?P<scope>.*)\))?(?P<br
 // e.g., "Reviewed-by: G. Gemini" or "BREAKING CHANGE: an API was changed".
 var footerRegex = regexp.MustCompile(`^([A-Za-z-]+|` + breakingChangeKey + `):\s(.*)`)
 
+// bugRegex matches the references to bugs: "b/123" and "#456", which must not
+// be part of a word, a path or an HTML entity, and the URLs of GitHub issues
+// and pull requests.
+var bugRegex = regexp.MustCompile(`(?:^|[^\w/.#&-])(?:(b/)(\d+)|#(\d+))\b|https?://[\w.-]+/([\w.-]+/[\w.-]+)/(?:issues|pull)/(\d+)`)
+
+// parseBugReferences returns the bugs referenced in texts, without
+// duplicates, in order of appearance.
+func parseBugReferences(texts ...string) []*BugReference {
+	var bugs []*BugReference
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		for _, match := range bugRegex.FindAllStringSubmatch(text, -1) {
+			var bug *BugReference
+			switch {
+			case match[1] != "":
+				bug = &BugReference{Prefix: match[1], ID: match[2]}
+			case match[3] != "":
+				bug = &BugReference{Prefix: "#", ID: match[3]}
+			default:
+				bug = &BugReference{Prefix: match[4] + "#", ID: match[5], URL: match[0]}
+			}
+			if !seen[bug.String()] {
+				seen[bug.String()] = true
+				bugs = append(bugs, bug)
+			}
+		}
+	}
+	return bugs
+}
+
 // parsedHeader holds the result of parsing the header line.
 type parsedHeader struct {
 	Type        string
@@ -250,15 +305,22 @@ func parseSimpleCommit(commitPart commitPart, hashString string) (*ConventionalC
 	bodyLines, footerLines := separateBodyAndFooters(lines[1:])
 
 	footers, footerIsBreaking := parseFooters(footerLines)
+	body := strings.TrimSpace(strings.Join(bodyLines, "\n"))
+	footerKeys := slices.Sorted(maps.Keys(footers))
+	bugTexts := []string{header.Description, body}
+	for _, key := range footerKeys {
+		bugTexts = append(bugTexts, footers[key])
+	}
 
 	return &ConventionalCommit{
 		Type:        header.Type,
 		Scope:       header.Scope,
 		Description: header.Description,
-		Body:        strings.TrimSpace(strings.Join(bodyLines, "\n")),
+		Body:        body,
 		Footers:     footers,
 		IsBreaking:  header.IsBreaking || footerIsBreaking,
 		IsNested:    commitPart.isNested,
 		SHA:         hashString,
+		Bugs:        parseBugReferences(bugTexts...),
 	}, nil
 }
@@ -353,6 +353,28 @@ END_NESTED_COMMIT`,
 				},
 			},
 		},
+		{
+			name: "bug references",
+			message: `fix: handle nil client (#456)
+
+Fixes b/123 and https://github.com/googleapis/google-cloud-go/issues/789.
+
+Bug: b/123`,
+			want: []*ConventionalCommit{
+				{
+					Type:        "fix",
+					Description: "handle nil client (#456)",
+					Body:        "Fixes b/123 and https://github.com/googleapis/google-cloud-go/issues/789.",
+					Footers:     map[string]string{"Bug": "b/123"},
+					SHA:         "fake-sha",
+					Bugs: []*BugReference{
+						{Prefix: "#", ID: "456"},
+						{Prefix: "b/", ID: "123"},
+						{Prefix: "googleapis/google-cloud-go#", ID: "789", URL: "https://github.com/googleapis/google-cloud-go/issues/789"},
+					},
+				},
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -438,3 +460,28 @@ fix(sub): fix a bug that is never closed`,
 		})
 	}
 }
+
+func TestParseBugReferences(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "buganizer", text: "b/123", want: []string{"b/123"}},
+		{name: "github issue", text: "fixes #45, #46", want: []string{"#45", "#46"}},
+		{name: "pull request url", text: "see https://github.com/o/r/pull/7", want: []string{"o/r#7"}},
+		{name: "path", text: "moved a/b/123 and lib/b/4", want: nil},
+		{name: "anchor", text: "see README#1 and &#123;", want: nil},
+		{name: "duplicates", text: "#1 b/2 #1", want: []string{"#1", "b/2"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, bug := range parseBugReferences(test.text) {
+				got = append(got, bug.String())
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("parseBugReferences(%q) mismatch (-want +got):\n%s", test.text, diff)
+			}
+		})
+	}
+}
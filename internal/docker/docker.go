@@ -19,16 +19,25 @@
 package docker
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/logging"
 )
 
 // Command is the string representation of a command to be passed to the language-specific
@@ -45,22 +54,124 @@ const (
 	CommandGenerate Command = "generate"
 	// CommandReleaseInit performs release for a library.
 	CommandReleaseInit Command = "release-init"
+	// CommandTest tests a library.
+	CommandTest Command = "test"
 )
 
 // Docker contains all the information required to run language-specific
-// Docker containers.
+// Docker containers. It is safe for concurrent use, e.g. by the libraries
+// generated in parallel.
 type Docker struct {
 	// The Docker image to run.
 	Image string
 
-	// The user ID to run the container as.
+	// Images are the images which run some of the commands in place of
+	// Image, by command. See [config.LibrarianConfig.Containers].
+	Images map[Command]string
+
+	// LibraryImages are the images which run the commands of some libraries
+	// in place of Image and Images, by library ID. See
+	// [config.LibraryState.Image].
+	LibraryImages map[string]string
+
+	// Local determines whether the images are local builds, which are never
+	// pulled. See [config.Config.ImageLocal].
+	Local bool
+
+	// Archive is the path of a tarball of images, as written by "docker
+	// save", which is loaded before the images are used. See
+	// [config.Config.ImageArchive].
+	Archive string
+
+	// Platform is the platform, e.g. "linux/amd64", which the images run
+	// as. If empty, the native platform of this machine is selected for the
+	// images which are available for it. See [config.Config.ImagePlatform].
+	Platform string
+
+	// RequireNative fails the images which would run under emulation, rather
+	// than only warning. See [config.Config.NativeImage].
+	RequireNative bool
+
+	// platforms are the platforms selected for the images by Prewarm, by
+	// image.
+	platforms   map[string]string
+	platformsMu sync.Mutex
+
+	// The ID of the current user, who owns the files written by the
+	// containers to the host.
 	uid string
 
-	// The group ID to run the container as.
+	// The group ID of the current user.
 	gid string
 
-	// run runs the docker command.
-	run func(args ...string) error
+	// userMode is how the files written by the containers are made owned by
+	// the current user. It is detected on the first container run, see
+	// detectUserMode.
+	userMode     string
+	userModeOnce sync.Once
+
+	// mounts are the extra host directories mounted into every container, in
+	// addition to the directories of each command.
+	mounts []*config.ContainerMount
+
+	// run runs the docker command, writing its standard output and error to
+	// stdout and stderr, or to those of the process if nil.
+	run func(stdout, stderr io.Writer, args ...string) error
+
+	// output runs the docker command and returns its standard output.
+	output func(args ...string) ([]byte, error)
+
+	// Kubernetes, if set, runs the containers as Kubernetes Jobs in place of
+	// the local Docker daemon. See [config.Config.ContainerBackend].
+	Kubernetes *config.Kubernetes
+
+	// kubectl runs the kubectl command, reading its standard input from stdin
+	// if not nil, and writing its standard output and error to stdout and
+	// stderr, or to those of the process if nil.
+	kubectl func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error
+
+	// storage syncs the mounted directories with the Jobs through the Cloud
+	// Storage location of Kubernetes. It is created on first use, see
+	// storageClient.
+	storage   objectStorage
+	storageMu sync.Mutex
+
+	// RecordDir is the fixture directory into which container runs are
+	// recorded. See [config.Config.ContainerRecord].
+	RecordDir string
+
+	// ReplayDir is the fixture directory from which container runs are
+	// replayed instead of running containers. See
+	// [config.Config.ContainerReplay].
+	ReplayDir string
+
+	// LogDir is the directory into which the standard output and error of
+	// each container run are written, in separate files. The output is also
+	// logged with slog at LogLevel. If empty, the output is written to the
+	// standard output and error of the process instead.
+	LogDir string
+
+	// LogLevel is the level at which the output of container runs is logged.
+	LogLevel slog.Level
+
+	// DebugShell determines what happens when a container run fails. See
+	// [config.Config.DebugShell].
+	DebugShell string
+
+	// interactive runs the docker command attached to the terminal, or is nil
+	// if the standard input is not a terminal.
+	interactive func(args ...string) error
+
+	// debugOutput is where the debug shell instructions are written.
+	debugOutput io.Writer
+
+	// invocations is the number of container runs so far, by library ID.
+	invocations   map[string]int
+	invocationsMu sync.Mutex
+
+	// retryDelays are the delays before running a docker command again
+	// after a failure which is safe to retry. See runWithRetries.
+	retryDelays []time.Duration
 }
 
 // BuildRequest contains all the information required for a language
@@ -69,6 +180,9 @@ type BuildRequest struct {
 	// cfg is a pointer to the [config.Config] struct, holding general configuration
 	// values parsed from flags or environment variables.
 	Cfg *config.Config
+	// LibrarianConfig is a pointer to the [config.LibrarianConfig] struct, holding
+	// the environment variables to inject into the container.
+	LibrarianConfig *config.LibrarianConfig
 	// state is a pointer to the [config.LibrarianState] struct, representing
 	// the overall state of the generation and release pipeline.
 	State *config.LibrarianState
@@ -76,6 +190,9 @@ type BuildRequest struct {
 	LibraryID string
 	// RepoDir is the local root directory of the language repository.
 	RepoDir string
+	// Variant is the variant of the build matrix to build the library for,
+	// or nil if the library has no variants.
+	Variant *config.BuildVariant
 }
 
 // ConfigureRequest contains all the information required for a language
@@ -84,6 +201,9 @@ type ConfigureRequest struct {
 	// cfg is a pointer to the [config.Config] struct, holding general configuration
 	// values parsed from flags or environment variables.
 	Cfg *config.Config
+	// LibrarianConfig is a pointer to the [config.LibrarianConfig] struct, holding
+	// the environment variables to inject into the container.
+	LibrarianConfig *config.LibrarianConfig
 	// state is a pointer to the [config.LibrarianState] struct, representing
 	// the overall state of the generation and release pipeline.
 	State *config.LibrarianState
@@ -101,6 +221,9 @@ type GenerateRequest struct {
 	// cfg is a pointer to the [config.Config] struct, holding general configuration
 	// values parsed from flags or environment variables.
 	Cfg *config.Config
+	// LibrarianConfig is a pointer to the [config.LibrarianConfig] struct, holding
+	// the environment variables to inject into the container.
+	LibrarianConfig *config.LibrarianConfig
 	// state is a pointer to the [config.LibrarianState] struct, representing
 	// the overall state of the generation and release pipeline.
 	State *config.LibrarianState
@@ -115,6 +238,27 @@ type GenerateRequest struct {
 	RepoDir string
 }
 
+// TestRequest contains all the information required for a language
+// container to run the test command.
+type TestRequest struct {
+	// Cfg is a pointer to the [config.Config] struct, holding general configuration
+	// values parsed from flags or environment variables.
+	Cfg *config.Config
+	// LibrarianConfig is a pointer to the [config.LibrarianConfig] struct, holding
+	// the environment variables to inject into the container.
+	LibrarianConfig *config.LibrarianConfig
+	// State is a pointer to the [config.LibrarianState] struct, representing
+	// the overall state of the generation and release pipeline.
+	State *config.LibrarianState
+	// LibraryID specifies the ID of the library to test.
+	LibraryID string
+	// RepoDir is the local root directory of the language repository.
+	RepoDir string
+	// Variant is the variant of the build matrix to test the library for,
+	// or nil if the library has no variants.
+	Variant *config.BuildVariant
+}
+
 // ReleaseInitRequest contains all the information required for a language
 // container to run the  init command.
 type ReleaseInitRequest struct {
@@ -140,18 +284,66 @@ type ReleaseInitRequest struct {
 	PartialRepoDir string
 }
 
+// GeneratorSourceDir is the directory in the container at which the local
+// generator source is mounted. See [config.Config.GeneratorSource].
+const GeneratorSourceDir = "/generator"
+
+// ServiceConfigDir is the directory in the container at which the directory
+// of the service config specified for generation is mounted. See
+// [config.Config.ServiceConfig].
+const ServiceConfigDir = "/service-config"
+
+// reservedMountDirs are the directories in the container at which librarian
+// mounts the directories of the commands.
+var reservedMountDirs = []string{"/input", "/librarian", "/output", "/repo", "/source"}
+
 // New constructs a Docker instance which will invoke the specified
 // Docker image as required to implement language-specific commands,
 // providing the container with required environment variables.
-func New(workRoot, image, uid, gid string) (*Docker, error) {
+//
+// The extra mounts are mounted into every container, and must not overlap with
+// the directories that librarian mounts itself.
+func New(workRoot, image, uid, gid string, mounts []*config.ContainerMount) (*Docker, error) {
+	if err := validateMounts(mounts); err != nil {
+		return nil, err
+	}
 	docker := &Docker{
-		Image: image,
-		uid:   uid,
-		gid:   gid,
+		Image:       image,
+		uid:         uid,
+		gid:         gid,
+		mounts:      mounts,
+		retryDelays: defaultRetryDelays,
+	}
+	if workRoot != "" {
+		docker.LogDir = filepath.Join(workRoot, LogsDir)
+	}
+	docker.run = func(stdout, stderr io.Writer, args ...string) error {
+		return docker.runCommand(stdout, stderr, "docker", args...)
+	}
+	docker.output = func(args ...string) ([]byte, error) {
+		cmd := exec.Command("docker", args...)
+		logging.LogCommand(cmd)
+		return cmd.Output()
 	}
-	docker.run = func(args ...string) error {
-		return docker.runCommand("docker", args...)
+	docker.kubectl = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		cmd := exec.Command("kubectl", args...)
+		cmd.Stdin = stdin
+		cmd.Stdout = cmp.Or[io.Writer](stdout, os.Stdout)
+		cmd.Stderr = cmp.Or[io.Writer](stderr, os.Stderr)
+		logging.LogCommand(cmd)
+		return cmd.Run()
 	}
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		docker.interactive = func(args ...string) error {
+			cmd := exec.Command("docker", args...)
+			cmd.Stdin = os.Stdin
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			logging.LogCommand(cmd)
+			return cmd.Run()
+		}
+	}
+	docker.debugOutput = os.Stderr
 	return docker, nil
 }
 
@@ -185,7 +377,10 @@ func (c *Docker) Generate(ctx context.Context, request *GenerateRequest) error {
 		fmt.Sprintf("%s:/source:ro", request.ApiRoot), // readonly volume
 	}
 
-	return c.runDocker(ctx, request.Cfg, CommandGenerate, mounts, commandArgs)
+	env := request.LibrarianConfig.EnvironmentFor(string(CommandGenerate), request.LibraryID)
+	sandbox := containerSandbox(request.Cfg, request.LibrarianConfig, CommandGenerate)
+	limits := request.LibrarianConfig.ContainerLimitsFor(request.LibraryID)
+	return c.runDocker(ctx, request.Cfg, CommandGenerate, request.LibraryID, mounts, env, sandbox, limits, commandArgs)
 }
 
 // Build builds the library with an ID of libraryID, as configured in
@@ -212,7 +407,41 @@ func (c *Docker) Build(ctx context.Context, request *BuildRequest) error {
 		"--repo=/repo",
 	}
 
-	return c.runDocker(ctx, request.Cfg, CommandBuild, mounts, commandArgs)
+	env := request.LibrarianConfig.BuildEnvironmentFor(string(CommandBuild), request.LibraryID, request.Variant)
+	sandbox := containerSandbox(request.Cfg, request.LibrarianConfig, CommandBuild)
+	limits := request.LibrarianConfig.ContainerLimitsFor(request.LibraryID)
+	return c.runDocker(ctx, request.Cfg, CommandBuild, request.LibraryID, mounts, env, sandbox, limits, commandArgs)
+}
+
+// Test runs the tests, such as integration tests, of the library with an ID
+// of libraryID, as configured in the Librarian state file for the repository
+// with a root of repoRoot.
+func (c *Docker) Test(ctx context.Context, request *TestRequest) error {
+	jsonFilePath := filepath.Join(request.RepoDir, config.LibrarianDir, config.TestRequest)
+	if err := writeLibraryState(request.State, request.LibraryID, jsonFilePath); err != nil {
+		return err
+	}
+	defer func(name string) {
+		err := os.Remove(name)
+		if err != nil {
+			slog.Warn("fail to remove file", slog.String("name", name), slog.Any("err", err))
+		}
+	}(jsonFilePath)
+
+	librarianDir := filepath.Join(request.RepoDir, config.LibrarianDir)
+	mounts := []string{
+		fmt.Sprintf("%s:/librarian", librarianDir),
+		fmt.Sprintf("%s:/repo", request.RepoDir),
+	}
+	commandArgs := []string{
+		"--librarian=/librarian",
+		"--repo=/repo",
+	}
+
+	env := request.LibrarianConfig.BuildEnvironmentFor(string(CommandTest), request.LibraryID, request.Variant)
+	sandbox := containerSandbox(request.Cfg, request.LibrarianConfig, CommandTest)
+	limits := request.LibrarianConfig.ContainerLimitsFor(request.LibraryID)
+	return c.runDocker(ctx, request.Cfg, CommandTest, request.LibraryID, mounts, env, sandbox, limits, commandArgs)
 }
 
 // Configure configures an API within a repository, either adding it to an
@@ -243,7 +472,10 @@ func (c *Docker) Configure(ctx context.Context, request *ConfigureRequest) (stri
 		fmt.Sprintf("%s:/source:ro", request.ApiRoot), // readonly volume
 	}
 
-	if err := c.runDocker(ctx, request.Cfg, CommandConfigure, mounts, commandArgs); err != nil {
+	env := request.LibrarianConfig.EnvironmentFor(string(CommandConfigure), request.LibraryID)
+	sandbox := containerSandbox(request.Cfg, request.LibrarianConfig, CommandConfigure)
+	limits := request.LibrarianConfig.ContainerLimitsFor(request.LibraryID)
+	if err := c.runDocker(ctx, request.Cfg, CommandConfigure, request.LibraryID, mounts, env, sandbox, limits, commandArgs); err != nil {
 		return "", err
 	}
 
@@ -275,15 +507,34 @@ func (c *Docker) ReleaseInit(ctx context.Context, request *ReleaseInitRequest) e
 		fmt.Sprintf("%s:/output", request.Output),
 	}
 
-	if err := c.runDocker(ctx, request.Cfg, CommandReleaseInit, mounts, commandArgs); err != nil {
+	env := request.LibrarianConfig.EnvironmentFor(string(CommandReleaseInit), request.LibraryID)
+	sandbox := containerSandbox(request.Cfg, request.LibrarianConfig, CommandReleaseInit)
+	limits := request.LibrarianConfig.ContainerLimitsFor(request.LibraryID)
+	if err := c.runDocker(ctx, request.Cfg, CommandReleaseInit, request.LibraryID, mounts, env, sandbox, limits, commandArgs); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (c *Docker) runDocker(_ context.Context, cfg *config.Config, command Command, mounts []string, commandArgs []string) (err error) {
-	mounts = maybeRelocateMounts(cfg, mounts)
+// runDocker runs command in the container for the library with the given ID,
+// which is empty if the command applies to all libraries. The container is
+// bound by limits, which may be nil.
+func (c *Docker) runDocker(ctx context.Context, cfg *config.Config, command Command, libraryID string, mounts []string, env []*config.EnvironmentVariable, sandbox *config.ContainerSandbox, limits *config.ResourceLimits, commandArgs []string) (err error) {
+	if c.ReplayDir != "" {
+		return c.runFixture(command, libraryID, mounts, env, commandArgs, nil)
+	}
+	if c.Kubernetes != nil {
+		run := func() error {
+			return c.runJob(ctx, command, libraryID, append(slices.Clip(mounts), c.mountArgs()...), env, sandbox, limits, commandArgs)
+		}
+		if c.RecordDir != "" {
+			return c.runFixture(command, libraryID, mounts, env, commandArgs, run)
+		}
+		return run()
+	}
+	localMounts := mounts
+	mounts = maybeRelocateMounts(cfg, append(slices.Clip(mounts), c.mountArgs()...))
 
 	args := []string{
 		"run",
@@ -294,16 +545,298 @@ func (c *Docker) runDocker(_ context.Context, cfg *config.Config, command Comman
 		args = append(args, "-v", mount)
 	}
 
-	// Run as the current user in the container - primarily so that any files
-	// we create end up being owned by the current user (and easily deletable).
-	if c.uid != "" && c.gid != "" {
-		args = append(args, "--user", fmt.Sprintf("%s:%s", c.uid, c.gid))
+	envArgs, cleanup, err := environmentArgs(env)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	args = append(args, envArgs...)
+	args = append(args, sandboxArgs(sandbox)...)
+	args = append(args, limitArgs(limits)...)
+	timeout := limits.TimeoutDuration()
+	var name string
+	if timeout > 0 {
+		// The container is named, so that it can be killed on timeout.
+		// Killing the docker CLI would leave the container running.
+		name = fmt.Sprintf("librarian-%s-%d-%d", command, os.Getpid(), containerCount.Add(1))
+		args = append(args, "--name", name)
+	}
+
+	// Any files the container writes must end up being owned by the current
+	// user (and easily deletable), see chownMounts for rootful Docker.
+	args = append(args, c.userArgs()...)
+	args = append(args, c.platformArgs(c.imageFor(command, libraryID))...)
+	if c.Local {
+		args = append(args, "--pull=never")
 	}
 
-	args = append(args, c.Image)
+	runArgs := args
+	args = append(slices.Clip(args), c.imageFor(command, libraryID))
 	args = append(args, string(command))
 	args = append(args, commandArgs...)
-	return c.run(args...)
+	run := func() (err error) {
+		var killed func() bool
+		if timeout > 0 {
+			stop := c.killAfter(timeout, name)
+			killed = stop
+			defer func() {
+				if stop() && err != nil {
+					err = fmt.Errorf("%s container of library %q timed out after %s: %w", command, libraryID, timeout, err)
+				}
+			}()
+		}
+		if c.LogDir == "" {
+			return containerError(c.runWithRetries(ctx, nil, nil, true, killed, args...))
+		}
+		logs, err := openLogs(c.LogDir, command, libraryID, c.LogLevel)
+		if err != nil {
+			return err
+		}
+		runErr := containerError(c.runWithRetries(ctx, logs.stdout, logs.stderr, true, killed, args...))
+		if err := logs.close(); err != nil {
+			slog.Warn("failed to close container logs", "err", err)
+		}
+		return failure.WithLogs(runErr, logs.paths()...)
+	}
+	if c.RecordDir != "" {
+		err = c.runFixture(command, libraryID, localMounts, env, commandArgs, run)
+	} else {
+		err = run()
+	}
+	// The extra mounts are not chowned, as they are not librarian's.
+	c.chownMounts(c.imageFor(command, libraryID), maybeRelocateMounts(cfg, localMounts))
+	if err != nil && c.DebugShell != "" && failure.CategoryOf(err) == failure.ContainerFailure {
+		c.debugShell(command, libraryID, args, debugShellArgs(runArgs, c.imageFor(command, libraryID)))
+	}
+	return err
+}
+
+// debugShellArgs returns the docker arguments which open an interactive shell
+// in a container like the one run with runArgs, the arguments before the
+// image.
+func debugShellArgs(runArgs []string, image string) []string {
+	var args []string
+	for i := 0; i < len(runArgs); i++ {
+		if runArgs[i] == "--name" {
+			// The shell may outlive the timeout of the failed run.
+			i++
+			continue
+		}
+		args = append(args, runArgs[i])
+	}
+	return append(args, "-it", "--entrypoint", "/bin/sh", image)
+}
+
+// debugShell opens an interactive shell in a container like the failed one,
+// which ran with args, or prints the docker commands to rerun the failed
+// command and to open the shell.
+func (c *Docker) debugShell(command Command, libraryID string, args, shellArgs []string) {
+	w := c.debugOutput
+	if w == nil {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, "The %s container of library %q failed. To rerun it:\n\n  %s\n\n", command, libraryID, formatDockerCommand(args))
+	if slices.Contains(args, "--env-file") {
+		fmt.Fprintf(w, "The env file with the secret environment variables is removed after the run; recreate it to run the commands.\n\n")
+	}
+	if c.DebugShell == config.DebugShellInteractive && c.interactive != nil {
+		fmt.Fprintf(w, "Opening a shell in a container with the same image, mounts and environment. Exit the shell to continue.\n")
+		if err := c.interactive(shellArgs...); err != nil {
+			slog.Warn("debug shell exited with an error", "err", err)
+		}
+		return
+	}
+	fmt.Fprintf(w, "To open a shell in a container with the same image, mounts and environment:\n\n  %s\n\n", formatDockerCommand(shellArgs))
+}
+
+// formatDockerCommand formats the docker command with args for a shell.
+func formatDockerCommand(args []string) string {
+	// The command is shown to the user, so secrets in it are redacted.
+	quoted := []string{"docker"}
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(logging.Redact(arg)))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// containerError classifies the error of running a container. The category
+// of an Error is that of its cause. Otherwise, the docker CLI exits with 125
+// when the docker daemon fails, and with the exit code of the container.
+func containerError(err error) error {
+	var dockerErr *Error
+	if errors.As(err, &dockerErr) {
+		return failure.New(dockerErr.category, err)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() != exitCodeDocker {
+		return failure.New(failure.ContainerFailure, err)
+	}
+	return failure.New(failure.TransientInfra, err)
+}
+
+// validateMounts checks that no extra mount shadows a directory mounted by
+// librarian, or another extra mount.
+func validateMounts(mounts []*config.ContainerMount) error {
+	var dirs []string
+	for _, mount := range mounts {
+		for _, reserved := range reservedMountDirs {
+			if overlaps(mount.ContainerDir, reserved) {
+				return failure.New(failure.UserConfig, fmt.Errorf("container mount %s overlaps with %s mounted by librarian", mount.ContainerDir, reserved))
+			}
+		}
+		for _, dir := range dirs {
+			if overlaps(mount.ContainerDir, dir) {
+				return failure.New(failure.UserConfig, fmt.Errorf("container mount %s overlaps with container mount %s", mount.ContainerDir, dir))
+			}
+		}
+		dirs = append(dirs, mount.ContainerDir)
+	}
+	return nil
+}
+
+// overlaps reports whether the absolute paths a and b are the same, or one
+// contains the other.
+func overlaps(a, b string) bool {
+	within := func(path, dir string) bool {
+		return path == dir || dir == "/" || strings.HasPrefix(path, dir+"/")
+	}
+	return within(a, b) || within(b, a)
+}
+
+// mountArgs returns the docker arguments of the extra mounts.
+func (c *Docker) mountArgs() []string {
+	var mounts []string
+	for _, mount := range c.mounts {
+		arg := fmt.Sprintf("%s:%s", mount.HostDir, mount.ContainerDir)
+		if mount.ReadOnly {
+			arg += ":ro"
+		}
+		mounts = append(mounts, arg)
+	}
+	return mounts
+}
+
+// containerSandbox returns the sandbox of the container running command. The
+// -sandbox flag applies the strictest sandbox to the generate command, which
+// runs the least trusted code; otherwise the sandbox configured in lc is used.
+func containerSandbox(cfg *config.Config, lc *config.LibrarianConfig, command Command) *config.ContainerSandbox {
+	if cfg.Sandbox && command == CommandGenerate {
+		return config.StrictContainerSandbox()
+	}
+	return lc.SandboxFor(string(command))
+}
+
+// imageFor returns the image which runs command for the library with the
+// given ID, which is empty for the commands of no particular library.
+func (c *Docker) imageFor(command Command, libraryID string) string {
+	if image, ok := c.LibraryImages[libraryID]; ok && libraryID != "" {
+		return image
+	}
+	if image, ok := c.Images[command]; ok {
+		return image
+	}
+	return c.Image
+}
+
+// containerCount is the number of containers named by this process.
+var containerCount atomic.Int64
+
+// limitArgs returns the docker arguments bounding the resources of the
+// container by limits, which may be nil.
+func limitArgs(limits *config.ResourceLimits) []string {
+	if limits == nil {
+		return nil
+	}
+	var args []string
+	if limits.CPUs != "" {
+		args = append(args, fmt.Sprintf("--cpus=%s", limits.CPUs))
+	}
+	if limits.Memory != "" {
+		args = append(args, fmt.Sprintf("--memory=%s", limits.Memory))
+	}
+	return args
+}
+
+// killAfter kills the container with the given name once timeout elapses.
+// The returned function stops the timer, and reports whether the container
+// was killed.
+func (c *Docker) killAfter(timeout time.Duration, name string) (stop func() bool) {
+	var killed atomic.Bool
+	timer := time.AfterFunc(timeout, func() {
+		killed.Store(true)
+		slog.Warn("Container timed out, killing it", "container", name, "timeout", timeout)
+		if _, err := c.output("kill", name); err != nil {
+			slog.Warn("failed to kill container", "container", name, "err", err)
+		}
+	})
+	return func() bool {
+		timer.Stop()
+		return killed.Load()
+	}
+}
+
+// sandboxArgs returns the docker arguments isolating the container as
+// described by sandbox, which may be nil.
+func sandboxArgs(sandbox *config.ContainerSandbox) []string {
+	if sandbox == nil {
+		return nil
+	}
+	var args []string
+	if sandbox.Network != "" {
+		args = append(args, fmt.Sprintf("--network=%s", sandbox.Network))
+	}
+	if sandbox.ReadOnly {
+		args = append(args, "--read-only")
+	}
+	if sandbox.DropCapabilities {
+		args = append(args, "--cap-drop=ALL", "--security-opt=no-new-privileges")
+	}
+	for _, path := range sandbox.Tmpfs {
+		args = append(args, "--tmpfs", path)
+	}
+	return args
+}
+
+// environmentArgs returns the docker arguments injecting env into the
+// container. Static values are passed on the command line, while secret
+// values are written to an env file which is removed by the returned cleanup
+// function, so that they do not show up in logs.
+func environmentArgs(env []*config.EnvironmentVariable) ([]string, func(), error) {
+	var args []string
+	var secrets []string
+	for _, variable := range env {
+		if variable.SecretEnv == "" {
+			args = append(args, "-e", fmt.Sprintf("%s=%s", variable.Name, variable.Value))
+			continue
+		}
+		value, ok := os.LookupEnv(variable.SecretEnv)
+		if !ok {
+			return nil, nil, fmt.Errorf("environment variable %s for secret %s is not set", variable.SecretEnv, variable.Name)
+		}
+		logging.AddSecret(value)
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, nil, fmt.Errorf("secret %s contains a line break, which env files do not support", variable.Name)
+		}
+		secrets = append(secrets, fmt.Sprintf("%s=%s\n", variable.Name, value))
+	}
+	if len(secrets) == 0 {
+		return args, func() {}, nil
+	}
+	envFile, err := os.CreateTemp("", "librarian-env-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create env file: %w", err)
+	}
+	cleanup := func() {
+		if err := os.Remove(envFile.Name()); err != nil {
+			slog.Warn("fail to remove file", slog.String("name", envFile.Name()), slog.Any("err", err))
+		}
+	}
+	defer envFile.Close()
+	if _, err := envFile.WriteString(strings.Join(secrets, "")); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to write env file: %w", err)
+	}
+	return append(args, "--env-file", envFile.Name()), cleanup, nil
 }
 
 func maybeRelocateMounts(cfg *config.Config, mounts []string) []string {
@@ -324,10 +857,16 @@ func maybeRelocateMounts(cfg *config.Config, mounts []string) []string {
 	return relocatedMounts
 }
 
-func (c *Docker) runCommand(cmdName string, args ...string) error {
+func (c *Docker) runCommand(stdout, stderr io.Writer, cmdName string, args ...string) error {
 	cmd := exec.Command(cmdName, args...)
-	cmd.Stderr = os.Stderr
 	cmd.Stdout = os.Stdout
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	cmd.Stderr = os.Stderr
+	if stderr != nil {
+		cmd.Stderr = stderr
+	}
 	slog.Info(fmt.Sprintf("=== Docker start %s", strings.Repeat("=", 63)))
 	slog.Info(cmd.String())
 	slog.Info(strings.Repeat("-", 80))
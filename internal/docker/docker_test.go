@@ -18,13 +18,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
 )
 
 func TestNew(t *testing.T) {
@@ -34,7 +38,8 @@ func TestNew(t *testing.T) {
 		testUID      = "1000"
 		testGID      = "1001"
 	)
-	d, err := New(testWorkRoot, testImage, testUID, testGID)
+	mounts := []*config.ContainerMount{{HostDir: "/home/user/.m2", ContainerDir: "/root/.m2"}}
+	d, err := New(testWorkRoot, testImage, testUID, testGID, mounts)
 	if err != nil {
 		t.Fatalf("New() error = %v", err)
 	}
@@ -47,9 +52,18 @@ func TestNew(t *testing.T) {
 	if d.gid != testGID {
 		t.Errorf("d.gid = %q, want %q", d.gid, testGID)
 	}
+	if want := filepath.Join(testWorkRoot, LogsDir); d.LogDir != want {
+		t.Errorf("d.LogDir = %q, want %q", d.LogDir, want)
+	}
+	if diff := cmp.Diff(mounts, d.mounts); diff != "" {
+		t.Errorf("d.mounts mismatch (-want +got):\n%s", diff)
+	}
 	if d.run == nil {
 		t.Error("d.run is nil")
 	}
+	if d.output == nil {
+		t.Error("d.output is nil")
+	}
 }
 
 func TestDockerRun(t *testing.T) {
@@ -107,6 +121,191 @@ func TestDockerRun(t *testing.T) {
 				"--source=/source",
 			},
 		},
+		{
+			name: "Generate in declared container",
+			docker: &Docker{
+				Image: testImage,
+				Images: map[Command]string{
+					CommandGenerate: "generatorImage",
+					CommandBuild:    "builderImage",
+				},
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				generateRequest := &GenerateRequest{
+					Cfg:       cfg,
+					State:     state,
+					RepoDir:   repoDir,
+					ApiRoot:   testAPIRoot,
+					Output:    testOutput,
+					LibraryID: testLibraryID,
+				}
+
+				return d.Generate(ctx, generateRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s/.librarian/generator-input:/input", repoDir),
+				"-v", fmt.Sprintf("%s:/output", testOutput),
+				"-v", fmt.Sprintf("%s:/source:ro", testAPIRoot),
+				"generatorImage",
+				string(CommandGenerate),
+				"--librarian=/librarian",
+				"--input=/input",
+				"--output=/output",
+				"--source=/source",
+			},
+		},
+		{
+			name: "Generate with pinned library image",
+			docker: &Docker{
+				Image: testImage,
+				Images: map[Command]string{
+					CommandGenerate: "generatorImage",
+				},
+				LibraryImages: map[string]string{
+					testLibraryID: "pinnedImage",
+				},
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				generateRequest := &GenerateRequest{
+					Cfg:       cfg,
+					State:     state,
+					RepoDir:   repoDir,
+					ApiRoot:   testAPIRoot,
+					Output:    testOutput,
+					LibraryID: testLibraryID,
+				}
+
+				return d.Generate(ctx, generateRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s/.librarian/generator-input:/input", repoDir),
+				"-v", fmt.Sprintf("%s:/output", testOutput),
+				"-v", fmt.Sprintf("%s:/source:ro", testAPIRoot),
+				"pinnedImage",
+				string(CommandGenerate),
+				"--librarian=/librarian",
+				"--input=/input",
+				"--output=/output",
+				"--source=/source",
+			},
+		},
+		{
+			name: "Generate with environment",
+			docker: &Docker{
+				Image: testImage,
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				generateRequest := &GenerateRequest{
+					Cfg: cfg,
+					LibrarianConfig: &config.LibrarianConfig{
+						Environment: []*config.EnvironmentVariable{
+							{Name: "ALL", Value: "1"},
+							{Name: "GENERATE_ONLY", Value: "2", Commands: []string{"generate"}},
+							{Name: "BUILD_ONLY", Value: "3", Commands: []string{"build"}},
+							{Name: "OTHER_LIBRARY", Value: "4", Libraries: []string{"other"}},
+						},
+					},
+					State:     state,
+					RepoDir:   repoDir,
+					ApiRoot:   testAPIRoot,
+					Output:    testOutput,
+					LibraryID: testLibraryID,
+				}
+
+				return d.Generate(ctx, generateRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s/.librarian/generator-input:/input", repoDir),
+				"-v", fmt.Sprintf("%s:/output", testOutput),
+				"-v", fmt.Sprintf("%s:/source:ro", testAPIRoot),
+				"-e", "ALL=1",
+				"-e", "GENERATE_ONLY=2",
+				testImage,
+				string(CommandGenerate),
+				"--librarian=/librarian",
+				"--input=/input",
+				"--output=/output",
+				"--source=/source",
+			},
+		},
+		{
+			name: "Generate with sandbox flag",
+			docker: &Docker{
+				Image: testImage,
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				generateRequest := &GenerateRequest{
+					Cfg: &config.Config{Sandbox: true},
+					LibrarianConfig: &config.LibrarianConfig{
+						Sandbox: &config.ContainerSandbox{Network: "none"},
+					},
+					State:     state,
+					RepoDir:   repoDir,
+					ApiRoot:   testAPIRoot,
+					Output:    testOutput,
+					LibraryID: testLibraryID,
+				}
+
+				return d.Generate(ctx, generateRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s/.librarian/generator-input:/input", repoDir),
+				"-v", fmt.Sprintf("%s:/output", testOutput),
+				"-v", fmt.Sprintf("%s:/source:ro", testAPIRoot),
+				"--network=none",
+				"--read-only",
+				"--cap-drop=ALL",
+				"--security-opt=no-new-privileges",
+				"--tmpfs", "/tmp",
+				testImage,
+				string(CommandGenerate),
+				"--librarian=/librarian",
+				"--input=/input",
+				"--output=/output",
+				"--source=/source",
+			},
+		},
+		{
+			name: "Generate with local image",
+			docker: &Docker{
+				Image: testImage,
+				Local: true,
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				generateRequest := &GenerateRequest{
+					Cfg:       &config.Config{},
+					State:     state,
+					RepoDir:   repoDir,
+					ApiRoot:   testAPIRoot,
+					Output:    testOutput,
+					LibraryID: testLibraryID,
+				}
+
+				return d.Generate(ctx, generateRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s/.librarian/generator-input:/input", repoDir),
+				"-v", fmt.Sprintf("%s:/output", testOutput),
+				"-v", fmt.Sprintf("%s:/source:ro", testAPIRoot),
+				"--pull=never",
+				testImage,
+				string(CommandGenerate),
+				"--librarian=/librarian",
+				"--input=/input",
+				"--output=/output",
+				"--source=/source",
+			},
+		},
 		{
 			name: "Generate with invalid repo root",
 			docker: &Docker{
@@ -204,6 +403,132 @@ func TestDockerRun(t *testing.T) {
 				"--repo=/repo",
 			},
 		},
+		{
+			name: "Test",
+			docker: &Docker{
+				Image: testImage,
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				testRequest := &TestRequest{
+					Cfg:       cfg,
+					State:     state,
+					LibraryID: testLibraryID,
+					RepoDir:   repoDir,
+				}
+
+				return d.Test(ctx, testRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s:/repo", repoDir),
+				testImage,
+				string(CommandTest),
+				"--librarian=/librarian",
+				"--repo=/repo",
+			},
+		},
+		{
+			name: "Build with extra mounts",
+			docker: &Docker{
+				Image: testImage,
+				mounts: []*config.ContainerMount{
+					{HostDir: "/home/user/.m2", ContainerDir: "/root/.m2"},
+					{HostDir: "/cache/npm", ContainerDir: "/npm", ReadOnly: true},
+				},
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				buildRequest := &BuildRequest{
+					Cfg:       cfg,
+					State:     state,
+					LibraryID: testLibraryID,
+					RepoDir:   repoDir,
+				}
+
+				return d.Build(ctx, buildRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s:/repo", repoDir),
+				"-v", "/home/user/.m2:/root/.m2",
+				"-v", "/cache/npm:/npm:ro",
+				testImage,
+				string(CommandBuild),
+				"--librarian=/librarian",
+				"--repo=/repo",
+			},
+		},
+		{
+			name: "Build with configured sandbox",
+			docker: &Docker{
+				Image: testImage,
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				buildRequest := &BuildRequest{
+					Cfg: cfg,
+					LibrarianConfig: &config.LibrarianConfig{
+						Sandbox: &config.ContainerSandbox{
+							Commands: []string{"build"},
+							ReadOnly: true,
+							Tmpfs:    []string{"/tmp", "/root/.cache"},
+						},
+					},
+					State:     state,
+					LibraryID: testLibraryID,
+					RepoDir:   repoDir,
+				}
+
+				return d.Build(ctx, buildRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s:/repo", repoDir),
+				"--read-only",
+				"--tmpfs", "/tmp",
+				"--tmpfs", "/root/.cache",
+				testImage,
+				string(CommandBuild),
+				"--librarian=/librarian",
+				"--repo=/repo",
+			},
+		},
+		{
+			name: "Build with container limits",
+			docker: &Docker{
+				Image: testImage,
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				buildRequest := &BuildRequest{
+					Cfg: cfg,
+					LibrarianConfig: &config.LibrarianConfig{
+						ContainerLimits: &config.ContainerLimits{
+							ResourceLimits: config.ResourceLimits{CPUs: "2", Memory: "4g"},
+							Libraries: []*config.LibraryContainerLimits{
+								{ID: testLibraryID, ResourceLimits: config.ResourceLimits{Memory: "8g"}},
+							},
+						},
+					},
+					State:     state,
+					LibraryID: testLibraryID,
+					RepoDir:   repoDir,
+				}
+
+				return d.Build(ctx, buildRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s:/repo", repoDir),
+				"--cpus=2",
+				"--memory=8g",
+				testImage,
+				string(CommandBuild),
+				"--librarian=/librarian",
+				"--repo=/repo",
+			},
+		},
 		{
 			name: "Build with invalid repo dir",
 			docker: &Docker{
@@ -531,7 +856,7 @@ func TestDockerRun(t *testing.T) {
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			test.docker.run = func(args ...string) error {
+			test.docker.run = func(_, _ io.Writer, args ...string) error {
 				if test.docker.Image == mockImage {
 					return errors.New("simulate docker command failure for testing")
 				}
@@ -560,6 +885,241 @@ func TestDockerRun(t *testing.T) {
 	}
 }
 
+func TestDockerRun_Timeout(t *testing.T) {
+	killed := make(chan struct{})
+	var name string
+	d := &Docker{Image: "testImage"}
+	d.run = func(_, _ io.Writer, args ...string) error {
+		if i := slices.Index(args, "--name"); i >= 0 {
+			name = args[i+1]
+		}
+		// The container runs until it is killed.
+		<-killed
+		return errors.New("exit status 137")
+	}
+	var killArgs []string
+	d.output = func(args ...string) ([]byte, error) {
+		killArgs = args
+		close(killed)
+		return nil, nil
+	}
+	err := d.Build(t.Context(), &BuildRequest{
+		Cfg: &config.Config{},
+		LibrarianConfig: &config.LibrarianConfig{
+			ContainerLimits: &config.ContainerLimits{ResourceLimits: config.ResourceLimits{Timeout: "10ms"}},
+		},
+		State:     &config.LibrarianState{},
+		LibraryID: "a",
+		RepoDir:   t.TempDir(),
+	})
+	if err == nil || !strings.Contains(err.Error(), `build container of library "a" timed out after 10ms`) {
+		t.Errorf("Build() error = %v, want a timeout", err)
+	}
+	if name == "" {
+		t.Fatal("Build() did not name the container")
+	}
+	if diff := cmp.Diff([]string{"kill", name}, killArgs); diff != "" {
+		t.Errorf("kill mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDockerRun_DebugShell(t *testing.T) {
+	for _, test := range []struct {
+		name            string
+		debugShell      string
+		interactive     bool
+		wantOutput      []string
+		wantInteractive bool
+	}{
+		{
+			name:       "print",
+			debugShell: config.DebugShellPrint,
+			wantOutput: []string{
+				"The build container of library \"a\" failed. To rerun it:",
+				"testImage build --librarian=/librarian --repo=/repo",
+				"To open a shell in a container with the same image, mounts and environment:",
+				"-e 'GREETING=hello world' -it --entrypoint /bin/sh testImage",
+			},
+		},
+		{
+			name:        "interactive",
+			debugShell:  config.DebugShellInteractive,
+			interactive: true,
+			wantOutput: []string{
+				"testImage build --librarian=/librarian --repo=/repo",
+				"Opening a shell",
+			},
+			wantInteractive: true,
+		},
+		{
+			name:       "interactive without terminal",
+			debugShell: config.DebugShellInteractive,
+			wantOutput: []string{"-it --entrypoint /bin/sh testImage"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var output strings.Builder
+			d := &Docker{Image: "testImage", DebugShell: test.debugShell, debugOutput: &output}
+			d.run = func(_, _ io.Writer, args ...string) error {
+				return exec.Command("sh", "-c", "exit 1").Run()
+			}
+			var shellArgs []string
+			if test.interactive {
+				d.interactive = func(args ...string) error {
+					shellArgs = args
+					return nil
+				}
+			}
+			err := d.Build(t.Context(), &BuildRequest{
+				Cfg: &config.Config{},
+				LibrarianConfig: &config.LibrarianConfig{
+					Environment:     []*config.EnvironmentVariable{{Name: "GREETING", Value: "hello world"}},
+					ContainerLimits: &config.ContainerLimits{ResourceLimits: config.ResourceLimits{Timeout: "1h"}},
+				},
+				State:     &config.LibrarianState{},
+				LibraryID: "a",
+				RepoDir:   t.TempDir(),
+			})
+			if failure.CategoryOf(err) != failure.ContainerFailure {
+				t.Fatalf("Build() error = %v, want a container failure", err)
+			}
+			for _, want := range test.wantOutput {
+				if !strings.Contains(output.String(), want) {
+					t.Errorf("output = %q, want it to contain %q", output.String(), want)
+				}
+			}
+			if got := shellArgs != nil; got != test.wantInteractive {
+				t.Fatalf("opened shell = %t, want %t", got, test.wantInteractive)
+			}
+			if test.wantInteractive {
+				if slices.Contains(shellArgs, "--name") {
+					t.Errorf("shell args %q contain --name", shellArgs)
+				}
+				if diff := cmp.Diff([]string{"-it", "--entrypoint", "/bin/sh", "testImage"}, shellArgs[len(shellArgs)-4:]); diff != "" {
+					t.Errorf("shell args mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestDockerRun_DebugShellNotConfigured(t *testing.T) {
+	var output strings.Builder
+	d := &Docker{Image: "testImage", debugOutput: &output}
+	d.run = func(_, _ io.Writer, args ...string) error {
+		return exec.Command("sh", "-c", "exit 1").Run()
+	}
+	if err := d.Build(t.Context(), &BuildRequest{Cfg: &config.Config{}, State: &config.LibrarianState{}, LibraryID: "a", RepoDir: t.TempDir()}); err == nil {
+		t.Fatal("Build() error = nil, want error")
+	}
+	if output.Len() != 0 {
+		t.Errorf("output = %q, want none", output.String())
+	}
+}
+
+func TestFormatDockerCommand(t *testing.T) {
+	got := formatDockerCommand([]string{"run", "-v", "/a b:/c", "-e", "X=it's", "--rm", ""})
+	want := `docker run -v '/a b:/c' -e 'X=it'\''s' --rm ''`
+	if got != want {
+		t.Errorf("formatDockerCommand() = %s, want %s", got, want)
+	}
+}
+
+func TestValidateMounts(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		mounts     []*config.ContainerMount
+		wantErrMsg string
+	}{
+		{
+			name: "valid",
+			mounts: []*config.ContainerMount{
+				{HostDir: "/home/user/.m2", ContainerDir: "/root/.m2"},
+				{HostDir: "/cache/npm", ContainerDir: "/npm"},
+				{HostDir: "/cache/repository", ContainerDir: "/repository"},
+			},
+		},
+		{
+			name:       "shadows librarian mount",
+			mounts:     []*config.ContainerMount{{HostDir: "/cache", ContainerDir: "/source"}},
+			wantErrMsg: "overlaps with /source mounted by librarian",
+		},
+		{
+			name:       "inside librarian mount",
+			mounts:     []*config.ContainerMount{{HostDir: "/cache", ContainerDir: "/repo/cache"}},
+			wantErrMsg: "overlaps with /repo mounted by librarian",
+		},
+		{
+			name:       "root",
+			mounts:     []*config.ContainerMount{{HostDir: "/cache", ContainerDir: "/"}},
+			wantErrMsg: "mounted by librarian",
+		},
+		{
+			name: "overlapping mounts",
+			mounts: []*config.ContainerMount{
+				{HostDir: "/cache", ContainerDir: "/cache"},
+				{HostDir: "/cache/npm", ContainerDir: "/cache/npm"},
+			},
+			wantErrMsg: "overlaps with container mount /cache",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateMounts(test.mounts)
+			if test.wantErrMsg == "" {
+				if err != nil {
+					t.Errorf("validateMounts() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+				t.Fatalf("validateMounts() error = %v, want error containing %q", err, test.wantErrMsg)
+			}
+			if got := failure.CategoryOf(err); got != failure.UserConfig {
+				t.Errorf("failure.CategoryOf() = %q, want %q", got, failure.UserConfig)
+			}
+		})
+	}
+}
+
+func TestEnvironmentArgs(t *testing.T) {
+	t.Setenv("LIBRARIAN_TEST_SECRET", "s3cr3t")
+	env := []*config.EnvironmentVariable{
+		{Name: "STATIC", Value: "value"},
+		{Name: "TOKEN", SecretEnv: "LIBRARIAN_TEST_SECRET"},
+	}
+	args, cleanup, err := environmentArgs(env)
+	if err != nil {
+		t.Fatalf("environmentArgs() error = %v", err)
+	}
+	if len(args) != 4 {
+		t.Fatalf("environmentArgs() = %v, want 4 arguments", args)
+	}
+	if diff := cmp.Diff([]string{"-e", "STATIC=value", "--env-file"}, args[:3]); diff != "" {
+		t.Errorf("environmentArgs() mismatch (-want +got):\n%s", diff)
+	}
+	if strings.Contains(strings.Join(args, " "), "s3cr3t") {
+		t.Errorf("environmentArgs() = %v, contains the secret value", args)
+	}
+	envFile := args[3]
+	got, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("TOKEN=s3cr3t\n", string(got)); diff != "" {
+		t.Errorf("env file mismatch (-want +got):\n%s", diff)
+	}
+	cleanup()
+	if _, err := os.Stat(envFile); !os.IsNotExist(err) {
+		t.Errorf("env file %s was not removed", envFile)
+	}
+
+	if _, _, err := environmentArgs([]*config.EnvironmentVariable{
+		{Name: "TOKEN", SecretEnv: "LIBRARIAN_TEST_UNSET_SECRET"},
+	}); err == nil {
+		t.Errorf("environmentArgs() with unset secret should return error")
+	}
+}
+
 func TestWriteLibraryState(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -806,9 +1366,39 @@ func TestDocker_runCommand(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &Docker{}
-			if err := c.runCommand(tt.cmdName, tt.args...); (err != nil) != tt.wantErr {
+			if err := c.runCommand(nil, nil, tt.cmdName, tt.args...); (err != nil) != tt.wantErr {
 				t.Errorf("Docker.runCommand() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func TestContainerError(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		err  error
+		want failure.Category
+	}{
+		{
+			name: "container exited with error",
+			err:  exec.Command("sh", "-c", "exit 1").Run(),
+			want: failure.ContainerFailure,
+		},
+		{
+			name: "docker daemon error",
+			err:  exec.Command("sh", "-c", "exit 125").Run(),
+			want: failure.TransientInfra,
+		},
+		{
+			name: "docker not found",
+			err:  exec.Command("/nonexistent/docker").Run(),
+			want: failure.TransientInfra,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := failure.CategoryOf(containerError(test.err)); got != test.want {
+				t.Errorf("CategoryOf(containerError(%v)) = %q, want %q", test.err, got, test.want)
+			}
+		})
+	}
+}
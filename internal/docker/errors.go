@@ -0,0 +1,247 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+// The known causes of the failures of docker, detected by Error.
+var (
+	ErrDaemonNotRunning    = errors.New("the docker daemon is not running")
+	ErrRegistryAuth        = errors.New("authentication with the container registry failed")
+	ErrRegistryUnavailable = errors.New("the container registry is unavailable")
+	ErrNoSpace             = errors.New("no space left on device")
+	ErrOOMKilled           = errors.New("the container was killed, most likely for running out of memory")
+)
+
+const (
+	// exitCodeDocker is the exit code of "docker run" when docker itself,
+	// rather than the container, fails.
+	exitCodeDocker = 125
+	// exitCodeKilled is the exit code of a container killed with SIGKILL,
+	// e.g. by the kernel when it runs out of memory.
+	exitCodeKilled = 128 + 9
+)
+
+// Error is a failure of docker with a known cause, and how to remedy it.
+// errors.Is reports whether it has one of the causes, e.g. ErrOOMKilled.
+type Error struct {
+	// Cause is the cause of the failure, one of the Err variables.
+	Cause error
+	// Hint tells how to remedy the cause.
+	Hint string
+
+	err      error
+	category failure.Category
+	// retry reports whether the command had no effect, and may succeed
+	// when run again.
+	retry bool
+}
+
+// Error returns the cause, the underlying error and the hint.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %v; %s", e.Cause, e.err, e.Hint)
+}
+
+// Unwrap returns the cause and the underlying error.
+func (e *Error) Unwrap() []error {
+	return []error{e.Cause, e.err}
+}
+
+// knownFailure is a failure mode of docker, detected from the standard error
+// of the docker command.
+type knownFailure struct {
+	cause    error
+	category failure.Category
+	retry    bool
+	hint     string
+	// messages are the lowercase substrings of the standard error which
+	// reveal the failure.
+	messages []string
+	// inContainer reports whether the messages are detected in the output of
+	// the container too, rather than only in that of docker.
+	inContainer bool
+}
+
+// knownFailures are the failure modes of docker, in the order they are
+// detected.
+var knownFailures = []*knownFailure{
+	{
+		cause:    ErrDaemonNotRunning,
+		category: failure.TransientInfra,
+		retry:    true,
+		hint:     `start the docker daemon, e.g. with "sudo systemctl start docker", and check that DOCKER_HOST, if set, points to it`,
+		messages: []string{
+			"cannot connect to the docker daemon",
+			"is the docker daemon running",
+			"error during connect",
+		},
+	},
+	{
+		cause:    ErrRegistryAuth,
+		category: failure.UserConfig,
+		hint:     `log in to the registry, e.g. with "docker login" or "gcloud auth configure-docker", or check that the image exists`,
+		messages: []string{
+			"unauthorized:",
+			"authentication required",
+			"no basic auth credentials",
+			"pull access denied",
+			"error getting credentials",
+			"denied:",
+		},
+	},
+	{
+		cause:    ErrRegistryUnavailable,
+		category: failure.TransientInfra,
+		retry:    true,
+		hint:     "retry later, or pull the images through a registry mirror with -registry-mirror",
+		messages: []string{
+			"toomanyrequests",
+			"tls handshake timeout",
+			"i/o timeout",
+			"connection reset by peer",
+			"502 bad gateway",
+			"503 service unavailable",
+			"504 gateway timeout",
+			"unexpected eof",
+		},
+	},
+	{
+		cause:       ErrNoSpace,
+		category:    failure.TransientInfra,
+		hint:        `free disk space, e.g. by removing unused images with "docker system prune", or use a -output directory on a larger disk`,
+		messages:    []string{"no space left on device"},
+		inContainer: true,
+	},
+}
+
+// oomKilled is the failure of a container killed with SIGKILL, which is not
+// detected from its output.
+var oomKilled = &knownFailure{
+	cause:    ErrOOMKilled,
+	category: failure.ContainerFailure,
+	hint:     "raise the memory limit of the container in container_limits of .librarian/config.yaml, or the memory available to docker",
+}
+
+// dockerError returns err as an Error if its cause is known from stderr, the
+// standard error of the docker command. If ran is true, the command ran a
+// container, so stderr holds the output of the container too, unless docker
+// itself failed. killed, if not nil, reports whether librarian killed the
+// container, e.g. on timeout, which is then not an OOM kill.
+func dockerError(err error, stderr string, ran bool, killed func() bool) error {
+	if err == nil {
+		return nil
+	}
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+	inContainer := ran && exitCode != exitCodeDocker
+	if inContainer && exitCode == exitCodeKilled && (killed == nil || !killed()) {
+		return oomKilled.error(err)
+	}
+	stderr = strings.ToLower(stderr)
+	for _, known := range knownFailures {
+		if inContainer && !known.inContainer {
+			continue
+		}
+		for _, message := range known.messages {
+			if strings.Contains(stderr, message) {
+				return known.error(err)
+			}
+		}
+	}
+	return err
+}
+
+func (f *knownFailure) error(err error) *Error {
+	return &Error{Cause: f.cause, Hint: f.hint, err: err, category: f.category, retry: f.retry}
+}
+
+// categoryOr returns the category of the cause of err if it is an Error, or
+// category otherwise.
+func categoryOr(err error, category failure.Category) failure.Category {
+	var dockerErr *Error
+	if errors.As(err, &dockerErr) {
+		return dockerErr.category
+	}
+	return category
+}
+
+// defaultRetryDelays are the delays before running a docker command again,
+// after it failed for a cause which left it without effect and may be gone.
+var defaultRetryDelays = []time.Duration{5 * time.Second, 15 * time.Second, 45 * time.Second}
+
+// runWithRetries runs the docker command with args like c.run, and detects
+// the cause of its failure, see dockerError. The command is run again after
+// each of c.retryDelays while it fails for a cause which is safe to retry:
+// the daemon was unreachable, or the registry failed, before any container
+// started.
+func (c *Docker) runWithRetries(ctx context.Context, stdout, stderr io.Writer, ran bool, killed func() bool, args ...string) error {
+	for attempt := 0; ; attempt++ {
+		tail := &stderrTail{}
+		err := c.run(stdout, io.MultiWriter(cmp.Or[io.Writer](stderr, os.Stderr), tail), args...)
+		err = dockerError(err, tail.String(), ran, killed)
+		var dockerErr *Error
+		if !errors.As(err, &dockerErr) || !dockerErr.retry || attempt == len(c.retryDelays) {
+			return err
+		}
+		slog.Warn("docker command failed, retrying", "cause", dockerErr.Cause, "attempt", attempt+1, "delay", c.retryDelays[attempt])
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(c.retryDelays[attempt]):
+		}
+	}
+}
+
+// stderrTailSize is the size of the end of the standard error of a docker
+// command which is kept to detect the cause of its failure.
+const stderrTailSize = 64 << 10
+
+// stderrTail keeps the last stderrTailSize bytes written to it.
+type stderrTail struct {
+	buf []byte
+}
+
+// Write keeps p, and drops the bytes before the last stderrTailSize.
+func (t *stderrTail) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > 2*stderrTailSize {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-stderrTailSize:]...)
+	}
+	return len(p), nil
+}
+
+// String returns the last stderrTailSize bytes written.
+func (t *stderrTail) String() string {
+	if len(t.buf) > stderrTailSize {
+		return string(t.buf[len(t.buf)-stderrTailSize:])
+	}
+	return string(t.buf)
+}
@@ -0,0 +1,200 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+func TestDockerError(t *testing.T) {
+	t.Parallel()
+	exit := func(code int) error {
+		return exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+	}
+	for _, test := range []struct {
+		name         string
+		err          error
+		stderr       string
+		ran          bool
+		killed       bool
+		wantCause    error
+		wantCategory failure.Category
+	}{
+		{
+			name:         "daemon not running",
+			err:          exit(125),
+			stderr:       "docker: Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?",
+			ran:          true,
+			wantCause:    ErrDaemonNotRunning,
+			wantCategory: failure.TransientInfra,
+		},
+		{
+			name:         "pull unauthorized",
+			err:          exit(1),
+			stderr:       "Error response from daemon: Head \"https://us-docker.pkg.dev/v2/p/r/image/manifests/v1\": denied: Permission denied",
+			wantCause:    ErrRegistryAuth,
+			wantCategory: failure.UserConfig,
+		},
+		{
+			name:         "rate limited",
+			err:          exit(125),
+			stderr:       "docker: Error response from daemon: toomanyrequests: You have reached your pull rate limit.",
+			ran:          true,
+			wantCause:    ErrRegistryUnavailable,
+			wantCategory: failure.TransientInfra,
+		},
+		{
+			name:         "out of disk in container",
+			err:          exit(1),
+			stderr:       "write /output/foo.go: no space left on device",
+			ran:          true,
+			wantCause:    ErrNoSpace,
+			wantCategory: failure.TransientInfra,
+		},
+		{
+			name:         "OOM killed",
+			err:          exit(137),
+			ran:          true,
+			wantCause:    ErrOOMKilled,
+			wantCategory: failure.ContainerFailure,
+		},
+		{
+			name:         "killed on timeout",
+			err:          exit(137),
+			ran:          true,
+			killed:       true,
+			wantCategory: failure.ContainerFailure,
+		},
+		{
+			name:         "registry message in container output",
+			err:          exit(1),
+			stderr:       "fetching dependencies: 401 unauthorized: bad token",
+			ran:          true,
+			wantCategory: failure.ContainerFailure,
+		},
+		{
+			name:         "unknown",
+			err:          exit(125),
+			stderr:       "docker: invalid reference format.",
+			ran:          true,
+			wantCategory: failure.TransientInfra,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			err := dockerError(test.err, test.stderr, test.ran, func() bool { return test.killed })
+			var dockerErr *Error
+			if got := errors.As(err, &dockerErr); got != (test.wantCause != nil) {
+				t.Fatalf("dockerError() = %v, want cause %v", err, test.wantCause)
+			}
+			if test.wantCause != nil {
+				if !errors.Is(err, test.wantCause) {
+					t.Errorf("errors.Is(%v, %v) = false, want true", err, test.wantCause)
+				}
+				if !strings.Contains(err.Error(), dockerErr.Hint) {
+					t.Errorf("dockerError() = %q, want the hint %q", err, dockerErr.Hint)
+				}
+			}
+			if got := failure.CategoryOf(containerError(err)); got != test.wantCategory {
+				t.Errorf("CategoryOf(containerError(%v)) = %q, want %q", err, got, test.wantCategory)
+			}
+		})
+	}
+}
+
+func TestRunWithRetries(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name         string
+		stderr       []string
+		wantRuns     int
+		wantCause    error
+		wantCategory failure.Category
+	}{
+		{
+			name:     "daemon started",
+			stderr:   []string{"Cannot connect to the Docker daemon at unix:///var/run/docker.sock.", ""},
+			wantRuns: 2,
+		},
+		{
+			name:         "daemon never started",
+			stderr:       []string{"error during connect", "error during connect", "error during connect"},
+			wantRuns:     3,
+			wantCause:    ErrDaemonNotRunning,
+			wantCategory: failure.TransientInfra,
+		},
+		{
+			name:         "not retried",
+			stderr:       []string{"unauthorized: authentication required", ""},
+			wantRuns:     1,
+			wantCause:    ErrRegistryAuth,
+			wantCategory: failure.UserConfig,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			runs := 0
+			d := &Docker{
+				retryDelays: []time.Duration{time.Millisecond, time.Millisecond},
+				run: func(_, stderr io.Writer, args ...string) error {
+					message := test.stderr[runs]
+					runs++
+					if message == "" {
+						return nil
+					}
+					io.WriteString(stderr, message)
+					return exec.Command("sh", "-c", "exit 125").Run()
+				},
+			}
+			err := d.runWithRetries(t.Context(), io.Discard, io.Discard, true, nil, "run", "image")
+			if runs != test.wantRuns {
+				t.Errorf("runWithRetries() ran %d times, want %d", runs, test.wantRuns)
+			}
+			if test.wantCause == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, test.wantCause) {
+				t.Errorf("runWithRetries() error = %v, want %v", err, test.wantCause)
+			}
+			if got := failure.CategoryOf(containerError(err)); got != test.wantCategory {
+				t.Errorf("CategoryOf(containerError(%v)) = %q, want %q", err, got, test.wantCategory)
+			}
+		})
+	}
+}
+
+func TestStderrTail(t *testing.T) {
+	t.Parallel()
+	tail := &stderrTail{}
+	for range 3 * stderrTailSize / 1024 {
+		io.WriteString(tail, strings.Repeat("x", 1023)+"\n")
+	}
+	io.WriteString(tail, "no space left on device")
+	got := tail.String()
+	if len(got) != stderrTailSize || !strings.HasSuffix(got, "no space left on device") {
+		t.Errorf("String() has %d bytes ending with %q, want %d bytes ending with the last write", len(got), got[len(got)-30:], stderrTailSize)
+	}
+}
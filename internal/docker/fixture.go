@@ -0,0 +1,344 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+const (
+	invocationFile = "invocation.json"
+	outputsDir     = "outputs"
+)
+
+// invocation is the recording of a single container run. It is stored as
+// invocation.json in a directory of the fixture directory named after the
+// position of the run and the command, e.g. "001-generate", under the
+// directory of its library, if any, e.g. "pubsub/001-generate". The files
+// which the container created or modified are stored in the "outputs"
+// directory next to it, in a subdirectory per mount.
+type invocation struct {
+	// Command is the command passed to the container.
+	Command string `json:"command"`
+	// Args are the arguments passed to the command.
+	Args []string `json:"args"`
+	// Env is the environment of the container, as NAME=value pairs. Only the
+	// names of secrets are recorded.
+	Env []string `json:"env,omitempty"`
+	// Mounts are the directories mounted into the container.
+	Mounts []*mountRecord `json:"mounts"`
+	// Error is the error of the container run, if it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// mountRecord is the recording of a directory mounted into a container.
+type mountRecord struct {
+	// Target is the path of the directory in the container.
+	Target string `json:"target"`
+	// ReadOnly reports whether the directory is mounted read-only.
+	ReadOnly bool `json:"readOnly,omitempty"`
+	// Inputs maps the path of each file in the directory before the run,
+	// relative to the directory, to the SHA-256 of its content.
+	Inputs map[string]string `json:"inputs"`
+	// Deleted are the files which the container deleted.
+	Deleted []string `json:"deleted,omitempty"`
+
+	// source is the directory on the local filesystem.
+	source string
+}
+
+// newInvocation captures the inputs of a container run. The mounts are in the
+// "source:target[:ro]" format used by the -v flag of docker.
+func newInvocation(command Command, mounts []string, env []*config.EnvironmentVariable, commandArgs []string) (*invocation, error) {
+	inv := &invocation{
+		Command: string(command),
+		Args:    commandArgs,
+	}
+	for _, variable := range env {
+		if variable.SecretEnv != "" {
+			inv.Env = append(inv.Env, variable.Name)
+			continue
+		}
+		inv.Env = append(inv.Env, fmt.Sprintf("%s=%s", variable.Name, variable.Value))
+	}
+	for _, mount := range mounts {
+		parts := strings.Split(mount, ":")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid mount %q", mount)
+		}
+		record := &mountRecord{
+			Target:   parts[1],
+			ReadOnly: len(parts) > 2 && parts[2] == "ro",
+			source:   parts[0],
+		}
+		inputs, err := hashTree(record.source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mount %s: %w", mount, err)
+		}
+		record.Inputs = inputs
+		inv.Mounts = append(inv.Mounts, record)
+	}
+	return inv, nil
+}
+
+// record writes the invocation and the changes the container made to the
+// writable mounts into dir.
+func (inv *invocation) record(dir string, runErr error) error {
+	if runErr != nil {
+		inv.Error = runErr.Error()
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, mount := range inv.Mounts {
+		if mount.ReadOnly {
+			continue
+		}
+		after, err := hashTree(mount.source)
+		if err != nil {
+			return fmt.Errorf("failed to read mount %s: %w", mount.source, err)
+		}
+		for _, path := range slices.Sorted(maps.Keys(mount.Inputs)) {
+			if _, ok := after[path]; !ok {
+				mount.Deleted = append(mount.Deleted, path)
+			}
+		}
+		for path, hash := range after {
+			if mount.Inputs[path] == hash {
+				continue
+			}
+			dst := filepath.Join(dir, outputsDir, mountDirName(mount.Target), path)
+			if err := copyFile(dst, filepath.Join(mount.source, path)); err != nil {
+				return err
+			}
+		}
+	}
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, invocationFile), data, 0644)
+}
+
+// replay checks inv against the invocation recorded in dir, and applies the
+// recorded changes to the writable mounts of inv. It returns the recorded
+// error of the container run, if any.
+func (inv *invocation) replay(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, invocationFile))
+	if err != nil {
+		return fmt.Errorf("failed to read recorded container run: %w", err)
+	}
+	var recorded invocation
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return fmt.Errorf("failed to parse recorded container run %s: %w", dir, err)
+	}
+	if err := inv.match(&recorded); err != nil {
+		return fmt.Errorf("container run does not match recording %s: %w", dir, err)
+	}
+	for i, mount := range inv.Mounts {
+		if mount.ReadOnly {
+			continue
+		}
+		for _, path := range recorded.Mounts[i].Deleted {
+			if err := os.Remove(filepath.Join(mount.source, path)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+		outputs := filepath.Join(dir, outputsDir, mountDirName(mount.Target))
+		if err := copyTree(mount.source, outputs); err != nil {
+			return fmt.Errorf("failed to replay outputs of %s: %w", mount.Target, err)
+		}
+	}
+	if recorded.Error != "" {
+		return failure.New(failure.ContainerFailure, errors.New(recorded.Error))
+	}
+	return nil
+}
+
+// match reports the first difference between the inputs of inv and those of
+// recorded.
+func (inv *invocation) match(recorded *invocation) error {
+	if inv.Command != recorded.Command {
+		return fmt.Errorf("command is %q, recorded %q", inv.Command, recorded.Command)
+	}
+	if !slices.Equal(inv.Args, recorded.Args) {
+		return fmt.Errorf("arguments are %q, recorded %q", inv.Args, recorded.Args)
+	}
+	if !slices.Equal(inv.Env, recorded.Env) {
+		return fmt.Errorf("environment is %q, recorded %q", inv.Env, recorded.Env)
+	}
+	if len(inv.Mounts) != len(recorded.Mounts) {
+		return fmt.Errorf("got %d mounts, recorded %d", len(inv.Mounts), len(recorded.Mounts))
+	}
+	for i, mount := range inv.Mounts {
+		want := recorded.Mounts[i]
+		if mount.Target != want.Target || mount.ReadOnly != want.ReadOnly {
+			return fmt.Errorf("mount %d is %s, recorded %s", i, mount.Target, want.Target)
+		}
+		for _, path := range slices.Sorted(maps.Keys(want.Inputs)) {
+			hash, ok := mount.Inputs[path]
+			if !ok {
+				return fmt.Errorf("%s is missing from %s", path, mount.Target)
+			}
+			if hash != want.Inputs[path] {
+				return fmt.Errorf("%s in %s has changed", path, mount.Target)
+			}
+		}
+		for _, path := range slices.Sorted(maps.Keys(mount.Inputs)) {
+			if _, ok := want.Inputs[path]; !ok {
+				return fmt.Errorf("%s in %s was not recorded", path, mount.Target)
+			}
+		}
+	}
+	return nil
+}
+
+// runFixture records or replays a container run, depending on whether
+// RecordDir or ReplayDir is set. run runs the container, and is only called
+// when recording. The runs of each library are numbered, and recorded, in the
+// subdirectory of the fixture directory named after it, so that the runs of
+// libraries generated in parallel are replayed whatever their order.
+func (c *Docker) runFixture(command Command, libraryID string, mounts []string, env []*config.EnvironmentVariable, commandArgs []string, run func() error) error {
+	inv, err := newInvocation(command, mounts, env, commandArgs)
+	if err != nil {
+		return err
+	}
+	c.invocationsMu.Lock()
+	if c.invocations == nil {
+		c.invocations = make(map[string]int)
+	}
+	c.invocations[libraryID]++
+	position := c.invocations[libraryID]
+	c.invocationsMu.Unlock()
+	if c.ReplayDir != "" {
+		dir := filepath.Join(c.ReplayDir, libraryID)
+		// Look the recording up by position only, so that a different
+		// command is reported as a mismatch rather than a missing recording.
+		matches, err := filepath.Glob(filepath.Join(dir, fmt.Sprintf("%03d-*", position)))
+		if err != nil {
+			return err
+		}
+		if len(matches) != 1 {
+			return fmt.Errorf("found %d recordings of container run %d in %s, want 1", len(matches), position, dir)
+		}
+		slog.Info("Replaying container run", "recording", filepath.Base(matches[0]))
+		return inv.replay(matches[0])
+	}
+	name := fmt.Sprintf("%03d-%s", position, command)
+	runErr := run()
+	slog.Info("Recording container run", "recording", filepath.Join(libraryID, name))
+	if err := inv.record(filepath.Join(c.RecordDir, libraryID, name), runErr); err != nil {
+		return fmt.Errorf("failed to record container run: %w", err)
+	}
+	return runErr
+}
+
+// hashTree returns the SHA-256 of each regular file in dir, keyed by the
+// slash-separated path relative to dir. Git metadata is skipped.
+func hashTree(dir string) (map[string]string, error) {
+	hashes := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		hash, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		hashes[filepath.ToSlash(rel)] = hash
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return hashes, nil
+	}
+	return hashes, err
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// mountDirName returns the name of the directory holding the outputs of the
+// mount with the given target, e.g. "output" for "/output".
+func mountDirName(target string) string {
+	return strings.ReplaceAll(strings.Trim(target, "/"), "/", "_")
+}
+
+// copyTree copies the regular files in src to dst. A missing src is treated
+// as an empty directory.
+func copyTree(dst, src string) error {
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		return copyFile(filepath.Join(dst, rel), path)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func copyFile(dst, src string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
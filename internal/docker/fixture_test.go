@@ -0,0 +1,243 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	t.Setenv("TEST_SECRET", "secret-value")
+	env := []*config.EnvironmentVariable{
+		{Name: "PLAIN", Value: "value"},
+		{Name: "SECRET", SecretEnv: "TEST_SECRET"},
+	}
+	// setup creates the mounted directories of a container run.
+	setup := func(t *testing.T) (string, []string) {
+		t.Helper()
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"librarian/request.json": "{}",
+			"output/stale.txt":       "stale",
+			"source/api.proto":       "syntax = \"proto3\";",
+		})
+		return dir, []string{
+			filepath.Join(dir, "librarian") + ":/librarian",
+			filepath.Join(dir, "output") + ":/output",
+			filepath.Join(dir, "source") + ":/source:ro",
+		}
+	}
+	fixtures := t.TempDir()
+	var runs int
+	recorder := &Docker{
+		RecordDir: fixtures,
+		run: func(_, _ io.Writer, args ...string) error {
+			runs++
+			dir := strings.Split(args[slices.Index(args, "-v")+1], ":")[0]
+			root := filepath.Dir(dir)
+			writeFiles(t, root, map[string]string{
+				"librarian/response.json": `{"id": "example"}`,
+				"output/generated.go":     "package generated",
+			})
+			if err := os.Remove(filepath.Join(root, "output/stale.txt")); err != nil {
+				t.Fatal(err)
+			}
+			if runs == 2 {
+				return errors.New("exit status 1")
+			}
+			return nil
+		},
+	}
+	recordDir, recordMounts := setup(t)
+	if err := recorder.runDocker(t.Context(), &config.Config{}, CommandGenerate, "", recordMounts, env, nil, nil, []string{"--output=/output"}); err != nil {
+		t.Fatalf("runDocker() error = %v", err)
+	}
+	_, failingMounts := setup(t)
+	if err := recorder.runDocker(t.Context(), &config.Config{}, CommandBuild, "", failingMounts, nil, nil, nil, nil); err == nil {
+		t.Fatal("runDocker() error = nil, want error")
+	}
+
+	for _, name := range []string{"001-generate", "002-build"} {
+		if _, err := os.Stat(filepath.Join(fixtures, name, invocationFile)); err != nil {
+			t.Errorf("recording %s: %v", name, err)
+		}
+	}
+
+	replayer := &Docker{
+		ReplayDir: fixtures,
+		run: func(_, _ io.Writer, args ...string) error {
+			t.Fatalf("run() called while replaying with %v", args)
+			return nil
+		},
+	}
+	replayDir, replayMounts := setup(t)
+	// Secrets are not needed to replay a container run.
+	t.Setenv("TEST_SECRET", "")
+	os.Unsetenv("TEST_SECRET")
+	if err := replayer.runDocker(t.Context(), &config.Config{}, CommandGenerate, "", replayMounts, env, nil, nil, []string{"--output=/output"}); err != nil {
+		t.Fatalf("replay runDocker() error = %v", err)
+	}
+	for _, path := range []string{"librarian/response.json", "output/generated.go"} {
+		want, err := os.ReadFile(filepath.Join(recordDir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(replayDir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(string(want), string(got)); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", path, diff)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(replayDir, "output/stale.txt")); !os.IsNotExist(err) {
+		t.Errorf("deleted file was not deleted on replay, err = %v", err)
+	}
+
+	_, failingReplayMounts := setup(t)
+	err := replayer.runDocker(t.Context(), &config.Config{}, CommandBuild, "", failingReplayMounts, nil, nil, nil, nil)
+	if err == nil || err.Error() != "exit status 1" {
+		t.Errorf("replay runDocker() error = %v, want recorded error", err)
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	fixtures := t.TempDir()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"source/api.proto": "v1"})
+	mounts := []string{filepath.Join(dir, "source") + ":/source:ro"}
+	recorder := &Docker{
+		RecordDir: fixtures,
+		run:       func(_, _ io.Writer, args ...string) error { return nil },
+	}
+	if err := recorder.runDocker(t.Context(), &config.Config{}, CommandGenerate, "", mounts, nil, nil, nil, []string{"--source=/source"}); err != nil {
+		t.Fatalf("runDocker() error = %v", err)
+	}
+
+	for _, test := range []struct {
+		name       string
+		command    Command
+		files      map[string]string
+		args       []string
+		wantErrMsg string
+	}{
+		{
+			name:       "different command",
+			command:    CommandBuild,
+			args:       []string{"--source=/source"},
+			wantErrMsg: `command is "build", recorded "generate"`,
+		},
+		{
+			name:       "different arguments",
+			command:    CommandGenerate,
+			wantErrMsg: "arguments are",
+		},
+		{
+			name:       "changed input",
+			command:    CommandGenerate,
+			files:      map[string]string{"source/api.proto": "v2"},
+			args:       []string{"--source=/source"},
+			wantErrMsg: "api.proto in /source has changed",
+		},
+		{
+			name:       "new input",
+			command:    CommandGenerate,
+			files:      map[string]string{"source/other.proto": "v1"},
+			args:       []string{"--source=/source"},
+			wantErrMsg: "other.proto in /source was not recorded",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			writeFiles(t, dir, map[string]string{"source/api.proto": "v1"})
+			writeFiles(t, dir, test.files)
+			t.Cleanup(func() {
+				os.Remove(filepath.Join(dir, "source/other.proto"))
+			})
+			replayer := &Docker{ReplayDir: fixtures}
+			err := replayer.runDocker(t.Context(), &config.Config{}, test.command, "", mounts, nil, nil, nil, test.args)
+			if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+				t.Errorf("runDocker() error = %v, want error containing %q", err, test.wantErrMsg)
+			}
+		})
+	}
+}
+
+func TestRecordAndReplay_Libraries(t *testing.T) {
+	fixtures := t.TempDir()
+	mounts := func(t *testing.T, libraryID string) []string {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"source/api.proto": libraryID})
+		return []string{filepath.Join(dir, "source") + ":/source:ro"}
+	}
+	recorder := &Docker{
+		RecordDir: fixtures,
+		run:       func(_, _ io.Writer, args ...string) error { return nil },
+	}
+	// The libraries are run concurrently, and their runs are numbered apart.
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, libraryID := range []string{"a", "b"} {
+		wg.Go(func() {
+			for _, command := range []Command{CommandGenerate, CommandBuild} {
+				if err := recorder.runDocker(t.Context(), &config.Config{}, command, libraryID, mounts(t, libraryID), nil, nil, nil, nil); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a/001-generate", "a/002-build", "b/001-generate", "b/002-build"} {
+		if _, err := os.Stat(filepath.Join(fixtures, name, invocationFile)); err != nil {
+			t.Errorf("recording %s: %v", name, err)
+		}
+	}
+
+	// The runs of the libraries are replayed in another order.
+	replayer := &Docker{ReplayDir: fixtures}
+	for _, libraryID := range []string{"b", "a"} {
+		for _, command := range []Command{CommandGenerate, CommandBuild} {
+			if err := replayer.runDocker(t.Context(), &config.Config{}, command, libraryID, mounts(t, libraryID), nil, nil, nil, nil); err != nil {
+				t.Errorf("replay runDocker(%s, %s) error = %v", command, libraryID, err)
+			}
+		}
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
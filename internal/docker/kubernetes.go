@@ -0,0 +1,794 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gcs"
+	"github.com/googleapis/librarian/internal/logging"
+)
+
+const (
+	// defaultStorageImage is the default [config.Kubernetes.StorageImage].
+	defaultStorageImage = "gcr.io/google.com/cloudsdktool/google-cloud-cli:slim"
+
+	// jobContainerName is the name of the language container in the pods of
+	// the Jobs.
+	jobContainerName = "librarian"
+
+	// podRunningTimeout is how long log streaming waits for the pod of a Job
+	// to start, e.g. while the cluster scales up.
+	podRunningTimeout = "15m"
+
+	// storageSyncDir is the directory of the storage containers under which
+	// the mounted directories are synced, one subdirectory per mount.
+	storageSyncDir = "/librarian-sync"
+
+	// networkLabel is the pod label of Jobs whose sandbox has no network
+	// access. Kubernetes has no per-pod equivalent of "docker run
+	// --network=none", so the cluster must have a NetworkPolicy denying all
+	// traffic of the pods with the label.
+	networkLabel = "librarian.googleapis.com/network"
+)
+
+// jobPollInterval is how often the status of a Job is checked.
+var jobPollInterval = 5 * time.Second
+
+// objectStorage stores the mounted directories synced with Jobs through Cloud
+// Storage.
+type objectStorage interface {
+	Upload(ctx context.Context, bucket, name string, r io.Reader) error
+	Download(ctx context.Context, bucket, name string) ([]byte, error)
+	Delete(ctx context.Context, bucket, name string) error
+}
+
+// jobMount is a directory mounted into the language container of a Job.
+type jobMount struct {
+	hostDir      string
+	containerDir string
+	readOnly     bool
+}
+
+// parseMount parses a docker mount argument, "host:container[:ro]".
+func parseMount(mount string) *jobMount {
+	parts := strings.Split(mount, ":")
+	m := &jobMount{hostDir: parts[0]}
+	if len(parts) > 1 {
+		m.containerDir = parts[1]
+	}
+	m.readOnly = len(parts) > 2 && parts[2] == "ro"
+	return m
+}
+
+// runJob runs command as a Kubernetes Job, in place of running it with the
+// local Docker daemon. The directories of mounts are shared with the Job
+// through the persistent volume claim or the Cloud Storage location of
+// c.Kubernetes. The output of the language container is streamed into the
+// logs of the run, and the Job is deleted once it completes, fails or is
+// terminated after the timeout of limits.
+func (c *Docker) runJob(ctx context.Context, command Command, libraryID string, mounts []string, env []*config.EnvironmentVariable, sandbox *config.ContainerSandbox, limits *config.ResourceLimits, commandArgs []string) error {
+	name := fmt.Sprintf("librarian-%s-%s-%d", command, strconv.FormatInt(now().UnixNano(), 36), containerCount.Add(1))
+	var jobMounts []*jobMount
+	for _, mount := range mounts {
+		jobMounts = append(jobMounts, parseMount(mount))
+	}
+	job, secret, err := c.jobManifest(name, command, libraryID, jobMounts, env, sandbox, limits, commandArgs)
+	if err != nil {
+		return err
+	}
+	if c.Kubernetes.Storage != "" {
+		if err := c.uploadMounts(ctx, name, jobMounts); err != nil {
+			return err
+		}
+		defer c.deleteObjects(ctx, name, jobMounts)
+	}
+
+	list := &k8sObject{APIVersion: "v1", Kind: "List"}
+	if secret != nil {
+		list.Items = append(list.Items, secret)
+	}
+	list.Items = append(list.Items, job)
+	manifest, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	if err := c.kubectl(bytes.NewReader(manifest), io.Discard, nil, c.kubectlArgs("create", "--filename=-")...); err != nil {
+		return failure.New(failure.TransientInfra, fmt.Errorf("failed to create Job %s: %w", name, err))
+	}
+	defer c.deleteJob(name, secret != nil)
+	slog.Info("Started Kubernetes Job", "job", name, "command", command, "library", libraryID)
+
+	var logs *containerLogs
+	var stdout, stderr io.Writer
+	if c.LogDir != "" {
+		if logs, err = openLogs(c.LogDir, command, libraryID, c.LogLevel); err != nil {
+			return err
+		}
+		stdout, stderr = logs.stdout, logs.stderr
+	}
+	logsArgs := c.kubectlArgs("logs", "job/"+name, "--container="+jobContainerName)
+	streamErr := c.kubectl(nil, stdout, stderr, append(logsArgs, "--follow", "--pod-running-timeout="+podRunningTimeout)...)
+	runErr := c.waitForJob(ctx, name, command, libraryID, limits.TimeoutDuration())
+	if streamErr != nil {
+		// The pod may have failed before running, which ends the stream
+		// early; the logs of the terminated container are still available.
+		slog.Warn("failed to stream logs of Job, fetching them after it ended", "job", name, "err", streamErr)
+		if err := c.kubectl(nil, stdout, stderr, logsArgs...); err != nil {
+			slog.Warn("failed to fetch logs of Job", "job", name, "err", err)
+		}
+	}
+	if logs != nil {
+		if err := logs.close(); err != nil {
+			slog.Warn("failed to close container logs", "err", err)
+		}
+		runErr = failure.WithLogs(runErr, logs.paths()...)
+	}
+	if runErr != nil {
+		return runErr
+	}
+	if c.Kubernetes.Storage != "" {
+		return c.downloadMounts(ctx, name, jobMounts)
+	}
+	return nil
+}
+
+// kubectlArgs returns args prefixed with the namespace of the Jobs, if any.
+func (c *Docker) kubectlArgs(args ...string) []string {
+	if c.Kubernetes.Namespace == "" {
+		return args
+	}
+	return append([]string{"--namespace=" + c.Kubernetes.Namespace}, args...)
+}
+
+// waitForJob waits for the Job with the given name to complete, and returns
+// an error if it fails, including when it is terminated after timeout.
+func (c *Docker) waitForJob(ctx context.Context, name string, command Command, libraryID string, timeout time.Duration) error {
+	for {
+		var out bytes.Buffer
+		if err := c.kubectl(nil, &out, nil, c.kubectlArgs("get", "job/"+name, "--output=json")...); err != nil {
+			return failure.New(failure.TransientInfra, fmt.Errorf("failed to get status of Job %s: %w", name, err))
+		}
+		var job struct {
+			Status struct {
+				Conditions []struct {
+					Type    string `json:"type"`
+					Status  string `json:"status"`
+					Reason  string `json:"reason"`
+					Message string `json:"message"`
+				} `json:"conditions"`
+			} `json:"status"`
+		}
+		if err := json.Unmarshal(out.Bytes(), &job); err != nil {
+			return failure.New(failure.TransientInfra, fmt.Errorf("failed to parse status of Job %s: %w", name, err))
+		}
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != "True" {
+				continue
+			}
+			switch condition.Type {
+			case "Complete":
+				return nil
+			case "Failed":
+				err := fmt.Errorf("job %s failed: %s: %s", name, condition.Reason, condition.Message)
+				if condition.Reason == "DeadlineExceeded" {
+					err = fmt.Errorf("%s container of library %q timed out after %s: %w", command, libraryID, timeout, err)
+				}
+				return failure.New(failure.ContainerFailure, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+// deleteJob deletes the Job with the given name, its pods, and its secret if
+// any.
+func (c *Docker) deleteJob(name string, secret bool) {
+	args := []string{"delete", "job/" + name}
+	if secret {
+		args = append(args, "secret/"+name)
+	}
+	args = append(args, "--ignore-not-found", "--cascade=background", "--wait=false")
+	if err := c.kubectl(nil, io.Discard, nil, c.kubectlArgs(args...)...); err != nil {
+		slog.Warn("failed to delete Job", "job", name, "err", err)
+	}
+}
+
+// jobManifest returns the Job running command for the library with ID
+// libraryID, and the Secret holding the secret values of env, or nil if there
+// are none.
+func (c *Docker) jobManifest(name string, command Command, libraryID string, mounts []*jobMount, env []*config.EnvironmentVariable, sandbox *config.ContainerSandbox, limits *config.ResourceLimits, commandArgs []string) (job, secret *k8sObject, err error) {
+	container := &k8sContainer{
+		Name:            jobContainerName,
+		Image:           c.imageFor(command, libraryID),
+		ImagePullPolicy: "IfNotPresent",
+		Args:            append([]string{string(command)}, commandArgs...),
+		Resources:       jobResources(c.Kubernetes.Requests, limits),
+	}
+	if c.Local {
+		container.ImagePullPolicy = "Never"
+	}
+	for _, variable := range env {
+		if variable.SecretEnv == "" {
+			container.Env = append(container.Env, &k8sEnvVar{Name: variable.Name, Value: variable.Value})
+			continue
+		}
+		value, ok := os.LookupEnv(variable.SecretEnv)
+		if !ok {
+			return nil, nil, fmt.Errorf("environment variable %s for secret %s is not set", variable.SecretEnv, variable.Name)
+		}
+		logging.AddSecret(value)
+		if secret == nil {
+			secret = &k8sObject{
+				APIVersion: "v1",
+				Kind:       "Secret",
+				Metadata:   k8sMetadata{Name: name},
+				StringData: map[string]string{},
+			}
+		}
+		secret.StringData[variable.Name] = value
+		container.Env = append(container.Env, &k8sEnvVar{
+			Name:      variable.Name,
+			ValueFrom: &k8sEnvSource{SecretKeyRef: &k8sSecretKeyRef{Name: name, Key: variable.Name}},
+		})
+	}
+
+	pod := &podSpec{
+		RestartPolicy:      "Never",
+		ServiceAccountName: c.Kubernetes.ServiceAccount,
+		SecurityContext:    c.podSecurityContext(),
+		Containers:         []*k8sContainer{container},
+	}
+	labels := map[string]string{}
+	if sandbox != nil {
+		if sandbox.Network == "none" {
+			labels[networkLabel] = "none"
+		}
+		if sandbox.ReadOnly || sandbox.DropCapabilities {
+			container.SecurityContext = &containerSecurityContext{ReadOnlyRootFilesystem: sandbox.ReadOnly}
+			if sandbox.DropCapabilities {
+				noEscalation := false
+				container.SecurityContext.AllowPrivilegeEscalation = &noEscalation
+				container.SecurityContext.Capabilities = &k8sCapabilities{Drop: []string{"ALL"}}
+			}
+		}
+		for i, dir := range sandbox.Tmpfs {
+			volume := fmt.Sprintf("tmpfs-%d", i)
+			pod.Volumes = append(pod.Volumes, &k8sVolume{Name: volume, EmptyDir: &k8sEmptyDir{Medium: "Memory"}})
+			container.VolumeMounts = append(container.VolumeMounts, &k8sVolumeMount{Name: volume, MountPath: dir})
+		}
+	}
+
+	if volume := c.Kubernetes.Volume; volume != nil {
+		pod.Volumes = append(pod.Volumes, &k8sVolume{Name: "work", PersistentVolumeClaim: &k8sClaimSource{ClaimName: volume.Claim}})
+		for _, mount := range mounts {
+			rel, err := filepath.Rel(volume.MountPath, mount.hostDir)
+			if err != nil || !filepath.IsLocal(rel) {
+				return nil, nil, failure.New(failure.UserConfig, fmt.Errorf("directory %s mounted into the container is not on the Kubernetes volume %s mounted at %s", mount.hostDir, volume.Claim, volume.MountPath))
+			}
+			volumeMount := &k8sVolumeMount{Name: "work", MountPath: mount.containerDir, ReadOnly: mount.readOnly}
+			if rel != "." {
+				volumeMount.SubPath = filepath.ToSlash(rel)
+			}
+			container.VolumeMounts = append(container.VolumeMounts, volumeMount)
+		}
+	} else if err := c.addStorageContainers(pod, name, mounts); err != nil {
+		return nil, nil, err
+	}
+
+	var deadline int64
+	if timeout := limits.TimeoutDuration(); timeout > 0 {
+		deadline = int64((timeout + time.Second - 1) / time.Second)
+	}
+	job = &k8sObject{
+		APIVersion: "batch/v1",
+		Kind:       "Job",
+		Metadata:   k8sMetadata{Name: name},
+		Spec: &jobSpec{
+			ActiveDeadlineSeconds: deadline,
+			Template: podTemplate{
+				Metadata: k8sMetadata{Labels: labels},
+				Spec:     pod,
+			},
+		},
+	}
+	return job, secret, nil
+}
+
+// addStorageContainers adds the containers which sync the directories of
+// mounts with Cloud Storage to pod, whose only container is the language
+// container. The directories are downloaded into empty volumes by an init
+// container before the language container runs, so the language container
+// becomes an init container too, and the writable directories are uploaded
+// by the main container after it succeeds.
+func (c *Docker) addStorageContainers(pod *podSpec, name string, mounts []*jobMount) error {
+	bucket, prefix, err := gcs.ParseURL(c.Kubernetes.Storage)
+	if err != nil {
+		return failure.New(failure.UserConfig, err)
+	}
+	container := pod.Containers[0]
+	download := []string{"set -e"}
+	upload := []string{"set -e"}
+	var syncMounts []*k8sVolumeMount
+	for i, mount := range mounts {
+		volume := fmt.Sprintf("mount-%d", i)
+		syncDir := fmt.Sprintf("%s/%d", storageSyncDir, i)
+		pod.Volumes = append(pod.Volumes, &k8sVolume{Name: volume, EmptyDir: &k8sEmptyDir{}})
+		container.VolumeMounts = append(container.VolumeMounts, &k8sVolumeMount{Name: volume, MountPath: mount.containerDir, ReadOnly: mount.readOnly})
+		syncMounts = append(syncMounts, &k8sVolumeMount{Name: volume, MountPath: syncDir})
+		input := fmt.Sprintf("gs://%s/%s", bucket, storageObject(prefix, name, "input", i))
+		download = append(download, fmt.Sprintf("gcloud storage cat %s | tar -xzf - --no-same-owner -C %s", shellQuote(input), syncDir))
+		if !mount.readOnly {
+			output := fmt.Sprintf("gs://%s/%s", bucket, storageObject(prefix, name, "output", i))
+			upload = append(upload, fmt.Sprintf("tar -czf - -C %s . | gcloud storage cp - %s", syncDir, shellQuote(output)))
+		}
+	}
+	image := cmp.Or(c.Kubernetes.StorageImage, defaultStorageImage)
+	// gcloud writes its configuration, which the user of the pod may not
+	// be able to write to the home directory.
+	storageEnv := []*k8sEnvVar{{Name: "CLOUDSDK_CONFIG", Value: "/tmp/gcloud"}}
+	pod.InitContainers = []*k8sContainer{
+		{
+			Name:         "download",
+			Image:        image,
+			Command:      []string{"sh", "-c", strings.Join(download, "\n")},
+			Env:          storageEnv,
+			VolumeMounts: syncMounts,
+		},
+		container,
+	}
+	pod.Containers = []*k8sContainer{
+		{
+			Name:         "upload",
+			Image:        image,
+			Command:      []string{"sh", "-c", strings.Join(upload, "\n")},
+			Env:          storageEnv,
+			VolumeMounts: syncMounts,
+		},
+	}
+	return nil
+}
+
+// storageObject returns the name of the Cloud Storage object holding the
+// input or output tarball of the i-th mount of the Job with the given name.
+func storageObject(prefix, job, kind string, i int) string {
+	return path.Join(prefix, job, fmt.Sprintf("%s-%d.tar.gz", kind, i))
+}
+
+// storageClient returns the Cloud Storage client of c, creating it on first
+// use.
+func (c *Docker) storageClient() (objectStorage, error) {
+	c.storageMu.Lock()
+	defer c.storageMu.Unlock()
+	if c.storage == nil {
+		client, err := gcs.NewClient()
+		if err != nil {
+			return nil, failure.New(failure.UserConfig, err)
+		}
+		c.storage = client
+	}
+	return c.storage, nil
+}
+
+// uploadMounts uploads the directories of mounts to Cloud Storage, as the
+// inputs of the Job with the given name.
+func (c *Docker) uploadMounts(ctx context.Context, name string, mounts []*jobMount) error {
+	storage, err := c.storageClient()
+	if err != nil {
+		return err
+	}
+	// The storage location is checked by jobManifest.
+	bucket, prefix, _ := gcs.ParseURL(c.Kubernetes.Storage)
+	for i, mount := range mounts {
+		if err := os.MkdirAll(mount.hostDir, 0755); err != nil {
+			return err
+		}
+		r, w := io.Pipe()
+		go func() {
+			w.CloseWithError(writeTarGz(w, mount.hostDir))
+		}()
+		err := storage.Upload(ctx, bucket, storageObject(prefix, name, "input", i), r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("failed to upload %s for Job %s: %w", mount.hostDir, name, err)
+		}
+	}
+	return nil
+}
+
+// downloadMounts replaces the writable directories of mounts with the outputs
+// of the Job with the given name in Cloud Storage.
+func (c *Docker) downloadMounts(ctx context.Context, name string, mounts []*jobMount) error {
+	storage, err := c.storageClient()
+	if err != nil {
+		return err
+	}
+	bucket, prefix, _ := gcs.ParseURL(c.Kubernetes.Storage)
+	// A directory nested in another mounted directory, e.g. /librarian in
+	// /repo, is synced separately, so it replaces its copy in the parent.
+	order := make([]int, len(mounts))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return len(mounts[a].hostDir) - len(mounts[b].hostDir)
+	})
+	for _, i := range order {
+		mount := mounts[i]
+		if mount.readOnly {
+			continue
+		}
+		data, err := storage.Download(ctx, bucket, storageObject(prefix, name, "output", i))
+		if err != nil {
+			return fmt.Errorf("failed to download %s of Job %s: %w", mount.hostDir, name, err)
+		}
+		if err := removeContents(mount.hostDir); err != nil {
+			return err
+		}
+		if err := extractTarGz(bytes.NewReader(data), mount.hostDir); err != nil {
+			return fmt.Errorf("failed to extract %s of Job %s: %w", mount.hostDir, name, err)
+		}
+	}
+	return nil
+}
+
+// deleteObjects deletes the inputs and outputs of the Job with the given name
+// from Cloud Storage.
+func (c *Docker) deleteObjects(ctx context.Context, name string, mounts []*jobMount) {
+	bucket, prefix, _ := gcs.ParseURL(c.Kubernetes.Storage)
+	for i, mount := range mounts {
+		objects := []string{storageObject(prefix, name, "input", i)}
+		if !mount.readOnly {
+			objects = append(objects, storageObject(prefix, name, "output", i))
+		}
+		for _, object := range objects {
+			if err := c.storage.Delete(ctx, bucket, object); err != nil {
+				// Outputs are missing if the Job failed.
+				slog.Debug("failed to delete object of Job", "job", name, "object", object, "err", err)
+			}
+		}
+	}
+}
+
+// podSecurityContext returns the security context running the pods as the
+// current user, so that the files they write are owned by the user.
+func (c *Docker) podSecurityContext() *podSecurityContext {
+	uid, err := strconv.ParseInt(c.uid, 10, 64)
+	if err != nil {
+		return nil
+	}
+	context := &podSecurityContext{RunAsUser: &uid}
+	if gid, err := strconv.ParseInt(c.gid, 10, 64); err == nil {
+		context.RunAsGroup = &gid
+	}
+	return context
+}
+
+// jobResources returns the resources of the language container: the
+// requests, which may be nil, and limits, which may be nil too.
+func jobResources(requests *config.ResourceRequests, limits *config.ResourceLimits) *k8sResources {
+	resources := &k8sResources{}
+	if requests != nil {
+		resources.Requests = quantities(requests.CPU, requests.Memory)
+	}
+	if limits != nil {
+		resources.Limits = quantities(limits.CPUs, kubernetesMemory(limits.Memory))
+	}
+	if resources.Requests == nil && resources.Limits == nil {
+		return nil
+	}
+	return resources
+}
+
+// quantities returns the non-empty cpu and memory quantities, or nil if both
+// are empty.
+func quantities(cpu, memory string) map[string]string {
+	if cpu == "" && memory == "" {
+		return nil
+	}
+	q := map[string]string{}
+	if cpu != "" {
+		q["cpu"] = cpu
+	}
+	if memory != "" {
+		q["memory"] = memory
+	}
+	return q
+}
+
+// kubernetesMemory converts a docker memory limit, e.g. "4g", to a Kubernetes
+// quantity, e.g. "4Gi".
+func kubernetesMemory(memory string) string {
+	if memory == "" {
+		return ""
+	}
+	units := map[byte]string{'b': "", 'k': "Ki", 'm': "Mi", 'g': "Gi"}
+	last := memory[len(memory)-1] | 0x20
+	if unit, ok := units[last]; ok {
+		return memory[:len(memory)-1] + unit
+	}
+	return memory
+}
+
+// shellQuote quotes arg for a POSIX shell, if needed.
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsFunc(arg, func(r rune) bool {
+		return !strings.ContainsRune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=,@%+", r)
+	}) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// writeTarGz writes the content of dir to w as a gzipped tarball.
+func writeTarGz(w io.Writer, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// extractTarGz extracts the gzipped tarball r into dir. The entries are
+// written through an os.Root of dir, so that no entry is written outside of
+// dir, even through a symlink extracted before it, and symlinks must point to
+// a path in dir.
+func extractTarGz(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(header.Name)
+		if name == "." {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return fmt.Errorf("invalid path %q in tarball", header.Name)
+		}
+		target := filepath.FromSlash(name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := root.MkdirAll(target, header.FileInfo().Mode().Perm()|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := root.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := root.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, header.FileInfo().Mode().Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			link := filepath.FromSlash(header.Linkname)
+			if filepath.IsAbs(link) || !filepath.IsLocal(filepath.Join(filepath.Dir(target), link)) {
+				return fmt.Errorf("invalid symlink %q to %q in tarball", header.Name, header.Linkname)
+			}
+			if err := root.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := root.Symlink(link, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// removeContents removes everything in dir, but not dir itself.
+func removeContents(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return os.MkdirAll(dir, 0755)
+}
+
+// The subset of the Kubernetes API objects which librarian creates. The
+// fields of all kinds are in k8sObject, as only one of them is set per kind.
+type k8sObject struct {
+	APIVersion string      `json:"apiVersion"`
+	Kind       string      `json:"kind"`
+	Metadata   k8sMetadata `json:"metadata"`
+	// StringData is the data of a Secret.
+	StringData map[string]string `json:"stringData,omitempty"`
+	// Spec is the spec of a Job.
+	Spec *jobSpec `json:"spec,omitempty"`
+	// Items are the objects of a List.
+	Items []*k8sObject `json:"items,omitempty"`
+}
+
+type k8sMetadata struct {
+	Name   string            `json:"name,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type jobSpec struct {
+	// BackoffLimit is always zero: failed runs are not retried.
+	BackoffLimit          int         `json:"backoffLimit"`
+	ActiveDeadlineSeconds int64       `json:"activeDeadlineSeconds,omitempty"`
+	Template              podTemplate `json:"template"`
+}
+
+type podTemplate struct {
+	Metadata k8sMetadata `json:"metadata"`
+	Spec     *podSpec    `json:"spec"`
+}
+
+type podSpec struct {
+	RestartPolicy      string              `json:"restartPolicy"`
+	ServiceAccountName string              `json:"serviceAccountName,omitempty"`
+	SecurityContext    *podSecurityContext `json:"securityContext,omitempty"`
+	InitContainers     []*k8sContainer     `json:"initContainers,omitempty"`
+	Containers         []*k8sContainer     `json:"containers"`
+	Volumes            []*k8sVolume        `json:"volumes,omitempty"`
+}
+
+type podSecurityContext struct {
+	RunAsUser  *int64 `json:"runAsUser,omitempty"`
+	RunAsGroup *int64 `json:"runAsGroup,omitempty"`
+}
+
+type k8sContainer struct {
+	Name            string                    `json:"name"`
+	Image           string                    `json:"image"`
+	ImagePullPolicy string                    `json:"imagePullPolicy,omitempty"`
+	Command         []string                  `json:"command,omitempty"`
+	Args            []string                  `json:"args,omitempty"`
+	Env             []*k8sEnvVar              `json:"env,omitempty"`
+	Resources       *k8sResources             `json:"resources,omitempty"`
+	SecurityContext *containerSecurityContext `json:"securityContext,omitempty"`
+	VolumeMounts    []*k8sVolumeMount         `json:"volumeMounts,omitempty"`
+}
+
+type k8sEnvVar struct {
+	Name      string        `json:"name"`
+	Value     string        `json:"value,omitempty"`
+	ValueFrom *k8sEnvSource `json:"valueFrom,omitempty"`
+}
+
+type k8sEnvSource struct {
+	SecretKeyRef *k8sSecretKeyRef `json:"secretKeyRef"`
+}
+
+type k8sSecretKeyRef struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+type k8sResources struct {
+	Requests map[string]string `json:"requests,omitempty"`
+	Limits   map[string]string `json:"limits,omitempty"`
+}
+
+type containerSecurityContext struct {
+	ReadOnlyRootFilesystem   bool             `json:"readOnlyRootFilesystem,omitempty"`
+	AllowPrivilegeEscalation *bool            `json:"allowPrivilegeEscalation,omitempty"`
+	Capabilities             *k8sCapabilities `json:"capabilities,omitempty"`
+}
+
+type k8sCapabilities struct {
+	Drop []string `json:"drop"`
+}
+
+type k8sVolume struct {
+	Name                  string          `json:"name"`
+	PersistentVolumeClaim *k8sClaimSource `json:"persistentVolumeClaim,omitempty"`
+	EmptyDir              *k8sEmptyDir    `json:"emptyDir,omitempty"`
+}
+
+type k8sClaimSource struct {
+	ClaimName string `json:"claimName"`
+}
+
+type k8sEmptyDir struct {
+	Medium string `json:"medium,omitempty"`
+}
+
+type k8sVolumeMount struct {
+	Name      string `json:"name"`
+	MountPath string `json:"mountPath"`
+	SubPath   string `json:"subPath,omitempty"`
+	ReadOnly  bool   `json:"readOnly,omitempty"`
+}
@@ -0,0 +1,422 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+// fakeKubectl simulates kubectl and a cluster which runs each Job created to
+// the given condition.
+type fakeKubectl struct {
+	condition string
+	reason    string
+	onCreate  func(list *k8sObject)
+	calls     [][]string
+	created   *k8sObject
+}
+
+func (f *fakeKubectl) run(stdin io.Reader, stdout, _ io.Writer, args ...string) error {
+	f.calls = append(f.calls, args)
+	verb := args[0]
+	if strings.HasPrefix(verb, "--namespace=") {
+		verb = args[1]
+	}
+	switch verb {
+	case "create":
+		f.created = &k8sObject{}
+		if err := json.NewDecoder(stdin).Decode(f.created); err != nil {
+			return err
+		}
+		if f.onCreate != nil {
+			f.onCreate(f.created)
+		}
+	case "logs":
+		if stdout != nil {
+			fmt.Fprintln(stdout, "running in the cluster")
+		}
+	case "get":
+		fmt.Fprintf(stdout, `{"status": {"conditions": [{"type": %q, "status": "True", "reason": %q}]}}`, f.condition, f.reason)
+	}
+	return nil
+}
+
+// job returns the Job of the created objects.
+func (f *fakeKubectl) job(t *testing.T) *k8sObject {
+	t.Helper()
+	if f.created == nil {
+		t.Fatal("no Job was created")
+	}
+	return f.created.Items[len(f.created.Items)-1]
+}
+
+// fakeStorage is an in-memory Cloud Storage bucket.
+type fakeStorage map[string][]byte
+
+func (s fakeStorage) Upload(_ context.Context, bucket, name string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	s[bucket+"/"+name] = data
+	return err
+}
+
+func (s fakeStorage) Download(_ context.Context, bucket, name string) ([]byte, error) {
+	data, ok := s[bucket+"/"+name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return data, nil
+}
+
+func (s fakeStorage) Delete(_ context.Context, bucket, name string) error {
+	delete(s, bucket+"/"+name)
+	return nil
+}
+
+func TestRunJob_Volume(t *testing.T) {
+	root := t.TempDir()
+	repoDir := filepath.Join(root, "repo")
+	if err := os.MkdirAll(filepath.Join(repoDir, config.LibrarianDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_TOKEN", "secret value")
+	kubectl := &fakeKubectl{condition: "Complete"}
+	d := &Docker{
+		Image: "testImage",
+		uid:   "1000",
+		gid:   "1001",
+		Kubernetes: &config.Kubernetes{
+			Namespace:      "librarian",
+			ServiceAccount: "generator",
+			Requests:       &config.ResourceRequests{CPU: "2", Memory: "8Gi"},
+			Volume:         &config.KubernetesVolume{Claim: "work", MountPath: root},
+		},
+		kubectl: kubectl.run,
+		LogDir:  t.TempDir(),
+	}
+	err := d.Build(t.Context(), &BuildRequest{
+		Cfg: &config.Config{},
+		LibrarianConfig: &config.LibrarianConfig{
+			Environment:     []*config.EnvironmentVariable{{Name: "TOKEN", SecretEnv: "TEST_TOKEN"}},
+			ContainerLimits: &config.ContainerLimits{ResourceLimits: config.ResourceLimits{Memory: "4g", Timeout: "30m"}},
+			Sandbox:         &config.ContainerSandbox{Network: "none", ReadOnly: true, Tmpfs: []string{"/tmp"}},
+		},
+		State:     &config.LibrarianState{},
+		LibraryID: "a",
+		RepoDir:   repoDir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := kubectl.created.Items[0]
+	if diff := cmp.Diff(map[string]string{"TOKEN": "secret value"}, secret.StringData); diff != "" {
+		t.Errorf("secret mismatch (-want +got):\n%s", diff)
+	}
+	job := kubectl.job(t)
+	name := job.Metadata.Name
+	if job.Spec.ActiveDeadlineSeconds != 1800 {
+		t.Errorf("activeDeadlineSeconds = %d, want 1800", job.Spec.ActiveDeadlineSeconds)
+	}
+	if diff := cmp.Diff(map[string]string{networkLabel: "none"}, job.Spec.Template.Metadata.Labels); diff != "" {
+		t.Errorf("labels mismatch (-want +got):\n%s", diff)
+	}
+	pod := job.Spec.Template.Spec
+	if pod.ServiceAccountName != "generator" || *pod.SecurityContext.RunAsUser != 1000 || *pod.SecurityContext.RunAsGroup != 1001 {
+		t.Errorf("pod = %+v, want service account generator running as 1000:1001", pod)
+	}
+	container := pod.Containers[0]
+	want := &k8sContainer{
+		Name:            jobContainerName,
+		Image:           "testImage",
+		ImagePullPolicy: "IfNotPresent",
+		Args:            []string{"build", "--librarian=/librarian", "--repo=/repo"},
+		Env: []*k8sEnvVar{{
+			Name:      "TOKEN",
+			ValueFrom: &k8sEnvSource{SecretKeyRef: &k8sSecretKeyRef{Name: name, Key: "TOKEN"}},
+		}},
+		Resources: &k8sResources{
+			Requests: map[string]string{"cpu": "2", "memory": "8Gi"},
+			Limits:   map[string]string{"memory": "4Gi"},
+		},
+		SecurityContext: &containerSecurityContext{ReadOnlyRootFilesystem: true},
+		VolumeMounts: []*k8sVolumeMount{
+			{Name: "tmpfs-0", MountPath: "/tmp"},
+			{Name: "work", MountPath: "/librarian", SubPath: "repo/.librarian"},
+			{Name: "work", MountPath: "/repo", SubPath: "repo"},
+		},
+	}
+	if diff := cmp.Diff(want, container); diff != "" {
+		t.Errorf("container mismatch (-want +got):\n%s", diff)
+	}
+
+	wantCalls := [][]string{
+		{"--namespace=librarian", "create", "--filename=-"},
+		{"--namespace=librarian", "logs", "job/" + name, "--container=librarian", "--follow", "--pod-running-timeout=15m"},
+		{"--namespace=librarian", "get", "job/" + name, "--output=json"},
+		{"--namespace=librarian", "delete", "job/" + name, "secret/" + name, "--ignore-not-found", "--cascade=background", "--wait=false"},
+	}
+	if diff := cmp.Diff(wantCalls, kubectl.calls); diff != "" {
+		t.Errorf("kubectl calls mismatch (-want +got):\n%s", diff)
+	}
+	matches, err := filepath.Glob(filepath.Join(d.LogDir, "*-build-a.stdout.log"))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("log files = %v, %v, want one stdout log", matches, err)
+	}
+	logs, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(logs), "running in the cluster") {
+		t.Errorf("logs = %q, want the output of the Job", logs)
+	}
+}
+
+func TestRunJob_NotOnVolume(t *testing.T) {
+	kubectl := &fakeKubectl{condition: "Complete"}
+	d := &Docker{
+		Image:      "testImage",
+		Kubernetes: &config.Kubernetes{Volume: &config.KubernetesVolume{Claim: "work", MountPath: t.TempDir()}},
+		kubectl:    kubectl.run,
+	}
+	err := d.runDocker(t.Context(), &config.Config{}, CommandBuild, "a", []string{t.TempDir() + ":/repo"}, nil, nil, nil, nil)
+	if failure.CategoryOf(err) != failure.UserConfig {
+		t.Errorf("runDocker() error = %v, want a %s error", err, failure.UserConfig)
+	}
+	if len(kubectl.calls) != 0 {
+		t.Errorf("kubectl calls = %v, want none", kubectl.calls)
+	}
+}
+
+func TestRunJob_Failed(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		reason  string
+		wantErr string
+	}{
+		{
+			name:    "failed",
+			reason:  "BackoffLimitExceeded",
+			wantErr: "failed: BackoffLimitExceeded",
+		},
+		{
+			name:    "timed out",
+			reason:  "DeadlineExceeded",
+			wantErr: `build container of library "a" timed out after 10m0s`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			kubectl := &fakeKubectl{condition: "Failed", reason: test.reason}
+			d := &Docker{
+				Image:      "testImage",
+				Kubernetes: &config.Kubernetes{Volume: &config.KubernetesVolume{Claim: "work", MountPath: root}},
+				kubectl:    kubectl.run,
+			}
+			limits := &config.ResourceLimits{Timeout: "10m"}
+			err := d.runDocker(t.Context(), &config.Config{}, CommandBuild, "a", []string{root + ":/repo"}, nil, nil, limits, nil)
+			if failure.CategoryOf(err) != failure.ContainerFailure || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("runDocker() error = %v, want a %s error containing %q", err, failure.ContainerFailure, test.wantErr)
+			}
+			if last := kubectl.calls[len(kubectl.calls)-1]; last[0] != "delete" {
+				t.Errorf("last kubectl call = %v, want the Job deleted", last)
+			}
+		})
+	}
+}
+
+func TestRunJob_Storage(t *testing.T) {
+	sourceDir := t.TempDir()
+	outputDir := t.TempDir()
+	writeFiles(t, sourceDir, map[string]string{"a/api.proto": `syntax = "proto3";`})
+	writeFiles(t, outputDir, map[string]string{"stale.txt": "removed by the run"})
+	storage := fakeStorage{}
+	kubectl := &fakeKubectl{condition: "Complete"}
+	kubectl.onCreate = func(list *k8sObject) {
+		// The Job reads the inputs and writes the outputs, as the storage
+		// containers would.
+		name := list.Items[0].Metadata.Name
+		input := t.TempDir()
+		if err := extractTarGz(bytes.NewReader(storage["bucket/runs/"+name+"/input-0.tar.gz"]), input); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(input, "a", "api.proto")); err != nil {
+			t.Errorf("input of the Job is missing the source: %v", err)
+		}
+		output := t.TempDir()
+		writeFiles(t, output, map[string]string{"lib/client.go": "package lib"})
+		var buf bytes.Buffer
+		if err := writeTarGz(&buf, output); err != nil {
+			t.Fatal(err)
+		}
+		storage["bucket/runs/"+name+"/output-1.tar.gz"] = buf.Bytes()
+	}
+	d := &Docker{
+		Image:      "testImage",
+		Kubernetes: &config.Kubernetes{Storage: "gs://bucket/runs"},
+		kubectl:    kubectl.run,
+		storage:    storage,
+	}
+	mounts := []string{sourceDir + ":/source:ro", outputDir + ":/output"}
+	if err := d.runDocker(t.Context(), &config.Config{}, CommandGenerate, "a", mounts, nil, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	pod := kubectl.job(t).Spec.Template.Spec
+	var got []string
+	for _, container := range pod.InitContainers {
+		got = append(got, container.Name)
+	}
+	for _, container := range pod.Containers {
+		got = append(got, container.Name)
+	}
+	if diff := cmp.Diff([]string{"download", jobContainerName, "upload"}, got); diff != "" {
+		t.Errorf("containers mismatch (-want +got):\n%s", diff)
+	}
+	if upload := pod.Containers[0].Command[2]; strings.Contains(upload, "output-0") || !strings.Contains(upload, "gs://bucket/runs/") {
+		t.Errorf("upload script = %q, want only the writable mount uploaded", upload)
+	}
+
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "lib" {
+		t.Errorf("output directory = %v, want only the output of the Job", entries)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "lib", "client.go")); err != nil {
+		t.Error(err)
+	}
+	if len(storage) != 0 {
+		t.Errorf("objects left in storage: %v", storage)
+	}
+}
+
+func TestExtractTarGz_Symlinks(t *testing.T) {
+	type entry struct {
+		name     string
+		typeflag byte
+		linkname string
+	}
+	for _, test := range []struct {
+		name    string
+		entries []entry
+		// outsideLink is whether dir has a symlink to a directory outside of
+		// it before the extraction.
+		outsideLink bool
+		wantErr     bool
+	}{
+		{
+			name: "symlink in dir",
+			entries: []entry{
+				{name: "a/file.txt", typeflag: tar.TypeReg},
+				{name: "b/link", typeflag: tar.TypeSymlink, linkname: "../a/file.txt"},
+			},
+		},
+		{
+			name:    "absolute symlink",
+			entries: []entry{{name: "link", typeflag: tar.TypeSymlink, linkname: "/etc"}},
+			wantErr: true,
+		},
+		{
+			name:    "symlink out of dir",
+			entries: []entry{{name: "a/link", typeflag: tar.TypeSymlink, linkname: "../../outside"}},
+			wantErr: true,
+		},
+		{
+			name: "file through symlink",
+			entries: []entry{
+				{name: "a", typeflag: tar.TypeDir},
+				{name: "link", typeflag: tar.TypeSymlink, linkname: "a"},
+				{name: "link/file.txt", typeflag: tar.TypeReg},
+			},
+		},
+		{
+			name:        "file through existing symlink out of dir",
+			entries:     []entry{{name: "link/file.txt", typeflag: tar.TypeReg}},
+			outsideLink: true,
+			wantErr:     true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			tw := tar.NewWriter(gz)
+			for _, e := range test.entries {
+				header := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0755}
+				if err := tw.WriteHeader(header); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := gz.Close(); err != nil {
+				t.Fatal(err)
+			}
+			parent := t.TempDir()
+			dir := filepath.Join(parent, "dir")
+			if err := os.Mkdir(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if test.outsideLink {
+				if err := os.Mkdir(filepath.Join(parent, "outside"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(filepath.Join(parent, "outside"), filepath.Join(dir, "link")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := extractTarGz(&buf, dir)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("extractTarGz() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if _, err := os.Lstat(filepath.Join(parent, "outside", "file.txt")); err == nil {
+				t.Errorf("extractTarGz() wrote outside of %s", dir)
+			}
+		})
+	}
+}
+
+func TestKubernetesMemory(t *testing.T) {
+	for _, test := range []struct {
+		memory string
+		want   string
+	}{
+		{memory: "", want: ""},
+		{memory: "1024", want: "1024"},
+		{memory: "512b", want: "512"},
+		{memory: "512k", want: "512Ki"},
+		{memory: "512M", want: "512Mi"},
+		{memory: "4g", want: "4Gi"},
+	} {
+		if got := kubernetesMemory(test.memory); got != test.want {
+			t.Errorf("kubernetesMemory(%q) = %q, want %q", test.memory, got, test.want)
+		}
+	}
+}
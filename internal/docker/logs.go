@@ -0,0 +1,148 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogsDir is the directory of the work root into which the output of
+// container runs is written.
+const LogsDir = "logs"
+
+// now returns the current time. It is a variable so it can be replaced during
+// testing.
+var now = time.Now
+
+// containerLogs holds the log files of the standard output and error of a
+// container run. The output is also logged line by line with slog.
+type containerLogs struct {
+	stdoutPath string
+	stderrPath string
+	stdout     *lineWriter
+	stderr     *lineWriter
+	files      []*os.File
+}
+
+// openLogs creates the log files of a container run in dir. The files are
+// named after the time of the run, the command and the library, e.g.
+// "20250102T030405.678Z-generate-google-cloud-foo.stdout.log".
+func openLogs(dir string, command Command, libraryID string, level slog.Level) (*containerLogs, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to make log directory: %w", err)
+	}
+	name := fmt.Sprintf("%s-%s", now().UTC().Format("20060102T150405.000Z"), command)
+	if libraryID != "" {
+		name += "-" + libraryFileName(libraryID)
+	}
+	logs := &containerLogs{}
+	for _, stream := range []string{"stdout", "stderr"} {
+		path := filepath.Join(dir, fmt.Sprintf("%s.%s.log", name, stream))
+		f, err := os.Create(path)
+		if err != nil {
+			logs.close()
+			return nil, fmt.Errorf("failed to create log file: %w", err)
+		}
+		logs.files = append(logs.files, f)
+		w := &lineWriter{
+			w:     f,
+			level: level,
+			attrs: []any{"command", command, "library", libraryID, "stream", stream},
+		}
+		if stream == "stdout" {
+			logs.stdoutPath, logs.stdout = path, w
+		} else {
+			logs.stderrPath, logs.stderr = path, w
+		}
+	}
+	return logs, nil
+}
+
+// paths returns the paths of the log files.
+func (l *containerLogs) paths() []string {
+	return []string{l.stdoutPath, l.stderrPath}
+}
+
+// close logs any incomplete last lines and closes the log files.
+func (l *containerLogs) close() error {
+	var errs []error
+	for _, w := range []*lineWriter{l.stdout, l.stderr} {
+		if w != nil {
+			w.flush()
+		}
+	}
+	for _, f := range l.files {
+		errs = append(errs, f.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// lineWriter writes to w, and logs each complete line written at level.
+type lineWriter struct {
+	w     io.Writer
+	level slog.Level
+	attrs []any
+	buf   []byte
+}
+
+// Write writes p to the underlying writer, and logs the lines it completes.
+func (l *lineWriter) Write(p []byte) (int, error) {
+	n, err := l.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		l.log(l.buf[:i])
+		l.buf = l.buf[i+1:]
+	}
+	return n, nil
+}
+
+// flush logs the remaining incomplete line, if any.
+func (l *lineWriter) flush() {
+	if len(l.buf) > 0 {
+		l.log(l.buf)
+		l.buf = nil
+	}
+}
+
+func (l *lineWriter) log(line []byte) {
+	slog.Log(context.Background(), l.level, string(bytes.TrimRight(line, "\r")), l.attrs...)
+}
+
+// libraryFileName returns libraryID with the characters which are not
+// allowed in file names replaced.
+func libraryFileName(libraryID string) string {
+	b := []byte(libraryID)
+	for i, c := range b {
+		if c == '/' || c == '\\' {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
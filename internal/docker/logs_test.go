@@ -0,0 +1,84 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+func TestRunDockerLogs(t *testing.T) {
+	now = func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 678000000, time.UTC) }
+	t.Cleanup(func() { now = time.Now })
+	logDir := filepath.Join(t.TempDir(), LogsDir)
+	d := &Docker{
+		Image:  "testImage",
+		LogDir: logDir,
+		run: func(stdout, stderr io.Writer, args ...string) error {
+			fmt.Fprint(stdout, "generating\ndone")
+			fmt.Fprint(stderr, "warning: deprecated\n")
+			return errors.New("exit status 1")
+		},
+	}
+	err := d.runDocker(t.Context(), &config.Config{}, CommandGenerate, "google/cloud/foo", nil, nil, nil, nil, nil)
+	if err == nil {
+		t.Fatal("runDocker() error = nil, want error")
+	}
+
+	prefix := filepath.Join(logDir, "20250102T030405.678Z-generate-google_cloud_foo")
+	wantLogs := []string{prefix + ".stdout.log", prefix + ".stderr.log"}
+	if diff := cmp.Diff(wantLogs, failure.Logs(err)); diff != "" {
+		t.Errorf("failure.Logs() mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(err.Error(), wantLogs[0]) {
+		t.Errorf("runDocker() error = %q, want it to mention %s", err, wantLogs[0])
+	}
+	for i, want := range []string{"generating\ndone", "warning: deprecated\n"} {
+		got, err := os.ReadFile(wantLogs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", wantLogs[i], diff)
+		}
+	}
+}
+
+func TestRunDockerWithoutLogDir(t *testing.T) {
+	d := &Docker{
+		Image: "testImage",
+		run: func(stdout, stderr io.Writer, args ...string) error {
+			// The standard error is also kept to detect the cause of
+			// failures, see dockerError.
+			if stdout != nil {
+				t.Errorf("run() got stdout %v, want nil", stdout)
+			}
+			return nil
+		},
+	}
+	if err := d.runDocker(t.Context(), &config.Config{}, CommandBuild, "", nil, nil, nil, nil, nil); err != nil {
+		t.Fatalf("runDocker() error = %v", err)
+	}
+}
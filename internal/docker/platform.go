@@ -0,0 +1,162 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+// hostPlatform is the platform of the images which run natively on this
+// machine. Containers run in a Linux VM on other operating systems, such as
+// with Docker Desktop on macOS. It is a variable so it can be replaced during
+// testing.
+var hostPlatform = "linux/" + runtime.GOARCH
+
+// defaultPlatform is the platform selected for images which are not
+// available for hostPlatform, if they are available for it.
+const defaultPlatform = "linux/amd64"
+
+// manifestList is the part of the output of "docker manifest inspect" which
+// lists the platforms of a multi-platform image.
+type manifestList struct {
+	Manifests []struct {
+		Platform *struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+			Variant      string `json:"variant"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+// platformPattern matches the platforms of images, e.g. "linux/arm64" or
+// "linux/arm/v7".
+var platformPattern = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(/[a-z0-9]+)?$`)
+
+// manifestPlatforms returns the platforms, e.g. "linux/arm64", listed in the
+// manifest list of image in its registry, or nil if image is not a
+// multi-platform image or its manifest cannot be inspected.
+func (c *Docker) manifestPlatforms(image string) []string {
+	out, err := c.output("manifest", "inspect", image)
+	if err != nil {
+		slog.Debug("failed to inspect image manifest", "image", image, "err", err)
+		return nil
+	}
+	list := &manifestList{}
+	if err := json.Unmarshal(out, list); err != nil {
+		slog.Debug("failed to parse image manifest", "image", image, "err", err)
+		return nil
+	}
+	var platforms []string
+	for _, manifest := range list.Manifests {
+		p := manifest.Platform
+		// Attestations are listed with the "unknown" platform.
+		if p == nil || p.OS == "" || p.OS == "unknown" {
+			continue
+		}
+		platforms = append(platforms, strings.TrimSuffix(p.OS+"/"+p.Architecture+"/"+p.Variant, "/"))
+	}
+	return platforms
+}
+
+// parsePlatforms returns the platforms in the output of a docker command,
+// ignoring anything else.
+func parsePlatforms(out []byte) []string {
+	var platforms []string
+	for _, field := range strings.Fields(string(out)) {
+		if platformPattern.MatchString(field) {
+			platforms = append(platforms, field)
+		}
+	}
+	return platforms
+}
+
+// selectPlatform returns the platform in platforms which runs natively, or
+// defaultPlatform or the first Linux platform if there is none. It returns an
+// empty string if no platform is a Linux one.
+func selectPlatform(platforms []string) string {
+	if i := slices.IndexFunc(platforms, isNative); i >= 0 {
+		return platforms[i]
+	}
+	if slices.Contains(platforms, defaultPlatform) {
+		return defaultPlatform
+	}
+	for _, platform := range platforms {
+		if strings.HasPrefix(platform, "linux/") {
+			return platform
+		}
+	}
+	return ""
+}
+
+// isNative reports whether images of platform run natively, i.e. without
+// emulation, on this machine. The variant of the platform is ignored.
+func isNative(platform string) bool {
+	parts := strings.Split(platform, "/")
+	return len(parts) >= 2 && parts[0]+"/"+parts[1] == hostPlatform
+}
+
+// resolvePlatform selects the platform to run image with, among the
+// platforms it is available for: Platform if it is set, and otherwise the
+// native platform, if the image is available for it. A warning is logged if
+// the image runs under emulation, which is slow, or an error is returned with
+// RequireNative. Nothing is selected if the platforms are unknown, and docker
+// selects the platform.
+func (c *Docker) resolvePlatform(image string, platforms []string) error {
+	platform := c.Platform
+	if platform == "" {
+		if len(platforms) == 0 {
+			return nil
+		}
+		if platform = selectPlatform(platforms); platform == "" {
+			return failure.New(failure.UserConfig, fmt.Errorf("image %s is not available for Linux, got platforms %v", image, platforms))
+		}
+	}
+	if !isNative(platform) {
+		if c.RequireNative {
+			return failure.New(failure.UserConfig, fmt.Errorf("image %s would run under emulation as %s on this %s machine, which -native-image forbids", image, platform, hostPlatform))
+		}
+		slog.Warn("Image will run under emulation, which is slow; publish it for the platform of this machine", "image", image, "platform", platform, "host", hostPlatform)
+	}
+	c.platformsMu.Lock()
+	defer c.platformsMu.Unlock()
+	if c.platforms == nil {
+		c.platforms = make(map[string]string)
+	}
+	c.platforms[image] = platform
+	return nil
+}
+
+// platformArgs returns the docker arguments which select the platform of
+// image, if any.
+func (c *Docker) platformArgs(image string) []string {
+	c.platformsMu.Lock()
+	platform, ok := c.platforms[image]
+	c.platformsMu.Unlock()
+	if !ok {
+		platform = c.Platform
+	}
+	if platform == "" {
+		return nil
+	}
+	return []string{"--platform", platform}
+}
@@ -0,0 +1,180 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// setHostPlatform replaces hostPlatform for the duration of the test.
+func setHostPlatform(t *testing.T, platform string) {
+	t.Helper()
+	old := hostPlatform
+	hostPlatform = platform
+	t.Cleanup(func() { hostPlatform = old })
+}
+
+func TestSelectPlatform(t *testing.T) {
+	setHostPlatform(t, "linux/arm64")
+	for _, test := range []struct {
+		name      string
+		platforms []string
+		want      string
+	}{
+		{
+			name:      "native",
+			platforms: []string{"linux/amd64", "linux/arm64/v8"},
+			want:      "linux/arm64/v8",
+		},
+		{
+			name:      "default",
+			platforms: []string{"linux/s390x", "linux/amd64"},
+			want:      "linux/amd64",
+		},
+		{
+			name:      "first linux",
+			platforms: []string{"windows/amd64", "linux/s390x"},
+			want:      "linux/s390x",
+		},
+		{
+			name:      "no linux",
+			platforms: []string{"windows/amd64"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := selectPlatform(test.platforms); got != test.want {
+				t.Errorf("selectPlatform(%v) = %q, want %q", test.platforms, got, test.want)
+			}
+		})
+	}
+}
+
+func TestResolvePlatform(t *testing.T) {
+	setHostPlatform(t, "linux/arm64")
+	for _, test := range []struct {
+		name          string
+		platform      string
+		requireNative bool
+		platforms     []string
+		wantArgs      []string
+		wantErrMsg    string
+	}{
+		{
+			name:      "native",
+			platforms: []string{"linux/amd64", "linux/arm64"},
+			wantArgs:  []string{"--platform", "linux/arm64"},
+		},
+		{
+			name:      "emulated",
+			platforms: []string{"linux/amd64"},
+			wantArgs:  []string{"--platform", "linux/amd64"},
+		},
+		{
+			name:          "emulated with native required",
+			requireNative: true,
+			platforms:     []string{"linux/amd64"},
+			wantErrMsg:    "would run under emulation",
+		},
+		{
+			name:      "configured platform",
+			platform:  "linux/amd64",
+			platforms: []string{"linux/amd64", "linux/arm64"},
+			wantArgs:  []string{"--platform", "linux/amd64"},
+		},
+		{
+			name: "unknown platforms",
+		},
+		{
+			name:       "not available for linux",
+			platforms:  []string{"windows/amd64"},
+			wantErrMsg: "not available for Linux",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			d := &Docker{Platform: test.platform, RequireNative: test.requireNative}
+			err := d.resolvePlatform("example.com/image:latest", test.platforms)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Errorf("resolvePlatform() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.wantArgs, d.platformArgs("example.com/image:latest")); diff != "" {
+				t.Errorf("platformArgs() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrewarm_MultiPlatform(t *testing.T) {
+	setHostPlatform(t, "linux/arm64")
+	const manifest = `{
+  "manifests": [
+    {"platform": {"architecture": "amd64", "os": "linux"}},
+    {"platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}},
+    {"platform": {"architecture": "unknown", "os": "unknown"}}
+  ]
+}`
+	var calls [][]string
+	d := &Docker{
+		Image: "example.com/image:latest",
+		run: func(_, _ io.Writer, args ...string) error {
+			calls = append(calls, args)
+			return nil
+		},
+		output: func(args ...string) ([]byte, error) {
+			if args[0] == "manifest" {
+				return []byte(manifest), nil
+			}
+			return []byte("example.com/image@sha256:abc linux/arm64\n"), nil
+		},
+	}
+	if err := d.Prewarm(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"pull", "--quiet", "--platform", "linux/arm64/v8", "example.com/image:latest"}}
+	if diff := cmp.Diff(want, calls); diff != "" {
+		t.Errorf("pull mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPrewarm_SinglePlatform(t *testing.T) {
+	setHostPlatform(t, "linux/arm64")
+	d := &Docker{
+		Image:         "example.com/image:latest",
+		RequireNative: true,
+		run: func(_, _ io.Writer, args ...string) error {
+			return nil
+		},
+		output: func(args ...string) ([]byte, error) {
+			if args[0] == "manifest" {
+				return nil, errors.New("not a manifest list")
+			}
+			return []byte("example.com/image@sha256:abc linux/amd64\n"), nil
+		},
+	}
+	err := d.Prewarm(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "-native-image") {
+		t.Errorf("Prewarm() error = %v, want error about -native-image", err)
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+// defaultRegistry is the registry of images whose reference does not start
+// with a registry host.
+const defaultRegistry = "docker.io"
+
+// MirrorImage rewrites image so that it is pulled through the registry mirror,
+// by replacing the registry host of the image with mirror. The mirror may
+// include a path prefix, e.g. "us-docker.pkg.dev/my-project/docker-remote".
+// The image is returned unchanged if mirror is empty.
+func MirrorImage(image, mirror string) string {
+	if mirror == "" || image == "" {
+		return image
+	}
+	_, path := splitRegistry(image)
+	return strings.TrimSuffix(mirror, "/") + "/" + path
+}
+
+// splitRegistry splits an image reference into the registry host and the
+// remaining path, following the rules used by the docker CLI.
+func splitRegistry(image string) (string, string) {
+	host, path, found := strings.Cut(image, "/")
+	if found && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		return host, path
+	}
+	if !found {
+		// Official images, e.g. "ubuntu:24.04".
+		return defaultRegistry, "library/" + image
+	}
+	return defaultRegistry, image
+}
+
+// imageDigest returns the digest an image reference is pinned to, or an empty
+// string if it is not pinned.
+func imageDigest(image string) string {
+	_, digest, found := strings.Cut(image, "@")
+	if !found {
+		return ""
+	}
+	return digest
+}
+
+// Prewarm pulls the images of c, and any additional images, in parallel so that
+// later container runs do not stall on a pull. Images pinned to a digest are
+// verified against the digest after being pulled. Local images are not pulled,
+// but verified to exist locally, after loading the image archive of c if any.
+// Nothing is pulled when container runs are replayed, or run as Kubernetes
+// Jobs, whose images are pulled by the nodes of the cluster.
+func (c *Docker) Prewarm(ctx context.Context, images ...string) error {
+	if c.ReplayDir != "" || c.Kubernetes != nil {
+		return nil
+	}
+	if c.Archive != "" {
+		if err := c.load(ctx); err != nil {
+			return err
+		}
+	}
+	images = append([]string{c.Image}, images...)
+	for _, image := range c.Images {
+		images = append(images, image)
+	}
+	for _, image := range c.LibraryImages {
+		images = append(images, image)
+	}
+	slices.Sort(images)
+	images = slices.Compact(images)
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(images))
+	for i, image := range images {
+		if image == "" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c.Local {
+				platforms, err := c.verifyLocal(image)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				errs[i] = c.resolvePlatform(image, platforms)
+				return
+			}
+			errs[i] = c.pull(ctx, image)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// pull pulls image for the platform selected for it. The platform of a
+// multi-platform image is selected before pulling it, from the platforms of
+// its manifest list; that of another image once it is pulled.
+func (c *Docker) pull(ctx context.Context, image string) error {
+	platforms := c.manifestPlatforms(image)
+	if len(platforms) > 0 || c.Platform != "" {
+		if err := c.resolvePlatform(image, platforms); err != nil {
+			return err
+		}
+	}
+	slog.Info("Pulling image", "image", image)
+	args := append([]string{"pull", "--quiet"}, c.platformArgs(image)...)
+	if err := c.runWithRetries(ctx, nil, nil, false, nil, append(args, image)...); err != nil {
+		return failure.New(categoryOr(err, failure.TransientInfra), fmt.Errorf("failed to pull image %s: %w", image, err))
+	}
+	out, err := c.output("image", "inspect", "--format", `{{join .RepoDigests "\n"}} {{.Os}}/{{.Architecture}}`, image)
+	if err != nil {
+		return failure.New(failure.TransientInfra, fmt.Errorf("failed to inspect image %s: %w", image, err))
+	}
+	if len(platforms) == 0 && c.Platform == "" {
+		if err := c.resolvePlatform(image, parsePlatforms(out)); err != nil {
+			return err
+		}
+	}
+	var digests []string
+	for _, repoDigest := range strings.Fields(string(out)) {
+		if digest := imageDigest(repoDigest); digest != "" {
+			digests = append(digests, digest)
+		}
+	}
+	want := imageDigest(image)
+	if want == "" {
+		slog.Info("Pulled image", "image", image, "digests", digests)
+		return nil
+	}
+	if !slices.Contains(digests, want) {
+		return failure.New(failure.UserConfig, fmt.Errorf("digest mismatch for image %s: got %v", image, digests))
+	}
+	slog.Info("Pulled image and verified digest", "image", image)
+	return nil
+}
+
+// load loads the images in the archive of c into the local Docker daemon.
+func (c *Docker) load(ctx context.Context) error {
+	slog.Info("Loading images", "archive", c.Archive)
+	if err := c.runWithRetries(ctx, nil, nil, false, nil, "load", "--quiet", "--input", c.Archive); err != nil {
+		return failure.New(categoryOr(err, failure.UserConfig), fmt.Errorf("failed to load images from %s: %w", c.Archive, err))
+	}
+	return nil
+}
+
+// verifyLocal checks that the local image exists in the local Docker daemon,
+// as it is not pulled, and returns its platforms.
+func (c *Docker) verifyLocal(image string) ([]string, error) {
+	out, err := c.output("image", "inspect", "--format", "{{.Id}} {{.Os}}/{{.Architecture}}", image)
+	if err != nil {
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("local image %s not found, build or load it before running with -image-local or -offline: %w", image, err))
+	}
+	slog.Info("Using local image", "image", image)
+	return parsePlatforms(out), nil
+}
@@ -0,0 +1,241 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+func TestMirrorImage(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		image  string
+		mirror string
+		want   string
+	}{
+		{
+			name:  "no mirror",
+			image: "us-central1-docker.pkg.dev/project/repo/image:latest",
+			want:  "us-central1-docker.pkg.dev/project/repo/image:latest",
+		},
+		{
+			name:   "registry host replaced",
+			image:  "us-central1-docker.pkg.dev/project/repo/image@sha256:abc",
+			mirror: "mirror.example.com/cache/",
+			want:   "mirror.example.com/cache/project/repo/image@sha256:abc",
+		},
+		{
+			name:   "registry with port",
+			image:  "localhost:5000/image:1.0",
+			mirror: "mirror.example.com",
+			want:   "mirror.example.com/image:1.0",
+		},
+		{
+			name:   "docker hub image",
+			image:  "someorg/image:1.0",
+			mirror: "mirror.gcr.io",
+			want:   "mirror.gcr.io/someorg/image:1.0",
+		},
+		{
+			name:   "official image",
+			image:  "ubuntu:24.04",
+			mirror: "mirror.gcr.io",
+			want:   "mirror.gcr.io/library/ubuntu:24.04",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := MirrorImage(test.image, test.mirror); got != test.want {
+				t.Errorf("MirrorImage() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPrewarm(t *testing.T) {
+	const digest = "sha256:0123456789abcdef"
+	for _, test := range []struct {
+		name        string
+		image       string
+		images      map[Command]string
+		extra       []string
+		repoDigests string
+		pullErr     error
+		wantPulls   []string
+		wantErrMsg  string
+	}{
+		{
+			name:        "pulls images once",
+			image:       "example.com/image:latest",
+			extra:       []string{"example.com/other:latest", "example.com/image:latest"},
+			repoDigests: "example.com/image@" + digest + "\n",
+			wantPulls:   []string{"example.com/image:latest", "example.com/other:latest"},
+		},
+		{
+			name:        "pulls declared containers",
+			image:       "example.com/image:latest",
+			images:      map[Command]string{CommandBuild: "example.com/builder:1.0", CommandTest: "example.com/builder:1.0"},
+			repoDigests: "example.com/image@" + digest + "\n",
+			wantPulls:   []string{"example.com/builder:1.0", "example.com/image:latest"},
+		},
+		{
+			name:        "verifies digest",
+			image:       "example.com/image@" + digest,
+			repoDigests: "mirror.example.com/image@sha256:fff\nexample.com/image@" + digest + "\n",
+			wantPulls:   []string{"example.com/image@" + digest},
+		},
+		{
+			name:        "digest mismatch",
+			image:       "example.com/image@" + digest,
+			repoDigests: "example.com/image@sha256:fff\n",
+			wantPulls:   []string{"example.com/image@" + digest},
+			wantErrMsg:  "digest mismatch",
+		},
+		{
+			name:       "pull fails",
+			image:      "example.com/image:latest",
+			pullErr:    errors.New("network unreachable"),
+			wantPulls:  []string{"example.com/image:latest"},
+			wantErrMsg: "failed to pull image",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var pulls []string
+			d := &Docker{
+				Image:  test.image,
+				Images: test.images,
+				run: func(_, _ io.Writer, args ...string) error {
+					mu.Lock()
+					defer mu.Unlock()
+					pulls = append(pulls, args[len(args)-1])
+					return test.pullErr
+				},
+				output: func(args ...string) ([]byte, error) {
+					return []byte(test.repoDigests), nil
+				},
+			}
+			err := d.Prewarm(t.Context(), test.extra...)
+			slices.Sort(pulls)
+			if diff := cmp.Diff(test.wantPulls, pulls); diff != "" {
+				t.Errorf("pulled images mismatch (-want +got):\n%s", diff)
+			}
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Errorf("Prewarm() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Prewarm() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestPrewarm_Local(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		inspectErr error
+		wantErrMsg string
+	}{
+		{
+			name: "image exists",
+		},
+		{
+			name:       "image missing",
+			inspectErr: errors.New("no such image"),
+			wantErrMsg: "local image generator:dev not found",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var inspected []string
+			d := &Docker{
+				Image: "generator:dev",
+				Local: true,
+				run: func(_, _ io.Writer, args ...string) error {
+					t.Errorf("run(%v) called, want no pulls of local images", args)
+					return nil
+				},
+				output: func(args ...string) ([]byte, error) {
+					inspected = append(inspected, args[len(args)-1])
+					return []byte("sha256:abc\n"), test.inspectErr
+				},
+			}
+			err := d.Prewarm(t.Context())
+			if diff := cmp.Diff([]string{"generator:dev"}, inspected); diff != "" {
+				t.Errorf("inspected images mismatch (-want +got):\n%s", diff)
+			}
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Errorf("Prewarm() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Prewarm() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestPrewarm_Archive(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		loadErr error
+		wantErr bool
+	}{
+		{name: "loaded"},
+		{name: "invalid archive", loadErr: errors.New("invalid tar header"), wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var commands [][]string
+			d := &Docker{
+				Image:   "generator:v1",
+				Local:   true,
+				Archive: "images.tar",
+				run: func(_, _ io.Writer, args ...string) error {
+					commands = append(commands, args)
+					return test.loadErr
+				},
+				output: func(args ...string) ([]byte, error) {
+					commands = append(commands, args[:2])
+					return []byte("sha256:abc\n"), nil
+				},
+			}
+			err := d.Prewarm(t.Context())
+			if test.wantErr {
+				if failure.CategoryOf(err) != failure.UserConfig {
+					t.Errorf("Prewarm() error = %v, want a %s error", err, failure.UserConfig)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := [][]string{{"load", "--quiet", "--input", "images.tar"}, {"image", "inspect"}}
+			if diff := cmp.Diff(want, commands); diff != "" {
+				t.Errorf("docker commands mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// The ways of running containers so that the files they write to the host are
+// owned by the current user, depending on the container runtime.
+const (
+	// userModeNone leaves the files as they are, since the current user is
+	// root or unknown.
+	userModeNone = "none"
+	// userModeKeepID runs the containers of rootless Podman with
+	// --userns=keep-id, which maps the current user to the same ID in the
+	// container.
+	userModeKeepID = "keep-id"
+	// userModeRootless runs the containers of rootless Docker as the user of
+	// the image. The root of the container is the current user on the host.
+	userModeRootless = "rootless"
+	// userModeChown runs the containers of rootful Docker as the current user,
+	// with --user, and then chowns the directories which librarian creates
+	// for them to the current user, in case the container wrote files there
+	// as another user.
+	userModeChown = "chown"
+)
+
+// chownDirs are the directories, in the container, which chownMounts chowns
+// if they are mounted writable. They hold the requests, responses and
+// outputs of the container, which librarian creates for it, while the other
+// mounts, e.g. the repository or the caches of the host, are left as they
+// are.
+var chownDirs = []string{"/librarian", "/output"}
+
+// runtimeInfo is the part of the output of "docker info" which tells whether
+// the container runtime is rootless.
+type runtimeInfo struct {
+	// SecurityOptions is set by Docker, and contains "name=rootless" in
+	// rootless mode.
+	SecurityOptions []string `json:"SecurityOptions"`
+	// Host is set by Podman.
+	Host *struct {
+		Security struct {
+			Rootless bool `json:"rootless"`
+		} `json:"security"`
+	} `json:"host"`
+}
+
+// detectUserMode returns the user mode of the container runtime, detecting it
+// on the first call. An undetectable runtime is assumed to be rootful Docker.
+func (c *Docker) detectUserMode() string {
+	c.userModeOnce.Do(func() {
+		if c.userMode != "" {
+			return
+		}
+		if c.uid == "" || c.gid == "" || c.uid == "0" {
+			c.userMode = userModeNone
+			return
+		}
+		c.userMode = userModeChown
+		out, err := c.output("info", "--format", "{{json .}}")
+		if err != nil {
+			slog.Warn("failed to detect the container runtime, assuming rootful Docker", "err", err)
+			return
+		}
+		info := &runtimeInfo{}
+		if err := json.Unmarshal(out, info); err != nil {
+			slog.Warn("failed to parse the container runtime info, assuming rootful Docker", "err", err)
+			return
+		}
+		switch {
+		case info.Host != nil && info.Host.Security.Rootless:
+			c.userMode = userModeKeepID
+		case slices.ContainsFunc(info.SecurityOptions, func(option string) bool {
+			return strings.Contains(option, "name=rootless")
+		}):
+			c.userMode = userModeRootless
+		}
+		slog.Info("Detected container runtime", "user_mode", c.userMode)
+	})
+	return c.userMode
+}
+
+// userArgs returns the docker arguments which select the user running the
+// container.
+func (c *Docker) userArgs() []string {
+	switch c.detectUserMode() {
+	case userModeKeepID:
+		return []string{"--userns=keep-id"}
+	case userModeChown:
+		return []string{"--user", fmt.Sprintf("%s:%s", c.uid, c.gid)}
+	}
+	return nil
+}
+
+// chownMounts makes the files in the writable mounts of chownDirs owned by
+// the current user, if the user mode requires it. As an image may write
+// files as another user than the one it is run as, they are chowned by
+// another container of image, run as root. A failure, e.g. because image has
+// no chown, is only logged, as the files are usually owned by the current
+// user already.
+func (c *Docker) chownMounts(image string, mounts []string) {
+	if c.detectUserMode() != userModeChown {
+		return
+	}
+	args := []string{"run", "--rm", "--user", "0:0"}
+	var dirs []string
+	for _, mount := range mounts {
+		parts := strings.Split(mount, ":")
+		if len(parts) < 2 || (len(parts) > 2 && parts[2] == "ro") || !slices.Contains(chownDirs, parts[1]) {
+			continue
+		}
+		args = append(args, "-v", mount)
+		dirs = append(dirs, parts[1])
+	}
+	if len(dirs) == 0 {
+		return
+	}
+	args = append(args, c.platformArgs(image)...)
+	if c.Local {
+		args = append(args, "--pull=never")
+	}
+	args = append(args, "--entrypoint", "chown", image, "-R", fmt.Sprintf("%s:%s", c.uid, c.gid))
+	args = append(args, dirs...)
+	if err := c.run(nil, nil, args...); err != nil {
+		slog.Warn("failed to chown the files written by the container", "dirs", strings.Join(dirs, ", "), "err", err)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestDetectUserMode(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		uid     string
+		info    string
+		infoErr error
+		want    string
+	}{
+		{
+			name: "rootful docker",
+			uid:  "1000",
+			info: `{"SecurityOptions":["name=seccomp,profile=builtin"]}`,
+			want: userModeChown,
+		},
+		{
+			name: "rootless docker",
+			uid:  "1000",
+			info: `{"SecurityOptions":["name=seccomp,profile=builtin","name=rootless","name=cgroupns"]}`,
+			want: userModeRootless,
+		},
+		{
+			name: "rootless podman",
+			uid:  "1000",
+			info: `{"host":{"security":{"rootless":true}}}`,
+			want: userModeKeepID,
+		},
+		{
+			name: "rootful podman",
+			uid:  "1000",
+			info: `{"host":{"security":{"rootless":false}}}`,
+			want: userModeChown,
+		},
+		{
+			name:    "undetectable",
+			uid:     "1000",
+			infoErr: errors.New("docker info failed"),
+			want:    userModeChown,
+		},
+		{
+			name: "invalid info",
+			uid:  "1000",
+			info: "not json",
+			want: userModeChown,
+		},
+		{
+			name: "root",
+			uid:  "0",
+			want: userModeNone,
+		},
+		{
+			name: "unknown user",
+			want: userModeNone,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			d := &Docker{
+				uid: test.uid,
+				gid: "1000",
+				output: func(args ...string) ([]byte, error) {
+					calls++
+					return []byte(test.info), test.infoErr
+				},
+			}
+			if got := d.detectUserMode(); got != test.want {
+				t.Errorf("detectUserMode() = %q, want %q", got, test.want)
+			}
+			d.detectUserMode()
+			if calls > 1 {
+				t.Errorf("docker info ran %d times, want at most once", calls)
+			}
+		})
+	}
+}
+
+func TestDockerRun_UserMode(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		userMode string
+		want     [][]string
+	}{
+		{
+			name:     "keep-id",
+			userMode: userModeKeepID,
+			want: [][]string{
+				{"run", "--rm", "-v", "/repo:/repo:ro", "-v", "/out:/output", "--userns=keep-id", "testImage", "release-init"},
+			},
+		},
+		{
+			name:     "rootless",
+			userMode: userModeRootless,
+			want: [][]string{
+				{"run", "--rm", "-v", "/repo:/repo:ro", "-v", "/out:/output", "testImage", "release-init"},
+			},
+		},
+		{
+			name:     "chown",
+			userMode: userModeChown,
+			want: [][]string{
+				{"run", "--rm", "-v", "/repo:/repo:ro", "-v", "/out:/output", "--user", "1000:1001", "testImage", "release-init"},
+				{"run", "--rm", "--user", "0:0", "-v", "/out:/output", "--entrypoint", "chown", "testImage", "-R", "1000:1001", "/output"},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var got [][]string
+			d := &Docker{
+				Image:    "testImage",
+				uid:      "1000",
+				gid:      "1001",
+				userMode: test.userMode,
+				run: func(_, _ io.Writer, args ...string) error {
+					got = append(got, args)
+					return nil
+				},
+			}
+			mounts := []string{"/repo:/repo:ro", "/out:/output"}
+			if err := d.runDocker(t.Context(), &config.Config{}, CommandReleaseInit, "a", mounts, nil, nil, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("docker runs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDockerRun_ChownOnlyLibrarianDirs(t *testing.T) {
+	var got [][]string
+	d := &Docker{
+		Image:    "testImage",
+		uid:      "1000",
+		gid:      "1001",
+		userMode: userModeChown,
+		mounts:   []*config.ContainerMount{{HostDir: "/home/user/.m2", ContainerDir: "/root/.m2"}},
+		run: func(_, _ io.Writer, args ...string) error {
+			got = append(got, args)
+			return nil
+		},
+	}
+	mounts := []string{"/repo/.librarian:/librarian", "/repo:/repo"}
+	if err := d.runDocker(t.Context(), &config.Config{}, CommandBuild, "a", mounts, nil, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"run", "--rm", "--user", "0:0", "-v", "/repo/.librarian:/librarian", "--entrypoint", "chown", "testImage", "-R", "1000:1001", "/librarian"}
+	if len(got) != 2 {
+		t.Fatalf("docker ran %d times, want 2", len(got))
+	}
+	if diff := cmp.Diff(want, got[1]); diff != "" {
+		t.Errorf("chown mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDockerRun_ChownFails(t *testing.T) {
+	d := &Docker{
+		Image:    "testImage",
+		uid:      "1000",
+		gid:      "1001",
+		userMode: userModeChown,
+		run: func(_, _ io.Writer, args ...string) error {
+			if slices.Contains(args, "chown") {
+				return errors.New("executable file not found")
+			}
+			return nil
+		},
+	}
+	mounts := []string{"/repo:/repo:ro", "/out:/output"}
+	if err := d.runDocker(t.Context(), &config.Config{}, CommandReleaseInit, "a", mounts, nil, nil, nil, nil); err != nil {
+		t.Errorf("runDocker() error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package failure classifies the errors of librarian runs, so that the
+// wrappers running librarian, such as CI jobs, can apply different retry and
+// alerting policies to each class of failure. Each category maps to a
+// distinct process exit code.
+package failure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Category is the class of a failure.
+type Category string
+
+// The set of failure categories.
+const (
+	// Unknown is the category of errors which have not been classified.
+	Unknown Category = "unknown"
+	// UserConfig is the category of invalid flags, configuration or state,
+	// which need to be fixed before retrying.
+	UserConfig Category = "user-config"
+	// TransientInfra is the category of infrastructure failures, such as
+	// network errors and timeouts, which are likely to succeed on retry.
+	TransientInfra Category = "transient-infra"
+	// ContainerFailure is the category of language containers exiting with an
+	// error.
+	ContainerFailure Category = "container-failure"
+	// GitConflict is the category of git operations which conflict with
+	// concurrent changes, such as a rejected push.
+	GitConflict Category = "git-conflict"
+	// ForgeAPI is the category of errors returned by the API of the code
+	// forge, such as GitHub.
+	ForgeAPI Category = "forge-api"
+)
+
+// exitCodes are the process exit codes of the categories. Exit code 1 is used
+// for unknown errors, and 2 matches the exit code of usage errors.
+var exitCodes = map[Category]int{
+	Unknown:          1,
+	UserConfig:       2,
+	TransientInfra:   3,
+	ContainerFailure: 4,
+	GitConflict:      5,
+	ForgeAPI:         6,
+}
+
+// Error is an error with a failure category.
+type Error struct {
+	// Category is the class of the failure.
+	Category Category
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns the message of the underlying error.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns err classified as category. It returns nil if err is nil, and
+// err unchanged if err is already classified, since the classification
+// closest to the cause is the most accurate one.
+func New(category Category, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Category: category, Err: err}
+}
+
+// CategoryOf returns the category of err. Errors which have not been
+// classified are Unknown, except for timeouts, which are TransientInfra.
+func CategoryOf(err error) Category {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Category
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return TransientInfra
+	}
+	return Unknown
+}
+
+// ExitCode returns the process exit code for err, or 0 if err is nil.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return exitCodes[CategoryOf(err)]
+}
+
+// CategoryOfExitCode returns the category of the failure of a librarian
+// process which exited with code, or Unknown if code is not the exit code of
+// a category.
+func CategoryOfExitCode(code int) Category {
+	for category, c := range exitCodes {
+		if c == code {
+			return category
+		}
+	}
+	return Unknown
+}
+
+// logsError is an error annotated with the log files which explain it.
+type logsError struct {
+	err  error
+	logs []string
+}
+
+// Error returns the message of the underlying error followed by the logs.
+func (e *logsError) Error() string {
+	return fmt.Sprintf("%v (logs: %s)", e.err, strings.Join(e.logs, ", "))
+}
+
+// Unwrap returns the underlying error.
+func (e *logsError) Unwrap() error {
+	return e.err
+}
+
+// WithLogs returns err annotated with the paths of the log files which
+// explain it, such as the output of a failed container run. The paths are
+// appended to the error message. It returns err unchanged if err is nil or
+// there are no logs.
+func WithLogs(err error, logs ...string) error {
+	if err == nil || len(logs) == 0 {
+		return err
+	}
+	return &logsError{err: err, logs: logs}
+}
+
+// Logs returns the paths of the log files err was annotated with by
+// WithLogs, or nil.
+func Logs(err error) []string {
+	var e *logsError
+	if errors.As(err, &e) {
+		return e.logs
+	}
+	return nil
+}
+
+// Report is the JSON representation of a failed run.
+type Report struct {
+	// Category is the class of the failure.
+	Category Category `json:"category"`
+	// ExitCode is the exit code of the process.
+	ExitCode int `json:"exit_code"`
+	// Message is the error message.
+	Message string `json:"message"`
+	// Logs are the paths of the log files which explain the failure.
+	Logs []string `json:"logs,omitempty"`
+}
+
+// JSON returns the JSON encoded Report of err.
+func JSON(err error) ([]byte, error) {
+	return json.Marshal(&Report{
+		Category: CategoryOf(err),
+		ExitCode: ExitCode(err),
+		Message:  err.Error(),
+		Logs:     Logs(err),
+	})
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package failure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCategoryOf(t *testing.T) {
+	base := errors.New("boom")
+	for _, test := range []struct {
+		name         string
+		err          error
+		wantCategory Category
+		wantExitCode int
+	}{
+		{
+			name:         "nil",
+			wantCategory: Unknown,
+		},
+		{
+			name:         "unclassified",
+			err:          base,
+			wantCategory: Unknown,
+			wantExitCode: 1,
+		},
+		{
+			name:         "classified",
+			err:          New(ContainerFailure, base),
+			wantCategory: ContainerFailure,
+			wantExitCode: 4,
+		},
+		{
+			name:         "wrapped",
+			err:          fmt.Errorf("failed to push: %w", New(GitConflict, base)),
+			wantCategory: GitConflict,
+			wantExitCode: 5,
+		},
+		{
+			name:         "innermost classification wins",
+			err:          New(UserConfig, fmt.Errorf("wrapped: %w", New(ForgeAPI, base))),
+			wantCategory: ForgeAPI,
+			wantExitCode: 6,
+		},
+		{
+			name:         "deadline exceeded",
+			err:          fmt.Errorf("failed to clone: %w", context.DeadlineExceeded),
+			wantCategory: TransientInfra,
+			wantExitCode: 3,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := CategoryOf(test.err); got != test.wantCategory {
+				t.Errorf("CategoryOf() = %q, want %q", got, test.wantCategory)
+			}
+			if got := ExitCode(test.err); got != test.wantExitCode {
+				t.Errorf("ExitCode() = %d, want %d", got, test.wantExitCode)
+			}
+		})
+	}
+}
+
+func TestCategoryOfExitCode(t *testing.T) {
+	for _, category := range []Category{Unknown, UserConfig, TransientInfra, ContainerFailure, GitConflict, ForgeAPI} {
+		if got := CategoryOfExitCode(ExitCode(New(category, errors.New("boom")))); got != category {
+			t.Errorf("CategoryOfExitCode() = %q, want %q", got, category)
+		}
+	}
+	if got := CategoryOfExitCode(137); got != Unknown {
+		t.Errorf("CategoryOfExitCode(137) = %q, want %q", got, Unknown)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if err := New(UserConfig, nil); err != nil {
+		t.Errorf("New(nil) = %v, want nil", err)
+	}
+	base := errors.New("boom")
+	err := New(UserConfig, base)
+	if !errors.Is(err, base) {
+		t.Errorf("New() = %v, want it to wrap %v", err, base)
+	}
+	if err.Error() != base.Error() {
+		t.Errorf("New().Error() = %q, want %q", err.Error(), base.Error())
+	}
+}
+
+func TestJSON(t *testing.T) {
+	got, err := JSON(New(UserConfig, errors.New("invalid flag")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"category":"user-config","exit_code":2,"message":"invalid flag"}`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("JSON() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWithLogs(t *testing.T) {
+	if err := WithLogs(nil, "a.log"); err != nil {
+		t.Errorf("WithLogs(nil) = %v, want nil", err)
+	}
+	base := New(ContainerFailure, errors.New("exit status 1"))
+	if err := WithLogs(base); err != base {
+		t.Errorf("WithLogs() without logs = %v, want %v", err, base)
+	}
+	err := fmt.Errorf("failed to generate: %w", WithLogs(base, "/tmp/stdout.log", "/tmp/stderr.log"))
+	if got := CategoryOf(err); got != ContainerFailure {
+		t.Errorf("CategoryOf() = %q, want %q", got, ContainerFailure)
+	}
+	want := "failed to generate: exit status 1 (logs: /tmp/stdout.log, /tmp/stderr.log)"
+	if diff := cmp.Diff(want, err.Error()); diff != "" {
+		t.Errorf("Error() mismatch (-want +got):\n%s", diff)
+	}
+	got, jsonErr := JSON(err)
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	wantJSON := `{"category":"container-failure","exit_code":4,"message":"` + want + `","logs":["/tmp/stdout.log","/tmp/stderr.log"]}`
+	if diff := cmp.Diff(wantJSON, string(got)); diff != "" {
+		t.Errorf("JSON() mismatch (-want +got):\n%s", diff)
+	}
+}
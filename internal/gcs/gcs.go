@@ -0,0 +1,167 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gcs provides the operations on Google Cloud Storage which Librarian
+// needs to keep the artifacts of its runs.
+package gcs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/auth/credentials"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+const (
+	defaultBaseURL = "https://storage.googleapis.com"
+	readWriteScope = "https://www.googleapis.com/auth/devstorage.read_write"
+)
+
+// Client is a client of the JSON API of Cloud Storage.
+type Client struct {
+	baseURL    string
+	token      func(ctx context.Context) (string, error)
+	httpClient *http.Client
+}
+
+// NewClient creates a Client authenticated with the application default
+// credentials.
+func NewClient() (*Client, error) {
+	creds, err := credentials.DetectDefault(&credentials.DetectOptions{Scopes: []string{readWriteScope}})
+	if err != nil {
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("failed to find Google Cloud credentials: %w", err))
+	}
+	token := func(ctx context.Context) (string, error) {
+		t, err := creds.Token(ctx)
+		if err != nil {
+			return "", err
+		}
+		return t.Value, nil
+	}
+	return &Client{baseURL: defaultBaseURL, token: token, httpClient: http.DefaultClient}, nil
+}
+
+// Object is an object in a bucket.
+type Object struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"timeCreated"`
+}
+
+// ParseURL splits a gs://bucket/prefix URL into its bucket and its prefix,
+// without leading or trailing slashes.
+func ParseURL(gsURL string) (bucket, prefix string, err error) {
+	rest, ok := strings.CutPrefix(gsURL, "gs://")
+	if !ok {
+		return "", "", fmt.Errorf("invalid Cloud Storage URL, want gs://bucket[/prefix]: %q", gsURL)
+	}
+	bucket, prefix, _ = strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("invalid Cloud Storage URL, want gs://bucket[/prefix]: %q", gsURL)
+	}
+	return bucket, strings.Trim(prefix, "/"), nil
+}
+
+// BrowserURL returns the URL of the Cloud Console page of the objects under
+// prefix in bucket.
+func BrowserURL(bucket, prefix string) string {
+	return fmt.Sprintf("https://console.cloud.google.com/storage/browser/%s/%s", bucket, prefix)
+}
+
+// Upload writes the content of r to the object with the given name in bucket,
+// replacing any existing object.
+func (c *Client) Upload(ctx context.Context, bucket, name string, r io.Reader) error {
+	path := fmt.Sprintf("/upload/storage/v1/b/%s/o?uploadType=media&name=%s", url.PathEscape(bucket), url.QueryEscape(name))
+	_, err := c.do(ctx, http.MethodPost, path, r)
+	return err
+}
+
+// Download returns the content of the object with the given name in bucket.
+func (c *Client) Download(ctx context.Context, bucket, name string) ([]byte, error) {
+	return c.do(ctx, http.MethodGet, fmt.Sprintf("/storage/v1/b/%s/o/%s?alt=media", url.PathEscape(bucket), url.PathEscape(name)), nil)
+}
+
+// List returns the objects in bucket whose name starts with prefix.
+func (c *Client) List(ctx context.Context, bucket, prefix string) ([]*Object, error) {
+	var objects []*Object
+	pageToken := ""
+	for {
+		query := url.Values{"prefix": {prefix}, "fields": {"items(name,timeCreated),nextPageToken"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		data, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/storage/v1/b/%s/o?%s", url.PathEscape(bucket), query.Encode()), nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items         []*Object `json:"items"`
+			NextPageToken string    `json:"nextPageToken"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse objects of bucket %s: %w", bucket, err)
+		}
+		objects = append(objects, page.Items...)
+		if page.NextPageToken == "" {
+			return objects, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// Delete deletes the object with the given name in bucket.
+func (c *Client) Delete(ctx context.Context, bucket, name string) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/storage/v1/b/%s/o/%s", url.PathEscape(bucket), url.PathEscape(name)), nil)
+	return err
+}
+
+// do sends an authenticated request to the JSON API, and returns the body of
+// the response.
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	token, err := c.token(ctx)
+	if err != nil {
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("failed to get Google Cloud access token: %w", err))
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, failure.New(failure.TransientInfra, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, failure.New(failure.TransientInfra, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("google cloud credentials are missing or not permitted: %w", err))
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+			return nil, failure.New(failure.TransientInfra, err)
+		}
+		return nil, err
+	}
+	return data, nil
+}
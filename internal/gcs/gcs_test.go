@@ -0,0 +1,166 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer test-token")
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return &Client{
+		baseURL:    server.URL,
+		token:      func(ctx context.Context) (string, error) { return "test-token", nil },
+		httpClient: server.Client(),
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	for _, test := range []struct {
+		url        string
+		wantBucket string
+		wantPrefix string
+		wantErr    bool
+	}{
+		{url: "gs://bucket", wantBucket: "bucket"},
+		{url: "gs://bucket/", wantBucket: "bucket"},
+		{url: "gs://bucket/a/b/", wantBucket: "bucket", wantPrefix: "a/b"},
+		{url: "gs:///a", wantErr: true},
+		{url: "https://bucket/a", wantErr: true},
+	} {
+		t.Run(test.url, func(t *testing.T) {
+			bucket, prefix, err := ParseURL(test.url)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseURL() error = %v, wantErr %t", err, test.wantErr)
+			}
+			if bucket != test.wantBucket || prefix != test.wantPrefix {
+				t.Errorf("ParseURL() = %q, %q, want %q, %q", bucket, prefix, test.wantBucket, test.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestUpload(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/upload/storage/v1/b/bucket/o" {
+			t.Errorf("request = %s %s, want POST /upload/storage/v1/b/bucket/o", r.Method, r.URL.Path)
+		}
+		if got := r.URL.Query().Get("name"); got != "run/a b.txt" {
+			t.Errorf("name = %q, want %q", got, "run/a b.txt")
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "content" {
+			t.Errorf("body = %q, want %q", body, "content")
+		}
+		fmt.Fprint(w, `{}`)
+	})
+	if err := client.Upload(context.Background(), "bucket", "run/a b.txt", strings.NewReader("content")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.EscapedPath() != "/storage/v1/b/bucket/o/runs%2Fa" {
+			t.Errorf("request = %s %s, want GET /storage/v1/b/bucket/o/runs%%2Fa", r.Method, r.URL.EscapedPath())
+		}
+		if got := r.URL.Query().Get("alt"); got != "media" {
+			t.Errorf("alt = %q, want %q", got, "media")
+		}
+		fmt.Fprint(w, "content")
+	})
+	got, err := client.Download(context.Background(), "bucket", "runs/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "content" {
+		t.Errorf("Download() = %q, want %q", got, "content")
+	}
+}
+
+func TestList(t *testing.T) {
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("prefix"); got != "runs/" {
+			t.Errorf("prefix = %q, want %q", got, "runs/")
+		}
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprintf(w, `{"items": [{"name": "runs/a", "timeCreated": %q}], "nextPageToken": "next"}`, created.Format(time.RFC3339))
+			return
+		}
+		fmt.Fprintf(w, `{"items": [{"name": "runs/b", "timeCreated": %q}]}`, created.Format(time.RFC3339))
+	})
+	got, err := client.List(context.Background(), "bucket", "runs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Object{{Name: "runs/a", Created: created}, {Name: "runs/b", Created: created}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("List() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.EscapedPath() != "/storage/v1/b/bucket/o/runs%2Fa" {
+			t.Errorf("request = %s %s, want DELETE /storage/v1/b/bucket/o/runs%%2Fa", r.Method, r.URL.EscapedPath())
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	if err := client.Delete(context.Background(), "bucket", "runs/a"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestErrorCategory(t *testing.T) {
+	for _, test := range []struct {
+		status int
+		want   failure.Category
+	}{
+		{status: http.StatusForbidden, want: failure.UserConfig},
+		{status: http.StatusServiceUnavailable, want: failure.TransientInfra},
+		{status: http.StatusNotFound, want: failure.Unknown},
+	} {
+		t.Run(http.StatusText(test.status), func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "failed", test.status)
+			})
+			err := client.Delete(context.Background(), "bucket", "a")
+			if err == nil {
+				t.Fatal("Delete() error = nil, want error")
+			}
+			if got := failure.CategoryOf(err); got != test.want {
+				t.Errorf("CategoryOf() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gerrit provides the operations on Gerrit code review which
+// Librarian needs, as an alternative to GitHub pull requests.
+package gerrit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// changeIDRegex matches the value of a Change-Id trailer.
+var changeIDRegex = regexp.MustCompile(`^I[0-9a-f]{40}$`)
+
+// xssiPrefix is the prefix of JSON responses of the Gerrit REST API.
+const xssiPrefix = ")]}'"
+
+// Client is a client of the REST API of a Gerrit host.
+type Client struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// NewClient creates a Client for the Gerrit host at baseURL, e.g.
+// https://example-review.googlesource.com. username and password are the
+// HTTP credentials of the account; requests are anonymous if password is
+// empty.
+func NewClient(baseURL, username, password string) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		username:   username,
+		password:   password,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// NewChangeID returns a new, random Change-Id. Gerrit only requires
+// Change-Ids to be unique within a project and branch, so it does not need
+// to be derived from the commit like the one of the commit-msg hook.
+func NewChangeID() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate Change-Id: %w", err)
+	}
+	return "I" + hex.EncodeToString(b), nil
+}
+
+// ChangeIDOf returns the Change-Id in the trailers of message, or an empty
+// string if it has none.
+func ChangeIDOf(message string) string {
+	_, trailers := gitrepo.ParseTrailers(message)
+	changeID := gitrepo.TrailerValue(trailers, gitrepo.TrailerChangeID)
+	if !changeIDRegex.MatchString(changeID) {
+		return ""
+	}
+	return changeID
+}
+
+// AddChangeID returns message with a Change-Id trailer holding changeID,
+// after its other trailers, unless message already has one.
+func AddChangeID(message, changeID string) string {
+	if ChangeIDOf(message) != "" {
+		return message
+	}
+	return gitrepo.AddTrailers(message, gitrepo.Trailer{Key: gitrepo.TrailerChangeID, Value: changeID})
+}
+
+// ReviewRef returns the ref to push to for creating or updating changes for
+// review on branch, with the given topic if not empty.
+func ReviewRef(branch, topic string) string {
+	ref := "refs/for/" + branch
+	if topic != "" {
+		ref += "%topic=" + topic
+	}
+	return ref
+}
+
+// SetReview votes on the labels of the current revision of the change with
+// the given Change-Id, in project and branch, e.g. {"Code-Review": 1}. The
+// message, if not empty, is posted on the change.
+func (c *Client) SetReview(ctx context.Context, project, branch, changeID string, labels map[string]int, message string) error {
+	input := struct {
+		Labels  map[string]int `json:"labels,omitempty"`
+		Message string         `json:"message,omitempty"`
+	}{Labels: labels, Message: message}
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	// The change is identified by its project, branch and Change-Id, as the
+	// Change-Id alone may be ambiguous.
+	id := strings.Join([]string{url.PathEscape(project), url.PathEscape(branch), changeID}, "~")
+	path := fmt.Sprintf("/changes/%s/revisions/current/review", id)
+	slog.Info("Voting on Gerrit change", "change", changeID, "labels", labels)
+	_, err = c.do(ctx, http.MethodPost, path, body)
+	return err
+}
+
+// do sends a request to the REST API, and returns the body of the response
+// without its XSSI prefix. Authenticated requests use the /a/ prefix of the
+// API.
+func (c *Client) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	if c.password != "" {
+		path = "/a" + path
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, failure.New(failure.TransientInfra, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, failure.New(failure.TransientInfra, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("gerrit credentials are missing or not permitted: %w", err))
+		case resp.StatusCode >= http.StatusInternalServerError:
+			return nil, failure.New(failure.TransientInfra, err)
+		}
+		return nil, failure.New(failure.ForgeAPI, err)
+	}
+	return bytes.TrimPrefix(data, []byte(xssiPrefix)), nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gerrit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+func TestChangeID(t *testing.T) {
+	id, err := NewChangeID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^I[0-9a-f]{40}$`).MatchString(id) {
+		t.Fatalf("NewChangeID() = %q, want I followed by 40 hex digits", id)
+	}
+	message := AddChangeID("feat: add a method\n\nbody\n", id)
+	if want := "feat: add a method\n\nbody\n\nChange-Id: " + id + "\n"; message != want {
+		t.Errorf("AddChangeID() = %q, want %q", message, want)
+	}
+	if got := ChangeIDOf(message); got != id {
+		t.Errorf("ChangeIDOf() = %q, want %q", got, id)
+	}
+	if got := AddChangeID(message, "Iffffffffffffffffffffffffffffffffffffffff"); got != message {
+		t.Errorf("AddChangeID() with an existing Change-Id = %q, want %q", got, message)
+	}
+	message = AddChangeID("feat: add a method\n\nCo-authored-by: Jane Doe <jane@example.com>\n", id)
+	if want := "feat: add a method\n\nCo-authored-by: Jane Doe <jane@example.com>\nChange-Id: " + id + "\n"; message != want {
+		t.Errorf("AddChangeID() with trailers = %q, want %q", message, want)
+	}
+	if got := ChangeIDOf("no trailer"); got != "" {
+		t.Errorf("ChangeIDOf() = %q, want empty", got)
+	}
+}
+
+func TestReviewRef(t *testing.T) {
+	for _, test := range []struct {
+		branch, topic, want string
+	}{
+		{branch: "main", want: "refs/for/main"},
+		{branch: "main", topic: "librarian-release", want: "refs/for/main%topic=librarian-release"},
+	} {
+		if got := ReviewRef(test.branch, test.topic); got != test.want {
+			t.Errorf("ReviewRef(%q, %q) = %q, want %q", test.branch, test.topic, got, test.want)
+		}
+	}
+}
+
+func TestSetReview(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		password     string
+		status       int
+		wantPath     string
+		wantCategory failure.Category
+	}{
+		{
+			name:     "authenticated",
+			password: "secret",
+			status:   http.StatusOK,
+			wantPath: "/a/changes/lang%2Frepo~main~I0123/revisions/current/review",
+		},
+		{
+			name:     "anonymous",
+			status:   http.StatusOK,
+			wantPath: "/changes/lang%2Frepo~main~I0123/revisions/current/review",
+		},
+		{
+			name:         "unauthorized",
+			password:     "secret",
+			status:       http.StatusUnauthorized,
+			wantPath:     "/a/changes/lang%2Frepo~main~I0123/revisions/current/review",
+			wantCategory: failure.UserConfig,
+		},
+		{
+			name:         "server error",
+			password:     "secret",
+			status:       http.StatusBadGateway,
+			wantPath:     "/a/changes/lang%2Frepo~main~I0123/revisions/current/review",
+			wantCategory: failure.TransientInfra,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var gotPath string
+			var gotInput map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.EscapedPath()
+				if user, password, ok := r.BasicAuth(); test.password != "" && (!ok || user != "bot" || password != test.password) {
+					t.Errorf("BasicAuth() = %q, %q, %t, want the credentials", user, password, ok)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&gotInput); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				w.WriteHeader(test.status)
+				w.Write([]byte(xssiPrefix + "\n{}"))
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL+"/", "bot", test.password)
+			err := client.SetReview(context.Background(), "lang/repo", "main", "I0123", map[string]int{"Code-Review": 1}, "Generated by librarian")
+			if got := failure.CategoryOf(err); err != nil && got != test.wantCategory || err == nil && test.wantCategory != "" {
+				t.Fatalf("SetReview() error = %v, want category %q", err, test.wantCategory)
+			}
+			if gotPath != test.wantPath {
+				t.Errorf("SetReview() path = %q, want %q", gotPath, test.wantPath)
+			}
+			wantInput := map[string]any{"labels": map[string]any{"Code-Review": float64(1)}, "message": "Generated by librarian"}
+			if diff := cmp.Diff(wantInput, gotInput); diff != "" {
+				t.Errorf("SetReview() input mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
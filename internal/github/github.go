@@ -18,13 +18,19 @@ package github
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v69/github"
+	"github.com/googleapis/librarian/internal/failure"
 	"github.com/googleapis/librarian/internal/gitrepo"
 )
 
@@ -40,6 +46,12 @@ type PullRequestReview = github.PullRequestReview
 // RepositoryRelease is a type alias for the go-github type.
 type RepositoryRelease = github.RepositoryRelease
 
+// Issue is a type alias for the go-github type.
+type Issue = github.Issue
+
+// IssueComment is a type alias for the go-github type.
+type IssueComment = github.IssueComment
+
 // MergeMethodRebase is a constant alias for the go-github constant.
 const MergeMethodRebase = github.MergeMethodRebase
 
@@ -51,19 +63,165 @@ type Client struct {
 	repo        *Repository
 }
 
-// NewClient creates a new Client to interact with GitHub.
-func NewClient(accessToken string, repo *Repository) (*Client, error) {
-	return newClientWithHTTP(accessToken, repo, nil)
+// DefaultHost is the host of github.com.
+const DefaultHost = "github.com"
+
+// Endpoints are the URLs of the API of a GitHub Enterprise Server instance.
+type Endpoints struct {
+	// APIURL is the base URL of the REST API, e.g.
+	// https://github.example.com/api/v3/.
+	APIURL string
+	// UploadURL is the base URL for uploads, e.g.
+	// https://github.example.com/api/uploads/. APIURL is used if empty.
+	UploadURL string
+}
+
+// Host returns the host of the GitHub instance, which is the host of APIURL,
+// or DefaultHost if e is nil or APIURL is empty.
+func (e *Endpoints) Host() string {
+	if e == nil || e.APIURL == "" {
+		return DefaultHost
+	}
+	u, err := url.Parse(e.APIURL)
+	if err != nil || u.Host == "" {
+		return DefaultHost
+	}
+	return u.Host
 }
 
-func newClientWithHTTP(accessToken string, repo *Repository, httpClient *http.Client) (*Client, error) {
+// NewClient creates a new Client to interact with GitHub. If endpoints is
+// not nil, the client interacts with the GitHub Enterprise Server instance
+// at endpoints instead of github.com.
+func NewClient(accessToken string, repo *Repository, endpoints *Endpoints) (*Client, error) {
+	return newClientWithHTTP(accessToken, repo, endpoints, nil)
+}
+
+func newClientWithHTTP(accessToken string, repo *Repository, endpoints *Endpoints, httpClient *http.Client) (*Client, error) {
+	client := github.NewClient(httpClient).WithAuthToken(accessToken)
+	if endpoints != nil && endpoints.APIURL != "" {
+		uploadURL := endpoints.UploadURL
+		if uploadURL == "" {
+			uploadURL = endpoints.APIURL
+		}
+		var err error
+		client, err = client.WithEnterpriseURLs(endpoints.APIURL, uploadURL)
+		if err != nil {
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("invalid GitHub Enterprise Server endpoints: %w", err))
+		}
+	}
 	return &Client{
-		Client:      github.NewClient(httpClient).WithAuthToken(accessToken),
+		Client:      client,
 		accessToken: accessToken,
 		repo:        repo,
 	}, nil
 }
 
+// ValidateToken checks that the access token of c is accepted by the GitHub
+// instance, by fetching the authenticated user.
+func (c *Client) ValidateToken(ctx context.Context) error {
+	_, resp, err := c.Users.Get(ctx, "")
+	if err == nil {
+		return nil
+	}
+	if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+		return failure.New(failure.UserConfig, fmt.Errorf("GitHub token is not valid for %s: %w", c.BaseURL, err))
+	}
+	return apiError(err)
+}
+
+// Permission is a permission which an access token may grant on a
+// repository, named as the repository permissions of fine-grained personal
+// access tokens and GitHub Apps.
+type Permission string
+
+const (
+	// PermissionContentsWrite allows pushing branches, and creating tags and
+	// releases.
+	PermissionContentsWrite Permission = "contents:write"
+	// PermissionIssuesWrite allows creating issues and comments.
+	PermissionIssuesWrite Permission = "issues:write"
+	// PermissionPullRequestsWrite allows creating and labeling pull requests.
+	PermissionPullRequestsWrite Permission = "pull_requests:write"
+)
+
+// roles are the roles on a repository, from the lowest to the highest.
+var roles = []string{"pull", "triage", "push", "maintain", "admin"}
+
+// minimumRole is the lowest role on a repository, as reported in the
+// permissions of the repository, which grants each Permission.
+var minimumRole = map[Permission]string{
+	PermissionContentsWrite:     "push",
+	PermissionIssuesWrite:       "triage",
+	PermissionPullRequestsWrite: "push",
+}
+
+// CheckPermissions checks that the access token of c grants the given
+// permissions on the repository of c, so that a token which lacks them fails
+// with a precise explanation before any work is done, rather than when
+// pushing.
+//
+// The scopes of classic personal access tokens are checked exactly. Other
+// tokens, such as fine-grained personal access tokens, do not report their
+// permissions, so the role of their owner on the repository is checked
+// instead, if it is reported.
+func (c *Client) CheckPermissions(ctx context.Context, permissions ...Permission) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+	repo, resp, err := c.Repositories.Get(ctx, c.repo.Owner, c.repo.Name)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return failure.New(failure.UserConfig, fmt.Errorf("GitHub token is not valid for %s: %w", c.BaseURL, err))
+		}
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return failure.New(failure.UserConfig, fmt.Errorf("repository %s/%s does not exist or is not accessible with the GitHub token", c.repo.Owner, c.repo.Name))
+		}
+		return apiError(err)
+	}
+	var names []string
+	for _, permission := range permissions {
+		names = append(names, string(permission))
+	}
+	if header, ok := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]; ok {
+		// All permissions on repositories are granted by the "repo" scope,
+		// or by "public_repo" for public repositories.
+		var scopes []string
+		for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				scopes = append(scopes, scope)
+			}
+		}
+		if slices.Contains(scopes, "repo") || (!repo.GetPrivate() && slices.Contains(scopes, "public_repo")) {
+			return nil
+		}
+		want := `"repo"`
+		if !repo.GetPrivate() {
+			want = `"repo" or "public_repo"`
+		}
+		return failure.New(failure.UserConfig, fmt.Errorf("GitHub token lacks the %s scope, which grants %s on %s/%s; the token has the scopes [%s]",
+			want, strings.Join(names, ", "), c.repo.Owner, c.repo.Name, strings.Join(scopes, ", ")))
+	}
+	if repo.Permissions == nil {
+		slog.Debug("GitHub token does not report its permissions, not checking them", "repo", c.repo.Owner+"/"+c.repo.Name)
+		return nil
+	}
+	var missing []string
+	role := ""
+	for _, permission := range permissions {
+		if !repo.Permissions[minimumRole[permission]] {
+			missing = append(missing, string(permission))
+			if slices.Index(roles, minimumRole[permission]) > slices.Index(roles, role) {
+				role = minimumRole[permission]
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return failure.New(failure.UserConfig, fmt.Errorf("GitHub token lacks %s on %s/%s, which requires its owner to have at least the %q role on the repository",
+			strings.Join(missing, ", "), c.repo.Owner, c.repo.Name, role))
+	}
+	return nil
+}
+
 // Token returns the access token for Client.
 func (c *Client) Token() string {
 	return c.accessToken
@@ -76,6 +234,20 @@ type Repository struct {
 	Owner string
 	// The name of the repository.
 	Name string
+	// The host of the GitHub instance of the repository, e.g.
+	// github.example.com for GitHub Enterprise Server. Empty means
+	// DefaultHost.
+	Host string
+}
+
+// URL returns the web URL of the repository, e.g.
+// https://github.com/googleapis/librarian.
+func (r *Repository) URL() string {
+	host := r.Host
+	if host == "" {
+		host = DefaultHost
+	}
+	return fmt.Sprintf("https://%s/%s/%s", host, r.Owner, r.Name)
 }
 
 // PullRequestMetadata identifies a pull request within a repository.
@@ -87,17 +259,42 @@ type PullRequestMetadata struct {
 }
 
 // ParseURL parses a GitHub URL (anything to do with a repository) to determine
-// the GitHub repo details (owner and name).
+// the GitHub repo details (owner and name). Both HTTPS URLs and SSH remotes,
+// such as "git@github.com:owner/name.git", are supported.
 func ParseURL(remoteURL string) (*Repository, error) {
-	if !strings.HasPrefix(remoteURL, "https://github.com/") {
+	return ParseHostURL(DefaultHost, remoteURL)
+}
+
+// ParseHostURL is like ParseURL, for a repository of the GitHub instance at
+// host, such as a GitHub Enterprise Server.
+func ParseHostURL(host, remoteURL string) (*Repository, error) {
+	remotePath, ok := cutHostPrefix(host, remoteURL)
+	if !ok {
 		return nil, fmt.Errorf("remote '%s' is not a GitHub remote", remoteURL)
 	}
-	remotePath := remoteURL[len("https://github.com/"):]
 	pathParts := strings.Split(remotePath, "/")
+	if len(pathParts) < 2 {
+		return nil, fmt.Errorf("remote '%s' is not a GitHub repository", remoteURL)
+	}
 	organization := pathParts[0]
 	repoName := pathParts[1]
 	repoName = strings.TrimSuffix(repoName, ".git")
-	return &Repository{Owner: organization, Name: repoName}, nil
+	repo := &Repository{Owner: organization, Name: repoName}
+	if host != DefaultHost {
+		repo.Host = host
+	}
+	return repo, nil
+}
+
+// cutHostPrefix returns remoteURL without the prefix of an HTTPS or SSH URL of
+// host, and whether it had one.
+func cutHostPrefix(host, remoteURL string) (string, bool) {
+	for _, format := range []string{"https://%s/", "git@%s:", "ssh://git@%s/"} {
+		if remotePath, ok := strings.CutPrefix(remoteURL, fmt.Sprintf(format, host)); ok {
+			return remotePath, true
+		}
+	}
+	return "", false
 }
 
 // GetRawContent fetches the raw content of a file within a repository repo,
@@ -108,7 +305,7 @@ func (c *Client) GetRawContent(ctx context.Context, path, ref string) ([]byte, e
 	}
 	body, _, err := c.Repositories.DownloadContents(ctx, c.repo.Owner, c.repo.Name, path, options)
 	if err != nil {
-		return nil, err
+		return nil, apiError(err)
 	}
 	defer body.Close()
 	return io.ReadAll(body)
@@ -132,7 +329,7 @@ func (c *Client) CreatePullRequest(ctx context.Context, repo *Repository, remote
 	}
 	pr, _, err := c.PullRequests.Create(ctx, repo.Owner, repo.Name, newPR)
 	if err != nil {
-		return nil, err
+		return nil, apiError(err)
 	}
 
 	slog.Info("PR created", "url", pr.GetHTMLURL())
@@ -145,7 +342,7 @@ func (c *Client) GetLabels(ctx context.Context, number int) ([]string, error) {
 	slog.Info("Getting labels", "number", number)
 	labels, _, err := c.Issues.ListLabelsByIssue(ctx, c.repo.Owner, c.repo.Name, number, nil)
 	if err != nil {
-		return nil, err
+		return nil, apiError(err)
 	}
 	var labelNames []string
 	for _, label := range labels {
@@ -158,14 +355,14 @@ func (c *Client) GetLabels(ctx context.Context, number int) ([]string, error) {
 func (c *Client) ReplaceLabels(ctx context.Context, number int, labels []string) error {
 	slog.Info("Replacing labels", "number", number, "labels", labels)
 	_, _, err := c.Issues.ReplaceLabelsForIssue(ctx, c.repo.Owner, c.repo.Name, number, labels)
-	return err
+	return apiError(err)
 }
 
 // AddLabelsToIssue adds labels to an existing issue in a GitHub repository.
 func (c *Client) AddLabelsToIssue(ctx context.Context, repo *Repository, number int, labels []string) error {
 	slog.Info("Labels added to issue", "number", number, "labels", labels)
 	_, _, err := c.Issues.AddLabelsToIssue(ctx, repo.Owner, repo.Name, number, labels)
-	return err
+	return apiError(err)
 }
 
 // FetchGitHubRepoFromRemote parses the GitHub repo name from the remote for this repository.
@@ -173,6 +370,13 @@ func (c *Client) AddLabelsToIssue(ctx context.Context, repo *Repository, number
 // provide an unambiguous result.
 // Remotes without any URLs, or where the first URL does not start with https://github.com/ are ignored.
 func FetchGitHubRepoFromRemote(repo gitrepo.Repository) (*Repository, error) {
+	return FetchGitHubRepoFromHostRemote(repo, DefaultHost)
+}
+
+// FetchGitHubRepoFromHostRemote is like FetchGitHubRepoFromRemote, for a
+// repository of the GitHub instance at host, such as a GitHub Enterprise
+// Server.
+func FetchGitHubRepoFromHostRemote(repo gitrepo.Repository, host string) (*Repository, error) {
 	remotes, err := repo.Remotes()
 	if err != nil {
 		return nil, err
@@ -181,15 +385,17 @@ func FetchGitHubRepoFromRemote(repo gitrepo.Repository) (*Repository, error) {
 	for _, remote := range remotes {
 		if remote.Config().Name == "origin" {
 			urls := remote.Config().URLs
-			if len(urls) > 0 && strings.HasPrefix(urls[0], "https://github.com/") {
-				return ParseURL(urls[0])
+			if len(urls) > 0 {
+				if _, ok := cutHostPrefix(host, urls[0]); ok {
+					return ParseHostURL(host, urls[0])
+				}
 			}
 			// If 'origin' exists but is not a GitHub remote, we stop.
 			break
 		}
 	}
 
-	return nil, fmt.Errorf("could not find an 'origin' remote pointing to a GitHub https URL")
+	return nil, fmt.Errorf("could not find an 'origin' remote pointing to a GitHub HTTPS or SSH URL")
 }
 
 // SearchPullRequests searches for pull requests in the repository using the provided raw query.
@@ -201,13 +407,13 @@ func (c *Client) SearchPullRequests(ctx context.Context, query string) ([]*PullR
 	for {
 		result, resp, err := c.Search.Issues(ctx, query, opts)
 		if err != nil {
-			return nil, err
+			return nil, apiError(err)
 		}
 		for _, issue := range result.Issues {
 			if issue.IsPullRequest() {
 				pr, _, err := c.PullRequests.Get(ctx, c.repo.Owner, c.repo.Name, issue.GetNumber())
 				if err != nil {
-					return nil, err
+					return nil, apiError(err)
 				}
 				prs = append(prs, pr)
 			}
@@ -223,18 +429,94 @@ func (c *Client) SearchPullRequests(ctx context.Context, query string) ([]*PullR
 // GetPullRequest gets a pull request by its number.
 func (c *Client) GetPullRequest(ctx context.Context, number int) (*PullRequest, error) {
 	pr, _, err := c.PullRequests.Get(ctx, c.repo.Owner, c.repo.Name, number)
-	return pr, err
+	return pr, apiError(err)
 }
 
-// CreateRelease creates a tag and release in the repository at the given commitish.
-func (c *Client) CreateRelease(ctx context.Context, tagName, name, body, commitish string) (*github.RepositoryRelease, error) {
+// UpdatePullRequestBody replaces the body of the pull request with the given
+// number.
+func (c *Client) UpdatePullRequestBody(ctx context.Context, number int, body string) error {
+	_, _, err := c.PullRequests.Edit(ctx, c.repo.Owner, c.repo.Name, number, &github.PullRequest{Body: &body})
+	return apiError(err)
+}
+
+// CreateRelease creates a tag and release in the repository at the given
+// commitish. The release is marked as a prerelease if prerelease is true.
+func (c *Client) CreateRelease(ctx context.Context, tagName, name, body, commitish string, prerelease bool) (*github.RepositoryRelease, error) {
 	r, _, err := c.Repositories.CreateRelease(ctx, c.repo.Owner, c.repo.Name, &github.RepositoryRelease{
 		TagName:         &tagName,
 		Name:            &name,
 		Body:            &body,
 		TargetCommitish: &commitish,
+		Prerelease:      &prerelease,
 	})
-	return r, err
+	return r, apiError(err)
+}
+
+// GetReleaseByTag gets the release of the repository with the given tag.
+func (c *Client) GetReleaseByTag(ctx context.Context, tagName string) (*github.RepositoryRelease, error) {
+	r, _, err := c.Repositories.GetReleaseByTag(ctx, c.repo.Owner, c.repo.Name, tagName)
+	return r, apiError(err)
+}
+
+// ListReleaseTags returns the tag names of all releases of the repository,
+// including drafts and prereleases.
+func (c *Client) ListReleaseTags(ctx context.Context) ([]string, error) {
+	var tags []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		releases, resp, err := c.Repositories.ListReleases(ctx, c.repo.Owner, c.repo.Name, opts)
+		if err != nil {
+			return nil, apiError(err)
+		}
+		for _, release := range releases {
+			tags = append(tags, release.GetTagName())
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return tags, nil
+}
+
+// ListReleaseAssets returns the names of the assets attached to the release
+// with the given ID.
+func (c *Client) ListReleaseAssets(ctx context.Context, releaseID int64) ([]string, error) {
+	var names []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		assets, resp, err := c.Repositories.ListReleaseAssets(ctx, c.repo.Owner, c.repo.Name, releaseID, opts)
+		if err != nil {
+			return nil, apiError(err)
+		}
+		for _, asset := range assets {
+			names = append(names, asset.GetName())
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return names, nil
+}
+
+// UploadReleaseAsset attaches a file with the given name and content to the
+// release with the given ID.
+func (c *Client) UploadReleaseAsset(ctx context.Context, releaseID int64, name string, content []byte) error {
+	f, err := os.CreateTemp("", "release-asset-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err := f.Write(content); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, _, err = c.Repositories.UploadReleaseAsset(ctx, c.repo.Owner, c.repo.Name, releaseID, &github.UploadOptions{Name: name}, f)
+	return apiError(err)
 }
 
 // CreateIssueComment adds a comment to the issue number provided.
@@ -242,7 +524,181 @@ func (c *Client) CreateIssueComment(ctx context.Context, number int, comment str
 	_, _, err := c.Issues.CreateComment(ctx, c.repo.Owner, c.repo.Name, number, &github.IssueComment{
 		Body: &comment,
 	})
-	return err
+	return apiError(err)
+}
+
+// ListIssueComments returns the comments on the issue or pull request number
+// provided, oldest first.
+func (c *Client) ListIssueComments(ctx context.Context, number int) ([]*IssueComment, error) {
+	var all []*IssueComment
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := c.Issues.ListComments(ctx, c.repo.Owner, c.repo.Name, number, opts)
+		if err != nil {
+			return nil, apiError(err)
+		}
+		all = append(all, comments...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// GetAuthenticatedUser returns the login of the user who owns the token of
+// the client.
+func (c *Client) GetAuthenticatedUser(ctx context.Context) (string, error) {
+	user, _, err := c.Users.Get(ctx, "")
+	if err != nil {
+		return "", apiError(err)
+	}
+	return user.GetLogin(), nil
+}
+
+// IsTeamMember reports whether user is an active member of the team with the
+// given slug in the organization org.
+func (c *Client) IsTeamMember(ctx context.Context, org, team, user string) (bool, error) {
+	membership, resp, err := c.Teams.GetTeamMembershipBySlug(ctx, org, team, user)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, apiError(err)
+	}
+	return membership.GetState() == "active", nil
+}
+
+// CreateIssue creates an issue in the repository with the given labels.
+func (c *Client) CreateIssue(ctx context.Context, title, body string, labels []string) (*Issue, error) {
+	slog.Info("Creating issue", "title", title, "labels", labels)
+	issue, _, err := c.Issues.Create(ctx, c.repo.Owner, c.repo.Name, &github.IssueRequest{
+		Title:  &title,
+		Body:   &body,
+		Labels: &labels,
+	})
+	return issue, apiError(err)
+}
+
+// FindOpenIssueWithLabel returns the most recently created open issue (not
+// pull request) with the given label, or nil if there is no such issue.
+func (c *Client) FindOpenIssueWithLabel(ctx context.Context, label string) (*Issue, error) {
+	issues, _, err := c.Issues.ListByRepo(ctx, c.repo.Owner, c.repo.Name, &github.IssueListByRepoOptions{
+		State:       "open",
+		Labels:      []string{label},
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return nil, apiError(err)
+	}
+	for _, issue := range issues {
+		if !issue.IsPullRequest() {
+			return issue, nil
+		}
+	}
+	return nil, nil
+}
+
+// RequestReviewers requests reviews on the pull request number provided from
+// the given users and teams. Team names are team slugs within the repository
+// owner's organization.
+func (c *Client) RequestReviewers(ctx context.Context, number int, users, teams []string) error {
+	slog.Info("Requesting reviewers", "number", number, "users", users, "teams", teams)
+	_, _, err := c.PullRequests.RequestReviewers(ctx, c.repo.Owner, c.repo.Name, number, github.ReviewersRequest{
+		Reviewers:     users,
+		TeamReviewers: teams,
+	})
+	return apiError(err)
+}
+
+// RequiresMergeQueue reports whether the rulesets of the repository require
+// the pull requests to branch to be merged through a merge queue.
+func (c *Client) RequiresMergeQueue(ctx context.Context, branch string) (bool, error) {
+	rules, _, err := c.Repositories.GetRulesForBranch(ctx, c.repo.Owner, c.repo.Name, branch)
+	if err != nil {
+		return false, apiError(err)
+	}
+	return rules != nil && len(rules.MergeQueue) > 0, nil
+}
+
+// IsInMergeQueue reports whether the pull request with the given number is
+// queued in a merge queue. Only the GraphQL API exposes the merge queue.
+func (c *Client) IsInMergeQueue(ctx context.Context, number int) (bool, error) {
+	query := map[string]any{
+		"query": `query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      isInMergeQueue
+    }
+  }
+}`,
+		"variables": map[string]any{
+			"owner":  c.repo.Owner,
+			"name":   c.repo.Name,
+			"number": number,
+		},
+	}
+	req, err := c.NewRequest(http.MethodPost, c.graphQLURL(), query)
+	if err != nil {
+		return false, err
+	}
+	var resp struct {
+		Data struct {
+			Repository struct {
+				PullRequest *struct {
+					IsInMergeQueue bool `json:"isInMergeQueue"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := c.Do(ctx, req, &resp); err != nil {
+		return false, apiError(err)
+	}
+	if len(resp.Errors) > 0 {
+		return false, failure.New(failure.ForgeAPI, fmt.Errorf("failed to query merge queue of pull request %d: %s", number, resp.Errors[0].Message))
+	}
+	if resp.Data.Repository.PullRequest == nil {
+		return false, failure.New(failure.ForgeAPI, fmt.Errorf("pull request %d not found", number))
+	}
+	return resp.Data.Repository.PullRequest.IsInMergeQueue, nil
+}
+
+// graphQLURL returns the URL of the GraphQL API, relative to the base URL of
+// the REST API: https://api.github.com/graphql for github.com, and
+// https://{host}/api/graphql for a GitHub Enterprise Server.
+func (c *Client) graphQLURL() string {
+	if strings.HasSuffix(c.BaseURL.Path, "/api/v3/") {
+		return "../graphql"
+	}
+	return "graphql"
+}
+
+// ListCommitsTouching returns the SHAs of the commits of branch which change
+// path, committed at or after since, from the newest to the oldest.
+func (c *Client) ListCommitsTouching(ctx context.Context, branch, path string, since time.Time) ([]string, error) {
+	var shas []string
+	opts := &github.CommitsListOptions{
+		SHA:         branch,
+		Path:        path,
+		Since:       since,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		commits, resp, err := c.Repositories.ListCommits(ctx, c.repo.Owner, c.repo.Name, opts)
+		if err != nil {
+			return nil, apiError(err)
+		}
+		for _, commit := range commits {
+			shas = append(shas, commit.GetSHA())
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return shas, nil
 }
 
 // hasLabel checks if a pull request has a given label.
@@ -267,7 +723,7 @@ func (c *Client) FindMergedPullRequestsWithPendingReleaseLabel(ctx context.Conte
 	for {
 		prs, resp, err := c.PullRequests.List(ctx, owner, repo, opt)
 		if err != nil {
-			return nil, err
+			return nil, apiError(err)
 		}
 		for _, pr := range prs {
 			if (pr.GetMerged() || pr.GetMergeCommitSHA() != "") && hasLabel(pr, "release:pending") {
@@ -281,3 +737,27 @@ func (c *Client) FindMergedPullRequestsWithPendingReleaseLabel(ctx context.Conte
 	}
 	return allPRs, nil
 }
+
+// apiError classifies an error returned by the GitHub API. Network errors,
+// rate limits and server errors are likely to succeed on retry.
+func apiError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var rateLimitErr *github.RateLimitError
+	var abuseRateLimitErr *github.AbuseRateLimitError
+	var responseErr *github.ErrorResponse
+	var urlErr *url.Error
+	switch {
+	case errors.As(err, &rateLimitErr), errors.As(err, &abuseRateLimitErr):
+		return failure.New(failure.TransientInfra, err)
+	case errors.As(err, &responseErr):
+		if responseErr.Response != nil && responseErr.Response.StatusCode >= http.StatusInternalServerError {
+			return failure.New(failure.TransientInfra, err)
+		}
+		return failure.New(failure.ForgeAPI, err)
+	case errors.As(err, &urlErr):
+		return failure.New(failure.TransientInfra, err)
+	}
+	return failure.New(failure.ForgeAPI, err)
+}
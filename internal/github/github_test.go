@@ -18,16 +18,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	gogitConfig "github.com/go-git/go-git/v5/config"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-github/v69/github"
+	"github.com/googleapis/librarian/internal/failure"
 	"github.com/googleapis/librarian/internal/gitrepo"
 )
 
@@ -35,7 +38,7 @@ func TestToken(t *testing.T) {
 	t.Parallel()
 	want := "fake-token"
 	repo := &Repository{Owner: "owner", Name: "repo"}
-	client, err := NewClient(want, repo)
+	client, err := NewClient(want, repo, nil)
 	if err != nil {
 		t.Fatalf("NewClient() error = %v", err)
 	}
@@ -44,6 +47,276 @@ func TestToken(t *testing.T) {
 	}
 }
 
+func TestNewClientEnterprise(t *testing.T) {
+	t.Parallel()
+	repo := &Repository{Owner: "owner", Name: "repo", Host: "github.example.com"}
+	for _, test := range []struct {
+		name          string
+		endpoints     *Endpoints
+		wantBaseURL   string
+		wantUploadURL string
+		wantErr       bool
+	}{
+		{
+			name:          "github.com",
+			wantBaseURL:   "https://api.github.com/",
+			wantUploadURL: "https://uploads.github.com/",
+		},
+		{
+			name: "enterprise",
+			endpoints: &Endpoints{
+				APIURL:    "https://github.example.com/api/v3/",
+				UploadURL: "https://github.example.com/api/uploads/",
+			},
+			wantBaseURL:   "https://github.example.com/api/v3/",
+			wantUploadURL: "https://github.example.com/api/uploads/",
+		},
+		{
+			name:          "enterprise without upload url",
+			endpoints:     &Endpoints{APIURL: "https://github.example.com"},
+			wantBaseURL:   "https://github.example.com/api/v3/",
+			wantUploadURL: "https://github.example.com/api/uploads/",
+		},
+		{
+			name:      "invalid url",
+			endpoints: &Endpoints{APIURL: "://github.example.com"},
+			wantErr:   true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			client, err := NewClient("fake-token", repo, test.endpoints)
+			if test.wantErr {
+				if failure.CategoryOf(err) != failure.UserConfig {
+					t.Errorf("NewClient() error = %v, want %q error", err, failure.UserConfig)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			if got := client.BaseURL.String(); got != test.wantBaseURL {
+				t.Errorf("BaseURL = %q, want %q", got, test.wantBaseURL)
+			}
+			if got := client.UploadURL.String(); got != test.wantUploadURL {
+				t.Errorf("UploadURL = %q, want %q", got, test.wantUploadURL)
+			}
+		})
+	}
+}
+
+func TestEndpointsHost(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name      string
+		endpoints *Endpoints
+		want      string
+	}{
+		{
+			name: "nil",
+			want: DefaultHost,
+		},
+		{
+			name:      "empty",
+			endpoints: &Endpoints{},
+			want:      DefaultHost,
+		},
+		{
+			name:      "enterprise",
+			endpoints: &Endpoints{APIURL: "https://github.example.com/api/v3/"},
+			want:      "github.example.com",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.endpoints.Host(); got != test.want {
+				t.Errorf("Host() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckPermissions(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name        string
+		status      int
+		scopes      []string
+		body        string
+		permissions []Permission
+		// wantErr is a substring of the wanted error, if any.
+		wantErr      string
+		wantCategory failure.Category
+	}{
+		{
+			name:        "classic token with repo scope",
+			scopes:      []string{"repo, read:org"},
+			body:        `{"private": true}`,
+			permissions: []Permission{PermissionContentsWrite, PermissionPullRequestsWrite},
+		},
+		{
+			name:        "classic token with public_repo scope on a public repository",
+			scopes:      []string{"public_repo"},
+			body:        `{"private": false}`,
+			permissions: []Permission{PermissionContentsWrite},
+		},
+		{
+			name:         "classic token with public_repo scope on a private repository",
+			scopes:       []string{"public_repo"},
+			body:         `{"private": true}`,
+			permissions:  []Permission{PermissionContentsWrite, PermissionPullRequestsWrite},
+			wantErr:      `lacks the "repo" scope, which grants contents:write, pull_requests:write on owner/repo; the token has the scopes [public_repo]`,
+			wantCategory: failure.UserConfig,
+		},
+		{
+			name:         "classic token without scopes",
+			scopes:       []string{""},
+			body:         `{"private": false}`,
+			permissions:  []Permission{PermissionIssuesWrite},
+			wantErr:      `lacks the "repo" or "public_repo" scope`,
+			wantCategory: failure.UserConfig,
+		},
+		{
+			name:        "fine-grained token with push role",
+			body:        `{"permissions": {"pull": true, "triage": true, "push": true}}`,
+			permissions: []Permission{PermissionContentsWrite, PermissionIssuesWrite},
+		},
+		{
+			name:         "fine-grained token with triage role",
+			body:         `{"permissions": {"pull": true, "triage": true}}`,
+			permissions:  []Permission{PermissionIssuesWrite, PermissionContentsWrite, PermissionPullRequestsWrite},
+			wantErr:      `lacks contents:write, pull_requests:write on owner/repo, which requires its owner to have at least the "push" role`,
+			wantCategory: failure.UserConfig,
+		},
+		{
+			name:        "token without reported permissions",
+			body:        `{}`,
+			permissions: []Permission{PermissionContentsWrite},
+		},
+		{
+			name:         "inaccessible repository",
+			status:       http.StatusNotFound,
+			permissions:  []Permission{PermissionContentsWrite},
+			wantErr:      "does not exist or is not accessible",
+			wantCategory: failure.UserConfig,
+		},
+		{
+			name:         "invalid token",
+			status:       http.StatusUnauthorized,
+			permissions:  []Permission{PermissionContentsWrite},
+			wantErr:      "GitHub token is not valid",
+			wantCategory: failure.UserConfig,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v3/repos/owner/repo" {
+					t.Errorf("request path = %q, want %q", r.URL.Path, "/api/v3/repos/owner/repo")
+				}
+				for _, scopes := range test.scopes {
+					w.Header().Add("X-OAuth-Scopes", scopes)
+				}
+				if test.status != 0 {
+					w.WriteHeader(test.status)
+					return
+				}
+				fmt.Fprint(w, test.body)
+			}))
+			defer server.Close()
+
+			client, err := newClientWithHTTP("fake-token", &Repository{Owner: "owner", Name: "repo"}, &Endpoints{APIURL: server.URL}, server.Client())
+			if err != nil {
+				t.Fatalf("newClientWithHTTP() error = %v", err)
+			}
+			err = client.CheckPermissions(context.Background(), test.permissions...)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CheckPermissions() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("CheckPermissions() error = %v, want it to contain %q", err, test.wantErr)
+			}
+			if got := failure.CategoryOf(err); got != test.wantCategory {
+				t.Errorf("CheckPermissions() error category = %q, want %q", got, test.wantCategory)
+			}
+		})
+	}
+}
+
+func TestValidateToken(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name         string
+		status       int
+		wantErr      bool
+		wantCategory failure.Category
+	}{
+		{
+			name:   "valid token",
+			status: http.StatusOK,
+		},
+		{
+			name:         "invalid token",
+			status:       http.StatusUnauthorized,
+			wantErr:      true,
+			wantCategory: failure.UserConfig,
+		},
+		{
+			name:         "server error",
+			status:       http.StatusBadGateway,
+			wantErr:      true,
+			wantCategory: failure.TransientInfra,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v3/user" {
+					t.Errorf("request path = %q, want %q", r.URL.Path, "/api/v3/user")
+				}
+				w.WriteHeader(test.status)
+				fmt.Fprint(w, `{"login": "octocat"}`)
+			}))
+			defer server.Close()
+
+			client, err := newClientWithHTTP("fake-token", &Repository{Owner: "owner", Name: "repo"}, &Endpoints{APIURL: server.URL}, server.Client())
+			if err != nil {
+				t.Fatalf("newClientWithHTTP() error = %v", err)
+			}
+			err = client.ValidateToken(context.Background())
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ValidateToken() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if got := failure.CategoryOf(err); test.wantErr && got != test.wantCategory {
+				t.Errorf("ValidateToken() error category = %q, want %q", got, test.wantCategory)
+			}
+		})
+	}
+}
+
+func TestRepositoryURL(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		repo *Repository
+		want string
+	}{
+		{
+			repo: &Repository{Owner: "owner", Name: "repo"},
+			want: "https://github.com/owner/repo",
+		},
+		{
+			repo: &Repository{Owner: "owner", Name: "repo", Host: "github.example.com"},
+			want: "https://github.example.com/owner/repo",
+		},
+	} {
+		if got := test.repo.URL(); got != test.want {
+			t.Errorf("URL() = %q, want %q", got, test.want)
+		}
+	}
+}
+
 func TestGetRawContent(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -101,7 +374,7 @@ func TestGetRawContent(t *testing.T) {
 			defer server.Close()
 
 			repo := &Repository{Owner: "owner", Name: "repo"}
-			client, err := newClientWithHTTP("fake-token", repo, server.Client())
+			client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
 			if err != nil {
 				t.Fatalf("newClientWithHTTP() error = %v", err)
 			}
@@ -170,6 +443,13 @@ func TestFetchGitHubRepoFromRemote(t *testing.T) {
 			},
 			wantRepo: &Repository{Owner: "owner", Name: "repo"},
 		},
+		{
+			name: "origin is a GitHub SSH remote",
+			remotes: map[string][]string{
+				"origin": {"git@github.com:owner/repo.git"},
+			},
+			wantRepo: &Repository{Owner: "owner", Name: "repo"},
+		},
 		{
 			name:          "No remotes",
 			remotes:       map[string][]string{},
@@ -263,6 +543,16 @@ func TestParseURL(t *testing.T) {
 			wantRepo:  &Repository{Owner: "owner", Name: "repo"},
 			wantErr:   false,
 		},
+		{
+			name:      "Valid SSH remote",
+			remoteURL: "git@github.com:owner/repo.git",
+			wantRepo:  &Repository{Owner: "owner", Name: "repo"},
+		},
+		{
+			name:      "Valid SSH URL",
+			remoteURL: "ssh://git@github.com/owner/repo.git",
+			wantRepo:  &Repository{Owner: "owner", Name: "repo"},
+		},
 		{
 			name:          "Invalid URL scheme",
 			remoteURL:     "http://github.com/owner/repo.git",
@@ -376,7 +666,7 @@ func TestCreatePullRequest(t *testing.T) {
 			defer server.Close()
 
 			repo := &Repository{Owner: "owner", Name: "repo"}
-			client, err := newClientWithHTTP("fake-token", repo, server.Client())
+			client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
 			if err != nil {
 				t.Fatalf("newClientWithHTTP() error = %v", err)
 			}
@@ -446,7 +736,7 @@ func TestAddLabelsToIssue(t *testing.T) {
 			defer server.Close()
 
 			repo := &Repository{Owner: "owner", Name: "repo"}
-			client, err := newClientWithHTTP("fake-token", repo, server.Client())
+			client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
 			if err != nil {
 				t.Fatalf("newClientWithHTTP() error = %v", err)
 			}
@@ -511,7 +801,7 @@ func TestGetLabels(t *testing.T) {
 			defer server.Close()
 
 			repo := &Repository{Owner: "owner", Name: "repo"}
-			client, err := newClientWithHTTP("fake-token", repo, server.Client())
+			client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
 			if err != nil {
 				t.Fatalf("newClientWithHTTP() error = %v", err)
 			}
@@ -587,7 +877,7 @@ func TestReplaceLabels(t *testing.T) {
 			defer server.Close()
 
 			repo := &Repository{Owner: "owner", Name: "repo"}
-			client, err := newClientWithHTTP("fake-token", repo, server.Client())
+			client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
 			if err != nil {
 				t.Fatalf("newClientWithHTTP() error = %v", err)
 			}
@@ -696,7 +986,7 @@ func TestSearchPullRequests(t *testing.T) {
 			defer server.Close()
 
 			repo := &Repository{Owner: "owner", Name: "repo"}
-			client, err := newClientWithHTTP("fake-token", repo, server.Client())
+			client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
 			if err != nil {
 				t.Fatalf("newClientWithHTTP() error = %v", err)
 			}
@@ -762,7 +1052,7 @@ func TestGetPullRequest(t *testing.T) {
 			defer server.Close()
 
 			repo := &Repository{Owner: "owner", Name: "repo"}
-			client, err := newClientWithHTTP("fake-token", repo, server.Client())
+			client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
 			if err != nil {
 				t.Fatalf("newClientWithHTTP() error = %v", err)
 			}
@@ -795,6 +1085,7 @@ func TestCreateRelease(t *testing.T) {
 		releaseName   string
 		body          string
 		commitish     string
+		prerelease    bool
 		handler       http.HandlerFunc
 		wantRelease   *github.RepositoryRelease
 		wantErr       bool
@@ -821,10 +1112,31 @@ func TestCreateRelease(t *testing.T) {
 				if *newRelease.TagName != "v1.0.0" {
 					t.Errorf("unexpected tag name: got %q, want %q", *newRelease.TagName, "v1.0.0")
 				}
+				if newRelease.GetPrerelease() {
+					t.Errorf("unexpected prerelease: got true, want false")
+				}
 				fmt.Fprint(w, `{"tag_name": "v1.0.0", "name": "Version 1.0.0"}`)
 			},
 			wantRelease: &github.RepositoryRelease{TagName: github.Ptr("v1.0.0"), Name: github.Ptr("Version 1.0.0")},
 		},
+		{
+			name:        "Prerelease",
+			tagName:     "v1.1.0-beta.1",
+			releaseName: "Version 1.1.0-beta.1",
+			commitish:   "main",
+			prerelease:  true,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				var newRelease github.RepositoryRelease
+				if err := json.NewDecoder(r.Body).Decode(&newRelease); err != nil {
+					t.Fatalf("failed to decode request body: %v", err)
+				}
+				if !newRelease.GetPrerelease() {
+					t.Errorf("unexpected prerelease: got false, want true")
+				}
+				fmt.Fprint(w, `{"tag_name": "v1.1.0-beta.1", "prerelease": true}`)
+			},
+			wantRelease: &github.RepositoryRelease{TagName: github.Ptr("v1.1.0-beta.1"), Prerelease: github.Ptr(true)},
+		},
 		{
 			name:          "API Error",
 			tagName:       "v1.0.0",
@@ -842,13 +1154,13 @@ func TestCreateRelease(t *testing.T) {
 			defer server.Close()
 
 			repo := &Repository{Owner: "owner", Name: "repo"}
-			client, err := newClientWithHTTP("fake-token", repo, server.Client())
+			client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
 			if err != nil {
 				t.Fatalf("newClientWithHTTP() error = %v", err)
 			}
 			client.BaseURL, _ = url.Parse(server.URL + "/")
 
-			release, err := client.CreateRelease(context.Background(), test.tagName, test.releaseName, test.body, test.commitish)
+			release, err := client.CreateRelease(context.Background(), test.tagName, test.releaseName, test.body, test.commitish, test.prerelease)
 
 			if test.wantErr {
 				if err == nil {
@@ -867,6 +1179,163 @@ func TestCreateRelease(t *testing.T) {
 	}
 }
 
+func TestListReleaseTags(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/releases" {
+			t.Errorf("unexpected path: got %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `[{"tag_name": "foo-1.1.0"}]`)
+			return
+		}
+		w.Header().Set("Link", `<http://`+r.Host+`/repos/owner/repo/releases?page=2>; rel="next"`)
+		fmt.Fprint(w, `[{"tag_name": "foo-1.0.0"}, {"tag_name": "bar-2.0.0"}]`)
+	}))
+	defer server.Close()
+
+	repo := &Repository{Owner: "owner", Name: "repo"}
+	client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
+	if err != nil {
+		t.Fatalf("newClientWithHTTP() error = %v", err)
+	}
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	got, err := client.ListReleaseTags(context.Background())
+	if err != nil {
+		t.Fatalf("ListReleaseTags() error = %v", err)
+	}
+	want := []string{"foo-1.0.0", "bar-2.0.0", "foo-1.1.0"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ListReleaseTags() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUpdatePullRequestBody(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("unexpected method: got %s, want %s", r.Method, http.MethodPatch)
+		}
+		if r.URL.Path != "/repos/owner/repo/pulls/42" {
+			t.Errorf("unexpected path: got %s", r.URL.Path)
+		}
+		var pr github.PullRequest
+		if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
+			t.Fatalf("failed to decode request body: %v", err)
+		}
+		if pr.GetBody() != "new body" {
+			t.Errorf("unexpected body: got %q, want %q", pr.GetBody(), "new body")
+		}
+		if pr.Title != nil {
+			t.Errorf("unexpected title: got %q, want none", pr.GetTitle())
+		}
+		fmt.Fprint(w, `{"number": 42}`)
+	}))
+	defer server.Close()
+
+	repo := &Repository{Owner: "owner", Name: "repo"}
+	client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
+	if err != nil {
+		t.Fatalf("newClientWithHTTP() error = %v", err)
+	}
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	if err := client.UpdatePullRequestBody(context.Background(), 42, "new body"); err != nil {
+		t.Errorf("UpdatePullRequestBody() error = %v", err)
+	}
+}
+
+func TestGetReleaseByTag(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/releases/tags/foo-1.0.0" {
+			t.Errorf("unexpected path: got %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"id": 123, "tag_name": "foo-1.0.0"}`)
+	}))
+	defer server.Close()
+
+	repo := &Repository{Owner: "owner", Name: "repo"}
+	client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
+	if err != nil {
+		t.Fatalf("newClientWithHTTP() error = %v", err)
+	}
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	got, err := client.GetReleaseByTag(context.Background(), "foo-1.0.0")
+	if err != nil {
+		t.Fatalf("GetReleaseByTag() error = %v", err)
+	}
+	want := &github.RepositoryRelease{ID: github.Ptr(int64(123)), TagName: github.Ptr("foo-1.0.0")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetReleaseByTag() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestListReleaseAssets(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/releases/123/assets" {
+			t.Errorf("unexpected path: got %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `[{"id": 3, "name": "foo-1.0.0.tar.gz.sig"}]`)
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=2>; rel="next"`, "http://"+r.Host, r.URL.Path))
+		fmt.Fprint(w, `[{"id": 1, "name": "sbom.cdx.json"}, {"id": 2, "name": "foo-1.0.0.tar.gz"}]`)
+	}))
+	defer server.Close()
+
+	repo := &Repository{Owner: "owner", Name: "repo"}
+	client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
+	if err != nil {
+		t.Fatalf("newClientWithHTTP() error = %v", err)
+	}
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	got, err := client.ListReleaseAssets(context.Background(), 123)
+	if err != nil {
+		t.Fatalf("ListReleaseAssets() error = %v", err)
+	}
+	want := []string{"sbom.cdx.json", "foo-1.0.0.tar.gz", "foo-1.0.0.tar.gz.sig"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ListReleaseAssets() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUploadReleaseAsset(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("unexpected method: got %s, want %s", r.Method, http.MethodPost)
+		}
+		wantPath := "/repos/owner/repo/releases/123/assets"
+		if r.URL.Path != wantPath {
+			t.Errorf("unexpected path: got %s, want %s", r.URL.Path, wantPath)
+		}
+		if got := r.URL.Query().Get("name"); got != "sbom.cdx.json" {
+			t.Errorf("unexpected name: got %q, want %q", got, "sbom.cdx.json")
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != "{}" {
+			t.Errorf("unexpected body: got %q, want %q", body, "{}")
+		}
+		fmt.Fprint(w, `{"id": 1}`)
+	}))
+	defer server.Close()
+
+	repo := &Repository{Owner: "owner", Name: "repo"}
+	client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
+	if err != nil {
+		t.Fatalf("newClientWithHTTP() error = %v", err)
+	}
+	client.UploadURL, _ = url.Parse(server.URL + "/")
+	if err := client.UploadReleaseAsset(context.Background(), 123, "sbom.cdx.json", []byte("{}")); err != nil {
+		t.Errorf("UploadReleaseAsset() error = %v", err)
+	}
+}
+
 func TestCreateIssueComment(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -914,7 +1383,7 @@ func TestCreateIssueComment(t *testing.T) {
 			defer server.Close()
 
 			repo := &Repository{Owner: "owner", Name: "repo"}
-			client, err := newClientWithHTTP("fake-token", repo, server.Client())
+			client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
 			if err != nil {
 				t.Fatalf("newClientWithHTTP() error = %v", err)
 			}
@@ -935,42 +1404,45 @@ func TestCreateIssueComment(t *testing.T) {
 	}
 }
 
-func TestFindMergedPullRequestsWithPendingReleaseLabel(t *testing.T) {
+func TestCreateIssue(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
 		name          string
 		handler       http.HandlerFunc
-		wantPRs       []*PullRequest
+		wantNumber    int
 		wantErr       bool
 		wantErrSubstr string
 	}{
 		{
-			name: "Success with single page",
+			name: "Success",
 			handler: func(w http.ResponseWriter, r *http.Request) {
-				if r.URL.Query().Get("state") != "closed" {
-					t.Errorf("unexpected state: got %q", r.URL.Query().Get("state"))
+				if r.Method != http.MethodPost {
+					t.Errorf("unexpected method: got %s, want %s", r.Method, http.MethodPost)
 				}
-				pr0 := github.PullRequest{Number: github.Ptr(0), HTMLURL: github.Ptr("https://github.com/owner/repo/pull/0"), MergeCommitSHA: github.Ptr("sha456"), Labels: []*github.Label{{Name: github.Ptr("release:pending")}}}
-				pr1 := github.PullRequest{Number: github.Ptr(1), Labels: []*github.Label{{Name: github.Ptr("release:pending")}}}
-				pr2 := github.PullRequest{Number: github.Ptr(2), Labels: []*github.Label{{Name: github.Ptr("other-label")}}}
-				pr3 := github.PullRequest{Number: github.Ptr(3), HTMLURL: github.Ptr("https://github.com/owner/repo/pull/3"), MergeCommitSHA: github.Ptr("sha123"), Merged: github.Ptr(true), Labels: []*github.Label{{Name: github.Ptr("release:pending")}}}
-				prs := []*github.PullRequest{&pr0, &pr1, &pr2, &pr3}
-				b, err := json.Marshal(prs)
-				if err != nil {
-					t.Fatalf("json.Marshal() failed: %v", err)
+				wantPath := "/repos/owner/repo/issues"
+				if r.URL.Path != wantPath {
+					t.Errorf("unexpected path: got %s, want %s", r.URL.Path, wantPath)
 				}
-				fmt.Fprint(w, string(b))
-			},
-			wantPRs: []*PullRequest{
-				{Number: github.Ptr(0), HTMLURL: github.Ptr("https://github.com/owner/repo/pull/0"), MergeCommitSHA: github.Ptr("sha456"), Labels: []*github.Label{{Name: github.Ptr("release:pending")}}},
-				{Number: github.Ptr(3), HTMLURL: github.Ptr("https://github.com/owner/repo/pull/3"), MergeCommitSHA: github.Ptr("sha123"), Merged: github.Ptr(true), Labels: []*github.Label{{Name: github.Ptr("release:pending")}}},
+				var request github.IssueRequest
+				if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+					t.Fatalf("failed to decode request body: %v", err)
+				}
+				want := github.IssueRequest{
+					Title:  github.Ptr("title"),
+					Body:   github.Ptr("body"),
+					Labels: &[]string{"label"},
+				}
+				if diff := cmp.Diff(want, request); diff != "" {
+					t.Errorf("CreateIssue() request mismatch (-want +got):\n%s", diff)
+				}
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"number": 7}`)
 			},
+			wantNumber: 7,
 		},
 		{
-			name: "API error",
-			handler: func(w http.ResponseWriter, r *http.Request) {
-				w.WriteHeader(http.StatusInternalServerError)
-			},
+			name:          "API Error",
+			handler:       func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
 			wantErr:       true,
 			wantErrSubstr: "500",
 		},
@@ -981,27 +1453,666 @@ func TestFindMergedPullRequestsWithPendingReleaseLabel(t *testing.T) {
 			defer server.Close()
 
 			repo := &Repository{Owner: "owner", Name: "repo"}
-			client, err := newClientWithHTTP("fake-token", repo, server.Client())
+			client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
 			if err != nil {
 				t.Fatalf("newClientWithHTTP() error = %v", err)
 			}
 			client.BaseURL, _ = url.Parse(server.URL + "/")
 
-			prs, err := client.FindMergedPullRequestsWithPendingReleaseLabel(context.Background(), "owner", "repo")
-
+			issue, err := client.CreateIssue(context.Background(), "title", "body", []string{"label"})
 			if test.wantErr {
 				if err == nil {
-					t.Errorf("FindMergedPullRequestsWithPendingReleaseLabel() err = nil, want error containing %q", test.wantErrSubstr)
+					t.Errorf("CreateIssue() err = nil, want error containing %q", test.wantErrSubstr)
 				} else if !strings.Contains(err.Error(), test.wantErrSubstr) {
-					t.Errorf("FindMergedPullRequestsWithPendingReleaseLabel() err = %v, want error containing %q", err, test.wantErrSubstr)
+					t.Errorf("CreateIssue() err = %v, want error containing %q", err, test.wantErrSubstr)
 				}
-			} else if err != nil {
-				t.Errorf("FindMergedPullRequestsWithPendingReleaseLabel() err = %v, want nil", err)
+				return
 			}
-
-			if diff := cmp.Diff(test.wantPRs, prs); diff != "" {
-				t.Errorf("FindMergedPullRequestsWithPendingReleaseLabel() prs mismatch (-want +got):\n%s", diff)
+			if err != nil {
+				t.Fatalf("CreateIssue() err = %v, want nil", err)
+			}
+			if issue.GetNumber() != test.wantNumber {
+				t.Errorf("CreateIssue() number = %d, want %d", issue.GetNumber(), test.wantNumber)
+			}
+		})
+	}
+}
+
+func TestFindOpenIssueWithLabel(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name          string
+		handler       http.HandlerFunc
+		wantNumber    int
+		wantErr       bool
+		wantErrSubstr string
+	}{
+		{
+			name: "Found",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				wantPath := "/repos/owner/repo/issues"
+				if r.URL.Path != wantPath {
+					t.Errorf("unexpected path: got %s, want %s", r.URL.Path, wantPath)
+				}
+				if got := r.URL.Query().Get("labels"); got != "some-label" {
+					t.Errorf("unexpected labels: got %s, want %s", got, "some-label")
+				}
+				if got := r.URL.Query().Get("state"); got != "open" {
+					t.Errorf("unexpected state: got %s, want %s", got, "open")
+				}
+				fmt.Fprint(w, `[{"number": 1, "pull_request": {"url": "pr"}}, {"number": 2}]`)
+			},
+			wantNumber: 2,
+		},
+		{
+			name: "Not found",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `[]`)
+			},
+		},
+		{
+			name:          "API Error",
+			handler:       func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			wantErr:       true,
+			wantErrSubstr: "500",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			repo := &Repository{Owner: "owner", Name: "repo"}
+			client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
+			if err != nil {
+				t.Fatalf("newClientWithHTTP() error = %v", err)
+			}
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			issue, err := client.FindOpenIssueWithLabel(context.Background(), "some-label")
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("FindOpenIssueWithLabel() err = nil, want error containing %q", test.wantErrSubstr)
+				} else if !strings.Contains(err.Error(), test.wantErrSubstr) {
+					t.Errorf("FindOpenIssueWithLabel() err = %v, want error containing %q", err, test.wantErrSubstr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindOpenIssueWithLabel() err = %v, want nil", err)
+			}
+			if issue.GetNumber() != test.wantNumber {
+				t.Errorf("FindOpenIssueWithLabel() number = %d, want %d", issue.GetNumber(), test.wantNumber)
+			}
+		})
+	}
+}
+
+func TestRequestReviewers(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name          string
+		users         []string
+		teams         []string
+		handler       http.HandlerFunc
+		wantErr       bool
+		wantErrSubstr string
+	}{
+		{
+			name:  "Success",
+			users: []string{"octocat"},
+			teams: []string{"yoshi-go"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("unexpected method: got %s, want %s", r.Method, http.MethodPost)
+				}
+				wantPath := "/repos/owner/repo/pulls/123/requested_reviewers"
+				if r.URL.Path != wantPath {
+					t.Errorf("unexpected path: got %s, want %s", r.URL.Path, wantPath)
+				}
+				var request github.ReviewersRequest
+				if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+					t.Fatalf("failed to decode request body: %v", err)
+				}
+				want := github.ReviewersRequest{
+					Reviewers:     []string{"octocat"},
+					TeamReviewers: []string{"yoshi-go"},
+				}
+				if diff := cmp.Diff(want, request); diff != "" {
+					t.Errorf("RequestReviewers() request mismatch (-want +got):\n%s", diff)
+				}
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"number": 123}`)
+			},
+		},
+		{
+			name:          "API Error",
+			users:         []string{"octocat"},
+			handler:       func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusUnprocessableEntity) },
+			wantErr:       true,
+			wantErrSubstr: "422",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			repo := &Repository{Owner: "owner", Name: "repo"}
+			client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
+			if err != nil {
+				t.Fatalf("newClientWithHTTP() error = %v", err)
+			}
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			err = client.RequestReviewers(context.Background(), 123, test.users, test.teams)
+
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("RequestReviewers() err = nil, want error containing %q", test.wantErrSubstr)
+				} else if !strings.Contains(err.Error(), test.wantErrSubstr) {
+					t.Errorf("RequestReviewers() err = %v, want error containing %q", err, test.wantErrSubstr)
+				}
+			} else if err != nil {
+				t.Errorf("RequestReviewers() err = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestFindMergedPullRequestsWithPendingReleaseLabel(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name          string
+		handler       http.HandlerFunc
+		wantPRs       []*PullRequest
+		wantErr       bool
+		wantErrSubstr string
+	}{
+		{
+			name: "Success with single page",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("state") != "closed" {
+					t.Errorf("unexpected state: got %q", r.URL.Query().Get("state"))
+				}
+				pr0 := github.PullRequest{Number: github.Ptr(0), HTMLURL: github.Ptr("https://github.com/owner/repo/pull/0"), MergeCommitSHA: github.Ptr("sha456"), Labels: []*github.Label{{Name: github.Ptr("release:pending")}}}
+				pr1 := github.PullRequest{Number: github.Ptr(1), Labels: []*github.Label{{Name: github.Ptr("release:pending")}}}
+				pr2 := github.PullRequest{Number: github.Ptr(2), Labels: []*github.Label{{Name: github.Ptr("other-label")}}}
+				pr3 := github.PullRequest{Number: github.Ptr(3), HTMLURL: github.Ptr("https://github.com/owner/repo/pull/3"), MergeCommitSHA: github.Ptr("sha123"), Merged: github.Ptr(true), Labels: []*github.Label{{Name: github.Ptr("release:pending")}}}
+				prs := []*github.PullRequest{&pr0, &pr1, &pr2, &pr3}
+				b, err := json.Marshal(prs)
+				if err != nil {
+					t.Fatalf("json.Marshal() failed: %v", err)
+				}
+				fmt.Fprint(w, string(b))
+			},
+			wantPRs: []*PullRequest{
+				{Number: github.Ptr(0), HTMLURL: github.Ptr("https://github.com/owner/repo/pull/0"), MergeCommitSHA: github.Ptr("sha456"), Labels: []*github.Label{{Name: github.Ptr("release:pending")}}},
+				{Number: github.Ptr(3), HTMLURL: github.Ptr("https://github.com/owner/repo/pull/3"), MergeCommitSHA: github.Ptr("sha123"), Merged: github.Ptr(true), Labels: []*github.Label{{Name: github.Ptr("release:pending")}}},
+			},
+		},
+		{
+			name: "API error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantErr:       true,
+			wantErrSubstr: "500",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			repo := &Repository{Owner: "owner", Name: "repo"}
+			client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
+			if err != nil {
+				t.Fatalf("newClientWithHTTP() error = %v", err)
+			}
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			prs, err := client.FindMergedPullRequestsWithPendingReleaseLabel(context.Background(), "owner", "repo")
+
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("FindMergedPullRequestsWithPendingReleaseLabel() err = nil, want error containing %q", test.wantErrSubstr)
+				} else if !strings.Contains(err.Error(), test.wantErrSubstr) {
+					t.Errorf("FindMergedPullRequestsWithPendingReleaseLabel() err = %v, want error containing %q", err, test.wantErrSubstr)
+				}
+			} else if err != nil {
+				t.Errorf("FindMergedPullRequestsWithPendingReleaseLabel() err = %v, want nil", err)
+			}
+
+			if diff := cmp.Diff(test.wantPRs, prs); diff != "" {
+				t.Errorf("FindMergedPullRequestsWithPendingReleaseLabel() prs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		handler http.HandlerFunc
+		want    failure.Category
+	}{
+		{
+			name:    "not found",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+			want:    failure.ForgeAPI,
+		},
+		{
+			name:    "validation failed",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusUnprocessableEntity) },
+			want:    failure.ForgeAPI,
+		},
+		{
+			name:    "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadGateway) },
+			want:    failure.TransientInfra,
+		},
+		{
+			name: "rate limit",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-RateLimit-Limit", "5000")
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", fmt.Sprint(time.Now().Add(time.Hour).Unix()))
+				w.WriteHeader(http.StatusForbidden)
+			},
+			want: failure.TransientInfra,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			repo := &Repository{Owner: "owner", Name: "repo"}
+			client, err := newClientWithHTTP("fake-token", repo, nil, server.Client())
+			if err != nil {
+				t.Fatalf("newClientWithHTTP() error = %v", err)
+			}
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			err = client.CreateIssueComment(context.Background(), 1, "comment")
+			if got := failure.CategoryOf(err); got != test.want {
+				t.Errorf("CategoryOf(%v) = %q, want %q", err, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFetchGitHubRepoFromHostRemote(t *testing.T) {
+	t.Parallel()
+	repo := newTestGitRepo(t, map[string][]string{
+		"origin": {"https://github.example.com/owner/repo.git"},
+	})
+	got, err := FetchGitHubRepoFromHostRemote(repo, "github.example.com")
+	if err != nil {
+		t.Fatalf("FetchGitHubRepoFromHostRemote() error = %v", err)
+	}
+	want := &Repository{Owner: "owner", Name: "repo", Host: "github.example.com"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FetchGitHubRepoFromHostRemote() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := FetchGitHubRepoFromRemote(repo); err == nil {
+		t.Error("FetchGitHubRepoFromRemote() error = nil, want error for a remote of another host")
+	}
+}
+
+func TestParseHostURL(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name      string
+		host      string
+		remoteURL string
+		want      *Repository
+		wantErr   bool
+	}{
+		{
+			name:      "enterprise",
+			host:      "github.example.com",
+			remoteURL: "https://github.example.com/owner/repo.git",
+			want:      &Repository{Owner: "owner", Name: "repo", Host: "github.example.com"},
+		},
+		{
+			name:      "default host",
+			host:      DefaultHost,
+			remoteURL: "https://github.com/owner/repo",
+			want:      &Repository{Owner: "owner", Name: "repo"},
+		},
+		{
+			name:      "other host",
+			host:      "github.example.com",
+			remoteURL: "https://github.com/owner/repo",
+			wantErr:   true,
+		},
+		{
+			name:      "missing repository name",
+			host:      "github.example.com",
+			remoteURL: "https://github.example.com/owner",
+			wantErr:   true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseHostURL(test.host, test.remoteURL)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseHostURL() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("ParseHostURL() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListIssueComments(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wantPath := "/repos/owner/repo/issues/7/comments"
+		if r.URL.Path != wantPath {
+			t.Errorf("unexpected path: got %s, want %s", r.URL.Path, wantPath)
+		}
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=2>; rel="next"`, "http://"+r.Host, r.URL.Path))
+			fmt.Fprint(w, `[{"id": 1, "body": "first"}]`)
+			return
+		}
+		fmt.Fprint(w, `[{"id": 2, "body": "second"}]`)
+	}))
+	defer server.Close()
+
+	client, err := newClientWithHTTP("fake-token", &Repository{Owner: "owner", Name: "repo"}, nil, server.Client())
+	if err != nil {
+		t.Fatalf("newClientWithHTTP() error = %v", err)
+	}
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	comments, err := client.ListIssueComments(context.Background(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, comment := range comments {
+		got = append(got, comment.GetBody())
+	}
+	if diff := cmp.Diff([]string{"first", "second"}, got); diff != "" {
+		t.Errorf("ListIssueComments() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetAuthenticatedUser(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			t.Errorf("unexpected path: got %s, want %s", r.URL.Path, "/user")
+		}
+		fmt.Fprint(w, `{"login": "octocat"}`)
+	}))
+	defer server.Close()
+
+	client, err := newClientWithHTTP("fake-token", &Repository{Owner: "owner", Name: "repo"}, nil, server.Client())
+	if err != nil {
+		t.Fatalf("newClientWithHTTP() error = %v", err)
+	}
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	got, err := client.GetAuthenticatedUser(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "octocat" {
+		t.Errorf("GetAuthenticatedUser() = %q, want %q", got, "octocat")
+	}
+}
+
+func TestIsTeamMember(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		handler http.HandlerFunc
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "active member",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				wantPath := "/orgs/googleapis/teams/release-team/memberships/octocat"
+				if r.URL.Path != wantPath {
+					t.Errorf("unexpected path: got %s, want %s", r.URL.Path, wantPath)
+				}
+				fmt.Fprint(w, `{"state": "active"}`)
+			},
+			want: true,
+		},
+		{
+			name: "pending member",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"state": "pending"}`)
+			},
+		},
+		{
+			name:    "not a member",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+		},
+		{
+			name:    "API error",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			client, err := newClientWithHTTP("fake-token", &Repository{Owner: "owner", Name: "repo"}, nil, server.Client())
+			if err != nil {
+				t.Fatalf("newClientWithHTTP() error = %v", err)
+			}
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			got, err := client.IsTeamMember(context.Background(), "googleapis", "release-team", "octocat")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("IsTeamMember() error = %v, wantErr %t", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("IsTeamMember() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRequiresMergeQueue(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		handler http.HandlerFunc
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "merge queue",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				wantPath := "/repos/owner/repo/rules/branches/main"
+				if r.URL.Path != wantPath {
+					t.Errorf("unexpected path: got %s, want %s", r.URL.Path, wantPath)
+				}
+				fmt.Fprint(w, `[
+  {"type": "pull_request", "ruleset_id": 1, "parameters": {"required_approving_review_count": 1}},
+  {"type": "merge_queue", "ruleset_id": 2, "parameters": {"merge_method": "SQUASH", "grouping_strategy": "ALLGREEN"}}
+]`)
+			},
+			want: true,
+		},
+		{
+			name: "no merge queue",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `[{"type": "deletion", "ruleset_id": 1}]`)
+			},
+		},
+		{
+			name:    "no rules",
+			handler: func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, `[]`) },
+		},
+		{
+			name:    "API error",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			client, err := newClientWithHTTP("fake-token", &Repository{Owner: "owner", Name: "repo"}, nil, server.Client())
+			if err != nil {
+				t.Fatalf("newClientWithHTTP() error = %v", err)
+			}
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			got, err := client.RequiresMergeQueue(context.Background(), "main")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("RequiresMergeQueue() error = %v, wantErr %t", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("RequiresMergeQueue() = %t, want %t", got, test.want)
 			}
 		})
 	}
 }
+
+func TestIsInMergeQueue(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name     string
+		basePath string
+		wantPath string
+		response string
+		want     bool
+		wantErr  string
+	}{
+		{
+			name:     "queued",
+			basePath: "/",
+			wantPath: "/graphql",
+			response: `{"data": {"repository": {"pullRequest": {"isInMergeQueue": true}}}}`,
+			want:     true,
+		},
+		{
+			name:     "not queued",
+			basePath: "/",
+			wantPath: "/graphql",
+			response: `{"data": {"repository": {"pullRequest": {"isInMergeQueue": false}}}}`,
+		},
+		{
+			name:     "enterprise server",
+			basePath: "/api/v3/",
+			wantPath: "/api/graphql",
+			response: `{"data": {"repository": {"pullRequest": {"isInMergeQueue": true}}}}`,
+			want:     true,
+		},
+		{
+			name:     "GraphQL error",
+			basePath: "/",
+			wantPath: "/graphql",
+			response: `{"errors": [{"message": "Resource not accessible by integration"}]}`,
+			wantErr:  "Resource not accessible by integration",
+		},
+		{
+			name:     "pull request not found",
+			basePath: "/",
+			wantPath: "/graphql",
+			response: `{"data": {"repository": {"pullRequest": null}}}`,
+			wantErr:  "pull request 42 not found",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("unexpected method: got %s, want %s", r.Method, http.MethodPost)
+				}
+				if r.URL.Path != test.wantPath {
+					t.Errorf("unexpected path: got %s, want %s", r.URL.Path, test.wantPath)
+				}
+				var body struct {
+					Variables map[string]any `json:"variables"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request body: %v", err)
+				}
+				want := map[string]any{"owner": "owner", "name": "repo", "number": float64(42)}
+				if diff := cmp.Diff(want, body.Variables); diff != "" {
+					t.Errorf("variables mismatch (-want +got):\n%s", diff)
+				}
+				fmt.Fprint(w, test.response)
+			}))
+			defer server.Close()
+
+			client, err := newClientWithHTTP("fake-token", &Repository{Owner: "owner", Name: "repo"}, nil, server.Client())
+			if err != nil {
+				t.Fatalf("newClientWithHTTP() error = %v", err)
+			}
+			client.BaseURL, _ = url.Parse(server.URL + test.basePath)
+
+			got, err := client.IsInMergeQueue(context.Background(), 42)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("IsInMergeQueue() error = %v, want to contain %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("IsInMergeQueue() error = %v", err)
+			}
+			if got != test.want {
+				t.Errorf("IsInMergeQueue() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestListCommitsTouching(t *testing.T) {
+	t.Parallel()
+	since := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/commits" {
+			t.Errorf("unexpected path: got %s", r.URL.Path)
+		}
+		query := r.URL.Query()
+		if got, want := query.Get("sha"), "main"; got != want {
+			t.Errorf("sha = %q, want %q", got, want)
+		}
+		if got, want := query.Get("path"), ".librarian/state.yaml"; got != want {
+			t.Errorf("path = %q, want %q", got, want)
+		}
+		if got, want := query.Get("since"), since.Format(time.RFC3339); got != want {
+			t.Errorf("since = %q, want %q", got, want)
+		}
+		if query.Get("page") == "2" {
+			fmt.Fprint(w, `[{"sha": "ccc"}]`)
+			return
+		}
+		w.Header().Set("Link", `<http://`+r.Host+`/repos/owner/repo/commits?page=2>; rel="next"`)
+		fmt.Fprint(w, `[{"sha": "aaa"}, {"sha": "bbb"}]`)
+	}))
+	defer server.Close()
+
+	client, err := newClientWithHTTP("fake-token", &Repository{Owner: "owner", Name: "repo"}, nil, server.Client())
+	if err != nil {
+		t.Fatalf("newClientWithHTTP() error = %v", err)
+	}
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	got, err := client.ListCommitsTouching(context.Background(), "main", ".librarian/state.yaml", since)
+	if err != nil {
+		t.Fatalf("ListCommitsTouching() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"aaa", "bbb", "ccc"}, got); diff != "" {
+		t.Errorf("ListCommitsTouching() mismatch (-want +got):\n%s", diff)
+	}
+}
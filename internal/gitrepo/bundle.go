@@ -0,0 +1,83 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BundleSuffix is the file name suffix of git bundles, as created by
+// "git bundle create".
+const BundleSuffix = ".bundle"
+
+// IsBundle reports whether path names a git bundle file.
+func IsBundle(path string) bool {
+	return strings.HasSuffix(path, BundleSuffix)
+}
+
+// CloneBundle clones the git bundle at bundlePath into dir, checking out the
+// HEAD of the bundle, and opens the clone. An existing dir is opened as is,
+// like [RepositoryOptions.MaybeClone] does for remote repositories. Cloning
+// a bundle reads the local file only, so it works without network access.
+func CloneBundle(bundlePath, dir string) (*LocalRepository, error) {
+	if _, err := os.Stat(dir); err == nil {
+		return open(dir)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to check for repository at %q: %w", dir, err)
+	}
+	bundlePath, err := filepath.Abs(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Cloning bundle", "bundle", bundlePath, "dir", dir)
+	if err := runGit("", nil, "clone", "--quiet", bundlePath, dir); err != nil {
+		return nil, fmt.Errorf("failed to clone bundle %s: %w", bundlePath, err)
+	}
+	return open(dir)
+}
+
+// WriteBundle writes the commits of branchName which are not reachable from
+// the base commit to a git bundle at path, so that they can be transferred
+// without network access and fetched from the bundle into any clone which
+// has base.
+func (r *LocalRepository) WriteBundle(path, base, branchName string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	ref := fmt.Sprintf("refs/heads/%s", branchName)
+	if err := runGit(r.Dir, nil, "bundle", "create", "--quiet", path, ref, "^"+base); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	slog.Info("Wrote bundle", "path", path, "branch", branchName)
+	return nil
+}
+
+// FormatPatches writes the commits of HEAD which are not reachable from the
+// base commit as patches in mailbox format into dir, one file per commit,
+// which "git am" applies.
+func (r *LocalRepository) FormatPatches(dir, base string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := runGit(r.Dir, nil, "format-patch", "--quiet", "--output-directory", dir, base+"..HEAD"); err != nil {
+		return fmt.Errorf("failed to format patches: %w", err)
+	}
+	slog.Info("Wrote patches", "dir", dir)
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	repo, dir := initTestRepo(t)
+	base := createAndCommit(t, repo, "a.txt", []byte("a"), "feat: a")
+	inputBundle := filepath.Join(t.TempDir(), "input"+BundleSuffix)
+	if err := runGit(dir, nil, "bundle", "create", "--quiet", inputBundle, "master"); err != nil {
+		t.Fatal(err)
+	}
+	if !IsBundle(inputBundle) {
+		t.Errorf("IsBundle(%q) = false, want true", inputBundle)
+	}
+
+	cloneDir := filepath.Join(t.TempDir(), "clone")
+	clone, err := CloneBundle(inputBundle, cloneDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := clone.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != base.Hash.String() {
+		t.Errorf("HEAD of the clone = %s, want %s", head, base.Hash)
+	}
+	if err := clone.CreateBranchAndCheckout("librarian-offline"); err != nil {
+		t.Fatal(err)
+	}
+	createAndCommit(t, clone.repo, "b.txt", []byte("b"), "feat: b")
+
+	outputDir := t.TempDir()
+	outputBundle := filepath.Join(outputDir, "changes"+BundleSuffix)
+	if err := clone.WriteBundle(outputBundle, head, "librarian-offline"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGit(dir, nil, "fetch", "--quiet", outputBundle, "librarian-offline:librarian-offline"); err != nil {
+		t.Fatalf("failed to fetch the bundle into the original repository: %v", err)
+	}
+	patchDir := filepath.Join(outputDir, "patches")
+	if err := clone.FormatPatches(patchDir, head); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(patchDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), "-feat-b.patch") {
+		t.Errorf("patches = %v, want one patch of feat: b", entries)
+	}
+
+	// An existing clone is opened rather than cloned again.
+	if _, err := CloneBundle(filepath.Join(t.TempDir(), "missing"+BundleSuffix), cloneDir); err != nil {
+		t.Errorf("CloneBundle() of an existing clone error = %v", err)
+	}
+}
+
+func TestCloneBundle_Invalid(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "invalid"+BundleSuffix)
+	writeTestFile(t, bundle, "not a bundle")
+	if _, err := CloneBundle(bundle, filepath.Join(t.TempDir(), "clone")); err == nil {
+		t.Error("CloneBundle() of an invalid bundle succeeded")
+	}
+}
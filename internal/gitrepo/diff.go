@@ -0,0 +1,75 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/logging"
+)
+
+// DiffFile returns the unified diff of the file at path, relative to the root
+// of a repository, from before to after, using "git diff --no-index". A nil
+// content is a file which does not exist. The diff is empty if the contents
+// are equal.
+func DiffFile(path string, before, after []byte) (string, error) {
+	dir, err := os.MkdirTemp("", "librarian-diff-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	var args []string
+	for _, side := range []struct {
+		prefix  string
+		content []byte
+	}{
+		{"a", before},
+		{"b", after},
+	} {
+		if side.content == nil {
+			args = append(args, os.DevNull)
+			continue
+		}
+		name := filepath.Join(side.prefix, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), side.content, 0644); err != nil {
+			return "", err
+		}
+		args = append(args, name)
+	}
+
+	args = append([]string{"diff", "--no-index", "--no-color", "--no-prefix", "--"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	logging.LogCommand(cmd)
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	// git diff --no-index exits with 1 if the files differ.
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return "", fmt.Errorf("git diff: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffFile(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		before []byte
+		after  []byte
+		want   []string
+	}{
+		{
+			name:   "modified",
+			before: []byte("a\nb\n"),
+			after:  []byte("a\nB\n"),
+			want:   []string{"--- a/src/foo.go\n", "+++ b/src/foo.go\n", "-b\n", "+B\n"},
+		},
+		{
+			name:  "added",
+			after: []byte("a\n"),
+			want:  []string{"--- /dev/null\n", "+++ b/src/foo.go\n", "+a\n"},
+		},
+		{
+			name:   "deleted",
+			before: []byte("a\n"),
+			want:   []string{"--- a/src/foo.go\n", "+++ /dev/null\n", "-a\n"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := DiffFile("src/foo.go", test.before, test.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("DiffFile() = %q, want to contain %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffFile_Equal(t *testing.T) {
+	got, err := DiffFile("foo.go", []byte("a\n"), []byte("a\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "" {
+		t.Errorf("DiffFile() = %q, want empty", got)
+	}
+}
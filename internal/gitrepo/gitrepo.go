@@ -20,18 +20,26 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	httpAuth "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/googleapis/librarian/internal/failure"
 )
 
 // Repository defines the interface for git repository operations.
 type Repository interface {
 	AddAll() (git.Status, error)
+	Status() (git.Status, error)
+	ReadFileAtHead(path string) ([]byte, error)
+	ReadFileAtCommit(commitHash, path string) ([]byte, error)
 	Commit(msg string) error
 	IsClean() (bool, error)
 	Remotes() ([]*git.Remote, error)
@@ -40,21 +48,47 @@ type Repository interface {
 	ChangedFilesInCommit(commitHash string) ([]string, error)
 	GetCommitsForPathsSinceTag(paths []string, tagName string) ([]*Commit, error)
 	GetCommitsForPathsSinceCommit(paths []string, sinceCommit string) ([]*Commit, error)
+	CommitsSinceTag(tagName string, pathFilters []string, page *HistoryPage) (*CommitPage, error)
+	LastCommitTouching(paths []string) (*Commit, error)
+	TagCommitTime(tagName string) (time.Time, error)
+	CommitTime(commitHash string) (time.Time, error)
+	Tags() ([]string, error)
+	TagsMatching(prefix string) ([]string, error)
 	CreateBranchAndCheckout(name string) error
+	CheckoutCommit(commitHash string) error
 	Push(branchName string) error
+	PushForReview(branchName, ref string) error
+	FetchTags(names []string) error
+	PushToMirror(mirror *Mirror, refSpecs []string) error
+	MirrorRefs(mirror *Mirror) (map[string]string, error)
+	WriteBundle(path, base, branchName string) error
+	FormatPatches(dir, base string) error
 }
 
 // LocalRepository represents a git repository.
 type LocalRepository struct {
 	Dir         string
 	repo        *git.Repository
+	gitUsername string
 	gitPassword string
+	ssh         *SSHOptions
+	history     pathHashCache
+	// sparseDirs are the directories of the sparse checkout of a clone, which
+	// are kept when checking out other commits. Empty for a full checkout.
+	sparseDirs []string
 }
 
+// defaultGitUsername is the username of HTTP basic auth when none is set.
+// GitHub authentication needs the username set to a non-empty value, but it
+// does not need to match the token.
+const defaultGitUsername = "cloud-sdk-librarian"
+
 // Commit represents a git commit.
 type Commit struct {
 	Hash    plumbing.Hash
 	Message string
+	// Author is the author of the commit, formatted as "Name <email>".
+	Author string
 }
 
 // RepositoryOptions are used to configure a [LocalRepository].
@@ -71,6 +105,37 @@ type RepositoryOptions struct {
 	CI string
 	// GitPassword is used for HTTP basic auth.
 	GitPassword string
+	// SSH configures authentication with remotes which use SSH, such as
+	// "git@github.com:owner/repo.git". Optional.
+	SSH *SSHOptions
+	// Tuning configures the performance of the clone, if the repository is
+	// cloned. Optional; repositories are cloned with go-git if nil.
+	Tuning *TuningOptions
+	// SparsePaths are the directories checked out, with a sparse checkout, if
+	// the repository is cloned. All the objects of the repository are still
+	// fetched, so that its history and files at any commit can be read.
+	// Optional; all files are checked out if empty.
+	SparsePaths []string
+}
+
+// SSHOptions configure authentication with remotes which use SSH.
+type SSHOptions struct {
+	// KeyFile is the path of the private key to authenticate with, e.g. of a
+	// deploy key. If empty, the keys of the SSH agent listening on
+	// SSH_AUTH_SOCK are used.
+	KeyFile string
+	// KnownHostsFile is the path of the known_hosts file used to verify the
+	// host keys of remotes. If empty, the files listed in SSH_KNOWN_HOSTS are
+	// used, or ~/.ssh/known_hosts and /etc/ssh/ssh_known_hosts if it is not
+	// set.
+	KnownHostsFile string
+}
+
+// IsSSHURL reports whether url is the URL of a remote which uses SSH, such as
+// "git@github.com:owner/repo.git" or "ssh://git@github.com/owner/repo.git".
+func IsSSHURL(url string) bool {
+	endpoint, err := transport.NewEndpoint(url)
+	return err == nil && endpoint.Protocol == "ssh"
 }
 
 // NewRepository provides access to a git repository based on the provided options.
@@ -85,6 +150,7 @@ func NewRepository(opts *RepositoryOptions) (*LocalRepository, error) {
 		return repo, err
 	}
 	repo.gitPassword = opts.GitPassword
+	repo.ssh = opts.SSH
 	return repo, nil
 }
 
@@ -106,7 +172,10 @@ func newRepositoryWithoutUser(opts *RepositoryOptions) (*LocalRepository, error)
 			return nil, fmt.Errorf("gitrepo: remote URL is required when cloning")
 		}
 		slog.Info("Repository not found, executing clone")
-		return clone(opts.Dir, opts.RemoteURL, opts.CI)
+		if opts.Tuning != nil {
+			return cloneTuned(opts.Dir, opts.RemoteURL, opts.CI, opts.SSH, opts.Tuning, opts.SparsePaths)
+		}
+		return clone(opts.Dir, opts.RemoteURL, opts.CI, opts.SSH, opts.SparsePaths)
 	}
 	return nil, fmt.Errorf("failed to check for repository at %q: %w", opts.Dir, err)
 }
@@ -124,9 +193,32 @@ func open(dir string) (*LocalRepository, error) {
 	}, nil
 }
 
-func clone(dir, url, ci string) (*LocalRepository, error) {
+// InitRepository creates an empty git repository in dir, creating dir if it
+// does not exist. It fails if dir is already a git repository.
+func InitRepository(dir string) (*LocalRepository, error) {
+	slog.Info("Initializing repository", "dir", dir)
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalRepository{
+		Dir:  dir,
+		repo: repo,
+	}, nil
+}
+
+func clone(dir, url, ci string, ssh *SSHOptions, sparsePaths []string) (*LocalRepository, error) {
 	slog.Info("Cloning repository", "url", url, "dir", dir)
+	dirs := sparseDirs(sparsePaths)
+	var auth transport.AuthMethod
+	if IsSSHURL(url) {
+		var err error
+		if auth, err = sshAuth(ssh); err != nil {
+			return nil, err
+		}
+	}
 	options := &git.CloneOptions{
+		Auth:          auth,
 		URL:           url,
 		ReferenceName: plumbing.HEAD,
 		SingleBranch:  true,
@@ -134,6 +226,8 @@ func clone(dir, url, ci string) (*LocalRepository, error) {
 		// .NET uses submodules for conformance tests.
 		// (There may be other examples too.)
 		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		// A sparse checkout is made after cloning.
+		NoCheckout: len(dirs) > 0,
 	}
 	if ci == "" {
 		options.Progress = os.Stdout // When not a CI build, output progress.
@@ -141,28 +235,114 @@ func clone(dir, url, ci string) (*LocalRepository, error) {
 
 	repo, err := git.PlainClone(dir, false, options)
 	if err != nil {
-		return nil, err
+		return nil, remoteError(err)
 	}
-	return &LocalRepository{
+	r := &LocalRepository{
 		Dir:  dir,
 		repo: repo,
-	}, nil
+		ssh:  ssh,
+	}
+	if len(dirs) > 0 {
+		if err := r.checkoutSparsely(dirs); err != nil {
+			return nil, err
+		}
+	}
+	usesLFS, err := r.UsesLFS()
+	if err != nil {
+		return nil, err
+	}
+	if usesLFS {
+		if err := r.pullLFS(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
 }
 
 // AddAll adds all pending changes from the working tree to the index,
 // so that the changes can later be committed.
+//
+// If the repository uses Git LFS, the changes are added, and their status is
+// read, with git so that the LFS clean filter replaces tracked files with LFS
+// pointers.
 func (r *LocalRepository) AddAll() (git.Status, error) {
 	worktree, err := r.repo.Worktree()
 	if err != nil {
 		return git.Status{}, err
 	}
-	err = worktree.AddWithOptions(&git.AddOptions{All: true})
+	usesLFS, err := r.UsesLFS()
+	if err != nil {
+		return git.Status{}, err
+	}
+	if usesLFS {
+		err = runGit(r.Dir, nil, "add", "--all")
+	} else {
+		err = worktree.AddWithOptions(&git.AddOptions{All: true})
+	}
 	if err != nil {
 		return git.Status{}, err
 	}
+	if usesLFS {
+		return r.lfsStatus()
+	}
 	return worktree.Status()
 }
 
+// Status returns the status of the working tree, without adding any changes
+// to the index. If the repository uses Git LFS, the status is read with git,
+// see lfsStatus.
+func (r *LocalRepository) Status() (git.Status, error) {
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		return git.Status{}, err
+	}
+	usesLFS, err := r.UsesLFS()
+	if err != nil {
+		return git.Status{}, err
+	}
+	if usesLFS {
+		return r.lfsStatus()
+	}
+	return worktree.Status()
+}
+
+// ReadFileAtHead returns the content of the file at path, relative to the root
+// of the repository, in the commit at HEAD. The returned error wraps
+// [os.ErrNotExist] if the file does not exist at HEAD.
+func (r *LocalRepository) ReadFileAtHead(path string) ([]byte, error) {
+	ref, err := r.repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	return r.readFileAt(ref.Hash(), path, "HEAD")
+}
+
+// ReadFileAtCommit returns the content of the file at path, relative to the
+// root of the repository, in the commit with the given hash. The error wraps
+// [os.ErrNotExist] if the file does not exist in the commit.
+func (r *LocalRepository) ReadFileAtCommit(commitHash, path string) ([]byte, error) {
+	return r.readFileAt(plumbing.NewHash(commitHash), path, commitHash)
+}
+
+func (r *LocalRepository) readFileAt(hash plumbing.Hash, path, revision string) ([]byte, error) {
+	commit, err := r.repo.CommitObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	file, err := commit.File(path)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, fmt.Errorf("%s at %s: %w", path, revision, os.ErrNotExist)
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
 // Commit creates a new commit with the provided message and author
 // information.
 func (r *LocalRepository) Commit(msg string) error {
@@ -171,13 +351,22 @@ func (r *LocalRepository) Commit(msg string) error {
 		return err
 	}
 
-	status, err := worktree.Status()
+	status, err := r.Status()
 	if err != nil {
 		return err
 	}
 	if status.IsClean() {
 		return fmt.Errorf("no modifications to commit")
 	}
+	matcher, err := r.lfsMatcher()
+	if err != nil {
+		return err
+	}
+	if matcher != nil {
+		if err := r.verifyLFSPointers(matcher); err != nil {
+			return err
+		}
+	}
 	// The author of the commit will be read from git config.
 	hash, err := worktree.Commit(msg, &git.CommitOptions{})
 	if err != nil {
@@ -192,15 +381,10 @@ func (r *LocalRepository) Commit(msg string) error {
 
 // IsClean reports whether the working tree has no uncommitted changes.
 func (r *LocalRepository) IsClean() (bool, error) {
-	worktree, err := r.repo.Worktree()
-	if err != nil {
-		return false, err
-	}
-	status, err := worktree.Status()
+	status, err := r.Status()
 	if err != nil {
 		return false, err
 	}
-
 	return status.IsClean(), nil
 }
 
@@ -218,6 +402,41 @@ func (r *LocalRepository) HeadHash() (string, error) {
 	return ref.Hash().String(), nil
 }
 
+// ResolveRef returns the hash of the commit which ref refers to. The ref may
+// be a commit hash, a tag, or a local or remote branch. A branch which has not
+// been fetched from the origin remote yet, e.g. because the repository was
+// cloned with a single branch, is fetched first.
+func (r *LocalRepository) ResolveRef(ref string) (string, error) {
+	remoteBranch := "origin/" + ref
+	for _, revision := range []string{ref, remoteBranch} {
+		if hash, err := r.repo.ResolveRevision(plumbing.Revision(revision)); err == nil {
+			return hash.String(), nil
+		}
+	}
+	slog.Info("Fetching branch", "branch", ref)
+	auth, err := r.auth()
+	if err != nil {
+		return "", err
+	}
+	refSpec := config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", ref, ref))
+	err = r.repo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{refSpec},
+		Auth:       auth,
+	})
+	switch {
+	case errors.Is(err, git.NoMatchingRefSpecError{}), errors.Is(err, git.ErrRemoteNotFound):
+		return "", failure.New(failure.UserConfig, fmt.Errorf("%q is not a commit, tag or branch of %s", ref, r.Dir))
+	case err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate):
+		return "", remoteError(err)
+	}
+	hash, err := r.repo.ResolveRevision(plumbing.Revision(remoteBranch))
+	if err != nil {
+		return "", err
+	}
+	return hash.String(), nil
+}
+
 // GetDir returns the directory of the repository.
 func (r *LocalRepository) GetDir() string {
 	return r.Dir
@@ -246,6 +465,46 @@ func (r *LocalRepository) GetCommitsForPathsSinceTag(paths []string, tagName str
 	return r.GetCommitsForPathsSinceCommit(paths, hash)
 }
 
+// TagCommitTime returns the committer time of the commit that the tag
+// tagName, either lightweight or annotated, points to.
+func (r *LocalRepository) TagCommitTime(tagName string) (time.Time, error) {
+	hash, err := r.repo.ResolveRevision(plumbing.Revision("refs/tags/" + tagName))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to find tag %s: %w", tagName, err)
+	}
+	commit, err := r.repo.CommitObject(*hash)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get commit object for tag %s: %w", tagName, err)
+	}
+	return commit.Committer.When, nil
+}
+
+// CommitTime returns the committer time of the commit commitHash.
+func (r *LocalRepository) CommitTime(commitHash string) (time.Time, error) {
+	commit, err := r.repo.CommitObject(plumbing.NewHash(commitHash))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get commit object for %s: %w", commitHash, err)
+	}
+	return commit.Committer.When, nil
+}
+
+// Tags returns the names of all tags in the repository, sorted.
+func (r *LocalRepository) Tags() ([]string, error) {
+	iter, err := r.repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	var tags []string
+	if err := iter.ForEach(func(ref *plumbing.Reference) error {
+		tags = append(tags, ref.Name().Short())
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	slices.Sort(tags)
+	return tags, nil
+}
+
 // GetCommitsForPathsSinceCommit returns the commits affecting any of the given
 // paths, stopping looking at the given commit (which is not included in the
 // results).
@@ -304,6 +563,7 @@ func (r *LocalRepository) GetCommitsForPathsSinceCommit(paths []string, sinceCom
 				commits = append(commits, &Commit{
 					Hash:    commit.Hash,
 					Message: commit.Message,
+					Author:  commit.Author.String(),
 				})
 				return nil
 			}
@@ -397,19 +657,72 @@ func (r *LocalRepository) CreateBranchAndCheckout(name string) error {
 	})
 }
 
+// CheckoutCommit checks out the commit with the given hash, leaving HEAD
+// detached. Changes in the working tree, including untracked files, are
+// discarded.
+func (r *LocalRepository) CheckoutCommit(commitHash string) error {
+	slog.Info("Checking out commit", "hash", commitHash)
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if err := worktree.Checkout(&git.CheckoutOptions{
+		Hash:                      plumbing.NewHash(commitHash),
+		Force:                     true,
+		SparseCheckoutDirectories: goGitSparseDirs(r.sparseDirs),
+	}); err != nil {
+		return err
+	}
+	return worktree.Clean(&git.CleanOptions{Dir: true})
+}
+
 // Push pushes the local branch to the origin remote.
 func (r *LocalRepository) Push(branchName string) error {
 	// https://stackoverflow.com/a/75727620
 	refSpec := config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/heads/%s", branchName, branchName))
+	if err := r.push(branchName, refSpec); err != nil {
+		return err
+	}
+	slog.Info("Successfully pushed branch to remote 'origin", "branch", branchName)
+	return nil
+}
+
+// PushForReview pushes the local branch to ref of the origin remote, such as
+// the refs/for/<branch> ref through which Gerrit creates or updates a change
+// for each pushed commit. Unlike [LocalRepository.Push], the ref is not
+// force-pushed, as the remote does not store it.
+func (r *LocalRepository) PushForReview(branchName, ref string) error {
+	refSpec := config.RefSpec(fmt.Sprintf("refs/heads/%s:%s", branchName, ref))
+	if err := r.push(branchName, refSpec); err != nil {
+		return err
+	}
+	slog.Info("Successfully pushed branch for review", "branch", branchName, "ref", ref)
+	return nil
+}
+
+// SetBasicAuth sets the credentials of HTTP basic auth with the origin
+// remote, replacing the GitPassword of the options of the repository.
+func (r *LocalRepository) SetBasicAuth(username, password string) {
+	r.gitUsername = username
+	r.gitPassword = password
+}
+
+// push pushes refSpec of the local branch to the origin remote.
+func (r *LocalRepository) push(branchName string, refSpec config.RefSpec) error {
 	slog.Info("Pushing changes", slog.Any("refspec", refSpec))
-	var auth *httpAuth.BasicAuth
-	if r.gitPassword != "" {
-		slog.Info("Authenticating with basic auth")
-		auth = &httpAuth.BasicAuth{
-			// GitHub authentication needs the username set to a non-empty value, but
-			// it does not need to match the token
-			Username: "cloud-sdk-librarian",
-			Password: r.gitPassword,
+	auth, err := r.auth()
+	if err != nil {
+		return err
+	}
+	usesLFS, err := r.UsesLFS()
+	if err != nil {
+		return err
+	}
+	if usesLFS {
+		// go-git does not upload LFS objects, which would leave the pushed
+		// pointers dangling.
+		if err := r.pushLFS(branchName); err != nil {
+			return fmt.Errorf("failed to push LFS objects: %w", err)
 		}
 	}
 	if err := r.repo.Push(&git.PushOptions{
@@ -417,8 +730,81 @@ func (r *LocalRepository) Push(branchName string) error {
 		RefSpecs:   []config.RefSpec{refSpec},
 		Auth:       auth,
 	}); err != nil {
-		return err
+		return remoteError(err)
 	}
-	slog.Info("Successfully pushed branch to remote 'origin", "branch", branchName)
 	return nil
 }
+
+// auth returns the authentication for operations on the origin remote: SSH
+// authentication if the remote uses SSH, otherwise HTTP basic auth, or nil if
+// no password is configured.
+func (r *LocalRepository) auth() (transport.AuthMethod, error) {
+	if remote, err := r.repo.Remote("origin"); err == nil && len(remote.Config().URLs) > 0 && IsSSHURL(remote.Config().URLs[0]) {
+		return sshAuth(r.ssh)
+	}
+	if r.gitPassword == "" {
+		return nil, nil
+	}
+	slog.Info("Authenticating with basic auth")
+	return &httpAuth.BasicAuth{
+		Username: r.username(),
+		Password: r.gitPassword,
+	}, nil
+}
+
+// username returns the username of HTTP basic auth.
+func (r *LocalRepository) username() string {
+	if r.gitUsername == "" {
+		return defaultGitUsername
+	}
+	return r.gitUsername
+}
+
+// sshAuth returns the authentication with an SSH remote configured by opts,
+// which may be nil.
+func sshAuth(opts *SSHOptions) (transport.AuthMethod, error) {
+	if opts == nil {
+		opts = &SSHOptions{}
+	}
+	var auth transport.AuthMethod
+	var hostKeys *gitssh.HostKeyCallbackHelper
+	if opts.KeyFile != "" {
+		slog.Info("Authenticating with SSH key", "file", opts.KeyFile)
+		keys, err := gitssh.NewPublicKeysFromFile(gitssh.DefaultUsername, opts.KeyFile, "")
+		if err != nil {
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("failed to read SSH key: %w", err))
+		}
+		auth, hostKeys = keys, &keys.HostKeyCallbackHelper
+	} else {
+		slog.Info("Authenticating with SSH agent")
+		agent, err := gitssh.NewSSHAgentAuth(gitssh.DefaultUsername)
+		if err != nil {
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("failed to connect to SSH agent: %w", err))
+		}
+		auth, hostKeys = agent, &agent.HostKeyCallbackHelper
+	}
+	if opts.KnownHostsFile != "" {
+		callback, err := gitssh.NewKnownHostsCallback(opts.KnownHostsFile)
+		if err != nil {
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("failed to read known hosts: %w", err))
+		}
+		hostKeys.HostKeyCallback = callback
+	}
+	return auth, nil
+}
+
+// remoteError classifies an error of an operation on a remote repository.
+// Rejected updates conflict with changes to the remote, and authentication
+// errors and missing repositories need the configuration to be fixed. Other
+// errors, such as network errors, are likely to succeed on retry.
+func remoteError(err error) error {
+	switch {
+	case errors.Is(err, git.ErrNonFastForwardUpdate), strings.Contains(err.Error(), "rejected"):
+		return failure.New(failure.GitConflict, err)
+	case errors.Is(err, transport.ErrAuthenticationRequired),
+		errors.Is(err, transport.ErrAuthorizationFailed),
+		errors.Is(err, transport.ErrRepositoryNotFound):
+		return failure.New(failure.UserConfig, err)
+	}
+	return failure.New(failure.TransientInfra, err)
+}
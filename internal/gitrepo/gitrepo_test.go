@@ -15,15 +15,22 @@
 package gitrepo
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	goGitConfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	httpAuth "github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/failure"
 )
 
 func TestNewRepository(t *testing.T) {
@@ -904,6 +911,9 @@ func TestGetCommitsForPathsSinceCommit(t *testing.T) {
 			gotCommitMessages := []string{}
 			for _, c := range gotCommits {
 				gotCommitMessages = append(gotCommitMessages, strings.Split(c.Message, "\n")[0])
+				if c.Author != "Test <test@example.com>" {
+					t.Errorf("author of %s = %q, want %q", c.Hash, c.Author, "Test <test@example.com>")
+				}
 			}
 
 			if diff := cmp.Diff(test.wantCommits, gotCommitMessages); diff != "" {
@@ -1016,6 +1026,139 @@ func TestCreateBranchAndCheckout(t *testing.T) {
 	}
 }
 
+func TestCheckoutCommit(t *testing.T) {
+	repo, dir := initTestRepo(t)
+	first := createAndCommit(t, repo, "a.txt", []byte("first"), "feat: first")
+	createAndCommit(t, repo, "a.txt", []byte("second"), "feat: second")
+	if err := os.WriteFile(filepath.Join(dir, "untracked.txt"), []byte("untracked"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "untracked"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "untracked", "b.txt"), []byte("untracked"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := &LocalRepository{Dir: dir, repo: repo}
+
+	if err := r.CheckoutCommit(first.Hash.String()); err != nil {
+		t.Fatalf("CheckoutCommit() error = %v", err)
+	}
+	head, err := r.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(first.Hash.String(), head); diff != "" {
+		t.Errorf("HeadHash() mismatch (-want +got):\n%s", diff)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("first", string(got)); diff != "" {
+		t.Errorf("a.txt mismatch (-want +got):\n%s", diff)
+	}
+	for _, name := range []string{"untracked.txt", "untracked"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", name)
+		}
+	}
+}
+
+func TestResolveRef(t *testing.T) {
+	upstream, upstreamDir := initTestRepo(t)
+	first := createAndCommit(t, upstream, "a.txt", []byte("first"), "feat: first")
+	if _, err := upstream.CreateTag("v1.0.0", first.Hash, nil); err != nil {
+		t.Fatal(err)
+	}
+	second := createAndCommit(t, upstream, "a.txt", []byte("second"), "feat: second")
+	if err := upstream.Storer.SetReference(plumbing.NewHashReference("refs/heads/feature", first.Hash)); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	repo, err := git.PlainClone(dir, false, &git.CloneOptions{URL: upstreamDir, SingleBranch: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &LocalRepository{Dir: dir, repo: repo}
+
+	for _, test := range []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "commit",
+			ref:  first.Hash.String(),
+			want: first.Hash.String(),
+		},
+		{
+			name: "tag",
+			ref:  "v1.0.0",
+			want: first.Hash.String(),
+		},
+		{
+			name: "local branch",
+			ref:  "master",
+			want: second.Hash.String(),
+		},
+		{
+			name: "branch not fetched yet",
+			ref:  "feature",
+			want: first.Hash.String(),
+		},
+		{
+			name:    "unknown ref",
+			ref:     "unknown",
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := r.ResolveRef(test.ref)
+			if test.wantErr {
+				if got := failure.CategoryOf(err); got != failure.UserConfig {
+					t.Errorf("ResolveRef() error = %v with category %q, want %q", err, got, failure.UserConfig)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveRef() error = %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("ResolveRef() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPushForReview(t *testing.T) {
+	upstreamDir := t.TempDir()
+	upstream, err := git.PlainInit(upstreamDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, dir := initTestRepo(t)
+	if _, err := repo.CreateRemote(&goGitConfig.RemoteConfig{Name: "origin", URLs: []string{upstreamDir}}); err != nil {
+		t.Fatal(err)
+	}
+	commit := createAndCommit(t, repo, "a.txt", []byte("a"), "feat: a")
+	r := &LocalRepository{Dir: dir, repo: repo}
+	if err := r.PushForReview("master", "refs/for/main%topic=librarian"); err != nil {
+		t.Fatal(err)
+	}
+	ref, err := upstream.Reference("refs/for/main%topic=librarian", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.Hash() != commit.Hash {
+		t.Errorf("PushForReview() pushed %s, want %s", ref.Hash(), commit.Hash)
+	}
+	if _, err := upstream.Reference("refs/heads/master", false); err == nil {
+		t.Error("PushForReview() pushed the branch itself")
+	}
+}
+
 // initTestRepo creates a new git repository in a temporary directory.
 func initTestRepo(t *testing.T) (*git.Repository, string) {
 	t.Helper()
@@ -1089,6 +1232,72 @@ func setupRepoForChangedFilesTest(t *testing.T) (*LocalRepository, map[string]st
 }
 
 // setupRepoForGetCommitsTest creates a repository with a few commits and tags.
+func TestTagCommitTime(t *testing.T) {
+	t.Parallel()
+	repo, dir := initTestRepo(t)
+	commit := createAndCommit(t, repo, "a.txt", []byte("a"), "feat: a")
+	if _, err := repo.CreateTag("v1.0.0", commit.Hash, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateTag("v1.0.0-annotated", commit.Hash, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "tagger", Email: "tagger@example.com", When: commit.Committer.When.Add(time.Hour)},
+		Message: "release",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	r := &LocalRepository{Dir: dir, repo: repo}
+
+	for _, tag := range []string{"v1.0.0", "v1.0.0-annotated"} {
+		got, err := r.TagCommitTime(tag)
+		if err != nil {
+			t.Fatalf("TagCommitTime(%q) error = %v", tag, err)
+		}
+		if !got.Equal(commit.Committer.When) {
+			t.Errorf("TagCommitTime(%q) = %v, want %v", tag, got, commit.Committer.When)
+		}
+	}
+	if _, err := r.TagCommitTime("v2.0.0"); err == nil {
+		t.Error("TagCommitTime() error = nil, want error for a missing tag")
+	}
+}
+
+func TestCommitTime(t *testing.T) {
+	t.Parallel()
+	repo, dir := initTestRepo(t)
+	commit := createAndCommit(t, repo, "a.txt", []byte("a"), "feat: a")
+	r := &LocalRepository{Dir: dir, repo: repo}
+	got, err := r.CommitTime(commit.Hash.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(commit.Committer.When) {
+		t.Errorf("CommitTime() = %v, want %v", got, commit.Committer.When)
+	}
+	if _, err := r.CommitTime("0123456789abcdef0123456789abcdef01234567"); err == nil {
+		t.Error("CommitTime() error = nil, want error for a missing commit")
+	}
+}
+
+func TestTags(t *testing.T) {
+	t.Parallel()
+	repo, dir := initTestRepo(t)
+	commit := createAndCommit(t, repo, "a.txt", []byte("a"), "feat: a")
+	for _, tag := range []string{"foo-1.0.0", "bar-2.0.0"} {
+		if _, err := repo.CreateTag(tag, commit.Hash, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := &LocalRepository{Dir: dir, repo: repo}
+	got, err := r.Tags()
+	if err != nil {
+		t.Fatalf("Tags() error = %v", err)
+	}
+	want := []string{"bar-2.0.0", "foo-1.0.0"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Tags() mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func setupRepoForGetCommitsTest(t *testing.T) (*LocalRepository, map[string]string) {
 	t.Helper()
 	repo, dir := initTestRepo(t)
@@ -1113,3 +1322,174 @@ func setupRepoForGetCommitsTest(t *testing.T) (*LocalRepository, map[string]stri
 
 	return &LocalRepository{Dir: dir, repo: repo}, commits
 }
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+	repo, dir := initTestRepo(t)
+	createAndCommit(t, repo, "a.txt", []byte("first"), "feat: first")
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("second"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := &LocalRepository{Dir: dir, repo: repo}
+
+	status, err := r.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if got := status.File("a.txt").Worktree; got != git.Modified {
+		t.Errorf("Status() of a.txt = %q, want %q", got, git.Modified)
+	}
+	if got := status.File("a.txt").Staging; got != git.Unmodified {
+		t.Errorf("Status() staged a.txt as %q, want it unstaged", got)
+	}
+}
+
+func TestReadFileAtHead(t *testing.T) {
+	t.Parallel()
+	repo, dir := initTestRepo(t)
+	createAndCommit(t, repo, "dir/a.txt", []byte("first"), "feat: first")
+	if err := os.WriteFile(filepath.Join(dir, "dir/a.txt"), []byte("second"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := &LocalRepository{Dir: dir, repo: repo}
+
+	got, err := r.ReadFileAtHead("dir/a.txt")
+	if err != nil {
+		t.Fatalf("ReadFileAtHead() error = %v", err)
+	}
+	if diff := cmp.Diff("first", string(got)); diff != "" {
+		t.Errorf("ReadFileAtHead() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := r.ReadFileAtHead("missing.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadFileAtHead() of missing file error = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestReadFileAtCommit(t *testing.T) {
+	t.Parallel()
+	repo, dir := initTestRepo(t)
+	first := createAndCommit(t, repo, "dir/a.txt", []byte("first"), "feat: first")
+	createAndCommit(t, repo, "dir/a.txt", []byte("second"), "feat: second")
+	r := &LocalRepository{Dir: dir, repo: repo}
+
+	got, err := r.ReadFileAtCommit(first.Hash.String(), "dir/a.txt")
+	if err != nil {
+		t.Fatalf("ReadFileAtCommit() error = %v", err)
+	}
+	if diff := cmp.Diff("first", string(got)); diff != "" {
+		t.Errorf("ReadFileAtCommit() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := r.ReadFileAtCommit(first.Hash.String(), "missing.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadFileAtCommit() of missing file error = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestRemoteError(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		err  error
+		want failure.Category
+	}{
+		{
+			name: "non fast forward",
+			err:  git.ErrNonFastForwardUpdate,
+			want: failure.GitConflict,
+		},
+		{
+			name: "rejected by remote",
+			err:  errors.New("command error on refs/heads/main: protected branch hook declined (rejected)"),
+			want: failure.GitConflict,
+		},
+		{
+			name: "authentication required",
+			err:  fmt.Errorf("push: %w", transport.ErrAuthenticationRequired),
+			want: failure.UserConfig,
+		},
+		{
+			name: "repository not found",
+			err:  transport.ErrRepositoryNotFound,
+			want: failure.UserConfig,
+		},
+		{
+			name: "network error",
+			err:  errors.New("dial tcp: connection refused"),
+			want: failure.TransientInfra,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := remoteError(test.err)
+			if got := failure.CategoryOf(err); got != test.want {
+				t.Errorf("CategoryOf(remoteError(%v)) = %q, want %q", test.err, got, test.want)
+			}
+			if !errors.Is(err, test.err) {
+				t.Errorf("remoteError() = %v, want it to wrap %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestIsSSHURL(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		url  string
+		want bool
+	}{
+		{url: "git@github.com:owner/repo.git", want: true},
+		{url: "ssh://git@github.com/owner/repo.git", want: true},
+		{url: "https://github.com/owner/repo.git"},
+		{url: "/path/to/repo"},
+	} {
+		t.Run(test.url, func(t *testing.T) {
+			if got := IsSSHURL(test.url); got != test.want {
+				t.Errorf("IsSSHURL(%q) = %t, want %t", test.url, got, test.want)
+			}
+		})
+	}
+}
+
+func TestAuth(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name         string
+		remoteURL    string
+		ssh          *SSHOptions
+		wantBasic    bool
+		wantCategory failure.Category
+	}{
+		{
+			name:      "https",
+			remoteURL: "https://github.com/owner/repo.git",
+			wantBasic: true,
+		},
+		{
+			name:         "ssh with missing key",
+			remoteURL:    "git@github.com:owner/repo.git",
+			ssh:          &SSHOptions{KeyFile: filepath.Join(t.TempDir(), "missing")},
+			wantCategory: failure.UserConfig,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repo, err := git.PlainInit(t.TempDir(), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := repo.CreateRemote(&goGitConfig.RemoteConfig{Name: "origin", URLs: []string{test.remoteURL}}); err != nil {
+				t.Fatal(err)
+			}
+			r := &LocalRepository{repo: repo, gitPassword: "secret-token", ssh: test.ssh}
+			got, err := r.auth()
+			if test.wantCategory != "" {
+				if category := failure.CategoryOf(err); category != test.wantCategory {
+					t.Errorf("auth() error = %v with category %q, want %q", err, category, test.wantCategory)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("auth() error = %v", err)
+			}
+			if _, ok := got.(*httpAuth.BasicAuth); ok != test.wantBasic {
+				t.Errorf("auth() = %T, want basic auth %t", got, test.wantBasic)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// HistoryPage selects a page of the commits returned by the history queries,
+// which walk the history from the most recent commit backwards.
+type HistoryPage struct {
+	// After is the hash of the last commit of the previous page, as returned
+	// in [CommitPage.Next]. The page starts with the commit following it. It
+	// is empty for the first page.
+	After string
+	// Limit is the maximum number of commits in the page, or zero for all of
+	// them.
+	Limit int
+}
+
+// CommitPage is a page of commits, most recent first.
+type CommitPage struct {
+	Commits []*Commit
+	// Next is the [HistoryPage.After] of the next page, or empty if there is
+	// no next page.
+	Next string
+}
+
+// pathHashCache memoizes the hashes of the tree entries of paths at commits,
+// which the history queries compare between a commit and its parent. Walks
+// of the same history, e.g. for each library of a release, reuse them
+// instead of looking up the trees again.
+type pathHashCache struct {
+	mu     sync.Mutex
+	hashes map[pathAtCommit]plumbing.Hash
+}
+
+type pathAtCommit struct {
+	commit plumbing.Hash
+	path   string
+}
+
+// errStopWalk stops a walk of the history once its page is complete.
+var errStopWalk = errors.New("walk stopped")
+
+// CommitsSinceTag returns the commits since the commit of the tag tagName,
+// which is not included, that change any of pathFilters, in page. The paths
+// are files or directories relative to the root of the repository; the
+// commits are not filtered if there are none. If tagName is empty, all the
+// commits are searched.
+//
+// Merge commits are compared with their first parent, and the initial commit
+// with an empty tree.
+func (r *LocalRepository) CommitsSinceTag(tagName string, pathFilters []string, page *HistoryPage) (*CommitPage, error) {
+	var since plumbing.Hash
+	if tagName != "" {
+		hash, err := r.repo.ResolveRevision(plumbing.Revision("refs/tags/" + tagName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find tag %s: %w", tagName, err)
+		}
+		since = *hash
+	}
+	result, err := r.walkHistory(since, pathFilters, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits since tag %s: %w", tagName, err)
+	}
+	return result, nil
+}
+
+// LastCommitTouching returns the most recent commit which changes any of
+// paths, files or directories relative to the root of the repository, or nil
+// if none does.
+func (r *LocalRepository) LastCommitTouching(paths []string) (*Commit, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no paths to check for commits")
+	}
+	result, err := r.walkHistory(plumbing.ZeroHash, paths, &HistoryPage{Limit: 1})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the last commit touching %s: %w", strings.Join(paths, ", "), err)
+	}
+	if len(result.Commits) == 0 {
+		return nil, nil
+	}
+	return result.Commits[0], nil
+}
+
+// TagsMatching returns the names of the tags starting with prefix, sorted.
+// Only the references are listed, the tagged objects are not read.
+func (r *LocalRepository) TagsMatching(prefix string) ([]string, error) {
+	refs, err := r.repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	var tags []string
+	if err := refs.ForEach(func(ref *plumbing.Reference) error {
+		if !ref.Name().IsTag() {
+			return nil
+		}
+		if name := ref.Name().Short(); strings.HasPrefix(name, prefix) {
+			tags = append(tags, name)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	slices.Sort(tags)
+	return tags, nil
+}
+
+// walkHistory returns the commits changing any of paths in page, walking
+// from HEAD, or from the commit after page.After, and stopping at since,
+// unless it is zero. It fails if since is not found.
+func (r *LocalRepository) walkHistory(since plumbing.Hash, paths []string, page *HistoryPage) (*CommitPage, error) {
+	if page == nil {
+		page = &HistoryPage{}
+	}
+	opts := &git.LogOptions{Order: git.LogOrderCommitterTime}
+	if page.After != "" {
+		opts.From = plumbing.NewHash(page.After)
+	}
+	iter, err := r.repo.Log(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	paths = cleanPaths(paths)
+	result := &CommitPage{}
+	found := false
+	err = iter.ForEach(func(commit *object.Commit) error {
+		if commit.Hash == since {
+			found = true
+			return storer.ErrStop
+		}
+		if page.After != "" && commit.Hash.String() == page.After {
+			return nil
+		}
+		changed, err := r.changesPaths(commit, paths)
+		if err != nil || !changed {
+			return err
+		}
+		if page.Limit > 0 && len(result.Commits) == page.Limit {
+			result.Next = result.Commits[len(result.Commits)-1].Hash.String()
+			return errStopWalk
+		}
+		result.Commits = append(result.Commits, &Commit{
+			Hash:    commit.Hash,
+			Message: commit.Message,
+			Author:  commit.Author.String(),
+		})
+		return nil
+	})
+	if err != nil && err != errStopWalk {
+		return nil, err
+	}
+	if err == nil && !since.IsZero() && !found {
+		return nil, fmt.Errorf("did not find commit %s when iterating", since)
+	}
+	return result, nil
+}
+
+// cleanPaths returns paths relative to the root of the repository, without
+// the paths which select the whole repository.
+func cleanPaths(paths []string) []string {
+	var cleaned []string
+	for _, p := range paths {
+		p = strings.Trim(path.Clean("/"+p), "/")
+		if p == "" {
+			return nil
+		}
+		cleaned = append(cleaned, p)
+	}
+	return cleaned
+}
+
+// changesPaths reports whether commit changes any of paths compared with its
+// first parent, or any commit if there are no paths.
+func (r *LocalRepository) changesPaths(commit *object.Commit, paths []string) (bool, error) {
+	if len(paths) == 0 {
+		return true, nil
+	}
+	var parent *object.Commit
+	if commit.NumParents() > 0 {
+		var err error
+		if parent, err = commit.Parent(0); err != nil {
+			return false, err
+		}
+	}
+	for _, p := range paths {
+		current, err := r.pathHash(commit, p)
+		if err != nil {
+			return false, err
+		}
+		var previous plumbing.Hash
+		if parent != nil {
+			if previous, err = r.pathHash(parent, p); err != nil {
+				return false, err
+			}
+		}
+		if current != previous {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// pathHash returns the hash of the tree entry of p at commit, or the zero
+// hash if p does not exist at commit.
+func (r *LocalRepository) pathHash(commit *object.Commit, p string) (plumbing.Hash, error) {
+	key := pathAtCommit{commit: commit.Hash, path: p}
+	r.history.mu.Lock()
+	hash, ok := r.history.hashes[key]
+	r.history.mu.Unlock()
+	if ok {
+		return hash, nil
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	entry, err := tree.FindEntry(p)
+	switch {
+	case errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound):
+		hash = plumbing.ZeroHash
+	case err != nil:
+		return plumbing.ZeroHash, err
+	default:
+		hash = entry.Hash
+	}
+	r.history.mu.Lock()
+	if r.history.hashes == nil {
+		r.history.hashes = make(map[pathAtCommit]plumbing.Hash)
+	}
+	r.history.hashes[key] = hash
+	r.history.mu.Unlock()
+	return hash, nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newHistoryTestRepo returns a repository whose history is, from the oldest
+// commit: "initial" adding a and b, tagged a-1.0.0, then "a1", "b1", "a2",
+// "a3". It returns the hashes of the commits by message.
+func newHistoryTestRepo(t *testing.T) (*MemoryRepository, map[string]string) {
+	t.Helper()
+	r, err := NewMemoryRepository("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := make(map[string]string)
+	commit := func(msg string, files map[string]string) {
+		hash, err := r.CommitFiles(msg, files)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes[msg] = hash
+	}
+	commit("initial", map[string]string{"a/a.go": "a0", "b/b.go": "b0"})
+	for _, tag := range []string{"a-1.0.0", "b-1.0.0", "other"} {
+		if err := r.CreateTag(tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit("a1", map[string]string{"a/a.go": "a1"})
+	commit("b1", map[string]string{"b/b.go": "b1"})
+	commit("a2", map[string]string{"a/sub/a.go": "a2"})
+	commit("a3", map[string]string{"a/a.go": "a3"})
+	return r, hashes
+}
+
+// messages returns the messages of the commits of page.
+func messages(page *CommitPage) []string {
+	var msgs []string
+	for _, commit := range page.Commits {
+		msgs = append(msgs, strings.TrimSpace(commit.Message))
+	}
+	return msgs
+}
+
+func TestCommitsSinceTag(t *testing.T) {
+	r, _ := newHistoryTestRepo(t)
+	for _, test := range []struct {
+		name        string
+		tag         string
+		pathFilters []string
+		want        []string
+	}{
+		{
+			name:        "path since tag",
+			tag:         "a-1.0.0",
+			pathFilters: []string{"a"},
+			want:        []string{"a3", "a2", "a1"},
+		},
+		{
+			name:        "nested path",
+			tag:         "a-1.0.0",
+			pathFilters: []string{"./a/sub/"},
+			want:        []string{"a2"},
+		},
+		{
+			name: "no filters",
+			tag:  "a-1.0.0",
+			want: []string{"a3", "a2", "b1", "a1"},
+		},
+		{
+			name:        "no tag includes the initial commit",
+			pathFilters: []string{"b"},
+			want:        []string{"b1", "initial"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			page, err := r.CommitsSinceTag(test.tag, test.pathFilters, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, messages(page)); diff != "" {
+				t.Errorf("CommitsSinceTag() mismatch (-want +got):\n%s", diff)
+			}
+			if page.Next != "" {
+				t.Errorf("CommitsSinceTag() Next = %q, want no next page", page.Next)
+			}
+		})
+	}
+	if _, err := r.CommitsSinceTag("missing", nil, nil); err == nil {
+		t.Error("CommitsSinceTag() of a missing tag succeeded, want error")
+	}
+}
+
+func TestCommitsSinceTag_Pagination(t *testing.T) {
+	r, _ := newHistoryTestRepo(t)
+	var got []string
+	page := &HistoryPage{Limit: 2}
+	for i := 0; ; i++ {
+		result, err := r.CommitsSinceTag("a-1.0.0", []string{"a"}, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprint(messages(result)))
+		if result.Next == "" {
+			break
+		}
+		if i > 2 {
+			t.Fatalf("CommitsSinceTag() did not finish paging, got %v", got)
+		}
+		page = &HistoryPage{After: result.Next, Limit: 2}
+	}
+	if diff := cmp.Diff([]string{"[a3 a2]", "[a1]"}, got); diff != "" {
+		t.Errorf("CommitsSinceTag() pages mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLastCommitTouching(t *testing.T) {
+	r, hashes := newHistoryTestRepo(t)
+	for _, test := range []struct {
+		paths []string
+		want  string
+	}{
+		{paths: []string{"b"}, want: hashes["b1"]},
+		{paths: []string{"a/sub", "b/b.go"}, want: hashes["a2"]},
+		{paths: []string{"c"}},
+	} {
+		commit, err := r.LastCommitTouching(test.paths)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		if commit != nil {
+			got = commit.Hash.String()
+		}
+		if got != test.want {
+			t.Errorf("LastCommitTouching(%v) = %q, want %q", test.paths, got, test.want)
+		}
+	}
+	if _, err := r.LastCommitTouching(nil); err == nil {
+		t.Error("LastCommitTouching() without paths succeeded, want error")
+	}
+}
+
+func TestTagsMatching(t *testing.T) {
+	r, _ := newHistoryTestRepo(t)
+	got, err := r.TagsMatching("a-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a-1.0.0"}, got); diff != "" {
+		t.Errorf("TagsMatching() mismatch (-want +got):\n%s", diff)
+	}
+	all, err := r.TagsMatching("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a-1.0.0", "b-1.0.0", "other"}, all); diff != "" {
+		t.Errorf("TagsMatching() mismatch (-want +got):\n%s", diff)
+	}
+}
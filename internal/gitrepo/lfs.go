@@ -0,0 +1,247 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+	"github.com/googleapis/librarian/internal/logging"
+)
+
+// lfsPointerMaxSize is the maximum size of a Git LFS pointer file. Blobs
+// larger than this are never pointers.
+const lfsPointerMaxSize = 1024
+
+// lfsPointerRegexp matches the content of a Git LFS pointer file, as described
+// in https://github.com/git-lfs/git-lfs/blob/main/docs/spec.md.
+var lfsPointerRegexp = regexp.MustCompile(`^version https://git-lfs\.github\.com/spec/v1\n(?:[a-z0-9.-]+ [^\n]*\n)*?oid sha256:[0-9a-f]{64}\n(?:[a-z0-9.-]+ [^\n]*\n)*?size [0-9]+\n(?:[a-z0-9.-]+ [^\n]*\n)*$`)
+
+// ErrLFSPointerMissing is returned when a file tracked by Git LFS is staged
+// with its content instead of an LFS pointer.
+var ErrLFSPointerMissing = errors.New("file tracked by Git LFS is not an LFS pointer")
+
+// runGit runs git with the given arguments in dir, adding env to the
+// environment of the process. It is a variable so that tests can replace it.
+var runGit = func(dir string, env []string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	logging.LogCommand(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// outputGit runs git with the given arguments in dir and returns its standard
+// output. It is a variable so that tests can replace it.
+var outputGit = func(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	logging.LogCommand(cmd)
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// UsesLFS reports whether any .gitattributes file in the working tree assigns
+// the Git LFS filter to a path.
+func (r *LocalRepository) UsesLFS() (bool, error) {
+	matcher, err := r.lfsMatcher()
+	if err != nil {
+		return false, err
+	}
+	return matcher != nil, nil
+}
+
+// lfsMatcher returns a matcher for the attributes of the working tree, or nil
+// if the repository does not use Git LFS.
+func (r *LocalRepository) lfsMatcher() (gitattributes.Matcher, error) {
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	patterns, err := gitattributes.ReadPatterns(worktree.Filesystem, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .gitattributes: %w", err)
+	}
+	for _, pattern := range patterns {
+		for _, attribute := range pattern.Attributes {
+			if attribute.Name() == "filter" && attribute.Value() == "lfs" {
+				return gitattributes.NewMatcher(patterns), nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// isLFSTracked reports whether path is assigned the Git LFS filter by matcher.
+func isLFSTracked(matcher gitattributes.Matcher, path string) bool {
+	results, _ := matcher.Match(strings.Split(path, "/"), []string{"filter"})
+	filter, ok := results["filter"]
+	return ok && filter.Value() == "lfs"
+}
+
+// isLFSPointer reports whether content is a Git LFS pointer.
+func isLFSPointer(content []byte) bool {
+	return len(content) <= lfsPointerMaxSize && lfsPointerRegexp.Match(content)
+}
+
+// pullLFS replaces the LFS pointers in the working tree with the content they
+// point to. go-git does not run the LFS smudge filter, so this shells out to
+// git-lfs, which must be installed.
+func (r *LocalRepository) pullLFS() error {
+	slog.Info("Repository uses Git LFS, pulling LFS objects", "dir", r.Dir)
+	if err := runGit(r.Dir, nil, "lfs", "install", "--local"); err != nil {
+		return fmt.Errorf("failed to set up Git LFS, is git-lfs installed?: %w", err)
+	}
+	return runGit(r.Dir, r.sshEnv(), "lfs", "pull")
+}
+
+// pushLFS uploads the LFS objects referenced by the local branch to the origin
+// remote.
+func (r *LocalRepository) pushLFS(branchName string) error {
+	// The credentials are passed in the environment rather than as arguments,
+	// which would be visible to other processes and in error messages.
+	env := r.sshEnv()
+	if r.gitPassword != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(r.username() + ":" + r.gitPassword))
+		env = []string{
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraheader",
+			"GIT_CONFIG_VALUE_0=AUTHORIZATION: basic " + credentials,
+		}
+	}
+	slog.Info("Pushing LFS objects", "branch", branchName)
+	return runGit(r.Dir, env, "lfs", "push", "origin", branchName)
+}
+
+// sshEnv returns the environment which makes git use the SSH key and known
+// hosts file of the repository, if any, for remotes which use SSH.
+func (r *LocalRepository) sshEnv() []string {
+	if r.ssh == nil || (r.ssh.KeyFile == "" && r.ssh.KnownHostsFile == "") {
+		return nil
+	}
+	command := []string{"ssh"}
+	if r.ssh.KeyFile != "" {
+		command = append(command, "-i", shellQuote(r.ssh.KeyFile), "-o", "IdentitiesOnly=yes")
+	}
+	if r.ssh.KnownHostsFile != "" {
+		command = append(command, "-o", "UserKnownHostsFile="+shellQuote(r.ssh.KnownHostsFile))
+	}
+	return []string{"GIT_SSH_COMMAND=" + strings.Join(command, " ")}
+}
+
+// shellQuote quotes s for the shell which git runs GIT_SSH_COMMAND with.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// verifyLFSPointers checks that every file in the index which is tracked by
+// Git LFS is staged as an LFS pointer rather than with its content.
+func (r *LocalRepository) verifyLFSPointers(matcher gitattributes.Matcher) error {
+	idx, err := r.repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+	var invalid []string
+	for _, entry := range idx.Entries {
+		if !isLFSTracked(matcher, entry.Name) {
+			continue
+		}
+		if entry.Size > lfsPointerMaxSize {
+			invalid = append(invalid, entry.Name)
+			continue
+		}
+		blob, err := r.repo.BlobObject(entry.Hash)
+		if err != nil {
+			return fmt.Errorf("failed to read blob for %s: %w", entry.Name, err)
+		}
+		reader, err := blob.Reader()
+		if err != nil {
+			return err
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+		if !isLFSPointer(content) {
+			invalid = append(invalid, entry.Name)
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("%w: %s", ErrLFSPointerMissing, strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
+// lfsStatus returns the status of the working tree from git. go-git does not
+// run the LFS clean filter when comparing the working tree to the index, so
+// it reports every checked out LFS file as modified.
+func (r *LocalRepository) lfsStatus() (git.Status, error) {
+	out, err := outputGit(r.Dir, "status", "--porcelain=v1", "-z", "--untracked-files=all")
+	if err != nil {
+		return git.Status{}, err
+	}
+	return parsePorcelainStatus(out)
+}
+
+// parsePorcelainStatus parses the output of git status --porcelain=v1 -z. The
+// status codes of git are those of go-git.
+func parsePorcelainStatus(out []byte) (git.Status, error) {
+	status := git.Status{}
+	entries := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if entry == "" {
+			continue
+		}
+		if len(entry) < 4 || entry[2] != ' ' {
+			return git.Status{}, fmt.Errorf("invalid git status entry %q", entry)
+		}
+		fileStatus := &git.FileStatus{
+			Staging:  git.StatusCode(entry[0]),
+			Worktree: git.StatusCode(entry[1]),
+		}
+		// The original path of a renamed or copied file follows its path.
+		if fileStatus.Staging == git.Renamed || fileStatus.Staging == git.Copied {
+			i++
+			if i == len(entries) {
+				return git.Status{}, fmt.Errorf("git status entry %q has no original path", entry)
+			}
+			fileStatus.Extra = entries[i]
+		}
+		status[entry[3:]] = fileStatus
+	}
+	return status, nil
+}
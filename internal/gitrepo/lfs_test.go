@@ -0,0 +1,330 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/google/go-cmp/cmp"
+)
+
+const testLFSPointer = `version https://git-lfs.github.com/spec/v1
+oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+size 12345
+`
+
+func TestIsLFSPointer(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{
+			name:    "pointer",
+			content: testLFSPointer,
+			want:    true,
+		},
+		{
+			name:    "pointer with extension",
+			content: "version https://git-lfs.github.com/spec/v1\next-0-foo sha256:abc\noid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\nsize 1\n",
+			want:    true,
+		},
+		{
+			name:    "missing size",
+			content: "version https://git-lfs.github.com/spec/v1\noid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\n",
+		},
+		{
+			name:    "content",
+			content: "golden file content\n",
+		},
+		{
+			name:    "too large",
+			content: testLFSPointer + strings.Repeat("x", lfsPointerMaxSize),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			if got := isLFSPointer([]byte(test.content)); got != test.want {
+				t.Errorf("isLFSPointer() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUsesLFS(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name       string
+		attributes map[string]string
+		want       bool
+	}{
+		{
+			name: "no attributes",
+		},
+		{
+			name: "attributes without lfs",
+			attributes: map[string]string{
+				".gitattributes": "*.go text eol=lf\n",
+			},
+		},
+		{
+			name: "root attributes",
+			attributes: map[string]string{
+				".gitattributes": "*.bin filter=lfs diff=lfs merge=lfs -text\n",
+			},
+			want: true,
+		},
+		{
+			name: "nested attributes",
+			attributes: map[string]string{
+				"testdata/.gitattributes": "*.golden filter=lfs diff=lfs merge=lfs -text\n",
+			},
+			want: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repo, dir := initTestRepo(t)
+			for path, content := range test.attributes {
+				writeTestFile(t, filepath.Join(dir, path), content)
+			}
+			r := &LocalRepository{Dir: dir, repo: repo}
+			got, err := r.UsesLFS()
+			if err != nil {
+				t.Fatalf("UsesLFS() error = %v", err)
+			}
+			if got != test.want {
+				t.Errorf("UsesLFS() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestVerifyLFSPointers(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		files   map[string]string
+		wantErr error
+	}{
+		{
+			name: "pointers",
+			files: map[string]string{
+				"testdata/a.golden": testLFSPointer,
+				"main.go":           "package main\n",
+			},
+		},
+		{
+			name: "content",
+			files: map[string]string{
+				"testdata/a.golden": "golden file content\n",
+			},
+			wantErr: ErrLFSPointerMissing,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repo, dir := initTestRepo(t)
+			createAndCommit(t, repo, "testdata/.gitattributes", []byte("*.golden filter=lfs diff=lfs merge=lfs -text\n"), "chore: track golden files with lfs")
+			worktree, err := repo.Worktree()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for path, content := range test.files {
+				writeTestFile(t, filepath.Join(dir, path), content)
+				if _, err := worktree.Add(path); err != nil {
+					t.Fatal(err)
+				}
+			}
+			r := &LocalRepository{Dir: dir, repo: repo}
+			matcher, err := r.lfsMatcher()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.verifyLFSPointers(matcher); !errors.Is(err, test.wantErr) {
+				t.Errorf("verifyLFSPointers() error = %v, want %v", err, test.wantErr)
+			}
+			if test.wantErr == nil {
+				return
+			}
+			if err := r.Commit("feat: add golden file"); !errors.Is(err, test.wantErr) {
+				t.Errorf("Commit() error = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestAddAllWithLFS(t *testing.T) {
+	repo, dir := initTestRepo(t)
+	writeTestFile(t, filepath.Join(dir, ".gitattributes"), "*.bin filter=lfs diff=lfs merge=lfs -text\n")
+	writeTestFile(t, filepath.Join(dir, "a.bin"), testLFSPointer)
+	var gotArgs [][]string
+	setRunGit(t, func(dir string, env []string, args ...string) error {
+		gotArgs = append(gotArgs, args)
+		worktree, err := repo.Worktree()
+		if err != nil {
+			return err
+		}
+		return worktree.AddWithOptions(&git.AddOptions{All: true})
+	})
+	r := &LocalRepository{Dir: dir, repo: repo}
+	status, err := r.AddAll()
+	if err != nil {
+		t.Fatalf("AddAll() error = %v", err)
+	}
+	if diff := cmp.Diff([][]string{{"add", "--all"}}, gotArgs); diff != "" {
+		t.Errorf("git arguments mismatch (-want +got):\n%s", diff)
+	}
+	if status.IsClean() {
+		t.Errorf("AddAll() status is clean, want staged changes")
+	}
+}
+
+func TestStatusWithLFS(t *testing.T) {
+	repo, dir := initTestRepo(t)
+	writeTestFile(t, filepath.Join(dir, ".gitattributes"), "*.bin filter=lfs diff=lfs merge=lfs -text\n")
+	// go-git reports the file as changed, while git, which runs the LFS
+	// filters, does not.
+	writeTestFile(t, filepath.Join(dir, "a.bin"), "binary content")
+	r := &LocalRepository{Dir: dir, repo: repo}
+	for _, test := range []struct {
+		name      string
+		out       string
+		want      git.Status
+		wantClean bool
+	}{
+		{
+			name:      "clean",
+			want:      git.Status{},
+			wantClean: true,
+		},
+		{
+			name: "changes",
+			out:  " M a.bin\x00R  new.txt\x00old.txt\x00?? dir/untracked.txt\x00",
+			want: git.Status{
+				"a.bin":             {Staging: git.Unmodified, Worktree: git.Modified},
+				"new.txt":           {Staging: git.Renamed, Worktree: git.Unmodified, Extra: "old.txt"},
+				"dir/untracked.txt": {Staging: git.Untracked, Worktree: git.Untracked},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var gotArgs []string
+			original := outputGit
+			outputGit = func(dir string, args ...string) ([]byte, error) {
+				gotArgs = args
+				return []byte(test.out), nil
+			}
+			t.Cleanup(func() { outputGit = original })
+
+			got, err := r.Status()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Status() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff([]string{"status", "--porcelain=v1", "-z", "--untracked-files=all"}, gotArgs); diff != "" {
+				t.Errorf("git arguments mismatch (-want +got):\n%s", diff)
+			}
+			clean, err := r.IsClean()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if clean != test.wantClean {
+				t.Errorf("IsClean() = %t, want %t", clean, test.wantClean)
+			}
+		})
+	}
+}
+
+func TestParsePorcelainStatus_Invalid(t *testing.T) {
+	for _, out := range []string{"M\x00", "R  new.txt\x00"} {
+		if _, err := parsePorcelainStatus([]byte(out)); err == nil {
+			t.Errorf("parsePorcelainStatus(%q) error = nil, want an error", out)
+		}
+	}
+}
+
+func TestPushLFS(t *testing.T) {
+	var gotEnv []string
+	var gotArgs []string
+	setRunGit(t, func(dir string, env []string, args ...string) error {
+		gotEnv = env
+		gotArgs = args
+		return nil
+	})
+	r := &LocalRepository{Dir: t.TempDir(), gitPassword: "secret-token"}
+	if err := r.pushLFS("librarian-branch"); err != nil {
+		t.Fatalf("pushLFS() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"lfs", "push", "origin", "librarian-branch"}, gotArgs); diff != "" {
+		t.Errorf("git arguments mismatch (-want +got):\n%s", diff)
+	}
+	if len(gotEnv) != 3 || !strings.HasPrefix(gotEnv[2], "GIT_CONFIG_VALUE_0=AUTHORIZATION: basic ") {
+		t.Errorf("git environment = %v, want credentials in the environment", gotEnv)
+	}
+}
+
+func setRunGit(t *testing.T, fn func(dir string, env []string, args ...string) error) {
+	t.Helper()
+	original := runGit
+	runGit = fn
+	t.Cleanup(func() { runGit = original })
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSSHEnv(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		ssh  *SSHOptions
+		want []string
+	}{
+		{
+			name: "no options",
+		},
+		{
+			name: "agent",
+			ssh:  &SSHOptions{},
+		},
+		{
+			name: "key and known hosts",
+			ssh:  &SSHOptions{KeyFile: "/keys/deploy key", KnownHostsFile: "/etc/known_hosts"},
+			want: []string{"GIT_SSH_COMMAND=ssh -i '/keys/deploy key' -o IdentitiesOnly=yes -o UserKnownHostsFile='/etc/known_hosts'"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := &LocalRepository{ssh: test.ssh}
+			if diff := cmp.Diff(test.want, r.sshEnv()); diff != "" {
+				t.Errorf("sshEnv() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// MemoryRepository is a [Repository] whose objects and working tree are held
+// in memory, for unit tests of the flows built on [Repository], such as
+// committing and computing releases, without touching the disk or spawning
+// git processes. Pushes are recorded instead of being sent to a remote.
+//
+// As its working tree is not on disk, its directory is empty, and files are
+// written with [MemoryRepository.WriteFile].
+type MemoryRepository struct {
+	*LocalRepository
+	// Pushed are the refs pushed by Push and PushForReview, in order, e.g.
+	// "refs/heads/librarian-20250610T120000Z".
+	Pushed []string
+}
+
+// NewMemoryRepository creates an empty in-memory repository, with an origin
+// remote at remoteURL if it is not empty. Commits are authored by a test
+// user.
+func NewMemoryRepository(remoteURL string) (*MemoryRepository, error) {
+	repo, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return nil, err
+	}
+	cfg.User.Name = "Librarian Test"
+	cfg.User.Email = "librarian-test@example.com"
+	if remoteURL != "" {
+		cfg.Remotes["origin"] = &config.RemoteConfig{Name: "origin", URLs: []string{remoteURL}}
+	}
+	if err := repo.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	return &MemoryRepository{LocalRepository: &LocalRepository{repo: repo}}, nil
+}
+
+// WriteFile writes a file of the working tree, creating its parent
+// directories as needed. The path is relative to the root of the working
+// tree and uses forward slashes.
+func (r *MemoryRepository) WriteFile(name string, content []byte) error {
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if err := worktree.Filesystem.MkdirAll(path.Dir(name), 0755); err != nil {
+		return err
+	}
+	return util.WriteFile(worktree.Filesystem, name, content, 0644)
+}
+
+// ReadFile reads a file of the working tree.
+func (r *MemoryRepository) ReadFile(name string) ([]byte, error) {
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	return util.ReadFile(worktree.Filesystem, name)
+}
+
+// CommitFiles writes files, keyed by their path, and commits all the changes
+// of the working tree with msg. It returns the hash of the commit.
+func (r *MemoryRepository) CommitFiles(msg string, files map[string]string) (string, error) {
+	for name, content := range files {
+		if err := r.WriteFile(name, []byte(content)); err != nil {
+			return "", err
+		}
+	}
+	if _, err := r.AddAll(); err != nil {
+		return "", err
+	}
+	if err := r.Commit(msg); err != nil {
+		return "", err
+	}
+	return r.HeadHash()
+}
+
+// CreateTag creates a lightweight tag of the HEAD commit.
+func (r *MemoryRepository) CreateTag(name string) error {
+	head, err := r.repo.Head()
+	if err != nil {
+		return err
+	}
+	if _, err := r.repo.CreateTag(name, head.Hash(), nil); err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", name, err)
+	}
+	return nil
+}
+
+// Push records the push of the branch.
+func (r *MemoryRepository) Push(branchName string) error {
+	if _, err := r.repo.Reference(plumbing.NewBranchReferenceName(branchName), false); err != nil {
+		return fmt.Errorf("failed to push branch %s: %w", branchName, err)
+	}
+	r.Pushed = append(r.Pushed, plumbing.NewBranchReferenceName(branchName).String())
+	return nil
+}
+
+// PushForReview records the push of the branch to ref.
+func (r *MemoryRepository) PushForReview(branchName, ref string) error {
+	if _, err := r.repo.Reference(plumbing.NewBranchReferenceName(branchName), false); err != nil {
+		return fmt.Errorf("failed to push branch %s: %w", branchName, err)
+	}
+	r.Pushed = append(r.Pushed, ref)
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// The in-memory repository is used through the Repository interface.
+var _ Repository = (*MemoryRepository)(nil)
+
+func TestMemoryRepository(t *testing.T) {
+	r, err := NewMemoryRepository("https://github.com/googleapis/google-cloud-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CommitFiles("chore: initial commit", map[string]string{"a/a.go": "package a", "b/b.go": "package b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CreateTag("a-1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	fix, err := r.CommitFiles("fix(a): fix a bug", map[string]string{"a/a.go": "package a // fixed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CommitFiles("feat(b): add a feature", map[string]string{"b/b.go": "package b // featured"}); err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := r.GetCommitsForPathsSinceTag([]string{"a"}, "a-1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || commits[0].Hash.String() != fix {
+		t.Errorf("GetCommitsForPathsSinceTag() = %v, want the fix commit %s", commits, fix)
+	}
+	files, err := r.ChangedFilesInCommit(fix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a/a.go"}, files); diff != "" {
+		t.Errorf("ChangedFilesInCommit() mismatch (-want +got):\n%s", diff)
+	}
+	content, err := r.ReadFileAtCommit(fix, "a/a.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(content); got != "package a // fixed" {
+		t.Errorf("ReadFileAtCommit() = %q, want the fixed file", got)
+	}
+
+	if err := r.CreateBranchAndCheckout("librarian-test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.WriteFile("a/a.go", []byte("package a // regenerated")); err != nil {
+		t.Fatal(err)
+	}
+	if clean, err := r.IsClean(); err != nil || clean {
+		t.Fatalf("IsClean() = %t, %v, want false", clean, err)
+	}
+	if _, err := r.AddAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Commit("chore: regenerate"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Push("librarian-test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Push("unknown"); err == nil {
+		t.Error("Push() of an unknown branch error = nil, want an error")
+	}
+	if diff := cmp.Diff([]string{"refs/heads/librarian-test"}, r.Pushed); diff != "" {
+		t.Errorf("Pushed mismatch (-want +got):\n%s", diff)
+	}
+	remotes, err := r.Remotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remotes) != 1 || remotes[0].Config().URLs[0] != "https://github.com/googleapis/google-cloud-go" {
+		t.Errorf("Remotes() = %v, want the origin remote", remotes)
+	}
+}
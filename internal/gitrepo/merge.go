@@ -0,0 +1,75 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/logging"
+)
+
+// ErrMergeConflict is returned by [MergeFile] when the changes of both sides
+// overlap.
+var ErrMergeConflict = errors.New("merge conflict")
+
+// MergeFile performs a three-way merge of the changes made to base in ours and
+// in theirs, using "git merge-file". If the changes overlap, the error wraps
+// [ErrMergeConflict] and the returned content contains conflict markers.
+func MergeFile(ours, base, theirs []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "librarian-merge-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	var paths []string
+	for _, file := range []struct {
+		name    string
+		content []byte
+	}{
+		{"ours", ours},
+		{"base", base},
+		{"theirs", theirs},
+	} {
+		path := filepath.Join(dir, file.name)
+		if err := os.WriteFile(path, file.content, 0644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	args := append([]string{"merge-file", "--stdout"}, paths...)
+	cmd := exec.Command("git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	logging.LogCommand(cmd)
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	// git merge-file exits with the number of conflicts, or a negative
+	// status on errors.
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 && exitErr.ExitCode() < 128 {
+		return stdout.Bytes(), fmt.Errorf("%d conflicting changes: %w", exitErr.ExitCode(), ErrMergeConflict)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("git merge-file: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
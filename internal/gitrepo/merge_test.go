@@ -0,0 +1,70 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMergeFile(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+	for _, test := range []struct {
+		name         string
+		ours         string
+		theirs       string
+		want         string
+		wantConflict bool
+	}{
+		{
+			name:   "separate changes",
+			ours:   "a\nB\nc\nd\ne\n",
+			theirs: "a\nb\nc\nd\nE\n",
+			want:   "a\nB\nc\nd\nE\n",
+		},
+		{
+			name:   "same change",
+			ours:   "a\nB\nc\nd\ne\n",
+			theirs: "a\nB\nc\nd\ne\n",
+			want:   "a\nB\nc\nd\ne\n",
+		},
+		{
+			name:         "overlapping changes",
+			ours:         "a\nB\nc\nd\ne\n",
+			theirs:       "a\nX\nc\nd\ne\n",
+			wantConflict: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := MergeFile([]byte(test.ours), []byte(base), []byte(test.theirs))
+			if test.wantConflict {
+				if !errors.Is(err, ErrMergeConflict) {
+					t.Fatalf("MergeFile() error = %v, want %v", err, ErrMergeConflict)
+				}
+				if !strings.Contains(string(got), "<<<<<<<") {
+					t.Errorf("MergeFile() = %q, want conflict markers", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("MergeFile() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
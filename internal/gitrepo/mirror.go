@@ -0,0 +1,155 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	httpAuth "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// Mirror is a secondary remote of a repository, to which the commits and
+// tags of origin are also pushed, e.g. a copy of the repository on another
+// host. Mirrors are not configured as remotes of the repository.
+type Mirror struct {
+	// Name identifies the mirror in logs and errors.
+	Name string
+	// URL is the URL of the mirror.
+	URL string
+	// Username and Password are the credentials of HTTP basic auth with the
+	// mirror. The username defaults to that of origin. They are not used if
+	// the mirror uses SSH.
+	Username string
+	Password string
+	// SSH configures authentication with the mirror if it uses SSH. If nil,
+	// the SSH options of the repository are used.
+	SSH *SSHOptions
+}
+
+// FetchTags fetches the tags with the given names from the origin remote,
+// e.g. the tags of GitHub releases, which are created on the remote, or all
+// the tags if there are no names.
+func (r *LocalRepository) FetchTags(names []string) error {
+	refSpecs := []config.RefSpec{"+refs/tags/*:refs/tags/*"}
+	if len(names) > 0 {
+		refSpecs = nil
+		for _, name := range names {
+			refSpecs = append(refSpecs, config.RefSpec(fmt.Sprintf("+refs/tags/%s:refs/tags/%s", name, name)))
+		}
+	}
+	auth, err := r.auth()
+	if err != nil {
+		return err
+	}
+	if err := r.repo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   refSpecs,
+		Auth:       auth,
+		Tags:       git.NoTags,
+	}); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to fetch tags: %w", remoteError(err))
+	}
+	return nil
+}
+
+// PushToMirror pushes refSpecs to mirror, e.g.
+// "refs/tags/v1.2.3:refs/tags/v1.2.3", or "<hash>:refs/heads/main" to push a
+// commit to a branch. Refs are not force-pushed, so that the history of the
+// mirror is never rewritten. Pushing refs which the mirror already has
+// succeeds.
+func (r *LocalRepository) PushToMirror(mirror *Mirror, refSpecs []string) error {
+	remote, auth, err := r.mirrorRemote(mirror)
+	if err != nil {
+		return err
+	}
+	var specs []config.RefSpec
+	for _, refSpec := range refSpecs {
+		spec := config.RefSpec(refSpec)
+		if err := spec.Validate(); err != nil {
+			return fmt.Errorf("invalid refspec %q: %w", refSpec, err)
+		}
+		// Refs which do not exist are skipped by go-git, which would report
+		// their push as successful.
+		if !spec.IsExactSHA1() {
+			if _, err := r.repo.Reference(plumbing.ReferenceName(spec.Src()), true); err != nil {
+				return fmt.Errorf("failed to push %s to mirror %s: %w", spec.Src(), mirror.Name, err)
+			}
+		}
+		specs = append(specs, spec)
+	}
+	slog.Info("Pushing to mirror", "mirror", mirror.Name, "refspecs", refSpecs)
+	if err := remote.Push(&git.PushOptions{
+		RemoteName: remote.Config().Name,
+		RefSpecs:   specs,
+		Auth:       auth,
+	}); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to push to mirror %s: %w", mirror.Name, remoteError(err))
+	}
+	return nil
+}
+
+// MirrorRefs returns the hashes of the refs of mirror by their names, e.g.
+// "refs/tags/v1.2.3" or "refs/heads/main". Annotated tags are listed with the
+// hash of the tag object.
+func (r *LocalRepository) MirrorRefs(mirror *Mirror) (map[string]string, error) {
+	remote, auth, err := r.mirrorRemote(mirror)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("failed to list the refs of mirror %s: %w", mirror.Name, remoteError(err))
+	}
+	hashes := make(map[string]string, len(refs))
+	for _, ref := range refs {
+		if ref.Type() == plumbing.HashReference {
+			hashes[ref.Name().String()] = ref.Hash().String()
+		}
+	}
+	return hashes, nil
+}
+
+// mirrorRemote returns an anonymous remote of mirror and the authentication
+// with it.
+func (r *LocalRepository) mirrorRemote(mirror *Mirror) (*git.Remote, transport.AuthMethod, error) {
+	if mirror.URL == "" {
+		return nil, nil, fmt.Errorf("mirror %s has no url", mirror.Name)
+	}
+	remote := git.NewRemote(r.repo.Storer, &config.RemoteConfig{
+		Name: cmp.Or(mirror.Name, "mirror"),
+		URLs: []string{mirror.URL},
+	})
+	if IsSSHURL(mirror.URL) {
+		auth, err := sshAuth(cmp.Or(mirror.SSH, r.ssh))
+		return remote, auth, err
+	}
+	if mirror.Password == "" {
+		return remote, nil, nil
+	}
+	return remote, &httpAuth.BasicAuth{
+		Username: cmp.Or(mirror.Username, r.username()),
+		Password: mirror.Password,
+	}, nil
+}
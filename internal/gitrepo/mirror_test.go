@@ -0,0 +1,93 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	goGitConfig "github.com/go-git/go-git/v5/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMirror(t *testing.T) {
+	t.Parallel()
+	originDir := t.TempDir()
+	origin, err := git.PlainInit(originDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirrorDir := t.TempDir()
+	if _, err := git.PlainInit(mirrorDir, true); err != nil {
+		t.Fatal(err)
+	}
+	repo, dir := initTestRepo(t)
+	if _, err := repo.CreateRemote(&goGitConfig.RemoteConfig{Name: "origin", URLs: []string{originDir}}); err != nil {
+		t.Fatal(err)
+	}
+	commit := createAndCommit(t, repo, "a.txt", []byte("a"), "feat: a")
+	r := &LocalRepository{Dir: dir, repo: repo}
+	if err := r.Push("master"); err != nil {
+		t.Fatal(err)
+	}
+	// The tag is created on origin, as GitHub does for releases.
+	if _, err := origin.CreateTag("a-v1.0.0", commit.Hash, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.FetchTags([]string{"a-v1.0.0"}); err != nil {
+		t.Fatalf("FetchTags() error = %v", err)
+	}
+	if _, err := repo.Tag("a-v1.0.0"); err != nil {
+		t.Fatalf("FetchTags() did not fetch the tag: %v", err)
+	}
+
+	mirror := &Mirror{Name: "backup", URL: mirrorDir}
+	got, err := r.MirrorRefs(mirror)
+	if err != nil {
+		t.Fatalf("MirrorRefs() of an empty mirror error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("MirrorRefs() of an empty mirror = %v, want none", got)
+	}
+	refSpecs := []string{
+		"refs/tags/a-v1.0.0:refs/tags/a-v1.0.0",
+		commit.Hash.String() + ":refs/heads/main",
+	}
+	if err := r.PushToMirror(mirror, refSpecs); err != nil {
+		t.Fatalf("PushToMirror() error = %v", err)
+	}
+	// Pushing refs which the mirror already has succeeds.
+	if err := r.PushToMirror(mirror, refSpecs); err != nil {
+		t.Fatalf("PushToMirror() again error = %v", err)
+	}
+	got, err = r.MirrorRefs(mirror)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"refs/tags/a-v1.0.0": commit.Hash.String(),
+		"refs/heads/main":    commit.Hash.String(),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("MirrorRefs() mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := r.PushToMirror(mirror, []string{"refs/tags/unknown:refs/tags/unknown"}); err == nil {
+		t.Error("PushToMirror() of an unknown tag succeeded, want error")
+	}
+	if err := r.PushToMirror(&Mirror{Name: "no-url"}, refSpecs); err == nil {
+		t.Error("PushToMirror() to a mirror without URL succeeded, want error")
+	}
+}
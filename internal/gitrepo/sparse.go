@@ -0,0 +1,93 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// sparseDirs returns the directories of a sparse checkout of paths, cleaned,
+// sorted and without the directories within others. It returns nil, i.e. a
+// full checkout, if paths is empty or contains the root of the repository.
+func sparseDirs(paths []string) []string {
+	var dirs []string
+	for _, p := range paths {
+		p = path.Clean(strings.Trim(p, "/"))
+		if p == "." || p == "" {
+			return nil
+		}
+		dirs = append(dirs, p)
+	}
+	slices.Sort(dirs)
+	dirs = slices.Compact(dirs)
+	var kept []string
+	for _, dir := range dirs {
+		// Sorted, a directory comes right before the directories within it.
+		if len(kept) > 0 && strings.HasPrefix(dir, kept[len(kept)-1]+"/") {
+			continue
+		}
+		kept = append(kept, dir)
+	}
+	return kept
+}
+
+// goGitSparseDirs returns dirs as the directories of
+// git.CheckoutOptions.SparseCheckoutDirectories, which are matched as
+// prefixes of the paths of files, so that "google/api" does not include
+// "google/apikeys".
+func goGitSparseDirs(dirs []string) []string {
+	prefixes := make([]string, len(dirs))
+	for i, dir := range dirs {
+		prefixes[i] = dir + "/"
+	}
+	return prefixes
+}
+
+// checkoutSparsely checks out the files in dirs at HEAD, for a clone without
+// a checkout. All the objects of the repository are still available, so the
+// files outside dirs can be read at any commit, but they are not in the
+// working tree.
+func (r *LocalRepository) checkoutSparsely(dirs []string) error {
+	slog.Info("Checking out directories", "dir", r.Dir, "dirs", dirs)
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		return err
+	}
+	head, err := r.repo.Head()
+	if err != nil {
+		return err
+	}
+	// go-git checks out every file in a directory which is missing from the
+	// working tree, even those skipped by the sparse checkout, so the
+	// directories are created first.
+	for _, dir := range dirs {
+		if err := os.MkdirAll(filepath.Join(r.Dir, filepath.FromSlash(dir)), 0755); err != nil {
+			return err
+		}
+	}
+	r.sparseDirs = dirs
+	return worktree.Checkout(&git.CheckoutOptions{
+		Branch:                    head.Name(),
+		Force:                     true,
+		SparseCheckoutDirectories: goGitSparseDirs(dirs),
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSparseDirs(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name  string
+		paths []string
+		want  []string
+	}{
+		{name: "empty"},
+		{
+			name:  "cleaned and nested",
+			paths: []string{"google/cloud/foo/v1/", "google/api", "google/cloud/foo", "/google/apikeys/v1", "google/api"},
+			want:  []string{"google/api", "google/apikeys/v1", "google/cloud/foo"},
+		},
+		{name: "root", paths: []string{"google/api", "/"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			if diff := cmp.Diff(test.want, sparseDirs(test.paths)); diff != "" {
+				t.Errorf("sparseDirs(%q) mismatch (-want +got):\n%s", test.paths, diff)
+			}
+		})
+	}
+}
+
+func TestNewRepository_SparsePaths(t *testing.T) {
+	t.Parallel()
+	origin, originDir := initTestRepo(t)
+	createAndCommit(t, origin, "google/api/annotations.proto", []byte("api"), "feat: api")
+	createAndCommit(t, origin, "google/apikeys/v2/apikeys.proto", []byte("apikeys"), "feat: apikeys")
+	first := createAndCommit(t, origin, "google/foo/v1/foo.proto", []byte("foo"), "feat: foo")
+	createAndCommit(t, origin, "google/bar/v1/bar.proto", []byte("bar"), "feat: bar")
+	for _, test := range []struct {
+		name   string
+		tuning *TuningOptions
+	}{
+		{name: "go-git"},
+		{name: "git", tuning: &TuningOptions{}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			if test.tuning != nil {
+				if _, err := exec.LookPath("git"); err != nil {
+					t.Skip("git not installed")
+				}
+			}
+			dir := filepath.Join(t.TempDir(), "clone")
+			r, err := NewRepository(&RepositoryOptions{
+				Dir:         dir,
+				MaybeClone:  true,
+				RemoteURL:   originDir,
+				CI:          "test",
+				Tuning:      test.tuning,
+				SparsePaths: []string{"google/api", "google/foo/v1/"},
+			})
+			if err != nil {
+				t.Fatalf("NewRepository() error = %v", err)
+			}
+			checkedOut := func(want map[string]bool) {
+				t.Helper()
+				for file, wantExists := range want {
+					_, err := os.Stat(filepath.Join(dir, file))
+					if exists := err == nil; exists != wantExists {
+						t.Errorf("%s checked out = %t, want %t", file, exists, wantExists)
+					}
+				}
+				clean, err := r.IsClean()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !clean {
+					t.Errorf("IsClean() = false, want true")
+				}
+			}
+			checkedOut(map[string]bool{
+				"google/api/annotations.proto":    true,
+				"google/foo/v1/foo.proto":         true,
+				"google/apikeys/v2/apikeys.proto": false,
+				"google/bar/v1/bar.proto":         false,
+			})
+			got, err := r.ReadFileAtHead("google/bar/v1/bar.proto")
+			if err != nil {
+				t.Fatalf("ReadFileAtHead() error = %v", err)
+			}
+			if string(got) != "bar" {
+				t.Errorf("ReadFileAtHead() = %q, want %q", got, "bar")
+			}
+			if err := r.CheckoutCommit(first.Hash.String()); err != nil {
+				t.Fatal(err)
+			}
+			checkedOut(map[string]bool{
+				"google/foo/v1/foo.proto":         true,
+				"google/apikeys/v2/apikeys.proto": false,
+			})
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The keys of the trailers read or written by librarian.
+const (
+	// TrailerChangeID identifies a Gerrit change.
+	TrailerChangeID = "Change-Id"
+	// TrailerCoAuthoredBy credits another author of the commit.
+	TrailerCoAuthoredBy = "Co-authored-by"
+	// TrailerLibraryIDs lists the IDs of the libraries changed by the commit,
+	// separated by commas.
+	TrailerLibraryIDs = "Library-IDs"
+	// TrailerPiperOriginRevID is the number of the CL a commit was copied
+	// from.
+	TrailerPiperOriginRevID = "PiperOrigin-RevId"
+	// TrailerReleaseID is the ID of the release of a release commit.
+	TrailerReleaseID = "Release-ID"
+)
+
+// trailerRegex matches the first line of a trailer, e.g.
+// "Co-authored-by: Jane Doe <jane@example.com>".
+var trailerRegex = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*):[ \t]*(.*)$`)
+
+// Trailer is a "Key: value" trailer of a commit message, such as
+// "Co-authored-by" or "PiperOrigin-RevId". The lines of a value which spans
+// multiple lines are separated by "\n".
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+// String formats the trailer as in a commit message, indenting the
+// continuation lines of its value.
+func (t Trailer) String() string {
+	return t.Key + ": " + strings.ReplaceAll(t.Value, "\n", "\n ")
+}
+
+// ParseTrailers splits message into its body and its trailers: the lines of
+// its last paragraph, if they are all trailers or the continuation lines of a
+// trailer, indented with whitespace. The subject of the message is never a
+// trailer. The body is returned without trailing newlines.
+func ParseTrailers(message string) (string, []Trailer) {
+	message = strings.TrimRight(message, "\n")
+	i := strings.LastIndex(message, "\n\n")
+	if i == -1 {
+		return message, nil
+	}
+	var trailers []Trailer
+	for _, line := range strings.Split(message[i+2:], "\n") {
+		if line != "" && (line[0] == ' ' || line[0] == '\t') && len(trailers) > 0 {
+			trailers[len(trailers)-1].Value += "\n" + strings.TrimSpace(line)
+			continue
+		}
+		matches := trailerRegex.FindStringSubmatch(line)
+		if matches == nil {
+			return message, nil
+		}
+		trailers = append(trailers, Trailer{Key: matches[1], Value: strings.TrimSpace(matches[2])})
+	}
+	return strings.TrimRight(message[:i], "\n"), trailers
+}
+
+// TrailerValue returns the value of the last of the trailers with key, which
+// is compared case-insensitively like git does, or an empty string if there
+// is none.
+func TrailerValue(trailers []Trailer, key string) string {
+	for i := len(trailers) - 1; i >= 0; i-- {
+		if strings.EqualFold(trailers[i].Key, key) {
+			return trailers[i].Value
+		}
+	}
+	return ""
+}
+
+// AddTrailers returns message with trailers added after its existing
+// trailers, or in a new last paragraph if it has none. The message ends with
+// a newline.
+func AddTrailers(message string, trailers ...Trailer) string {
+	body, existing := ParseTrailers(message)
+	return FormatMessage(body, append(existing, trailers...))
+}
+
+// FormatMessage returns the commit message with the given body and trailers,
+// which form the last paragraph of the message. The message ends with a
+// newline.
+func FormatMessage(body string, trailers []Trailer) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(body, "\n"))
+	b.WriteString("\n")
+	for i, trailer := range trailers {
+		if i == 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(trailer.String() + "\n")
+	}
+	return b.String()
+}
+
+// Trailers returns the trailers of the message of the commit.
+func (c *Commit) Trailers() []Trailer {
+	_, trailers := ParseTrailers(c.Message)
+	return trailers
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseTrailers(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name         string
+		message      string
+		wantBody     string
+		wantTrailers []Trailer
+	}{
+		{
+			name:     "subject only",
+			message:  "feat: add a method\n",
+			wantBody: "feat: add a method",
+		},
+		{
+			name:     "subject is never a trailer",
+			message:  "Release-ID: release-1",
+			wantBody: "Release-ID: release-1",
+		},
+		{
+			name:     "trailers",
+			message:  "feat: add a method\n\nSome body.\n\nPiperOrigin-RevId: 123\nCo-authored-by: Jane Doe <jane@example.com>\n",
+			wantBody: "feat: add a method\n\nSome body.",
+			wantTrailers: []Trailer{
+				{Key: TrailerPiperOriginRevID, Value: "123"},
+				{Key: TrailerCoAuthoredBy, Value: "Jane Doe <jane@example.com>"},
+			},
+		},
+		{
+			name:     "continuation line",
+			message:  "feat: add a method\n\nLibrary-IDs: a,\n  b\n",
+			wantBody: "feat: add a method",
+			wantTrailers: []Trailer{
+				{Key: TrailerLibraryIDs, Value: "a,\nb"},
+			},
+		},
+		{
+			name:     "last paragraph is not all trailers",
+			message:  "feat: add a method\n\nSee https://example.com.\nPiperOrigin-RevId: 123\n",
+			wantBody: "feat: add a method\n\nSee https://example.com.\nPiperOrigin-RevId: 123",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			body, trailers := ParseTrailers(test.message)
+			if body != test.wantBody {
+				t.Errorf("ParseTrailers() body = %q, want %q", body, test.wantBody)
+			}
+			if diff := cmp.Diff(test.wantTrailers, trailers); diff != "" {
+				t.Errorf("ParseTrailers() trailers mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTrailerValue(t *testing.T) {
+	t.Parallel()
+	trailers := []Trailer{
+		{Key: "Co-Authored-By", Value: "Jane Doe <jane@example.com>"},
+		{Key: TrailerCoAuthoredBy, Value: "John Doe <john@example.com>"},
+	}
+	if got, want := TrailerValue(trailers, TrailerCoAuthoredBy), "John Doe <john@example.com>"; got != want {
+		t.Errorf("TrailerValue() = %q, want %q", got, want)
+	}
+	if got := TrailerValue(trailers, TrailerReleaseID); got != "" {
+		t.Errorf("TrailerValue() = %q, want empty", got)
+	}
+}
+
+func TestAddTrailers(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "new paragraph",
+			message: "feat: add a method\n\nSome body.\n",
+			want:    "feat: add a method\n\nSome body.\n\nRelease-ID: release-1\n",
+		},
+		{
+			name:    "after existing trailers",
+			message: "feat: add a method\n\nPiperOrigin-RevId: 123",
+			want:    "feat: add a method\n\nPiperOrigin-RevId: 123\nRelease-ID: release-1\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := AddTrailers(test.message, Trailer{Key: TrailerReleaseID, Value: "release-1"})
+			if got != test.want {
+				t.Errorf("AddTrailers() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatMessage_RoundTrip(t *testing.T) {
+	t.Parallel()
+	message := "feat: add a method\n\nSome body.\n\nLibrary-IDs: a,\n b\nCo-authored-by: Jane Doe <jane@example.com>\n"
+	body, trailers := ParseTrailers(message)
+	if got := FormatMessage(body, trailers); got != message {
+		t.Errorf("FormatMessage() = %q, want %q", got, message)
+	}
+	commit := &Commit{Message: message}
+	if diff := cmp.Diff(trailers, commit.Trailers()); diff != "" {
+		t.Errorf("Trailers() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// TuningOff disables the tuning of clones in ParseTuningOptions.
+const TuningOff = "off"
+
+// TuningOptions are the performance settings of the clones of large
+// repositories, such as language monorepos. When set, repositories are cloned
+// with the git command, which fetches submodules and checks out files in
+// parallel, instead of go-git, and the settings are kept in the config of the
+// clone, so that later git commands run with them too.
+type TuningOptions struct {
+	// FetchJobs is the number of submodules, and of remotes, fetched in
+	// parallel, i.e. fetch.parallel and submodule.fetchJobs. Zero keeps the
+	// default of git.
+	FetchJobs int
+	// CheckoutWorkers is the number of workers which check out files, i.e.
+	// checkout.workers. Zero keeps the default of git, a single worker.
+	CheckoutWorkers int
+	// FSMonitor enables the file system monitor and the untracked cache of
+	// git, which speed up the status of large working trees which are used
+	// repeatedly. The monitor is a daemon, so it is off by default.
+	FSMonitor bool
+	// CommitGraph writes the commit-graph file after cloning, and after each
+	// fetch, which speeds up the walks of the history of the repository.
+	CommitGraph bool
+}
+
+// DefaultTuningOptions returns the tuning used unless it is overridden.
+// Cloning with git is several times faster than with go-git on repositories
+// with many files, even on a single CPU, as BenchmarkClone shows. More
+// checkout workers than CPUs only add overhead, so the workers are capped at
+// the number of CPUs, and at eight, as checking out is then bound by the disk.
+func DefaultTuningOptions() *TuningOptions {
+	return &TuningOptions{
+		FetchJobs:       4,
+		CheckoutWorkers: min(runtime.NumCPU(), 8),
+		CommitGraph:     true,
+	}
+}
+
+// ParseTuningOptions parses the tuning of clones from a comma-separated list
+// of settings, which override DefaultTuningOptions, e.g.
+// "checkout-workers=16,fsmonitor=true". The settings are fetch-jobs,
+// checkout-workers, fsmonitor and commit-graph. An empty string returns
+// DefaultTuningOptions, and TuningOff returns nil, which disables tuning.
+func ParseTuningOptions(s string) (*TuningOptions, error) {
+	if s == TuningOff {
+		return nil, nil
+	}
+	opts := DefaultTuningOptions()
+	if s == "" {
+		return opts, nil
+	}
+	for _, setting := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(setting), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid git tuning setting %q, want name=value", setting)
+		}
+		var err error
+		switch name {
+		case "fetch-jobs":
+			opts.FetchJobs, err = parseTuningCount(value)
+		case "checkout-workers":
+			opts.CheckoutWorkers, err = parseTuningCount(value)
+		case "fsmonitor":
+			opts.FSMonitor, err = strconv.ParseBool(value)
+		case "commit-graph":
+			opts.CommitGraph, err = strconv.ParseBool(value)
+		default:
+			return nil, fmt.Errorf("unknown git tuning setting %q, want fetch-jobs, checkout-workers, fsmonitor or commit-graph", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of git tuning setting %s: %w", name, err)
+		}
+	}
+	return opts, nil
+}
+
+func parseTuningCount(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%d is negative", n)
+	}
+	return n, nil
+}
+
+// gitConfig returns the git config settings of the tuning, keyed by
+// "section.key".
+func (o *TuningOptions) gitConfig() map[string]string {
+	settings := make(map[string]string)
+	if o.FetchJobs > 0 {
+		settings["fetch.parallel"] = strconv.Itoa(o.FetchJobs)
+		settings["submodule.fetchJobs"] = strconv.Itoa(o.FetchJobs)
+	}
+	if o.CheckoutWorkers > 0 {
+		settings["checkout.workers"] = strconv.Itoa(o.CheckoutWorkers)
+	}
+	if o.FSMonitor {
+		settings["core.fsmonitor"] = "true"
+		settings["core.untrackedCache"] = "true"
+	}
+	if o.CommitGraph {
+		settings["core.commitGraph"] = "true"
+		settings["fetch.writeCommitGraph"] = "true"
+	}
+	return settings
+}
+
+// cloneTuned clones url into dir with the git command and the settings of
+// tuning, like clone does with go-git. It falls back to clone if git is not
+// installed.
+func cloneTuned(dir, url, ci string, ssh *SSHOptions, tuning *TuningOptions, sparsePaths []string) (*LocalRepository, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		slog.Warn("git not found, cloning without tuning", "error", err)
+		return clone(dir, url, ci, ssh, sparsePaths)
+	}
+	dirs := sparseDirs(sparsePaths)
+	slog.Info("Cloning repository with git", "url", url, "dir", dir, "tuning", fmt.Sprintf("%+v", *tuning))
+	settings := tuning.gitConfig()
+	var args []string
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		args = append(args, "-c", key+"="+settings[key])
+	}
+	args = append(args, "clone", "--single-branch", "--recurse-submodules")
+	if tuning.FetchJobs > 0 {
+		args = append(args, "--jobs", strconv.Itoa(tuning.FetchJobs))
+	}
+	if ci != "" {
+		args = append(args, "--quiet")
+	}
+	if len(dirs) > 0 {
+		// Only the files at the root are checked out, until the directories
+		// are added to the sparse checkout below.
+		args = append(args, "--sparse")
+	}
+	args = append(args, "--", url, dir)
+	env := sshCommandEnv(url, ssh)
+	if err := runGit("", env, args...); err != nil {
+		return nil, remoteError(err)
+	}
+	// Like the clones of go-git, the clone has all the tags of the remote,
+	// and not only those of its branch.
+	if err := runGit(dir, env, "fetch", "--quiet", "--tags", "origin"); err != nil {
+		return nil, remoteError(err)
+	}
+	if len(dirs) > 0 {
+		slog.Info("Checking out directories", "dir", dir, "dirs", dirs)
+		if err := runGit(dir, nil, append([]string{"sparse-checkout", "set", "--cone"}, dirs...)...); err != nil {
+			return nil, fmt.Errorf("failed to set sparse checkout: %w", err)
+		}
+	}
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &LocalRepository{
+		Dir:        dir,
+		repo:       repo,
+		ssh:        ssh,
+		sparseDirs: dirs,
+	}
+	if err := r.applyTuning(tuning); err != nil {
+		return nil, err
+	}
+	usesLFS, err := r.UsesLFS()
+	if err != nil {
+		return nil, err
+	}
+	if usesLFS {
+		if err := r.pullLFS(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// applyTuning keeps the settings of tuning in the config of the repository,
+// and writes its commit-graph file if enabled. Failing to write the
+// commit-graph file is only logged, as it does not affect correctness.
+func (r *LocalRepository) applyTuning(tuning *TuningOptions) error {
+	cfg, err := r.repo.Config()
+	if err != nil {
+		return err
+	}
+	for key, value := range tuning.gitConfig() {
+		section, option, _ := strings.Cut(key, ".")
+		cfg.Raw.Section(section).SetOption(option, value)
+	}
+	if err := r.repo.SetConfig(cfg); err != nil {
+		return fmt.Errorf("failed to write git tuning: %w", err)
+	}
+	if tuning.CommitGraph {
+		if err := runGit(r.Dir, nil, "commit-graph", "write", "--reachable"); err != nil {
+			slog.Warn("failed to write commit-graph", "dir", r.Dir, "error", err)
+		}
+	}
+	return nil
+}
+
+// sshCommandEnv returns the environment which makes the git command
+// authenticate with the SSH key and known hosts of ssh, if url uses SSH.
+// Without a key, ssh uses the SSH agent itself.
+func sshCommandEnv(url string, ssh *SSHOptions) []string {
+	if !IsSSHURL(url) || ssh == nil || (ssh.KeyFile == "" && ssh.KnownHostsFile == "") {
+		return nil
+	}
+	command := []string{"ssh"}
+	if ssh.KeyFile != "" {
+		command = append(command, "-i", strconv.Quote(ssh.KeyFile), "-o", "IdentitiesOnly=yes")
+	}
+	if ssh.KnownHostsFile != "" {
+		command = append(command, "-o", "UserKnownHostsFile="+strconv.Quote(ssh.KnownHostsFile))
+	}
+	return []string{"GIT_SSH_COMMAND=" + strings.Join(command, " ")}
+}
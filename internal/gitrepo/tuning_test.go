@@ -0,0 +1,197 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseTuningOptions(t *testing.T) {
+	t.Parallel()
+	defaults := DefaultTuningOptions()
+	for _, test := range []struct {
+		name    string
+		s       string
+		want    *TuningOptions
+		wantErr bool
+	}{
+		{name: "default", s: "", want: defaults},
+		{name: "off", s: TuningOff, want: nil},
+		{
+			name: "overrides",
+			s:    "checkout-workers=16, fsmonitor=true,commit-graph=false,fetch-jobs=0",
+			want: &TuningOptions{CheckoutWorkers: 16, FSMonitor: true},
+		},
+		{name: "no value", s: "fsmonitor", wantErr: true},
+		{name: "unknown setting", s: "workers=2", wantErr: true},
+		{name: "negative count", s: "fetch-jobs=-1", wantErr: true},
+		{name: "invalid bool", s: "commit-graph=sometimes", wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseTuningOptions(test.s)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseTuningOptions(%q) error = %v, wantErr %t", test.s, err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("ParseTuningOptions(%q) mismatch (-want +got):\n%s", test.s, diff)
+			}
+		})
+	}
+}
+
+func TestNewRepository_Tuning(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	origin, originDir := initTestRepo(t)
+	commit := createAndCommit(t, origin, "a.txt", []byte("a"), "feat: a")
+	if _, err := origin.CreateTag("a-v1.0.0", commit.Hash, nil); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "clone")
+	r, err := NewRepository(&RepositoryOptions{
+		Dir:        dir,
+		MaybeClone: true,
+		RemoteURL:  originDir,
+		CI:         "test",
+		Tuning:     &TuningOptions{FetchJobs: 2, CheckoutWorkers: 4, CommitGraph: true},
+	})
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	head, err := r.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != commit.Hash.String() {
+		t.Errorf("HeadHash() = %s, want %s", head, commit.Hash)
+	}
+	tags, err := r.Tags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a-v1.0.0"}, tags); diff != "" {
+		t.Errorf("Tags() mismatch (-want +got):\n%s", diff)
+	}
+	cfg, err := r.repo.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, setting := range [][3]string{
+		{"checkout", "workers", "4"},
+		{"fetch", "parallel", "2"},
+		{"submodule", "fetchJobs", "2"},
+		{"fetch", "writeCommitGraph", "true"},
+	} {
+		if got := cfg.Raw.Section(setting[0]).Option(setting[1]); got != setting[2] {
+			t.Errorf("%s.%s = %q, want %q", setting[0], setting[1], got, setting[2])
+		}
+	}
+	if got := cfg.Raw.Section("core").Option("fsmonitor"); got != "" {
+		t.Errorf("core.fsmonitor = %q, want unset", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git", "objects", "info", "commit-graph")); err != nil {
+		t.Errorf("commit-graph not written: %v", err)
+	}
+}
+
+func TestSSHCommandEnv(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name string
+		url  string
+		ssh  *SSHOptions
+		want []string
+	}{
+		{
+			name: "key and known hosts",
+			url:  "git@github.com:googleapis/librarian.git",
+			ssh:  &SSHOptions{KeyFile: "/keys/deploy", KnownHostsFile: "/keys/known_hosts"},
+			want: []string{`GIT_SSH_COMMAND=ssh -i "/keys/deploy" -o IdentitiesOnly=yes -o UserKnownHostsFile="/keys/known_hosts"`},
+		},
+		{
+			name: "agent",
+			url:  "ssh://git@github.com/googleapis/librarian.git",
+			ssh:  &SSHOptions{},
+		},
+		{
+			name: "https",
+			url:  "https://github.com/googleapis/librarian.git",
+			ssh:  &SSHOptions{KeyFile: "/keys/deploy"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			if diff := cmp.Diff(test.want, sshCommandEnv(test.url, test.ssh)); diff != "" {
+				t.Errorf("sshCommandEnv() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// BenchmarkClone compares the clones of go-git with those of git with
+// several tunings, on a repository with many files, like a language monorepo.
+// Run it with:
+//
+//	go test ./internal/gitrepo -run '^$' -bench BenchmarkClone
+func BenchmarkClone(b *testing.B) {
+	if _, err := exec.LookPath("git"); err != nil {
+		b.Skip("git not installed")
+	}
+	origin := b.TempDir()
+	for i := range 20000 {
+		path := filepath.Join(origin, fmt.Sprintf("pkg%03d", i%200), fmt.Sprintf("file%05d.go", i))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(path, fmt.Appendf(nil, "package pkg\n\nconst File%05d = %d\n", i, i), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "--initial-branch=main"},
+		{"add", "--all"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "feat: files"},
+	} {
+		if err := runGit(origin, nil, args...); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.Run("go-git", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := clone(filepath.Join(b.TempDir(), "clone"), origin, "test", nil, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	for _, workers := range []int{1, 4, 8, 16} {
+		b.Run(fmt.Sprintf("git/checkout-workers=%d", workers), func(b *testing.B) {
+			tuning := &TuningOptions{FetchJobs: 4, CheckoutWorkers: workers}
+			for b.Loop() {
+				if _, err := cloneTuned(filepath.Join(b.TempDir(), "clone"), origin, "test", nil, tuning, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
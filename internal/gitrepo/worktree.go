@@ -0,0 +1,74 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// AddWorktree checks out HEAD into a new linked worktree at dir, on the
+// branch branchName, and opens it. An existing branch with the same name is
+// reset to HEAD. The worktree shares the objects and branches of r, but has
+// its own index and HEAD, so that changes are committed in it independently
+// of r and of the other worktrees.
+func (r *LocalRepository) AddWorktree(dir, branchName string) (*LocalRepository, error) {
+	slog.Info("Adding worktree", "dir", dir, "branch", branchName)
+	if err := runGit(r.Dir, nil, "worktree", "add", "--quiet", "-B", branchName, dir, "HEAD"); err != nil {
+		return nil, fmt.Errorf("failed to add worktree: %w", err)
+	}
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, err
+	}
+	return &LocalRepository{
+		Dir:         dir,
+		repo:        repo,
+		gitUsername: r.gitUsername,
+		gitPassword: r.gitPassword,
+		ssh:         r.ssh,
+	}, nil
+}
+
+// RemoveWorktree removes the linked worktree at dir, discarding its
+// uncommitted changes. Its branch is kept.
+func (r *LocalRepository) RemoveWorktree(dir string) error {
+	if err := runGit(r.Dir, nil, "worktree", "remove", "--force", dir); err != nil {
+		return fmt.Errorf("failed to remove worktree: %w", err)
+	}
+	return nil
+}
+
+// AddPaths adds the pending changes of the files at paths, relative to the
+// root of the repository, to the index, including their deletion.
+func (r *LocalRepository) AddPaths(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	args := append([]string{"add", "--all", "--"}, paths...)
+	return runGit(r.Dir, nil, args...)
+}
+
+// ApplyCommit applies the changes of the commit to the working tree and the
+// index, without committing them, like "git cherry-pick --no-commit". It
+// fails if they conflict with the changes in the working tree.
+func (r *LocalRepository) ApplyCommit(commitHash string) error {
+	if err := runGit(r.Dir, nil, "cherry-pick", "--no-commit", commitHash); err != nil {
+		return fmt.Errorf("failed to apply commit %s: %w", commitHash, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitrepo
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWorktree(t *testing.T) {
+	gitRepo, dir := initTestRepo(t)
+	for _, args := range [][]string{
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test User"},
+	} {
+		if err := runGit(dir, nil, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createAndCommit(t, gitRepo, "a/a.txt", []byte("a"), "feat: a")
+	createAndCommit(t, gitRepo, "b/b.txt", []byte("b"), "feat: b")
+	repo, err := open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worktreeDir := filepath.Join(t.TempDir(), "worktrees", "a")
+	worktree, err := repo.AddWorktree(worktreeDir, "librarian-generate-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(worktreeDir, "a", "a.txt"), "regenerated")
+	writeTestFile(t, filepath.Join(worktreeDir, "a", "new.txt"), "new")
+	if err := os.Remove(filepath.Join(worktreeDir, "b", "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := worktree.AddPaths([]string{"a/a.txt", "a/new.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := worktree.Commit("feat(a): regenerate"); err != nil {
+		t.Fatal(err)
+	}
+	commit, err := worktree.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clean, err := repo.IsClean(); err != nil || !clean {
+		t.Errorf("IsClean() of the main worktree = %t, %v, want true", clean, err)
+	}
+	if err := repo.RemoveWorktree(worktreeDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(worktreeDir); !os.IsNotExist(err) {
+		t.Errorf("worktree %s still exists after RemoveWorktree(), err = %v", worktreeDir, err)
+	}
+	if got, err := repo.ResolveRef("librarian-generate-a"); err != nil || got != commit {
+		t.Errorf("ResolveRef() of the branch of the worktree = %q, %v, want %q", got, err, commit)
+	}
+
+	if err := repo.ApplyCommit(commit); err != nil {
+		t.Fatal(err)
+	}
+	status, err := repo.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changed []string
+	for path := range status {
+		changed = append(changed, path)
+	}
+	slices.Sort(changed)
+	if diff := cmp.Diff([]string{"a/a.txt", "a/new.txt"}, changed); diff != "" {
+		t.Errorf("files changed by ApplyCommit() mismatch (-want +got):\n%s", diff)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "a", "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "regenerated" {
+		t.Errorf("a/a.txt = %q, want %q", content, "regenerated")
+	}
+}
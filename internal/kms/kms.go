@@ -0,0 +1,136 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kms provides the operations on Cloud Key Management Service which
+// Librarian needs to sign the artifacts of releases.
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"cloud.google.com/go/auth/credentials"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+const (
+	defaultBaseURL = "https://cloudkms.googleapis.com"
+	cloudKMSScope  = "https://www.googleapis.com/auth/cloudkms"
+)
+
+// keyVersionRegex matches the resource names of the versions of crypto keys.
+var keyVersionRegex = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+/cryptoKeyVersions/[^/]+$`)
+
+// IsKeyVersion reports whether name is the resource name of the version of a
+// crypto key, e.g.
+// "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1".
+func IsKeyVersion(name string) bool {
+	return keyVersionRegex.MatchString(name)
+}
+
+// Client is a client of the REST API of Cloud KMS.
+type Client struct {
+	baseURL    string
+	token      func(ctx context.Context) (string, error)
+	httpClient *http.Client
+}
+
+// NewClient creates a Client authenticated with the application default
+// credentials.
+func NewClient() (*Client, error) {
+	creds, err := credentials.DetectDefault(&credentials.DetectOptions{Scopes: []string{cloudKMSScope}})
+	if err != nil {
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("failed to find Google Cloud credentials: %w", err))
+	}
+	token := func(ctx context.Context) (string, error) {
+		t, err := creds.Token(ctx)
+		if err != nil {
+			return "", err
+		}
+		return t.Value, nil
+	}
+	return &Client{baseURL: defaultBaseURL, token: token, httpClient: http.DefaultClient}, nil
+}
+
+// SignSHA256 signs the SHA-256 digest of data with the asymmetric key
+// version keyVersion, whose algorithm must use SHA-256, e.g.
+// EC_SIGN_P256_SHA256 or RSA_SIGN_PKCS1_4096_SHA256, and returns the
+// signature.
+func (c *Client) SignSHA256(ctx context.Context, keyVersion string, data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	request, err := json.Marshal(map[string]any{
+		"digest": map[string]string{"sha256": base64.StdEncoding.EncodeToString(digest[:])},
+	})
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/v1/%s:asymmetricSign", keyVersion), bytes.NewReader(request))
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Signature string `json:"signature"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse signature of key %s: %w", keyVersion, err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(response.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signature of key %s: %w", keyVersion, err)
+	}
+	return signature, nil
+}
+
+// do sends an authenticated request to the REST API, and returns the body of
+// the response.
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	token, err := c.token(ctx)
+	if err != nil {
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("failed to get Google Cloud access token: %w", err))
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, failure.New(failure.TransientInfra, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, failure.New(failure.TransientInfra, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("google cloud credentials are missing or not permitted: %w", err))
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+			return nil, failure.New(failure.TransientInfra, err)
+		}
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+const testKeyVersion = "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1"
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer test-token")
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return &Client{
+		baseURL:    server.URL,
+		token:      func(ctx context.Context) (string, error) { return "test-token", nil },
+		httpClient: server.Client(),
+	}
+}
+
+func TestIsKeyVersion(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		want bool
+	}{
+		{name: testKeyVersion, want: true},
+		{name: "projects/p/locations/global/keyRings/r/cryptoKeys/k"},
+		{name: "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1/extra"},
+		{name: ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := IsKeyVersion(test.name); got != test.want {
+				t.Errorf("IsKeyVersion(%q) = %t, want %t", test.name, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSignSHA256(t *testing.T) {
+	data := []byte("artifact")
+	digest := sha256.Sum256(data)
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if want := "/v1/" + testKeyVersion + ":asymmetricSign"; r.Method != http.MethodPost || r.URL.Path != want {
+			t.Errorf("request = %s %s, want POST %s", r.Method, r.URL.Path, want)
+		}
+		var request struct {
+			Digest struct {
+				SHA256 string `json:"sha256"`
+			} `json:"digest"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatal(err)
+		}
+		if want := base64.StdEncoding.EncodeToString(digest[:]); request.Digest.SHA256 != want {
+			t.Errorf("digest = %q, want %q", request.Digest.SHA256, want)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"signature": base64.StdEncoding.EncodeToString([]byte("signature"))})
+	})
+	got, err := client.SignSHA256(context.Background(), testKeyVersion, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, []byte("signature")) {
+		t.Errorf("SignSHA256() = %q, want %q", got, "signature")
+	}
+}
+
+func TestErrorCategory(t *testing.T) {
+	for _, test := range []struct {
+		status int
+		want   failure.Category
+	}{
+		{status: http.StatusForbidden, want: failure.UserConfig},
+		{status: http.StatusServiceUnavailable, want: failure.TransientInfra},
+		{status: http.StatusNotFound, want: failure.Unknown},
+	} {
+		t.Run(http.StatusText(test.status), func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "failed", test.status)
+			})
+			_, err := client.SignSHA256(context.Background(), testKeyVersion, []byte("artifact"))
+			if err == nil {
+				t.Fatal("SignSHA256() error = nil, want error")
+			}
+			if got := failure.CategoryOf(err); got != test.want {
+				t.Errorf("CategoryOf() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	protoPackageRegex = regexp.MustCompile(`\bpackage\s+([\w.]+)\s*;`)
+	protoServiceRegex = regexp.MustCompile(`\bservice\s+(\w+)\s*\{`)
+)
+
+// protoFile is what the API check reads from a proto file.
+type protoFile struct {
+	pkg      string
+	imports  []string
+	services []string
+}
+
+// apiChecker checks the API definitions in an API source on the host, before
+// they are generated. The proto files are scanned, not compiled: the check is
+// meant to catch broken API definitions in seconds, with the paths at fault,
+// rather than after minutes in the generate container.
+type apiChecker struct {
+	apiRoot string
+	// protos are the scanned proto files by path relative to apiRoot, or nil
+	// for the files which do not exist.
+	protos map[string]*protoFile
+	// problems are the problems found, prefixed by the path at fault.
+	problems []string
+}
+
+// checkAPIs checks the APIs in apiRoot. For each API whose directory exists,
+// it checks that:
+//   - the directory contains proto files, whose comments, strings and braces
+//     are terminated and balanced, and which declare a package;
+//   - the files imported by the proto files exist in apiRoot, apart from the
+//     well-known types of google/protobuf;
+//   - its service config, if any, exists and parses, and the APIs it lists are
+//     services declared in the proto files of their packages.
+//
+// All the problems found are reported together in a single error.
+func checkAPIs(apiRoot string, apis []*config.API) error {
+	c := &apiChecker{apiRoot: apiRoot, protos: make(map[string]*protoFile)}
+	for _, api := range apis {
+		c.checkAPI(api)
+	}
+	if len(c.problems) == 0 {
+		return nil
+	}
+	return failure.New(failure.UserConfig, fmt.Errorf("invalid API definitions in %s:\n  %s", apiRoot, strings.Join(c.problems, "\n  ")))
+}
+
+func (c *apiChecker) problem(path, format string, args ...any) {
+	c.problems = append(c.problems, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+// checkAPI checks the proto files and the service config of api.
+func (c *apiChecker) checkAPI(api *config.API) {
+	files, err := c.protoFiles(api.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Missing APIs are reported by validateAPIPaths for API sources,
+		// and otherwise by the generator, which may not need protos.
+		return
+	}
+	if err != nil {
+		c.problem(api.Path, "%v", err)
+		return
+	}
+	if len(files) == 0 {
+		c.problem(api.Path, "no proto files")
+	}
+	for _, file := range files {
+		proto := c.proto(file)
+		if proto == nil {
+			continue
+		}
+		for _, imported := range proto.imports {
+			if strings.HasPrefix(imported, "google/protobuf/") {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(c.apiRoot, imported)); err != nil {
+				c.problem(file, "imported file %s not found", imported)
+			}
+		}
+	}
+	if api.ServiceConfig != "" {
+		c.checkServiceConfig(path.Join(api.Path, api.ServiceConfig))
+	}
+}
+
+// checkServiceConfig checks that the service config at p parses, and that
+// the APIs it lists are declared in the proto files of their packages, e.g.
+// the service FooService in package google.cloud.foo.v1 is declared in a
+// proto file in google/cloud/foo/v1.
+func (c *apiChecker) checkServiceConfig(p string) {
+	data, err := os.ReadFile(filepath.Join(c.apiRoot, p))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			c.problem(p, "service config not found")
+		} else {
+			c.problem(p, "%v", err)
+		}
+		return
+	}
+	var sc serviceConfig
+	if err := yaml.Unmarshal(data, &sc); err != nil {
+		c.problem(p, "failed to parse service config: %v", err)
+		return
+	}
+	for _, api := range sc.APIs {
+		i := strings.LastIndex(api.Name, ".")
+		if i <= 0 {
+			c.problem(p, "invalid API name %q", api.Name)
+			continue
+		}
+		pkg, service := api.Name[:i], api.Name[i+1:]
+		if !c.declaresService(pkg, service) {
+			c.problem(p, "API %s is not a service declared in %s", api.Name, strings.ReplaceAll(pkg, ".", "/"))
+		}
+	}
+}
+
+// declaresService reports whether a proto file of package pkg in the
+// directory of the package declares service.
+func (c *apiChecker) declaresService(pkg, service string) bool {
+	files, err := c.protoFiles(strings.ReplaceAll(pkg, ".", "/"))
+	if err != nil {
+		return false
+	}
+	for _, file := range files {
+		if proto := c.proto(file); proto != nil && proto.pkg == pkg && slices.Contains(proto.services, service) {
+			return true
+		}
+	}
+	return false
+}
+
+// protoFiles returns the paths of the proto files in the directory dir,
+// relative to the API root. The error wraps [fs.ErrNotExist] if dir does not
+// exist.
+func (c *apiChecker) protoFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(c.apiRoot, dir))
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".proto") {
+			files = append(files, path.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// proto returns the scanned proto file at p, or nil if it cannot be scanned,
+// in which case the problem is recorded. Each file is scanned once.
+func (c *apiChecker) proto(p string) *protoFile {
+	if proto, ok := c.protos[p]; ok {
+		return proto
+	}
+	c.protos[p] = nil
+	data, err := os.ReadFile(filepath.Join(c.apiRoot, p))
+	if err != nil {
+		c.problem(p, "%v", err)
+		return nil
+	}
+	code, err := stripProtoComments(string(data))
+	if err != nil {
+		c.problem(p, "%v", err)
+		return nil
+	}
+	proto := &protoFile{}
+	match := protoPackageRegex.FindStringSubmatch(code)
+	if match == nil {
+		c.problem(p, "no package declared")
+		return nil
+	}
+	proto.pkg = match[1]
+	for _, match := range protoImportRegex.FindAllStringSubmatch(code, -1) {
+		proto.imports = append(proto.imports, match[1])
+	}
+	for _, match := range protoServiceRegex.FindAllStringSubmatch(code, -1) {
+		proto.services = append(proto.services, match[1])
+	}
+	c.protos[p] = proto
+	return proto
+}
+
+// stripProtoComments returns the proto source code with its comments blanked
+// out, keeping the line breaks. It fails, with the line at fault, if a
+// comment or a string is not terminated, or if the braces are not balanced.
+func stripProtoComments(source string) (string, error) {
+	var b strings.Builder
+	line := 1
+	var open []int // the lines of the open braces
+	for i := 0; i < len(source); i++ {
+		ch := source[i]
+		switch {
+		case ch == '\n':
+			line++
+			b.WriteByte(ch)
+		case strings.HasPrefix(source[i:], "//"):
+			end := strings.IndexByte(source[i:], '\n')
+			if end == -1 {
+				end = len(source) - i
+			}
+			b.WriteString(strings.Repeat(" ", end))
+			i += end - 1
+		case strings.HasPrefix(source[i:], "/*"):
+			end := strings.Index(source[i+2:], "*/")
+			if end == -1 {
+				return "", fmt.Errorf("line %d: unterminated comment", line)
+			}
+			comment := source[i : i+2+end+2]
+			newlines := strings.Count(comment, "\n")
+			line += newlines
+			b.WriteString(strings.Repeat("\n", newlines))
+			b.WriteByte(' ')
+			i += len(comment) - 1
+		case ch == '"' || ch == '\'':
+			j := i + 1
+			for ; j < len(source) && source[j] != ch && source[j] != '\n'; j++ {
+				if source[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(source) || source[j] != ch {
+				return "", fmt.Errorf("line %d: unterminated string", line)
+			}
+			b.WriteString(source[i : j+1])
+			i = j
+		case ch == '{':
+			open = append(open, line)
+			b.WriteByte(ch)
+		case ch == '}':
+			if len(open) == 0 {
+				return "", fmt.Errorf("line %d: unbalanced }", line)
+			}
+			open = open[:len(open)-1]
+			b.WriteByte(ch)
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	if len(open) > 0 {
+		return "", fmt.Errorf("line %d: { is never closed", open[len(open)-1])
+	}
+	return b.String(), nil
+}
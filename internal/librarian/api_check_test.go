@@ -0,0 +1,221 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+func TestCheckAPIs(t *testing.T) {
+	t.Parallel()
+	const fooProto = `syntax = "proto3";
+
+// The Foo API.
+package google.cloud.foo.v1;
+
+import "google/protobuf/empty.proto";
+import "google/cloud/foo/v1/resources.proto";
+
+service FooService {
+  /* Not a service { */
+  rpc GetFoo(GetFooRequest) returns (google.protobuf.Empty) {
+    option (google.api.http) = { get: "/v1/{name=foos/*}" };
+  }
+}
+`
+	const resourcesProto = `syntax = "proto3";
+
+package google.cloud.foo.v1;
+
+message GetFooRequest {
+  string name = 1;
+}
+`
+	const fooServiceConfig = `type: google.api.Service
+name: foo.googleapis.com
+apis:
+- name: google.cloud.foo.v1.FooService
+`
+	for _, test := range []struct {
+		name  string
+		files map[string]string
+		apis  []*config.API
+		// wantProblems are the problems which must be in the error, or none
+		// for no error.
+		wantProblems []string
+	}{
+		{
+			name: "valid",
+			files: map[string]string{
+				"google/cloud/foo/v1/foo.proto":       fooProto,
+				"google/cloud/foo/v1/resources.proto": resourcesProto,
+				"google/cloud/foo/v1/foo_v1.yaml":     fooServiceConfig,
+			},
+			apis: []*config.API{{Path: "google/cloud/foo/v1", ServiceConfig: "foo_v1.yaml"}},
+		},
+		{
+			name:  "missing API is not checked",
+			files: map[string]string{},
+			apis:  []*config.API{{Path: "google/cloud/foo/v1"}},
+		},
+		{
+			name: "no proto files",
+			files: map[string]string{
+				"google/cloud/foo/v1/foo_v1.yaml": "type: google.api.Service\n",
+			},
+			apis:         []*config.API{{Path: "google/cloud/foo/v1"}},
+			wantProblems: []string{"google/cloud/foo/v1: no proto files"},
+		},
+		{
+			name: "missing import",
+			files: map[string]string{
+				"google/cloud/foo/v1/foo.proto": fooProto,
+			},
+			apis:         []*config.API{{Path: "google/cloud/foo/v1"}},
+			wantProblems: []string{"google/cloud/foo/v1/foo.proto: imported file google/cloud/foo/v1/resources.proto not found"},
+		},
+		{
+			name: "unbalanced braces",
+			files: map[string]string{
+				"google/cloud/foo/v1/foo.proto": "syntax = \"proto3\";\npackage google.cloud.foo.v1;\nservice FooService {\n",
+			},
+			apis:         []*config.API{{Path: "google/cloud/foo/v1"}},
+			wantProblems: []string{"google/cloud/foo/v1/foo.proto: line 3: { is never closed"},
+		},
+		{
+			name: "no package",
+			files: map[string]string{
+				"google/cloud/foo/v1/foo.proto": "syntax = \"proto3\";\n// package google.cloud.foo.v1;\n",
+			},
+			apis:         []*config.API{{Path: "google/cloud/foo/v1"}},
+			wantProblems: []string{"google/cloud/foo/v1/foo.proto: no package declared"},
+		},
+		{
+			name: "missing service config",
+			files: map[string]string{
+				"google/cloud/foo/v1/foo.proto":       fooProto,
+				"google/cloud/foo/v1/resources.proto": resourcesProto,
+			},
+			apis:         []*config.API{{Path: "google/cloud/foo/v1", ServiceConfig: "foo_v1.yaml"}},
+			wantProblems: []string{"google/cloud/foo/v1/foo_v1.yaml: service config not found"},
+		},
+		{
+			name: "service config lists undeclared services",
+			files: map[string]string{
+				"google/cloud/foo/v1/foo.proto":       fooProto,
+				"google/cloud/foo/v1/resources.proto": resourcesProto,
+				"google/cloud/foo/v1/foo_v1.yaml":     fooServiceConfig + "- name: google.cloud.foo.v1.BarService\n- name: google.cloud.bar.v1.BarService\n",
+			},
+			apis: []*config.API{{Path: "google/cloud/foo/v1", ServiceConfig: "foo_v1.yaml"}},
+			wantProblems: []string{
+				"google/cloud/foo/v1/foo_v1.yaml: API google.cloud.foo.v1.BarService is not a service declared in google/cloud/foo/v1",
+				"google/cloud/foo/v1/foo_v1.yaml: API google.cloud.bar.v1.BarService is not a service declared in google/cloud/bar/v1",
+			},
+		},
+		{
+			name: "invalid service config",
+			files: map[string]string{
+				"google/cloud/foo/v1/foo.proto":       fooProto,
+				"google/cloud/foo/v1/resources.proto": resourcesProto,
+				"google/cloud/foo/v1/foo_v1.yaml":     "apis: {",
+			},
+			apis:         []*config.API{{Path: "google/cloud/foo/v1", ServiceConfig: "foo_v1.yaml"}},
+			wantProblems: []string{"google/cloud/foo/v1/foo_v1.yaml: failed to parse service config"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			apiRoot := t.TempDir()
+			for name, content := range test.files {
+				path := filepath.Join(apiRoot, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := checkAPIs(apiRoot, test.apis)
+			if len(test.wantProblems) == 0 {
+				if err != nil {
+					t.Fatalf("checkAPIs() error = %v", err)
+				}
+				return
+			}
+			if failure.CategoryOf(err) != failure.UserConfig {
+				t.Fatalf("checkAPIs() error = %v, want %q error", err, failure.UserConfig)
+			}
+			for _, want := range test.wantProblems {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("checkAPIs() error = %v, want error containing %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStripProtoComments(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		source  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "comments",
+			source: "a // b\n/* c\nd */ e \"// f\"",
+			want:   "a     \n\n  e \"// f\"",
+		},
+		{
+			name:    "unterminated comment",
+			source:  "a\n/* b",
+			wantErr: "line 2: unterminated comment",
+		},
+		{
+			name:    "unterminated string",
+			source:  "a\noption b = \"c;\n",
+			wantErr: "line 2: unterminated string",
+		},
+		{
+			name:    "unbalanced close",
+			source:  "a {}\n}",
+			wantErr: "line 2: unbalanced }",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := stripProtoComments(test.source)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Fatalf("stripProtoComments() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("stripProtoComments() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
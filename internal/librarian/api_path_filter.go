@@ -0,0 +1,93 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+// apiPathFilter selects the libraries to regenerate by the paths of their
+// APIs, see [config.Config.APIPathFilter].
+type apiPathFilter struct {
+	// include and exclude are the segments of the globs which include and
+	// exclude API paths.
+	include [][]string
+	exclude [][]string
+}
+
+// parseAPIPathFilter parses the globs of -api-path-filter. It returns nil if
+// filter is empty.
+func parseAPIPathFilter(filter string) (*apiPathFilter, error) {
+	if filter == "" {
+		return nil, nil
+	}
+	f := &apiPathFilter{}
+	for _, glob := range strings.Split(filter, ",") {
+		glob = strings.TrimSpace(glob)
+		exclude := strings.HasPrefix(glob, "!")
+		glob = strings.Trim(strings.TrimPrefix(glob, "!"), "/")
+		if glob == "" {
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("invalid -api-path-filter %q: empty glob", filter))
+		}
+		segments := strings.Split(glob, "/")
+		for _, segment := range segments {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, failure.New(failure.UserConfig, fmt.Errorf("invalid -api-path-filter glob %q: %w", glob, err))
+			}
+		}
+		if exclude {
+			f.exclude = append(f.exclude, segments)
+		} else {
+			f.include = append(f.include, segments)
+		}
+	}
+	return f, nil
+}
+
+// matches reports whether the API path is included by the filter: it matches
+// an include glob, or there is none, and it matches no exclude glob.
+func (f *apiPathFilter) matches(apiPath string) bool {
+	segments := strings.Split(apiPath, "/")
+	match := func(pattern []string) bool { return globMatch(pattern, segments) }
+	if len(f.include) > 0 && !slices.ContainsFunc(f.include, match) {
+		return false
+	}
+	return !slices.ContainsFunc(f.exclude, match)
+}
+
+// selects reports whether library is regenerated. The APIs of a library are
+// generated together, so a library is only selected if some of its APIs
+// match an include glob and none matches an exclude glob. A nil filter
+// selects every library.
+func (f *apiPathFilter) selects(library *config.LibraryState) bool {
+	if f == nil {
+		return true
+	}
+	included := false
+	for _, api := range library.APIs {
+		segments := strings.Split(api.Path, "/")
+		if slices.ContainsFunc(f.exclude, func(pattern []string) bool { return globMatch(pattern, segments) }) {
+			return false
+		}
+		included = included || f.matches(api.Path)
+	}
+	return included
+}
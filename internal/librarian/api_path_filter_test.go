@@ -0,0 +1,121 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+func TestAPIPathFilter_Selects(t *testing.T) {
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "aiplatform", APIs: []*config.API{{Path: "google/cloud/aiplatform/v1"}}},
+			{ID: "aiplatform-beta", APIs: []*config.API{{Path: "google/cloud/aiplatform/v1beta1"}}},
+			{ID: "aiplatform-all", APIs: []*config.API{{Path: "google/cloud/aiplatform/v1"}, {Path: "google/cloud/aiplatform/v1beta1"}}},
+			{ID: "pubsub", APIs: []*config.API{{Path: "google/pubsub/v1"}}},
+			{ID: "handwritten"},
+		},
+	}
+	for _, test := range []struct {
+		name   string
+		filter string
+		want   []string
+	}{
+		{
+			name: "no filter",
+			want: []string{"aiplatform", "aiplatform-beta", "aiplatform-all", "pubsub", "handwritten"},
+		},
+		{
+			name:   "include",
+			filter: "google/cloud/aiplatform/**",
+			want:   []string{"aiplatform", "aiplatform-beta", "aiplatform-all"},
+		},
+		{
+			name:   "include and exclude",
+			filter: "google/cloud/aiplatform/**,!google/cloud/aiplatform/v1beta1",
+			want:   []string{"aiplatform"},
+		},
+		{
+			name:   "exclude only",
+			filter: "!**/v1beta1",
+			want:   []string{"aiplatform", "pubsub"},
+		},
+		{
+			name:   "segment glob",
+			filter: "google/*/v1, google/cloud/*/v1",
+			want:   []string{"aiplatform", "aiplatform-all", "pubsub"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := parseAPIPathFilter(test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, library := range state.Libraries {
+				if filter.selects(library) {
+					got = append(got, library.ID)
+				}
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("selected libraries mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseAPIPathFilter_Invalid(t *testing.T) {
+	for _, filter := range []string{"google/**,", "!", "google/[v1"} {
+		if _, err := parseAPIPathFilter(filter); failure.CategoryOf(err) != failure.UserConfig {
+			t.Errorf("parseAPIPathFilter(%q) error = %v, want a %s error", filter, err, failure.UserConfig)
+		}
+	}
+}
+
+func TestLibrariesToRegenerate(t *testing.T) {
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "aiplatform", APIs: []*config.API{{Path: "google/cloud/aiplatform/v1"}}},
+			{ID: "pubsub", APIs: []*config.API{{Path: "google/pubsub/v1"}}},
+		},
+	}
+	filter, err := parseAPIPathFilter("google/pubsub/**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &generateRunner{cfg: &config.Config{APIPathFilter: "google/pubsub/**"}, state: state, apiPathFilter: filter}
+	libraries, err := r.librariesToRegenerate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(libraries) != 1 || libraries[0].ID != "pubsub" {
+		t.Errorf("librariesToRegenerate() = %v, want [pubsub]", libraries)
+	}
+	if diff := cmp.Diff([]string{"google/pubsub/v1"}, r.apiPathsToGenerate()); diff != "" {
+		t.Errorf("apiPathsToGenerate() mismatch (-want +got):\n%s", diff)
+	}
+
+	r.apiPathFilter, err = parseAPIPathFilter("google/storage/**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.librariesToRegenerate(); failure.CategoryOf(err) != failure.UserConfig {
+		t.Errorf("librariesToRegenerate() error = %v, want a %s error", err, failure.UserConfig)
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+const (
+	apiSourceCheckoutDir    = "api-source"
+	apiSourceSnapshotDir    = "api-source-snapshot"
+	apiSourceProvenanceFile = "api-source-provenance.json"
+)
+
+// apiSourceProvenance records which API definitions a generation ran against.
+type apiSourceProvenance struct {
+	// Commit is the HEAD commit of the API source repository.
+	Commit string `json:"commit"`
+	// Ref is the ref specified with -api-ref which Commit was resolved from.
+	Ref string `json:"ref,omitempty"`
+	// Dirty reports whether the API source repository had uncommitted changes.
+	Dirty bool `json:"dirty"`
+	// Snapshot is the directory the working tree of a dirty API source
+	// repository was copied to. Generation reads API definitions from here.
+	Snapshot string `json:"snapshot,omitempty"`
+}
+
+// openAPISource opens the API source repository specified in cfg. If it is
+// cloned, only sparsePaths are checked out, unless sparsePaths is empty.
+//
+// If cfg.APIRef is set, the API source is checked out at the ref, see
+// checkoutAPISource. Unless cfg.APIRootAllowDirty is set, this is otherwise
+// equivalent to cloneOrOpenRepo and the returned provenance is nil. If it is
+// set, cfg.APISource must be a local directory. If it has uncommitted
+// changes, its working tree is copied into the work root, and the returned
+// provenance records the snapshot.
+func openAPISource(cfg *config.Config, sparsePaths []string) (*gitrepo.LocalRepository, *apiSourceProvenance, error) {
+	if cfg.APIRef != "" {
+		return checkoutAPISource(cfg, sparsePaths)
+	}
+	if !cfg.APIRootAllowDirty {
+		repo, err := cloneOrOpenAPISource(cfg, sparsePaths)
+		return repo, nil, err
+	}
+	if isRemote(cfg.APISource) {
+		return nil, nil, errors.New("-api-root-allow-dirty requires -api-source to be a local directory")
+	}
+	dir, err := filepath.Abs(cfg.APISource)
+	if err != nil {
+		return nil, nil, err
+	}
+	repo, err := gitrepo.NewRepository(&gitrepo.RepositoryOptions{
+		Dir: dir,
+		CI:  cfg.CI,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	clean, err := repo.IsClean()
+	if err != nil {
+		return nil, nil, err
+	}
+	if clean {
+		return repo, nil, nil
+	}
+	head, err := repo.HeadHash()
+	if err != nil {
+		return nil, nil, err
+	}
+	provenance := &apiSourceProvenance{
+		Commit:   head,
+		Dirty:    true,
+		Snapshot: filepath.Join(cfg.WorkRoot, apiSourceSnapshotDir),
+	}
+	slog.Warn("API source has uncommitted changes, generating from a snapshot", "source", dir, "snapshot", provenance.Snapshot)
+	if err := snapshotWorkingTree(provenance.Snapshot, dir); err != nil {
+		return nil, nil, fmt.Errorf("failed to snapshot API source: %w", err)
+	}
+	if err := writeAPISourceProvenance(cfg.WorkRoot, provenance); err != nil {
+		return nil, nil, err
+	}
+	return repo, provenance, nil
+}
+
+// checkoutAPISource checks out the API source repository at cfg.APIRef. A
+// local API source, or git bundle of one, is cloned into the work root first,
+// so that its working tree is left untouched.
+func checkoutAPISource(cfg *config.Config, sparsePaths []string) (*gitrepo.LocalRepository, *apiSourceProvenance, error) {
+	var repo *gitrepo.LocalRepository
+	var err error
+	switch {
+	case isRemote(cfg.APISource):
+		repo, err = cloneOrOpenAPISource(cfg, sparsePaths)
+	case gitrepo.IsBundle(cfg.APISource):
+		repo, err = gitrepo.CloneBundle(cfg.APISource, filepath.Join(cfg.WorkRoot, apiSourceCheckoutDir))
+	default:
+		var dir string
+		dir, err = filepath.Abs(cfg.APISource)
+		if err != nil {
+			return nil, nil, err
+		}
+		repo, err = gitrepo.NewRepository(&gitrepo.RepositoryOptions{
+			Dir:        filepath.Join(cfg.WorkRoot, apiSourceCheckoutDir),
+			MaybeClone: true,
+			RemoteURL:  dir,
+			CI:         cfg.CI,
+			Tuning:     gitTuning(cfg),
+		})
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	commit, err := repo.ResolveRef(cfg.APIRef)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve -api-ref: %w", err)
+	}
+	slog.Info("Checking out API source", "ref", cfg.APIRef, "commit", commit)
+	if err := repo.CheckoutCommit(commit); err != nil {
+		return nil, nil, fmt.Errorf("failed to check out API source at %s: %w", cfg.APIRef, err)
+	}
+	provenance := &apiSourceProvenance{
+		Commit: commit,
+		Ref:    cfg.APIRef,
+	}
+	if err := writeAPISourceProvenance(cfg.WorkRoot, provenance); err != nil {
+		return nil, nil, err
+	}
+	return repo, provenance, nil
+}
+
+// cloneOrOpenAPISource is like cloneOrOpenRepo for the API source, but only
+// checks out sparsePaths if it is cloned from a remote, unless sparsePaths is
+// empty.
+func cloneOrOpenAPISource(cfg *config.Config, sparsePaths []string) (*gitrepo.LocalRepository, error) {
+	if !isRemote(cfg.APISource) || len(sparsePaths) == 0 {
+		return cloneOrOpenRepo(cfg.WorkRoot, cfg.APISource, cfg.CI, cfg.GitHubToken, apiSourceSSHOptions(cfg), gitTuning(cfg))
+	}
+	return gitrepo.NewRepository(&gitrepo.RepositoryOptions{
+		Dir:         remoteRepoDir(cfg.WorkRoot, cfg.APISource),
+		MaybeClone:  true,
+		RemoteURL:   cfg.APISource,
+		CI:          cfg.CI,
+		GitPassword: cfg.GitHubToken,
+		SSH:         apiSourceSSHOptions(cfg),
+		Tuning:      gitTuning(cfg),
+		SparsePaths: sparsePaths,
+	})
+}
+
+// sharedAPIDependencies are the directories of the API source which most
+// APIs import, and which are checked out with the APIs of the libraries.
+var sharedAPIDependencies = []string{
+	"google/api",
+	"google/cloud/location",
+	"google/iam/v1",
+	"google/longrunning",
+	"google/rpc",
+	"google/type",
+}
+
+// apiSourceSparsePaths returns the directories of the API source which
+// generate checks out when it clones the API source: the APIs of the
+// libraries in the state of languageRepo, or of cfg.Library only, and
+// cfg.API, with sharedAPIDependencies and the API source dependencies of
+// librarianConfig. It returns nil, for a full checkout, for other commands,
+// with cfg.APISourceFullClone, or if the APIs are unknown.
+func apiSourceSparsePaths(cfg *config.Config, languageRepo *gitrepo.LocalRepository, librarianConfig *config.LibrarianConfig) []string {
+	// sync-apis copies the imports of the APIs too, which may be anywhere in
+	// the API source.
+	if cfg.CommandName != generateCmdName || cfg.APISourceFullClone || !isRemote(cfg.APISource) {
+		return nil
+	}
+	// The service configs of the APIs are not needed, so the state is
+	// loaded without the API source.
+	state, err := loadRepoState(languageRepo, "", cfg.Library)
+	if err != nil || state == nil {
+		slog.Info("APIs of the libraries unknown, checking out the whole API source", "err", err)
+		return nil
+	}
+	var paths []string
+	if cfg.Library != "" {
+		if library := findLibraryByID(state, cfg.Library); library != nil {
+			for _, api := range library.APIs {
+				paths = append(paths, api.Path)
+			}
+		}
+	} else {
+		paths = stateAPIPaths(state)
+	}
+	if cfg.API != "" {
+		paths = append(paths, cfg.API)
+	}
+	if len(paths) == 0 {
+		slog.Info("No APIs to generate, checking out the whole API source")
+		return nil
+	}
+	paths = append(paths, sharedAPIDependencies...)
+	if librarianConfig != nil {
+		paths = append(paths, librarianConfig.APISourceDependencies...)
+	}
+	return paths
+}
+
+// validateAPIPaths returns an error listing the APIs which do not exist in the
+// API source at apiRoot.
+func validateAPIPaths(apiRoot string, apiPaths []string) error {
+	var missing []string
+	for _, apiPath := range apiPaths {
+		if _, err := os.Stat(filepath.Join(apiRoot, apiPath)); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			missing = append(missing, apiPath)
+		}
+	}
+	if len(missing) > 0 {
+		return failure.New(failure.UserConfig, fmt.Errorf("APIs not found in API source: %s", strings.Join(missing, ", ")))
+	}
+	return nil
+}
+
+// snapshotWorkingTree copies the working tree of the git repository in src,
+// excluding the .git directory, to dst.
+func snapshotWorkingTree(dst, src string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		// Skip the destination in case the work root is inside the API source.
+		if d.IsDir() && (d.Name() == ".git" || path == dst) {
+			return filepath.SkipDir
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0755)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return copyFile(target, path)
+		}
+	})
+}
+
+func writeAPISourceProvenance(workRoot string, provenance *apiSourceProvenance) error {
+	data, err := json.MarshalIndent(provenance, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(workRoot, apiSourceProvenanceFile)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write API source provenance: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+func TestOpenAPISource(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name       string
+		allowDirty bool
+		dirty      bool
+		url        bool
+		wantDirty  bool
+		wantErr    bool
+	}{
+		{
+			name: "clean source",
+		},
+		{
+			name:    "dirty source is rejected",
+			dirty:   true,
+			wantErr: true,
+		},
+		{
+			name:       "dirty source is allowed",
+			allowDirty: true,
+			dirty:      true,
+			wantDirty:  true,
+		},
+		{
+			name:       "clean source with allow dirty",
+			allowDirty: true,
+		},
+		{
+			name:       "allow dirty with remote source",
+			allowDirty: true,
+			url:        true,
+			wantErr:    true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			sourceDir := newTestGitRepo(t).GetDir()
+			if test.dirty {
+				if err := os.WriteFile(filepath.Join(sourceDir, "new.proto"), []byte("syntax = \"proto3\";"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cfg := &config.Config{
+				APISource:         sourceDir,
+				APIRootAllowDirty: test.allowDirty,
+				WorkRoot:          t.TempDir(),
+			}
+			if test.url {
+				cfg.APISource = "https://github.com/googleapis/googleapis"
+			}
+			repo, provenance, err := openAPISource(cfg, nil)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("openAPISource() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if repo == nil {
+				t.Fatal("openAPISource() returned nil repo")
+			}
+			if !test.wantDirty {
+				if provenance != nil {
+					t.Errorf("openAPISource() provenance = %v, want nil", provenance)
+				}
+				return
+			}
+			head, err := repo.HeadHash()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := &apiSourceProvenance{
+				Commit:   head,
+				Dirty:    true,
+				Snapshot: filepath.Join(cfg.WorkRoot, apiSourceSnapshotDir),
+			}
+			if diff := cmp.Diff(want, provenance); diff != "" {
+				t.Errorf("openAPISource() provenance mismatch (-want +got):\n%s", diff)
+			}
+			if _, err := os.Stat(filepath.Join(want.Snapshot, "new.proto")); err != nil {
+				t.Errorf("uncommitted file missing from snapshot: %v", err)
+			}
+			data, err := os.ReadFile(filepath.Join(cfg.WorkRoot, apiSourceProvenanceFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got apiSourceProvenance
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, &got); diff != "" {
+				t.Errorf("provenance file mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSnapshotWorkingTree(t *testing.T) {
+	t.Parallel()
+	src := t.TempDir()
+	for _, path := range []string{
+		".git/HEAD",
+		"google/cloud/foo/v1/foo.proto",
+		"README.md",
+	} {
+		fullPath := filepath.Join(src, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(path), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dst := filepath.Join(t.TempDir(), "snapshot")
+	if err := snapshotWorkingTree(dst, src); err != nil {
+		t.Fatalf("snapshotWorkingTree() error = %v", err)
+	}
+	got, err := getDirectoryFilesnames(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"README.md", "google/cloud/foo/v1/foo.proto"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("snapshotWorkingTree() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckoutAPISource(t *testing.T) {
+	t.Parallel()
+	sourceRepo := newTestGitRepo(t)
+	sourceDir := sourceRepo.GetDir()
+	tagged, err := sourceRepo.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, sourceDir, "tag", "v1.0.0")
+	if err := os.WriteFile(filepath.Join(sourceDir, "README.md"), []byte("updated"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, sourceDir, "commit", "-am", "update README")
+	sourceHead, err := sourceRepo.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		APISource: sourceDir,
+		APIRef:    "v1.0.0",
+		WorkRoot:  t.TempDir(),
+	}
+	repo, provenance, err := openAPISource(cfg, nil)
+	if err != nil {
+		t.Fatalf("openAPISource() error = %v", err)
+	}
+	if diff := cmp.Diff(filepath.Join(cfg.WorkRoot, apiSourceCheckoutDir), repo.GetDir()); diff != "" {
+		t.Errorf("openAPISource() dir mismatch (-want +got):\n%s", diff)
+	}
+	want := &apiSourceProvenance{
+		Commit: tagged,
+		Ref:    "v1.0.0",
+	}
+	if diff := cmp.Diff(want, provenance); diff != "" {
+		t.Errorf("openAPISource() provenance mismatch (-want +got):\n%s", diff)
+	}
+	got, err := os.ReadFile(filepath.Join(repo.GetDir(), "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("test", string(got)); diff != "" {
+		t.Errorf("README.md mismatch (-want +got):\n%s", diff)
+	}
+	// The local API source is left untouched.
+	head, err := sourceRepo.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != sourceHead {
+		t.Errorf("API source HEAD = %s, want %s", head, sourceHead)
+	}
+
+	cfg.APIRef = "unknown"
+	if _, _, err := openAPISource(cfg, nil); failure.CategoryOf(err) != failure.UserConfig {
+		t.Errorf("openAPISource() error = %v, want %q error", err, failure.UserConfig)
+	}
+}
+
+func TestValidateAPIPaths(t *testing.T) {
+	t.Parallel()
+	apiRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(apiRoot, "google/cloud/foo/v1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateAPIPaths(apiRoot, []string{"google/cloud/foo/v1"}); err != nil {
+		t.Errorf("validateAPIPaths() error = %v", err)
+	}
+	err := validateAPIPaths(apiRoot, []string{"google/cloud/foo/v1", "google/cloud/bar/v1", "google/cloud/baz/v1"})
+	if failure.CategoryOf(err) != failure.UserConfig {
+		t.Fatalf("validateAPIPaths() error = %v, want %q error", err, failure.UserConfig)
+	}
+	want := "APIs not found in API source: google/cloud/bar/v1, google/cloud/baz/v1"
+	if diff := cmp.Diff(want, err.Error()); diff != "" {
+		t.Errorf("validateAPIPaths() error mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAPISourceSparsePaths(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	state := `image: gcr.io/test/image:v1
+libraries:
+  - id: foo
+    source_roots: [foo]
+    apis:
+      - path: google/cloud/foo/v1
+      - path: google/cloud/foo/v2
+  - id: bar
+    source_roots: [bar]
+    apis:
+      - path: google/cloud/bar/v1
+`
+	if err := writeFile(filepath.Join(dir, config.LibrarianDir, librarianStateFile), state); err != nil {
+		t.Fatal(err)
+	}
+	languageRepo := &gitrepo.LocalRepository{Dir: dir}
+	librarianConfig := &config.LibrarianConfig{APISourceDependencies: []string{"google/geo/type"}}
+	for _, test := range []struct {
+		name         string
+		cfg          *config.Config
+		languageRepo *gitrepo.LocalRepository
+		want         []string
+	}{
+		{
+			name: "all libraries",
+			cfg:  &config.Config{CommandName: generateCmdName, APISource: defaultAPISource},
+			want: slices.Concat([]string{"google/cloud/bar/v1", "google/cloud/foo/v1", "google/cloud/foo/v2"},
+				sharedAPIDependencies, []string{"google/geo/type"}),
+		},
+		{
+			name: "library and new API",
+			cfg:  &config.Config{CommandName: generateCmdName, APISource: defaultAPISource, Library: "bar", API: "google/cloud/bar/v2"},
+			want: slices.Concat([]string{"google/cloud/bar/v1", "google/cloud/bar/v2"},
+				sharedAPIDependencies, []string{"google/geo/type"}),
+		},
+		{
+			name: "full clone",
+			cfg:  &config.Config{CommandName: generateCmdName, APISource: defaultAPISource, APISourceFullClone: true},
+		},
+		{
+			name: "local API source",
+			cfg:  &config.Config{CommandName: generateCmdName, APISource: t.TempDir()},
+		},
+		{
+			name: "sync-apis",
+			cfg:  &config.Config{CommandName: syncAPIsCmdName, APISource: defaultAPISource},
+		},
+		{
+			name:         "no state",
+			cfg:          &config.Config{CommandName: generateCmdName, APISource: defaultAPISource},
+			languageRepo: &gitrepo.LocalRepository{Dir: t.TempDir()},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repo := languageRepo
+			if test.languageRepo != nil {
+				repo = test.languageRepo
+			}
+			got := apiSourceSparsePaths(test.cfg, repo, librarianConfig)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("apiSourceSparsePaths() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
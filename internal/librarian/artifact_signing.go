@@ -0,0 +1,267 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/kms"
+	"github.com/googleapis/librarian/internal/logging"
+)
+
+const (
+	// gpgSignatureSuffix is the suffix of the ASCII-armored detached GPG
+	// signature of an artifact.
+	gpgSignatureSuffix = ".asc"
+	// kmsSignatureSuffix is the suffix of the Cloud KMS signature of the
+	// SHA-256 digest of an artifact.
+	kmsSignatureSuffix = ".sig"
+)
+
+// cmdSignArtifacts is the command for the `release sign-artifacts`
+// subcommand.
+var cmdSignArtifacts = &cli.Command{
+	Short:     "sign-artifacts signs the built artifacts of the released libraries.",
+	UsageLine: "librarian release sign-artifacts -release-artifacts=<dir> [flags]",
+	Long: `Signs the built artifacts of the released libraries.
+
+It is run after the build of the artifacts, and before "librarian release
+tag-and-release" uploads them, with their signatures, to the GitHub releases.
+The artifacts are read from the artifact root of -release-artifacts, in a
+subdirectory per library ID, and are signed as configured by "artifact_signing"
+in .librarian/config.yaml: the detached signature of each artifact is written
+next to it, "{artifact}.asc" with GPG, or "{artifact}.sig" with Cloud KMS. The
+signatures left by a previous run are replaced.
+
+The GPG key, and its passphrase, are read from the environment variables named
+by "key_secret_env" and "passphrase_secret_env", which the build environment
+populates from its secrets provider. Nothing is signed if "artifact_signing" is
+not configured.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newCommandRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return signArtifacts(ctx, cfg.ReleaseArtifacts, runner.librarianConfig)
+	},
+}
+
+func init() {
+	cmdSignArtifacts.Init()
+	fs := cmdSignArtifacts.Flags
+	cfg := cmdSignArtifacts.Config
+
+	addFlagErrorFormat(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagReleaseArtifacts(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+// signArtifacts signs the artifacts in the library directories of the
+// artifact root, as configured by the artifact signing of lc, and writes
+// their signatures next to them.
+func signArtifacts(ctx context.Context, root string, lc *config.LibrarianConfig) error {
+	if root == "" {
+		return failure.New(failure.UserConfig, errors.New("-release-artifacts is required"))
+	}
+	if lc == nil || lc.ArtifactSigning == nil {
+		slog.Info("No artifact signing configured, nothing to sign")
+		return nil
+	}
+	signer, err := newArtifactSigner(lc.ArtifactSigning)
+	if err != nil {
+		return err
+	}
+	libraries, err := os.ReadDir(root)
+	if err != nil {
+		return failure.New(failure.UserConfig, fmt.Errorf("failed to read release artifacts: %w", err))
+	}
+	for _, library := range libraries {
+		if !library.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, library.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			// Signatures left by a previous run are replaced, not signed.
+			if !entry.Type().IsRegular() || isArtifactSignature(entry.Name()) {
+				continue
+			}
+			if _, err := signer.sign(ctx, filepath.Join(dir, entry.Name())); err != nil {
+				return fmt.Errorf("failed to sign artifact %s of %s: %w", entry.Name(), library.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// artifactSigner signs the built artifacts of releases.
+type artifactSigner interface {
+	// sign writes the detached signature of the artifact at path next to
+	// it, and returns the path of the signature.
+	sign(ctx context.Context, path string) (string, error)
+}
+
+// newArtifactSigner returns the signer configured by signing, or nil if
+// signing is nil. The keys are read from the environment variables named by
+// signing, which the build environment populates from its secrets.
+func newArtifactSigner(signing *config.ArtifactSigning) (artifactSigner, error) {
+	if signing == nil {
+		return nil, nil
+	}
+	switch signing.Method {
+	case config.ArtifactSigningGPG:
+		key, err := signingSecret(signing.KeySecretEnv)
+		if err != nil {
+			return nil, err
+		}
+		passphrase := ""
+		if signing.PassphraseSecretEnv != "" {
+			if passphrase, err = signingSecret(signing.PassphraseSecretEnv); err != nil {
+				return nil, err
+			}
+		}
+		return &gpgSigner{key: key, passphrase: passphrase}, nil
+	case config.ArtifactSigningKMS:
+		client, err := newKMSClient()
+		if err != nil {
+			return nil, err
+		}
+		return &kmsSigner{client: client, key: signing.KMSKey}, nil
+	}
+	return nil, failure.New(failure.UserConfig, fmt.Errorf("invalid artifact signing method %q", signing.Method))
+}
+
+// signingSecret returns the value of the environment variable env, which
+// holds a secret of the artifact signing key.
+func signingSecret(env string) (string, error) {
+	value, ok := os.LookupEnv(env)
+	if !ok || value == "" {
+		return "", failure.New(failure.UserConfig, fmt.Errorf("environment variable %s of the artifact signing key is not set", env))
+	}
+	logging.AddSecret(value)
+	return value, nil
+}
+
+// signatureSuffix returns the suffix of the signatures written by the
+// artifact signing method.
+func signatureSuffix(method string) string {
+	if method == config.ArtifactSigningKMS {
+		return kmsSignatureSuffix
+	}
+	return gpgSignatureSuffix
+}
+
+// isArtifactSignature reports whether the file name is that of the
+// signature of an artifact, which is not signed itself.
+func isArtifactSignature(name string) bool {
+	return strings.HasSuffix(name, gpgSignatureSuffix) || strings.HasSuffix(name, kmsSignatureSuffix)
+}
+
+// gpgSigner signs artifacts with a GPG private key.
+type gpgSigner struct {
+	// key is the ASCII-armored private key.
+	key string
+	// passphrase is the passphrase of the key, or empty if it has none.
+	passphrase string
+}
+
+// runGPG runs gpg with the given arguments, writing stdin to its standard
+// input. It is a variable so that tests can replace it.
+var runGPG = func(stdin string, args ...string) error {
+	cmd := exec.Command("gpg", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	logging.LogCommand(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("gpg %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// sign imports the key into a temporary GPG home directory, so that the
+// keyring of the user is neither used nor changed, and writes the
+// ASCII-armored detached signature of the artifact to "{path}.asc".
+func (s *gpgSigner) sign(ctx context.Context, path string) (string, error) {
+	home, err := os.MkdirTemp("", "librarian-gpg-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(home)
+	if err := runGPG(s.key, "--batch", "--homedir", home, "--import"); err != nil {
+		return "", failure.New(failure.UserConfig, fmt.Errorf("failed to import the artifact signing key: %w", err))
+	}
+	signature := path + gpgSignatureSuffix
+	if err := runGPG(s.passphrase, "--batch", "--yes", "--homedir", home, "--pinentry-mode", "loopback", "--passphrase-fd", "0",
+		"--armor", "--detach-sign", "--output", signature, path); err != nil {
+		return "", err
+	}
+	slog.Info("Signed artifact", "artifact", path, "signature", signature)
+	return signature, nil
+}
+
+// kmsClient signs data with Cloud KMS.
+type kmsClient interface {
+	SignSHA256(ctx context.Context, keyVersion string, data []byte) ([]byte, error)
+}
+
+// newKMSClient creates the client which signs artifacts with Cloud KMS. It
+// is replaced in tests.
+var newKMSClient = func() (kmsClient, error) {
+	return kms.NewClient()
+}
+
+// kmsSigner signs artifacts with an asymmetric key of Cloud KMS.
+type kmsSigner struct {
+	client kmsClient
+	// key is the resource name of the version of the key.
+	key string
+}
+
+// sign writes the signature of the SHA-256 digest of the artifact to
+// "{path}.sig".
+func (s *kmsSigner) sign(ctx context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	signed, err := s.client.SignSHA256(ctx, s.key, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign with %s: %w", s.key, err)
+	}
+	signature := path + kmsSignatureSuffix
+	if err := os.WriteFile(signature, signed, 0644); err != nil {
+		return "", err
+	}
+	slog.Info("Signed artifact", "artifact", path, "signature", signature)
+	return signature, nil
+}
@@ -0,0 +1,368 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	gh "github.com/google/go-github/v69/github"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/github"
+	"github.com/googleapis/librarian/internal/sbom"
+)
+
+const testKMSKey = "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1"
+
+type mockKMSClient struct {
+	signErr error
+}
+
+func (c *mockKMSClient) SignSHA256(ctx context.Context, keyVersion string, data []byte) ([]byte, error) {
+	if c.signErr != nil {
+		return nil, c.signErr
+	}
+	return []byte("signature of " + string(data)), nil
+}
+
+func useMockKMSClient(t *testing.T, client *mockKMSClient) {
+	t.Helper()
+	saved := newKMSClient
+	newKMSClient = func() (kmsClient, error) { return client, nil }
+	t.Cleanup(func() { newKMSClient = saved })
+}
+
+func TestNewArtifactSigner(t *testing.T) {
+	t.Setenv("TEST_GPG_KEY", "key")
+	t.Setenv("TEST_GPG_PASSPHRASE", "passphrase")
+	useMockKMSClient(t, &mockKMSClient{})
+	for _, test := range []struct {
+		name       string
+		signing    *config.ArtifactSigning
+		want       artifactSigner
+		wantErrMsg string
+	}{
+		{
+			name: "not configured",
+		},
+		{
+			name:    "gpg",
+			signing: &config.ArtifactSigning{Method: config.ArtifactSigningGPG, KeySecretEnv: "TEST_GPG_KEY", PassphraseSecretEnv: "TEST_GPG_PASSPHRASE"},
+			want:    &gpgSigner{key: "key", passphrase: "passphrase"},
+		},
+		{
+			name:    "gpg without passphrase",
+			signing: &config.ArtifactSigning{Method: config.ArtifactSigningGPG, KeySecretEnv: "TEST_GPG_KEY"},
+			want:    &gpgSigner{key: "key"},
+		},
+		{
+			name:       "gpg key not set",
+			signing:    &config.ArtifactSigning{Method: config.ArtifactSigningGPG, KeySecretEnv: "TEST_GPG_KEY_UNSET"},
+			wantErrMsg: "TEST_GPG_KEY_UNSET",
+		},
+		{
+			name:       "gpg passphrase not set",
+			signing:    &config.ArtifactSigning{Method: config.ArtifactSigningGPG, KeySecretEnv: "TEST_GPG_KEY", PassphraseSecretEnv: "TEST_GPG_PASSPHRASE_UNSET"},
+			wantErrMsg: "TEST_GPG_PASSPHRASE_UNSET",
+		},
+		{
+			name:    "kms",
+			signing: &config.ArtifactSigning{Method: config.ArtifactSigningKMS, KMSKey: testKMSKey},
+			want:    &kmsSigner{client: &mockKMSClient{}, key: testKMSKey},
+		},
+		{
+			name:       "invalid method",
+			signing:    &config.ArtifactSigning{Method: "sigstore"},
+			wantErrMsg: "invalid artifact signing method",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := newArtifactSigner(test.signing)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("newArtifactSigner() error = %v, want containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got, cmp.AllowUnexported(gpgSigner{}, kmsSigner{}, mockKMSClient{})); diff != "" {
+				t.Errorf("newArtifactSigner() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGPGSigner(t *testing.T) {
+	saved := runGPG
+	t.Cleanup(func() { runGPG = saved })
+	var stdins []string
+	runGPG = func(stdin string, args ...string) error {
+		stdins = append(stdins, stdin)
+		if slices.Contains(args, "--detach-sign") {
+			output := args[slices.Index(args, "--output")+1]
+			return os.WriteFile(output, []byte("-----BEGIN PGP SIGNATURE-----"), 0644)
+		}
+		return nil
+	}
+	artifact := filepath.Join(t.TempDir(), "lib-1.2.3.tar.gz")
+	if err := os.WriteFile(artifact, []byte("artifact"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	signer := &gpgSigner{key: "key", passphrase: "passphrase"}
+	got, err := signer.sign(context.Background(), artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := artifact + ".asc"; got != want {
+		t.Errorf("sign() = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(got); err != nil {
+		t.Errorf("signature was not written: %v", err)
+	}
+	if diff := cmp.Diff([]string{"key", "passphrase"}, stdins); diff != "" {
+		t.Errorf("gpg stdin mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestKMSSigner(t *testing.T) {
+	artifact := filepath.Join(t.TempDir(), "lib-1.2.3.tar.gz")
+	if err := os.WriteFile(artifact, []byte("artifact"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	signer := &kmsSigner{client: &mockKMSClient{}, key: testKMSKey}
+	got, err := signer.sign(context.Background(), artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := artifact + ".sig"; got != want {
+		t.Errorf("sign() = %q, want %q", got, want)
+	}
+	data, err := os.ReadFile(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "signature of artifact" {
+		t.Errorf("signature = %q, want %q", data, "signature of artifact")
+	}
+
+	signer.client = &mockKMSClient{signErr: errors.New("permission denied")}
+	if _, err := signer.sign(context.Background(), artifact); err == nil || !strings.Contains(err.Error(), testKMSKey) {
+		t.Errorf("sign() error = %v, want containing %q", err, testKMSKey)
+	}
+}
+
+func TestSignArtifacts(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		files     []string
+		signing   *config.ArtifactSigning
+		kmsClient *mockKMSClient
+		want      []string
+		// wantSignature is the content of the signature of storage-1.2.3.tar.gz,
+		// if set.
+		wantSignature string
+		wantErrMsg    string
+	}{
+		{
+			name:  "not configured",
+			files: []string{"storage/storage-1.2.3.tar.gz"},
+			want:  []string{"storage/storage-1.2.3.tar.gz"},
+		},
+		{
+			name:      "kms",
+			files:     []string{"storage/storage-1.2.3.tar.gz", "storage/storage-1.2.3.whl", "pubsub/pubsub-2.0.0.tar.gz"},
+			signing:   &config.ArtifactSigning{Method: config.ArtifactSigningKMS, KMSKey: testKMSKey},
+			kmsClient: &mockKMSClient{},
+			want: []string{
+				"pubsub/pubsub-2.0.0.tar.gz",
+				"pubsub/pubsub-2.0.0.tar.gz.sig",
+				"storage/storage-1.2.3.tar.gz",
+				"storage/storage-1.2.3.tar.gz.sig",
+				"storage/storage-1.2.3.whl",
+				"storage/storage-1.2.3.whl.sig",
+			},
+		},
+		{
+			name:          "signature of a previous run",
+			files:         []string{"storage/storage-1.2.3.tar.gz", "storage/storage-1.2.3.tar.gz.sig"},
+			signing:       &config.ArtifactSigning{Method: config.ArtifactSigningKMS, KMSKey: testKMSKey},
+			kmsClient:     &mockKMSClient{},
+			want:          []string{"storage/storage-1.2.3.tar.gz", "storage/storage-1.2.3.tar.gz.sig"},
+			wantSignature: "signature of storage/storage-1.2.3.tar.gz",
+		},
+		{
+			name:       "signing fails",
+			files:      []string{"storage/storage-1.2.3.tar.gz"},
+			signing:    &config.ArtifactSigning{Method: config.ArtifactSigningKMS, KMSKey: testKMSKey},
+			kmsClient:  &mockKMSClient{signErr: errors.New("permission denied")},
+			wantErrMsg: "failed to sign artifact storage-1.2.3.tar.gz of storage",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if test.kmsClient != nil {
+				useMockKMSClient(t, test.kmsClient)
+			}
+			root := t.TempDir()
+			writeArtifacts(t, root, test.files)
+			err := signArtifacts(context.Background(), root, &config.LibrarianConfig{ArtifactSigning: test.signing})
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("signArtifacts() error = %v, want containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			if err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				rel, err := filepath.Rel(root, path)
+				got = append(got, filepath.ToSlash(rel))
+				return err
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("files mismatch (-want +got):\n%s", diff)
+			}
+			if test.wantSignature != "" {
+				data, err := os.ReadFile(filepath.Join(root, "storage/storage-1.2.3.tar.gz.sig"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != test.wantSignature {
+					t.Errorf("signature = %q, want %q", data, test.wantSignature)
+				}
+			}
+		})
+	}
+}
+
+func TestSignArtifacts_NoArtifactRoot(t *testing.T) {
+	if err := signArtifacts(context.Background(), "", &config.LibrarianConfig{}); err == nil {
+		t.Error("signArtifacts() error = nil, want an error")
+	}
+}
+
+// writeArtifacts writes each of files, relative to root, with its name as its
+// content.
+func writeArtifacts(t *testing.T, root string, files []string) {
+	t.Helper()
+	for _, name := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAttachArtifacts(t *testing.T) {
+	library := &config.LibraryState{ID: "google-cloud-storage"}
+	kms := &config.ArtifactSigning{Method: config.ArtifactSigningKMS, KMSKey: testKMSKey}
+	for _, test := range []struct {
+		name       string
+		files      []string
+		signing    *config.ArtifactSigning
+		attached   []string
+		listErr    error
+		wantAssets []string
+		wantErrMsg string
+	}{
+		{
+			name:       "unsigned",
+			files:      []string{"storage-1.2.3.tar.gz"},
+			wantAssets: []string{"storage-1.2.3.tar.gz"},
+		},
+		{
+			name:       "signed",
+			files:      []string{"storage-1.2.3.tar.gz", "storage-1.2.3.tar.gz.sig", "storage-1.2.3.whl", "storage-1.2.3.whl.sig"},
+			signing:    kms,
+			wantAssets: []string{"storage-1.2.3.tar.gz", "storage-1.2.3.tar.gz.sig", "storage-1.2.3.whl", "storage-1.2.3.whl.sig"},
+		},
+		{
+			name:       "artifact not signed",
+			files:      []string{"storage-1.2.3.tar.gz", "storage-1.2.3.whl", "storage-1.2.3.whl.sig"},
+			signing:    kms,
+			wantErrMsg: "artifact storage-1.2.3.tar.gz of google-cloud-storage is not signed",
+		},
+		{
+			name:       "signed with another method",
+			files:      []string{"storage-1.2.3.tar.gz", "storage-1.2.3.tar.gz.asc"},
+			signing:    kms,
+			wantErrMsg: "artifact storage-1.2.3.tar.gz of google-cloud-storage is not signed",
+		},
+		{
+			name:       "assets already attached",
+			files:      []string{"storage-1.2.3.tar.gz", "storage-1.2.3.tar.gz.sig", "storage-1.2.3.whl", "storage-1.2.3.whl.sig"},
+			signing:    kms,
+			attached:   []string{"storage-1.2.3.tar.gz", "storage-1.2.3.tar.gz.sig", sbom.FileName},
+			wantAssets: []string{"storage-1.2.3.whl", "storage-1.2.3.whl.sig"},
+		},
+		{
+			name: "no artifacts",
+		},
+		{
+			name:       "listing assets fails",
+			files:      []string{"storage-1.2.3.tar.gz"},
+			listErr:    errors.New("server error"),
+			wantErrMsg: "failed to list the assets of release",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			var files []string
+			for _, name := range test.files {
+				files = append(files, filepath.Join(library.ID, name))
+			}
+			writeArtifacts(t, root, files)
+			ghClient := &mockGitHubClient{releaseAssets: test.attached, listReleaseAssetsErr: test.listErr}
+			r := &tagAndReleaseRunner{
+				cfg:             &config.Config{ReleaseArtifacts: root},
+				ghClient:        ghClient,
+				librarianConfig: &config.LibrarianConfig{ArtifactSigning: test.signing},
+			}
+			err := r.attachArtifacts(context.Background(), &github.RepositoryRelease{ID: gh.Ptr(int64(1))}, library)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("attachArtifacts() error = %v, want containing %q", err, test.wantErrMsg)
+				}
+				if ghClient.uploadReleaseAssetCalls != 0 {
+					t.Errorf("uploaded %d assets, want none", ghClient.uploadReleaseAssetCalls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.wantAssets, ghClient.uploadedAssetNames); diff != "" {
+				t.Errorf("uploaded assets mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
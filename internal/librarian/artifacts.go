@@ -0,0 +1,389 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/docker"
+	"github.com/googleapis/librarian/internal/gcs"
+)
+
+const (
+	artifactsManifestFile = "artifacts.json"
+
+	// runArtifactsDir is the directory of the artifacts which belong to no
+	// library.
+	runArtifactsDir = "_run"
+)
+
+// defaultArtifactsInclude are the globs of the artifacts uploaded when
+// -artifacts-include is not specified: the reports at the root of the work
+// root, the generated code and the container logs.
+var defaultArtifactsInclude = []string{"*", "output/**", docker.LogsDir + "/**"}
+
+// artifactStore stores the artifacts of runs, e.g. in Cloud Storage.
+type artifactStore interface {
+	Upload(ctx context.Context, bucket, name string, r io.Reader) error
+	List(ctx context.Context, bucket, prefix string) ([]*gcs.Object, error)
+	Delete(ctx context.Context, bucket, name string) error
+}
+
+// newArtifactStore creates the store into which artifacts are uploaded. It is
+// replaced in tests.
+var newArtifactStore = func() (artifactStore, error) {
+	return gcs.NewClient()
+}
+
+// artifactRun is what commands record about their run to key its artifacts.
+type artifactRun struct {
+	// id is the ID of the run, e.g. the release ID of a release. It is empty
+	// if the command has none.
+	id string
+	// libraryIDs are the libraries which the run worked on.
+	libraryIDs []string
+}
+
+type artifactRunKey struct{}
+
+// newArtifactContext returns a copy of ctx carrying run.
+func newArtifactContext(ctx context.Context, run *artifactRun) context.Context {
+	return context.WithValue(ctx, artifactRunKey{}, run)
+}
+
+// artifactRunFromContext returns the run carried by ctx, or nil if there is
+// none. The methods of artifactRun do nothing on nil.
+func artifactRunFromContext(ctx context.Context) *artifactRun {
+	run, _ := ctx.Value(artifactRunKey{}).(*artifactRun)
+	return run
+}
+
+// setID sets the ID of the run.
+func (r *artifactRun) setID(id string) {
+	if r == nil {
+		return
+	}
+	r.id = id
+}
+
+// addLibraries records the libraries of the state as the libraries of the
+// run.
+func (r *artifactRun) addLibraries(state *config.LibrarianState) {
+	if r == nil || state == nil {
+		return
+	}
+	for _, library := range state.Libraries {
+		if !slices.Contains(r.libraryIDs, library.ID) {
+			r.libraryIDs = append(r.libraryIDs, library.ID)
+		}
+	}
+}
+
+// artifactsManifest records where the artifacts of a run were uploaded.
+type artifactsManifest struct {
+	// RunID is the ID of the run under which the artifacts are stored.
+	RunID string `json:"run_id"`
+	// URL is the gs:// URL of the artifacts of the run.
+	URL string `json:"url"`
+	// BrowserURL is the URL of the artifacts in the Cloud Console.
+	BrowserURL string `json:"browser_url"`
+	// Artifacts are the uploaded artifacts.
+	Artifacts []*uploadedArtifact `json:"artifacts"`
+}
+
+// uploadedArtifact is an artifact uploaded from the work root.
+type uploadedArtifact struct {
+	// Path is the path of the artifact relative to the work root.
+	Path string `json:"path"`
+	// Library is the ID of the library which the artifact belongs to, or
+	// empty if it belongs to no library.
+	Library string `json:"library,omitempty"`
+	// URL is the gs:// URL of the artifact.
+	URL string `json:"url"`
+}
+
+// uploadRunArtifacts uploads the artifacts of a run which started at start,
+// if -artifacts-url is specified. Artifacts must not fail the run, so problems
+// are only logged.
+func uploadRunArtifacts(ctx context.Context, cfg *config.Config, start time.Time) {
+	if cfg.ArtifactsURL == "" || cfg.WorkRoot == "" {
+		return
+	}
+	store, err := newArtifactStore()
+	if err != nil {
+		slog.Warn("failed to upload artifacts", "url", cfg.ArtifactsURL, "error", err)
+		return
+	}
+	manifest, err := uploadArtifacts(ctx, store, cfg, artifactRunFromContext(ctx), start)
+	if err != nil {
+		slog.Warn("failed to upload artifacts", "url", cfg.ArtifactsURL, "error", err)
+		return
+	}
+	if manifest == nil {
+		return
+	}
+	slog.Info("Uploaded artifacts", "count", len(manifest.Artifacts), "url", manifest.URL, "browser_url", manifest.BrowserURL)
+	if cfg.ArtifactsRetention > 0 {
+		if err := deleteExpiredArtifacts(ctx, store, cfg.ArtifactsURL, cfg.ArtifactsRetention, now()); err != nil {
+			slog.Warn("failed to delete expired artifacts", "url", cfg.ArtifactsURL, "error", err)
+		}
+	}
+}
+
+// uploadArtifacts uploads the files of the work root matching
+// -artifacts-include to {prefix}/{run-id}/{library-id}/{path}, and a manifest
+// of them to {prefix}/{run-id}/artifacts.json. The manifest is also written
+// into the work root. It returns nil if no file matches.
+func uploadArtifacts(ctx context.Context, store artifactStore, cfg *config.Config, run *artifactRun, start time.Time) (*artifactsManifest, error) {
+	bucket, prefix, err := gcs.ParseURL(cfg.ArtifactsURL)
+	if err != nil {
+		return nil, err
+	}
+	globs := defaultArtifactsInclude
+	if cfg.ArtifactsInclude != "" {
+		globs = nil
+		for _, glob := range strings.Split(cfg.ArtifactsInclude, ",") {
+			if glob = strings.TrimSpace(glob); glob != "" {
+				globs = append(globs, glob)
+			}
+		}
+	}
+	files, err := findArtifacts(cfg.WorkRoot, globs)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		slog.Info("No artifacts to upload", "dir", cfg.WorkRoot)
+		return nil, nil
+	}
+
+	runID := fmt.Sprintf("%s-%s", cfg.CommandName, start.UTC().Format("20060102T150405Z"))
+	var libraryIDs []string
+	if run != nil {
+		runID = cmp.Or(run.id, runID)
+		libraryIDs = run.libraryIDs
+	}
+	runPrefix := path.Join(prefix, runID)
+	manifest := &artifactsManifest{
+		RunID:      runID,
+		URL:        fmt.Sprintf("gs://%s/%s", bucket, runPrefix),
+		BrowserURL: gcs.BrowserURL(bucket, runPrefix),
+	}
+	for _, file := range files {
+		library := artifactLibrary(file, libraryIDs)
+		name := path.Join(runPrefix, cmp.Or(library, runArtifactsDir), file)
+		if err := uploadFile(ctx, store, bucket, name, filepath.Join(cfg.WorkRoot, file)); err != nil {
+			return nil, fmt.Errorf("failed to upload %s: %w", file, err)
+		}
+		manifest.Artifacts = append(manifest.Artifacts, &uploadedArtifact{
+			Path:    file,
+			Library: library,
+			URL:     fmt.Sprintf("gs://%s/%s", bucket, name),
+		})
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, '\n')
+	if err := os.WriteFile(filepath.Join(cfg.WorkRoot, artifactsManifestFile), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write artifacts manifest: %w", err)
+	}
+	if err := store.Upload(ctx, bucket, path.Join(runPrefix, artifactsManifestFile), bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to upload artifacts manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+func uploadFile(ctx context.Context, store artifactStore, bucket, name, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return store.Upload(ctx, bucket, name, f)
+}
+
+// findArtifacts returns the sorted, slash-separated paths of the regular files
+// in workRoot which match any of globs. The manifest of a previous upload is
+// skipped, and so are the directories which cannot contain a match, such as
+// the clones of repositories.
+func findArtifacts(workRoot string, globs []string) ([]string, error) {
+	var patterns [][]string
+	for _, glob := range globs {
+		segments := strings.Split(strings.Trim(filepath.ToSlash(glob), "/"), "/")
+		for _, segment := range segments {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("invalid artifacts glob %q: %w", glob, err)
+			}
+		}
+		patterns = append(patterns, segments)
+	}
+	var files []string
+	err := filepath.WalkDir(workRoot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(workRoot, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		segments := strings.Split(filepath.ToSlash(rel), "/")
+		if d.IsDir() {
+			if !slices.ContainsFunc(patterns, func(pattern []string) bool { return globMayContain(pattern, segments) }) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || rel == artifactsManifestFile {
+			return nil
+		}
+		if slices.ContainsFunc(patterns, func(pattern []string) bool { return globMatch(pattern, segments) }) {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+// globMatch reports whether the segments of a path match the segments of a
+// glob, where a "**" segment matches any number of segments.
+func globMatch(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if globMatch(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], segments[0])
+	return ok && globMatch(pattern[1:], segments[1:])
+}
+
+// globMayContain reports whether files in the directory with the given
+// segments may match the segments of a glob.
+func globMayContain(pattern, dir []string) bool {
+	for i, segment := range dir {
+		if i >= len(pattern)-1 {
+			return pattern[len(pattern)-1] == "**"
+		}
+		if pattern[i] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(pattern[i], segment); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// artifactLibrary returns the ID of the library which the artifact at the
+// slash-separated path file of the work root belongs to: the generated code
+// in output/{library-id}, and the container logs of the library. It returns
+// empty if the artifact belongs to no library.
+func artifactLibrary(file string, libraryIDs []string) string {
+	logName, isLog := strings.CutPrefix(file, docker.LogsDir+"/")
+	if isLog {
+		logName = strings.TrimSuffix(strings.TrimSuffix(logName, ".stdout.log"), ".stderr.log")
+	}
+	library := ""
+	for _, id := range libraryIDs {
+		var ok bool
+		if isLog {
+			// Log files are named {time}-{command}-{library-id}, with the
+			// slashes of the library ID replaced.
+			ok = strings.HasSuffix(logName, "-"+strings.NewReplacer("/", "_", "\\", "_").Replace(id))
+		} else {
+			ok = strings.HasPrefix(file, "output/"+id+"/")
+		}
+		if ok && len(id) > len(library) {
+			library = id
+		}
+	}
+	return library
+}
+
+// deleteExpiredArtifacts deletes the artifacts of the runs under artifactsURL
+// whose manifest was created before the retention period ending at now. Only
+// the {prefix}/{run-id}/ directories which contain the manifest of librarian,
+// artifacts.json, are deleted, so that other objects under artifactsURL, or in
+// its bucket, are kept.
+func deleteExpiredArtifacts(ctx context.Context, store artifactStore, artifactsURL string, retention time.Duration, now time.Time) error {
+	bucket, prefix, err := gcs.ParseURL(artifactsURL)
+	if err != nil {
+		return err
+	}
+	if prefix != "" {
+		prefix += "/"
+	}
+	objects, err := store.List(ctx, bucket, prefix)
+	if err != nil {
+		return err
+	}
+	expired := make(map[string]bool)
+	for _, object := range objects {
+		run, name, ok := strings.Cut(strings.TrimPrefix(object.Name, prefix), "/")
+		if ok && name == artifactsManifestFile && object.Created.Before(now.Add(-retention)) {
+			expired[run] = true
+		}
+	}
+	deleted := 0
+	// The manifests are deleted last, so that a run whose deletion fails is
+	// deleted again by the next run.
+	for _, manifests := range []bool{false, true} {
+		for _, object := range objects {
+			run, name, ok := strings.Cut(strings.TrimPrefix(object.Name, prefix), "/")
+			if !ok || !expired[run] || (name == artifactsManifestFile) != manifests {
+				continue
+			}
+			if err := store.Delete(ctx, bucket, object.Name); err != nil {
+				return err
+			}
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		slog.Info("Deleted expired artifacts", "count", deleted, "runs", len(expired), "retention", retention)
+	}
+	return nil
+}
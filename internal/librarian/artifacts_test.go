@@ -0,0 +1,225 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gcs"
+)
+
+// fakeArtifactStore keeps the uploaded objects in memory.
+type fakeArtifactStore struct {
+	objects map[string]string
+	created map[string]time.Time
+	deleted []string
+}
+
+func (s *fakeArtifactStore) Upload(ctx context.Context, bucket, name string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if s.objects == nil {
+		s.objects = make(map[string]string)
+	}
+	s.objects[bucket+"/"+name] = string(data)
+	return nil
+}
+
+func (s *fakeArtifactStore) List(ctx context.Context, bucket, prefix string) ([]*gcs.Object, error) {
+	var objects []*gcs.Object
+	for name, created := range s.created {
+		objects = append(objects, &gcs.Object{Name: name, Created: created})
+	}
+	slices.SortFunc(objects, func(a, b *gcs.Object) int { return a.Created.Compare(b.Created) })
+	return objects, nil
+}
+
+func (s *fakeArtifactStore) Delete(ctx context.Context, bucket, name string) error {
+	s.deleted = append(s.deleted, name)
+	return nil
+}
+
+func TestUploadArtifacts(t *testing.T) {
+	start := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	for _, test := range []struct {
+		name          string
+		include       string
+		run           *artifactRun
+		wantRunID     string
+		wantArtifacts []*uploadedArtifact
+	}{
+		{
+			name:      "default include",
+			run:       &artifactRun{libraryIDs: []string{"a", "a/b"}},
+			wantRunID: "generate-20250304T050607Z",
+			wantArtifacts: []*uploadedArtifact{
+				{Path: "generation-report.json", URL: "gs://bucket/runs/generate-20250304T050607Z/_run/generation-report.json"},
+				{Path: "logs/20250304T050607.000Z-generate-a.stderr.log", Library: "a", URL: "gs://bucket/runs/generate-20250304T050607Z/a/logs/20250304T050607.000Z-generate-a.stderr.log"},
+				{Path: "logs/20250304T050607.000Z-generate-a_b.stdout.log", Library: "a/b", URL: "gs://bucket/runs/generate-20250304T050607Z/a/b/logs/20250304T050607.000Z-generate-a_b.stdout.log"},
+				{Path: "output/a/a.go", Library: "a", URL: "gs://bucket/runs/generate-20250304T050607Z/a/output/a/a.go"},
+				{Path: "output/a/b/b.go", Library: "a/b", URL: "gs://bucket/runs/generate-20250304T050607Z/a/b/output/a/b/b.go"},
+			},
+		},
+		{
+			name:      "release with globs",
+			include:   "logs/*.stderr.log, **/*.json",
+			run:       &artifactRun{id: "release-20250304T050607Z", libraryIDs: []string{"a"}},
+			wantRunID: "release-20250304T050607Z",
+			wantArtifacts: []*uploadedArtifact{
+				{Path: "generation-report.json", URL: "gs://bucket/runs/release-20250304T050607Z/_run/generation-report.json"},
+				{Path: "logs/20250304T050607.000Z-generate-a.stderr.log", Library: "a", URL: "gs://bucket/runs/release-20250304T050607Z/a/logs/20250304T050607.000Z-generate-a.stderr.log"},
+				{Path: "repo/.librarian/state.json", URL: "gs://bucket/runs/release-20250304T050607Z/_run/repo/.librarian/state.json"},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			workRoot := t.TempDir()
+			for _, path := range []string{
+				"generation-report.json",
+				"logs/20250304T050607.000Z-generate-a.stderr.log",
+				"logs/20250304T050607.000Z-generate-a_b.stdout.log",
+				"output/a/a.go",
+				"output/a/b/b.go",
+				"repo/.librarian/state.json",
+			} {
+				if err := writeFile(filepath.Join(workRoot, path), path); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cfg := &config.Config{
+				ArtifactsInclude: test.include,
+				ArtifactsURL:     "gs://bucket/runs",
+				CommandName:      "generate",
+				WorkRoot:         workRoot,
+			}
+			store := &fakeArtifactStore{}
+			manifest, err := uploadArtifacts(context.Background(), store, cfg, test.run, start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if manifest.RunID != test.wantRunID {
+				t.Errorf("RunID = %q, want %q", manifest.RunID, test.wantRunID)
+			}
+			if diff := cmp.Diff(test.wantArtifacts, manifest.Artifacts); diff != "" {
+				t.Errorf("uploadArtifacts() mismatch (-want +got):\n%s", diff)
+			}
+			for _, artifact := range manifest.Artifacts {
+				if got := store.objects[artifact.URL[len("gs://"):]]; got != artifact.Path {
+					t.Errorf("content of %s = %q, want %q", artifact.URL, got, artifact.Path)
+				}
+			}
+			data, err := os.ReadFile(filepath.Join(workRoot, artifactsManifestFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := &artifactsManifest{}
+			if err := json.Unmarshal(data, got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(manifest, got); diff != "" {
+				t.Errorf("written manifest mismatch (-want +got):\n%s", diff)
+			}
+			if _, ok := store.objects["bucket/runs/"+test.wantRunID+"/"+artifactsManifestFile]; !ok {
+				t.Errorf("manifest was not uploaded")
+			}
+		})
+	}
+}
+
+func TestUploadArtifacts_NoArtifacts(t *testing.T) {
+	cfg := &config.Config{ArtifactsURL: "gs://bucket", CommandName: "generate", WorkRoot: t.TempDir()}
+	store := &fakeArtifactStore{}
+	manifest, err := uploadArtifacts(context.Background(), store, cfg, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest != nil || len(store.objects) != 0 {
+		t.Errorf("uploadArtifacts() = %v, uploaded %v, want nothing", manifest, store.objects)
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	for _, test := range []struct {
+		glob string
+		path string
+		want bool
+	}{
+		{glob: "*", path: "report.json", want: true},
+		{glob: "*", path: "output/a.go", want: false},
+		{glob: "output/**", path: "output/a/b/c.go", want: true},
+		{glob: "**/*.log", path: "logs/a.log", want: true},
+		{glob: "**/*.log", path: "a.log", want: true},
+		{glob: "logs/*.log", path: "logs/a/b.log", want: false},
+	} {
+		t.Run(test.glob+" "+test.path, func(t *testing.T) {
+			if got := globMatch(strings.Split(test.glob, "/"), strings.Split(test.path, "/")); got != test.want {
+				t.Errorf("globMatch() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDeleteExpiredArtifacts(t *testing.T) {
+	now := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)
+	store := &fakeArtifactStore{
+		created: map[string]time.Time{
+			"runs/old/a":                      now.Add(-48 * time.Hour),
+			"runs/old/artifacts.json":         now.Add(-48 * time.Hour),
+			"runs/recent/a":                   now.Add(-time.Hour),
+			"runs/recent/artifacts.json":      now.Add(-time.Hour),
+			"runs/unrelated/a":                now.Add(-48 * time.Hour),
+			"runs/unrelated/b/artifacts.json": now.Add(-48 * time.Hour),
+			"runs/artifacts.json":             now.Add(-48 * time.Hour),
+			"other/old/a":                     now.Add(-48 * time.Hour),
+			"other/old/artifacts.json":        now.Add(-48 * time.Hour),
+		},
+	}
+	if err := deleteExpiredArtifacts(context.Background(), store, "gs://bucket/runs", 24*time.Hour, now); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"runs/old/a", "runs/old/artifacts.json"}, store.deleted); diff != "" {
+		t.Errorf("deleted mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDeleteExpiredArtifacts_Bucket(t *testing.T) {
+	now := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)
+	store := &fakeArtifactStore{
+		created: map[string]time.Time{
+			"old/a":              now.Add(-48 * time.Hour),
+			"old/artifacts.json": now.Add(-48 * time.Hour),
+			"backups/db.sql":     now.Add(-48 * time.Hour),
+			"index.html":         now.Add(-48 * time.Hour),
+		},
+	}
+	if err := deleteExpiredArtifacts(context.Background(), store, "gs://bucket", 24*time.Hour, now); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"old/a", "old/artifacts.json"}, store.deleted); diff != "" {
+		t.Errorf("deleted mismatch (-want +got):\n%s", diff)
+	}
+}
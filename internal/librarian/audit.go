@@ -0,0 +1,247 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gcs"
+	"github.com/googleapis/librarian/internal/github"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// The actions recorded in the audit log.
+const (
+	auditBranchPushed      = "branch_pushed"
+	auditChangesSent       = "changes_sent_for_review"
+	auditCommentHandled    = "comment_handled"
+	auditCommitCreated     = "commit_created"
+	auditLibraryBlocked    = "library_blocked"
+	auditLibraryUnblocked  = "library_unblocked"
+	auditMirrorPushFailed  = "mirror_push_failed"
+	auditMirrorPushed      = "mirror_pushed"
+	auditOfflineOutput     = "offline_output_written"
+	auditPullRequestOpened = "pull_request_opened"
+	auditReleaseApproved   = "release_approved"
+	auditReleaseCreated    = "release_created"
+	auditStateModified     = "state_modified"
+)
+
+// auditRecord is a line of the audit log, recording a mutating action of a
+// run.
+type auditRecord struct {
+	// Time is when the action was taken.
+	Time time.Time `json:"time"`
+	// Actor is who ran the command, see auditActor.
+	Actor string `json:"actor"`
+	// Command is the name of the command of the run.
+	Command string `json:"command"`
+	// Flags are the flags specified for the command.
+	Flags map[string]string `json:"flags,omitempty"`
+	// Action is the kind of the action, e.g. "commit_created".
+	Action string `json:"action"`
+	// Details identify what the action changed, e.g. the SHA of a commit.
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// auditLog records the mutating actions of a run to -audit-log. A local file
+// is appended to as the actions are taken. The records for a Cloud Storage
+// location are uploaded as a new object at the end of the run, as objects
+// cannot be appended to.
+type auditLog struct {
+	// location is the absolute path of the file, or the gs:// URL.
+	location string
+	actor    string
+	command  string
+	flags    map[string]string
+	start    time.Time
+	// records are the records to upload to Cloud Storage.
+	records []*auditRecord
+}
+
+// newAuditLog returns the audit log of a run of command which started at
+// start with the flags set in fs, or nil if -audit-log is not specified.
+func newAuditLog(cfg *config.Config, fs *flag.FlagSet, start time.Time) (*auditLog, error) {
+	if cfg.AuditLog == "" {
+		return nil, nil
+	}
+	location := cfg.AuditLog
+	if !strings.HasPrefix(location, "gs://") {
+		var err error
+		if location, err = filepath.Abs(location); err != nil {
+			return nil, err
+		}
+	}
+	flags := make(map[string]string)
+	if fs != nil {
+		fs.Visit(func(f *flag.Flag) {
+			flags[f.Name] = f.Value.String()
+		})
+	}
+	return &auditLog{
+		location: location,
+		actor:    auditActor(),
+		command:  cfg.CommandName,
+		flags:    flags,
+		start:    start,
+	}, nil
+}
+
+// auditActor returns who runs librarian: the GitHub user who triggered the
+// workflow in GitHub Actions, and the local user otherwise.
+func auditActor() string {
+	if actor := os.Getenv("GITHUB_ACTOR"); actor != "" {
+		return actor
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return cmp.Or(os.Getenv("USER"), "unknown")
+}
+
+type auditLogKey struct{}
+
+// newAuditContext returns a copy of ctx carrying log.
+func newAuditContext(ctx context.Context, log *auditLog) context.Context {
+	return context.WithValue(ctx, auditLogKey{}, log)
+}
+
+// auditLogFromContext returns the audit log carried by ctx, or nil if there
+// is none. The methods of auditLog do nothing on nil.
+func auditLogFromContext(ctx context.Context) *auditLog {
+	log, _ := ctx.Value(auditLogKey{}).(*auditLog)
+	return log
+}
+
+// record records action with details. The action has been taken already, so
+// a failure to record it is logged rather than failing the run.
+func (l *auditLog) record(action string, details map[string]string) {
+	if l == nil {
+		return
+	}
+	record := &auditRecord{
+		Time:    now().UTC(),
+		Actor:   l.actor,
+		Command: l.command,
+		Flags:   l.flags,
+		Action:  action,
+		Details: details,
+	}
+	if strings.HasPrefix(l.location, "gs://") {
+		l.records = append(l.records, record)
+		return
+	}
+	if err := appendAuditRecord(l.location, record); err != nil {
+		slog.Error("failed to write audit log", "path", l.location, "action", action, "error", err)
+	}
+}
+
+// recordCommit records the commit at the head of repo, created with message.
+func (l *auditLog) recordCommit(repo gitrepo.Repository, message string) {
+	if l == nil {
+		return
+	}
+	sha, err := repo.HeadHash()
+	if err != nil {
+		slog.Error("failed to get the commit to audit", "error", err)
+	}
+	subject, _, _ := strings.Cut(message, "\n")
+	l.record(auditCommitCreated, map[string]string{"sha": sha, "subject": subject})
+}
+
+// recordPullRequest records pr, opened in repo from branch.
+func (l *auditLog) recordPullRequest(repo *github.Repository, branch string, pr *github.PullRequestMetadata) {
+	if l == nil || pr == nil {
+		return
+	}
+	l.record(auditPullRequestOpened, map[string]string{
+		"branch": branch,
+		"number": strconv.Itoa(pr.Number),
+		"url":    fmt.Sprintf("%s/pull/%d", repo.URL(), pr.Number),
+	})
+}
+
+func appendAuditRecord(path string, record *auditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// flushAuditLog uploads the records of the audit log carried by ctx, if it is
+// in Cloud Storage, see upload.
+func flushAuditLog(ctx context.Context) {
+	l := auditLogFromContext(ctx)
+	if l == nil || len(l.records) == 0 {
+		return
+	}
+	store, err := newArtifactStore()
+	if err == nil {
+		err = l.upload(ctx, store)
+	}
+	if err != nil {
+		slog.Error("failed to upload audit log", "url", l.location, "records", len(l.records), "error", err)
+	}
+}
+
+// upload uploads the records to {prefix}/{start}-{command}.jsonl. Each run
+// writes a new object, so that the records of earlier runs are never
+// overwritten.
+func (l *auditLog) upload(ctx context.Context, store artifactStore) error {
+	bucket, prefix, err := gcs.ParseURL(l.location)
+	if err != nil {
+		return err
+	}
+	var data bytes.Buffer
+	for _, record := range l.records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		data.Write(append(line, '\n'))
+	}
+	name := path.Join(prefix, fmt.Sprintf("%s-%s.jsonl", l.start.UTC().Format("20060102T150405.000000000Z"), l.command))
+	if err := store.Upload(ctx, bucket, name, &data); err != nil {
+		return err
+	}
+	l.records = nil
+	return nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/github"
+)
+
+func readAuditRecords(t *testing.T, data string) []*auditRecord {
+	t.Helper()
+	var records []*auditRecord
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		record := &auditRecord{}
+		if err := json.Unmarshal([]byte(line), record); err != nil {
+			t.Fatalf("invalid audit record %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestNewAuditLog(t *testing.T) {
+	t.Parallel()
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	cfg := &config.Config{CommandName: "generate", AuditLog: "gs://bucket/audit"}
+	addFlagLibrary(fs, cfg)
+	addFlagPush(fs, cfg)
+	if err := fs.Parse([]string{"-library", "a"}); err != nil {
+		t.Fatal(err)
+	}
+	l, err := newAuditLog(cfg, fs, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]string{"library": "a"}, l.flags); diff != "" {
+		t.Errorf("flags mismatch (-want +got):\n%s", diff)
+	}
+	if l.location != "gs://bucket/audit" || l.command != "generate" || l.actor == "" {
+		t.Errorf("newAuditLog() = %+v, want gs://bucket/audit of generate with an actor", l)
+	}
+
+	cfg.AuditLog = ""
+	if l, err := newAuditLog(cfg, fs, time.Now()); l != nil || err != nil {
+		t.Errorf("newAuditLog() without -audit-log = %v, %v, want nil", l, err)
+	}
+}
+
+func TestAuditLog_File(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "audit", "librarian.jsonl")
+	if err := writeFile(path, `{"action":"previous"}`+"\n"); err != nil {
+		t.Fatal(err)
+	}
+	l := &auditLog{location: path, actor: "someone", command: "release init", flags: map[string]string{"push": "true"}}
+	l.record(auditStateModified, map[string]string{"path": ".librarian/state.yaml"})
+	l.recordCommit(&MockRepository{HeadHashValue: "abc123"}, "chore: release\n\nbody")
+	l.recordPullRequest(&github.Repository{Owner: "googleapis", Name: "librarian"}, "librarian-1", &github.PullRequestMetadata{Number: 7})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := readAuditRecords(t, string(data))
+	want := []*auditRecord{
+		{Action: "previous"},
+		{
+			Actor:   "someone",
+			Command: "release init",
+			Flags:   map[string]string{"push": "true"},
+			Action:  auditStateModified,
+			Details: map[string]string{"path": ".librarian/state.yaml"},
+		},
+		{
+			Actor:   "someone",
+			Command: "release init",
+			Flags:   map[string]string{"push": "true"},
+			Action:  auditCommitCreated,
+			Details: map[string]string{"sha": "abc123", "subject": "chore: release"},
+		},
+		{
+			Actor:   "someone",
+			Command: "release init",
+			Flags:   map[string]string{"push": "true"},
+			Action:  auditPullRequestOpened,
+			Details: map[string]string{
+				"branch": "librarian-1",
+				"number": "7",
+				"url":    "https://github.com/googleapis/librarian/pull/7",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(auditRecord{}, "Time")); diff != "" {
+		t.Errorf("audit log mismatch (-want +got):\n%s", diff)
+	}
+	for _, record := range got[1:] {
+		if record.Time.IsZero() {
+			t.Errorf("record %s has no time", record.Action)
+		}
+	}
+}
+
+func TestAuditLog_CloudStorage(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2025, 3, 4, 5, 6, 7, 8, time.UTC)
+	l := &auditLog{location: "gs://bucket/audit", actor: "someone", command: "tag-and-release", start: start}
+	l.record(auditReleaseCreated, map[string]string{"tag": "a-1.0.0"})
+	l.record(auditReleaseCreated, map[string]string{"tag": "b-2.0.0"})
+	store := &fakeArtifactStore{}
+	if err := l.upload(context.Background(), store); err != nil {
+		t.Fatal(err)
+	}
+	data, ok := store.objects["bucket/audit/20250304T050607.000000008Z-tag-and-release.jsonl"]
+	if !ok {
+		t.Fatalf("audit log was not uploaded, got %v", store.objects)
+	}
+	var tags []string
+	for _, record := range readAuditRecords(t, data) {
+		tags = append(tags, record.Details["tag"])
+	}
+	if diff := cmp.Diff([]string{"a-1.0.0", "b-2.0.0"}, tags); diff != "" {
+		t.Errorf("uploaded records mismatch (-want +got):\n%s", diff)
+	}
+	if len(l.records) != 0 {
+		t.Errorf("records were kept after upload: %v", l.records)
+	}
+}
+
+func TestAuditLog_Nil(t *testing.T) {
+	t.Parallel()
+	l := auditLogFromContext(context.Background())
+	if l != nil {
+		t.Fatalf("auditLogFromContext() = %v, want nil", l)
+	}
+	// Recording without an audit log does nothing.
+	l.record(auditStateModified, nil)
+	l.recordCommit(&MockRepository{HeadHashError: os.ErrNotExist}, "message")
+	l.recordPullRequest(nil, "branch", nil)
+}
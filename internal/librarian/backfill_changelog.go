@@ -0,0 +1,268 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/github"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/semver"
+)
+
+// changelogHeading is the heading of a changelog written by backfill-changelog.
+const changelogHeading = "# Changelog"
+
+var cmdBackfillChangelog = &cli.Command{
+	Short:     "backfill-changelog reconstructs the changelogs of libraries from git history",
+	UsageLine: "librarian backfill-changelog [flags]",
+	Long: `Reconstructs the "CHANGELOG.md" of libraries from the tags of their past releases
+and the conventional commit messages between them, e.g. when onboarding a
+repository which never maintained changelogs.
+
+For each release tag of a library, matching the "tag_format" of the library, a
+section is written like the release notes of "librarian release init": the
+commits changing the source roots of the library since the previous release
+tag are grouped by type, with breaking changes first. The sections are ordered
+from the newest release to the oldest, and dated with the time of the tagged
+commit.
+
+The changelog is written to "CHANGELOG.md" in the first source root of the
+library. Libraries which already have a changelog, or which have no release
+tags, are skipped. With "-library", only the specified library is considered.
+
+If the "-commit" or "-push" flags are specified, the changes are committed and,
+with "-push", a pull request is created.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newBackfillChangelogRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return runner.run(ctx)
+	},
+}
+
+func init() {
+	cmdBackfillChangelog.Init()
+	fs := cmdBackfillChangelog.Flags
+	cfg := cmdBackfillChangelog.Config
+
+	addFlagAuditLog(fs, cfg)
+	addFlagCommit(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLibrary(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagPush(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+type backfillChangelogRunner struct {
+	cfg             *config.Config
+	repo            gitrepo.Repository
+	state           *config.LibrarianState
+	librarianConfig *config.LibrarianConfig
+	releaseNotes    *releaseNotesFormat
+	ghClient        GitHubClient
+	gerrit          *gerritReview
+}
+
+func newBackfillChangelogRunner(cfg *config.Config) (*backfillChangelogRunner, error) {
+	runner, err := newCommandRunner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &backfillChangelogRunner{
+		cfg:             runner.cfg,
+		repo:            runner.repo,
+		state:           runner.state,
+		librarianConfig: runner.librarianConfig,
+		releaseNotes:    runner.releaseNotes,
+		ghClient:        runner.ghClient,
+		gerrit:          runner.gerrit,
+	}, nil
+}
+
+func (r *backfillChangelogRunner) run(ctx context.Context) error {
+	if err := checkReviewPermissions(ctx, r.cfg, r.ghClient, r.gerrit); err != nil {
+		return err
+	}
+	libraries := r.state.Libraries
+	if r.cfg.Library != "" {
+		library := r.state.LibraryByID(r.cfg.Library)
+		if library == nil {
+			return failure.New(failure.UserConfig, fmt.Errorf("library %q not found in state", r.cfg.Library))
+		}
+		libraries = []*config.LibraryState{library}
+	}
+	tags, err := r.repo.Tags()
+	if err != nil {
+		return err
+	}
+	ghRepo, err := github.FetchGitHubRepoFromHostRemote(r.repo, r.cfg.GitHubHost())
+	if err != nil {
+		return fmt.Errorf("failed to fetch github repo from remote: %w", err)
+	}
+
+	repoDir := r.repo.GetDir()
+	var backfilled []string
+	for _, library := range libraries {
+		if len(library.SourceRoots) == 0 {
+			slog.Info("Skipping library without source roots", "library", library.ID)
+			continue
+		}
+		path := filepath.Join(repoDir, library.SourceRoots[0], changelogFile)
+		if _, err := os.Stat(path); err == nil {
+			slog.Info("Skipping library with a changelog", "library", library.ID, "path", path)
+			continue
+		}
+		changelog, err := backfillChangelog(r.repo, ghRepo, r.librarianConfig, r.releaseNotes, library, tags)
+		if err != nil {
+			return fmt.Errorf("failed to backfill changelog of library %s: %w", library.ID, err)
+		}
+		if changelog == "" {
+			slog.Info("Skipping library without release tags", "library", library.ID)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(changelog), 0644); err != nil {
+			return err
+		}
+		slog.Info("Backfilled changelog", "library", library.ID, "path", path)
+		backfilled = append(backfilled, library.ID)
+	}
+	if len(backfilled) == 0 {
+		slog.Info("No changelogs to backfill")
+		return nil
+	}
+
+	return commitAndPush(ctx, &commitInfo{
+		cfg:           r.cfg,
+		state:         r.state,
+		repo:          r.repo,
+		ghClient:      r.ghClient,
+		gerrit:        r.gerrit,
+		commitMessage: fmt.Sprintf("chore: backfill changelogs of %s", strings.Join(backfilled, ", ")),
+		libraryIDs:    backfilled,
+	})
+}
+
+// releaseTag is a tag of a release of a library.
+type releaseTag struct {
+	tag     string
+	version *semver.Version
+}
+
+// libraryReleaseTags returns the tags among tags of the releases of library,
+// ordered by version from the oldest release.
+func libraryReleaseTags(library *config.LibraryState, tags []string) []*releaseTag {
+	tagRegex := tagFormatRegexp(library)
+	var releases []*releaseTag
+	for _, tag := range tags {
+		matches := tagRegex.FindStringSubmatch(tag)
+		if matches == nil {
+			continue
+		}
+		version, err := semver.Parse(matches[1])
+		if err != nil {
+			continue
+		}
+		releases = append(releases, &releaseTag{tag: tag, version: version})
+	}
+	slices.SortFunc(releases, func(a, b *releaseTag) int { return a.version.Compare(b.version) })
+	return releases
+}
+
+// backfillChangelog returns the changelog of library reconstructed from its
+// release tags among tags, or an empty string if it has none. The referenced
+// bugs link to the bug trackers of librarianConfig.
+func backfillChangelog(repo gitrepo.Repository, ghRepo *github.Repository, librarianConfig *config.LibrarianConfig, format *releaseNotesFormat, library *config.LibraryState, tags []string) (string, error) {
+	releases := libraryReleaseTags(library, tags)
+	if len(releases) == 0 {
+		return "", nil
+	}
+	// The commits since the tag of a release are the commits of the later
+	// releases, so the commits of a release are those since the previous tag
+	// which are not since its own tag.
+	since, err := repo.GetCommitsForPathsSinceTag(library.SourceRoots, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to get commits: %w", err)
+	}
+	var entries []string
+	previousTag := ""
+	for _, release := range releases {
+		sinceRelease, err := repo.GetCommitsForPathsSinceTag(library.SourceRoots, release.tag)
+		if err != nil {
+			return "", fmt.Errorf("failed to get commits since tag %s: %w", release.tag, err)
+		}
+		later := make(map[string]bool)
+		for _, commit := range sinceRelease {
+			later[commit.Hash.String()] = true
+		}
+		var commits []*gitrepo.Commit
+		for _, commit := range since {
+			if !later[commit.Hash.String()] {
+				commits = append(commits, commit)
+			}
+		}
+		conventionalCommits, err := convertToConventionalCommits(repo, library, commits)
+		if err != nil {
+			return "", err
+		}
+		date, err := repo.TagCommitTime(release.tag)
+		if err != nil {
+			return "", err
+		}
+
+		var entry bytes.Buffer
+		data := &releaseNotesData{
+			NewVersion:  release.version.String(),
+			PreviousTag: previousTag,
+			NewTag:      release.tag,
+			Repo:        ghRepo,
+			Date:        format.formatDate(date),
+			Sections:    format.sectionsOf(conventionalCommits),
+			Bugs:        &bugLinker{librarianConfig: librarianConfig, repo: ghRepo},
+		}
+		if err := format.execute(&entry, data); err != nil {
+			// This should not happen, as the templates are validated when
+			// they are loaded.
+			return "", fmt.Errorf("error executing template: %v", err)
+		}
+		entries = append(entries, strings.TrimSpace(entry.String()))
+		since, previousTag = sinceRelease, release.tag
+	}
+	slices.Reverse(entries)
+	return fmt.Sprintf("%s\n\n%s\n", changelogHeading, strings.Join(entries, "\n\n")), nil
+}
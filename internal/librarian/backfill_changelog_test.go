@@ -0,0 +1,140 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+func TestLibraryReleaseTags(t *testing.T) {
+	t.Parallel()
+	library := &config.LibraryState{ID: "a"}
+	tags := []string{"a-1.10.0", "a-1.2.0", "a-2.0.0-preview1", "b-3.0.0", "a-latest", "a-1.9.1"}
+	var got []string
+	for _, release := range libraryReleaseTags(library, tags) {
+		got = append(got, release.tag)
+	}
+	want := []string{"a-1.2.0", "a-1.9.1", "a-1.10.0", "a-2.0.0-preview1"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("libraryReleaseTags() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBackfillChangelogRun(t *testing.T) {
+	t.Parallel()
+	repo := newTestGitRepo(t)
+	repoDir := repo.GetDir()
+	hashes := make(map[string]string)
+	commit := func(path, message string, tags ...string) {
+		t.Helper()
+		if err := writeFile(filepath.Join(repoDir, path), message); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, repoDir, "add", ".")
+		runGit(t, repoDir, "commit", "-m", message)
+		out, err := exec.Command("git", "-C", repoDir, "rev-parse", "HEAD").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes[message] = strings.TrimSpace(string(out))
+		for _, tag := range tags {
+			runGit(t, repoDir, "tag", tag)
+		}
+	}
+	commit("src/a/a.go", "feat: add a", "some-library-1.0.0")
+	commit("src/b/b.go", "feat: add b", "other-library-1.0.0")
+	commit("src/a/a.go", "fix: fix a")
+	commit("src/a/doc.go", "chore: update a")
+	commit("src/a/a.go", "docs: document a", "some-library-1.0.1")
+	commit("src/a/a.go", "feat!: change a", "some-library-2.0.0")
+	commit("src/a/a.go", "feat: unreleased")
+	state := &config.LibrarianState{
+		Image: "some/image:v1.2.3",
+		Libraries: []*config.LibraryState{
+			{ID: "some-library", Version: "2.0.0", SourceRoots: []string{"src/a"}},
+			{ID: "other-library", Version: "1.0.0", SourceRoots: []string{"src/b"}},
+			{ID: "unreleased-library", SourceRoots: []string{"src/c"}},
+		},
+	}
+	if err := writeFile(filepath.Join(repoDir, "src/b", changelogFile), "# Existing\n"); err != nil {
+		t.Fatal(err)
+	}
+	r := &backfillChangelogRunner{
+		cfg:      &config.Config{},
+		repo:     repo,
+		state:    state,
+		ghClient: &mockGitHubClient{},
+	}
+	if err := r.run(context.Background()); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	link := func(message string) string {
+		return fmt.Sprintf("([%s](https://github.com/googleapis/librarian/commit/%s))", hashes[message][:7], hashes[message])
+	}
+	want := fmt.Sprintf(`# Changelog
+
+## [2.0.0](https://github.com/googleapis/librarian/compare/some-library-1.0.1...some-library-2.0.0) (%[1]s)
+
+### ⚠ BREAKING CHANGES
+* change a %[2]s
+
+### Features
+* change a %[2]s
+
+## [1.0.1](https://github.com/googleapis/librarian/compare/some-library-1.0.0...some-library-1.0.1) (%[1]s)
+
+### Bug Fixes
+* fix a %[3]s
+
+### Documentation
+* document a %[4]s
+
+## 1.0.0 (%[1]s)
+
+### Features
+* add a %[5]s
+`, now().Format("2006-01-02"), link("feat!: change a"), link("fix: fix a"), link("docs: document a"), link("feat: add a"))
+	for path, wantContent := range map[string]string{
+		"src/a/" + changelogFile: want,
+		"src/b/" + changelogFile: "# Existing\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(repoDir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(wantContent, string(got)); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", path, diff)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, "src/c", changelogFile)); !os.IsNotExist(err) {
+		t.Errorf("changelog of unreleased-library was written")
+	}
+
+	r.cfg.Library = "unknown"
+	if err := r.run(context.Background()); failure.CategoryOf(err) != failure.UserConfig {
+		t.Errorf("run() error = %v, want %q error", err, failure.UserConfig)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+var cmdBlockLibrary = &cli.Command{
+	Short:     "block-library blocks the generation of a library",
+	UsageLine: "librarian block-library -library=<id> -reason=<reason> [-bug=<bug>] [flags]",
+	Long: `Blocks the generation of a library, e.g. while a bug of the generator breaks it.
+
+The block is recorded as "blocked" of the library in ".librarian/state.yaml",
+with the reason specified with "-reason", the optional bug tracking it
+specified with "-bug", and the date. When all libraries are generated, blocked
+libraries are skipped, and reported in the pull request. They can still be
+generated on their own with "-library". The "status" command lists the
+blocked libraries.
+
+The block is removed with the "unblock-library" command. Both commands are
+recorded in the audit log, if any.
+
+If the "-commit" or "-push" flags are specified, the change is committed and,
+with "-push", a pull request is created.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newBlockLibraryRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return runner.block(ctx)
+	},
+}
+
+var cmdUnblockLibrary = &cli.Command{
+	Short:     "unblock-library unblocks the generation of a library",
+	UsageLine: "librarian unblock-library -library=<id> [flags]",
+	Long: `Unblocks the generation of a library blocked with the "block-library" command,
+removing "blocked" of the library in ".librarian/state.yaml".
+
+If the "-commit" or "-push" flags are specified, the change is committed and,
+with "-push", a pull request is created.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newBlockLibraryRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return runner.unblock(ctx)
+	},
+}
+
+func init() {
+	cmdBlockLibrary.Init()
+	fs := cmdBlockLibrary.Flags
+	cfg := cmdBlockLibrary.Config
+
+	addFlagAuditLog(fs, cfg)
+	addFlagBug(fs, cfg)
+	addFlagCommit(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLibrary(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagPush(fs, cfg)
+	addFlagReason(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+
+	cmdUnblockLibrary.Init()
+	fs = cmdUnblockLibrary.Flags
+	cfg = cmdUnblockLibrary.Config
+
+	addFlagAuditLog(fs, cfg)
+	addFlagCommit(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagLibrary(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagPush(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+type blockLibraryRunner struct {
+	cfg      *config.Config
+	repo     gitrepo.Repository
+	state    *config.LibrarianState
+	ghClient GitHubClient
+	gerrit   *gerritReview
+}
+
+func newBlockLibraryRunner(cfg *config.Config) (*blockLibraryRunner, error) {
+	runner, err := newCommandRunner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &blockLibraryRunner{
+		cfg:      runner.cfg,
+		repo:     runner.repo,
+		state:    runner.state,
+		ghClient: runner.ghClient,
+		gerrit:   runner.gerrit,
+	}, nil
+}
+
+// block records the generation of the library of -library as blocked.
+func (r *blockLibraryRunner) block(ctx context.Context) error {
+	library, err := r.library(ctx)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(r.cfg.Reason) == "" {
+		return failure.New(failure.UserConfig, errors.New("-reason is required"))
+	}
+	library.Blocked = &config.GenerationBlock{
+		Reason: r.cfg.Reason,
+		Bug:    r.cfg.Bug,
+		Since:  now().Format(time.DateOnly),
+	}
+	if err := saveLibrarianState(ctx, r.repo.GetDir(), r.state); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	auditLogFromContext(ctx).record(auditLibraryBlocked, map[string]string{
+		"library": library.ID,
+		"reason":  r.cfg.Reason,
+		"bug":     r.cfg.Bug,
+	})
+	slog.Info("Blocked generation of library", "library", library.ID, "reason", r.cfg.Reason, "bug", r.cfg.Bug)
+	return r.commit(ctx, library.ID, fmt.Sprintf("chore: block generation of %s\n\n%s", library.ID, blockDescription(library.Blocked)))
+}
+
+// unblock removes the block of the generation of the library of -library.
+func (r *blockLibraryRunner) unblock(ctx context.Context) error {
+	library, err := r.library(ctx)
+	if err != nil {
+		return err
+	}
+	if library.Blocked == nil {
+		return failure.New(failure.UserConfig, fmt.Errorf("generation of library %q is not blocked", library.ID))
+	}
+	block := library.Blocked
+	library.Blocked = nil
+	if err := saveLibrarianState(ctx, r.repo.GetDir(), r.state); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	auditLogFromContext(ctx).record(auditLibraryUnblocked, map[string]string{
+		"library": library.ID,
+		"reason":  block.Reason,
+		"bug":     block.Bug,
+	})
+	slog.Info("Unblocked generation of library", "library", library.ID)
+	return r.commit(ctx, library.ID, fmt.Sprintf("chore: unblock generation of %s", library.ID))
+}
+
+// library returns the library of -library, after checking the permissions
+// to send the change for review.
+func (r *blockLibraryRunner) library(ctx context.Context) (*config.LibraryState, error) {
+	if err := checkReviewPermissions(ctx, r.cfg, r.ghClient, r.gerrit); err != nil {
+		return nil, err
+	}
+	if r.cfg.Library == "" {
+		return nil, failure.New(failure.UserConfig, errors.New("-library is required"))
+	}
+	library := r.state.LibraryByID(r.cfg.Library)
+	if library == nil {
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("library %q not found in state", r.cfg.Library))
+	}
+	return library, nil
+}
+
+func (r *blockLibraryRunner) commit(ctx context.Context, libraryID, message string) error {
+	return commitAndPush(ctx, &commitInfo{
+		cfg:           r.cfg,
+		state:         r.state,
+		repo:          r.repo,
+		ghClient:      r.ghClient,
+		gerrit:        r.gerrit,
+		commitMessage: message,
+		libraryIDs:    []string{libraryID},
+	})
+}
+
+// blockDescription describes block, e.g. "generator bug (b/123)".
+func blockDescription(block *config.GenerationBlock) string {
+	if block.Bug == "" {
+		return block.Reason
+	}
+	return fmt.Sprintf("%s (%s)", block.Reason, block.Bug)
+}
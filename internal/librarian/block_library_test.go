@@ -0,0 +1,83 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+func TestBlockLibrary(t *testing.T) {
+	t.Parallel()
+	repo := newTestGitRepo(t)
+	state := &config.LibrarianState{
+		Image: "some/image:v1.2.3",
+		Libraries: []*config.LibraryState{
+			{ID: "some-library", SourceRoots: []string{"src/a"}},
+		},
+	}
+	r := &blockLibraryRunner{
+		cfg: &config.Config{
+			Library: "some-library",
+			Reason:  "generator bug",
+			Bug:     "b/123",
+		},
+		repo:     repo,
+		state:    state,
+		ghClient: &mockGitHubClient{},
+	}
+	ctx := context.Background()
+	if err := r.block(ctx); err != nil {
+		t.Fatalf("block() error = %v", err)
+	}
+	statePath := filepath.Join(repo.GetDir(), config.LibrarianDir, librarianStateFile)
+	got, err := parseLibrarianState(statePath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &config.GenerationBlock{Reason: "generator bug", Bug: "b/123", Since: time.Now().Format(time.DateOnly)}
+	if diff := cmp.Diff(want, got.LibraryByID("some-library").Blocked); diff != "" {
+		t.Errorf("blocked mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := r.unblock(ctx); err != nil {
+		t.Fatalf("unblock() error = %v", err)
+	}
+	got, err = parseLibrarianState(statePath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blocked := got.LibraryByID("some-library").Blocked; blocked != nil {
+		t.Errorf("blocked = %+v after unblock(), want nil", blocked)
+	}
+	if err := r.unblock(ctx); failure.CategoryOf(err) != failure.UserConfig {
+		t.Errorf("unblock() of a library which is not blocked error = %v, want %q error", err, failure.UserConfig)
+	}
+
+	r.cfg.Reason = ""
+	if err := r.block(ctx); failure.CategoryOf(err) != failure.UserConfig {
+		t.Errorf("block() without -reason error = %v, want %q error", err, failure.UserConfig)
+	}
+	r.cfg.Library = "unknown"
+	if err := r.block(ctx); failure.CategoryOf(err) != failure.UserConfig {
+		t.Errorf("block() of an unknown library error = %v, want %q error", err, failure.UserConfig)
+	}
+}
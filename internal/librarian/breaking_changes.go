@@ -0,0 +1,628 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// The kinds of breaking changes of the surface of an API, after the rules of
+// "buf breaking" of the same name.
+const (
+	breakingPackageChanged          = "package-changed"
+	breakingServiceDeleted          = "service-deleted"
+	breakingRPCDeleted              = "rpc-deleted"
+	breakingRPCSignatureChanged     = "rpc-signature-changed"
+	breakingMessageDeleted          = "message-deleted"
+	breakingFieldDeleted            = "field-deleted"
+	breakingFieldNameChanged        = "field-name-changed"
+	breakingFieldTypeChanged        = "field-type-changed"
+	breakingFieldCardinalityChanged = "field-cardinality-changed"
+	breakingFieldOneofChanged       = "field-oneof-changed"
+	breakingEnumDeleted             = "enum-deleted"
+	breakingEnumValueDeleted        = "enum-value-deleted"
+	breakingEnumValueNameChanged    = "enum-value-name-changed"
+)
+
+// breakingChange is a breaking change of the surface of an API of a library
+// since the library was last generated.
+type breakingChange struct {
+	// API is the path of the API.
+	API string `json:"api"`
+	// File is the path of the proto file, relative to the API root.
+	File string `json:"file"`
+	// Kind is one of the kinds of breaking changes, e.g. "field-deleted".
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+}
+
+// protoSurface is the surface of proto files which generated code depends
+// on, keyed by fully qualified names.
+type protoSurface struct {
+	// pkgs are the packages of the files.
+	pkgs map[string]string
+	// files are the files declaring the services, messages and enums.
+	files    map[string]string
+	services map[string]map[string]string // the signatures of the RPCs
+	messages map[string]map[int]*protoField
+	enums    map[string]map[int]string // the names of the values
+}
+
+// protoField is a field of a message.
+type protoField struct {
+	name string
+	typ  string
+	// label is "repeated", "optional" or empty.
+	label string
+	oneof string
+}
+
+func newProtoSurface() *protoSurface {
+	return &protoSurface{
+		pkgs:     make(map[string]string),
+		files:    make(map[string]string),
+		services: make(map[string]map[string]string),
+		messages: make(map[string]map[int]*protoField),
+		enums:    make(map[string]map[int]string),
+	}
+}
+
+// findBreakingChanges returns the breaking changes of the surface of the APIs
+// of library since it was last generated. For each API, the proto files
+// changed by a commit of sourceRepo since then are compared at the last
+// generated commit and in apiRoot, together, so that declarations moved
+// between the files of an API are not reported.
+func findBreakingChanges(sourceRepo gitrepo.Repository, apiRoot string, library *config.LibraryState) ([]*breakingChange, error) {
+	if library.LastGeneratedCommit == "" {
+		return nil, nil
+	}
+	var changes []*breakingChange
+	for _, api := range library.APIs {
+		files, err := changedProtoFiles(sourceRepo, api.Path, library.LastGeneratedCommit)
+		if err != nil {
+			return nil, err
+		}
+		old, current := newProtoSurface(), newProtoSurface()
+		scanned := true
+		for _, file := range files {
+			before, err := sourceRepo.ReadFileAtCommit(library.LastGeneratedCommit, file)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			if err == nil {
+				if err := old.scan(file, string(before)); err != nil {
+					slog.Warn("Skip breaking change detection of API", "api", api.Path, "file", file, "commit", library.LastGeneratedCommit, "err", err)
+					scanned = false
+					break
+				}
+			}
+			after, err := os.ReadFile(filepath.Join(apiRoot, file))
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			if err == nil {
+				if err := current.scan(file, string(after)); err != nil {
+					// The generator reports the invalid file.
+					slog.Warn("Skip breaking change detection of API", "api", api.Path, "file", file, "err", err)
+					scanned = false
+					break
+				}
+			}
+		}
+		if !scanned {
+			continue
+		}
+		for _, change := range compareProtoSurfaces(old, current) {
+			change.API = api.Path
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// changedProtoFiles returns the paths of the proto files of the API at
+// apiPath, but not of its sub-APIs, which a commit of sourceRepo since commit
+// changed.
+func changedProtoFiles(sourceRepo gitrepo.Repository, apiPath, commit string) ([]string, error) {
+	commits, err := sourceRepo.GetCommitsForPathsSinceCommit([]string{apiPath}, commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the changes of API %s: %w", apiPath, err)
+	}
+	files := make(map[string]bool)
+	for _, c := range commits {
+		changed, err := sourceRepo.ChangedFilesInCommit(c.Hash.String())
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range changed {
+			if path.Dir(file) == apiPath && strings.HasSuffix(file, ".proto") {
+				files[file] = true
+			}
+		}
+	}
+	return slices.Sorted(maps.Keys(files)), nil
+}
+
+// compareProtoSurfaces returns the breaking changes from old to current,
+// sorted by file and description.
+func compareProtoSurfaces(old, current *protoSurface) []*breakingChange {
+	var changes []*breakingChange
+	add := func(name, kind, format string, args ...any) {
+		changes = append(changes, &breakingChange{File: old.files[name], Kind: kind, Description: fmt.Sprintf(format, args...)})
+	}
+	// The declarations of a file whose package changed all move with it, and
+	// are not reported on their own.
+	moved := make(map[string]bool)
+	for file, pkg := range old.pkgs {
+		if currentPkg, ok := current.pkgs[file]; ok && currentPkg != pkg {
+			changes = append(changes, &breakingChange{File: file, Kind: breakingPackageChanged, Description: fmt.Sprintf("package changed from %s to %s", pkg, currentPkg)})
+			moved[file] = true
+		}
+	}
+	for service, rpcs := range old.services {
+		currentRPCs, ok := current.services[service]
+		if !ok {
+			if !moved[old.files[service]] {
+				add(service, breakingServiceDeleted, "service %s deleted", service)
+			}
+			continue
+		}
+		for rpc, signature := range rpcs {
+			currentSignature, ok := currentRPCs[rpc]
+			switch {
+			case !ok:
+				add(service, breakingRPCDeleted, "RPC %s.%s deleted", service, rpc)
+			case signature != currentSignature:
+				add(service, breakingRPCSignatureChanged, "RPC %s.%s changed from %s to %s", service, rpc, signature, currentSignature)
+			}
+		}
+	}
+	for message, fields := range old.messages {
+		currentFields, ok := current.messages[message]
+		if !ok {
+			if !moved[old.files[message]] {
+				add(message, breakingMessageDeleted, "message %s deleted", message)
+			}
+			continue
+		}
+		for number, field := range fields {
+			currentField, ok := currentFields[number]
+			if !ok {
+				add(message, breakingFieldDeleted, "field %d %s of message %s deleted", number, field.name, message)
+				continue
+			}
+			if field.name != currentField.name {
+				add(message, breakingFieldNameChanged, "field %d of message %s renamed from %s to %s", number, message, field.name, currentField.name)
+			}
+			if field.typ != currentField.typ {
+				add(message, breakingFieldTypeChanged, "field %s.%s changed type from %s to %s", message, field.name, field.typ, currentField.typ)
+			}
+			if (field.label == "repeated") != (currentField.label == "repeated") {
+				add(message, breakingFieldCardinalityChanged, "field %s.%s changed from %s to %s", message, field.name, fieldCardinality(field), fieldCardinality(currentField))
+			}
+			if field.oneof != currentField.oneof {
+				add(message, breakingFieldOneofChanged, "field %s.%s moved from oneof %q to oneof %q", message, field.name, field.oneof, currentField.oneof)
+			}
+		}
+	}
+	for enum, values := range old.enums {
+		currentValues, ok := current.enums[enum]
+		if !ok {
+			if !moved[old.files[enum]] {
+				add(enum, breakingEnumDeleted, "enum %s deleted", enum)
+			}
+			continue
+		}
+		for number, name := range values {
+			currentName, ok := currentValues[number]
+			switch {
+			case !ok:
+				add(enum, breakingEnumValueDeleted, "value %d %s of enum %s deleted", number, name, enum)
+			case name != currentName:
+				add(enum, breakingEnumValueNameChanged, "value %d of enum %s renamed from %s to %s", number, enum, name, currentName)
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].File != changes[j].File {
+			return changes[i].File < changes[j].File
+		}
+		return changes[i].Description < changes[j].Description
+	})
+	return changes
+}
+
+func fieldCardinality(field *protoField) string {
+	if field.label == "repeated" {
+		return "repeated"
+	}
+	return "singular"
+}
+
+// scan adds the declarations of the proto source code of file to the
+// surface. Like [apiChecker], it scans the declarations rather than compiling
+// the file, so types are compared as written.
+func (p *protoSurface) scan(file, source string) error {
+	code, err := stripProtoComments(source)
+	if err != nil {
+		return err
+	}
+	s := &protoScanner{tokens: tokenizeProto(code), file: file, surface: p}
+	return s.scanFile()
+}
+
+// tokenizeProto splits proto code without comments into identifiers,
+// numbers, strings and punctuation. Qualified names, such as
+// google.protobuf.Timestamp, are single tokens.
+func tokenizeProto(code string) []string {
+	var tokens []string
+	for i := 0; i < len(code); {
+		ch := code[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '"' || ch == '\'':
+			j := i + 1
+			for ; j < len(code) && code[j] != ch; j++ {
+				if code[j] == '\\' {
+					j++
+				}
+			}
+			tokens = append(tokens, code[i:min(j+1, len(code))])
+			i = j + 1
+		case isProtoNameChar(ch):
+			j := i
+			for j < len(code) && isProtoNameChar(code[j]) {
+				j++
+			}
+			tokens = append(tokens, code[i:j])
+			i = j
+		default:
+			tokens = append(tokens, string(ch))
+			i++
+		}
+	}
+	return tokens
+}
+
+func isProtoNameChar(ch byte) bool {
+	return ch == '_' || ch == '.' || ch == '-' || ch == '+' ||
+		('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ('0' <= ch && ch <= '9')
+}
+
+// protoScanner scans the declarations of a proto file from its tokens.
+type protoScanner struct {
+	tokens  []string
+	pos     int
+	file    string
+	pkg     string
+	surface *protoSurface
+}
+
+func (s *protoScanner) peek() string {
+	if s.pos < len(s.tokens) {
+		return s.tokens[s.pos]
+	}
+	return ""
+}
+
+func (s *protoScanner) next() string {
+	token := s.peek()
+	s.pos++
+	return token
+}
+
+func (s *protoScanner) expect(want string) error {
+	if got := s.next(); got != want {
+		return fmt.Errorf("expected %q, got %q", want, got)
+	}
+	return nil
+}
+
+// skipStatement skips the tokens up to the end of the statement, i.e. up to
+// a semicolon, or a closed block.
+func (s *protoScanner) skipStatement() error {
+	depth := 0
+	for s.pos < len(s.tokens) {
+		switch s.next() {
+		case ";":
+			if depth == 0 {
+				return nil
+			}
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth == 0 {
+				if s.peek() == ";" {
+					s.pos++
+				}
+				return nil
+			}
+		}
+	}
+	return errors.New("unexpected end of file")
+}
+
+// skipBrackets skips a bracketed list of field options, if any.
+func (s *protoScanner) skipBrackets() {
+	if s.peek() != "[" {
+		return
+	}
+	depth := 0
+	for s.pos < len(s.tokens) {
+		switch s.next() {
+		case "[":
+			depth++
+		case "]":
+			depth--
+			if depth == 0 {
+				return
+			}
+		}
+	}
+}
+
+func (s *protoScanner) scanFile() error {
+	for s.pos < len(s.tokens) {
+		switch s.peek() {
+		case "package":
+			s.pos++
+			s.pkg = s.next()
+			s.surface.pkgs[s.file] = s.pkg
+			if err := s.expect(";"); err != nil {
+				return err
+			}
+		case "message":
+			s.pos++
+			if err := s.scanMessage(s.qualify(s.next())); err != nil {
+				return err
+			}
+		case "enum":
+			s.pos++
+			if err := s.scanEnum(s.qualify(s.next())); err != nil {
+				return err
+			}
+		case "service":
+			s.pos++
+			if err := s.scanService(s.qualify(s.next())); err != nil {
+				return err
+			}
+		default:
+			// syntax, edition, import, option and extend.
+			if err := s.skipStatement(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *protoScanner) qualify(name string) string {
+	if s.pkg == "" {
+		return name
+	}
+	return s.pkg + "." + name
+}
+
+func (s *protoScanner) scanMessage(name string) error {
+	if err := s.expect("{"); err != nil {
+		return err
+	}
+	fields := make(map[int]*protoField)
+	s.surface.messages[name] = fields
+	s.surface.files[name] = s.file
+	return s.scanMessageBody(name, fields, "")
+}
+
+// scanMessageBody scans the declarations of message name up to its closing
+// brace, or those of its oneof if oneof is not empty.
+func (s *protoScanner) scanMessageBody(name string, fields map[int]*protoField, oneof string) error {
+	for {
+		token := s.next()
+		switch token {
+		case "":
+			return fmt.Errorf("message %s is never closed", name)
+		case "}":
+			return nil
+		case ";":
+		case "message":
+			if err := s.scanMessage(name + "." + s.next()); err != nil {
+				return err
+			}
+		case "enum":
+			if err := s.scanEnum(name + "." + s.next()); err != nil {
+				return err
+			}
+		case "oneof":
+			group := s.next()
+			if err := s.expect("{"); err != nil {
+				return err
+			}
+			if err := s.scanMessageBody(name, fields, group); err != nil {
+				return err
+			}
+		case "option", "reserved", "extensions", "extend":
+			s.pos--
+			if err := s.skipStatement(); err != nil {
+				return err
+			}
+		default:
+			field := &protoField{oneof: oneof}
+			if token == "repeated" || token == "optional" || token == "required" {
+				field.label = token
+				token = s.next()
+			}
+			if token == "map" {
+				var b strings.Builder
+				b.WriteString(token)
+				for s.pos < len(s.tokens) {
+					part := s.next()
+					b.WriteString(part)
+					if part == ">" {
+						break
+					}
+				}
+				token = b.String()
+			}
+			field.typ = token
+			field.name = s.next()
+			if err := s.expect("="); err != nil {
+				return fmt.Errorf("field %s of message %s: %w", field.name, name, err)
+			}
+			n, err := strconv.ParseInt(s.next(), 0, 32)
+			if err != nil {
+				return fmt.Errorf("field %s of message %s: invalid number: %w", field.name, name, err)
+			}
+			number := int(n)
+			if field.typ == "group" {
+				// Groups declare a message; their fields are not tracked.
+				if err := s.skipStatement(); err != nil {
+					return err
+				}
+				fields[number] = field
+				continue
+			}
+			s.skipBrackets()
+			if err := s.expect(";"); err != nil {
+				return fmt.Errorf("field %s of message %s: %w", field.name, name, err)
+			}
+			fields[number] = field
+		}
+	}
+}
+
+func (s *protoScanner) scanEnum(name string) error {
+	if err := s.expect("{"); err != nil {
+		return err
+	}
+	values := make(map[int]string)
+	s.surface.enums[name] = values
+	s.surface.files[name] = s.file
+	for {
+		token := s.next()
+		switch token {
+		case "":
+			return fmt.Errorf("enum %s is never closed", name)
+		case "}":
+			return nil
+		case ";":
+		case "option", "reserved":
+			s.pos--
+			if err := s.skipStatement(); err != nil {
+				return err
+			}
+		default:
+			if err := s.expect("="); err != nil {
+				return fmt.Errorf("value %s of enum %s: %w", token, name, err)
+			}
+			number, err := strconv.ParseInt(s.next(), 0, 32)
+			if err != nil {
+				return fmt.Errorf("value %s of enum %s: invalid number: %w", token, name, err)
+			}
+			s.skipBrackets()
+			if err := s.expect(";"); err != nil {
+				return fmt.Errorf("value %s of enum %s: %w", token, name, err)
+			}
+			// Aliases keep the first name of the value.
+			if _, ok := values[int(number)]; !ok {
+				values[int(number)] = token
+			}
+		}
+	}
+}
+
+func (s *protoScanner) scanService(name string) error {
+	if err := s.expect("{"); err != nil {
+		return err
+	}
+	rpcs := make(map[string]string)
+	s.surface.services[name] = rpcs
+	s.surface.files[name] = s.file
+	for {
+		token := s.next()
+		switch token {
+		case "":
+			return fmt.Errorf("service %s is never closed", name)
+		case "}":
+			return nil
+		case ";":
+		case "rpc":
+			rpc := s.next()
+			request, err := s.scanRPCType()
+			if err != nil {
+				return fmt.Errorf("RPC %s of service %s: %w", rpc, name, err)
+			}
+			if err := s.expect("returns"); err != nil {
+				return fmt.Errorf("RPC %s of service %s: %w", rpc, name, err)
+			}
+			response, err := s.scanRPCType()
+			if err != nil {
+				return fmt.Errorf("RPC %s of service %s: %w", rpc, name, err)
+			}
+			rpcs[rpc] = fmt.Sprintf("(%s) returns (%s)", request, response)
+			if err := s.skipStatement(); err != nil {
+				return err
+			}
+		default:
+			s.pos--
+			if err := s.skipStatement(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// scanRPCType scans the parenthesized request or response type of an RPC,
+// e.g. "(stream Foo)".
+func (s *protoScanner) scanRPCType() (string, error) {
+	if err := s.expect("("); err != nil {
+		return "", err
+	}
+	typ := s.next()
+	if typ == "stream" && s.peek() != ")" {
+		typ += " " + s.next()
+	}
+	if err := s.expect(")"); err != nil {
+		return "", err
+	}
+	return typ, nil
+}
+
+// breakingChangesMarkdown returns the breaking changes of each library in
+// the report as a Markdown list.
+func breakingChangesMarkdown(libraries []*libraryGenerationReport) string {
+	var b strings.Builder
+	for _, library := range libraries {
+		for _, change := range library.BreakingChanges {
+			fmt.Fprintf(&b, "* %s: `%s`: %s (%s)\n", library.ID, change.File, change.Description, change.Kind)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,277 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/conventionalcommits"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+const baseProto = `syntax = "proto3";
+
+package google.foo.v1;
+
+import "google/api/annotations.proto";
+
+// The Foo service.
+service FooService {
+  option (google.api.default_host) = "foo.googleapis.com";
+
+  rpc GetFoo(GetFooRequest) returns (Foo) {
+    option (google.api.http) = {
+      get: "/v1/{name=foos/*}"
+    };
+  }
+  rpc WatchFoo(GetFooRequest) returns (stream Foo);
+}
+
+message Foo {
+  message Bar {
+    int32 size = 1;
+  }
+  enum State {
+    STATE_UNSPECIFIED = 0;
+    ACTIVE = 1;
+  }
+  string name = 1 [(google.api.field_behavior) = IDENTIFIER];
+  repeated string tags = 2;
+  oneof kind {
+    Bar bar = 3;
+    string text = 4;
+  }
+  map<string, string> labels = 5;
+  reserved 6;
+}
+
+message GetFooRequest {
+  string name = 1;
+}
+`
+
+func TestCompareProtoSurfaces(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		replace [][2]string
+		want    []*breakingChange
+	}{
+		{
+			name: "additions only",
+			replace: [][2]string{
+				{"  reserved 6;", "  reserved 6;\n  int64 count = 7;"},
+				{"    ACTIVE = 1;", "    ACTIVE = 1;\n    DELETED = 2;"},
+				{"returns (stream Foo);", "returns (stream Foo);\n  rpc DeleteFoo(GetFooRequest) returns (Foo);"},
+			},
+		},
+		{
+			name:    "comments and options",
+			replace: [][2]string{{"string name = 1 [(google.api.field_behavior) = IDENTIFIER];", "// The name.\n  string name = 1;"}},
+		},
+		{
+			name:    "field deleted",
+			replace: [][2]string{{"  repeated string tags = 2;\n", ""}},
+			want:    []*breakingChange{{File: "foo.proto", Kind: breakingFieldDeleted, Description: "field 2 tags of message google.foo.v1.Foo deleted"}},
+		},
+		{
+			name:    "field renamed and retyped",
+			replace: [][2]string{{"string text = 4;", "bytes content = 4;"}},
+			want: []*breakingChange{
+				{File: "foo.proto", Kind: breakingFieldNameChanged, Description: "field 4 of message google.foo.v1.Foo renamed from text to content"},
+				{File: "foo.proto", Kind: breakingFieldTypeChanged, Description: "field google.foo.v1.Foo.text changed type from string to bytes"},
+			},
+		},
+		{
+			name:    "field cardinality",
+			replace: [][2]string{{"repeated string tags = 2;", "string tags = 2;"}},
+			want:    []*breakingChange{{File: "foo.proto", Kind: breakingFieldCardinalityChanged, Description: "field google.foo.v1.Foo.tags changed from repeated to singular"}},
+		},
+		{
+			name:    "field out of oneof",
+			replace: [][2]string{{"    string text = 4;\n  }", "  }\n  string text = 4;"}},
+			want:    []*breakingChange{{File: "foo.proto", Kind: breakingFieldOneofChanged, Description: `field google.foo.v1.Foo.text moved from oneof "kind" to oneof ""`}},
+		},
+		{
+			name:    "nested message deleted",
+			replace: [][2]string{{"  message Bar {\n    int32 size = 1;\n  }\n", ""}, {"Bar bar = 3;", "int32 bar = 3;"}},
+			want: []*breakingChange{
+				{File: "foo.proto", Kind: breakingFieldTypeChanged, Description: "field google.foo.v1.Foo.bar changed type from Bar to int32"},
+				{File: "foo.proto", Kind: breakingMessageDeleted, Description: "message google.foo.v1.Foo.Bar deleted"},
+			},
+		},
+		{
+			name:    "enum value renamed",
+			replace: [][2]string{{"ACTIVE = 1;", "ENABLED = 1;"}},
+			want:    []*breakingChange{{File: "foo.proto", Kind: breakingEnumValueNameChanged, Description: "value 1 of enum google.foo.v1.Foo.State renamed from ACTIVE to ENABLED"}},
+		},
+		{
+			name:    "RPC signature",
+			replace: [][2]string{{"returns (stream Foo);", "returns (Foo);"}},
+			want:    []*breakingChange{{File: "foo.proto", Kind: breakingRPCSignatureChanged, Description: "RPC google.foo.v1.FooService.WatchFoo changed from (GetFooRequest) returns (stream Foo) to (GetFooRequest) returns (Foo)"}},
+		},
+		{
+			name:    "RPC deleted",
+			replace: [][2]string{{"  rpc WatchFoo(GetFooRequest) returns (stream Foo);\n", ""}},
+			want:    []*breakingChange{{File: "foo.proto", Kind: breakingRPCDeleted, Description: "RPC google.foo.v1.FooService.WatchFoo deleted"}},
+		},
+		{
+			name:    "package",
+			replace: [][2]string{{"package google.foo.v1;", "package google.foo.v2;"}},
+			want:    []*breakingChange{{File: "foo.proto", Kind: breakingPackageChanged, Description: "package changed from google.foo.v1 to google.foo.v2"}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			source := baseProto
+			for _, r := range test.replace {
+				if !strings.Contains(source, r[0]) {
+					t.Fatalf("proto does not contain %q", r[0])
+				}
+				source = strings.Replace(source, r[0], r[1], 1)
+			}
+			old, current := newProtoSurface(), newProtoSurface()
+			if err := old.scan("foo.proto", baseProto); err != nil {
+				t.Fatal(err)
+			}
+			if err := current.scan("foo.proto", source); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, compareProtoSurfaces(old, current)); diff != "" {
+				t.Errorf("compareProtoSurfaces() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFindBreakingChanges(t *testing.T) {
+	t.Parallel()
+	const (
+		changeCommit = "2222222222222222222222222222222222222222"
+		fooProto     = "syntax = \"proto3\";\npackage google.foo.v1;\nmessage Foo {\n  string name = 1;\n  int32 size = 2;\n}\n"
+		typesProto   = "syntax = \"proto3\";\npackage google.foo.v1;\nenum Color {\n  COLOR_UNSPECIFIED = 0;\n  RED = 1;\n}\n"
+	)
+	dir := t.TempDir()
+	// Foo lost its size, and moved to types.proto with Color, which is not
+	// a breaking change on its own.
+	for path, content := range map[string]string{
+		"google/foo/v1/foo.proto":   "syntax = \"proto3\";\npackage google.foo.v1;\n",
+		"google/foo/v1/types.proto": typesProto + "message Foo {\n  string name = 1;\n}\n",
+	} {
+		if err := writeFile(filepath.Join(dir, path), content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sourceRepo := &MockRepository{
+		Dir: dir,
+		GetCommitsForPathsSinceLastGenByPath: map[string][]*gitrepo.Commit{
+			"google/foo/v1": {{Hash: plumbing.NewHash(changeCommit), Message: "feat: move Foo"}},
+		},
+		ChangedFilesInCommitValueByHash: map[string][]string{
+			plumbing.NewHash(changeCommit).String(): {"google/foo/v1/foo.proto", "google/foo/v1/types.proto", "google/foo/v1/foo_v1.yaml"},
+		},
+		FilesAtCommit: map[string]map[string]string{
+			lastGeneratedCommit: {"google/foo/v1/foo.proto": fooProto, "google/foo/v1/types.proto": typesProto},
+		},
+	}
+	for _, test := range []struct {
+		name    string
+		library *config.LibraryState
+		want    []*breakingChange
+	}{
+		{
+			name: "breaking",
+			library: &config.LibraryState{
+				ID:                  "foo",
+				LastGeneratedCommit: lastGeneratedCommit,
+				APIs:                []*config.API{{Path: "google/foo/v1"}},
+			},
+			want: []*breakingChange{{
+				API:         "google/foo/v1",
+				File:        "google/foo/v1/foo.proto",
+				Kind:        breakingFieldDeleted,
+				Description: "field 2 size of message google.foo.v1.Foo deleted",
+			}},
+		},
+		{
+			name: "never generated",
+			library: &config.LibraryState{
+				ID:   "foo",
+				APIs: []*config.API{{Path: "google/foo/v1"}},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := findBreakingChanges(sourceRepo, dir, test.library)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("findBreakingChanges() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBreakingCommitMessages(t *testing.T) {
+	t.Parallel()
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "a", SourceRoots: []string{"a"}},
+			{ID: "b", SourceRoots: []string{"b"}},
+		},
+	}
+	report := &generationReport{
+		Libraries: []*libraryGenerationReport{
+			{
+				ID:              "a",
+				Files:           []*changedFile{{Path: "a/a.go", Change: fileChangeModified}},
+				BreakingChanges: []*breakingChange{{API: "google/a/v1", File: "google/a/v1/a.proto", Kind: breakingFieldDeleted, Description: "field 2 size of message google.a.v1.A deleted"}},
+			},
+			{ID: "b", Files: []*changedFile{{Path: "b/b.go", Change: fileChangeModified}}},
+		},
+	}
+	isBreaking := func(t *testing.T, message string, want map[string]bool) {
+		t.Helper()
+		commits, err := conventionalcommits.ParseCommits(message, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]bool)
+		for _, commit := range commits {
+			got[commit.Scope] = commit.IsBreaking
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("breaking commits of %q mismatch (-want +got):\n%s", message, diff)
+		}
+	}
+	isBreaking(t, attributedRunMessage(nil, state, report, "chore: regenerate\n\nGenerated from API source main at abc"), map[string]bool{"": false, "a": true})
+	isBreaking(t, squashedCommitMessage(nil, state, report, ""), map[string]bool{"": true})
+	for _, group := range []*commitGroup{
+		{libraryID: "a", breaking: hasBreakingChanges(report.Libraries[0], "google/a/v1")},
+		{libraryID: "b", breaking: hasBreakingChanges(report.Libraries[1], "")},
+	} {
+		isBreaking(t, group.commitMessage(nil, findLibraryByID(state, group.libraryID)), map[string]bool{group.libraryID: group.libraryID == "a"})
+	}
+	if !strings.Contains(report.markdown(), "* a: `google/a/v1/a.proto`: field 2 size of message google.a.v1.A deleted (field-deleted)") {
+		t.Errorf("markdown() = %q, want the breaking changes", report.markdown())
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/conventionalcommits"
+	"github.com/googleapis/librarian/internal/github"
+)
+
+// bugLinker resolves the URLs of the bugs referenced in commit messages, with
+// the bug trackers of the librarian config. References to issues of the
+// repository, e.g. "#456", link to the issues of repo unless a bug tracker
+// is configured for them.
+type bugLinker struct {
+	librarianConfig *config.LibrarianConfig
+	// repo is the GitHub repository of the commits, or nil if it is unknown.
+	repo *github.Repository
+}
+
+// url returns the URL of the bug referenced by ref, or an empty string if it
+// is unknown.
+func (l *bugLinker) url(ref string) string {
+	if l == nil {
+		return ""
+	}
+	if url := l.librarianConfig.BugURL(ref); url != "" {
+		return url
+	}
+	if l.repo != nil && len(ref) > 1 && ref[0] == '#' {
+		return l.repo.URL() + "/issues/" + ref[1:]
+	}
+	return ""
+}
+
+// Links returns the bugs referenced by a commit, with their URLs when they
+// are known. It is called by releaseNotesTemplate.
+func (l *bugLinker) Links(bugs []*conventionalcommits.BugReference) []*config.Bug {
+	var links []*config.Bug
+	for _, bug := range bugs {
+		links = append(links, &config.Bug{ID: bug.String(), URL: bug.URL})
+	}
+	l.link(links)
+	return links
+}
+
+// linkChanges resolves the URLs of the bugs of the changes of libraries, so
+// that the language container links them in the changelogs.
+func (l *bugLinker) linkChanges(libraries []*config.LibraryState) {
+	for _, library := range libraries {
+		for _, change := range library.Changes {
+			l.link(change.Bugs)
+		}
+	}
+}
+
+// link sets the URLs of bugs which have none yet.
+func (l *bugLinker) link(bugs []*config.Bug) {
+	for _, bug := range bugs {
+		if bug.URL == "" {
+			bug.URL = l.url(bug.ID)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/github"
+)
+
+func TestBugLinkerLinkChanges(t *testing.T) {
+	t.Parallel()
+	library := &config.LibraryState{
+		ID: "my-library",
+		Changes: []*config.Change{
+			{
+				Type:    "fix",
+				Subject: "a bug fix",
+				Bugs: []*config.Bug{
+					{ID: "#12"},
+					{ID: "b/345"},
+					{ID: "other/repo#6", URL: "https://github.com/other/repo/issues/6"},
+					{ID: "JIRA-7"},
+				},
+			},
+		},
+	}
+	linker := &bugLinker{
+		librarianConfig: &config.LibrarianConfig{
+			BugTrackers: []*config.BugTracker{{Prefix: "b/", URL: "https://issuetracker.google.com/issues/{id}"}},
+		},
+		repo: &github.Repository{Owner: "owner", Name: "repo"},
+	}
+	linker.linkChanges([]*config.LibraryState{library})
+	want := []*config.Bug{
+		{ID: "#12", URL: "https://github.com/owner/repo/issues/12"},
+		{ID: "b/345", URL: "https://issuetracker.google.com/issues/345"},
+		{ID: "other/repo#6", URL: "https://github.com/other/repo/issues/6"},
+		{ID: "JIRA-7"},
+	}
+	if diff := cmp.Diff(want, library.Changes[0].Bugs); diff != "" {
+		t.Errorf("linkChanges() mismatch (-want +got):\n%s", diff)
+	}
+
+	var noLinker *bugLinker
+	if got := noLinker.url("#12"); got != "" {
+		t.Errorf("url() of nil linker = %q, want empty", got)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/config"
+)
+
+// The outcomes of the build and the test of a cell of the build matrix.
+const (
+	matrixPassed  = "passed"
+	matrixFailed  = "failed"
+	matrixSkipped = "skipped"
+)
+
+// matrixCell is the result of building and testing a library in a variant of
+// the build matrix.
+type matrixCell struct {
+	Library string `json:"library"`
+	Variant string `json:"variant"`
+	// Build and Test are the outcomes of the build and the test of the
+	// cell. The test is skipped if the build failed.
+	Build string `json:"build"`
+	Test  string `json:"test"`
+	// Error is the error of the failed command, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// failed reports whether the build or the test of the cell failed.
+func (c *matrixCell) failed() bool {
+	return c.Build == matrixFailed || c.Test == matrixFailed
+}
+
+// runBuildMatrix runs the build and test commands of a library once for each
+// of its variants of the build matrix, or once if it has none. All the
+// variants run, even if some of them fail, and their results are recorded in
+// r.matrix. The library fails if any of its variants fails.
+func (r *generateRunner) runBuildMatrix(ctx context.Context, libraryID string) error {
+	variants := r.librarianConfig.BuildVariantsFor(libraryID)
+	build, test := r.cfg.RunsPhase(config.PhaseBuild), r.cfg.RunsPhase(config.PhaseTest)
+	if len(variants) == 0 || (!build && !test) {
+		if err := r.runBuildCommand(ctx, libraryID, nil); err != nil {
+			return err
+		}
+		return r.runTestCommand(ctx, libraryID, nil)
+	}
+	var errs []error
+	for _, variant := range variants {
+		cell := &matrixCell{Library: libraryID, Variant: variant.Name, Build: matrixSkipped, Test: matrixSkipped}
+		r.matrix = append(r.matrix, cell)
+		if build {
+			if err := r.runBuildCommand(ctx, libraryID, variant); err != nil {
+				cell.Build, cell.Error = matrixFailed, err.Error()
+				errs = append(errs, fmt.Errorf("build of variant %s failed: %w", variant.Name, err))
+				continue
+			}
+			cell.Build = matrixPassed
+		}
+		if test {
+			if err := r.runTestCommand(ctx, libraryID, variant); err != nil {
+				cell.Test, cell.Error = matrixFailed, err.Error()
+				errs = append(errs, fmt.Errorf("test of variant %s failed: %w", variant.Name, err))
+				continue
+			}
+			cell.Test = matrixPassed
+		}
+	}
+	if len(errs) > 0 {
+		slog.Error("library failed in the build matrix", "id", libraryID, "failed", len(errs), "variants", len(variants))
+		return fmt.Errorf("library %s failed in %d of %d variants: %w", libraryID, len(errs), len(variants), errors.Join(errs...))
+	}
+	return nil
+}
+
+// variantName returns the name of variant, or an empty string if it is nil.
+func variantName(variant *config.BuildVariant) string {
+	if variant == nil {
+		return ""
+	}
+	return variant.Name
+}
+
+// matrixMarkdown formats the cells of the build matrix as a table, followed by
+// the number of failed cells.
+func matrixMarkdown(cells []*matrixCell) string {
+	var b strings.Builder
+	b.WriteString("| Library | Variant | Build | Test |\n")
+	b.WriteString("|---|---|---|---|\n")
+	failed := 0
+	for _, cell := range cells {
+		if cell.failed() {
+			failed++
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", cell.Library, cell.Variant, matrixOutcome(cell.Build), matrixOutcome(cell.Test))
+	}
+	if failed > 0 {
+		fmt.Fprintf(&b, "\n**%d of %d cells failed.**\n", failed, len(cells))
+	} else {
+		fmt.Fprintf(&b, "\nAll %d cells passed.\n", len(cells))
+	}
+	return b.String()
+}
+
+func matrixOutcome(outcome string) string {
+	if outcome == matrixFailed {
+		return "**failed**"
+	}
+	return outcome
+}
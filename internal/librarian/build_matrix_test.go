@@ -0,0 +1,86 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestRunBuildMatrix(t *testing.T) {
+	t.Parallel()
+	lc := &config.LibrarianConfig{
+		BuildMatrix: []*config.BuildVariant{
+			{Name: "java8"},
+			{Name: "java11"},
+			{Name: "graalvm", Libraries: []string{"other-library"}},
+		},
+	}
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{{ID: "some-library"}},
+	}
+	container := &mockContainerClient{failBuildVariant: "java8"}
+	r := &generateRunner{
+		cfg:             &config.Config{Phases: "build,test"},
+		repo:            newTestGitRepo(t),
+		state:           state,
+		librarianConfig: lc,
+		containerClient: container,
+	}
+	err := r.runBuildMatrix(t.Context(), "some-library")
+	if err == nil || !strings.Contains(err.Error(), "library some-library failed in 1 of 2 variants") {
+		t.Errorf("runBuildMatrix() error = %v, want failure of variant java8", err)
+	}
+	if diff := cmp.Diff([]string{"java8", "java11"}, container.buildVariants); diff != "" {
+		t.Errorf("build variants mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"java11"}, container.testVariants); diff != "" {
+		t.Errorf("test variants mismatch (-want +got):\n%s", diff)
+	}
+	want := []*matrixCell{
+		{Library: "some-library", Variant: "java8", Build: matrixFailed, Test: matrixSkipped, Error: "simulated build failure of variant"},
+		{Library: "some-library", Variant: "java11", Build: matrixPassed, Test: matrixPassed},
+	}
+	if diff := cmp.Diff(want, r.matrix); diff != "" {
+		t.Errorf("matrix mismatch (-want +got):\n%s", diff)
+	}
+	if got := matrixMarkdown(r.matrix); !strings.Contains(got, "| some-library | java8 | **failed** | skipped |") || !strings.Contains(got, "1 of 2 cells failed") {
+		t.Errorf("matrixMarkdown() = %q, want failed cell of java8", got)
+	}
+}
+
+func TestRunBuildMatrix_NoVariants(t *testing.T) {
+	t.Parallel()
+	container := &mockContainerClient{}
+	r := &generateRunner{
+		cfg:             &config.Config{Phases: "build,test"},
+		repo:            newTestGitRepo(t),
+		state:           &config.LibrarianState{Libraries: []*config.LibraryState{{ID: "some-library"}}},
+		librarianConfig: &config.LibrarianConfig{},
+		containerClient: container,
+	}
+	if err := r.runBuildMatrix(t.Context(), "some-library"); err != nil {
+		t.Fatal(err)
+	}
+	if container.buildCalls != 1 || container.testCalls != 1 {
+		t.Errorf("runBuildMatrix() made %d build and %d test calls, want 1 and 1", container.buildCalls, container.testCalls)
+	}
+	if r.matrix != nil {
+		t.Errorf("runBuildMatrix() recorded cells %v, want none", r.matrix)
+	}
+}
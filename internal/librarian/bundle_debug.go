@@ -0,0 +1,391 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// debugBundlePrefix is the prefix of the names of debug bundles, and of
+	// the directory of their files.
+	debugBundlePrefix = "librarian-debug-"
+
+	debugBundleManifestFile = "manifest.json"
+
+	// maxDebugBundleFileSize is the size above which the files of a work
+	// root are left out of debug bundles.
+	maxDebugBundleFileSize = 10 << 20
+)
+
+var cmdBundleDebug = &cli.Command{
+	Short:     "bundle-debug packages a librarian run for bug reports",
+	UsageLine: "librarian bundle-debug [flags] [work-root]",
+	Long: `Packages what is needed to reproduce a librarian run into a single archive,
+which can be attached to bug reports.
+
+The run is identified by its working directory, specified as the argument or,
+by default, the most recent working directory which librarian created in the
+temporary directory. The archive is written into the working directory of
+this command as "librarian-debug-{timestamp}.tar.gz", or ".zip" with
+"-archive-format=zip", and its path is printed.
+
+The files of the archive are in a single "librarian-debug-{timestamp}"
+directory:
+- "work-root/", the files of the working directory of the run, such as the
+  generation report, the container output and the container logs. Git
+  repositories, the snapshot of the API source, binary files and files larger
+  than 10 MiB are left out.
+- "repos/{name}/effective-config.yaml", the effective ".librarian/config.yaml"
+  of each language repository of the run, as printed by
+  "print-effective-config".
+- "repos/{name}/state-before.yaml" and "repos/{name}/state-after.yaml", the
+  ".librarian/state.yaml" of each language repository at HEAD and in the
+  working tree. The language repositories are those cloned into the working
+  directory of the run, and the local repository specified with "-repo".
+- "container-record/", the invocation records of the container runs, if the
+  run was recorded with "-container-record" and the same flag is specified.
+- "manifest.json", which records the version of librarian, the platform and
+  the files which were left out.
+
+Secrets, such as the GitHub token and the tokens found by their form, are
+redacted from every file. Review the archive before sharing it nonetheless.`,
+}
+
+func init() {
+	cmdBundleDebug.Init()
+	// The work root of the run is the argument after the flags, which is only
+	// known once they are parsed.
+	cmdBundleDebug.Run = func(ctx context.Context, cfg *config.Config) error {
+		var runRoot string
+		switch args := cmdBundleDebug.Flags.Args(); len(args) {
+		case 0:
+			latest, err := latestWorkRoot(os.TempDir(), cfg.WorkRoot)
+			if err != nil {
+				return err
+			}
+			runRoot = latest
+		case 1:
+			runRoot = args[0]
+		default:
+			return failure.New(failure.UserConfig, fmt.Errorf("expected at most one working directory, got %q", args))
+		}
+		archive, err := bundleDebug(cfg, runRoot)
+		if err != nil {
+			return err
+		}
+		fmt.Println(archive)
+		return nil
+	}
+	fs := cmdBundleDebug.Flags
+	cfg := cmdBundleDebug.Config
+
+	addFlagArchiveFormat(fs, cfg)
+	addFlagContainerRecord(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+// debugBundleManifest describes a debug bundle.
+type debugBundleManifest struct {
+	// LibrarianVersion is the version of librarian which wrote the bundle.
+	LibrarianVersion string `json:"librarian_version"`
+	// Platform is the operating system and architecture, e.g. linux/amd64.
+	Platform string `json:"platform"`
+	// WorkRoot is the working directory of the bundled run.
+	WorkRoot string `json:"work_root"`
+	// Repos are the directories of the bundled language repositories.
+	Repos []string `json:"repos,omitempty"`
+	// Omitted are the files left out of the bundle, with the reason.
+	Omitted []*omittedFile `json:"omitted,omitempty"`
+	// Created is when the bundle was written.
+	Created time.Time `json:"created"`
+}
+
+// omittedFile is a file left out of a debug bundle.
+type omittedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// latestWorkRoot returns the most recent work root created by librarian in
+// dir, other than current, the work root of the running command.
+func latestWorkRoot(dir, current string) (string, error) {
+	roots, err := findWorkRoots(dir)
+	if err != nil {
+		return "", err
+	}
+	roots = slices.DeleteFunc(roots, func(root *workRootInfo) bool { return root.path == current })
+	if len(roots) == 0 {
+		return "", failure.New(failure.UserConfig, fmt.Errorf("no librarian working directory found in %s, specify the working directory of the run", dir))
+	}
+	latest := slices.MaxFunc(roots, func(a, b *workRootInfo) int { return a.created.Compare(b.created) })
+	return latest.path, nil
+}
+
+// bundleDebug writes the debug bundle of the run whose work root is runRoot
+// into cfg.WorkRoot, and returns its path.
+func bundleDebug(cfg *config.Config, runRoot string) (string, error) {
+	info, err := os.Stat(runRoot)
+	if err != nil {
+		return "", failure.New(failure.UserConfig, fmt.Errorf("invalid working directory of the run: %w", err))
+	}
+	if !info.IsDir() {
+		return "", failure.New(failure.UserConfig, fmt.Errorf("working directory of the run %s is not a directory", runRoot))
+	}
+	created := now().UTC()
+	name := debugBundlePrefix + created.Format("20060102T150405Z")
+	manifest := &debugBundleManifest{
+		LibrarianVersion: cli.Version(),
+		Platform:         runtime.GOOS + "/" + runtime.GOARCH,
+		WorkRoot:         runRoot,
+		Created:          created,
+	}
+	entries, err := workRootEntries(runRoot, path.Join(name, "work-root"), manifest)
+	if err != nil {
+		return "", err
+	}
+	repos, err := debugBundleRepos(runRoot, cfg.Repo)
+	if err != nil {
+		return "", err
+	}
+	for _, repoDir := range repos {
+		repoEntries, err := repoDebugEntries(repoDir, path.Join(name, "repos", filepath.Base(repoDir)))
+		if err != nil {
+			return "", fmt.Errorf("failed to bundle repository %s: %w", repoDir, err)
+		}
+		entries = append(entries, repoEntries...)
+		manifest.Repos = append(manifest.Repos, repoDir)
+	}
+	if cfg.ContainerRecord != "" {
+		recordEntries, err := containerRecordEntries(cfg.ContainerRecord, path.Join(name, "container-record"))
+		if err != nil {
+			return "", err
+		}
+		entries = append(entries, recordEntries...)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	entries = append(entries, redactedEntry(path.Join(name, debugBundleManifestFile), append(data, '\n')))
+	slices.SortFunc(entries, func(a, b *archiveEntry) int { return strings.Compare(a.name, b.name) })
+
+	format := cmp.Or(cfg.ArchiveFormat, config.ArchiveFormatTarGz)
+	archive := filepath.Join(cfg.WorkRoot, name+"."+format)
+	f, err := os.Create(archive)
+	if err != nil {
+		return "", err
+	}
+	if format == config.ArchiveFormatZip {
+		err = writeZip(f, entries, created)
+	} else {
+		err = writeTarGz(f, entries, created)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", archive, err)
+	}
+	slog.Info("Wrote debug bundle", "path", archive, "files", len(entries))
+	return archive, nil
+}
+
+// redactedEntry returns an archive entry of data with secrets redacted.
+func redactedEntry(name string, data []byte) *archiveEntry {
+	return &archiveEntry{name: name, data: []byte(logging.Redact(string(data))), mode: 0644}
+}
+
+// workRootEntries returns the archive entries, under the directory name, of
+// the files in runRoot, with secrets redacted. Git repositories, the snapshot
+// of the API source and earlier debug bundles are skipped, and binary and
+// large files are recorded as omitted in manifest.
+func workRootEntries(runRoot, name string, manifest *debugBundleManifest) ([]*archiveEntry, error) {
+	var entries []*archiveEntry
+	err := filepath.WalkDir(runRoot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(runRoot, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel == apiSourceSnapshotDir || isGitRepo(p) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), debugBundlePrefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if info.Size() > maxDebugBundleFileSize {
+			manifest.Omitted = append(manifest.Omitted, &omittedFile{Path: rel, Reason: "too large"})
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+			manifest.Omitted = append(manifest.Omitted, &omittedFile{Path: rel, Reason: "binary"})
+			return nil
+		}
+		entries = append(entries, redactedEntry(path.Join(name, rel), data))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read working directory %s: %w", runRoot, err)
+	}
+	return entries, nil
+}
+
+// isGitRepo reports whether dir is the root of a git repository.
+func isGitRepo(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// debugBundleRepos returns the directories of the language repositories of
+// the run: the repositories at the root of runRoot, which the run cloned, and
+// repo if it is a local directory. Only repositories with a librarian state
+// are returned.
+func debugBundleRepos(runRoot, repo string) ([]string, error) {
+	var candidates []string
+	dirEntries, err := os.ReadDir(runRoot)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range dirEntries {
+		if entry.IsDir() {
+			candidates = append(candidates, filepath.Join(runRoot, entry.Name()))
+		}
+	}
+	if repo != "" && !isRemote(repo) {
+		abs, err := filepath.Abs(repo)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, abs)
+	}
+	var repos []string
+	for _, dir := range candidates {
+		if slices.Contains(repos, dir) || !isGitRepo(dir) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, config.LibrarianDir, librarianStateFile)); err != nil {
+			continue
+		}
+		repos = append(repos, dir)
+	}
+	return repos, nil
+}
+
+// repoDebugEntries returns the archive entries, under the directory name, of
+// the effective config of the language repository in repoDir, and of its
+// state at HEAD and in the working tree, with secrets redacted.
+func repoDebugEntries(repoDir, name string) ([]*archiveEntry, error) {
+	var entries []*archiveEntry
+	lc, err := parseLibrarianConfig(filepath.Join(repoDir, config.LibrarianDir, librarianConfigFile))
+	if err != nil {
+		return nil, err
+	}
+	if lc != nil {
+		data, err := yaml.Marshal(lc)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, redactedEntry(path.Join(name, "effective-config.yaml"), data))
+	}
+	repo, err := gitrepo.NewRepository(&gitrepo.RepositoryOptions{Dir: repoDir})
+	if err != nil {
+		return nil, err
+	}
+	statePath := path.Join(config.LibrarianDir, librarianStateFile)
+	before, err := repo.ReadFileAtHead(statePath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read state at HEAD: %w", err)
+	default:
+		entries = append(entries, redactedEntry(path.Join(name, "state-before.yaml"), before))
+	}
+	after, err := os.ReadFile(filepath.Join(repoDir, filepath.FromSlash(statePath)))
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, redactedEntry(path.Join(name, "state-after.yaml"), after)), nil
+}
+
+// containerRecordEntries returns the archive entries, under the directory
+// name, of the invocation records of the container runs recorded in dir with
+// -container-record, with secrets redacted. The files which the containers
+// changed are left out.
+func containerRecordEntries(dir, name string) ([]*archiveEntry, error) {
+	var entries []*archiveEntry
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != "invocation.json" {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, redactedEntry(path.Join(name, filepath.ToSlash(rel)), data))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read container records %s: %w", dir, err)
+	}
+	return entries, nil
+}
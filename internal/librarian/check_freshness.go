@@ -0,0 +1,231 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+const checkFreshnessCmdName = "check-freshness"
+
+// staleLabelPrefix is the prefix of the label identifying the issue filed for
+// a stale library. The rest of the label is the ID of the library.
+const staleLabelPrefix = "librarian-stale:"
+
+// defaultStaleAfter is the default -stale-after.
+const defaultStaleAfter = 30 * 24 * time.Hour
+
+var cmdCheckFreshness = &cli.Command{
+	Short:     "check-freshness reports the libraries which have not been regenerated recently",
+	UsageLine: "librarian check-freshness [flags]",
+	Long: `Compares the date of the API source commit which each library in
+".librarian/state.yaml" was last generated from, its "last_generated_commit",
+with "-stale-after", 30 days by default, and reports the libraries generated
+from older commits, or never generated, as stale.
+
+The stale libraries are logged as warnings and printed one per line. With
+"-output-format=json", the freshness of every library is printed as a JSON
+array instead, with the ID, last generated commit and its date, age in days,
+and whether the library is stale.
+
+With "-file-issues", a GitHub issue is filed in the language repository for
+each stale library, labeled "librarian-stale:<id>" and mentioning the owners
+of the library, unless an open issue with the label exists.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newCheckFreshnessRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return runner.run(ctx, os.Stdout)
+	},
+}
+
+func init() {
+	cmdCheckFreshness.Init()
+	fs := cmdCheckFreshness.Flags
+	cfg := cmdCheckFreshness.Config
+
+	addFlagAPISource(fs, cfg)
+	addFlagAPISourceSSHKey(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagFileIssues(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagOutputFormat(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagStaleAfter(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+type checkFreshnessRunner struct {
+	cfg        *config.Config
+	state      *config.LibrarianState
+	sourceRepo gitrepo.Repository
+	ghClient   GitHubClient
+}
+
+func newCheckFreshnessRunner(cfg *config.Config) (*checkFreshnessRunner, error) {
+	runner, err := newCommandRunner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sourceRepo, err := cloneOrOpenRepo(cfg.WorkRoot, cfg.APISource, cfg.CI, cfg.GitHubToken, apiSourceSSHOptions(cfg), gitTuning(cfg))
+	if err != nil {
+		return nil, err
+	}
+	return &checkFreshnessRunner{
+		cfg:        runner.cfg,
+		state:      runner.state,
+		sourceRepo: sourceRepo,
+		ghClient:   runner.ghClient,
+	}, nil
+}
+
+// libraryFreshness is how recently a library was generated.
+type libraryFreshness struct {
+	ID                  string `json:"id"`
+	LastGeneratedCommit string `json:"last_generated_commit,omitempty"`
+	// CommitTime is the committer time of LastGeneratedCommit.
+	CommitTime time.Time `json:"commit_time,omitzero"`
+	// AgeDays is the number of whole days since CommitTime, or zero if the
+	// library was never generated.
+	AgeDays int  `json:"age_days"`
+	Stale   bool `json:"stale"`
+	owners  []string
+}
+
+// String returns a description of the freshness of the library.
+func (f *libraryFreshness) String() string {
+	if f.LastGeneratedCommit == "" {
+		return fmt.Sprintf("%s: never generated", f.ID)
+	}
+	return fmt.Sprintf("%s: last generated from %s of %s, %d days ago",
+		f.ID, shortSHA(f.LastGeneratedCommit), f.CommitTime.UTC().Format(time.DateOnly), f.AgeDays)
+}
+
+func (r *checkFreshnessRunner) run(ctx context.Context, w io.Writer) error {
+	if err := checkGitHubPermissions(ctx, r.cfg, r.ghClient); err != nil {
+		return err
+	}
+	staleAfter := r.cfg.StaleAfter
+	if staleAfter == 0 {
+		staleAfter = defaultStaleAfter
+	}
+	freshness, err := checkFreshness(r.state, r.sourceRepo, staleAfter)
+	if err != nil {
+		return err
+	}
+	var stale []*libraryFreshness
+	for _, f := range freshness {
+		if f.Stale {
+			slog.Warn("Library is stale", "library", f.ID, "last_generated_commit", f.LastGeneratedCommit, "age_days", f.AgeDays)
+			stale = append(stale, f)
+		}
+	}
+	if r.cfg.OutputFormat == config.OutputFormatJSON {
+		data, err := json.MarshalIndent(freshness, "", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s\n", data); err != nil {
+			return err
+		}
+	} else {
+		for _, f := range stale {
+			if _, err := fmt.Fprintln(w, f); err != nil {
+				return err
+			}
+		}
+	}
+	if r.cfg.FileIssues {
+		for _, f := range stale {
+			if err := fileStaleIssue(ctx, r.ghClient, f, staleAfter); err != nil {
+				return err
+			}
+		}
+	}
+	slog.Info("Checked freshness of libraries", "libraries", len(freshness), "stale", len(stale))
+	return nil
+}
+
+// checkFreshness returns the freshness of the libraries in state, whose last
+// generated commits are in sourceRepo. A library is stale if its last
+// generated commit is older than staleAfter, or it was never generated.
+func checkFreshness(state *config.LibrarianState, sourceRepo gitrepo.Repository, staleAfter time.Duration) ([]*libraryFreshness, error) {
+	var freshness []*libraryFreshness
+	for _, library := range state.Libraries {
+		f := &libraryFreshness{
+			ID:                  library.ID,
+			LastGeneratedCommit: library.LastGeneratedCommit,
+			Stale:               true,
+			owners:              library.Owners,
+		}
+		if library.LastGeneratedCommit != "" {
+			commitTime, err := sourceRepo.CommitTime(library.LastGeneratedCommit)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find the last generated commit of library %s in the API source: %w", library.ID, err)
+			}
+			age := now().Sub(commitTime)
+			f.CommitTime = commitTime
+			f.AgeDays = int(age / (24 * time.Hour))
+			f.Stale = age > staleAfter
+		}
+		freshness = append(freshness, f)
+	}
+	return freshness, nil
+}
+
+// fileStaleIssue files a GitHub issue for the stale library f, unless an
+// open issue for it exists.
+func fileStaleIssue(ctx context.Context, ghClient GitHubClient, f *libraryFreshness, staleAfter time.Duration) error {
+	label := staleLabelPrefix + f.ID
+	issue, err := ghClient.FindOpenIssueWithLabel(ctx, label)
+	if err != nil {
+		return fmt.Errorf("failed to search for existing stale library issue: %w", err)
+	}
+	if issue != nil {
+		slog.Info("Stale library already reported", "library", f.ID, "issue", issue.GetNumber())
+		return nil
+	}
+	title := fmt.Sprintf("%s has not been regenerated in %d days", f.ID, int(staleAfter/(24*time.Hour)))
+	var body strings.Builder
+	fmt.Fprintf(&body, "Library `%s` is stale: %s.\n\n", f.ID, strings.TrimPrefix(f.String(), f.ID+": "))
+	fmt.Fprintf(&body, "Regenerate it with `librarian generate -library=%s`, or fix what prevents its regeneration.\n", f.ID)
+	if len(f.owners) > 0 {
+		fmt.Fprintf(&body, "\nOwners: %s\n", strings.Join(f.owners, " "))
+	}
+	if _, err := ghClient.CreateIssue(ctx, title, body.String(), []string{label}); err != nil {
+		return fmt.Errorf("failed to create stale library issue: %w", err)
+	}
+	slog.Info("Filed stale library issue", "library", f.ID)
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	gh "github.com/google/go-github/v69/github"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/github"
+)
+
+const (
+	freshCommit = "1111111111111111111111111111111111111111"
+	staleCommit = "2222222222222222222222222222222222222222"
+)
+
+func setupFreshness(t *testing.T) (*config.LibrarianState, *MockRepository) {
+	t.Helper()
+	fixed := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	original := now
+	now = func() time.Time { return fixed }
+	t.Cleanup(func() { now = original })
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "fresh", LastGeneratedCommit: freshCommit},
+			{ID: "stale", LastGeneratedCommit: staleCommit, Owners: []string{"@octocat"}},
+			{ID: "new"},
+		},
+	}
+	sourceRepo := &MockRepository{
+		CommitTimeValueByHash: map[string]time.Time{
+			freshCommit: fixed.Add(-3 * 24 * time.Hour),
+			staleCommit: fixed.Add(-45 * 24 * time.Hour),
+		},
+	}
+	return state, sourceRepo
+}
+
+func TestCheckFreshness(t *testing.T) {
+	state, sourceRepo := setupFreshness(t)
+	freshness, err := checkFreshness(state, sourceRepo, defaultStaleAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range freshness {
+		got = append(got, f.String())
+	}
+	want := []string{
+		"fresh: last generated from 1111111 of 2025-06-27, 3 days ago",
+		"stale: last generated from 2222222 of 2025-05-16, 45 days ago",
+		"new: never generated",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("freshness mismatch (-want +got):\n%s", diff)
+	}
+	var stale []string
+	for _, f := range freshness {
+		if f.Stale {
+			stale = append(stale, f.ID)
+		}
+	}
+	if diff := cmp.Diff([]string{"stale", "new"}, stale); diff != "" {
+		t.Errorf("stale libraries mismatch (-want +got):\n%s", diff)
+	}
+
+	state.Libraries[0].LastGeneratedCommit = "3333333333333333333333333333333333333333"
+	if _, err := checkFreshness(state, sourceRepo, defaultStaleAfter); err == nil {
+		t.Error("checkFreshness() with a commit missing from the API source succeeded")
+	}
+}
+
+func TestCheckFreshnessRunner(t *testing.T) {
+	for _, test := range []struct {
+		name           string
+		outputFormat   string
+		fileIssues     bool
+		openIssue      *github.Issue
+		wantOutput     string
+		wantIssueCalls int
+	}{
+		{
+			name:       "text",
+			wantOutput: "stale: last generated from 2222222 of 2025-05-16, 45 days ago\nnew: never generated\n",
+		},
+		{
+			name:           "file issues",
+			fileIssues:     true,
+			wantOutput:     "stale: last generated from 2222222 of 2025-05-16, 45 days ago\nnew: never generated\n",
+			wantIssueCalls: 2,
+		},
+		{
+			name:       "issues already filed",
+			fileIssues: true,
+			openIssue:  &github.Issue{Number: gh.Ptr(7)},
+			wantOutput: "stale: last generated from 2222222 of 2025-05-16, 45 days ago\nnew: never generated\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			state, sourceRepo := setupFreshness(t)
+			ghClient := &mockGitHubClient{openIssue: test.openIssue}
+			r := &checkFreshnessRunner{
+				cfg: &config.Config{
+					CommandName:  checkFreshnessCmdName,
+					FileIssues:   test.fileIssues,
+					OutputFormat: test.outputFormat,
+					StaleAfter:   defaultStaleAfter,
+				},
+				state:      state,
+				sourceRepo: sourceRepo,
+				ghClient:   ghClient,
+			}
+			var out bytes.Buffer
+			if err := r.run(context.Background(), &out); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.wantOutput, out.String()); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+			if ghClient.createIssueCalls != test.wantIssueCalls {
+				t.Errorf("createIssueCalls = %d, want %d", ghClient.createIssueCalls, test.wantIssueCalls)
+			}
+			if test.wantIssueCalls == 0 {
+				return
+			}
+			// The last filed issue is of the never generated library.
+			if diff := cmp.Diff([]string{staleLabelPrefix + "new"}, ghClient.createdIssueLabels); diff != "" {
+				t.Errorf("issue labels mismatch (-want +got):\n%s", diff)
+			}
+			if !strings.Contains(ghClient.createdIssueBody, "librarian generate -library=new") {
+				t.Errorf("issue body %q does not explain how to regenerate the library", ghClient.createdIssueBody)
+			}
+		})
+	}
+}
+
+func TestCheckFreshnessRunner_JSON(t *testing.T) {
+	state, sourceRepo := setupFreshness(t)
+	r := &checkFreshnessRunner{
+		cfg:        &config.Config{OutputFormat: config.OutputFormatJSON, StaleAfter: defaultStaleAfter},
+		state:      state,
+		sourceRepo: sourceRepo,
+	}
+	var out bytes.Buffer
+	if err := r.run(context.Background(), &out); err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	want := []map[string]any{
+		{"id": "fresh", "last_generated_commit": freshCommit, "commit_time": "2025-06-27T12:00:00Z", "age_days": 3.0, "stale": false},
+		{"id": "stale", "last_generated_commit": staleCommit, "commit_time": "2025-05-16T12:00:00Z", "age_days": 45.0, "stale": true},
+		{"id": "new", "age_days": 0.0, "stale": true},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("JSON output mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+var cmdClean = &cli.Command{
+	Short:     "clean removes stale librarian working directories",
+	UsageLine: "librarian clean [flags]",
+	Long: `Removes the working directories which librarian created in the temporary
+directory, such as /tmp/librarian-20250102T030405Z.
+
+Only working directories older than "-older-than" are removed. The
+"-keep-last" most recent working directories are kept regardless of their
+age. The size of each working directory is reported, together with the total
+size reclaimed. With "-dry-run", nothing is removed.
+
+Working directories specified with the "-output" flag of other commands are
+never removed, unless they follow the naming scheme above. To remove the
+working directory at the end of each successful run instead, specify
+"-clean-work-root" on the "generate" and "release init" commands.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		return cleanWorkRoots(os.Stdout, os.TempDir(), cfg.OlderThan, cfg.KeepLast, cfg.DryRun, time.Now())
+	},
+}
+
+func init() {
+	cmdClean.Init()
+	fs := cmdClean.Flags
+	cfg := cmdClean.Config
+
+	addFlagDryRun(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagKeepLast(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagOlderThan(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+}
+
+// workRootInfo describes a working directory created by librarian.
+type workRootInfo struct {
+	path    string
+	created time.Time
+	// size is the total size of the files of the directory, or -1 if it is
+	// unknown.
+	size int64
+}
+
+// workRootSize returns the size of a working directory. It is a variable so
+// that tests can replace it.
+var workRootSize = dirSize
+
+// cleanWorkRoots removes the working directories in dir which were created
+// more than olderThan before now, except for the keepLast most recent ones.
+// Each removed directory and the total size are reported to w.
+func cleanWorkRoots(w io.Writer, dir string, olderThan time.Duration, keepLast int, dryRun bool, now time.Time) error {
+	roots, err := findWorkRoots(dir)
+	if err != nil {
+		return err
+	}
+	// Most recent first, so that the ones to keep come first.
+	slices.SortFunc(roots, func(a, b *workRootInfo) int {
+		return b.created.Compare(a.created)
+	})
+	if keepLast > len(roots) {
+		keepLast = len(roots)
+	}
+	action := "Removed"
+	if dryRun {
+		action = "Would remove"
+	}
+	var removed int
+	var total int64
+	var unknown bool
+	cutoff := now.Add(-olderThan)
+	for _, root := range roots[keepLast:] {
+		if !root.created.Before(cutoff) {
+			continue
+		}
+		if !dryRun {
+			slog.Info("Removing working directory", "dir", root.path)
+			if err := os.RemoveAll(root.path); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", root.path, err)
+			}
+		}
+		removed++
+		if root.size < 0 {
+			fmt.Fprintf(w, "%s %s (size unknown)\n", action, root.path)
+			unknown = true
+			continue
+		}
+		fmt.Fprintf(w, "%s %s (%s)\n", action, root.path, formatSize(root.size))
+		total += root.size
+	}
+	totalSize := formatSize(total)
+	if unknown {
+		totalSize = "at least " + totalSize
+	}
+	fmt.Fprintf(w, "%s %d of %d working directories (%s)\n", action, removed, len(roots), totalSize)
+	return nil
+}
+
+// findWorkRoots returns the working directories created by librarian in dir.
+// A directory whose size cannot be computed, e.g. because a container left a
+// file which the current user cannot read, is returned with an unknown size,
+// as it is all the more in need of cleaning.
+func findWorkRoots(dir string) ([]*workRootInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var roots []*workRootInfo
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		created, ok := config.ParseWorkRootName(entry.Name())
+		if !ok {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		size, err := workRootSize(path)
+		if err != nil {
+			slog.Warn("failed to compute size of working directory", "dir", path, "err", err)
+			size = -1
+		}
+		roots = append(roots, &workRootInfo{path: path, created: created, size: size})
+	}
+	return roots, nil
+}
+
+// dirSize returns the total size of the regular files in dir.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+// formatSize formats a number of bytes using binary units, e.g. "1.5 MiB".
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
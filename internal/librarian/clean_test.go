@@ -0,0 +1,186 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCleanWorkRoots(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		name       string
+		olderThan  time.Duration
+		keepLast   int
+		dryRun     bool
+		wantRemain []string
+		wantOutput []string
+	}{
+		{
+			name:      "older than",
+			olderThan: 48 * time.Hour,
+			wantRemain: []string{
+				"librarian-20250609T120000Z",
+				"librarian-latest",
+				"other-20250101T000000Z",
+			},
+			wantOutput: []string{
+				"Removed ",
+				"librarian-20250601T120000Z (2.0 KiB)",
+				"librarian-20250501T120000Z (10 B)",
+				"Removed 2 of 3 working directories (2.0 KiB)",
+			},
+		},
+		{
+			name:      "keep last",
+			olderThan: time.Hour,
+			keepLast:  2,
+			wantRemain: []string{
+				"librarian-20250601T120000Z",
+				"librarian-20250609T120000Z",
+				"librarian-latest",
+				"other-20250101T000000Z",
+			},
+			wantOutput: []string{
+				"Removed 1 of 3 working directories (10 B)",
+			},
+		},
+		{
+			name:      "dry run",
+			olderThan: time.Hour,
+			dryRun:    true,
+			wantRemain: []string{
+				"librarian-20250501T120000Z",
+				"librarian-20250601T120000Z",
+				"librarian-20250609T120000Z",
+				"librarian-latest",
+				"other-20250101T000000Z",
+			},
+			wantOutput: []string{
+				"Would remove 3 of 3 working directories (2.0 KiB)",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, size := range map[string]int{
+				"librarian-20250501T120000Z": 10,
+				"librarian-20250601T120000Z": 2048,
+				"librarian-20250609T120000Z": 1,
+				"librarian-latest":           1,
+				"other-20250101T000000Z":     1,
+			} {
+				path := filepath.Join(dir, name, "nested", "file")
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, bytes.Repeat([]byte("x"), size), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var out bytes.Buffer
+			if err := cleanWorkRoots(&out, dir, test.olderThan, test.keepLast, test.dryRun, now); err != nil {
+				t.Fatalf("cleanWorkRoots() error = %v", err)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.Name())
+			}
+			slices.Sort(got)
+			if diff := cmp.Diff(test.wantRemain, got); diff != "" {
+				t.Errorf("remaining directories mismatch (-want +got):\n%s", diff)
+			}
+			for _, want := range test.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("cleanWorkRoots() output = %q, want it to contain %q", out.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestCleanWorkRoots_SizeUnknown(t *testing.T) {
+	saved := workRootSize
+	t.Cleanup(func() { workRootSize = saved })
+	workRootSize = func(dir string) (int64, error) {
+		if filepath.Base(dir) == "librarian-20250501T120000Z" {
+			return 0, errors.New("permission denied")
+		}
+		return dirSize(dir)
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"librarian-20250501T120000Z", "librarian-20250601T120000Z"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, "file"), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	if err := cleanWorkRoots(&out, dir, time.Hour, 0, false, now); err != nil {
+		t.Fatalf("cleanWorkRoots() error = %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("%d working directories remain, want 0", len(entries))
+	}
+	for _, want := range []string{
+		"librarian-20250501T120000Z (size unknown)",
+		"librarian-20250601T120000Z (1 B)",
+		"Removed 2 of 2 working directories (at least 1 B)",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("cleanWorkRoots() output = %q, want it to contain %q", out.String(), want)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for _, test := range []struct {
+		size int64
+		want string
+	}{
+		{size: 0, want: "0 B"},
+		{size: 1023, want: "1023 B"},
+		{size: 1536, want: "1.5 KiB"},
+		{size: 5 * 1024 * 1024, want: "5.0 MiB"},
+		{size: 3 << 30, want: "3.0 GiB"},
+	} {
+		if got := formatSize(test.size); got != test.want {
+			t.Errorf("formatSize(%d) = %q, want %q", test.size, got, test.want)
+		}
+	}
+}
@@ -33,105 +33,212 @@ import (
 
 	"github.com/googleapis/librarian/internal/docker"
 
+	"github.com/googleapis/librarian/internal/cli"
 	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
 	"github.com/googleapis/librarian/internal/github"
 	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/metrics"
 )
 
 type commandRunner struct {
 	cfg             *config.Config
 	repo            gitrepo.Repository
 	sourceRepo      gitrepo.Repository
+	apiSource       *apiSourceProvenance
 	state           *config.LibrarianState
 	librarianConfig *config.LibrarianConfig
+	releaseNotes    *releaseNotesFormat
 	ghClient        GitHubClient
+	gerrit          *gerritReview
 	containerClient ContainerClient
 	workRoot        string
 	image           string
 }
 
+// defaultAPISource is the API definition repository used when -api-source is
+// not specified.
+const defaultAPISource = "https://github.com/googleapis/googleapis"
+
 func newCommandRunner(cfg *config.Config) (*commandRunner, error) {
-	if cfg.APISource == "" {
-		cfg.APISource = "https://github.com/googleapis/googleapis"
+	if cfg.APISource == "" && !cfg.Offline {
+		cfg.APISource = defaultAPISource
+	}
+	if cfg.Offline {
+		if err := checkOfflineInputs(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	languageRepo, err := cloneOrOpenRepo(cfg.WorkRoot, cfg.Repo, cfg.CI, cfg.GitHubToken, repoSSHOptions(cfg), gitTuning(cfg))
+	if err != nil {
+		return nil, err
 	}
 
-	languageRepo, err := cloneOrOpenRepo(cfg.WorkRoot, cfg.Repo, cfg.CI, cfg.GitHubToken)
+	librarianConfig, err := loadLibrarianConfig(languageRepo)
 	if err != nil {
 		return nil, err
 	}
+	if librarianConfig != nil {
+		if err := checkLibrarianVersion(librarianConfig.LibrarianVersion, cli.Version()); err != nil {
+			return nil, err
+		}
+	}
 
 	var sourceRepo gitrepo.Repository
 	var sourceRepoDir string
-	if cfg.CommandName == generateCmdName {
-		sourceRepo, err = cloneOrOpenRepo(cfg.WorkRoot, cfg.APISource, cfg.CI, cfg.GitHubToken)
+	var apiSource *apiSourceProvenance
+	if cfg.CommandName == generateCmdName || cfg.CommandName == syncAPIsCmdName {
+		var localSourceRepo *gitrepo.LocalRepository
+		localSourceRepo, apiSource, err = openAPISource(cfg, apiSourceSparsePaths(cfg, languageRepo, librarianConfig))
 		if err != nil {
 			return nil, err
 		}
+		sourceRepo = localSourceRepo
 		sourceRepoDir = sourceRepo.GetDir()
+		if apiSource != nil {
+			sourceRepoDir = apiSource.Snapshot
+		}
 	}
-	state, err := loadRepoState(languageRepo, sourceRepoDir)
+	state, err := loadRepoState(languageRepo, sourceRepoDir, cfg.Library)
 	if err != nil {
 		return nil, err
 	}
-
-	librarianConfig, err := loadLibrarianConfig(languageRepo)
-	if err != nil {
-		return nil, err
+	var releaseNotes *releaseNotesFormat
+	if languageRepo != nil {
+		if releaseNotes, err = loadReleaseNotesFormat(languageRepo.Dir); err != nil {
+			return nil, err
+		}
 	}
 
-	image := deriveImage(cfg.Image, state)
+	image := deriveImage(cfg.Image, state, nil)
 
-	var gitRepo *github.Repository
-	if isURL(cfg.Repo) {
-		gitRepo, err = github.ParseURL(cfg.Repo)
+	gerrit := newGerritReview(cfg, librarianConfig, languageRepo)
+	var ghClient GitHubClient
+	gitRepo, err := gitHubRepository(cfg, languageRepo)
+	switch {
+	case cfg.Offline:
+		// GitHub is never contacted offline, and a language repository
+		// cloned from a bundle has no GitHub remote.
+		slog.Info("Running offline, without a GitHub client")
+	case err == nil:
+		client, err := newGitHubClient(cfg, gitRepo)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse repo url: %w", err)
+			return nil, fmt.Errorf("failed to create GitHub client: %w", err)
 		}
-	} else {
-		gitRepo, err = github.FetchGitHubRepoFromRemote(languageRepo)
+		ghClient = client
+	case gerrit != nil:
+		// A repository reviewed on Gerrit may not be on GitHub at all. The
+		// commands which need GitHub fail in checkGitHubPermissions.
+		slog.Info("Language repository is not on GitHub, reviews are sent to Gerrit", "err", err)
+	default:
+		return nil, err
+	}
+
+	mounts, err := config.ParseContainerMounts(cfg.ContainerMounts)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.GeneratorSource != "" {
+		mount, err := generatorSourceMount(cfg.GeneratorSource)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get GitHub repo from remote: %w", err)
+			return nil, err
 		}
+		mounts = append(mounts, mount)
 	}
-	ghClient, err := github.NewClient(cfg.GitHubToken, gitRepo)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create GitHub client: %w", err)
+	if cfg.ServiceConfig != "" {
+		mount, err := serviceConfigMount(cfg.ServiceConfig)
+		if err != nil {
+			return nil, err
+		}
+		mounts = append(mounts, mount)
 	}
-
-	container, err := docker.New(cfg.WorkRoot, image, cfg.UserUID, cfg.UserGID)
+	container, err := docker.New(cfg.WorkRoot, docker.MirrorImage(image, cfg.RegistryMirror), cfg.UserUID, cfg.UserGID, mounts)
 	if err != nil {
 		return nil, err
 	}
+	for command, image := range librarianConfig.ContainerImages() {
+		if container.Images == nil {
+			container.Images = make(map[docker.Command]string)
+		}
+		container.Images[docker.Command(command)] = docker.MirrorImage(image, cfg.RegistryMirror)
+	}
+	if state != nil {
+		for _, library := range state.Libraries {
+			libraryImage := deriveImage(cfg.Image, state, library)
+			if libraryImage == image {
+				continue
+			}
+			if container.LibraryImages == nil {
+				container.LibraryImages = make(map[string]string)
+			}
+			container.LibraryImages[library.ID] = docker.MirrorImage(libraryImage, cfg.RegistryMirror)
+		}
+	}
+	container.Local = cfg.ImageLocal || cfg.Offline
+	container.Archive = cfg.ImageArchive
+	container.Platform = cfg.ImagePlatform
+	container.RequireNative = cfg.NativeImage
+	container.RecordDir = cfg.ContainerRecord
+	container.ReplayDir = cfg.ContainerReplay
+	container.DebugShell = cfg.DebugShell
+	if cfg.ContainerBackend == config.ContainerBackendKubernetes {
+		if librarianConfig == nil || librarianConfig.Kubernetes == nil {
+			return nil, failure.New(failure.UserConfig, errors.New("-container-backend=kubernetes requires a kubernetes section in .librarian/config.yaml"))
+		}
+		container.Kubernetes = librarianConfig.Kubernetes
+	}
+	if cfg.ContainerLogLevel != "" {
+		if err := container.LogLevel.UnmarshalText([]byte(cfg.ContainerLogLevel)); err != nil {
+			return nil, err
+		}
+	}
 	return &commandRunner{
 		cfg:             cfg,
 		workRoot:        cfg.WorkRoot,
 		repo:            languageRepo,
 		sourceRepo:      sourceRepo,
+		apiSource:       apiSource,
 		state:           state,
 		librarianConfig: librarianConfig,
+		releaseNotes:    releaseNotes,
 		image:           image,
 		ghClient:        ghClient,
+		gerrit:          gerrit,
 		containerClient: container,
 	}, nil
 }
 
-func cloneOrOpenRepo(workRoot, repo, ci string, gitPassword string) (*gitrepo.LocalRepository, error) {
+// checkOfflineInputs checks that the inputs of an offline run are local
+// files, as nothing can be fetched.
+func checkOfflineInputs(cfg *config.Config) error {
+	if isRemote(cfg.Repo) {
+		return failure.New(failure.UserConfig, fmt.Errorf("-offline requires -repo to be a local directory or git bundle, got %s", cfg.Repo))
+	}
+	if cfg.CommandName != generateCmdName && cfg.CommandName != syncAPIsCmdName {
+		return nil
+	}
+	if cfg.APISource == "" || isRemote(cfg.APISource) {
+		return failure.New(failure.UserConfig, fmt.Errorf("-offline requires -api-source to be a local directory or git bundle, got %q", cfg.APISource))
+	}
+	return nil
+}
+
+func cloneOrOpenRepo(workRoot, repo, ci string, gitPassword string, ssh *gitrepo.SSHOptions, tuning *gitrepo.TuningOptions) (*gitrepo.LocalRepository, error) {
 	if repo == "" {
 		return nil, errors.New("repo must be specified")
 	}
 
-	if isURL(repo) {
+	if isRemote(repo) {
 		// repo is a URL
-		// Take the last part of the URL as the directory name. It feels very
-		// unlikely that will clash with anything else (e.g. "output")
-		repoName := path.Base(strings.TrimSuffix(repo, "/"))
-		repoPath := filepath.Join(workRoot, repoName)
 		return gitrepo.NewRepository(&gitrepo.RepositoryOptions{
-			Dir:         repoPath,
+			Dir:         remoteRepoDir(workRoot, repo),
 			MaybeClone:  true,
 			RemoteURL:   repo,
 			CI:          ci,
 			GitPassword: gitPassword,
+			SSH:         ssh,
+			Tuning:      tuning,
 		})
 	}
 	// repo is a directory
@@ -143,6 +250,7 @@ func cloneOrOpenRepo(workRoot, repo, ci string, gitPassword string) (*gitrepo.Lo
 		Dir:         absRepoRoot,
 		CI:          ci,
 		GitPassword: gitPassword,
+		SSH:         ssh,
 	})
 	if err != nil {
 		return nil, err
@@ -157,14 +265,160 @@ func cloneOrOpenRepo(workRoot, repo, ci string, gitPassword string) (*gitrepo.Lo
 	return githubRepo, nil
 }
 
-func deriveImage(imageOverride string, state *config.LibrarianState) string {
+// remoteRepoDir returns the directory in workRoot which the repository at the
+// URL repo is cloned into.
+func remoteRepoDir(workRoot, repo string) string {
+	// Take the last part of the URL as the directory name. It feels very
+	// unlikely that will clash with anything else (e.g. "output")
+	return filepath.Join(workRoot, path.Base(strings.TrimSuffix(repo, "/")))
+}
+
+// repoSSHOptions returns the options of SSH authentication with the language
+// repository.
+func repoSSHOptions(cfg *config.Config) *gitrepo.SSHOptions {
+	return &gitrepo.SSHOptions{KeyFile: cfg.SSHKey, KnownHostsFile: cfg.SSHKnownHosts}
+}
+
+// gitTuning returns the tuning of the clones of remote repositories, or nil if
+// it is off.
+func gitTuning(cfg *config.Config) *gitrepo.TuningOptions {
+	// -git-tuning is validated with the other flags.
+	tuning, _ := gitrepo.ParseTuningOptions(cfg.GitTuning)
+	return tuning
+}
+
+// apiSourceSSHOptions returns the options of SSH authentication with the API
+// source repository.
+func apiSourceSSHOptions(cfg *config.Config) *gitrepo.SSHOptions {
+	return &gitrepo.SSHOptions{KeyFile: cfg.APISourceSSHKey, KnownHostsFile: cfg.SSHKnownHosts}
+}
+
+// gitHubRepository returns the GitHub repository of the language repository,
+// parsed from cfg.Repo if it is a URL, or from the remote of languageRepo
+// otherwise.
+func gitHubRepository(cfg *config.Config, languageRepo gitrepo.Repository) (*github.Repository, error) {
+	if isRemote(cfg.Repo) {
+		repo, err := github.ParseHostURL(cfg.GitHubHost(), cfg.Repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse repo url: %w", err)
+		}
+		return repo, nil
+	}
+	repo, err := github.FetchGitHubRepoFromHostRemote(languageRepo, cfg.GitHubHost())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitHub repo from remote: %w", err)
+	}
+	return repo, nil
+}
+
+// newGitHubClient creates a GitHub client for repo. When a GitHub Enterprise
+// Server instance is configured, the client interacts with it, and the
+// access token is validated up front, so that a token for the wrong
+// instance fails fast rather than at the end of a run.
+func newGitHubClient(cfg *config.Config, repo *github.Repository) (*github.Client, error) {
+	var endpoints *github.Endpoints
+	if cfg.GitHubAPIURL != "" {
+		endpoints = &github.Endpoints{
+			APIURL:    cfg.GitHubAPIURL,
+			UploadURL: cfg.GitHubUploadURL,
+		}
+	}
+	client, err := github.NewClient(cfg.GitHubToken, repo, endpoints)
+	if err != nil {
+		return nil, err
+	}
+	if endpoints != nil && cfg.GitHubToken != "" {
+		if err := client.ValidateToken(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// requiredGitHubPermissions returns the permissions on the language
+// repository which the GitHub token needs for the command and flags of cfg,
+// none if cfg is nil.
+func requiredGitHubPermissions(cfg *config.Config) []github.Permission {
+	var permissions []github.Permission
+	if cfg == nil {
+		return permissions
+	}
+	if cfg.Push || cfg.CommandName == tagAndReleaseCmdName {
+		permissions = append(permissions, github.PermissionContentsWrite, github.PermissionPullRequestsWrite)
+	}
+	if cfg.CommandName == approveReleaseCmdName {
+		permissions = append(permissions, github.PermissionPullRequestsWrite)
+	}
+	if cfg.ReportFailures || cfg.FileIssues {
+		permissions = append(permissions, github.PermissionIssuesWrite)
+	}
+	return permissions
+}
+
+// errNotOnGitHub returns the error of a command which needs GitHub, while the
+// language repository is only reviewed on Gerrit.
+func errNotOnGitHub(cfg *config.Config) error {
+	return failure.New(failure.UserConfig, fmt.Errorf("%s needs GitHub, but the language repository is not on GitHub", cfg.CommandName))
+}
+
+// checkGitHubPermissions fails early, before a command does any work, if the
+// GitHub token lacks a permission which the command needs, instead of when
+// the command pushes its changes.
+func checkGitHubPermissions(ctx context.Context, cfg *config.Config, ghClient GitHubClient) error {
+	permissions := requiredGitHubPermissions(cfg)
+	if len(permissions) == 0 {
+		return nil
+	}
+	if ghClient == nil {
+		return errNotOnGitHub(cfg)
+	}
+	return ghClient.CheckPermissions(ctx, permissions...)
+}
+
+// deriveImage returns the image of the language container for library, or
+// for the commands of no particular library if library is nil: imageOverride
+// if it is specified, and otherwise the image which library is pinned to in
+// the state, if any, or the image of the state. The image is used as is, so
+// it can follow any registry naming convention.
+func deriveImage(imageOverride string, state *config.LibrarianState, library *config.LibraryState) string {
 	if imageOverride != "" {
 		return imageOverride
 	}
-	if state == nil {
-		return ""
+	return state.LibraryImage(library)
+}
+
+// generatorSourceMount returns the read-only mount of the local generator
+// source directory dir into language containers.
+func generatorSourceMount(dir string) (*config.ContainerMount, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("invalid -generator-source: %w", err))
+	}
+	if !info.IsDir() {
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("invalid -generator-source %q, want a directory", dir))
+	}
+	return &config.ContainerMount{HostDir: dir, ContainerDir: docker.GeneratorSourceDir, ReadOnly: true}, nil
+}
+
+// serviceConfigMount returns the read-only mount of the directory of the
+// service config at path into language containers.
+func serviceConfigMount(path string) (*config.ContainerMount, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("invalid -service-config: %w", err))
+	}
+	if info.IsDir() {
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("invalid -service-config %q, want a file", path))
 	}
-	return state.Image
+	return &config.ContainerMount{HostDir: filepath.Dir(path), ContainerDir: docker.ServiceConfigDir, ReadOnly: true}, nil
 }
 
 func findLibraryIDByAPIPath(state *config.LibrarianState, apiPath string) string {
@@ -300,6 +554,11 @@ func coerceLibraryChanges(commits []*conventionalcommits.ConventionalCommit) []*
 			clNum = cl
 		}
 
+		var bugs []*config.Bug
+		for _, bug := range commit.Bugs {
+			bugs = append(bugs, &config.Bug{ID: bug.String(), URL: bug.URL})
+		}
+
 		changeType := getChangeType(commit)
 		changes = append(changes, &config.Change{
 			Type:       changeType,
@@ -307,67 +566,201 @@ func coerceLibraryChanges(commits []*conventionalcommits.ConventionalCommit) []*
 			Body:       commit.Body,
 			ClNum:      clNum,
 			CommitHash: commit.SHA,
+			Author:     commit.Author,
+			Bugs:       bugs,
 		})
 	}
 
 	return changes
 }
 
+// commitInfo contains the information needed to commit the changes in a
+// language repository and, optionally, create a pull request for them.
+type commitInfo struct {
+	cfg           *config.Config
+	state         *config.LibrarianState
+	repo          gitrepo.Repository
+	ghClient      GitHubClient
+	commitMessage string
+	// libraryIDs are the IDs of the libraries changed by the commit. The owners
+	// of these libraries are requested to review the pull request.
+	libraryIDs []string
+	// kind is the kind of the pull request, config.PullRequestKindGenerate or
+	// config.PullRequestKindRelease, which selects the labels and reviewers
+	// configured in librarianConfig.
+	kind            string
+	librarianConfig *config.LibrarianConfig
+	// branch is the name of the branch to commit to. A name based on the
+	// current time is used if empty.
+	branch string
+	// title is the title of the pull request. A title based on the current
+	// time is used if empty.
+	title string
+	// body is the body of the pull request. The commit message is used if
+	// empty.
+	body string
+	// followUpCommits are committed, in order, on top of the main commit
+	// before pushing.
+	followUpCommits []*followUpCommit
+	// gerrit, if set, sends the commits for review to Gerrit instead of
+	// creating a pull request.
+	gerrit *gerritReview
+	// topic is the Gerrit topic of the changes, unless one is configured.
+	// The branch name is used if empty.
+	topic string
+	// update, if not zero, is the number of the open pull request from branch
+	// to update in place, replacing its body, instead of creating a new one.
+	update int
+}
+
+// followUpCommit is an additional commit of a pull request.
+type followUpCommit struct {
+	message string
+	// apply makes the changes of the commit in the working tree.
+	apply func() error
+}
+
 // commitAndPush creates a commit and push request to GitHub for the generated
 // changes.
 // It uses the GitHub client to create a PR with the specified branch, title, and
 // description to the repository.
-func commitAndPush(ctx context.Context, cfg *config.Config, repo gitrepo.Repository, ghClient GitHubClient, commitMessage string) error {
-	if !cfg.Push && !cfg.Commit {
+func commitAndPush(ctx context.Context, info *commitInfo) error {
+	_, err := commitAndCreatePullRequest(ctx, info)
+	return err
+}
+
+// commitAndCreatePullRequest is like commitAndPush, but also returns the
+// created pull request, or nil if no pull request was created.
+func commitAndCreatePullRequest(ctx context.Context, info *commitInfo) (*github.PullRequestMetadata, error) {
+	cfg := info.cfg
+	repo := info.repo
+	if !cfg.Push && !cfg.Commit && !cfg.Offline {
 		slog.Info("Push flag and Commit flag are not specified, skipping committing")
-		return nil
+		return nil, nil
 	}
+	defer metrics.FromContext(ctx).StartPhase("commit")()
 
 	status, err := repo.AddAll()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if status.IsClean() {
+	if status.IsClean() && len(info.followUpCommits) == 0 {
 		slog.Info("No changes to commit, skipping commit and push.")
-		return nil
+		return nil, nil
 	}
 
+	// base is the commit which the offline output is relative to.
+	var base string
+	if cfg.Offline {
+		if base, err = repo.HeadHash(); err != nil {
+			return nil, err
+		}
+	}
 	datetimeNow := formatTimestamp(time.Now())
-	branch := fmt.Sprintf("librarian-%s", datetimeNow)
+	branch := info.branch
+	if branch == "" {
+		branch = fmt.Sprintf("librarian-%s", datetimeNow)
+	}
 	slog.Info("Creating branch", slog.String("branch", branch))
 	if err := repo.CreateBranchAndCheckout(branch); err != nil {
-		return err
+		return nil, err
 	}
 
-	// TODO: get correct language for message (https://github.com/googleapis/librarian/issues/885)
-	slog.Info("Committing", "message", commitMessage)
-	if err := repo.Commit(commitMessage); err != nil {
-		return err
+	title := info.title
+	if title == "" {
+		titlePrefix := "Librarian pull request"
+		title = fmt.Sprintf("%s: %s", titlePrefix, datetimeNow)
+	}
+	commitMessage := info.commitMessage
+	if info.gerrit != nil {
+		// Gerrit changes are described by their commit message alone.
+		if commitMessage == "" {
+			commitMessage = title
+		}
+		if commitMessage, err = info.gerrit.commitMessage(commitMessage); err != nil {
+			return nil, err
+		}
+	}
+	var messages []string
+	// The main commit is empty if all the changes are in follow-up commits.
+	if !status.IsClean() {
+		messages = append(messages, commitMessage)
+		// TODO: get correct language for message (https://github.com/googleapis/librarian/issues/885)
+		slog.Info("Committing", "message", commitMessage)
+		if err := repo.Commit(commitMessage); err != nil {
+			return nil, err
+		}
+		auditLogFromContext(ctx).recordCommit(repo, commitMessage)
+	}
+	for _, followUp := range info.followUpCommits {
+		if err := followUp.apply(); err != nil {
+			return nil, err
+		}
+		if _, err := repo.AddAll(); err != nil {
+			return nil, err
+		}
+		message := followUp.message
+		if info.gerrit != nil {
+			if message, err = info.gerrit.commitMessage(message); err != nil {
+				return nil, err
+			}
+		}
+		messages = append(messages, message)
+		slog.Info("Committing", "message", message)
+		if err := repo.Commit(message); err != nil {
+			return nil, err
+		}
+		auditLogFromContext(ctx).recordCommit(repo, message)
+	}
+
+	if cfg.Offline {
+		return nil, writeOfflineOutput(ctx, cfg.WorkRoot, repo, base, branch)
+	}
+
+	if info.gerrit != nil {
+		if !cfg.Push {
+			slog.Info("Push flag is not specified, skipping sending changes for review")
+			return nil, nil
+		}
+		return nil, info.gerrit.send(ctx, repo, branch, info.topic, messages)
 	}
 
 	if err := repo.Push(branch); err != nil {
-		return err
+		return nil, err
 	}
+	auditLogFromContext(ctx).record(auditBranchPushed, map[string]string{"branch": branch})
 
 	if !cfg.Push {
 		slog.Info("Push flag is not specified, skipping pull request creation")
-		return nil
+		return nil, nil
 	}
 
 	// Ensure we have a GitHub repository
-	gitHubRepo, err := github.FetchGitHubRepoFromRemote(repo)
+	gitHubRepo, err := github.FetchGitHubRepoFromHostRemote(repo, info.cfg.GitHubHost())
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Create a new branch, set title and message for the PR.
-	titlePrefix := "Librarian pull request"
-	title := fmt.Sprintf("%s: %s", titlePrefix, datetimeNow)
+	body := info.body
+	if body == "" {
+		body = info.commitMessage
+	}
+	if info.update != 0 {
+		slog.Info("Updating pull request", slog.String("branch", branch), slog.Int("number", info.update))
+		if err := info.ghClient.UpdatePullRequestBody(ctx, info.update, body); err != nil {
+			return nil, fmt.Errorf("failed to update pull request %d: %w", info.update, err)
+		}
+		return &github.PullRequestMetadata{Repo: gitHubRepo, Number: info.update}, nil
+	}
 	slog.Info("Creating pull request", slog.String("branch", branch), slog.String("title", title))
-	if _, err = ghClient.CreatePullRequest(ctx, gitHubRepo, branch, title, commitMessage); err != nil {
-		return fmt.Errorf("failed to create pull request: %w", err)
+	pr, err := info.ghClient.CreatePullRequest(ctx, gitHubRepo, branch, title, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pull request: %w", err)
 	}
-	return nil
+	auditLogFromContext(ctx).recordPullRequest(gitHubRepo, branch, pr)
+	requestOwnerReviews(ctx, info, pr)
+	applyPullRequestConfig(ctx, info, gitHubRepo, pr)
+	return pr, nil
 }
 
 func copyFile(dst, src string) (err error) {
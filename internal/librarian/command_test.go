@@ -17,6 +17,9 @@ package librarian
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -30,6 +33,7 @@ import (
 	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/google/go-cmp/cmp"
 	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
 	"github.com/googleapis/librarian/internal/github"
 	"github.com/googleapis/librarian/internal/gitrepo"
 )
@@ -99,11 +103,55 @@ func TestFindLibraryByID(t *testing.T) {
 	}
 }
 
+func TestGeneratorSourceMount(t *testing.T) {
+	dir := t.TempDir()
+	got, err := generatorSourceMount(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &config.ContainerMount{HostDir: dir, ContainerDir: "/generator", ReadOnly: true}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("generatorSourceMount() mismatch (-want +got):\n%s", diff)
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{file, filepath.Join(dir, "missing")} {
+		if _, err := generatorSourceMount(path); failure.CategoryOf(err) != failure.UserConfig {
+			t.Errorf("generatorSourceMount(%q) error = %v, want %q error", path, err, failure.UserConfig)
+		}
+	}
+}
+
+func TestServiceConfigMount(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "foo_v1.yaml")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := serviceConfigMount(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &config.ContainerMount{HostDir: dir, ContainerDir: "/service-config", ReadOnly: true}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("serviceConfigMount() mismatch (-want +got):\n%s", diff)
+	}
+	for _, path := range []string{dir, filepath.Join(dir, "missing.yaml")} {
+		if _, err := serviceConfigMount(path); failure.CategoryOf(err) != failure.UserConfig {
+			t.Errorf("serviceConfigMount(%q) error = %v, want %q error", path, err, failure.UserConfig)
+		}
+	}
+}
+
 func TestDeriveImage(t *testing.T) {
 	for _, test := range []struct {
 		name          string
 		imageOverride string
 		state         *config.LibrarianState
+		library       *config.LibraryState
 		want          string
 	}{
 		{
@@ -130,9 +178,23 @@ func TestDeriveImage(t *testing.T) {
 			state:         &config.LibrarianState{Image: "gcr.io/foo/bar:v1.2.3"},
 			want:          "gcr.io/foo/bar:v1.2.3",
 		},
+		{
+			name:          "no override, library pinned to a tag",
+			imageOverride: "",
+			state:         &config.LibrarianState{Image: "gcr.io/foo/bar:v1.2.3"},
+			library:       &config.LibraryState{ID: "a", Image: ":v1.0.0"},
+			want:          "gcr.io/foo/bar:v1.0.0",
+		},
+		{
+			name:          "with image override, library pinned",
+			imageOverride: "my/custom-image:v1",
+			state:         &config.LibrarianState{Image: "gcr.io/foo/bar:v1.2.3"},
+			library:       &config.LibraryState{ID: "a", Image: ":v1.0.0"},
+			want:          "my/custom-image:v1",
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			got := deriveImage(test.imageOverride, test.state)
+			got := deriveImage(test.imageOverride, test.state, test.library)
 
 			if got != test.want {
 				t.Errorf("deriveImage() = %q, want %q", got, test.want)
@@ -256,7 +318,7 @@ func TestCloneOrOpenLanguageRepo(t *testing.T) {
 				}
 			}()
 
-			repo, err := cloneOrOpenRepo(workRoot, test.repo, test.ci, "")
+			repo, err := cloneOrOpenRepo(workRoot, test.repo, test.ci, "", nil, nil)
 			if test.wantErr {
 				if err == nil {
 					t.Error("cloneOrOpenLanguageRepo() expected an error but got nil")
@@ -277,6 +339,131 @@ func TestCloneOrOpenLanguageRepo(t *testing.T) {
 	}
 }
 
+func TestGitHubRepository(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name         string
+		cfg          *config.Config
+		languageRepo gitrepo.Repository
+		want         *github.Repository
+		wantErr      bool
+	}{
+		{
+			name: "url",
+			cfg:  &config.Config{Repo: "https://github.com/owner/repo"},
+			want: &github.Repository{Owner: "owner", Name: "repo"},
+		},
+		{
+			name: "enterprise url",
+			cfg: &config.Config{
+				GitHubAPIURL: "https://github.example.com/api/v3/",
+				Repo:         "https://github.example.com/owner/repo",
+			},
+			want: &github.Repository{Owner: "owner", Name: "repo", Host: "github.example.com"},
+		},
+		{
+			name: "enterprise remote",
+			cfg: &config.Config{
+				GitHubAPIURL: "https://github.example.com/api/v3/",
+				Repo:         "/path/to/repo",
+			},
+			languageRepo: &MockRepository{
+				RemotesValue: []*git.Remote{git.NewRemote(memory.NewStorage(), &gogitConfig.RemoteConfig{Name: "origin", URLs: []string{"https://github.example.com/owner/repo.git"}})},
+			},
+			want: &github.Repository{Owner: "owner", Name: "repo", Host: "github.example.com"},
+		},
+		{
+			name:    "url of another host",
+			cfg:     &config.Config{GitHubAPIURL: "https://github.example.com/api/v3/", Repo: "https://github.com/owner/repo"},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := gitHubRepository(test.cfg, test.languageRepo)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("gitHubRepository() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("gitHubRepository() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewGitHubClient(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer valid-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"login": "octocat"}`)
+	}))
+	defer server.Close()
+	repo := &github.Repository{Owner: "owner", Name: "repo"}
+
+	client, err := newGitHubClient(&config.Config{GitHubToken: "valid-token", GitHubAPIURL: server.URL}, repo)
+	if err != nil {
+		t.Fatalf("newGitHubClient() error = %v", err)
+	}
+	if got, want := client.BaseURL.String(), server.URL+"/api/v3/"; got != want {
+		t.Errorf("BaseURL = %q, want %q", got, want)
+	}
+
+	_, err = newGitHubClient(&config.Config{GitHubToken: "invalid-token", GitHubAPIURL: server.URL}, repo)
+	if failure.CategoryOf(err) != failure.UserConfig {
+		t.Errorf("newGitHubClient() error = %v, want %q error", err, failure.UserConfig)
+	}
+
+	// Without an enterprise server, the token is not validated.
+	if _, err := newGitHubClient(&config.Config{GitHubToken: "invalid-token"}, repo); err != nil {
+		t.Errorf("newGitHubClient() error = %v", err)
+	}
+}
+
+func TestCheckGitHubPermissions(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		cfg     *config.Config
+		wantErr error
+		want    []github.Permission
+	}{
+		{
+			name: "nothing to write",
+			cfg:  &config.Config{CommandName: generateCmdName},
+		},
+		{
+			name: "push",
+			cfg:  &config.Config{CommandName: generateCmdName, Push: true},
+			want: []github.Permission{github.PermissionContentsWrite, github.PermissionPullRequestsWrite},
+		},
+		{
+			name: "tag and release reporting failures",
+			cfg:  &config.Config{CommandName: tagAndReleaseCmdName, ReportFailures: true},
+			want: []github.Permission{github.PermissionContentsWrite, github.PermissionPullRequestsWrite, github.PermissionIssuesWrite},
+		},
+		{
+			name:    "missing permission",
+			cfg:     &config.Config{CommandName: generateCmdName, ReportFailures: true},
+			wantErr: errors.New("missing issues:write"),
+			want:    []github.Permission{github.PermissionIssuesWrite},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			ghClient := &mockGitHubClient{checkPermissionsErr: test.wantErr}
+			if err := checkGitHubPermissions(context.Background(), test.cfg, ghClient); err != test.wantErr {
+				t.Errorf("checkGitHubPermissions() error = %v, want %v", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, ghClient.checkedPermissions); diff != "" {
+				t.Errorf("checkGitHubPermissions() permissions mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestCleanAndCopyLibrary(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -1089,6 +1276,8 @@ func TestCommitAndPush(t *testing.T) {
 		name            string
 		setupMockRepo   func(t *testing.T) gitrepo.Repository
 		setupMockClient func(t *testing.T) GitHubClient
+		state           *config.LibrarianState
+		libraryIDs      []string
 		commit          bool
 		push            bool
 		wantErr         bool
@@ -1152,6 +1341,35 @@ func TestCommitAndPush(t *testing.T) {
 			},
 			push: true,
 		},
+		{
+			name: "create a pull request and request owner reviews",
+			setupMockRepo: func(t *testing.T) gitrepo.Repository {
+				remote := git.NewRemote(memory.NewStorage(), &gogitConfig.RemoteConfig{
+					Name: "origin",
+					URLs: []string{"https://github.com/googleapis/librarian.git"},
+				})
+				status := make(git.Status)
+				status["file.txt"] = &git.FileStatus{Worktree: git.Modified}
+				return &MockRepository{
+					Dir:          t.TempDir(),
+					AddAllStatus: status,
+					RemotesValue: []*git.Remote{remote},
+				}
+			},
+			setupMockClient: func(t *testing.T) GitHubClient {
+				return &mockGitHubClient{
+					createdPR:           &github.PullRequestMetadata{Number: 123, Repo: &github.Repository{Owner: "test-owner", Name: "test-repo"}},
+					requestReviewersErr: errors.New("reviewer is not a collaborator"),
+				}
+			},
+			state: &config.LibrarianState{
+				Libraries: []*config.LibraryState{
+					{ID: "a", Owners: []string{"@octocat"}},
+				},
+			},
+			libraryIDs: []string{"a"},
+			push:       true,
+		},
 		{
 			name: "No GitHub Remote",
 			setupMockRepo: func(t *testing.T) gitrepo.Repository {
@@ -1314,7 +1532,13 @@ func TestCommitAndPush(t *testing.T) {
 				Commit: test.commit,
 			}
 
-			err := commitAndPush(context.Background(), localConfig, repo, client, "")
+			err := commitAndPush(context.Background(), &commitInfo{
+				cfg:        localConfig,
+				state:      test.state,
+				repo:       repo,
+				ghClient:   client,
+				libraryIDs: test.libraryIDs,
+			})
 
 			if test.wantErr {
 				if err == nil {
@@ -1332,6 +1556,129 @@ func TestCommitAndPush(t *testing.T) {
 	}
 }
 
+func TestCommitAndPush_MemoryRepository(t *testing.T) {
+	repo, err := gitrepo.NewMemoryRepository("https://github.com/googleapis/librarian.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := repo.CommitFiles("chore: initial commit", map[string]string{"a/a.go": "package a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile("a/a.go", []byte("package a // regenerated")); err != nil {
+		t.Fatal(err)
+	}
+	ghClient := &mockGitHubClient{
+		createdPR: &github.PullRequestMetadata{Number: 123, Repo: &github.Repository{Owner: "googleapis", Name: "librarian"}},
+	}
+	pr, err := commitAndCreatePullRequest(context.Background(), &commitInfo{
+		cfg:           &config.Config{Push: true},
+		repo:          repo,
+		ghClient:      ghClient,
+		commitMessage: "chore: regenerate",
+		branch:        "librarian-test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr == nil || pr.Number != 123 {
+		t.Errorf("commitAndCreatePullRequest() = %v, want pull request 123", pr)
+	}
+	if diff := cmp.Diff([]string{"refs/heads/librarian-test"}, repo.Pushed); diff != "" {
+		t.Errorf("pushed refs mismatch (-want +got):\n%s", diff)
+	}
+	head, err := repo.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := repo.ChangedFilesInCommit(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head == base {
+		t.Errorf("commitAndCreatePullRequest() did not commit on top of %s", base)
+	}
+	if diff := cmp.Diff([]string{"a/a.go"}, files); diff != "" {
+		t.Errorf("committed files mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCommitAndPush_UpdatePullRequest(t *testing.T) {
+	repo, err := gitrepo.NewMemoryRepository("https://github.com/googleapis/librarian.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CommitFiles("chore: initial commit", map[string]string{"a/a.go": "package a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteFile("a/a.go", []byte("package a // released")); err != nil {
+		t.Fatal(err)
+	}
+	ghClient := &mockGitHubClient{}
+	pr, err := commitAndCreatePullRequest(context.Background(), &commitInfo{
+		cfg:           &config.Config{Push: true},
+		repo:          repo,
+		ghClient:      ghClient,
+		commitMessage: "chore: release",
+		branch:        "librarian-20250101T000000Z",
+		body:          "refreshed body",
+		update:        42,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr == nil || pr.Number != 42 {
+		t.Errorf("commitAndCreatePullRequest() = %v, want pull request 42", pr)
+	}
+	if ghClient.createPullRequestCalls != 0 {
+		t.Errorf("created %d pull requests, want none", ghClient.createPullRequestCalls)
+	}
+	if diff := cmp.Diff([]string{"refs/heads/librarian-20250101T000000Z"}, repo.Pushed); diff != "" {
+		t.Errorf("pushed refs mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"refreshed body"}, ghClient.updatedBodies); diff != "" {
+		t.Errorf("updated bodies mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCommitAndPush_OnlyFollowUpCommits(t *testing.T) {
+	repo, err := gitrepo.NewMemoryRepository("https://github.com/googleapis/librarian.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := repo.CommitFiles("chore: initial commit", map[string]string{"a/a.go": "package a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = commitAndPush(context.Background(), &commitInfo{
+		cfg:           &config.Config{Commit: true},
+		repo:          repo,
+		commitMessage: "chore: update librarian state",
+		branch:        "librarian-test",
+		followUpCommits: []*followUpCommit{
+			{
+				message: "feat(a): regenerate",
+				apply:   func() error { return repo.WriteFile("a/a.go", []byte("package a // regenerated")) },
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits, err := repo.GetCommitsForPathsSinceCommit([]string{"a"}, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var subjects []string
+	for _, commit := range commits {
+		subject, _, _ := strings.Cut(commit.Message, "\n")
+		subjects = append(subjects, subject)
+	}
+	if diff := cmp.Diff([]string{"feat(a): regenerate"}, subjects); diff != "" {
+		t.Errorf("commits mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestCopyLibraryFiles(t *testing.T) {
 	t.Parallel()
 	setup := func(src string, files []string) {
@@ -0,0 +1,302 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// commitGroup is a group of changed files committed together.
+type commitGroup struct {
+	libraryID string
+	// apiPath is the path of the API of the files, if grouped by API.
+	apiPath string
+	files   []*changedFile
+	// breaking reports whether the API changes of the files are breaking.
+	breaking bool
+}
+
+// groupGenerationCommits arranges the changes of a generation run into
+// commits, as configured by the commit grouping of the repository. It returns
+// the message of the main commit, and the follow-up commits holding the
+// changes of each library or API, if grouped by library or API. The changes
+// of grouped files are reverted in the working tree, and applied again by
+// their follow-up commit, so that the main commit only holds the changes
+// which belong to no library, such as those of the state file.
+func groupGenerationCommits(lc *config.LibrarianConfig, repo gitrepo.Repository, state *config.LibrarianState, report *generationReport, message string) (string, []*followUpCommit, error) {
+	mode := lc.CommitGroupingMode()
+	switch mode {
+	case config.CommitGroupingRun:
+		return attributedRunMessage(lc, state, report, message), nil, nil
+	case config.CommitGroupingSquash:
+		return squashedCommitMessage(lc, state, report, message), nil, nil
+	}
+
+	status, err := repo.Status()
+	if err != nil {
+		return "", nil, err
+	}
+	var followUps []*followUpCommit
+	for _, group := range groupChangedFiles(mode, state, report, status) {
+		apply, err := setAsideChanges(repo, group.files)
+		if err != nil {
+			return "", nil, err
+		}
+		library := findLibraryByID(state, group.libraryID)
+		followUps = append(followUps, &followUpCommit{message: group.commitMessage(lc, library), apply: apply})
+	}
+	slog.Info("Grouped generated changes into commits", "grouping", mode, "commits", len(followUps))
+	return "chore: update librarian state\n\n" + message, followUps, nil
+}
+
+// groupChangedFiles groups the files of each library in the report which are
+// still changed in the working tree, by library or by API path.
+func groupChangedFiles(mode string, state *config.LibrarianState, report *generationReport, status git.Status) []*commitGroup {
+	var groups []*commitGroup
+	for _, libraryReport := range report.Libraries {
+		library := findLibraryByID(state, libraryReport.ID)
+		byAPI := make(map[string]*commitGroup)
+		for _, file := range libraryReport.Files {
+			if _, ok := status[file.Path]; !ok {
+				// Set aside by an earlier step, such as the quarantine of
+				// protected files.
+				continue
+			}
+			apiPath := ""
+			if mode == config.CommitGroupingAPI {
+				apiPath = apiForFile(library, file.Path)
+			}
+			group, ok := byAPI[apiPath]
+			if !ok {
+				group = &commitGroup{libraryID: libraryReport.ID, apiPath: apiPath, breaking: hasBreakingChanges(libraryReport, apiPath)}
+				byAPI[apiPath] = group
+				groups = append(groups, group)
+			}
+			group.files = append(group.files, file)
+		}
+	}
+	return groups
+}
+
+// apiForFile returns the path of the API of library which the file at
+// filePath most likely belongs to: the API whose path shares the longest run
+// of trailing segments, such as "secretmanager/v1", with the directory of the
+// file. It defaults to the first API of the library.
+func apiForFile(library *config.LibraryState, filePath string) string {
+	if library == nil || len(library.APIs) == 0 {
+		return ""
+	}
+	dir := "/" + path.Dir(filePath) + "/"
+	best, bestLength := library.APIs[0].Path, 0
+	for _, api := range library.APIs {
+		segments := strings.Split(api.Path, "/")
+		for n := len(segments); n > bestLength; n-- {
+			if strings.Contains(dir, "/"+strings.Join(segments[len(segments)-n:], "/")+"/") {
+				best, bestLength = api.Path, n
+				break
+			}
+		}
+	}
+	return best
+}
+
+// commitMessage returns the message of the commit of the group.
+func (g *commitGroup) commitMessage(lc *config.LibrarianConfig, library *config.LibraryState) string {
+	var b strings.Builder
+	breaking := ""
+	if g.breaking {
+		breaking = "!"
+	}
+	if g.apiPath != "" {
+		fmt.Fprintf(&b, "feat(%s)%s: regenerate %s\n\n", g.libraryID, breaking, g.apiPath)
+	} else {
+		fmt.Fprintf(&b, "feat(%s)%s: regenerate\n\n", g.libraryID, breaking)
+	}
+	writeLibraryChanges(&b, lc, library, g.files)
+	if trailers := coAuthorTrailers(lc, library); len(trailers) > 0 {
+		return gitrepo.AddTrailers(b.String(), trailers...)
+	}
+	return b.String()
+}
+
+// attributedRunMessage returns the message of the commit of all the changes
+// of the run, with the source commits of the changed libraries listed if
+// they are linked, and their authors credited as co-authors, as configured
+// by the source attribution.
+func attributedRunMessage(lc *config.LibrarianConfig, state *config.LibrarianState, report *generationReport, message string) string {
+	libraries := changedLibraries(state, report)
+	message = withBreakingCommits(report, message)
+	if lc != nil && lc.SourceAttribution != nil && lc.SourceAttribution.CommitURL != "" {
+		var b strings.Builder
+		for _, library := range libraries {
+			for _, change := range library.Changes {
+				fmt.Fprintf(&b, "* %s: %s: %s (%s)\n", library.ID, change.Type, change.Subject, formatSourceCommit(lc, change.CommitHash))
+			}
+		}
+		if b.Len() > 0 {
+			body, trailers := gitrepo.ParseTrailers(message)
+			message = gitrepo.FormatMessage(body+"\n\nSource commits:\n\n"+b.String(), trailers)
+		}
+	}
+	if trailers := coAuthorTrailers(lc, libraries...); len(trailers) > 0 {
+		return gitrepo.AddTrailers(message, trailers...)
+	}
+	return message
+}
+
+// hasBreakingChanges reports whether the library in the report has breaking
+// API changes, in the API at apiPath if it is not empty.
+func hasBreakingChanges(library *libraryGenerationReport, apiPath string) bool {
+	for _, change := range library.BreakingChanges {
+		if apiPath == "" || change.API == apiPath {
+			return true
+		}
+	}
+	return false
+}
+
+// withBreakingCommits returns the message of the commit of all the changes of
+// the run, with a nested breaking commit for each changed library with
+// breaking API changes, so that its next release is a major one.
+func withBreakingCommits(report *generationReport, message string) string {
+	var b strings.Builder
+	for _, library := range report.Libraries {
+		if len(library.Files) > 0 && len(library.BreakingChanges) > 0 {
+			fmt.Fprintf(&b, "BEGIN_NESTED_COMMIT\nfeat(%s)!: regenerate with breaking API changes\nEND_NESTED_COMMIT\n", library.ID)
+		}
+	}
+	if b.Len() == 0 {
+		return message
+	}
+	body, trailers := gitrepo.ParseTrailers(message)
+	if body != "" {
+		body += "\n\n"
+	}
+	return gitrepo.FormatMessage(body+b.String(), trailers)
+}
+
+// changedLibraries returns the libraries with changed files in the report.
+func changedLibraries(state *config.LibrarianState, report *generationReport) []*config.LibraryState {
+	var libraries []*config.LibraryState
+	for _, libraryReport := range report.Libraries {
+		if library := findLibraryByID(state, libraryReport.ID); library != nil && len(libraryReport.Files) > 0 {
+			libraries = append(libraries, library)
+		}
+	}
+	return libraries
+}
+
+// coAuthorTrailers returns a Co-authored-by trailer for each author of the
+// source commits of the changes of the libraries, if the source attribution
+// credits them.
+func coAuthorTrailers(lc *config.LibrarianConfig, libraries ...*config.LibraryState) []gitrepo.Trailer {
+	if !lc.SourceCoAuthors() {
+		return nil
+	}
+	var trailers []gitrepo.Trailer
+	seen := make(map[string]bool)
+	for _, library := range libraries {
+		if library == nil {
+			continue
+		}
+		for _, change := range library.Changes {
+			if change.Author == "" || seen[change.Author] {
+				continue
+			}
+			seen[change.Author] = true
+			trailers = append(trailers, gitrepo.Trailer{Key: gitrepo.TrailerCoAuthoredBy, Value: change.Author})
+		}
+	}
+	return trailers
+}
+
+// formatSourceCommit returns the short hash of a source commit, as a
+// Markdown link to the commit if the source attribution has a commit URL.
+func formatSourceCommit(lc *config.LibrarianConfig, hash string) string {
+	short := hash
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	if url := lc.SourceCommitURL(hash); url != "" {
+		return fmt.Sprintf("[%s](%s)", short, url)
+	}
+	return short
+}
+
+// squashedCommitMessage returns the message of a single commit of all the
+// changes in the report, detailing the changes of each library after the
+// message of the run. The trailers of the message of the run, if any, are
+// moved to the end of the commit message.
+func squashedCommitMessage(lc *config.LibrarianConfig, state *config.LibrarianState, report *generationReport, message string) string {
+	var libraries []*libraryGenerationReport
+	for _, library := range report.Libraries {
+		if len(library.Files) > 0 {
+			libraries = append(libraries, library)
+		}
+	}
+	if len(libraries) == 0 {
+		return message
+	}
+	body, trailers := gitrepo.ParseTrailers(message)
+	var b strings.Builder
+	breaking := ""
+	for _, library := range libraries {
+		if len(library.BreakingChanges) > 0 {
+			breaking = "!"
+		}
+	}
+	if len(libraries) == 1 {
+		fmt.Fprintf(&b, "feat(%s)%s: regenerate\n\n", libraries[0].ID, breaking)
+	} else {
+		fmt.Fprintf(&b, "feat%s: regenerate %d libraries\n\n", breaking, len(libraries))
+	}
+	if body != "" {
+		b.WriteString(body + "\n\n")
+	}
+	for _, library := range libraries {
+		fmt.Fprintf(&b, "%s:\n", library.ID)
+		writeLibraryChanges(&b, lc, findLibraryByID(state, library.ID), library.Files)
+		b.WriteString("\n")
+	}
+	trailers = append(trailers, coAuthorTrailers(lc, changedLibraries(state, report)...)...)
+	if len(trailers) > 0 {
+		return gitrepo.FormatMessage(b.String(), trailers)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// writeLibraryChanges writes the number of changed files of a library, and
+// the API changes it was regenerated from.
+func writeLibraryChanges(b *strings.Builder, lc *config.LibrarianConfig, library *config.LibraryState, files []*changedFile) {
+	linesDelta := 0
+	for _, file := range files {
+		linesDelta += file.LinesDelta
+	}
+	fmt.Fprintf(b, "Files changed: %d, lines: %+d.\n", len(files), linesDelta)
+	if library == nil || len(library.Changes) == 0 {
+		return
+	}
+	b.WriteString("\nAPI changes:\n\n")
+	for _, change := range library.Changes {
+		fmt.Fprintf(b, "* %s: %s (%s)\n", change.Type, change.Subject, formatSourceCommit(lc, change.CommitHash))
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestGroupGenerationCommits(t *testing.T) {
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{
+				ID: "a",
+				APIs: []*config.API{
+					{Path: "google/cloud/a/v1"},
+					{Path: "google/cloud/a/v2"},
+				},
+				Changes: []*config.Change{
+					{Type: "feat", Subject: "add a field", CommitHash: "1234567890abcdef"},
+				},
+			},
+			{ID: "b", APIs: []*config.API{{Path: "google/cloud/b/v1"}}},
+		},
+	}
+	report := &generationReport{
+		Libraries: []*libraryGenerationReport{
+			{
+				ID: "a",
+				Files: []*changedFile{
+					{Path: "a/apiv1/a.go", Change: fileChangeModified, LinesDelta: 2},
+					{Path: "a/apiv2/a.go", Change: fileChangeAdded, LinesDelta: 10},
+					{Path: "a/v2/doc.go", Change: fileChangeAdded, LinesDelta: 1},
+				},
+			},
+			{
+				ID: "b",
+				Files: []*changedFile{
+					{Path: "b/b.go", Change: fileChangeModified, LinesDelta: -1},
+				},
+			},
+		},
+	}
+	for _, test := range []struct {
+		name         string
+		grouping     string
+		wantMessage  string
+		wantSubjects []string
+	}{
+		{
+			name:        "run",
+			wantMessage: "feat: generated a\n",
+		},
+		{
+			name:     "squash",
+			grouping: config.CommitGroupingSquash,
+			wantMessage: `feat: regenerate 2 libraries
+
+feat: generated a
+
+a:
+Files changed: 3, lines: +13.
+
+API changes:
+
+* feat: add a field (1234567)
+
+b:
+Files changed: 1, lines: -1.
+`,
+		},
+		{
+			name:         "library",
+			grouping:     config.CommitGroupingLibrary,
+			wantMessage:  "chore: update librarian state\n\nfeat: generated a\n",
+			wantSubjects: []string{"feat(a): regenerate", "feat(b): regenerate"},
+		},
+		{
+			name:        "api",
+			grouping:    config.CommitGroupingAPI,
+			wantMessage: "chore: update librarian state\n\nfeat: generated a\n",
+			wantSubjects: []string{
+				"feat(a): regenerate google/cloud/a/v1",
+				"feat(a): regenerate google/cloud/a/v2",
+				"feat(b): regenerate google/cloud/b/v1",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repoDir := t.TempDir()
+			status := make(git.Status)
+			for _, path := range []string{"a/apiv1/a.go", "a/apiv2/a.go", "a/v2/doc.go", "b/b.go"} {
+				if err := writeFile(filepath.Join(repoDir, path), "generated"); err != nil {
+					t.Fatal(err)
+				}
+				status[path] = &git.FileStatus{Worktree: git.Modified}
+			}
+			repo := &MockRepository{
+				Dir:          repoDir,
+				AddAllStatus: status,
+				FilesAtHead: map[string]string{
+					"a/apiv1/a.go": "old",
+					"b/b.go":       "old",
+				},
+			}
+			lc := &config.LibrarianConfig{CommitGrouping: test.grouping}
+			message, followUps, err := groupGenerationCommits(lc, repo, state, report, "feat: generated a\n")
+			if err != nil {
+				t.Fatalf("groupGenerationCommits() error = %v", err)
+			}
+			if diff := cmp.Diff(test.wantMessage, message); diff != "" {
+				t.Errorf("groupGenerationCommits() message mismatch (-want +got):\n%s", diff)
+			}
+			var subjects []string
+			for _, followUp := range followUps {
+				subject, _, _ := strings.Cut(followUp.message, "\n")
+				subjects = append(subjects, subject)
+			}
+			if diff := cmp.Diff(test.wantSubjects, subjects); diff != "" {
+				t.Errorf("groupGenerationCommits() subjects mismatch (-want +got):\n%s", diff)
+			}
+			if len(followUps) == 0 {
+				return
+			}
+			assertFiles(t, repoDir, map[string]string{
+				"a/apiv1/a.go": "old",
+				"a/apiv2/a.go": "",
+				"a/v2/doc.go":  "",
+				"b/b.go":       "old",
+			})
+			for _, followUp := range followUps {
+				if err := followUp.apply(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			assertFiles(t, repoDir, map[string]string{
+				"a/apiv1/a.go": "generated",
+				"a/apiv2/a.go": "generated",
+				"a/v2/doc.go":  "generated",
+				"b/b.go":       "generated",
+			})
+		})
+	}
+}
+
+func TestApiForFile(t *testing.T) {
+	library := &config.LibraryState{
+		ID: "storage",
+		APIs: []*config.API{
+			{Path: "google/storage/v1"},
+			{Path: "google/storage/control/v2"},
+		},
+	}
+	for _, test := range []struct {
+		path string
+		want string
+	}{
+		{path: "storage/v1/storage.go", want: "google/storage/v1"},
+		{path: "storage/control/v2/control.go", want: "google/storage/control/v2"},
+		{path: "storage/internal/version.go", want: "google/storage/v1"},
+		{path: "README.md", want: "google/storage/v1"},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			if got := apiForFile(library, test.path); got != test.want {
+				t.Errorf("apiForFile() = %q, want %q", got, test.want)
+			}
+		})
+	}
+	if got := apiForFile(&config.LibraryState{ID: "empty"}, "a.go"); got != "" {
+		t.Errorf("apiForFile() of a library without APIs = %q, want empty", got)
+	}
+}
+
+func TestSquashedCommitMessage_Trailers(t *testing.T) {
+	state := &config.LibrarianState{Libraries: []*config.LibraryState{{ID: "a"}}}
+	report := &generationReport{
+		Libraries: []*libraryGenerationReport{
+			{ID: "a", Files: []*changedFile{{Path: "a/a.go", LinesDelta: 1}}},
+		},
+	}
+	message := "feat: generated a\n\nPiperOrigin-RevId: 123\nCo-authored-by: Jane Doe <jane@example.com>\n"
+	want := `feat(a): regenerate
+
+feat: generated a
+
+a:
+Files changed: 1, lines: +1.
+
+PiperOrigin-RevId: 123
+Co-authored-by: Jane Doe <jane@example.com>
+`
+	got := squashedCommitMessage(nil, state, report, message)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("squashedCommitMessage() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSourceAttribution(t *testing.T) {
+	library := &config.LibraryState{
+		ID: "a",
+		Changes: []*config.Change{
+			{Type: "feat", Subject: "add Foo", CommitHash: "1234567890abcdef", Author: "Jane Doe <jane@example.com>"},
+			{Type: "fix", Subject: "fix Bar", CommitHash: "fedcba0987654321", Author: "John Roe <john@example.com>"},
+			{Type: "docs", Subject: "document Foo", CommitHash: "abcdef1234567890", Author: "Jane Doe <jane@example.com>"},
+		},
+	}
+	state := &config.LibrarianState{Libraries: []*config.LibraryState{library, {ID: "b"}}}
+	report := &generationReport{
+		Libraries: []*libraryGenerationReport{
+			{ID: "a", Files: []*changedFile{{Path: "a/a.go", LinesDelta: 1}}},
+			{ID: "b"},
+		},
+	}
+	lc := &config.LibrarianConfig{
+		SourceAttribution: &config.SourceAttribution{
+			CoAuthors: true,
+			CommitURL: "https://github.com/googleapis/googleapis/commit/{hash}",
+		},
+	}
+
+	wantRun := `feat: generated a
+
+Source commits:
+
+* a: feat: add Foo ([1234567](https://github.com/googleapis/googleapis/commit/1234567890abcdef))
+* a: fix: fix Bar ([fedcba0](https://github.com/googleapis/googleapis/commit/fedcba0987654321))
+* a: docs: document Foo ([abcdef1](https://github.com/googleapis/googleapis/commit/abcdef1234567890))
+
+PiperOrigin-RevId: 123
+Co-authored-by: Jane Doe <jane@example.com>
+Co-authored-by: John Roe <john@example.com>
+`
+	if diff := cmp.Diff(wantRun, attributedRunMessage(lc, state, report, "feat: generated a\n\nPiperOrigin-RevId: 123\n")); diff != "" {
+		t.Errorf("attributedRunMessage() mismatch (-want +got):\n%s", diff)
+	}
+
+	group := &commitGroup{libraryID: "a", files: report.Libraries[0].Files}
+	wantLibrary := `feat(a): regenerate
+
+Files changed: 1, lines: +1.
+
+API changes:
+
+* feat: add Foo ([1234567](https://github.com/googleapis/googleapis/commit/1234567890abcdef))
+* fix: fix Bar ([fedcba0](https://github.com/googleapis/googleapis/commit/fedcba0987654321))
+* docs: document Foo ([abcdef1](https://github.com/googleapis/googleapis/commit/abcdef1234567890))
+
+Co-authored-by: Jane Doe <jane@example.com>
+Co-authored-by: John Roe <john@example.com>
+`
+	if diff := cmp.Diff(wantLibrary, group.commitMessage(lc, library)); diff != "" {
+		t.Errorf("commitMessage() mismatch (-want +got):\n%s", diff)
+	}
+
+	message := "feat: generated a\n"
+	if got := attributedRunMessage(nil, state, report, message); got != message {
+		t.Errorf("attributedRunMessage() without source attribution = %q, want %q", got, message)
+	}
+}
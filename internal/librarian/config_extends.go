@@ -0,0 +1,109 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// maxConfigExtendsDepth limits the length of a chain of configs extending
+// each other.
+const maxConfigExtendsDepth = 10
+
+var configHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// resolveConfigExtends returns the effective config of lc, which was read from
+// location, by recursively loading the configs it extends and overlaying lc on
+// top of them. The locations of the configs already visited are in seen, and
+// are used to detect cycles.
+func resolveConfigExtends(lc *config.LibrarianConfig, location string, seen []string) (*config.LibrarianConfig, error) {
+	if lc.Extends == "" {
+		return lc, nil
+	}
+	baseLocation, err := resolveConfigLocation(location, lc.Extends)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(seen, baseLocation) {
+		return nil, fmt.Errorf("config extends cycle: %s -> %s", strings.Join(seen, " -> "), baseLocation)
+	}
+	if len(seen) > maxConfigExtendsDepth {
+		return nil, fmt.Errorf("config extends chain is longer than %d", maxConfigExtendsDepth)
+	}
+	slog.Info("Loading base config", "location", baseLocation, "extended_by", location)
+	data, err := readConfigLocation(baseLocation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read base config %s: %w", baseLocation, err)
+	}
+	base := &config.LibrarianConfig{}
+	if err := yaml.Unmarshal(data, base); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal base config %s: %w", baseLocation, err)
+	}
+	base, err = resolveConfigExtends(base, baseLocation, append(seen, baseLocation))
+	if err != nil {
+		return nil, err
+	}
+	return base.Overlay(lc), nil
+}
+
+// resolveConfigLocation resolves ref, the value of an extends field, against
+// the location of the config declaring it.
+func resolveConfigLocation(from, ref string) (string, error) {
+	if isURL(ref) {
+		return ref, nil
+	}
+	if isURL(from) {
+		fromURL, err := url.Parse(from)
+		if err != nil {
+			return "", err
+		}
+		refURL, err := fromURL.Parse(ref)
+		if err != nil {
+			return "", fmt.Errorf("invalid extends %q: %w", ref, err)
+		}
+		return refURL.String(), nil
+	}
+	if filepath.IsAbs(ref) {
+		return filepath.Clean(ref), nil
+	}
+	return filepath.Join(filepath.Dir(from), ref), nil
+}
+
+func readConfigLocation(location string) ([]byte, error) {
+	if !isURL(location) {
+		return os.ReadFile(location)
+	}
+	resp, err := configHTTPClient.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
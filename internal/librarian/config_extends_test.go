@@ -0,0 +1,184 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestParseLibrarianConfigExtends(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/org/base.yaml":
+			w.Write([]byte(`extends: common.yaml
+global_files_allowlist:
+  - path: go.work
+    permissions: read-only
+`))
+		case "/org/common.yaml":
+			w.Write([]byte(`global_files_allowlist:
+  - path: README.md
+    permissions: write-only
+`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	for _, test := range []struct {
+		name       string
+		files      map[string]string
+		want       *config.LibrarianConfig
+		wantErrMsg string
+	}{
+		{
+			name: "local base",
+			files: map[string]string{
+				"config.yaml": `extends: ../shared/base.yaml
+global_files_allowlist:
+  - path: go.work
+    permissions: read-write
+`,
+				"../shared/base.yaml": `global_files_allowlist:
+  - path: go.work
+    permissions: read-only
+  - path: README.md
+    permissions: write-only
+`,
+			},
+			want: &config.LibrarianConfig{
+				GlobalFilesAllowlist: []*config.GlobalFile{
+					{Path: "go.work", Permissions: "read-write"},
+					{Path: "README.md", Permissions: "write-only"},
+				},
+			},
+		},
+		{
+			name: "remote base with relative extends",
+			files: map[string]string{
+				"config.yaml": "extends: " + server.URL + "/org/base.yaml\n",
+			},
+			want: &config.LibrarianConfig{
+				GlobalFilesAllowlist: []*config.GlobalFile{
+					{Path: "README.md", Permissions: "write-only"},
+					{Path: "go.work", Permissions: "read-only"},
+				},
+			},
+		},
+		{
+			name: "remote base not found",
+			files: map[string]string{
+				"config.yaml": "extends: " + server.URL + "/org/missing.yaml\n",
+			},
+			wantErrMsg: "failed to read base config",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"config.yaml": "extends: a.yaml\n",
+				"a.yaml":      "extends: config.yaml\n",
+			},
+			wantErrMsg: "config extends cycle",
+		},
+		{
+			name: "invalid effective config",
+			files: map[string]string{
+				"config.yaml": "extends: a.yaml\n",
+				"a.yaml": `global_files_allowlist:
+  - path: go.work
+    permissions: unknown
+`,
+			},
+			wantErrMsg: "invalid global config",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir := filepath.Join(t.TempDir(), "repo")
+			for name, content := range test.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := parseLibrarianConfig(filepath.Join(dir, "config.yaml"))
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("parseLibrarianConfig() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseLibrarianConfig() error = %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("parseLibrarianConfig() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrintEffectiveConfig(t *testing.T) {
+	t.Parallel()
+	repoDir := t.TempDir()
+	librarianDir := filepath.Join(repoDir, config.LibrarianDir)
+	if err := os.MkdirAll(librarianDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		librarianConfigFile: `extends: base.yaml
+global_files_allowlist:
+  - path: go.work
+    permissions: read-write
+`,
+		"base.yaml": `version_files:
+  - path: internal/version.go
+    kind: go-version
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(librarianDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out bytes.Buffer
+	if err := printEffectiveConfig(&out, &config.Config{Repo: repoDir}); err != nil {
+		t.Fatalf("printEffectiveConfig() error = %v", err)
+	}
+	want := `global_files_allowlist:
+    - path: go.work
+      permissions: read-write
+version_files:
+    - path: internal/version.go
+      kind: go-version
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("printEffectiveConfig() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/docker"
+)
+
+// containerResults is the content of config.ContainerResults, which a
+// container writes into its /librarian mount to report structured results
+// back to librarian. All the fields are optional.
+type containerResults struct {
+	// Warnings are logged and listed in the generation report, and do not
+	// fail the library.
+	Warnings []string `json:"warnings,omitempty"`
+	// GeneratedFiles are the paths, relative to the root of the language
+	// repository, of files which the container generated. They are
+	// classified as generated in the generation report, even if the generate
+	// container did not write them to /output, e.g. files written by the
+	// build.
+	GeneratedFiles []string `json:"generated_files,omitempty"`
+	// Skipped are the items, such as APIs or samples, which the container
+	// skipped, and why.
+	Skipped []*skippedItem `json:"skipped,omitempty"`
+	// Metrics are measurements of the container, e.g. the number of
+	// generated methods, by name.
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	// Fatal are errors which fail the library, even if the container exited
+	// successfully.
+	Fatal []string `json:"fatal,omitempty"`
+}
+
+// skippedItem is an item which a container skipped.
+type skippedItem struct {
+	Item   string `json:"item"`
+	Reason string `json:"reason"`
+}
+
+// containerReport is the results which a container command reported for a
+// library, as recorded in the generation report.
+type containerReport struct {
+	Library string `json:"library"`
+	Command string `json:"command"`
+	// Variant is the variant of the build matrix of the build and test
+	// commands, if any.
+	Variant string `json:"variant,omitempty"`
+	containerResults
+}
+
+// name identifies the container command of the report in Markdown.
+func (c *containerReport) name() string {
+	if c.Variant == "" {
+		return fmt.Sprintf("%s %s", c.Library, c.Command)
+	}
+	return fmt.Sprintf("%s %s (%s)", c.Library, c.Command, c.Variant)
+}
+
+// readContainerResults reads the results which a container wrote at path, and
+// removes the file so that it is neither committed nor read again. It returns
+// nil if the container wrote no results.
+func readContainerResults(path string) (*containerResults, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read container results: %w", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return nil, err
+	}
+	results := &containerResults{}
+	if err := json.Unmarshal(data, results); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", config.ContainerResults, err)
+	}
+	for _, file := range results.GeneratedFiles {
+		if !filepath.IsLocal(file) {
+			return nil, fmt.Errorf("invalid generated file %q in %s, want a path relative to the repository", file, config.ContainerResults)
+		}
+	}
+	return results, nil
+}
+
+// recordContainerResults reads the results which the container of command
+// reported for a library, if any, logs their warnings and records them for
+// the generation report. It returns an error if the container reported fatal
+// errors.
+func (r *generateRunner) recordContainerResults(command docker.Command, libraryID string, variant *config.BuildVariant) error {
+	results, err := readContainerResults(filepath.Join(r.repo.GetDir(), config.LibrarianDir, config.ContainerResults))
+	if err != nil || results == nil {
+		return err
+	}
+	for _, warning := range results.Warnings {
+		slog.Warn("container reported a warning", "command", command, "id", libraryID, "warning", warning)
+	}
+	for _, skipped := range results.Skipped {
+		slog.Info("container skipped an item", "command", command, "id", libraryID, "item", skipped.Item, "reason", skipped.Reason)
+	}
+	r.containers = append(r.containers, &containerReport{
+		Library:          libraryID,
+		Command:          string(command),
+		Variant:          variantName(variant),
+		containerResults: *results,
+	})
+	if len(results.Fatal) > 0 {
+		return fmt.Errorf("%s container reported fatal errors for library %s: %s", command, libraryID, strings.Join(results.Fatal, "; "))
+	}
+	return nil
+}
+
+// mergeContainerResults records the results of the containers in the report.
+// The changed files which a container declared generated are classified as
+// generated, unless they match the preserve_regex of their library.
+func (r *generationReport) mergeContainerResults(state *config.LibrarianState, containers []*containerReport) error {
+	r.Containers = containers
+	generated := make(map[string]map[string]bool)
+	for _, container := range containers {
+		for _, file := range container.GeneratedFiles {
+			if generated[container.Library] == nil {
+				generated[container.Library] = make(map[string]bool)
+			}
+			generated[container.Library][filepath.ToSlash(filepath.Clean(file))] = true
+		}
+	}
+	for _, libraryReport := range r.Libraries {
+		if generated[libraryReport.ID] == nil {
+			continue
+		}
+		library := findLibraryByID(state, libraryReport.ID)
+		libraryReport.CodegenOnly = true
+		for _, file := range libraryReport.Files {
+			if file.Kind == fileKindHandwritten && generated[libraryReport.ID][file.Path] {
+				preserved, err := matchesAny(library.PreserveRegex, file.Path)
+				if err != nil {
+					return fmt.Errorf("invalid preserve_regex of library %s: %w", library.ID, err)
+				}
+				if !preserved {
+					file.Kind = fileKindGenerated
+				}
+			}
+			if file.Kind == fileKindHandwritten {
+				libraryReport.CodegenOnly = false
+			}
+		}
+	}
+	return nil
+}
+
+// containerResultsMarkdown lists the fatal errors, warnings and skipped items
+// reported by the containers, or returns an empty string if there are none.
+func containerResultsMarkdown(containers []*containerReport) string {
+	var b strings.Builder
+	for _, container := range containers {
+		for _, fatal := range container.Fatal {
+			fmt.Fprintf(&b, "* %s: **fatal**: %s\n", container.name(), fatal)
+		}
+		for _, warning := range container.Warnings {
+			fmt.Fprintf(&b, "* %s: warning: %s\n", container.name(), warning)
+		}
+		for _, skipped := range container.Skipped {
+			fmt.Fprintf(&b, "* %s: skipped `%s`: %s\n", container.name(), skipped.Item, skipped.Reason)
+		}
+	}
+	return b.String()
+}
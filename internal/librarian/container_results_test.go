@@ -0,0 +1,212 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestReadContainerResults(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		content string
+		want    *containerResults
+		wantErr string
+	}{
+		{
+			name: "no results",
+		},
+		{
+			name: "all fields",
+			content: `{
+  "warnings": ["deprecated option"],
+  "generated_files": ["a/gen.go"],
+  "skipped": [{"item": "samples", "reason": "no samples"}],
+  "metrics": {"methods": 12},
+  "fatal": ["missing service config"],
+  "future_field": true
+}`,
+			want: &containerResults{
+				Warnings:       []string{"deprecated option"},
+				GeneratedFiles: []string{"a/gen.go"},
+				Skipped:        []*skippedItem{{Item: "samples", Reason: "no samples"}},
+				Metrics:        map[string]float64{"methods": 12},
+				Fatal:          []string{"missing service config"},
+			},
+		},
+		{
+			name:    "invalid JSON",
+			content: "warnings: []",
+			wantErr: "failed to parse",
+		},
+		{
+			name:    "generated file outside of the repository",
+			content: `{"generated_files": ["../a/gen.go"]}`,
+			wantErr: "invalid generated file",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), config.ContainerResults)
+			if test.content != "" {
+				if err := os.WriteFile(path, []byte(test.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := readContainerResults(path)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("readContainerResults() error = %v, want to contain %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("readContainerResults() mismatch (-want +got):\n%s", diff)
+			}
+			if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("readContainerResults() did not remove the results, stat error = %v", err)
+			}
+		})
+	}
+}
+
+func TestRunBuildCommand_ContainerResults(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		results string
+		wantErr string
+	}{
+		{
+			name:    "warnings",
+			results: `{"warnings": ["slow build"], "metrics": {"seconds": 3}}`,
+		},
+		{
+			name:    "fatal",
+			results: `{"fatal": ["missing dependency", "bad license"]}`,
+			wantErr: "build container reported fatal errors for library some-library: missing dependency; bad license",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repo := newTestGitRepo(t)
+			r := &generateRunner{
+				cfg:             &config.Config{Phases: "build"},
+				repo:            repo,
+				state:           &config.LibrarianState{Libraries: []*config.LibraryState{{ID: "some-library"}}},
+				containerClient: &mockContainerClient{buildResults: test.results},
+			}
+			err := r.runBuildCommand(t.Context(), "some-library", &config.BuildVariant{Name: "java11"})
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Fatal(err)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Fatalf("runBuildCommand() error = %v, want to contain %q", err, test.wantErr)
+			}
+			if len(r.containers) != 1 {
+				t.Fatalf("runBuildCommand() recorded %d container results, want 1", len(r.containers))
+			}
+			got := r.containers[0]
+			if got.Library != "some-library" || got.Command != "build" || got.Variant != "java11" {
+				t.Errorf("runBuildCommand() recorded results of %s, want some-library build (java11)", got.name())
+			}
+			if _, err := os.Stat(filepath.Join(repo.GetDir(), config.LibrarianDir, config.ContainerResults)); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("runBuildCommand() left the container results in the repository, stat error = %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerationReport_MergeContainerResults(t *testing.T) {
+	t.Parallel()
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "a", PreserveRegex: []string{"a/keep.go"}},
+			{ID: "b"},
+		},
+	}
+	report := &generationReport{
+		Libraries: []*libraryGenerationReport{
+			{
+				ID: "a",
+				Files: []*changedFile{
+					{Path: "a/gen.go", Kind: fileKindGenerated},
+					{Path: "a/built.go", Kind: fileKindHandwritten},
+					{Path: "a/keep.go", Kind: fileKindHandwritten},
+				},
+			},
+			{
+				ID:    "b",
+				Files: []*changedFile{{Path: "b/built.go", Kind: fileKindHandwritten}},
+			},
+		},
+	}
+	containers := []*containerReport{
+		{
+			Library: "a",
+			Command: "build",
+			containerResults: containerResults{
+				GeneratedFiles: []string{"a/built.go", "a/keep.go"},
+				Warnings:       []string{"slow build"},
+			},
+		},
+		{
+			Library: "b",
+			Command: "generate",
+			containerResults: containerResults{
+				GeneratedFiles: []string{"./b/built.go"},
+				Skipped:        []*skippedItem{{Item: "samples", Reason: "no samples"}},
+			},
+		},
+	}
+	if err := report.mergeContainerResults(state, containers); err != nil {
+		t.Fatal(err)
+	}
+	want := []*libraryGenerationReport{
+		{
+			ID: "a",
+			Files: []*changedFile{
+				{Path: "a/gen.go", Kind: fileKindGenerated},
+				{Path: "a/built.go", Kind: fileKindGenerated},
+				{Path: "a/keep.go", Kind: fileKindHandwritten},
+			},
+		},
+		{
+			ID:          "b",
+			CodegenOnly: true,
+			Files:       []*changedFile{{Path: "b/built.go", Kind: fileKindGenerated}},
+		},
+	}
+	if diff := cmp.Diff(want, report.Libraries); diff != "" {
+		t.Errorf("mergeContainerResults() mismatch (-want +got):\n%s", diff)
+	}
+	wantMarkdown := "### Container results\n\n" +
+		"* a build: warning: slow build\n" +
+		"* b generate: skipped `samples`: no samples\n"
+	if got := report.markdown(); !strings.Contains(got, wantMarkdown) {
+		t.Errorf("markdown() = %q, want to contain %q", got, wantMarkdown)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+const bazelBuildFile = "BUILD.bazel"
+
+// apiVersionRegex matches the last element of the path of an API, e.g.
+// "v1", "v2beta" or "v1p1alpha1".
+var apiVersionRegex = regexp.MustCompile(`^v\d+((alpha|beta)\d*)?(p\d+((alpha|beta)\d*)?)?$`)
+
+var cmdDiscoverAPIs = &cli.Command{
+	Short:     "discover-apis lists the APIs of googleapis not covered by any library",
+	UsageLine: "librarian discover-apis [flags]",
+	Long: `Scans the googleapis repository for APIs, and lists the paths of those which
+are not covered by any library in ".librarian/state.yaml" of the language
+repository, one per line.
+
+An API is a versioned directory, such as "google/cloud/functions/v2", which
+contains a "BUILD.bazel" file or a service config, a YAML file with
+"type: google.api.Service".
+
+With "-emit-stubs", an onboarding stub is printed for each uncovered API
+instead: a "libraries" entry of ".librarian/state.yaml" in YAML, ready to be
+merged into the state. The ID and source root of each stub are derived from
+the API path, and may need to be adjusted to the conventions of the
+repository. Alternatively, an API can be onboarded with
+"librarian generate -api=<path> -library=<id>", which runs the configure
+command of the language container.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		return discoverAPIs(os.Stdout, cfg)
+	},
+}
+
+func init() {
+	cmdDiscoverAPIs.Init()
+	fs := cmdDiscoverAPIs.Flags
+	cfg := cmdDiscoverAPIs.Config
+
+	addFlagAPISource(fs, cfg)
+	addFlagAPISourceSSHKey(fs, cfg)
+	addFlagEmitStubs(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+func discoverAPIs(w io.Writer, cfg *config.Config) error {
+	if cfg.APISource == "" {
+		cfg.APISource = defaultAPISource
+	}
+	sourceRepo, err := cloneOrOpenRepo(cfg.WorkRoot, cfg.APISource, cfg.CI, cfg.GitHubToken, apiSourceSSHOptions(cfg), gitTuning(cfg))
+	if err != nil {
+		return err
+	}
+	repoDir := cfg.Repo
+	if isRemote(cfg.Repo) {
+		languageRepo, err := cloneOrOpenRepo(cfg.WorkRoot, cfg.Repo, cfg.CI, cfg.GitHubToken, repoSSHOptions(cfg), gitTuning(cfg))
+		if err != nil {
+			return err
+		}
+		repoDir = languageRepo.GetDir()
+	}
+	state, err := parseLibrarianState(filepath.Join(repoDir, config.LibrarianDir, librarianStateFile), "")
+	if err != nil {
+		return err
+	}
+	apis, err := findAPIs(sourceRepo.GetDir())
+	if err != nil {
+		return err
+	}
+	uncovered := uncoveredAPIs(state, apis)
+	if !cfg.EmitStubs {
+		for _, api := range uncovered {
+			if _, err := fmt.Fprintln(w, api.Path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(uncovered) == 0 {
+		return nil
+	}
+	stubs, err := onboardingStubs(state, uncovered)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(&struct {
+		Libraries []*config.LibraryState `yaml:"libraries"`
+	}{Libraries: stubs})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// findAPIs returns the APIs in the googleapis repository at root, sorted by
+// path. The service config of an API is empty if it has none.
+func findAPIs(root string) ([]*config.API, error) {
+	var apis []*config.API
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !apiVersionRegex.MatchString(d.Name()) {
+			return nil
+		}
+		serviceConfig, err := findServiceConfigIn(path)
+		if err != nil {
+			return err
+		}
+		if serviceConfig == "" {
+			if _, err := os.Stat(filepath.Join(path, bazelBuildFile)); err != nil {
+				return nil
+			}
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		apis = append(apis, &config.API{
+			Path:          filepath.ToSlash(rel),
+			ServiceConfig: serviceConfig,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find APIs: %w", err)
+	}
+	return apis, nil
+}
+
+// uncoveredAPIs returns the APIs which are not covered by any library in
+// state.
+func uncoveredAPIs(state *config.LibrarianState, apis []*config.API) []*config.API {
+	covered := make(map[string]bool)
+	for _, library := range state.Libraries {
+		for _, api := range library.APIs {
+			covered[api.Path] = true
+		}
+	}
+	var uncovered []*config.API
+	for _, api := range apis {
+		if !covered[api.Path] {
+			uncovered = append(uncovered, api)
+		}
+	}
+	return uncovered
+}
+
+// libraryIDForAPI returns the default ID of the library of the API at
+// apiPath, e.g. "google-cloud-foo-v1" for "google/cloud/foo/v1".
+func libraryIDForAPI(apiPath string) string {
+	return strings.ReplaceAll(apiPath, "/", "-")
+}
+
+// onboardingStubs returns a library for each of apis, whose ID and source
+// root are derived from the path of the API, see libraryIDForAPI.
+func onboardingStubs(state *config.LibrarianState, apis []*config.API) ([]*config.LibraryState, error) {
+	var stubs []*config.LibraryState
+	for _, api := range apis {
+		id := libraryIDForAPI(api.Path)
+		if state.LibraryByID(id) != nil || slices.ContainsFunc(stubs, func(l *config.LibraryState) bool { return l.ID == id }) {
+			return nil, fmt.Errorf("library %q of API %s already exists", id, api.Path)
+		}
+		stub := &config.LibraryState{
+			ID:          id,
+			APIs:        []*config.API{{Path: api.Path, ServiceConfig: api.ServiceConfig}},
+			SourceRoots: []string{id},
+		}
+		if err := stub.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid stub for API %s: %w", api.Path, err)
+		}
+		stubs = append(stubs, stub)
+	}
+	return stubs, nil
+}
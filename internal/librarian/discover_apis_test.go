@@ -0,0 +1,153 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestFindAPIs(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	for path, content := range map[string]string{
+		"google/cloud/foo/v1/foo_v1.yaml":       "type: google.api.Service\nname: foo.googleapis.com",
+		"google/cloud/foo/v1/foo.proto":         "",
+		"google/cloud/foo/v2beta/BUILD.bazel":   "",
+		"google/cloud/foo/v1p1alpha1/other.txt": "",
+		"google/type/BUILD.bazel":               "",
+		".git/v1/BUILD.bazel":                   "",
+	} {
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := findAPIs(root)
+	if err != nil {
+		t.Fatalf("findAPIs() error = %v", err)
+	}
+	want := []*config.API{
+		{Path: "google/cloud/foo/v1", ServiceConfig: "foo_v1.yaml"},
+		{Path: "google/cloud/foo/v2beta"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("findAPIs() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiscoverAPIs(t *testing.T) {
+	t.Parallel()
+	apiSource := newTestGitRepo(t).GetDir()
+	for _, dir := range []string{"google/cloud/foo/v1", "google/cloud/bar/v1", "google/cloud/baz/v1"} {
+		if err := os.MkdirAll(filepath.Join(apiSource, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(apiSource, dir, bazelBuildFile), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, apiSource, "add", ".")
+	runGit(t, apiSource, "commit", "-m", "add APIs")
+	repo := t.TempDir()
+	state := &config.LibrarianState{
+		Image: "some/image:v1.2.3",
+		Libraries: []*config.LibraryState{
+			{
+				ID:          "foo",
+				APIs:        []*config.API{{Path: "google/cloud/foo/v1"}},
+				SourceRoots: []string{"foo"},
+			},
+		},
+	}
+	if err := os.MkdirAll(filepath.Join(repo, config.LibrarianDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveLibrarianState(context.Background(), repo, state); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name      string
+		emitStubs bool
+		want      string
+	}{
+		{
+			name: "list",
+			want: "google/cloud/bar/v1\ngoogle/cloud/baz/v1\n",
+		},
+		{
+			name:      "emit stubs",
+			emitStubs: true,
+			want: `libraries:
+    - id: google-cloud-bar-v1
+      version: ""
+      last_generated_commit: ""
+      apis:
+        - path: google/cloud/bar/v1
+          service_config: ""
+      source_roots:
+        - google-cloud-bar-v1
+      preserve_regex: []
+      remove_regex: []
+    - id: google-cloud-baz-v1
+      version: ""
+      last_generated_commit: ""
+      apis:
+        - path: google/cloud/baz/v1
+          service_config: ""
+      source_roots:
+        - google-cloud-baz-v1
+      preserve_regex: []
+      remove_regex: []
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			cfg := &config.Config{
+				APISource: apiSource,
+				EmitStubs: test.emitStubs,
+				Repo:      repo,
+				WorkRoot:  t.TempDir(),
+			}
+			if err := discoverAPIs(&out, cfg); err != nil {
+				t.Fatalf("discoverAPIs() error = %v", err)
+			}
+			if diff := cmp.Diff(test.want, out.String()); diff != "" {
+				t.Errorf("discoverAPIs() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOnboardingStubsExistingLibrary(t *testing.T) {
+	t.Parallel()
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{{ID: "google-cloud-foo-v1", SourceRoots: []string{"foo"}}},
+	}
+	if _, err := onboardingStubs(state, []*config.API{{Path: "google/cloud/foo/v1"}}); err == nil {
+		t.Error("onboardingStubs() error = nil, want error for an existing library")
+	}
+}
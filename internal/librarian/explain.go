@@ -0,0 +1,272 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/conventionalcommits"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/semver"
+	"gopkg.in/yaml.v3"
+)
+
+var cmdExplain = &cli.Command{
+	Short:     "explain prints everything librarian knows about a library",
+	UsageLine: "librarian explain -library=<id> [flags]",
+	Long: `Prints everything librarian knows about the library specified with
+"-library", to debug why it is, or is not, generated or released:
+
+- The entry of the library in ".librarian/state.yaml".
+- The entries of ".librarian/config.yaml" which only apply to the library,
+  such as environment variables and protected files.
+- The APIs of the library. If "-api-source" is specified, the number of proto
+  files found at the path of each API in the API source is printed.
+- The provenance of the last generation: the commit of the API source which
+  the library was last generated from, and the commit of the language
+  repository which recorded it.
+- The commits changing the library since its last release, with the change
+  each contributes to the next version, as classified from their
+  conventional commit messages.
+- The version and tag of the next release.
+
+Nothing is changed, and a local repository does not need to be clean.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		return explain(os.Stdout, cfg)
+	},
+}
+
+func init() {
+	cmdExplain.Init()
+	fs := cmdExplain.Flags
+	cfg := cmdExplain.Config
+
+	addFlagAPISource(fs, cfg)
+	addFlagAPISourceSSHKey(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLibrary(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+func explain(w io.Writer, cfg *config.Config) error {
+	if cfg.Library == "" {
+		return failure.New(failure.UserConfig, errors.New("-library is required"))
+	}
+	repo, err := openRepoForReading(cfg, cfg.Repo, repoSSHOptions(cfg))
+	if err != nil {
+		return err
+	}
+	var sourceDir string
+	if cfg.APISource != "" {
+		source, err := openRepoForReading(cfg, cfg.APISource, apiSourceSSHOptions(cfg))
+		if err != nil {
+			return err
+		}
+		sourceDir = source.GetDir()
+	}
+	state, err := loadRepoState(repo, sourceDir, cfg.Library)
+	if err != nil {
+		return err
+	}
+	lc, err := loadLibrarianConfig(repo)
+	if err != nil {
+		return err
+	}
+	return explainLibrary(w, repo, state, lc, cfg.Library, sourceDir)
+}
+
+// openRepoForReading clones a remote repository into the work root, or opens
+// a local one, which unlike with cloneOrOpenRepo does not need to be clean.
+func openRepoForReading(cfg *config.Config, location string, ssh *gitrepo.SSHOptions) (*gitrepo.LocalRepository, error) {
+	if isRemote(location) {
+		return cloneOrOpenRepo(cfg.WorkRoot, location, cfg.CI, cfg.GitHubToken, ssh, gitTuning(cfg))
+	}
+	dir, err := filepath.Abs(location)
+	if err != nil {
+		return nil, err
+	}
+	return gitrepo.NewRepository(&gitrepo.RepositoryOptions{Dir: dir, CI: cfg.CI})
+}
+
+// explainLibrary writes the description of the library with the given ID to
+// w. sourceDir is the root of the API source, or empty if it is unknown.
+func explainLibrary(w io.Writer, repo gitrepo.Repository, state *config.LibrarianState, lc *config.LibrarianConfig, id, sourceDir string) error {
+	library := state.LibraryByID(id)
+	if library == nil {
+		return failure.New(failure.UserConfig, fmt.Errorf("library %q not found in state", id))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Library %s\n", id)
+
+	data, err := yaml.Marshal(library)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(&b, "\nState:\n%s", indentLines(string(data)))
+
+	b.WriteString("\nConfig overrides:\n")
+	overrides := libraryConfigOverrides(lc, id)
+	if len(overrides) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, override := range overrides {
+		fmt.Fprintf(&b, "  %s\n", override)
+	}
+
+	b.WriteString("\nAPIs:\n")
+	if len(library.APIs) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, api := range library.APIs {
+		fmt.Fprintf(&b, "  %s (service config: %s)", api.Path, cmp.Or(api.ServiceConfig, "none"))
+		if sourceDir != "" {
+			protos, err := filepath.Glob(filepath.Join(sourceDir, api.Path, "*.proto"))
+			if err != nil {
+				return err
+			}
+			if len(protos) == 0 {
+				b.WriteString(": no proto files in API source")
+			} else {
+				fmt.Fprintf(&b, ": %d proto files in API source", len(protos))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\nLast generation:\n")
+	if library.LastGeneratedCommit == "" {
+		b.WriteString("  never generated\n")
+	} else {
+		fmt.Fprintf(&b, "  API source commit: %s\n", library.LastGeneratedCommit)
+		history, err := loadStateHistory(repo)
+		if err != nil {
+			return fmt.Errorf("failed to load history of state.yaml: %w", err)
+		}
+		fmt.Fprintf(&b, "  recorded in commit: %s\n", cmp.Or(history.lastGenerationCommit(id), "none"))
+	}
+
+	if library.Version == "" {
+		b.WriteString("\nPending changes:\n  never released\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	commits, err := GetConventionalCommitsSinceLastRelease(repo, library)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(&b, "\nPending changes since %s:\n", previousReleaseTag(library))
+	if len(commits) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, commit := range commits {
+		change := getHighestChange([]*conventionalcommits.ConventionalCommit{commit})
+		fmt.Fprintf(&b, "  %s %-5s %s\n", shortSHA(commit.SHA), change, commitHeader(commit))
+	}
+
+	b.WriteString("\nNext release:\n")
+	change := getHighestChange(commits)
+	if change == semver.None {
+		b.WriteString("  none, no releasable changes\n")
+	} else {
+		next, err := semver.DeriveNext(change, library.Version)
+		if err != nil {
+			return fmt.Errorf("failed to derive next version of library %s: %w", id, err)
+		}
+		fmt.Fprintf(&b, "  %s -> %s (%s), tag %s\n", library.Version, next, change, formatTag(library, next))
+	}
+	if library.ReleaseGroup != "" {
+		var members []string
+		for _, member := range releaseGroupMembers(state, library) {
+			if member.ID != id {
+				members = append(members, member.ID)
+			}
+		}
+		fmt.Fprintf(&b, "  released together with release group %s: %s\n", library.ReleaseGroup, cmp.Or(strings.Join(members, ", "), "none"))
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// libraryConfigOverrides describes the entries of lc which only apply to the
+// library with the given ID. Values of environment variables are omitted, as
+// they may be secret.
+func libraryConfigOverrides(lc *config.LibrarianConfig, id string) []string {
+	if lc == nil {
+		return nil
+	}
+	var overrides []string
+	for _, variable := range lc.Environment {
+		if !slices.Contains(variable.Libraries, id) {
+			continue
+		}
+		commands := "all commands"
+		if len(variable.Commands) > 0 {
+			commands = strings.Join(variable.Commands, ", ")
+		}
+		overrides = append(overrides, fmt.Sprintf("environment variable %s (%s)", variable.Name, commands))
+	}
+	if lc.ProtectedFiles != nil {
+		for _, library := range lc.ProtectedFiles.Libraries {
+			if library.ID == id {
+				overrides = append(overrides, fmt.Sprintf("protected files (on change: %s): %s",
+					lc.ProtectedFilesAction(), strings.Join(library.Paths, ", ")))
+			}
+		}
+	}
+	return overrides
+}
+
+// commitHeader returns the header of a conventional commit, e.g.
+// "feat(storage)!: add a field".
+func commitHeader(commit *conventionalcommits.ConventionalCommit) string {
+	header := commit.Type
+	if commit.Scope != "" {
+		header += "(" + commit.Scope + ")"
+	}
+	if commit.IsBreaking {
+		header += "!"
+	}
+	return header + ": " + commit.Description
+}
+
+// indentLines indents each line of s by two spaces.
+func indentLines(s string) string {
+	lines := strings.SplitAfter(s, "\n")
+	var b strings.Builder
+	for _, line := range lines {
+		if line != "" {
+			b.WriteString("  " + line)
+		}
+	}
+	return b.String()
+}
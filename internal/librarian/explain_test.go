@@ -0,0 +1,167 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+func TestExplainLibrary(t *testing.T) {
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{
+				ID:                  "a",
+				Version:             "1.2.0",
+				LastGeneratedCommit: "abc123",
+				APIs:                []*config.API{{Path: "google/a/v1", ServiceConfig: "a_v1.yaml"}},
+				SourceRoots:         []string{"a"},
+				ReleaseGroup:        "g",
+			},
+			{
+				ID:           "b",
+				Version:      "1.0.0",
+				SourceRoots:  []string{"b"},
+				ReleaseGroup: "g",
+			},
+		},
+	}
+	lc := &config.LibrarianConfig{
+		Environment: []*config.EnvironmentVariable{
+			{Name: "TOKEN", SecretEnv: "A_TOKEN", Commands: []string{"generate"}, Libraries: []string{"a"}},
+			{Name: "OTHER", Value: "b", Libraries: []string{"b"}},
+		},
+		ProtectedFiles: &config.ProtectedFiles{
+			Libraries: []*config.LibraryProtectedFiles{{ID: "a", Paths: []string{"a/handwritten.go"}}},
+		},
+	}
+	stateYAML := "libraries:\n  - id: a\n    last_generated_commit: abc123\n    source_roots: [a]\n"
+	generation := plumbing.NewHash("1111111111111111111111111111111111111111")
+	repo := &MockRepository{
+		FilesAtHead: map[string]string{".librarian/state.yaml": stateYAML},
+		FilesAtCommit: map[string]map[string]string{
+			generation.String(): {".librarian/state.yaml": stateYAML},
+		},
+		GetCommitsForPathsSinceLastGenValue: []*gitrepo.Commit{{Hash: generation}},
+		GetCommitsForPathsSinceTagValue: []*gitrepo.Commit{
+			{Hash: plumbing.NewHash("2222222222222222222222222222222222222222"), Message: "feat(a): add a method"},
+			{Hash: plumbing.NewHash("3333333333333333333333333333333333333333"), Message: "docs: fix a typo"},
+		},
+		ChangedFilesInCommitValue: []string{"a/client.go"},
+	}
+	sourceDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(sourceDir, "google/a/v1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.proto", "resources.proto", "BUILD.bazel"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, "google/a/v1", name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got strings.Builder
+	if err := explainLibrary(&got, repo, state, lc, "a", sourceDir); err != nil {
+		t.Fatal(err)
+	}
+	want := `Library a
+
+State:
+  id: a
+  version: 1.2.0
+  last_generated_commit: abc123
+  apis:
+      - path: google/a/v1
+        service_config: a_v1.yaml
+  source_roots:
+      - a
+  preserve_regex: []
+  remove_regex: []
+  release_group: g
+
+Config overrides:
+  environment variable TOKEN (generate)
+  protected files (on change: fail): a/handwritten.go
+
+APIs:
+  google/a/v1 (service config: a_v1.yaml): 2 proto files in API source
+
+Last generation:
+  API source commit: abc123
+  recorded in commit: 1111111111111111111111111111111111111111
+
+Pending changes since a-1.2.0:
+  2222222 minor feat(a): add a method
+  3333333 none  docs: fix a typo
+
+Next release:
+  1.2.0 -> 1.3.0 (minor), tag a-1.3.0
+  released together with release group g: b
+`
+	if diff := cmp.Diff(want, got.String()); diff != "" {
+		t.Errorf("explainLibrary() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExplainLibrary_NotReleased(t *testing.T) {
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{{ID: "a", SourceRoots: []string{"a"}}},
+	}
+	var got strings.Builder
+	if err := explainLibrary(&got, &MockRepository{}, state, nil, "a", ""); err != nil {
+		t.Fatal(err)
+	}
+	want := `Library a
+
+State:
+  id: a
+  version: ""
+  last_generated_commit: ""
+  apis: []
+  source_roots:
+      - a
+  preserve_regex: []
+  remove_regex: []
+
+Config overrides:
+  none
+
+APIs:
+  none
+
+Last generation:
+  never generated
+
+Pending changes:
+  never released
+`
+	if diff := cmp.Diff(want, got.String()); diff != "" {
+		t.Errorf("explainLibrary() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExplainLibrary_UnknownLibrary(t *testing.T) {
+	err := explainLibrary(&strings.Builder{}, &MockRepository{}, &config.LibrarianState{}, nil, "a", "")
+	if got := failure.CategoryOf(err); got != failure.UserConfig {
+		t.Errorf("CategoryOf(%v) = %q, want %q", err, got, failure.UserConfig)
+	}
+}
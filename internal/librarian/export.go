@@ -0,0 +1,354 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+const (
+	// exportDir is the directory of the work root into which the archives
+	// are written.
+	exportDir = "export"
+
+	exportProvenanceFile = "provenance.json"
+)
+
+var cmdExport = &cli.Command{
+	Short:     "export packages libraries into versioned archives",
+	UsageLine: "librarian export [flags]",
+	Long: `Packages the generated code of libraries into versioned archives, for
+consumers which ingest code drops rather than pulling from git.
+
+Each library is packaged into "export/{library-id}-{version}.tar.gz" in the
+working directory, or ".zip" with "-archive-format=zip". The slashes of the
+library ID are replaced with underscores, and the short hash of the HEAD commit
+is used in place of the version of an unreleased library. With "-library",
+only the specified library is exported; otherwise, all the libraries in
+".librarian/state.yaml" are.
+
+The files of the archive are in a single "{library-id}-{version}" directory:
+- The files of the source roots of the library, at their paths in the
+  repository.
+- "CHANGELOG.md", the changelog of the first source root of the library, or
+  of the repository if the library has none.
+- "provenance.json", which records the library, its version, the commit of the
+  language repository, the last generated commit of the API source, the
+  generator image and the version of librarian.
+
+Unlike other commands, a local repository does not need to be clean, so that
+local changes can be exported.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		return exportLibraries(cfg)
+	},
+}
+
+func init() {
+	cmdExport.Init()
+	fs := cmdExport.Flags
+	cfg := cmdExport.Config
+
+	addFlagArchiveFormat(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLibrary(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+// exportProvenance records where an exported library comes from.
+type exportProvenance struct {
+	// LibraryID is the ID of the library.
+	LibraryID string `json:"library_id"`
+	// Version is the version of the library in the state.
+	Version string `json:"version,omitempty"`
+	// Repo is the URL of the language repository, if it has an origin remote.
+	Repo string `json:"repo,omitempty"`
+	// Commit is the HEAD commit of the language repository.
+	Commit string `json:"commit"`
+	// LastGeneratedCommit is the commit of the API source which the library
+	// was last generated from.
+	LastGeneratedCommit string `json:"last_generated_commit,omitempty"`
+	// APIs are the paths of the APIs of the library.
+	APIs []string `json:"apis,omitempty"`
+	// SourceRoots are the exported directories.
+	SourceRoots []string `json:"source_roots"`
+	// Image is the generator image of the language repository.
+	Image string `json:"image,omitempty"`
+	// LibrarianVersion is the version of librarian which exported the
+	// library.
+	LibrarianVersion string `json:"librarian_version"`
+	// Exported is when the library was exported.
+	Exported time.Time `json:"exported"`
+}
+
+// archiveEntry is a file of an archive, read from the file at path, or data
+// if path is empty.
+type archiveEntry struct {
+	name string
+	path string
+	data []byte
+	mode fs.FileMode
+}
+
+func exportLibraries(cfg *config.Config) error {
+	repo, err := cloneOrOpenRepo(cfg.WorkRoot, cfg.Repo, cfg.CI, cfg.GitHubToken, repoSSHOptions(cfg), gitTuning(cfg))
+	if err != nil {
+		return err
+	}
+	state, err := loadRepoState(repo, "", cfg.Library)
+	if err != nil {
+		return err
+	}
+	libraries := state.Libraries
+	if cfg.Library != "" {
+		library := state.LibraryByID(cfg.Library)
+		if library == nil {
+			return failure.New(failure.UserConfig, fmt.Errorf("library %q not found in state", cfg.Library))
+		}
+		libraries = []*config.LibraryState{library}
+	}
+	head, err := repo.HeadHash()
+	if err != nil {
+		return err
+	}
+	provenance := &exportProvenance{
+		Commit:           head,
+		Image:            state.Image,
+		LibrarianVersion: cli.Version(),
+		Exported:         now().UTC(),
+	}
+	if remotes, err := repo.Remotes(); err == nil {
+		for _, remote := range remotes {
+			if remote.Config().Name == "origin" && len(remote.Config().URLs) > 0 {
+				provenance.Repo = remote.Config().URLs[0]
+			}
+		}
+	}
+	dir := filepath.Join(cfg.WorkRoot, exportDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+	for _, library := range libraries {
+		path, err := exportLibrary(repo.GetDir(), dir, library, provenance, cmp.Or(cfg.ArchiveFormat, config.ArchiveFormatTarGz))
+		if err != nil {
+			return fmt.Errorf("failed to export library %s: %w", library.ID, err)
+		}
+		slog.Info("Exported library", "library", library.ID, "path", path)
+	}
+	return nil
+}
+
+// exportLibrary writes the archive of library, in the given format, into dir
+// and returns its path. The provenance of the library is completed from
+// common, which describes the repository.
+func exportLibrary(repoDir, dir string, library *config.LibraryState, common *exportProvenance, format string) (string, error) {
+	name := strings.ReplaceAll(library.ID, "/", "_") + "-" + cmp.Or(library.Version, common.Commit[:min(7, len(common.Commit))])
+	entries, err := sourceRootEntries(repoDir, name, library.SourceRoots)
+	if err != nil {
+		return "", err
+	}
+	if changelog := findChangelog(repoDir, library); changelog != "" {
+		entries = append(entries, &archiveEntry{name: path.Join(name, changelogFile), path: changelog, mode: 0644})
+	}
+	provenance := *common
+	provenance.LibraryID = library.ID
+	provenance.Version = library.Version
+	provenance.LastGeneratedCommit = library.LastGeneratedCommit
+	provenance.SourceRoots = library.SourceRoots
+	for _, api := range library.APIs {
+		provenance.APIs = append(provenance.APIs, api.Path)
+	}
+	data, err := json.MarshalIndent(provenance, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	entries = append(entries, &archiveEntry{name: path.Join(name, exportProvenanceFile), data: append(data, '\n'), mode: 0644})
+	slices.SortFunc(entries, func(a, b *archiveEntry) int { return strings.Compare(a.name, b.name) })
+
+	archive := filepath.Join(dir, name+"."+format)
+	f, err := os.Create(archive)
+	if err != nil {
+		return "", err
+	}
+	if format == config.ArchiveFormatZip {
+		err = writeZip(f, entries, provenance.Exported)
+	} else {
+		err = writeTarGz(f, entries, provenance.Exported)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", archive, err)
+	}
+	return archive, nil
+}
+
+// sourceRootEntries returns the archive entries, under the directory name, of
+// the regular files in the source roots of a library in repoDir.
+func sourceRootEntries(repoDir, name string, sourceRoots []string) ([]*archiveEntry, error) {
+	var entries []*archiveEntry
+	for _, root := range sourceRoots {
+		err := filepath.WalkDir(filepath.Join(repoDir, root), func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(repoDir, p)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, &archiveEntry{name: path.Join(name, filepath.ToSlash(rel)), path: p, mode: info.Mode().Perm()})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read source root %s: %w", root, err)
+		}
+	}
+	return entries, nil
+}
+
+// findChangelog returns the path of the changelog of library: the
+// CHANGELOG.md of its first source root, or of the repository. It returns
+// empty if there is none.
+func findChangelog(repoDir string, library *config.LibraryState) string {
+	var candidates []string
+	if len(library.SourceRoots) > 0 {
+		candidates = append(candidates, filepath.Join(repoDir, library.SourceRoots[0], changelogFile))
+	}
+	candidates = append(candidates, filepath.Join(repoDir, changelogFile))
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// open returns the content of the entry.
+func (e *archiveEntry) open() (io.ReadCloser, int64, error) {
+	if e.path == "" {
+		return io.NopCloser(strings.NewReader(string(e.data))), int64(len(e.data)), nil
+	}
+	f, err := os.Open(e.path)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+// writeTarGz writes entries to w as a gzipped tar archive, with the given
+// modification time.
+func writeTarGz(w io.Writer, entries []*archiveEntry, modTime time.Time) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, entry := range entries {
+		r, size, err := entry.open()
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{
+			Name:    entry.name,
+			Mode:    int64(entry.mode),
+			Size:    size,
+			ModTime: modTime,
+			Format:  tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			r.Close()
+			return err
+		}
+		_, err = io.Copy(tw, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// writeZip writes entries to w as a zip archive, with the given modification
+// time.
+func writeZip(w io.Writer, entries []*archiveEntry, modTime time.Time) error {
+	zw := zip.NewWriter(w)
+	for _, entry := range entries {
+		r, _, err := entry.open()
+		if err != nil {
+			return err
+		}
+		header := &zip.FileHeader{
+			Name:     entry.name,
+			Method:   zip.Deflate,
+			Modified: modTime,
+		}
+		header.SetMode(entry.mode)
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			r.Close()
+			return err
+		}
+		_, err = io.Copy(fw, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
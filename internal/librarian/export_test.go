@@ -0,0 +1,174 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestExportLibrary(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		library  *config.LibraryState
+		format   string
+		wantPath string
+		want     map[string]string
+	}{
+		{
+			name: "tar.gz",
+			library: &config.LibraryState{
+				ID:          "google-cloud/pubsub",
+				Version:     "1.2.3",
+				SourceRoots: []string{"pubsub"},
+			},
+			format:   config.ArchiveFormatTarGz,
+			wantPath: "google-cloud_pubsub-1.2.3.tar.gz",
+			want: map[string]string{
+				"google-cloud_pubsub-1.2.3/CHANGELOG.md":        "pubsub changes",
+				"google-cloud_pubsub-1.2.3/pubsub/CHANGELOG.md": "pubsub changes",
+				"google-cloud_pubsub-1.2.3/pubsub/client.go":    "package pubsub",
+				"google-cloud_pubsub-1.2.3/pubsub/v1/v1.go":     "package v1",
+			},
+		},
+		{
+			name: "zip of unreleased library",
+			library: &config.LibraryState{
+				ID:          "storage",
+				SourceRoots: []string{"storage"},
+			},
+			format:   config.ArchiveFormatZip,
+			wantPath: "storage-abcdef1.zip",
+			want: map[string]string{
+				"storage-abcdef1/CHANGELOG.md":       "all changes",
+				"storage-abcdef1/storage/storage.go": "package storage",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repoDir := t.TempDir()
+			for name, content := range map[string]string{
+				"CHANGELOG.md":        "all changes",
+				"pubsub/CHANGELOG.md": "pubsub changes",
+				"pubsub/client.go":    "package pubsub",
+				"pubsub/v1/v1.go":     "package v1",
+				"storage/storage.go":  "package storage",
+			} {
+				if err := writeFile(filepath.Join(repoDir, name), content); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dir := t.TempDir()
+			common := &exportProvenance{Commit: "abcdef123456", Image: "gcr.io/test/image:v1", LibrarianVersion: "1.0.0"}
+			got, err := exportLibrary(repoDir, dir, test.library, common, test.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(dir, test.wantPath); got != want {
+				t.Errorf("exportLibrary() = %q, want %q", got, want)
+			}
+			files := readArchive(t, got, test.format)
+			provenanceName := strings.TrimSuffix(test.wantPath, "."+test.format) + "/" + exportProvenanceFile
+			provenance := &exportProvenance{}
+			if err := json.Unmarshal([]byte(files[provenanceName]), provenance); err != nil {
+				t.Fatalf("invalid %s: %v", provenanceName, err)
+			}
+			delete(files, provenanceName)
+			if diff := cmp.Diff(test.want, files); diff != "" {
+				t.Errorf("archive mismatch (-want +got):\n%s", diff)
+			}
+			wantProvenance := &exportProvenance{
+				LibraryID:        test.library.ID,
+				Version:          test.library.Version,
+				Commit:           "abcdef123456",
+				SourceRoots:      test.library.SourceRoots,
+				Image:            "gcr.io/test/image:v1",
+				LibrarianVersion: "1.0.0",
+			}
+			if diff := cmp.Diff(wantProvenance, provenance); diff != "" {
+				t.Errorf("provenance mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExportLibrary_MissingSourceRoot(t *testing.T) {
+	library := &config.LibraryState{ID: "a", Version: "1.0.0", SourceRoots: []string{"missing"}}
+	if _, err := exportLibrary(t.TempDir(), t.TempDir(), library, &exportProvenance{Commit: "abc"}, config.ArchiveFormatTarGz); err == nil {
+		t.Error("exportLibrary() error = nil, want error")
+	}
+}
+
+// readArchive returns the content of the files in the archive at path, by
+// name.
+func readArchive(t *testing.T, path, format string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	if format == config.ArchiveFormatZip {
+		r, err := zip.OpenReader(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		for _, f := range r.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[f.Name] = string(data)
+		}
+		return files
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = string(data)
+	}
+	return files
+}
@@ -0,0 +1,197 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/failure"
+	"gopkg.in/yaml.v3"
+)
+
+// flagsFileEnvVar is the environment variable holding the path of the flags
+// file, which replaces the default path.
+const flagsFileEnvVar = "LIBRARIAN_FLAGS_FILE"
+
+// flagSource is the layer which the value of a flag comes from. The layers
+// are, from the lowest precedence to the highest: the default of the flag,
+// the flags file, the environment variables, and the command line.
+type flagSource string
+
+const (
+	flagSourceDefault flagSource = "default"
+	flagSourceFile    flagSource = "file"
+	flagSourceEnv     flagSource = "env"
+	flagSourceFlag    flagSource = "flag"
+)
+
+// flagOrigin is where the effective value of a flag comes from.
+type flagOrigin struct {
+	Source flagSource
+	// Detail locates the value within its source: the environment variable,
+	// or the path of the flags file.
+	Detail string
+}
+
+// String returns the source of o, followed by its detail, if any.
+func (o flagOrigin) String() string {
+	if o.Detail == "" {
+		return string(o.Source)
+	}
+	return fmt.Sprintf("%s %s", o.Source, o.Detail)
+}
+
+// flagsFile is the YAML file of the flag values of a user or a machine, e.g.
+// the registry mirror of a CI environment. Values are keyed by the names of
+// the flags, without the leading dash.
+type flagsFile struct {
+	// Flags are the values of the flags of all the commands which have them.
+	Flags map[string]string `yaml:"flags,omitempty"`
+	// Commands are the values of the flags of a single command, keyed by the
+	// name of the command, e.g. "generate". They take precedence over Flags.
+	Commands map[string]map[string]string `yaml:"commands,omitempty"`
+}
+
+// flagsFilePath returns the path of the flags file: the value of
+// LIBRARIAN_FLAGS_FILE, or librarian/flags.yaml in the user config directory,
+// e.g. ~/.config/librarian/flags.yaml on Linux. It is empty if there is no
+// user config directory.
+func flagsFilePath() string {
+	if path := os.Getenv(flagsFileEnvVar); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "librarian", "flags.yaml")
+}
+
+// loadFlagsFile reads the flags file at path, or returns nil if there is
+// none.
+func loadFlagsFile(path string) (*flagsFile, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("failed to read flags file: %w", err))
+	}
+	var file flagsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("failed to parse flags file %s: %w", path, err))
+	}
+	return &file, nil
+}
+
+// flagEnvVar returns the environment variable which sets the flag with the
+// given name, e.g. LIBRARIAN_REGISTRY_MIRROR for -registry-mirror.
+func flagEnvVar(name string) string {
+	return "LIBRARIAN_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// flagLayers are the sources of the flag values which are not specified on
+// the command line.
+type flagLayers struct {
+	lookupEnv func(string) (string, bool)
+	file      *flagsFile
+	filePath  string
+}
+
+// newFlagLayers returns the layers of the environment of the process and of
+// its flags file.
+func newFlagLayers() (*flagLayers, error) {
+	path := flagsFilePath()
+	file, err := loadFlagsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &flagLayers{lookupEnv: os.LookupEnv, file: file, filePath: path}, nil
+}
+
+// parse parses the command line args of cmd, then sets each flag which args
+// do not specify from its non-empty environment variable, or else from the
+// flags file.
+// It returns the origin of the value of each flag of cmd.
+func (l *flagLayers) parse(cmd *cli.Command, args []string) (map[string]flagOrigin, error) {
+	if err := cmd.Parse(args); err != nil {
+		return nil, err
+	}
+	origins := make(map[string]flagOrigin)
+	cmd.Flags.Visit(func(f *flag.Flag) {
+		origins[f.Name] = flagOrigin{Source: flagSourceFlag}
+	})
+	if l.file != nil {
+		for name := range l.file.Commands[cmd.Name()] {
+			if cmd.Flags.Lookup(name) == nil {
+				return nil, failure.New(failure.UserConfig, fmt.Errorf("flags file %s: command %s has no flag -%s", l.filePath, cmd.Name(), name))
+			}
+		}
+	}
+	fileValues := l.fileValues(cmd)
+	var errs []error
+	cmd.Flags.VisitAll(func(f *flag.Flag) {
+		if _, ok := origins[f.Name]; ok {
+			return
+		}
+		var origin flagOrigin
+		// Empty environment variables are ignored, as CI systems often
+		// define the variables which they do not set.
+		value, ok := l.lookupEnv(flagEnvVar(f.Name))
+		if ok && value != "" {
+			origin = flagOrigin{Source: flagSourceEnv, Detail: flagEnvVar(f.Name)}
+		} else if value, ok = fileValues[f.Name]; ok {
+			origin = flagOrigin{Source: flagSourceFile, Detail: l.filePath}
+		} else {
+			origins[f.Name] = flagOrigin{Source: flagSourceDefault}
+			return
+		}
+		if err := cmd.Flags.Set(f.Name, value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value %q for flag -%s from %s: %w", value, f.Name, origin, err))
+			return
+		}
+		origins[f.Name] = origin
+	})
+	if len(errs) > 0 {
+		return nil, failure.New(failure.UserConfig, errors.Join(errs...))
+	}
+	return origins, nil
+}
+
+// fileValues returns the values of the flags file for cmd, the values for
+// all the commands overlaid with those for cmd.
+func (l *flagLayers) fileValues(cmd *cli.Command) map[string]string {
+	values := make(map[string]string)
+	if l.file == nil {
+		return values
+	}
+	for name, value := range l.file.Flags {
+		values[name] = value
+	}
+	for name, value := range l.file.Commands[cmd.Name()] {
+		values[name] = value
+	}
+	return values
+}
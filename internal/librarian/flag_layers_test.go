@@ -0,0 +1,179 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+// newFlagLayersTestCommand returns a command with the -repo,
+// -registry-mirror, -push and -max-concurrency flags.
+func newFlagLayersTestCommand() *cli.Command {
+	cmd := (&cli.Command{
+		Short:     "generate generates",
+		UsageLine: "librarian generate",
+		Long:      "Generates.",
+	}).Init()
+	addFlagMaxConcurrency(cmd.Flags, cmd.Config)
+	addFlagPush(cmd.Flags, cmd.Config)
+	addFlagRegistryMirror(cmd.Flags, cmd.Config)
+	addFlagRepo(cmd.Flags, cmd.Config)
+	return cmd
+}
+
+func TestFlagLayers(t *testing.T) {
+	t.Parallel()
+	env := map[string]string{
+		"LIBRARIAN_REGISTRY_MIRROR": "env.mirror.example.com",
+		"LIBRARIAN_REPO":            "/env/repo",
+		"LIBRARIAN_PUSH":            "",
+	}
+	layers := &flagLayers{
+		lookupEnv: func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		},
+		file: &flagsFile{
+			Flags: map[string]string{
+				"registry-mirror": "file.mirror.example.com",
+				"max-concurrency": "2",
+				"push":            "true",
+				"unknown":         "ignored",
+			},
+			Commands: map[string]map[string]string{
+				"generate": {"max-concurrency": "4"},
+				"status":   {"output-format": "json"},
+			},
+		},
+		filePath: "/home/user/.config/librarian/flags.yaml",
+	}
+	cmd := newFlagLayersTestCommand()
+	got, err := layers.parse(cmd, []string{"-repo=/flag/repo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := flagOrigin{Source: flagSourceFile, Detail: layers.filePath}
+	want := map[string]flagOrigin{
+		"repo":            {Source: flagSourceFlag},
+		"registry-mirror": {Source: flagSourceEnv, Detail: "LIBRARIAN_REGISTRY_MIRROR"},
+		"max-concurrency": file,
+		"push":            file,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parse() origins mismatch (-want +got):\n%s", diff)
+	}
+	if cmd.Config.Repo != "/flag/repo" {
+		t.Errorf("parse() repo = %q, want %q", cmd.Config.Repo, "/flag/repo")
+	}
+	if cmd.Config.RegistryMirror != "env.mirror.example.com" {
+		t.Errorf("parse() registry mirror = %q, want %q", cmd.Config.RegistryMirror, "env.mirror.example.com")
+	}
+	if cmd.Config.MaxConcurrency != 4 {
+		t.Errorf("parse() max concurrency = %d, want 4", cmd.Config.MaxConcurrency)
+	}
+	if !cmd.Config.Push {
+		t.Error("parse() push = false, want true")
+	}
+}
+
+func TestFlagLayers_Errors(t *testing.T) {
+	t.Parallel()
+	noEnv := func(string) (string, bool) { return "", false }
+	for _, test := range []struct {
+		name   string
+		layers *flagLayers
+	}{
+		{
+			name: "invalid env value",
+			layers: &flagLayers{
+				lookupEnv: func(name string) (string, bool) {
+					return "many", name == "LIBRARIAN_MAX_CONCURRENCY"
+				},
+			},
+		},
+		{
+			name: "invalid file value",
+			layers: &flagLayers{
+				lookupEnv: noEnv,
+				file:      &flagsFile{Flags: map[string]string{"push": "maybe"}},
+			},
+		},
+		{
+			name: "unknown flag of command",
+			layers: &flagLayers{
+				lookupEnv: noEnv,
+				file:      &flagsFile{Commands: map[string]map[string]string{"generate": {"library": "foo"}}},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := test.layers.parse(newFlagLayersTestCommand(), nil); failure.CategoryOf(err) != failure.UserConfig {
+				t.Errorf("parse() error = %v, want %q error", err, failure.UserConfig)
+			}
+		})
+	}
+}
+
+func TestLoadFlagsFile(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	got, err := loadFlagsFile(filepath.Join(dir, "missing.yaml"))
+	if err != nil || got != nil {
+		t.Errorf("loadFlagsFile() of a missing file = %v, %v, want nil, nil", got, err)
+	}
+	path := filepath.Join(dir, "flags.yaml")
+	if err := os.WriteFile(path, []byte("flags:\n  registry-mirror: mirror.gcr.io\ncommands:\n  generate:\n    max-concurrency: 4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err = loadFlagsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &flagsFile{
+		Flags:    map[string]string{"registry-mirror": "mirror.gcr.io"},
+		Commands: map[string]map[string]string{"generate": {"max-concurrency": "4"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("loadFlagsFile() mismatch (-want +got):\n%s", diff)
+	}
+	if err := os.WriteFile(path, []byte("flags: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadFlagsFile(path); failure.CategoryOf(err) != failure.UserConfig {
+		t.Errorf("loadFlagsFile() of an invalid file error = %v, want %q error", err, failure.UserConfig)
+	}
+}
+
+func TestFlagOrigin_String(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		origin flagOrigin
+		want   string
+	}{
+		{origin: flagOrigin{Source: flagSourceDefault}, want: "default"},
+		{origin: flagOrigin{Source: flagSourceEnv, Detail: "LIBRARIAN_REPO"}, want: "env LIBRARIAN_REPO"},
+	} {
+		if got := test.origin.String(); got != test.want {
+			t.Errorf("String() = %q, want %q", got, test.want)
+		}
+	}
+}
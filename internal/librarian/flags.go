@@ -16,26 +16,170 @@ package librarian
 
 import (
 	"flag"
+	"strconv"
+	"time"
 
 	"github.com/googleapis/librarian/internal/config"
 )
 
+func addFlagAllowedRepos(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.AllowedRepos, "allowed-repos", "", "a comma-separated list of the repositories, as accepted by -repo, which work requests may run on")
+}
+
 func addFlagAPI(fs *flag.FlagSet, cfg *config.Config) {
 	fs.StringVar(&cfg.API, "api", "", "path to the API to be configured/generated (e.g., google/cloud/functions/v2)")
 }
 
+func addFlagAPIPath(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.API, "api-path", "", "path to the API to propose a client library for (e.g., google/cloud/functions/v2)")
+}
+
+func addFlagAPIPathFilter(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.APIPathFilter, "api-path-filter", "", "a comma-separated list of globs of API paths selecting the libraries to regenerate, e.g. google/cloud/aiplatform/**,!**/v1beta1. Globs prefixed with ! exclude the libraries with a matching API.")
+}
+
 func addFlagAPISource(fs *flag.FlagSet, cfg *config.Config) {
 	fs.StringVar(&cfg.APISource, "api-source", "", "location of googleapis repository. If undefined, googleapis will be cloned to the output")
 }
 
+func addFlagAPISourceFullClone(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.APISourceFullClone, "api-source-full-clone", false, "check out every file of a cloned -api-source, instead of only the APIs of the libraries and their dependencies. Use it when the APIs import protos outside of the known dependencies.")
+}
+
+func addFlagAPISourceSSHKey(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.APISourceSSHKey, "api-source-ssh-key", "", "the path of the private key used to authenticate with the API source repository, when -api-source is an SSH URL. Defaults to the keys of the SSH agent.")
+}
+
+func addFlagAPIRef(fs *flag.FlagSet, cfg *config.Config) {
+	const usage = "a commit hash, tag or branch of -api-source to generate from instead of its HEAD. The API source is checked out in the working directory, and every API to generate must exist at the ref."
+	fs.StringVar(&cfg.APIRef, "api-ref", "", usage)
+	fs.StringVar(&cfg.APIRef, "api-commit", "", "alias of -api-ref.")
+}
+
+func addFlagAPIRootAllowDirty(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.APIRootAllowDirty, "api-root-allow-dirty", false, "allow generating from a local -api-source with uncommitted changes. The working tree is snapshotted into the working directory before generation.")
+}
+
+func addFlagArchiveFormat(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ArchiveFormat, "archive-format", config.ArchiveFormatTarGz, "the format of the written archives: tar.gz or zip")
+}
+
+func addFlagArtifactsInclude(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ArtifactsInclude, "artifacts-include", "", "a comma-separated list of globs, relative to the working directory, of the artifacts to upload to -artifacts-url. Defaults to the files at the root of the working directory and the output and logs directories.")
+}
+
+func addFlagArtifactsRetention(fs *flag.FlagSet, cfg *config.Config) {
+	fs.DurationVar(&cfg.ArtifactsRetention, "artifacts-retention", 0, "how long to keep artifacts under -artifacts-url, e.g. 720h. The artifacts of older runs are deleted. Defaults to keeping them forever.")
+}
+
+func addFlagArtifactsURL(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ArtifactsURL, "artifacts-url", "", "a Cloud Storage location, gs://bucket[/prefix], to upload the artifacts of the run to, keyed by run and library")
+}
+
+func addFlagAttachSBOM(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.AttachSBOM, "attach-sbom", false, "whether to attach a CycloneDX SBOM of each released library to its GitHub release")
+}
+
+func addFlagAuditLog(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.AuditLog, "audit-log", "", "a file, or a Cloud Storage location gs://bucket[/prefix], to which to append a record of every mutating action of the run, such as commits, pull requests, releases and state changes")
+}
+
+func addFlagBug(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.Bug, "bug", "", "the URL or ID of the bug tracking why the generation of the library is blocked")
+}
+
 func addFlagBuild(fs *flag.FlagSet, cfg *config.Config) {
 	fs.BoolVar(&cfg.Build, "build", false, "whether to build the generated code")
 }
 
+func addFlagChannel(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.Channel, "channel", config.ChannelStable, "the release channel: stable, or canary to release prerelease versions which are tracked apart from the stable versions")
+}
+
+func addFlagCheck(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.Check, "check", false, "check the release manifest for a newer version of librarian")
+}
+
+func addFlagCleanWorkRoot(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.CleanWorkRoot, "clean-work-root", false, "whether to remove the working directory created in /tmp at the end of a successful run. A directory specified with -output is never removed.")
+}
+
+func addFlagComment(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.Comment, "comment", "", "the body of the pull request comment to handle, e.g. \"/librarian regenerate\"")
+}
+
+func addFlagCommentAuthor(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.CommentAuthor, "comment-author", "", "the GitHub login of the author of the comment specified with -comment")
+}
+
 func addFlagCommit(fs *flag.FlagSet, cfg *config.Config) {
 	fs.BoolVar(&cfg.Commit, "commit", false, "whether to create a commit for a release")
 }
 
+func addFlagContainerBackend(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ContainerBackend, "container-backend", config.ContainerBackendDocker, "where language containers run: docker, with the local Docker daemon, or kubernetes, as Kubernetes Jobs configured in the kubernetes section of .librarian/config.yaml")
+}
+
+func addFlagContainerLogLevel(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ContainerLogLevel, "container-log-level", "info", "the level at which the output of containers is logged: debug, info, warn or error. The output is always written to log files in the logs directory of the working directory.")
+}
+
+func addFlagContainerMounts(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ContainerMounts, "container-mounts", "", "a comma-separated list of extra host directories to mount into language containers, e.g. caches, in the format {host-dir}:{container-dir}[:ro|:rw]")
+}
+
+func addFlagContainerRecord(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ContainerRecord, "container-record", "", "a directory to record every container run into, including its inputs and the files it changed, for replaying with -container-replay. With bundle-debug, the recorded directory to include.")
+}
+
+func addFlagContainerReplay(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ContainerReplay, "container-replay", "", "a directory recorded with -container-record. Container runs are replayed from the recording instead of running Docker.")
+}
+
+func addFlagDebugShell(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.DebugShell, "debug-shell", "", "what to do when a container phase fails: \"interactive\" opens a shell in a container with the same image, mounts and environment, and \"print\" prints the docker commands to do so")
+}
+
+func addFlagDryRun(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.DryRun, "dry-run", false, "whether to only report what would be removed or pushed, without changing anything")
+}
+
+func addFlagEmitStubs(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.EmitStubs, "emit-stubs", false, "whether to print onboarding stubs, entries of state.yaml, for the uncovered APIs instead of their paths")
+}
+
+func addFlagErrorFormat(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ErrorFormat, "error-format", config.ErrorFormatText, "the format in which to write the error of a failed run to stdout: text or json. With json, the failure category, exit code and message are written as a JSON object.")
+}
+
+func addFlagFileIssues(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.FileIssues, "file-issues", false, "whether to file a GitHub issue for each stale library, or library whose APIs were removed, unless an open issue for it exists")
+}
+
+func addFlagFix(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.Fix, "fix", false, "whether to update the versions in the state to the latest tags, and create missing GitHub releases of existing tags")
+}
+
+func addFlagForceRegenerate(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.ForceRegenerate, "force-regenerate", false, "whether to update the regeneration markers of the libraries and commit the regeneration, even if it changes nothing else")
+}
+
+func addFlagGeneratorSource(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.GeneratorSource, "generator-source", "", "a local directory of the generator source to mount read-only into language containers at /generator")
+}
+
+func addFlagGitHubAPIURL(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.GitHubAPIURL, "github-api-url", cfg.GitHubAPIURL, "the base URL of the REST API of a GitHub Enterprise Server instance, e.g. https://github.example.com/api/v3/. Defaults to the LIBRARIAN_GITHUB_API_URL environment variable, or github.com if not set.")
+}
+
+func addFlagGitHubUploadURL(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.GitHubUploadURL, "github-upload-url", cfg.GitHubUploadURL, "the base URL for uploads of a GitHub Enterprise Server instance, e.g. https://github.example.com/api/uploads/. Defaults to the LIBRARIAN_GITHUB_UPLOAD_URL environment variable, or -github-api-url if not set.")
+}
+
+func addFlagGitTuning(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.GitTuning, "git-tuning", "", "the performance settings of the clones of remote repositories, as a comma-separated list of fetch-jobs=N, checkout-workers=N, fsmonitor=BOOL and commit-graph=BOOL overriding the defaults, or \"off\" to clone with go-git")
+}
+
 func addFlagHostMount(fs *flag.FlagSet, cfg *config.Config) {
 	defaultValue := ""
 	fs.StringVar(&cfg.HostMount, "host-mount", defaultValue, "a mount point from Docker host and within the Docker. The format is {host-dir}:{local-dir}.")
@@ -45,6 +189,26 @@ func addFlagImage(fs *flag.FlagSet, cfg *config.Config) {
 	fs.StringVar(&cfg.Image, "image", "", "Container image to run for subcommands. Defaults to the image in the pipeline state.")
 }
 
+func addFlagImageArchive(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ImageArchive, "image-archive", "", "a tarball of container images, as written by docker save, to load before running the containers")
+}
+
+func addFlagImageLocal(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.ImageLocal, "image-local", false, "use local, unreleased builds of the container images: they are never pulled, must exist locally, and are recorded as local-dev")
+}
+
+func addFlagImagePlatform(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ImagePlatform, "image-platform", "", "the platform of the container images to run, e.g. linux/amd64. Defaults to the platform of this machine, if the images are available for it")
+}
+
+func addFlagKeepLast(fs *flag.FlagSet, cfg *config.Config) {
+	fs.IntVar(&cfg.KeepLast, "keep-last", 0, "the number of most recent working directories to keep, regardless of their age")
+}
+
+func addFlagLanguage(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.Language, "language", "", "the language of the repository, e.g. go or python.")
+}
+
 func addFlagLibrary(fs *flag.FlagSet, cfg *config.Config) {
 	fs.StringVar(&cfg.Library, "library", "", "The ID of a single library to update. This is repo-specific and defined in the state.yaml")
 }
@@ -53,24 +217,180 @@ func addFlagLibraryVersion(fs *flag.FlagSet, cfg *config.Config) {
 	fs.StringVar(&cfg.LibraryVersion, "library-version", "", "the library version to release. Requires the --library flag to be specified.")
 }
 
+func addFlagListen(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.Listen, "listen", "localhost:8080", "the TCP address on which to accept work requests, e.g. :8080 to accept them on all interfaces")
+}
+
+func addFlagLogCommands(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.LogCommands, "log-commands", false, "log every external command run, such as git, docker and kubectl, with its arguments and the environment variables it sets, with secrets redacted")
+}
+
+func addFlagMaxConcurrency(fs *flag.FlagSet, cfg *config.Config) {
+	fs.IntVar(&cfg.MaxConcurrency, "max-concurrency", 4, "the maximum number of work requests run at the same time, across all repositories. 0 means no limit.")
+}
+
+func addFlagMaxReleaseFiles(fs *flag.FlagSet, cfg *config.Config) {
+	fs.IntVar(&cfg.MaxReleaseFiles, "max-release-files", 3000, "the maximum number of files changed by a release pull request. Larger releases are split into multiple pull requests. 0 means no limit.")
+}
+
+func addFlagMaxReleaseLibraries(fs *flag.FlagSet, cfg *config.Config) {
+	fs.IntVar(&cfg.MaxReleaseLibraries, "max-release-libraries", 100, "the maximum number of libraries released by a release pull request. Larger releases are split into multiple pull requests. 0 means no limit.")
+}
+
+func addFlagMergeQueueTimeout(fs *flag.FlagSet, cfg *config.Config) {
+	fs.DurationVar(&cfg.MergeQueueTimeout, "merge-queue-timeout", time.Hour, "how long to wait for a release pull request in the merge queue to be merged, e.g. 30m. 0 means not waiting.")
+}
+
+func addFlagMetricsDir(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.MetricsDir, "metrics-dir", "", "the directory into which to write the metrics of the run, such as the duration of its phases, as a JSON file")
+}
+
+func addFlagMirror(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.Mirror, "mirror", "", "the name of the mirror of config.yaml to reconcile; all mirrors are reconciled if empty")
+}
+
+func addFlagNativeImage(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.NativeImage, "native-image", false, "fail instead of running container images under emulation, when they are not available for the platform of this machine")
+}
+
+func addFlagNewLibraryID(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.NewLibraryID, "new-library-id", "", "the ID to rename the library specified with -library to")
+}
+
+func addFlagNewSourceRoots(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.NewSourceRoots, "new-source-roots", "", "a comma-separated list of the paths to move the source roots of the library specified with -library to, in the order of its source roots in the state")
+}
+
+func addFlagOlderThan(fs *flag.FlagSet, cfg *config.Config) {
+	fs.DurationVar(&cfg.OlderThan, "older-than", 7*24*time.Hour, "the minimum age of the working directories to remove, e.g. 24h")
+}
+
+func addFlagOffline(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.Offline, "offline", false, "run without network access: -repo and -api-source are local directories or git bundles, images are never pulled, and commits are written as a git bundle and patches to the offline-output directory of the work root instead of being pushed")
+}
+
+func addFlagOutputFormat(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.OutputFormat, "output-format", config.OutputFormatText, "the format of the output: text or json")
+}
+
+func addFlagOverrideFreeze(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.OverrideFreeze, "override-freeze", "", "the bug, e.g. b/12345, justifying a release during an active freeze of the release policy, which blocks release pull requests and tagging otherwise")
+}
+
 func addFlagPR(fs *flag.FlagSet, cfg *config.Config) {
-	fs.StringVar(&cfg.PullRequest, "pr", "", "a pull request to operate on. It should be in the format of a uri https://github.com/{owner}/{repo}/pull/{number}. If not specified, will search for all merged pull requests with the label `release:pending` in the last 30 days.")
+	fs.StringVar(&cfg.PullRequest, "pr", "", "a pull request to operate on. It should be in the format of a uri https://github.com/{owner}/{repo}/pull/{number}. If not specified, tag-and-release searches for all merged pull requests with the label `release:pending` in the last 30 days, refresh-release-pr for the open release pull request, and handle-comment requires it.")
+}
+
+func addFlagParallel(fs *flag.FlagSet, cfg *config.Config) {
+	fs.IntVar(&cfg.Parallel, "parallel", 1, "the number of libraries regenerated at the same time. With more than one, each library is generated in its own git worktree of the language repository, on the branch librarian-generate-{id}, which is kept after the run.")
+}
+
+func addFlagPhases(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.Phases, "phases", "", "a comma-separated list of the container phases to run for each library, in the order configure, generate, build and test. Defaults to configure and generate, and build if -build is specified.")
+}
+
+func addFlagProfile(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.Profile, "profile", "", "a comma-separated list of profiles of the librarian process to write into the working directory: cpu (cpu.pprof), mem (mem.pprof) and trace (trace.out).")
+}
+
+func addFlagPubSubAudience(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.PubSubAudience, "pubsub-audience", "", "the audience of the OIDC tokens of the Pub/Sub push subscription, e.g. the URL of the push endpoint. Pub/Sub messages are rejected if not set.")
+}
+
+func addFlagPubSubServiceAccount(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.PubSubServiceAccount, "pubsub-service-account", "", "the email of the service account of the OIDC tokens of the Pub/Sub push subscription")
 }
 
 func addFlagPush(fs *flag.FlagSet, cfg *config.Config) {
 	fs.BoolVar(&cfg.Push, "push", false, "whether to push the generated code")
 }
 
+func addFlagReason(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.Reason, "reason", "", "why the generation of the library is blocked")
+}
+
+func addFlagRegistryMirror(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.RegistryMirror, "registry-mirror", "", "a registry to pull container images through, replacing the registry host of the image, e.g. mirror.gcr.io or us-docker.pkg.dev/{project}/{repo}.")
+}
+
+func addFlagReleaseArtifacts(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ReleaseArtifacts, "release-artifacts", "", "a directory with the built artifacts of the released libraries, in a subdirectory per library ID. release sign-artifacts signs them as configured by artifact_signing in .librarian/config.yaml, and release tag-and-release uploads them with their signatures to the GitHub release of each library.")
+}
+
+func addFlagReleaseManifest(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ReleaseManifest, "release-manifest", "", "the URL, or the path, of the manifest of the releases of librarian")
+}
+
+func addFlagReleasePublicKey(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ReleasePublicKey, "release-public-key", "", "the base64-encoded Ed25519 public key which verifies the signatures of the downloaded librarian binaries. If empty, only their checksums are verified.")
+}
+
+func addFlagReportFailures(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.ReportFailures, "report-failures", false, "whether to file a GitHub issue on the language repository when the command fails. Requires a GitHub token.")
+}
+
+func addFlagSandbox(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.Sandbox, "sandbox", false, "whether to run the generate container without network access, with a read-only root filesystem and without capabilities.")
+}
+
 func addFlagRepo(fs *flag.FlagSet, cfg *config.Config) {
 	fs.StringVar(&cfg.Repo, "repo", "",
 		`Code repository where the generated code will reside.
 			Can be a remote in the format of a remote URL such as 
-			https://github.com/{owner}/{repo} or a local file path like 
+			https://github.com/{owner}/{repo} or git@github.com:{owner}/{repo}.git,
+			or a local file path like 
 			/path/to/repo. Both absolute and relative paths are supported.
 			If not specified, will try to detect if the current working 
 			directory is configured as a language repository.`)
 }
 
+func addFlagRetryFailedFrom(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.RetryFailedFrom, "retry-failed-from", "", "the path of the generation-report.json of a previous run. Only the libraries which failed in that run are generated, from the same API source commit.")
+}
+
+func addFlagReview(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.Review, "review", false, "interactively accept or reject the regenerated changes to handwritten and manually edited files before committing. The decisions are recorded for the following runs.")
+}
+
+func addFlagServiceConfig(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.ServiceConfig, "service-config", "", "the path of the service config YAML of the API to generate. The -api path, and the -library ID of a new library, are derived from it if not specified.")
+}
+
+func addFlagSince(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.Since, "since", "", "a commit hash or tag: consider the commits since it for every library, instead of the commits since the last release of each library")
+}
+
+func addFlagSSHKey(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.SSHKey, "ssh-key", "", "the path of the private key, e.g. a deploy key, used to authenticate with the language repository when its remote is an SSH URL. Defaults to the keys of the SSH agent.")
+}
+
+func addFlagSSHKnownHosts(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.SSHKnownHosts, "ssh-known-hosts", "", "the path of the known_hosts file used to verify the host keys of SSH remotes. Defaults to ~/.ssh/known_hosts.")
+}
+
+func addFlagStaleAfter(fs *flag.FlagSet, cfg *config.Config) {
+	fs.DurationVar(&cfg.StaleAfter, "stale-after", defaultStaleAfter, "the age of the last generated API source commit of a library after which it is stale, e.g. 720h")
+}
+
+func addFlagVerbosity(fs *flag.FlagSet, cfg *config.Config) {
+	// -v and -vv raise the verbosity, regardless of their order.
+	verbosity := func(level int) func(string) error {
+		return func(value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if enabled {
+				cfg.Verbosity = max(cfg.Verbosity, level)
+			}
+			return err
+		}
+	}
+	fs.BoolFunc("v", "log debug messages", verbosity(1))
+	fs.BoolFunc("vv", "log debug messages, the source location of each message, and every external command as with -log-commands", verbosity(2))
+}
+
+func addFlagVerify(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&cfg.Verify, "verify", false, "whether to check that the files are up to date, instead of writing them")
+}
+
 func addFlagWorkRoot(fs *flag.FlagSet, cfg *config.Config) {
 	fs.StringVar(&cfg.WorkRoot, "output", "", "Working directory root. When this is not specified, a working directory will be created in /tmp.")
 }
@@ -0,0 +1,101 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// forceRegenerateMessage is the message of the commit of a forced
+// regeneration which changes nothing but the regeneration markers.
+const forceRegenerateMessage = "chore: regenerate"
+
+// regenerationMarker is the content of the regeneration marker of a library,
+// which records the provenance of its last forced regeneration.
+type regenerationMarker struct {
+	Library         string `yaml:"library"`
+	Image           string `yaml:"image"`
+	APISourceCommit string `yaml:"api_source_commit,omitempty"`
+	// LibrarianVersion is the version of librarian which regenerated the
+	// library.
+	LibrarianVersion string `yaml:"librarian_version"`
+	RegeneratedAt    string `yaml:"regenerated_at"`
+}
+
+// writeRegenerationMarkers writes the regeneration markers of the libraries
+// with the given IDs, for -force-regenerate.
+func (r *generateRunner) writeRegenerationMarkers(libraryIDs []string) error {
+	commit, dirty, err := r.generatedFrom()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		// Like the last generated commit, the commit is not recorded for
+		// uncommitted changes of the API source.
+		commit = ""
+	}
+	for _, id := range libraryIDs {
+		library := findLibraryByID(r.state, id)
+		if library == nil {
+			continue
+		}
+		data, err := yaml.Marshal(&regenerationMarker{
+			Library:          id,
+			Image:            r.generatorImage(library),
+			APISourceCommit:  commit,
+			LibrarianVersion: cli.Version(),
+			RegeneratedAt:    now().UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+		path := r.librarianConfig.RegenerationMarkerPath(library)
+		if err := writeFile(filepath.Join(r.repo.GetDir(), filepath.FromSlash(path)), string(data)); err != nil {
+			return fmt.Errorf("failed to write regeneration marker of library %s: %w", id, err)
+		}
+		slog.Info("Wrote regeneration marker", "library", id, "path", path)
+	}
+	return nil
+}
+
+// changesOnlyMarkers reports whether the changes in report are only those of
+// the regeneration markers of the libraries with the given IDs, and of the
+// files in the .librarian directory, such as the state.
+func (r *generateRunner) changesOnlyMarkers(report *generationReport, libraryIDs []string) bool {
+	markers := make(map[string]bool)
+	for _, id := range libraryIDs {
+		if library := findLibraryByID(r.state, id); library != nil {
+			markers[r.librarianConfig.RegenerationMarkerPath(library)] = true
+		}
+	}
+	var files []*changedFile
+	for _, library := range report.Libraries {
+		files = append(files, library.Files...)
+	}
+	for _, file := range append(files, report.Other...) {
+		if !markers[file.Path] && !strings.HasPrefix(file.Path, config.LibrarianDir+"/") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func TestWriteRegenerationMarkers(t *testing.T) {
+	fixed := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	original := now
+	now = func() time.Time { return fixed }
+	t.Cleanup(func() { now = original })
+
+	repo := newTestGitRepo(t)
+	r := &generateRunner{
+		cfg:  &config.Config{},
+		repo: repo,
+		state: &config.LibrarianState{
+			Image: "gcr.io/test/image:v1.2.3",
+			Libraries: []*config.LibraryState{
+				{ID: "lib1", SourceRoots: []string{"src/a"}},
+				{ID: "lib2", SourceRoots: []string{"src/b"}, Image: ":v1.0.0"},
+			},
+		},
+		librarianConfig: &config.LibrarianConfig{RegenerationMarker: "{source_root}/.regenerated.yaml"},
+		apiSource:       &apiSourceProvenance{Commit: "abc123"},
+		image:           "gcr.io/test/image:v1.2.3",
+	}
+	if err := r.writeRegenerationMarkers([]string{"lib1", "lib2"}); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]*regenerationMarker{
+		"src/a/.regenerated.yaml": {Library: "lib1", Image: "gcr.io/test/image:v1.2.3", APISourceCommit: "abc123", LibrarianVersion: cli.Version(), RegeneratedAt: "2025-06-30T12:00:00Z"},
+		"src/b/.regenerated.yaml": {Library: "lib2", Image: "gcr.io/test/image:v1.0.0", APISourceCommit: "abc123", LibrarianVersion: cli.Version(), RegeneratedAt: "2025-06-30T12:00:00Z"},
+	} {
+		data, err := os.ReadFile(filepath.Join(repo.GetDir(), path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := &regenerationMarker{}
+		if err := yaml.Unmarshal(data, got); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", path, diff)
+		}
+	}
+}
+
+func TestChangesOnlyMarkers(t *testing.T) {
+	t.Parallel()
+	r := &generateRunner{
+		state: &config.LibrarianState{
+			Libraries: []*config.LibraryState{{ID: "lib1", SourceRoots: []string{"src/a"}}},
+		},
+	}
+	for _, test := range []struct {
+		name   string
+		report *generationReport
+		want   bool
+	}{
+		{
+			name: "markers and state",
+			report: &generationReport{
+				Other: []*changedFile{
+					{Path: ".librarian/regenerations/lib1.yaml", Change: fileChangeAdded},
+					{Path: ".librarian/state.yaml", Change: fileChangeModified},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "generated file",
+			report: &generationReport{
+				Libraries: []*libraryGenerationReport{{ID: "lib1", Files: []*changedFile{{Path: "src/a/a.go", Change: fileChangeModified}}}},
+				Other:     []*changedFile{{Path: ".librarian/regenerations/lib1.yaml", Change: fileChangeModified}},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			if got := r.changesOnlyMarkers(test.report, []string{"lib1"}); got != test.want {
+				t.Errorf("changesOnlyMarkers() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
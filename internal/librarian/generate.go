@@ -15,17 +15,22 @@
 package librarian
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/googleapis/librarian/internal/cli"
 	"github.com/googleapis/librarian/internal/config"
 	"github.com/googleapis/librarian/internal/docker"
+	"github.com/googleapis/librarian/internal/failure"
 	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/metrics"
+	"github.com/googleapis/librarian/internal/sbom"
 )
 
 const (
@@ -46,21 +51,113 @@ The behavior is determined by the provided flags.
 To configure and generate a new library, specify both the "-api" and "-library" flags. This process involves:
 1. Running the "configure" command in the language container to set up the repository.
 2. Adding the new library's configuration to the ".librarian/state.yaml" file.
-3. Proceeding with the generation steps below.
+3. Scaffolding the files of the new library, such as its README, from the templates in
+   ".librarian/templates/new-library" and those written by the container to
+   ".librarian/configure-templates", rendered as Go templates into its first source root.
+4. Proceeding with the generation steps below.
+
+An API can also be onboarded from its service config YAML with "-service-config". The API path
+is then the directory of the service config within "-api-source", or the package of its first
+API if it is elsewhere, and "-library" defaults to the library of the API, or to a new library
+whose ID is derived from the API path, e.g. "google-cloud-foo-v1" for "google/cloud/foo/v1".
+The directory of the service config is mounted read-only at "/service-config" in the containers.
 
 **Regenerating existing libraries:**
 If only "-api" or "-library" is specified, the command regenerates that single, existing library.
+All the APIs of a library, e.g. its v1 and v1beta versions, are generated together by one invocation
+of the container, and if any of its commands fails, the changes to the library are rolled back, so that
+it is never left half-updated.
+Before the container is run, the APIs of the library are checked on the host: their proto files
+must have balanced braces and a package, the files they import must exist, and the APIs listed in
+their service configs must be services declared in their protos. Problems are reported with the
+paths at fault in the API source, without waiting for the container to fail.
 If neither flag is provided, it regenerates all libraries listed in ".librarian/state.yaml".
+Libraries whose generation is blocked with the "block-library" command are then skipped, and
+listed with the reason of their block in the commit message and under "blocked" in the report.
+Libraries whose APIs were removed from the API source since their last generation are blocked
+rather than failing, and listed under "removed_apis" in the report; with "-file-issues", an
+issue suggesting to remove them, or to generate them from their last commit with "-api-ref", is
+filed for each of them.
+
+To regenerate a subset of the libraries, e.g. when an API family breaks and must be excluded
+temporarily, specify "-api-path-filter" with a comma-separated list of globs of API paths, where
+"*" matches within a path segment and "**" across segments. Globs prefixed with "!" exclude API
+paths. As the APIs of a library are generated together, a library is regenerated if some of its
+APIs match the other globs, or there are none, and none of its APIs is excluded. For example,
+"google/cloud/aiplatform/**,!google/cloud/aiplatform/v1beta1" regenerates the aiplatform libraries
+except the one of v1beta1.
+
+After a run in which some libraries failed to generate, "-retry-failed-from" with the path of its
+"generation-report.json" generates only the failed libraries, which the report lists under
+"failed", from the API source commit recorded in the report, so that they match the libraries
+generated by the previous run.
+
+To regenerate several libraries at the same time, specify "-parallel" with the number of
+libraries to generate at once. Each library is then generated, built and committed in its own git
+worktree of the language repository, under "worktrees" in the work root, on the branch
+"librarian-generate-{id}". When all of them are done, the commits are applied to the language
+repository in the order of the libraries, as if they were generated one after another, and the
+worktrees are removed. The branches are kept, e.g. to propose the changes of a library on their own.
+
+**Generating from a specific API source version:**
+By default, the HEAD of the API source is used. To generate from an exact commit, tag or branch,
+specify it with "-api-ref" (or its alias "-api-commit"). The API source is then checked out in the
+work root, the generation fails if any API to generate does not exist at the ref, and the resolved
+commit is recorded as "last_generated_commit" in the state and in "api-source-provenance.json".
+
+When the API source is cloned, only the directories of the APIs to generate and of their common
+dependencies, plus the "api_source_dependencies" of '.librarian/config.yaml', are checked out.
+Specify "-api-source-full-clone" to check out every file, e.g. when the imports are unknown.
 
 The generation process for an existing library involves delegating to the language container's 
 'generate' command. After generation, the tool cleans the destination directory and copies the 
 new files into place, according to the configuration in '.librarian/state.yaml'. 
 If the '--build' flag is specified, the 'build' command is also executed.
 
+**Running a subset of the phases:**
+The "-phases" flag selects the container commands to run for each library, as a comma-separated
+list of "configure", "generate", "build" and "test", which always run in this order. For example,
+"-phases=build" builds the code in the repository without regenerating it, and "-phases=test" only
+runs the tests, such as integration tests, of the container's "test" command. "configure" requires
+"generate", and "last_generated_commit" is only updated when "generate" runs.
+
 **Output:**
 After generation, if a push configuration is provided (e.g., via the "-push-config" flag), the changes
 are committed to a new branch, and a pull request is created. Otherwise, the changes are left in the
-local working tree for inspection.`,
+local working tree for inspection.
+
+With "-offline", the run needs no network access: "-repo" and "-api-source" are local directories
+or git bundles, images are never pulled but must exist locally or be loaded from the "docker save"
+tarball of "-image-archive", and the changes are committed and written to "offline-output" in the
+work root as a git bundle and patches for manual transfer, instead of being pushed.
+
+With "-container-backend=kubernetes", each container runs as a Kubernetes Job, configured in the
+"kubernetes" section of '.librarian/config.yaml', instead of with the local Docker daemon. The
+mounted directories are shared with the Jobs through a persistent volume claim or Cloud Storage.
+
+If "conflict_resolution" is configured in '.librarian/config.yaml', files changed by generation
+which were also edited manually since the last generation of their library are resolved with the
+strategy of the first matching rule: "prefer-generated", "prefer-manual", "fail" or
+"three-way-merge". The resolved conflicts are listed in the commit message.
+
+With "-review", each change which regeneration made to a handwritten file, or to a file edited
+manually since the last generation of its library, is shown as a diff in the terminal before the
+changes are committed, to be accepted or rejected. Rejected changes are reverted. The decisions
+are recorded in ".librarian/review-decisions.yaml", together with the checksum of the regenerated
+content, and the following runs, with or without "-review", apply them to the same regenerated
+content without asking again.
+
+To commit a regeneration which changes nothing, e.g. to pick up a generator fix which is only
+recorded in metadata, specify "-force-regenerate". The regeneration marker of each generated
+library, ".librarian/regenerations/{id}.yaml" unless "regeneration_marker" is configured in
+'.librarian/config.yaml', is updated with the image, the API source commit, the librarian version
+and the time of the regeneration, "no_op" is not applied, and if nothing else changed, the changes
+are committed with the "chore: regenerate" message.
+
+A report of the changed files of each library is written to "generation-report.json" and
+"generation-report.md" in the work root. It tells generated files apart from handwritten ones, i.e.
+files matching "preserve_regex" or which the generator did not write, and is added as a comment
+to the pull request.`,
 	Run: func(ctx context.Context, cfg *config.Config) error {
 		runner, err := newGenerateRunner(cfg)
 		if err != nil {
@@ -76,12 +173,54 @@ func init() {
 	cfg := cmdGenerate.Config
 
 	addFlagAPI(fs, cfg)
+	addFlagAPIPathFilter(fs, cfg)
 	addFlagAPISource(fs, cfg)
+	addFlagAPISourceFullClone(fs, cfg)
+	addFlagAPISourceSSHKey(fs, cfg)
+	addFlagAPIRef(fs, cfg)
+	addFlagAPIRootAllowDirty(fs, cfg)
+	addFlagArtifactsInclude(fs, cfg)
+	addFlagArtifactsRetention(fs, cfg)
+	addFlagArtifactsURL(fs, cfg)
+	addFlagAuditLog(fs, cfg)
 	addFlagBuild(fs, cfg)
+	addFlagCleanWorkRoot(fs, cfg)
+	addFlagContainerBackend(fs, cfg)
+	addFlagContainerLogLevel(fs, cfg)
+	addFlagContainerMounts(fs, cfg)
+	addFlagContainerRecord(fs, cfg)
+	addFlagContainerReplay(fs, cfg)
+	addFlagDebugShell(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagFileIssues(fs, cfg)
+	addFlagForceRegenerate(fs, cfg)
+	addFlagGeneratorSource(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagGitTuning(fs, cfg)
 	addFlagHostMount(fs, cfg)
 	addFlagImage(fs, cfg)
+	addFlagImageArchive(fs, cfg)
+	addFlagImageLocal(fs, cfg)
+	addFlagImagePlatform(fs, cfg)
 	addFlagLibrary(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagMetricsDir(fs, cfg)
+	addFlagNativeImage(fs, cfg)
+	addFlagOffline(fs, cfg)
+	addFlagParallel(fs, cfg)
+	addFlagPhases(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagRegistryMirror(fs, cfg)
+	addFlagReportFailures(fs, cfg)
 	addFlagRepo(fs, cfg)
+	addFlagRetryFailedFrom(fs, cfg)
+	addFlagReview(fs, cfg)
+	addFlagSandbox(fs, cfg)
+	addFlagServiceConfig(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
 	addFlagWorkRoot(fs, cfg)
 	addFlagPush(fs, cfg)
 }
@@ -90,28 +229,127 @@ type generateRunner struct {
 	cfg             *config.Config
 	repo            gitrepo.Repository
 	sourceRepo      gitrepo.Repository
+	apiSource       *apiSourceProvenance
 	state           *config.LibrarianState
+	librarianConfig *config.LibrarianConfig
 	ghClient        GitHubClient
+	gerrit          *gerritReview
 	containerClient ContainerClient
 	workRoot        string
 	image           string
+	// dependencies are the dependencies of each library reported by the
+	// language container, keyed by library ID.
+	dependencies map[string][]*config.Dependency
+	// apiPathFilter selects the libraries to regenerate, or is nil to
+	// regenerate all of them.
+	apiPathFilter *apiPathFilter
+	// retryLibraryIDs are the libraries which failed in the run of
+	// -retry-failed-from, or nil to regenerate all libraries.
+	retryLibraryIDs []string
+	// breakingChanges are the breaking API changes of each generated library
+	// since it was last generated, keyed by library ID.
+	breakingChanges map[string][]*breakingChange
+	// matrix are the results of the cells of the build matrix of the run.
+	matrix []*matrixCell
+	// containers are the results reported by the containers of the run.
+	containers []*containerReport
+	// review prompts for the decisions of -review, or is nil without it.
+	review *terminalReview
+	// deferRecording leaves recordGeneration to the caller of
+	// regenerateLibrary. It is set for the libraries generated in worktrees,
+	// whose generation is recorded one library at a time, as the API source
+	// is not read concurrently.
+	deferRecording bool
 }
 
 func newGenerateRunner(cfg *config.Config) (*generateRunner, error) {
+	var retry *generationReport
+	if cfg.RetryFailedFrom != "" {
+		report, err := readGenerationReport(cfg.RetryFailedFrom)
+		if err != nil {
+			return nil, failure.New(failure.UserConfig, err)
+		}
+		if len(report.Failed) == 0 {
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("no library failed to generate in %s, nothing to retry", cfg.RetryFailedFrom))
+		}
+		if report.SourceCommit == "" {
+			slog.Warn("generation report does not record its API source commit, retrying from the current one", "report", cfg.RetryFailedFrom)
+		}
+		// The failed libraries are generated from the same API source as the
+		// other libraries of the run.
+		cfg.APIRef = report.SourceCommit
+		retry = report
+	}
 	runner, err := newCommandRunner(cfg)
 	if err != nil {
 		return nil, err
 	}
-	return &generateRunner{
+	r := &generateRunner{
 		cfg:             runner.cfg,
 		workRoot:        runner.workRoot,
 		repo:            runner.repo,
 		sourceRepo:      runner.sourceRepo,
+		apiSource:       runner.apiSource,
 		state:           runner.state,
+		librarianConfig: runner.librarianConfig,
 		image:           runner.image,
 		ghClient:        runner.ghClient,
+		gerrit:          runner.gerrit,
 		containerClient: runner.containerClient,
-	}, nil
+		dependencies:    make(map[string][]*config.Dependency),
+	}
+	if err := r.applyServiceConfig(); err != nil {
+		return nil, err
+	}
+	if r.apiPathFilter, err = parseAPIPathFilter(cfg.APIPathFilter); err != nil {
+		return nil, err
+	}
+	if retry != nil {
+		r.retryLibraryIDs = retry.Failed
+	}
+	if cfg.Review {
+		if r.review, err = newTerminalReview(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// applyServiceConfig derives -api from -service-config, and -library if it
+// is not specified: the library of the API, or the default ID of a new
+// library of the API. The service config is recorded for the API if none is.
+func (r *generateRunner) applyServiceConfig() error {
+	if r.cfg.ServiceConfig == "" {
+		return nil
+	}
+	path, err := filepath.Abs(r.cfg.ServiceConfig)
+	if err != nil {
+		return err
+	}
+	source, err := filepath.Abs(r.cfg.APISource)
+	if err != nil {
+		return err
+	}
+	apiPath, err := serviceConfigAPIPath(path, source)
+	if err != nil {
+		return failure.New(failure.UserConfig, fmt.Errorf("invalid -service-config: %w", err))
+	}
+	if r.cfg.API != "" && r.cfg.API != apiPath {
+		return failure.New(failure.UserConfig, fmt.Errorf("-api %s does not match the API %s of -service-config", r.cfg.API, apiPath))
+	}
+	r.cfg.API = apiPath
+	if r.cfg.Library == "" {
+		r.cfg.Library = cmp.Or(findLibraryIDByAPIPath(r.state, apiPath), libraryIDForAPI(apiPath))
+	}
+	slog.Info("Generating API of service config", "api", apiPath, "library", r.cfg.Library, "service_config", path)
+	if library := findLibraryByID(r.state, r.cfg.Library); library != nil {
+		for _, api := range library.APIs {
+			if api.Path == apiPath && api.ServiceConfig == "" {
+				api.ServiceConfig = filepath.Base(path)
+			}
+		}
+	}
+	return nil
 }
 
 // run executes the library generation process.
@@ -120,54 +358,266 @@ func newGenerateRunner(cfg *config.Config) (*generateRunner, error) {
 // command-line flags. If an API or library is specified, it generates a single library. Otherwise,
 // it iterates through all libraries defined in the state and generates them.
 func (r *generateRunner) run(ctx context.Context) error {
+	if err := checkReviewPermissions(ctx, r.cfg, r.ghClient, r.gerrit); err != nil {
+		return err
+	}
 	outputDir := filepath.Join(r.workRoot, "output")
 	if err := os.Mkdir(outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to make output directory, %s: %w", outputDir, err)
 	}
 	slog.Info("Code will be generated", "dir", outputDir)
+	artifactRunFromContext(ctx).addLibraries(r.state)
+	run := metrics.FromContext(ctx)
+	stopPrewarm := run.StartPhase("prewarm")
+	if err := r.containerClient.Prewarm(ctx); err != nil {
+		return fmt.Errorf("failed to prewarm container image: %w", err)
+	}
+	stopPrewarm()
 
 	prBody := ""
+	if r.cfg.ImageLocal {
+		prBody += fmt.Sprintf("WARNING: generated with the local image %s (local-dev)\n", r.image)
+	}
+	if r.cfg.ForceRegenerate {
+		prBody += "Forced regeneration: the regeneration markers of the libraries are updated\n"
+	}
+	if r.apiSource != nil && r.apiSource.Dirty {
+		prBody += fmt.Sprintf("WARNING: generated from uncommitted API source changes on top of %s\n", r.apiSource.Commit)
+	}
+	if r.apiSource != nil && r.apiSource.Ref != "" {
+		// The APIs removed from the API source are reported by library when
+		// all libraries are regenerated.
+		if r.cfg.API != "" || r.cfg.Library != "" {
+			if err := validateAPIPaths(r.sourceRepo.GetDir(), r.apiPathsToGenerate()); err != nil {
+				return err
+			}
+		}
+		prBody += fmt.Sprintf("Generated from API source %s at %s\n", r.apiSource.Ref, r.apiSource.Commit)
+	}
+	var generatedLibraryIDs, failedLibraryIDs, blockedLibraryIDs []string
+	var removedAPIs []*removedAPI
 	if r.cfg.API != "" || r.cfg.Library != "" {
 		libraryID := r.cfg.Library
 		if libraryID == "" {
 			libraryID = findLibraryIDByAPIPath(r.state, r.cfg.API)
 		}
 		if err := r.generateSingleLibrary(ctx, libraryID, outputDir); err != nil {
+			run.AddLibraries(1, 1)
 			return err
 		}
+		run.AddLibraries(1, 0)
 		prBody += fmt.Sprintf("feat: generated %s\n", libraryID)
+		generatedLibraryIDs = append(generatedLibraryIDs, libraryID)
 	} else {
-		failedGenerations := 0
-		for _, library := range r.state.Libraries {
+		libraries, err := r.librariesToRegenerate()
+		if err != nil {
+			return err
+		}
+		if r.apiPathFilter != nil {
+			prBody += fmt.Sprintf("Regenerated the libraries selected by -api-path-filter=%s\n", r.cfg.APIPathFilter)
+		}
+		// pending are the libraries to generate in parallel, in worktrees.
+		var pending []*config.LibraryState
+		if r.retryLibraryIDs != nil {
+			prBody += fmt.Sprintf("Retried the libraries which failed to generate in %s\n", filepath.Base(r.cfg.RetryFailedFrom))
+		}
+		for _, library := range libraries {
+			if library.Blocked != nil {
+				slog.Warn("Skip library whose generation is blocked", "library", library.ID, "reason", library.Blocked.Reason, "bug", library.Blocked.Bug)
+				prBody += fmt.Sprintf("%s was skipped, its generation is blocked: %s\n", library.ID, blockDescription(library.Blocked))
+				blockedLibraryIDs = append(blockedLibraryIDs, library.ID)
+				continue
+			}
+			if r.cfg.RunsPhase(config.PhaseGenerate) {
+				removed, err := blockRemovedAPIs(r.sourceRepo, r.apiRoot(r.sourceRepo.GetDir()), library)
+				if err != nil {
+					return err
+				}
+				if len(removed) > 0 {
+					prBody += fmt.Sprintf("%s was skipped and is now blocked, its APIs were removed from the API source: %s\n", library.ID, removedAPIPaths(removed))
+					blockedLibraryIDs = append(blockedLibraryIDs, library.ID)
+					removedAPIs = append(removedAPIs, removed...)
+					r.fileRemovedAPIsIssue(ctx, library, removed)
+					continue
+				}
+			}
+			if r.cfg.Parallel > 1 {
+				pending = append(pending, library)
+				continue
+			}
 			if err := r.generateSingleLibrary(ctx, library.ID, outputDir); err != nil {
 				// TODO(https://github.com/googleapis/librarian/issues/983): record failure and report in PR body when applicable
 				slog.Error("failed to generate library", "id", library.ID, "err", err)
 				prBody += fmt.Sprintf("%s failed to generate\n", library.ID)
-				failedGenerations++
+				failedLibraryIDs = append(failedLibraryIDs, library.ID)
+				continue
 			}
+			generatedLibraryIDs = append(generatedLibraryIDs, library.ID)
 		}
-		if failedGenerations > 0 && failedGenerations == len(r.state.Libraries) {
-			return fmt.Errorf("all %d libraries failed to generate", failedGenerations)
+		if len(pending) > 0 {
+			generated, failed, err := r.generateInWorktrees(ctx, pending, outputDir)
+			if err != nil {
+				return err
+			}
+			for _, id := range failed {
+				prBody += fmt.Sprintf("%s failed to generate\n", id)
+			}
+			generatedLibraryIDs = append(generatedLibraryIDs, generated...)
+			failedLibraryIDs = append(failedLibraryIDs, failed...)
+		}
+		attempted := len(libraries) - len(blockedLibraryIDs)
+		run.AddLibraries(attempted, len(failedLibraryIDs))
+		if len(failedLibraryIDs) > 0 && len(failedLibraryIDs) == attempted {
+			// The report still records the failures, which a run with
+			// -retry-failed-from generates again.
+			report := &generationReport{Failed: failedLibraryIDs, Blocked: blockedLibraryIDs, RemovedAPIs: removedAPIs, Matrix: r.matrix, Containers: r.containers}
+			if report.SourceCommit, err = r.sourceRepo.HeadHash(); err != nil {
+				return err
+			}
+			if err := writeGenerationReport(r.workRoot, report); err != nil {
+				return err
+			}
+			return fmt.Errorf("all %d libraries failed to generate", len(failedLibraryIDs))
 		}
 	}
 
-	if err := saveLibrarianState(r.repo.GetDir(), r.state); err != nil {
+	if err := saveLibrarianState(ctx, r.repo.GetDir(), r.state); err != nil {
+		return err
+	}
+	if r.cfg.ForceRegenerate {
+		if err := r.writeRegenerationMarkers(generatedLibraryIDs); err != nil {
+			return err
+		}
+	}
+	conflicts, err := resolveRegenerationConflicts(r.librarianConfig, r.repo, r.state, generatedLibraryIDs, r.cfg.Review)
+	if err != nil {
 		return err
 	}
-	if err := commitAndPush(ctx, r.cfg, r.repo, r.ghClient, prBody); err != nil {
+	for _, conflict := range conflicts {
+		prBody += fmt.Sprintf("Resolved conflict with manual edits of %s: %s\n", conflict.path, conflict.strategy)
+	}
+	report, err := newGenerationReport(r.repo, r.state, generatedLibraryIDs, outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to create generation report: %w", err)
+	}
+	report.Failed = failedLibraryIDs
+	report.Blocked = blockedLibraryIDs
+	report.RemovedAPIs = removedAPIs
+	report.Matrix = r.matrix
+	if err := report.mergeContainerResults(r.state, r.containers); err != nil {
+		return err
+	}
+	for _, library := range report.Libraries {
+		library.BreakingChanges = r.breakingChanges[library.ID]
+	}
+	rejected, err := r.reviewChanges(report, conflicts)
+	if err != nil {
 		return err
 	}
+	for _, path := range rejected {
+		prBody += fmt.Sprintf("Rejected regenerated changes to %s in review\n", path)
+	}
+	for _, library := range report.Libraries {
+		if len(library.Files) == 0 || len(library.BreakingChanges) == 0 {
+			continue
+		}
+		prBody += fmt.Sprintf("BREAKING: the APIs of %s changed incompatibly:\n", library.ID)
+		for _, change := range library.BreakingChanges {
+			prBody += fmt.Sprintf("* %s: %s (%s)\n", change.File, change.Description, change.Kind)
+		}
+	}
+	if report.SourceCommit, err = r.sourceRepo.HeadHash(); err != nil {
+		return err
+	}
+	// Forced regenerations are committed even if they change nothing
+	// meaningful.
+	if r.librarianConfig != nil && !r.cfg.ForceRegenerate {
+		if report.NoOp, err = report.isNoOp(r.librarianConfig.NoOp); err != nil {
+			return err
+		}
+	}
+	if err := writeGenerationReport(r.workRoot, report); err != nil {
+		return err
+	}
+	run.AddDiff(report.diffSize())
+	if report.NoOp {
+		slog.Info("No meaningful changes, the run is a no-op; skipping commit and pull request")
+		return nil
+	}
+	if err := r.writeSBOM(generatedLibraryIDs); err != nil {
+		return err
+	}
+	quarantine, err := guardProtectedFiles(r.cfg, r.librarianConfig, r.repo, report)
+	if err != nil {
+		return err
+	}
+	var commitMessage string
+	var groupCommits []*followUpCommit
+	if r.cfg.ForceRegenerate && r.changesOnlyMarkers(report, generatedLibraryIDs) {
+		commitMessage = forceRegenerateMessage + "\n\n" + prBody
+	} else if commitMessage, groupCommits, err = r.groupCommits(report, prBody); err != nil {
+		return err
+	}
+	commitInfo := &commitInfo{
+		cfg:             r.cfg,
+		state:           r.state,
+		repo:            r.repo,
+		ghClient:        r.ghClient,
+		gerrit:          r.gerrit,
+		commitMessage:   commitMessage,
+		libraryIDs:      generatedLibraryIDs,
+		kind:            config.PullRequestKindGenerate,
+		librarianConfig: r.librarianConfig,
+		followUpCommits: groupCommits,
+	}
+	if quarantine != nil {
+		commitInfo.commitMessage += "WARNING: changes to protected files are quarantined in a separate commit\n"
+		commitInfo.followUpCommits = append(commitInfo.followUpCommits, quarantine)
+	}
+	pr, err := commitAndCreatePullRequest(ctx, commitInfo)
+	if err != nil {
+		return err
+	}
+	if pr != nil {
+		if err := r.ghClient.CreateIssueComment(ctx, pr.Number, report.markdown()); err != nil {
+			slog.Warn("failed to comment generation report on pull request", "number", pr.Number, "err", err)
+		}
+	}
 	return nil
 }
 
+// fileRemovedAPIsIssue files an issue for library, whose APIs were removed,
+// with -file-issues. Failing to file it is logged and does not fail the run.
+func (r *generateRunner) fileRemovedAPIsIssue(ctx context.Context, library *config.LibraryState, removed []*removedAPI) {
+	if !r.cfg.FileIssues {
+		return
+	}
+	if err := fileRemovedAPIsIssue(ctx, r.ghClient, library, removed); err != nil {
+		slog.Warn("failed to file removed API issue", "library", library.ID, "err", err)
+	}
+}
+
+// groupCommits arranges the generated changes into commits, unless nothing is
+// committed, in which case the changes are left in the working tree.
+func (r *generateRunner) groupCommits(report *generationReport, prBody string) (string, []*followUpCommit, error) {
+	if !r.cfg.Commit && !r.cfg.Push {
+		return prBody, nil, nil
+	}
+	return groupGenerationCommits(r.librarianConfig, r.repo, r.state, report, prBody)
+}
+
 // generateSingleLibrary manages the generation of a single client library.
 //
 // It can either configure a new library if the API and library both are specified
 // and library not configured in state.yaml yet, or regenerate an existing library
 // if a libraryID is provided.
-// After ensuring the library is configured, it runs the generation and build commands.
+// After ensuring the library is configured, it runs the generation, build and
+// test commands, as far as their phases are selected.
 func (r *generateRunner) generateSingleLibrary(ctx context.Context, libraryID, outputDir string) error {
 	if r.needsConfigure() {
+		if !r.cfg.RunsPhase(config.PhaseConfigure) {
+			return fmt.Errorf("library %q is not configured yet, and the %q phase is not selected", r.cfg.Library, config.PhaseConfigure)
+		}
 		slog.Info("library not configured, start initial configuration", "library", r.cfg.Library)
 		configuredLibraryID, err := r.runConfigureCommand(ctx)
 		if err != nil {
@@ -186,30 +636,139 @@ func (r *generateRunner) generateSingleLibrary(ctx context.Context, libraryID, o
 		slog.Info("library has no APIs; skipping generation", "library", libraryID)
 		return nil
 	}
+	if err := r.checkLibraryAPIs(libraryState); err != nil {
+		return err
+	}
+	return r.generateLibrary(ctx, libraryState, outputDir)
+}
+
+// checkLibraryAPIs checks that the APIs of library are in the API source, and
+// were not removed from it, if the generate phase is selected.
+func (r *generateRunner) checkLibraryAPIs(library *config.LibraryState) error {
+	if !r.cfg.RunsPhase(config.PhaseGenerate) {
+		return nil
+	}
+	removed, err := findRemovedAPIs(r.sourceRepo, r.apiRoot(r.sourceRepo.GetDir()), library)
+	if err != nil {
+		return err
+	}
+	if len(removed) > 0 {
+		return removedAPIsError(library, removed)
+	}
+	return checkAPIs(r.apiRoot(r.sourceRepo.GetDir()), library.APIs)
+}
 
-	// For each library, create a separate output directory. This avoids
-	// libraries interfering with each other, and makes it easier to see what
-	// was generated for each library when debugging.
-	libraryOutputDir := filepath.Join(outputDir, libraryID)
-	if err := os.MkdirAll(libraryOutputDir, 0755); err != nil {
+// generateLibrary runs the phases of the configured library, see runPhases.
+// The APIs of a library, e.g. its v1 and v1beta versions, are generated
+// together by a single generate command. If any phase fails, the library is
+// rolled back, so that it is never left half-updated.
+func (r *generateRunner) generateLibrary(ctx context.Context, library *config.LibraryState, outputDir string) error {
+	saved := *library
+	if err := r.runPhases(ctx, library.ID, outputDir); err != nil {
+		if rollbackErr := r.rollbackLibrary(library, &saved); rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to roll back library %s: %w", library.ID, rollbackErr))
+		}
 		return err
 	}
+	return nil
+}
+
+// runPhases runs the generation, build and test commands of a library, as
+// far as their phases are selected. The build and test commands run for each
+// variant of the build matrix of the library.
+func (r *generateRunner) runPhases(ctx context.Context, libraryID, outputDir string) error {
+	if r.cfg.RunsPhase(config.PhaseGenerate) {
+		if err := r.regenerateLibrary(ctx, libraryID, outputDir); err != nil {
+			return err
+		}
+	}
+	return r.runBuildMatrix(ctx, libraryID)
+}
 
-	generatedLibraryID, err := r.runGenerateCommand(ctx, libraryID, libraryOutputDir)
+// rollbackLibrary restores library to saved, its state before generation,
+// and reverts the changes to the files in its source roots.
+func (r *generateRunner) rollbackLibrary(library, saved *config.LibraryState) error {
+	*library = *saved
+	delete(r.dependencies, library.ID)
+	status, err := r.repo.Status()
 	if err != nil {
 		return err
 	}
+	for _, path := range sortedStatusFiles(status) {
+		if libraryForFile(r.state, []string{library.ID}, path) != library.ID {
+			continue
+		}
+		file, err := newChangedFile(r.repo, path, status.File(path))
+		if err != nil {
+			return err
+		}
+		if file == nil {
+			continue
+		}
+		if err := restoreFileAtHead(r.repo, file); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", path, err)
+		}
+	}
+	slog.Warn("Rolled back library which failed to generate", "library", library.ID)
+	return nil
+}
 
-	if err := r.updateChangesSinceLastGeneration(generatedLibraryID); err != nil {
+// regenerateLibrary runs the generation command for a library, and updates
+// its changes and last generated commit in the state, unless deferRecording
+// is set.
+func (r *generateRunner) regenerateLibrary(ctx context.Context, libraryID, outputDir string) error {
+	generatedLibraryID, err := r.generateLibraryCode(ctx, libraryID, outputDir)
+	if err != nil || r.deferRecording {
 		return err
 	}
+	return r.recordGeneration(generatedLibraryID)
+}
 
-	if err := r.runBuildCommand(ctx, generatedLibraryID); err != nil {
+// generateLibraryCode runs the generation command for a library into its own
+// directory within outputDir, and copies the generated code into the
+// repository. It returns the ID of the generated library.
+func (r *generateRunner) generateLibraryCode(ctx context.Context, libraryID, outputDir string) (string, error) {
+	// For each library, create a separate output directory. This avoids
+	// libraries interfering with each other, and makes it easier to see what
+	// was generated for each library when debugging.
+	libraryOutputDir := filepath.Join(outputDir, libraryID)
+	if err := os.MkdirAll(libraryOutputDir, 0755); err != nil {
+		return "", err
+	}
+	return r.runGenerateCommand(ctx, libraryID, libraryOutputDir)
+}
+
+// recordGeneration updates the breaking API changes, the changes and the
+// last generated commit of a generated library in the state.
+func (r *generateRunner) recordGeneration(libraryID string) error {
+	if err := r.updateBreakingChanges(libraryID); err != nil {
 		return err
 	}
-	if err := r.updateLastGeneratedCommitState(generatedLibraryID); err != nil {
+	if err := r.updateChangesSinceLastGeneration(libraryID); err != nil {
 		return err
 	}
+	return r.updateLastGeneratedCommitState(libraryID)
+}
+
+// updateBreakingChanges records the breaking API changes of the library
+// since it was last generated, which mark its commit as breaking.
+func (r *generateRunner) updateBreakingChanges(libraryID string) error {
+	library := findLibraryByID(r.state, libraryID)
+	if library == nil {
+		return nil
+	}
+	changes, err := findBreakingChanges(r.sourceRepo, r.apiRoot(r.sourceRepo.GetDir()), library)
+	if err != nil {
+		return fmt.Errorf("failed to detect breaking API changes of library %s: %w", libraryID, err)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	slog.Warn("Detected breaking API changes", "library", libraryID, "changes", len(changes))
+	if r.breakingChanges == nil {
+		r.breakingChanges = make(map[string][]*breakingChange)
+	}
+	r.breakingChanges[libraryID] = changes
 	return nil
 }
 
@@ -233,6 +792,10 @@ func (r *generateRunner) updateChangesSinceLastGeneration(libraryID string) erro
 }
 
 func (r *generateRunner) updateLastGeneratedCommitState(libraryID string) error {
+	if r.apiSource != nil && r.apiSource.Dirty {
+		slog.Warn("API source has uncommitted changes, not updating last generated commit", "library", libraryID)
+		return nil
+	}
 	hash, err := r.sourceRepo.HeadHash()
 	if err != nil {
 		return err
@@ -252,30 +815,42 @@ func (r *generateRunner) updateLastGeneratedCommitState(libraryID string) error
 // If successful, it returns the ID of the generated library; otherwise, it
 // returns an empty string and an error.
 func (r *generateRunner) runGenerateCommand(ctx context.Context, libraryID, outputDir string) (string, error) {
-	apiRoot, err := filepath.Abs(r.sourceRepo.GetDir())
+	defer metrics.FromContext(ctx).StartPhase("generate")()
+	apiRoot, err := filepath.Abs(r.apiRoot(r.sourceRepo.GetDir()))
 	if err != nil {
 		return "", err
 	}
 
 	generateRequest := &docker.GenerateRequest{
-		Cfg:       r.cfg,
-		State:     r.state,
-		ApiRoot:   apiRoot,
-		LibraryID: libraryID,
-		Output:    outputDir,
-		RepoDir:   r.repo.GetDir(),
+		Cfg:             r.cfg,
+		LibrarianConfig: r.librarianConfig,
+		State:           r.state,
+		ApiRoot:         apiRoot,
+		LibraryID:       libraryID,
+		Output:          outputDir,
+		RepoDir:         r.repo.GetDir(),
 	}
 	slog.Info("Performing generation for library", "id", libraryID)
-	if err := r.containerClient.Generate(ctx, generateRequest); err != nil {
+	err = r.containerClient.Generate(ctx, generateRequest)
+	// The results are read even if the container failed, as they may explain
+	// the failure.
+	if err := errors.Join(err, r.recordContainerResults(docker.CommandGenerate, libraryID, nil)); err != nil {
 		return "", err
 	}
 
 	// Read the library state from the response.
-	if _, err := readLibraryState(
-		filepath.Join(generateRequest.RepoDir, config.LibrarianDir, config.GenerateResponse)); err != nil {
+	response, err := readLibraryState(
+		filepath.Join(generateRequest.RepoDir, config.LibrarianDir, config.GenerateResponse))
+	if err != nil {
 		return "", err
 	}
+	r.recordDependencies(libraryID, response)
 
+	if r.librarianConfig != nil {
+		if err := normalizeOutput(outputDir, r.librarianConfig.Normalization); err != nil {
+			return "", err
+		}
+	}
 	if err := cleanAndCopyLibrary(r.state, r.repo.GetDir(), libraryID, outputDir); err != nil {
 		return "", err
 	}
@@ -288,9 +863,10 @@ func (r *generateRunner) runGenerateCommand(ctx context.Context, libraryID, outp
 //
 // The `outputDir` parameter specifies the target directory where the built artifacts
 // should be placed.
-func (r *generateRunner) runBuildCommand(ctx context.Context, libraryID string) error {
-	if !r.cfg.Build {
-		slog.Info("Build flag not specified, skipping")
+func (r *generateRunner) runBuildCommand(ctx context.Context, libraryID string, variant *config.BuildVariant) error {
+	defer metrics.FromContext(ctx).StartPhase("build")()
+	if !r.cfg.RunsPhase(config.PhaseBuild) {
+		slog.Info("Build phase not selected, skipping")
 		return nil
 	}
 	if libraryID == "" {
@@ -299,24 +875,108 @@ func (r *generateRunner) runBuildCommand(ctx context.Context, libraryID string)
 	}
 
 	buildRequest := &docker.BuildRequest{
-		Cfg:       r.cfg,
-		State:     r.state,
-		LibraryID: libraryID,
-		RepoDir:   r.repo.GetDir(),
+		Cfg:             r.cfg,
+		LibrarianConfig: r.librarianConfig,
+		State:           r.state,
+		LibraryID:       libraryID,
+		RepoDir:         r.repo.GetDir(),
+		Variant:         variant,
 	}
-	slog.Info("Build requested for library", "id", libraryID)
-	if err := r.containerClient.Build(ctx, buildRequest); err != nil {
+	slog.Info("Build requested for library", "id", libraryID, "variant", variantName(variant))
+	err := r.containerClient.Build(ctx, buildRequest)
+	if err := errors.Join(err, r.recordContainerResults(docker.CommandBuild, libraryID, variant)); err != nil {
 		return err
 	}
 
 	// Read the library state from the response.
-	_, err := readLibraryState(
+	response, err := readLibraryState(
 		filepath.Join(buildRequest.RepoDir, config.LibrarianDir, config.BuildResponse),
 	)
+	if err != nil {
+		return err
+	}
+	r.recordDependencies(libraryID, response)
+	return nil
+}
 
+// runTestCommand runs the tests of a library, such as integration tests, in
+// the language container if the test phase is selected.
+func (r *generateRunner) runTestCommand(ctx context.Context, libraryID string, variant *config.BuildVariant) error {
+	if !r.cfg.RunsPhase(config.PhaseTest) {
+		return nil
+	}
+	defer metrics.FromContext(ctx).StartPhase("test")()
+	testRequest := &docker.TestRequest{
+		Cfg:             r.cfg,
+		LibrarianConfig: r.librarianConfig,
+		State:           r.state,
+		LibraryID:       libraryID,
+		RepoDir:         r.repo.GetDir(),
+		Variant:         variant,
+	}
+	slog.Info("Test requested for library", "id", libraryID, "variant", variantName(variant))
+	err := r.containerClient.Test(ctx, testRequest)
+	if err := errors.Join(err, r.recordContainerResults(docker.CommandTest, libraryID, variant)); err != nil {
+		return err
+	}
+
+	// Read the error message, if any, from the response.
+	_, err = readLibraryState(filepath.Join(testRequest.RepoDir, config.LibrarianDir, config.TestResponse))
 	return err
 }
 
+// recordDependencies records the dependencies of a library reported in a
+// response of the language container, if any.
+func (r *generateRunner) recordDependencies(libraryID string, response *config.LibraryState) {
+	if response == nil || len(response.Dependencies) == 0 {
+		return
+	}
+	r.dependencies[libraryID] = mergeDependencies(r.dependencies[libraryID], response.Dependencies)
+}
+
+// writeSBOM writes the SBOM of the generated libraries into the work root.
+func (r *generateRunner) writeSBOM(libraryIDs []string) error {
+	commit, dirty, err := r.generatedFrom()
+	if err != nil {
+		return err
+	}
+	var libraries []*sbom.Library
+	for _, id := range libraryIDs {
+		library := findLibraryByID(r.state, id)
+		if library == nil {
+			continue
+		}
+		l := sbomLibrary(library, r.generatorImage(library), r.cfg.APISource, commit, r.dependencies[id])
+		l.APISourceDirty = dirty
+		l.ImageLocal = r.cfg.ImageLocal
+		libraries = append(libraries, l)
+	}
+	return writeSBOM(r.workRoot, libraries)
+}
+
+// generatedFrom returns the commit of the API source which the libraries are
+// generated from, and whether uncommitted changes on top of it are.
+func (r *generateRunner) generatedFrom() (string, bool, error) {
+	if r.apiSource != nil {
+		return r.apiSource.Commit, r.apiSource.Dirty, nil
+	}
+	hash, err := r.sourceRepo.HeadHash()
+	if err != nil {
+		return "", false, err
+	}
+	return hash, false, nil
+}
+
+// generatorImage returns the image which generates the code of library: the
+// image of the generate container, if one is declared, unless the library is
+// pinned to another image.
+func (r *generateRunner) generatorImage(library *config.LibraryState) string {
+	if library.Image != "" {
+		return deriveImage(r.cfg.Image, r.state, library)
+	}
+	return cmp.Or(r.librarianConfig.ContainerImages()[string(docker.CommandGenerate)], r.image)
+}
+
 // runConfigureCommand executes the container's "configure" command for an API.
 //
 // This function performs the following steps:
@@ -339,8 +999,9 @@ func (r *generateRunner) runBuildCommand(ctx context.Context, libraryID string)
 // If successful, it returns the ID of the newly configured library; otherwise,
 // it returns an empty string and an error.
 func (r *generateRunner) runConfigureCommand(ctx context.Context) (string, error) {
+	defer metrics.FromContext(ctx).StartPhase("configure")()
 
-	apiRoot, err := filepath.Abs(r.cfg.APISource)
+	apiRoot, err := filepath.Abs(r.apiRoot(r.cfg.APISource))
 	if err != nil {
 		return "", err
 	}
@@ -349,24 +1010,27 @@ func (r *generateRunner) runConfigureCommand(ctx context.Context) (string, error
 	// Record to state, not write to state.yaml
 	r.state.Libraries = append(r.state.Libraries, &config.LibraryState{
 		ID:   r.cfg.Library,
-		APIs: []*config.API{{Path: r.cfg.API, Status: config.StatusNew}},
+		APIs: []*config.API{{Path: r.cfg.API, ServiceConfig: r.serviceConfigName(), Status: config.StatusNew}},
 	})
 
 	if err := populateServiceConfigIfEmpty(
 		r.state,
-		r.cfg.APISource); err != nil {
+		r.apiRoot(r.cfg.APISource),
+		r.cfg.Library); err != nil {
 		return "", err
 	}
 
 	configureRequest := &docker.ConfigureRequest{
-		Cfg:       r.cfg,
-		State:     r.state,
-		ApiRoot:   apiRoot,
-		LibraryID: r.cfg.Library,
-		RepoDir:   r.repo.GetDir(),
+		Cfg:             r.cfg,
+		LibrarianConfig: r.librarianConfig,
+		State:           r.state,
+		ApiRoot:         apiRoot,
+		LibraryID:       r.cfg.Library,
+		RepoDir:         r.repo.GetDir(),
 	}
 	slog.Info("Performing configuration for library", "id", r.cfg.Library)
-	if _, err := r.containerClient.Configure(ctx, configureRequest); err != nil {
+	_, err = r.containerClient.Configure(ctx, configureRequest)
+	if err := errors.Join(err, r.recordContainerResults(docker.CommandConfigure, r.cfg.Library, nil)); err != nil {
 		return "", err
 	}
 
@@ -393,10 +1057,85 @@ func (r *generateRunner) runConfigureCommand(ctx context.Context) (string, error
 		}
 		r.state.Libraries[i] = libraryState
 	}
+	if _, err := scaffoldLibrary(r.repo.GetDir(), libraryState); err != nil {
+		return "", err
+	}
 
 	return libraryState.ID, nil
 }
 
+// serviceConfigName returns the file name of -service-config, or an empty
+// string if it is not specified.
+func (r *generateRunner) serviceConfigName() string {
+	if r.cfg.ServiceConfig == "" {
+		return ""
+	}
+	return filepath.Base(r.cfg.ServiceConfig)
+}
+
+// apiPathsToGenerate returns the paths of the APIs which the run generates:
+// the API specified with -api, or the APIs of the library specified with
+// -library, or otherwise the APIs of all libraries.
+func (r *generateRunner) apiPathsToGenerate() []string {
+	if r.cfg.API != "" {
+		return []string{r.cfg.API}
+	}
+	var paths []string
+	for _, library := range r.state.Libraries {
+		if (r.cfg.Library != "" && library.ID != r.cfg.Library) || !r.selects(library) {
+			continue
+		}
+		for _, api := range library.APIs {
+			paths = append(paths, api.Path)
+		}
+	}
+	return paths
+}
+
+// librariesToRegenerate returns the libraries regenerated when neither -api
+// nor -library is specified: all the libraries in the state, or those selected
+// by -api-path-filter and -retry-failed-from.
+func (r *generateRunner) librariesToRegenerate() ([]*config.LibraryState, error) {
+	if r.apiPathFilter == nil && r.retryLibraryIDs == nil {
+		return r.state.Libraries, nil
+	}
+	var libraries []*config.LibraryState
+	for _, library := range r.state.Libraries {
+		if !r.selects(library) {
+			slog.Info("Skip library not selected by -api-path-filter or -retry-failed-from", "library", library.ID)
+			continue
+		}
+		libraries = append(libraries, library)
+	}
+	if len(libraries) == 0 && r.retryLibraryIDs != nil {
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("none of the failed libraries of -retry-failed-from %s is in the state", r.cfg.RetryFailedFrom))
+	}
+	if len(libraries) == 0 {
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("no library matches -api-path-filter %q", r.cfg.APIPathFilter))
+	}
+	return libraries, nil
+}
+
+// selects reports whether library is regenerated when neither -api nor
+// -library is specified: it is selected by -api-path-filter, and it failed in
+// the run of -retry-failed-from, if specified.
+func (r *generateRunner) selects(library *config.LibraryState) bool {
+	if r.retryLibraryIDs != nil && !slices.Contains(r.retryLibraryIDs, library.ID) {
+		return false
+	}
+	return r.apiPathFilter.selects(library)
+}
+
+// apiRoot returns the directory containing the API definitions to generate
+// from. This is the snapshot of the API source when it has uncommitted
+// changes, and defaultRoot otherwise.
+func (r *generateRunner) apiRoot(defaultRoot string) string {
+	if r.apiSource != nil && r.apiSource.Dirty {
+		return r.apiSource.Snapshot
+	}
+	return defaultRoot
+}
+
 func setAllAPIStatus(state *config.LibrarianState, status string) {
 	for _, library := range state.Libraries {
 		for _, api := range library.APIs {
@@ -0,0 +1,451 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/github"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// generatedCIHeader is the first line, after any shebang, of the files
+// written by generate-ci. Files with the header are owned by generate-ci, and
+// removed when they are no longer generated.
+const generatedCIHeader = `# Code generated by "librarian generate-ci". DO NOT EDIT.`
+
+var cmdGenerateCI = &cli.Command{
+	Short:     "generate-ci generates the CI trigger definitions of the standard workflows",
+	UsageLine: "librarian generate-ci [flags]",
+	Long: `Generates the CI trigger definitions of the standard librarian workflows of
+the language repository, from "ci_triggers" in ".librarian/config.yaml":
+
+- generate: regenerates the libraries, nightly by default.
+- release-init: creates a release pull request, weekly by default.
+- tag-and-release: tags and publishes merged release pull requests, on
+  pushes to the default branch by default.
+
+The provider of the definitions is one of:
+
+- github-actions: a workflow per standard workflow in ".github/workflows".
+- cloud-build: a trigger per standard workflow in ".cloudbuild", to import
+  with "gcloud builds triggers import". Scheduled triggers are run by Cloud
+  Scheduler.
+- kokoro: a job config and build script per standard workflow in ".kokoro".
+  The schedules are configured in the Kokoro jobs.
+
+The definitions are written into the local repository specified with "-repo",
+and the definitions of disabled workflows, or of another provider, are
+removed. With "-verify", nothing is written, and the command fails if any
+definition is out of date with the config, e.g. in a presubmit check.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		return generateCI(cfg)
+	},
+}
+
+func init() {
+	cmdGenerateCI.Init()
+	fs := cmdGenerateCI.Flags
+	cfg := cmdGenerateCI.Config
+
+	addFlagErrorFormat(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagVerify(fs, cfg)
+}
+
+// ciWorkflow is a standard workflow, as run by the generated definitions.
+type ciWorkflow struct {
+	name        string
+	description string
+	// schedule is a cron schedule, or empty to run on pushes to the
+	// default branch.
+	schedule string
+	args     []string
+}
+
+// defaultCIWorkflows returns the standard workflows with their defaults.
+func defaultCIWorkflows() []*ciWorkflow {
+	return []*ciWorkflow{
+		{
+			name:        config.CIWorkflowGenerate,
+			description: "Regenerates the libraries and creates a pull request.",
+			schedule:    "0 4 * * *",
+			args:        []string{"generate", "-repo=.", "-push"},
+		},
+		{
+			name:        config.CIWorkflowReleaseInit,
+			description: "Creates a release pull request.",
+			schedule:    "0 6 * * 1",
+			args:        []string{"release", "init", "-repo=.", "-push"},
+		},
+		{
+			name:        config.CIWorkflowTagAndRelease,
+			description: "Tags and publishes merged release pull requests.",
+			args:        []string{"release", "tag-and-release", "-repo=."},
+		},
+	}
+}
+
+// ciWorkflows returns the standard workflows customized by triggers, without
+// the disabled ones.
+func ciWorkflows(triggers *config.CITriggers) []*ciWorkflow {
+	var workflows []*ciWorkflow
+	for _, workflow := range defaultCIWorkflows() {
+		i := slices.IndexFunc(triggers.Workflows, func(w *config.CIWorkflow) bool { return w.Name == workflow.name })
+		if i >= 0 {
+			custom := triggers.Workflows[i]
+			if custom.Disabled {
+				continue
+			}
+			workflow.schedule = cmp.Or(custom.Schedule, workflow.schedule)
+			workflow.args = append(workflow.args, custom.Flags...)
+		}
+		workflows = append(workflows, workflow)
+	}
+	return workflows
+}
+
+// ciTemplateData is the data of the templates of CI files.
+type ciTemplateData struct {
+	Header      string
+	Name        string
+	Description string
+	Schedule    string
+	Args        []string
+	Version     string
+	Image       string
+	Branch      string
+	TokenSecret string
+	// Repo is nil for GitHub Actions.
+	Repo *github.Repository
+}
+
+var ciTemplateFuncs = template.FuncMap{
+	"shellJoin": func(args []string) string {
+		var quoted []string
+		for _, arg := range args {
+			quoted = append(quoted, shellQuoteArg(arg))
+		}
+		return strings.Join(quoted, " ")
+	},
+	"yamlQuote": func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	},
+	"regexpQuote": regexp.QuoteMeta,
+	"host": func(repo *github.Repository) string {
+		return cmp.Or(repo.Host, github.DefaultHost)
+	},
+}
+
+var (
+	gitHubActionsTemplate = template.Must(template.New("github-actions").Delims("[[", "]]").Funcs(ciTemplateFuncs).Parse(`[[.Header]]
+# [[.Description]]
+name: librarian [[.Name]]
+on:
+[[- if .Schedule]]
+  schedule:
+    - cron: "[[.Schedule]]"
+[[- else]]
+  push:
+    branches: ["[[.Branch]]"]
+[[- end]]
+  workflow_dispatch:
+permissions:
+  contents: write
+  pull-requests: write
+jobs:
+  librarian:
+    runs-on: ubuntu-latest
+    env:
+      LIBRARIAN_GITHUB_TOKEN: ${{ secrets.[[.TokenSecret]] }}
+    steps:
+      - uses: actions/checkout@v4
+        with:
+          fetch-depth: 0
+      - uses: actions/setup-go@v5
+        with:
+          go-version: stable
+      - run: go install github.com/googleapis/librarian/cmd/librarian@[[.Version]]
+      - run: librarian [[shellJoin .Args]]
+`))
+
+	cloudBuildTemplate = template.Must(template.New("cloud-build").Delims("[[", "]]").Funcs(ciTemplateFuncs).Parse(`[[.Header]]
+# [[.Description]]
+# Import with "gcloud builds triggers import --source=<this file>".
+[[- if .Schedule]]
+# Run with Cloud Scheduler on the schedule "[[.Schedule]]" (UTC).
+[[- end]]
+name: librarian-[[.Name]]
+description: [[yamlQuote .Description]]
+[[- if .Schedule]]
+sourceToBuild:
+  uri: https://[[host .Repo]]/[[.Repo.Owner]]/[[.Repo.Name]]
+  ref: refs/heads/[[.Branch]]
+  repoType: GITHUB
+[[- else]]
+github:
+  owner: [[.Repo.Owner]]
+  name: [[.Repo.Name]]
+  push:
+    branch: [[yamlQuote (printf "^%s$" (regexpQuote .Branch))]]
+[[- end]]
+build:
+  steps:
+    - name: [[yamlQuote .Image]]
+      args:
+[[- range .Args]]
+        - [[yamlQuote .]]
+[[- end]]
+      secretEnv: ['LIBRARIAN_GITHUB_TOKEN']
+  availableSecrets:
+    secretManager:
+      - versionName: projects/$PROJECT_ID/secrets/[[.TokenSecret]]/versions/latest
+        env: 'LIBRARIAN_GITHUB_TOKEN'
+  options:
+    logging: CLOUD_LOGGING_ONLY
+`))
+
+	kokoroConfigTemplate = template.Must(template.New("kokoro-config").Delims("[[", "]]").Funcs(ciTemplateFuncs).Parse(`[[.Header]]
+# [[.Description]]
+[[- if .Schedule]]
+# Configure the job to run on the schedule "[[.Schedule]]" (UTC).
+[[- else]]
+# Configure the job to run on pushes to the "[[.Branch]]" branch.
+[[- end]]
+build_file: "[[.Repo.Name]]/.kokoro/librarian-[[.Name]].sh"
+`))
+
+	kokoroScriptTemplate = template.Must(template.New("kokoro-script").Delims("[[", "]]").Funcs(ciTemplateFuncs).Parse(`#!/bin/bash
+[[.Header]]
+# [[.Description]]
+set -eo pipefail
+cd "${KOKORO_ARTIFACTS_DIR}/github/[[.Repo.Name]]"
+export LIBRARIAN_GITHUB_TOKEN="$(cat "${KOKORO_KEYSTORE_DIR}/[[.TokenSecret]]")"
+go install github.com/googleapis/librarian/cmd/librarian@[[.Version]]
+"$(go env GOPATH)/bin/librarian" [[shellJoin .Args]]
+`))
+)
+
+// ciFile is a file of CI trigger definitions, relative to the root of the
+// repository.
+type ciFile struct {
+	path       string
+	content    string
+	executable bool
+}
+
+// ciFileGlobs are the patterns matching the files which generate-ci may have
+// written, for any provider.
+var ciFileGlobs = []string{
+	".github/workflows/librarian-*.yaml",
+	".cloudbuild/librarian-*.yaml",
+	".kokoro/librarian-*.cfg",
+	".kokoro/librarian-*.sh",
+}
+
+// renderCIFiles renders the CI trigger definitions of triggers. repo is the
+// GitHub repository of the language repository, which is not needed for
+// GitHub Actions.
+func renderCIFiles(triggers *config.CITriggers, repo *github.Repository) ([]*ciFile, error) {
+	defaultSecret := "LIBRARIAN_GITHUB_TOKEN"
+	if triggers.Provider == config.CIProviderGitHubActions {
+		defaultSecret = "GITHUB_TOKEN"
+	}
+	var files []*ciFile
+	for _, workflow := range ciWorkflows(triggers) {
+		data := &ciTemplateData{
+			Header:      generatedCIHeader,
+			Name:        workflow.name,
+			Description: workflow.description,
+			Schedule:    workflow.schedule,
+			Args:        workflow.args,
+			Version:     cmp.Or(triggers.LibrarianVersion, "latest"),
+			Image:       triggers.Image,
+			Branch:      cmp.Or(triggers.Branch, "main"),
+			TokenSecret: cmp.Or(triggers.TokenSecret, defaultSecret),
+			Repo:        repo,
+		}
+		render := func(path string, tmpl *template.Template, executable bool) error {
+			var b strings.Builder
+			if err := tmpl.Execute(&b, data); err != nil {
+				return fmt.Errorf("failed to render %s: %w", path, err)
+			}
+			files = append(files, &ciFile{path: path, content: b.String(), executable: executable})
+			return nil
+		}
+		var err error
+		switch triggers.Provider {
+		case config.CIProviderGitHubActions:
+			err = render(fmt.Sprintf(".github/workflows/librarian-%s.yaml", workflow.name), gitHubActionsTemplate, false)
+		case config.CIProviderCloudBuild:
+			err = render(fmt.Sprintf(".cloudbuild/librarian-%s.yaml", workflow.name), cloudBuildTemplate, false)
+		case config.CIProviderKokoro:
+			if err = render(fmt.Sprintf(".kokoro/librarian-%s.cfg", workflow.name), kokoroConfigTemplate, false); err == nil {
+				err = render(fmt.Sprintf(".kokoro/librarian-%s.sh", workflow.name), kokoroScriptTemplate, true)
+			}
+		default:
+			err = fmt.Errorf("invalid ci triggers provider: %q", triggers.Provider)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// staleCIFiles returns the files in repoDir which generate-ci wrote, and which
+// are not among files, e.g. those of disabled workflows or of another
+// provider.
+func staleCIFiles(repoDir string, files []*ciFile) ([]string, error) {
+	var stale []string
+	for _, pattern := range ciFileGlobs {
+		matches, err := filepath.Glob(filepath.Join(repoDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			path, err := filepath.Rel(repoDir, match)
+			if err != nil {
+				return nil, err
+			}
+			path = filepath.ToSlash(path)
+			if slices.ContainsFunc(files, func(f *ciFile) bool { return f.path == path }) {
+				continue
+			}
+			content, err := os.ReadFile(match)
+			if err != nil {
+				return nil, err
+			}
+			if strings.Contains(string(content), generatedCIHeader) {
+				stale = append(stale, path)
+			}
+		}
+	}
+	return stale, nil
+}
+
+func generateCI(cfg *config.Config) error {
+	if isRemote(cfg.Repo) {
+		return failure.New(failure.UserConfig, errors.New("generate-ci requires -repo to be a local directory"))
+	}
+	repoDir, err := filepath.Abs(cfg.Repo)
+	if err != nil {
+		return err
+	}
+	lc, err := parseLibrarianConfig(filepath.Join(repoDir, config.LibrarianDir, librarianConfigFile))
+	if err != nil {
+		return err
+	}
+	if lc == nil || lc.CITriggers == nil {
+		return failure.New(failure.UserConfig, fmt.Errorf("no ci_triggers in %s", librarianConfigFile))
+	}
+	var ghRepo *github.Repository
+	if lc.CITriggers.Provider != config.CIProviderGitHubActions {
+		repo, err := gitrepo.NewRepository(&gitrepo.RepositoryOptions{Dir: repoDir})
+		if err != nil {
+			return err
+		}
+		if ghRepo, err = gitHubRepository(cfg, repo); err != nil {
+			return err
+		}
+	}
+	// The triggers run the librarian version pinned by the language
+	// repository, unless they pin their own.
+	triggers := *lc.CITriggers
+	triggers.LibrarianVersion = cmp.Or(triggers.LibrarianVersion, lc.LibrarianVersion)
+	files, err := renderCIFiles(&triggers, ghRepo)
+	if err != nil {
+		return err
+	}
+	stale, err := staleCIFiles(repoDir, files)
+	if err != nil {
+		return err
+	}
+	if cfg.Verify {
+		return verifyCIFiles(repoDir, files, stale)
+	}
+	for _, file := range files {
+		path := filepath.Join(repoDir, file.path)
+		if err := writeFile(path, file.content); err != nil {
+			return err
+		}
+		if file.executable {
+			if err := os.Chmod(path, 0755); err != nil {
+				return err
+			}
+		}
+		slog.Info("Wrote CI trigger definition", "path", file.path)
+	}
+	for _, path := range stale {
+		if err := os.Remove(filepath.Join(repoDir, path)); err != nil {
+			return err
+		}
+		slog.Info("Removed stale CI trigger definition", "path", path)
+	}
+	return nil
+}
+
+// verifyCIFiles checks that the files in repoDir match files, and that there
+// are no stale files.
+func verifyCIFiles(repoDir string, files []*ciFile, stale []string) error {
+	var outdated []string
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(repoDir, file.path))
+		if errors.Is(err, os.ErrNotExist) {
+			outdated = append(outdated, file.path+" (missing)")
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if string(content) != file.content {
+			outdated = append(outdated, file.path)
+		}
+	}
+	for _, path := range stale {
+		outdated = append(outdated, path+" (stale)")
+	}
+	if len(outdated) > 0 {
+		return failure.New(failure.UserConfig, fmt.Errorf("CI trigger definitions are out of date, run librarian generate-ci: %s", strings.Join(outdated, ", ")))
+	}
+	slog.Info("CI trigger definitions are up to date")
+	return nil
+}
+
+// shellQuoteArg quotes s for a POSIX shell, unless it only contains
+// characters which need no quoting.
+func shellQuoteArg(s string) string {
+	if s != "" && !strings.ContainsFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_=./,:@", r))
+	}) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,229 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/github"
+)
+
+func TestRenderCIFiles(t *testing.T) {
+	repo := &github.Repository{Owner: "googleapis", Name: "google-cloud-go"}
+	for _, test := range []struct {
+		name      string
+		triggers  *config.CITriggers
+		wantPaths []string
+		// wantContains maps paths to lines which their content must contain.
+		wantContains map[string][]string
+	}{
+		{
+			name: "github actions",
+			triggers: &config.CITriggers{
+				Provider:         config.CIProviderGitHubActions,
+				LibrarianVersion: "v0.3.0",
+				Workflows: []*config.CIWorkflow{
+					{Name: config.CIWorkflowGenerate, Schedule: "0 2 * * *", Flags: []string{"-build"}},
+					{Name: config.CIWorkflowReleaseInit, Disabled: true},
+				},
+			},
+			wantPaths: []string{
+				".github/workflows/librarian-generate.yaml",
+				".github/workflows/librarian-tag-and-release.yaml",
+			},
+			wantContains: map[string][]string{
+				".github/workflows/librarian-generate.yaml": {
+					`    - cron: "0 2 * * *"`,
+					"      - run: go install github.com/googleapis/librarian/cmd/librarian@v0.3.0",
+					"      - run: librarian generate -repo=. -push -build",
+					"      LIBRARIAN_GITHUB_TOKEN: ${{ secrets.GITHUB_TOKEN }}",
+				},
+				".github/workflows/librarian-tag-and-release.yaml": {
+					`    branches: ["main"]`,
+					"      - run: librarian release tag-and-release -repo=.",
+				},
+			},
+		},
+		{
+			name: "cloud build",
+			triggers: &config.CITriggers{
+				Provider:    config.CIProviderCloudBuild,
+				Image:       "us-docker.pkg.dev/project/images/librarian:v1",
+				Branch:      "release.x",
+				TokenSecret: "github-token",
+			},
+			wantPaths: []string{
+				".cloudbuild/librarian-generate.yaml",
+				".cloudbuild/librarian-release-init.yaml",
+				".cloudbuild/librarian-tag-and-release.yaml",
+			},
+			wantContains: map[string][]string{
+				".cloudbuild/librarian-release-init.yaml": {
+					`# Run with Cloud Scheduler on the schedule "0 6 * * 1" (UTC).`,
+					"  uri: https://github.com/googleapis/google-cloud-go",
+					"  ref: refs/heads/release.x",
+					"    - name: 'us-docker.pkg.dev/project/images/librarian:v1'",
+					"        - 'init'",
+					"      - versionName: projects/$PROJECT_ID/secrets/github-token/versions/latest",
+				},
+				".cloudbuild/librarian-tag-and-release.yaml": {
+					"  owner: googleapis",
+					`    branch: '^release\.x$'`,
+				},
+			},
+		},
+		{
+			name: "kokoro",
+			triggers: &config.CITriggers{
+				Provider: config.CIProviderKokoro,
+				Workflows: []*config.CIWorkflow{
+					{Name: config.CIWorkflowGenerate, Flags: []string{"-library=it's"}},
+				},
+			},
+			wantPaths: []string{
+				".kokoro/librarian-generate.cfg",
+				".kokoro/librarian-generate.sh",
+				".kokoro/librarian-release-init.cfg",
+				".kokoro/librarian-release-init.sh",
+				".kokoro/librarian-tag-and-release.cfg",
+				".kokoro/librarian-tag-and-release.sh",
+			},
+			wantContains: map[string][]string{
+				".kokoro/librarian-generate.cfg": {
+					`build_file: "google-cloud-go/.kokoro/librarian-generate.sh"`,
+				},
+				".kokoro/librarian-generate.sh": {
+					`export LIBRARIAN_GITHUB_TOKEN="$(cat "${KOKORO_KEYSTORE_DIR}/LIBRARIAN_GITHUB_TOKEN")"`,
+					`"$(go env GOPATH)/bin/librarian" generate -repo=. -push '-library=it'\''s'`,
+				},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			files, err := renderCIFiles(test.triggers, repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			contents := make(map[string]string)
+			for _, file := range files {
+				paths = append(paths, file.path)
+				contents[file.path] = file.content
+				if !strings.HasPrefix(file.content, generatedCIHeader) && !strings.HasPrefix(file.content, "#!/bin/bash\n"+generatedCIHeader) {
+					t.Errorf("%s does not start with the generated header:\n%s", file.path, file.content)
+				}
+			}
+			if diff := cmp.Diff(test.wantPaths, paths); diff != "" {
+				t.Errorf("renderCIFiles() paths mismatch (-want +got):\n%s", diff)
+			}
+			for path, lines := range test.wantContains {
+				for _, line := range lines {
+					if !strings.Contains(contents[path], line+"\n") {
+						t.Errorf("%s does not contain %q:\n%s", path, line, contents[path])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateCI(t *testing.T) {
+	repoDir := t.TempDir()
+	configYAML := "ci_triggers:\n  provider: github-actions\n"
+	if err := writeFile(filepath.Join(repoDir, config.LibrarianDir, librarianConfigFile), configYAML); err != nil {
+		t.Fatal(err)
+	}
+	staleKokoroFile := filepath.Join(repoDir, ".kokoro/librarian-generate.sh")
+	if err := writeFile(staleKokoroFile, "#!/bin/bash\n"+generatedCIHeader+"\n"); err != nil {
+		t.Fatal(err)
+	}
+	handwrittenFile := filepath.Join(repoDir, ".github/workflows/librarian-custom.yaml")
+	if err := writeFile(handwrittenFile, "name: custom\n"); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{Repo: repoDir}
+
+	cfg.Verify = true
+	err := generateCI(cfg)
+	if got := failure.CategoryOf(err); got != failure.UserConfig {
+		t.Fatalf("generateCI() with -verify before generation error = %v, want category %q", err, failure.UserConfig)
+	}
+	for _, want := range []string{"librarian-generate.yaml (missing)", ".kokoro/librarian-generate.sh (stale)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("generateCI() error = %v, want it to contain %q", err, want)
+		}
+	}
+
+	cfg.Verify = false
+	if err := generateCI(cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		".github/workflows/librarian-generate.yaml",
+		".github/workflows/librarian-release-init.yaml",
+		".github/workflows/librarian-tag-and-release.yaml",
+	} {
+		if _, err := os.Stat(filepath.Join(repoDir, path)); err != nil {
+			t.Errorf("generateCI() did not write %s: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(staleKokoroFile); !os.IsNotExist(err) {
+		t.Errorf("generateCI() did not remove the stale file, error = %v", err)
+	}
+	if _, err := os.Stat(handwrittenFile); err != nil {
+		t.Errorf("generateCI() removed a handwritten file: %v", err)
+	}
+
+	cfg.Verify = true
+	if err := generateCI(cfg); err != nil {
+		t.Errorf("generateCI() with -verify after generation error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, ".github/workflows/librarian-generate.yaml"), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := generateCI(cfg); err == nil || !strings.Contains(err.Error(), "librarian-generate.yaml") {
+		t.Errorf("generateCI() with -verify after an edit error = %v, want it to name the edited file", err)
+	}
+}
+
+func TestGenerateCI_NoTriggers(t *testing.T) {
+	err := generateCI(&config.Config{Repo: t.TempDir()})
+	if got := failure.CategoryOf(err); got != failure.UserConfig {
+		t.Errorf("generateCI() error = %v, want category %q", err, failure.UserConfig)
+	}
+}
+
+func TestShellQuoteArg(t *testing.T) {
+	for _, test := range []struct {
+		arg  string
+		want string
+	}{
+		{arg: "-repo=.", want: "-repo=."},
+		{arg: "a b", want: "'a b'"},
+		{arg: "it's", want: `'it'\''s'`},
+		{arg: "", want: "''"},
+		{arg: "$HOME", want: "'$HOME'"},
+	} {
+		if got := shellQuoteArg(test.arg); got != test.want {
+			t.Errorf("shellQuoteArg(%q) = %q, want %q", test.arg, got, test.want)
+		}
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/google/go-cmp/cmp"
 	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
 	"github.com/googleapis/librarian/internal/gitrepo"
 )
 
@@ -192,7 +193,7 @@ func TestRunBuildCommand(t *testing.T) {
 				containerClient: test.container,
 			}
 
-			err := r.runBuildCommand(context.Background(), test.libraryID)
+			err := r.runBuildCommand(context.Background(), test.libraryID, nil)
 			if test.wantErr {
 				if err == nil {
 					t.Errorf("%s should return error", test.name)
@@ -539,11 +540,13 @@ func TestGenerateScenarios(t *testing.T) {
 		container          *mockContainerClient
 		ghClient           GitHubClient
 		build              bool
+		phases             string
 		wantErr            bool
 		wantErrMsg         string
 		wantGenerateCalls  int
 		wantBuildCalls     int
 		wantConfigureCalls int
+		wantTestCalls      int
 	}{
 		{
 			name:    "generate single library including initial configuration",
@@ -565,6 +568,20 @@ func TestGenerateScenarios(t *testing.T) {
 			wantBuildCalls:     1,
 			wantConfigureCalls: 1,
 		},
+		{
+			name:    "prewarm fails",
+			library: "some-library",
+			repo:    newTestGitRepo(t),
+			state: &config.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+			},
+			container: &mockContainerClient{
+				prewarmErr: errors.New("prewarm failed"),
+			},
+			ghClient:   &mockGitHubClient{},
+			wantErr:    true,
+			wantErrMsg: "failed to prewarm container image",
+		},
 		{
 			name:    "generate single existing library by library id",
 			library: "some-library",
@@ -590,6 +607,62 @@ func TestGenerateScenarios(t *testing.T) {
 			wantBuildCalls:     1,
 			wantConfigureCalls: 0,
 		},
+		{
+			name:    "build and test single existing library without generation",
+			library: "some-library",
+			repo:    newTestGitRepo(t),
+			state: &config.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*config.LibraryState{
+					{
+						ID:          "some-library",
+						APIs:        []*config.API{{Path: "some/api"}},
+						SourceRoots: []string{"src/a"},
+					},
+				},
+			},
+			container:      &mockContainerClient{},
+			ghClient:       &mockGitHubClient{},
+			phases:         "build,test",
+			wantBuildCalls: 1,
+			wantTestCalls:  1,
+		},
+		{
+			name:    "test fails",
+			library: "some-library",
+			repo:    newTestGitRepo(t),
+			state: &config.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*config.LibraryState{
+					{
+						ID:          "some-library",
+						APIs:        []*config.API{{Path: "some/api"}},
+						SourceRoots: []string{"src/a"},
+					},
+				},
+			},
+			container: &mockContainerClient{
+				testErr: errors.New("integration tests failed"),
+			},
+			ghClient:   &mockGitHubClient{},
+			phases:     "test",
+			wantErr:    true,
+			wantErrMsg: "integration tests failed",
+		},
+		{
+			name:    "new library without configure phase",
+			api:     "some/api",
+			library: "some-library",
+			repo:    newTestGitRepo(t),
+			state: &config.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+			},
+			container:  &mockContainerClient{},
+			ghClient:   &mockGitHubClient{},
+			phases:     "generate,build",
+			wantErr:    true,
+			wantErrMsg: `the "configure" phase is not selected`,
+		},
 		{
 			name: "generate single existing library by api",
 			api:  "some/api",
@@ -878,6 +951,7 @@ func TestGenerateScenarios(t *testing.T) {
 				Library:   test.library,
 				APISource: t.TempDir(),
 				Build:     test.build,
+				Phases:    test.phases,
 			}
 
 			r := &generateRunner{
@@ -934,6 +1008,79 @@ func TestGenerateScenarios(t *testing.T) {
 			if diff := cmp.Diff(test.wantConfigureCalls, test.container.configureCalls); diff != "" {
 				t.Errorf("%s: run() configureCalls mismatch (-want +got):%s", test.name, diff)
 			}
+			if diff := cmp.Diff(test.wantTestCalls, test.container.testCalls); diff != "" {
+				t.Errorf("%s: run() testCalls mismatch (-want +got):%s", test.name, diff)
+			}
+		})
+	}
+}
+
+func TestApplyServiceConfig(t *testing.T) {
+	t.Parallel()
+	source := t.TempDir()
+	path := filepath.Join(source, "google", "cloud", "foo", "v1", "foo_v1.yaml")
+	if err := writeFile(path, "type: google.api.Service\nname: foo.googleapis.com\n"); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name        string
+		api         string
+		library     string
+		libraries   []*config.LibraryState
+		wantLibrary string
+		wantErr     bool
+	}{
+		{
+			name:        "new library",
+			wantLibrary: "google-cloud-foo-v1",
+		},
+		{
+			name:        "new library with ID",
+			library:     "foo",
+			wantLibrary: "foo",
+		},
+		{
+			name: "existing library",
+			libraries: []*config.LibraryState{
+				{ID: "foo", APIs: []*config.API{{Path: "google/cloud/foo/v1"}}},
+			},
+			wantLibrary: "foo",
+		},
+		{
+			name:    "mismatching API",
+			api:     "google/cloud/bar/v1",
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := &generateRunner{
+				cfg: &config.Config{
+					API:           test.api,
+					APISource:     source,
+					Library:       test.library,
+					ServiceConfig: path,
+				},
+				state: &config.LibrarianState{Libraries: test.libraries},
+			}
+			err := r.applyServiceConfig()
+			if test.wantErr {
+				if failure.CategoryOf(err) != failure.UserConfig {
+					t.Errorf("applyServiceConfig() error = %v, want %q error", err, failure.UserConfig)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.cfg.API != "google/cloud/foo/v1" || r.cfg.Library != test.wantLibrary {
+				t.Errorf("applyServiceConfig() set -api %q and -library %q, want %q and %q", r.cfg.API, r.cfg.Library, "google/cloud/foo/v1", test.wantLibrary)
+			}
+			if library := findLibraryByID(r.state, r.cfg.Library); library != nil && library.APIs[0].ServiceConfig != "foo_v1.yaml" {
+				t.Errorf("service config of existing library = %q, want %q", library.APIs[0].ServiceConfig, "foo_v1.yaml")
+			}
+			if got := r.serviceConfigName(); got != "foo_v1.yaml" {
+				t.Errorf("serviceConfigName() = %q, want %q", got, "foo_v1.yaml")
+			}
 		})
 	}
 }
@@ -963,6 +1110,28 @@ func TestUpdateLastGeneratedCommitState(t *testing.T) {
 	}
 }
 
+func TestUpdateLastGeneratedCommitStateDirtyAPISource(t *testing.T) {
+	t.Parallel()
+	r := &generateRunner{
+		sourceRepo: newTestGitRepo(t),
+		apiSource:  &apiSourceProvenance{Dirty: true},
+		state: &config.LibrarianState{
+			Libraries: []*config.LibraryState{
+				{
+					ID:                  "some-library",
+					LastGeneratedCommit: "previous",
+				},
+			},
+		},
+	}
+	if err := r.updateLastGeneratedCommitState("some-library"); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.state.Libraries[0].LastGeneratedCommit; got != "previous" {
+		t.Errorf("updateState() got = %v, want %v", got, "previous")
+	}
+}
+
 func TestUpdateChangesSinceLastGeneration(t *testing.T) {
 	t.Parallel()
 	hash1 := plumbing.NewHash("1234567")
@@ -1136,3 +1305,156 @@ func TestUpdateChangesSinceLastGeneration(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateRunner_RetryFailed(t *testing.T) {
+	state := &config.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*config.LibraryState{
+			{ID: "lib1", APIs: []*config.API{{Path: "some/api1"}}, SourceRoots: []string{"src/a"}},
+			{ID: "lib2", APIs: []*config.API{{Path: "some/api2"}}, SourceRoots: []string{"src/b"}},
+		},
+	}
+	sourceRepo := newTestGitRepo(t)
+	r := &generateRunner{
+		cfg:             &config.Config{APISource: t.TempDir()},
+		repo:            newTestGitRepo(t),
+		sourceRepo:      sourceRepo,
+		state:           state,
+		containerClient: &mockContainerClient{generateErr: errors.New("generate error")},
+		ghClient:        &mockGitHubClient{},
+		workRoot:        t.TempDir(),
+	}
+	if err := r.run(context.Background()); err == nil {
+		t.Fatal("run() succeeded, want an error")
+	}
+	reportPath := filepath.Join(r.workRoot, generationReportJSONFile)
+	report, err := readGenerationReport(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCommit, err := sourceRepo.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &generationReport{SourceCommit: wantCommit, Failed: []string{"lib1", "lib2"}}
+	if diff := cmp.Diff(want, report); diff != "" {
+		t.Errorf("generation report mismatch (-want +got):\n%s", diff)
+	}
+
+	r.retryLibraryIDs = []string{"lib2", "removed"}
+	libraries, err := r.librariesToRegenerate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(libraries) != 1 || libraries[0].ID != "lib2" {
+		t.Errorf("librariesToRegenerate() = %v, want [lib2]", libraries)
+	}
+	r.retryLibraryIDs = []string{"removed"}
+	if _, err := r.librariesToRegenerate(); failure.CategoryOf(err) != failure.UserConfig {
+		t.Errorf("librariesToRegenerate() error = %v, want a %s error", err, failure.UserConfig)
+	}
+
+	if err := writeGenerationReport(r.workRoot, &generationReport{SourceCommit: wantCommit}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newGenerateRunner(&config.Config{RetryFailedFrom: reportPath}); failure.CategoryOf(err) != failure.UserConfig {
+		t.Errorf("newGenerateRunner() of a report without failures error = %v, want a %s error", err, failure.UserConfig)
+	}
+}
+
+func TestGenerateRunner_Blocked(t *testing.T) {
+	state := &config.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*config.LibraryState{
+			{ID: "lib1", APIs: []*config.API{{Path: "some/api1"}}, SourceRoots: []string{"src/a"}, Blocked: &config.GenerationBlock{Reason: "generator bug"}},
+			{ID: "lib2", APIs: []*config.API{{Path: "some/api2"}}, SourceRoots: []string{"src/b"}},
+		},
+	}
+	sourceRepo := newTestGitRepo(t)
+	containerClient := &mockContainerClient{generateErr: errors.New("generate error")}
+	r := &generateRunner{
+		cfg:             &config.Config{APISource: t.TempDir()},
+		repo:            newTestGitRepo(t),
+		sourceRepo:      sourceRepo,
+		state:           state,
+		containerClient: containerClient,
+		ghClient:        &mockGitHubClient{},
+		workRoot:        t.TempDir(),
+	}
+	err := r.run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "all 1 libraries failed to generate") {
+		t.Fatalf("run() error = %v, want the failure of the library which is not blocked", err)
+	}
+	if containerClient.generateCalls != 1 {
+		t.Errorf("generateCalls = %d, want 1", containerClient.generateCalls)
+	}
+	report, err := readGenerationReport(filepath.Join(r.workRoot, generationReportJSONFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCommit, err := sourceRepo.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &generationReport{SourceCommit: wantCommit, Failed: []string{"lib2"}, Blocked: []string{"lib1"}}
+	if diff := cmp.Diff(want, report); diff != "" {
+		t.Errorf("generation report mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateRunner_RollbackOnFailure(t *testing.T) {
+	repo := newTestGitRepo(t)
+	repoDir := repo.GetDir()
+	handwritten := filepath.Join(repoDir, "src/a/handwritten.go")
+	if err := os.MkdirAll(filepath.Dir(handwritten), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(handwritten, []byte("package a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "add library")
+	sourceRepo := newTestGitRepo(t)
+	lastGenerated, err := sourceRepo.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, sourceRepo.GetDir(), "commit", "--allow-empty", "-m", "feat: update some/api/v1beta")
+	state := &config.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*config.LibraryState{
+			{
+				ID:                  "some-library",
+				APIs:                []*config.API{{Path: "some/api/v1"}, {Path: "some/api/v1beta"}},
+				SourceRoots:         []string{"src/a"},
+				LastGeneratedCommit: lastGenerated,
+			},
+		},
+	}
+	containerClient := &mockContainerClient{buildErr: errors.New("build error"), wantLibraryGen: true}
+	r := &generateRunner{
+		cfg:             &config.Config{APISource: t.TempDir(), Library: "some-library", Build: true},
+		repo:            repo,
+		sourceRepo:      sourceRepo,
+		state:           state,
+		containerClient: containerClient,
+		ghClient:        &mockGitHubClient{},
+		workRoot:        t.TempDir(),
+		dependencies:    make(map[string][]*config.Dependency),
+	}
+	if err := r.run(context.Background()); err == nil || !strings.Contains(err.Error(), "build error") {
+		t.Fatalf("run() error = %v, want the build error", err)
+	}
+	if containerClient.generateCalls != 1 {
+		t.Errorf("generateCalls = %d, want 1 for all the APIs of the library", containerClient.generateCalls)
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, "src/a/example.txt")); !os.IsNotExist(err) {
+		t.Errorf("generated file was not rolled back, stat error = %v", err)
+	}
+	if _, err := os.Stat(handwritten); err != nil {
+		t.Errorf("handwritten file was not restored: %v", err)
+	}
+	if got := state.Libraries[0].LastGeneratedCommit; got != lastGenerated {
+		t.Errorf("LastGeneratedCommit = %q, want %q", got, lastGenerated)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// worktreesDir is the directory of the work root holding the worktrees of
+// the libraries generated in parallel.
+const worktreesDir = "worktrees"
+
+// worktreeBranch returns the branch on which the changes of the library
+// generated in a worktree are committed.
+func worktreeBranch(libraryID string) string {
+	return fmt.Sprintf("librarian-generate-%s", libraryID)
+}
+
+// libraryWorktree is the generation of a library in its own worktree.
+type libraryWorktree struct {
+	libraryID string
+	repo      *gitrepo.LocalRepository
+	// runner generates the library in repo, recording its dependencies and
+	// container results apart from those of the other libraries.
+	runner *generateRunner
+	// commit is the commit of the changes of the library, or empty if it did
+	// not change.
+	commit string
+	err    error
+}
+
+// generateInWorktrees generates the libraries, -parallel at a time, each in
+// its own worktree of the language repository, so that their generation,
+// build and commit do not share a working tree and an index. The changes of
+// each library are committed on its branch, see worktreeBranch, and the
+// commits are applied to the working tree of the language repository in the
+// order of libraries, updating the state of each library as if they were
+// generated one after another. The worktrees are removed, but the branches
+// are kept, e.g. to propose the changes of a library on their own.
+//
+// It returns the IDs of the generated libraries, and those of the libraries
+// which failed to generate.
+func (r *generateRunner) generateInWorktrees(ctx context.Context, libraries []*config.LibraryState, outputDir string) (generated, failed []string, err error) {
+	repo, ok := r.repo.(*gitrepo.LocalRepository)
+	if !ok {
+		return nil, nil, errors.New("-parallel requires a local language repository")
+	}
+	// The worktrees are added and removed one at a time, as git locks the
+	// repository while doing so.
+	var worktrees []*libraryWorktree
+	defer func() {
+		for _, worktree := range worktrees {
+			if err := repo.RemoveWorktree(worktree.repo.GetDir()); err != nil {
+				slog.Warn("failed to remove worktree", "library", worktree.libraryID, "err", err)
+			}
+		}
+	}()
+	for _, library := range libraries {
+		dir := filepath.Join(r.workRoot, worktreesDir, library.ID)
+		worktreeRepo, err := repo.AddWorktree(dir, worktreeBranch(library.ID))
+		if err != nil {
+			return nil, nil, err
+		}
+		runner := *r
+		runner.repo = worktreeRepo
+		runner.dependencies = make(map[string][]*config.Dependency)
+		runner.breakingChanges = nil
+		runner.matrix = nil
+		runner.containers = nil
+		runner.deferRecording = true
+		worktree := &libraryWorktree{libraryID: library.ID, repo: worktreeRepo, runner: &runner}
+		// The API source is read one library at a time, before the
+		// libraries are generated.
+		worktree.err = r.checkLibraryAPIs(library)
+		worktrees = append(worktrees, worktree)
+	}
+
+	slog.Info("Generating libraries in worktrees", "libraries", len(worktrees), "parallel", r.cfg.Parallel)
+	slots := make(chan struct{}, r.cfg.Parallel)
+	var wg sync.WaitGroup
+	for _, worktree := range worktrees {
+		if worktree.err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			worktree.commit, worktree.err = worktree.generate(ctx, outputDir)
+		}()
+	}
+	wg.Wait()
+
+	for _, worktree := range worktrees {
+		r.containers = append(r.containers, worktree.runner.containers...)
+		r.matrix = append(r.matrix, worktree.runner.matrix...)
+		if worktree.err != nil {
+			slog.Error("failed to generate library", "id", worktree.libraryID, "err", worktree.err)
+			failed = append(failed, worktree.libraryID)
+			continue
+		}
+		for id, dependencies := range worktree.runner.dependencies {
+			r.dependencies[id] = dependencies
+		}
+		if worktree.commit != "" {
+			if err := repo.ApplyCommit(worktree.commit); err != nil {
+				return nil, nil, fmt.Errorf("failed to apply the changes of library %s: %w", worktree.libraryID, err)
+			}
+		}
+		// The state is derived from the API source, which is only read
+		// here, one library at a time.
+		if r.cfg.RunsPhase(config.PhaseGenerate) {
+			if err := r.recordGeneration(worktree.libraryID); err != nil {
+				return nil, nil, err
+			}
+		}
+		generated = append(generated, worktree.libraryID)
+	}
+	return generated, failed, nil
+}
+
+// generate runs the phases of the library in its worktree, as
+// generateSingleLibrary does, and commits the changes to its files. It
+// returns the hash of the commit, or an empty string if no file of the
+// library changed.
+func (w *libraryWorktree) generate(ctx context.Context, outputDir string) (string, error) {
+	r := w.runner
+	library := findLibraryByID(r.state, w.libraryID)
+	if len(library.APIs) == 0 {
+		slog.Info("library has no APIs; skipping generation", "library", w.libraryID)
+		return "", nil
+	}
+	if err := r.generateLibrary(ctx, library, outputDir); err != nil {
+		return "", err
+	}
+
+	status, err := w.repo.Status()
+	if err != nil {
+		return "", err
+	}
+	var paths []string
+	for _, path := range sortedStatusFiles(status) {
+		if libraryForFile(r.state, []string{w.libraryID}, path) == w.libraryID {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return "", nil
+	}
+	if err := w.repo.AddPaths(paths); err != nil {
+		return "", err
+	}
+	if err := w.repo.Commit(fmt.Sprintf("feat(%s): regenerate", w.libraryID)); err != nil {
+		return "", err
+	}
+	return w.repo.HeadHash()
+}
@@ -0,0 +1,249 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/docker"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeDockerScript is a docker command which runs the containers of the
+// tests of generateInWorktrees. The generate command writes a file into the
+// source root of the library, "src/{library-id}". The generation of lib1 is
+// the slowest, so that it finishes after the others. The runs listed in
+// FAKE_DOCKER_FAILURES, e.g. "generate lib3,build lib2", fail. Each run is
+// appended to the runs file next to the script. Other docker commands, e.g.
+// to pull the image, succeed without output.
+const fakeDockerScript = `#!/bin/sh
+[ "$1" = run ] || exit 0
+dir=$(dirname "$0")
+for arg in "$@"; do
+	case "$arg" in
+	*:/librarian) librarian=${arg%:/librarian} ;;
+	*:/output) output=${arg%:/output} ;;
+	generate|build) command=$arg ;;
+	esac
+done
+id=$(sed -n 's/.*"id": *"\([^"]*\)".*/\1/p' "$librarian/$command-request.json" | head -n 1)
+echo "$command $id" >> "$dir/runs"
+echo "{\"id\": \"$id\"}" > "$librarian/$command-response.json"
+case ",$FAKE_DOCKER_FAILURES," in
+*",$command $id,"*) exit 1 ;;
+esac
+case "$command $id" in
+"generate lib1") sleep 0.2 ;;
+esac
+if [ "$command" = generate ]; then
+	mkdir -p "$output/src/$id" && echo "$id" > "$output/src/$id/example.txt"
+fi
+`
+
+// newFakeDocker returns a Docker client for image which runs fakeDockerScript
+// instead of docker, with the runs listed in failures failing, and the
+// directory of the script, which holds its runs file.
+func newFakeDocker(t *testing.T, image, failures string) (*docker.Docker, string) {
+	t.Helper()
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "docker"), []byte(fakeDockerScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_DOCKER_FAILURES", failures)
+	containerClient, err := docker.New("", image, "", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return containerClient, bin
+}
+
+func TestGenerateRunner_Parallel(t *testing.T) {
+	repo := newTestGitRepo(t)
+	sourceRepo := newTestGitRepo(t)
+	state := &config.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*config.LibraryState{
+			{ID: "lib1", APIs: []*config.API{{Path: "some/api1"}}, SourceRoots: []string{"src/lib1"}},
+			{ID: "lib2", APIs: []*config.API{{Path: "some/api2"}}, SourceRoots: []string{"src/lib2"}},
+			{ID: "lib3", APIs: []*config.API{{Path: "some/api3"}}, SourceRoots: []string{"src/lib3"}},
+		},
+	}
+	// The libraries generated in parallel share the Docker client, which
+	// records their container runs.
+	containerClient, bin := newFakeDocker(t, state.Image, "generate lib3,build lib2")
+	containerClient.RecordDir = t.TempDir()
+	r := &generateRunner{
+		cfg:             &config.Config{APISource: t.TempDir(), Parallel: 2, Build: true},
+		repo:            repo,
+		sourceRepo:      sourceRepo,
+		state:           state,
+		containerClient: containerClient,
+		ghClient:        &mockGitHubClient{},
+		workRoot:        t.TempDir(),
+		dependencies:    make(map[string][]*config.Dependency),
+	}
+	if err := r.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(bin, "runs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := strings.Split(strings.TrimSpace(string(data)), "\n")
+	slices.Sort(runs)
+	if diff := cmp.Diff([]string{"build lib1", "build lib2", "generate lib1", "generate lib2", "generate lib3"}, runs); diff != "" {
+		t.Errorf("container runs mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, recording := range []string{"lib1/001-generate", "lib1/002-build", "lib2/001-generate", "lib2/002-build", "lib3/001-generate"} {
+		if _, err := os.Stat(filepath.Join(containerClient.RecordDir, recording, "invocation.json")); err != nil {
+			t.Errorf("container run %s was not recorded: %v", recording, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(repo.GetDir(), "src/lib1/example.txt")); err != nil {
+		t.Errorf("generated file was not applied to the repository: %v", err)
+	}
+	// lib2 is rolled back after its build failed, as it would be without
+	// -parallel.
+	for _, path := range []string{"src/lib2", "src/lib3"} {
+		if _, err := os.Stat(filepath.Join(repo.GetDir(), path)); !os.IsNotExist(err) {
+			t.Errorf("files of the failed library were applied to the repository, stat error = %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(r.workRoot, worktreesDir, "lib1")); !os.IsNotExist(err) {
+		t.Errorf("worktree was not removed, stat error = %v", err)
+	}
+	for _, id := range []string{"lib1", "lib2"} {
+		if _, err := repo.(*gitrepo.LocalRepository).ResolveRef(worktreeBranch(id)); err != nil {
+			t.Errorf("branch of library %s was not kept: %v", id, err)
+		}
+	}
+
+	head, err := sourceRepo.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, library := range state.Libraries {
+		got = append(got, library.LastGeneratedCommit)
+	}
+	if diff := cmp.Diff([]string{head, "", ""}, got); diff != "" {
+		t.Errorf("LastGeneratedCommit of the libraries mismatch (-want +got):\n%s", diff)
+	}
+	report, err := readGenerationReport(filepath.Join(r.workRoot, generationReportJSONFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"lib2", "lib3"}, report.Failed); diff != "" {
+		t.Errorf("failed libraries mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateRunner_ParallelAppliesEveryLibrary(t *testing.T) {
+	repo := newTestGitRepo(t)
+	sourceRepo := newTestGitRepo(t)
+	state := &config.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*config.LibraryState{
+			{ID: "lib1", APIs: []*config.API{{Path: "some/api1"}}, SourceRoots: []string{"src/lib1"}},
+			{ID: "lib2", APIs: []*config.API{{Path: "some/api2"}}, SourceRoots: []string{"src/lib2"}},
+			{ID: "lib3", APIs: []*config.API{{Path: "some/api3"}}, SourceRoots: []string{"src/lib3"}},
+		},
+	}
+	containerClient, _ := newFakeDocker(t, state.Image, "")
+	r := &generateRunner{
+		cfg:             &config.Config{APISource: t.TempDir(), Parallel: 3},
+		repo:            repo,
+		sourceRepo:      sourceRepo,
+		state:           state,
+		containerClient: containerClient,
+		ghClient:        &mockGitHubClient{},
+		workRoot:        t.TempDir(),
+		dependencies:    make(map[string][]*config.Dependency),
+	}
+	if err := r.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The commits of the worktrees are applied one after another to the
+	// working tree of the language repository.
+	for _, id := range []string{"lib1", "lib2", "lib3"} {
+		data, err := os.ReadFile(filepath.Join(repo.GetDir(), "src", id, "example.txt"))
+		if err != nil {
+			t.Errorf("changes of library %s were not applied to the repository: %v", id, err)
+			continue
+		}
+		if got := strings.TrimSpace(string(data)); got != id {
+			t.Errorf("src/%s/example.txt = %q, want %q", id, got, id)
+		}
+	}
+	report, err := readGenerationReport(filepath.Join(r.workRoot, generationReportJSONFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// lib1 finishes last, but is applied first.
+	var got []string
+	for _, library := range report.Libraries {
+		var paths []string
+		for _, file := range library.Files {
+			paths = append(paths, file.Path)
+		}
+		got = append(got, fmt.Sprintf("%s: %s", library.ID, strings.Join(paths, ", ")))
+	}
+	want := []string{
+		"lib1: src/lib1/example.txt",
+		"lib2: src/lib2/example.txt",
+		"lib3: src/lib3/example.txt",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("generated libraries mismatch (-want +got):\n%s", diff)
+	}
+	if len(report.Failed) != 0 {
+		t.Errorf("failed libraries = %v, want none", report.Failed)
+	}
+
+	head, err := sourceRepo.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(repo.GetDir(), config.LibrarianDir, librarianStateFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := &config.LibrarianState{}
+	if err := yaml.Unmarshal(data, saved); err != nil {
+		t.Fatal(err)
+	}
+	for _, library := range saved.Libraries {
+		if library.LastGeneratedCommit != head {
+			t.Errorf("LastGeneratedCommit of library %s = %q, want %q", library.ID, library.LastGeneratedCommit, head)
+		}
+	}
+	if diff := cmp.Diff(state, saved, cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("saved state mismatch (-want +got):\n%s", diff)
+	}
+}
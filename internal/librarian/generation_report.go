@@ -0,0 +1,347 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+const (
+	generationReportJSONFile     = "generation-report.json"
+	generationReportMarkdownFile = "generation-report.md"
+)
+
+// The kinds of changed files in a generation report.
+const (
+	// fileKindGenerated is a file written or removed by the generator.
+	fileKindGenerated = "generated"
+	// fileKindHandwritten is a file matching the preserve_regex of its
+	// library, i.e. a human-authored file, which generation changed.
+	fileKindHandwritten = "handwritten"
+)
+
+// The changes of a file in a generation report.
+const (
+	fileChangeAdded    = "added"
+	fileChangeModified = "modified"
+	fileChangeDeleted  = "deleted"
+)
+
+// generationReport describes the changes made to the language repository by a
+// generation run.
+type generationReport struct {
+	// SourceCommit is the commit of the API source repository the libraries
+	// were generated from.
+	SourceCommit string                     `json:"source_commit,omitempty"`
+	Libraries    []*libraryGenerationReport `json:"libraries"`
+	// Failed lists the IDs of the libraries which failed to generate. They
+	// are generated again by a run with -retry-failed-from.
+	Failed []string `json:"failed,omitempty"`
+	// Blocked lists the IDs of the libraries which were skipped because their
+	// generation is blocked.
+	Blocked []string `json:"blocked,omitempty"`
+	// RemovedAPIs lists the APIs which no longer exist in the API source.
+	// Their libraries are blocked, and listed in Blocked.
+	RemovedAPIs []*removedAPI `json:"removed_apis,omitempty"`
+	// Other lists the changed files which do not belong to a library.
+	Other []*changedFile `json:"other,omitempty"`
+	// NoOp reports whether none of the changes is meaningful, in which case
+	// nothing is committed.
+	NoOp bool `json:"no_op,omitempty"`
+	// Matrix are the results of the build and test of the libraries in the
+	// variants of the build matrix, if any.
+	Matrix []*matrixCell `json:"matrix,omitempty"`
+	// Containers are the results reported by the containers, if any.
+	Containers []*containerReport `json:"containers,omitempty"`
+}
+
+// libraryGenerationReport describes the changes made to a single library.
+type libraryGenerationReport struct {
+	ID string `json:"id"`
+	// CodegenOnly reports whether only generated files changed, as opposed to
+	// handwritten files.
+	CodegenOnly bool           `json:"codegen_only"`
+	LinesDelta  int            `json:"lines_delta"`
+	Files       []*changedFile `json:"files"`
+	// BreakingChanges are the breaking changes of the surface of the APIs
+	// of the library since it was last generated, if any.
+	BreakingChanges []*breakingChange `json:"breaking_changes,omitempty"`
+}
+
+// changedFile describes a file changed by a generation run.
+type changedFile struct {
+	// Path is relative to the root of the language repository.
+	Path   string `json:"path"`
+	Change string `json:"change"`
+	Kind   string `json:"kind"`
+	// LinesDelta is the number of lines in the file after the change, minus
+	// the number of lines before.
+	LinesDelta int `json:"lines_delta"`
+}
+
+// newGenerationReport classifies the changes in the working tree of repo by
+// library. A changed file in a source root of a library is generated if the
+// generator wrote it to the output directory of the library, or if it was
+// removed; it is handwritten if it matches the preserve_regex of the library.
+func newGenerationReport(repo gitrepo.Repository, state *config.LibrarianState, libraryIDs []string, outputDir string) (*generationReport, error) {
+	status, err := repo.Status()
+	if err != nil {
+		return nil, err
+	}
+	report := &generationReport{}
+	libraryReports := make(map[string]*libraryGenerationReport)
+	for _, id := range libraryIDs {
+		libraryReport := &libraryGenerationReport{ID: id, CodegenOnly: true}
+		libraryReports[id] = libraryReport
+		report.Libraries = append(report.Libraries, libraryReport)
+	}
+
+	for _, path := range sortedStatusFiles(status) {
+		file, err := newChangedFile(repo, path, status.File(path))
+		if err != nil {
+			return nil, err
+		}
+		if file == nil {
+			continue
+		}
+		id := libraryForFile(state, libraryIDs, path)
+		if id == "" {
+			report.Other = append(report.Other, file)
+			continue
+		}
+		handwritten, err := matchesAny(findLibraryByID(state, id).PreserveRegex, path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid preserve_regex of library %s: %w", id, err)
+		}
+		libraryReport := libraryReports[id]
+		if handwritten {
+			file.Kind = fileKindHandwritten
+			libraryReport.CodegenOnly = false
+		} else if _, err := os.Stat(filepath.Join(outputDir, id, path)); err == nil || file.Change == fileChangeDeleted {
+			file.Kind = fileKindGenerated
+		} else {
+			// A file of the library which the generator did not write, such
+			// as one updated by the build command.
+			file.Kind = fileKindHandwritten
+			libraryReport.CodegenOnly = false
+		}
+		libraryReport.LinesDelta += file.LinesDelta
+		libraryReport.Files = append(libraryReport.Files, file)
+	}
+	return report, nil
+}
+
+// newChangedFile returns the change of the file at path, or nil if it is
+// unchanged.
+func newChangedFile(repo gitrepo.Repository, path string, fileStatus *git.FileStatus) (*changedFile, error) {
+	file := &changedFile{Path: path}
+	switch {
+	case fileStatus.Worktree == git.Untracked || fileStatus.Staging == git.Added:
+		file.Change = fileChangeAdded
+	case fileStatus.Worktree == git.Deleted || fileStatus.Staging == git.Deleted:
+		file.Change = fileChangeDeleted
+	case fileStatus.Worktree == git.Modified || fileStatus.Staging == git.Modified:
+		file.Change = fileChangeModified
+	default:
+		return nil, nil
+	}
+	if file.Change != fileChangeAdded {
+		before, err := repo.ReadFileAtHead(path)
+		if err != nil {
+			return nil, err
+		}
+		file.LinesDelta -= countLines(before)
+	}
+	if file.Change != fileChangeDeleted {
+		after, err := os.ReadFile(filepath.Join(repo.GetDir(), path))
+		if err != nil {
+			return nil, err
+		}
+		file.LinesDelta += countLines(after)
+	}
+	return file, nil
+}
+
+func countLines(content []byte) int {
+	if len(content) == 0 {
+		return 0
+	}
+	lines := bytes.Count(content, []byte("\n"))
+	if content[len(content)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
+
+func matchesAny(patterns []string, path string) (bool, error) {
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, err
+		}
+		if re.MatchString(path) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// diffSize returns the number of changed files in the report, and the net
+// number of lines added.
+func (r *generationReport) diffSize() (files, linesDelta int) {
+	for _, library := range r.Libraries {
+		files += len(library.Files)
+		linesDelta += library.LinesDelta
+	}
+	for _, file := range r.Other {
+		files++
+		linesDelta += file.LinesDelta
+	}
+	return files, linesDelta
+}
+
+// isNoOp reports whether fewer files than required by noOp changed
+// meaningfully. Runs are never no-ops if noOp is nil.
+func (r *generationReport) isNoOp(noOp *config.NoOp) (bool, error) {
+	if noOp == nil {
+		return false, nil
+	}
+	var files []*changedFile
+	for _, library := range r.Libraries {
+		files = append(files, library.Files...)
+	}
+	files = append(files, r.Other...)
+	meaningful := 0
+	for _, file := range files {
+		if strings.HasPrefix(file.Path, config.LibrarianDir+"/") {
+			continue
+		}
+		ignored, err := matchesAny(noOp.Ignore, file.Path)
+		if err != nil {
+			return false, fmt.Errorf("invalid no-op ignore path: %w", err)
+		}
+		if !ignored {
+			meaningful++
+		}
+	}
+	return meaningful < max(noOp.MinFiles, 1), nil
+}
+
+// handwrittenFiles returns the handwritten files changed in the report.
+func (r *generationReport) handwrittenFiles() []string {
+	var files []string
+	for _, library := range r.Libraries {
+		for _, file := range library.Files {
+			if file.Kind == fileKindHandwritten {
+				files = append(files, file.Path)
+			}
+		}
+	}
+	return files
+}
+
+// markdown formats the report for a pull request comment.
+func (r *generationReport) markdown() string {
+	var b strings.Builder
+	b.WriteString("## Generation report\n\n")
+	if r.NoOp {
+		b.WriteString("No meaningful changes: the run is a no-op, and nothing is committed.\n\n")
+	}
+	b.WriteString("| Library | Added | Modified | Deleted | Lines | Codegen only |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, library := range r.Libraries {
+		counts := make(map[string]int)
+		for _, file := range library.Files {
+			counts[file.Change]++
+		}
+		codegenOnly := "yes"
+		if !library.CodegenOnly {
+			codegenOnly = "**no**"
+		}
+		fmt.Fprintf(&b, "| %s | %d | %d | %d | %+d | %s |\n", library.ID,
+			counts[fileChangeAdded], counts[fileChangeModified], counts[fileChangeDeleted], library.LinesDelta, codegenOnly)
+	}
+	if handwritten := r.handwrittenFiles(); len(handwritten) > 0 {
+		b.WriteString("\n### Handwritten files changed\n\n")
+		b.WriteString("These files were not written by the generator; review them carefully.\n\n")
+		for _, path := range handwritten {
+			fmt.Fprintf(&b, "* `%s`\n", path)
+		}
+	}
+	if len(r.Other) > 0 {
+		b.WriteString("\n### Other changed files\n\n")
+		for _, file := range r.Other {
+			fmt.Fprintf(&b, "* `%s` (%s)\n", file.Path, file.Change)
+		}
+	}
+	if breaking := breakingChangesMarkdown(r.Libraries); breaking != "" {
+		b.WriteString("\n### Breaking API changes\n\n")
+		b.WriteString(breaking)
+	}
+	if len(r.RemovedAPIs) > 0 {
+		b.WriteString("\n### Removed APIs\n\n")
+		b.WriteString(removedAPIsMarkdown(r.RemovedAPIs))
+	}
+	if len(r.Matrix) > 0 {
+		b.WriteString("\n### Build matrix\n\n")
+		b.WriteString(matrixMarkdown(r.Matrix))
+	}
+	if results := containerResultsMarkdown(r.Containers); results != "" {
+		b.WriteString("\n### Container results\n\n")
+		b.WriteString(results)
+	}
+	return b.String()
+}
+
+// readGenerationReport reads the generation-report.json at path, written by a
+// previous run.
+func readGenerationReport(path string) (*generationReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read generation report: %w", err)
+	}
+	report := &generationReport{}
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, fmt.Errorf("failed to parse generation report %s: %w", path, err)
+	}
+	return report, nil
+}
+
+// writeGenerationReport writes the report as JSON and Markdown into dir.
+func writeGenerationReport(dir string, report *generationReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = errors.Join(
+		os.WriteFile(filepath.Join(dir, generationReportJSONFile), data, 0644),
+		os.WriteFile(filepath.Join(dir, generationReportMarkdownFile), []byte(report.markdown()), 0644),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write generation report: %w", err)
+	}
+	return nil
+}
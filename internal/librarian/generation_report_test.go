@@ -0,0 +1,222 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestNewGenerationReport(t *testing.T) {
+	repoDir := t.TempDir()
+	outputDir := t.TempDir()
+	for path, content := range map[string]string{
+		filepath.Join(repoDir, "a/gen.go"):                "line 1\nline 2\nline 3\n",
+		filepath.Join(repoDir, "a/new.go"):                "line 1\n",
+		filepath.Join(repoDir, "a/handwritten.go"):        "line 1\nline 2",
+		filepath.Join(repoDir, "a/built.txt"):             "line 1\n",
+		filepath.Join(repoDir, ".librarian/state.yaml"):   "image: x\n",
+		filepath.Join(outputDir, "a", "a/gen.go"):         "",
+		filepath.Join(outputDir, "a", "a/new.go"):         "",
+		filepath.Join(outputDir, "a", "a/handwritten.go"): "",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := &MockRepository{
+		Dir: repoDir,
+		AddAllStatus: git.Status{
+			"a/gen.go":              &git.FileStatus{Worktree: git.Modified},
+			"a/new.go":              &git.FileStatus{Worktree: git.Untracked},
+			"a/old.go":              &git.FileStatus{Worktree: git.Deleted},
+			"a/handwritten.go":      &git.FileStatus{Worktree: git.Modified},
+			"a/built.txt":           &git.FileStatus{Worktree: git.Modified},
+			"a/unchanged.go":        &git.FileStatus{Worktree: git.Unmodified},
+			".librarian/state.yaml": &git.FileStatus{Worktree: git.Modified},
+		},
+		FilesAtHead: map[string]string{
+			"a/gen.go":              "line 1\n",
+			"a/old.go":              "line 1\nline 2\n",
+			"a/handwritten.go":      "line 1\n",
+			"a/built.txt":           "line 1\n",
+			".librarian/state.yaml": "image: x\n",
+		},
+	}
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "a", SourceRoots: []string{"a"}, PreserveRegex: []string{"^a/handwritten\\.go$"}},
+		},
+	}
+
+	got, err := newGenerationReport(repo, state, []string{"a"}, outputDir)
+	if err != nil {
+		t.Fatalf("newGenerationReport() error = %v", err)
+	}
+	want := &generationReport{
+		Libraries: []*libraryGenerationReport{
+			{
+				ID:         "a",
+				LinesDelta: 2,
+				Files: []*changedFile{
+					{Path: "a/built.txt", Change: fileChangeModified, Kind: fileKindHandwritten},
+					{Path: "a/gen.go", Change: fileChangeModified, Kind: fileKindGenerated, LinesDelta: 2},
+					{Path: "a/handwritten.go", Change: fileChangeModified, Kind: fileKindHandwritten, LinesDelta: 1},
+					{Path: "a/new.go", Change: fileChangeAdded, Kind: fileKindGenerated, LinesDelta: 1},
+					{Path: "a/old.go", Change: fileChangeDeleted, Kind: fileKindGenerated, LinesDelta: -2},
+				},
+			},
+		},
+		Other: []*changedFile{
+			{Path: ".librarian/state.yaml", Change: fileChangeModified},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("newGenerationReport() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"a/built.txt", "a/handwritten.go"}, got.handwrittenFiles()); diff != "" {
+		t.Errorf("handwrittenFiles() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerationReport_Markdown(t *testing.T) {
+	report := &generationReport{
+		Libraries: []*libraryGenerationReport{
+			{
+				ID:          "a",
+				CodegenOnly: true,
+				LinesDelta:  10,
+				Files: []*changedFile{
+					{Path: "a/gen.go", Change: fileChangeModified, Kind: fileKindGenerated, LinesDelta: 10},
+				},
+			},
+			{
+				ID:         "b",
+				LinesDelta: -3,
+				Files: []*changedFile{
+					{Path: "b/handwritten.go", Change: fileChangeDeleted, Kind: fileKindHandwritten, LinesDelta: -3},
+				},
+			},
+		},
+		Other: []*changedFile{
+			{Path: ".librarian/state.yaml", Change: fileChangeModified},
+		},
+	}
+	want := "## Generation report\n\n" +
+		"| Library | Added | Modified | Deleted | Lines | Codegen only |\n" +
+		"|---|---|---|---|---|---|\n" +
+		"| a | 0 | 1 | 0 | +10 | yes |\n" +
+		"| b | 0 | 0 | 1 | -3 | **no** |\n" +
+		"\n### Handwritten files changed\n\n" +
+		"These files were not written by the generator; review them carefully.\n\n" +
+		"* `b/handwritten.go`\n" +
+		"\n### Other changed files\n\n" +
+		"* `.librarian/state.yaml` (modified)\n"
+	if diff := cmp.Diff(want, report.markdown()); diff != "" {
+		t.Errorf("markdown() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerationReport_IsNoOp(t *testing.T) {
+	report := &generationReport{
+		Libraries: []*libraryGenerationReport{
+			{
+				ID: "a",
+				Files: []*changedFile{
+					{Path: "a/gapic_metadata.json", Change: fileChangeModified, Kind: fileKindGenerated},
+					{Path: "a/version.go", Change: fileChangeModified, Kind: fileKindGenerated},
+				},
+			},
+		},
+		Other: []*changedFile{
+			{Path: ".librarian/state.yaml", Change: fileChangeModified},
+		},
+	}
+	for _, test := range []struct {
+		name string
+		noOp *config.NoOp
+		want bool
+	}{
+		{
+			name: "not configured",
+		},
+		{
+			name: "meaningful change",
+			noOp: &config.NoOp{Ignore: []string{`/gapic_metadata\.json$`}},
+		},
+		{
+			name: "only ignored changes",
+			noOp: &config.NoOp{Ignore: []string{`/gapic_metadata\.json$`, `/version\.go$`}},
+			want: true,
+		},
+		{
+			name: "fewer meaningful changes than required",
+			noOp: &config.NoOp{Ignore: []string{`/gapic_metadata\.json$`}, MinFiles: 2},
+			want: true,
+		},
+		{
+			name: "enough meaningful changes",
+			noOp: &config.NoOp{MinFiles: 2},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := report.isNoOp(test.noOp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("isNoOp() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWriteGenerationReport(t *testing.T) {
+	dir := t.TempDir()
+	report := &generationReport{
+		Libraries: []*libraryGenerationReport{{ID: "a", CodegenOnly: true}},
+	}
+	if err := writeGenerationReport(dir, report); err != nil {
+		t.Fatalf("writeGenerationReport() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, generationReportJSONFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &generationReport{}
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(report, got); diff != "" {
+		t.Errorf("JSON report mismatch (-want +got):\n%s", diff)
+	}
+	markdown, err := os.ReadFile(filepath.Join(dir, generationReportMarkdownFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(markdown), "## Generation report") {
+		t.Errorf("Markdown report = %q, want a generation report", markdown)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gerrit"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// gerritReview sends the commits of a command for review to Gerrit, as
+// configured in the gerrit section of config.yaml, instead of creating a
+// GitHub pull request.
+type gerritReview struct {
+	config *config.Gerrit
+	client GerritClient
+}
+
+// newGerritReview returns the Gerrit review of the language repository, or
+// nil if the repository is not reviewed on Gerrit. The Gerrit credentials of
+// cfg, if any, replace the GitHub token to push to repo.
+func newGerritReview(cfg *config.Config, lc *config.LibrarianConfig, repo *gitrepo.LocalRepository) *gerritReview {
+	if lc == nil || lc.Gerrit == nil {
+		return nil
+	}
+	if cfg.GerritPassword != "" {
+		repo.SetBasicAuth(cfg.GerritUser, cfg.GerritPassword)
+	}
+	return &gerritReview{
+		config: lc.Gerrit,
+		client: gerrit.NewClient(lc.Gerrit.URL, cfg.GerritUser, cfg.GerritPassword),
+	}
+}
+
+// commitMessage returns message with a new Change-Id trailer, which Gerrit
+// requires to identify the change of the commit.
+func (g *gerritReview) commitMessage(message string) (string, error) {
+	changeID, err := gerrit.NewChangeID()
+	if err != nil {
+		return "", err
+	}
+	return gerrit.AddChangeID(message, changeID), nil
+}
+
+// send pushes the commits of branch for review, which creates a change for
+// each commit, grouped under the configured topic, or topic, or the branch
+// name. It then votes on the configured labels of each change. messages are
+// the messages of the pushed commits.
+func (g *gerritReview) send(ctx context.Context, repo gitrepo.Repository, branch, topic string, messages []string) error {
+	target := g.config.TargetBranch()
+	topic = cmp.Or(g.config.Topic, topic, branch)
+	slog.Info("Sending changes for review", "branch", target, "topic", topic)
+	if err := repo.PushForReview(branch, gerrit.ReviewRef(target, topic)); err != nil {
+		return err
+	}
+	auditLogFromContext(ctx).record(auditChangesSent, map[string]string{"branch": target, "topic": topic})
+	if len(g.config.Labels) == 0 {
+		return nil
+	}
+	for _, message := range messages {
+		changeID := gerrit.ChangeIDOf(message)
+		if err := g.client.SetReview(ctx, g.config.Project, target, changeID, g.config.Labels, ""); err != nil {
+			return fmt.Errorf("failed to vote on change %s: %w", changeID, err)
+		}
+	}
+	return nil
+}
+
+// checkReviewPermissions is like checkGitHubPermissions, but does not check
+// the GitHub token of commands which send their changes for review to Gerrit,
+// as they do not need GitHub. The Gerrit credentials are checked when
+// pushing.
+func checkReviewPermissions(ctx context.Context, cfg *config.Config, ghClient GitHubClient, gerrit *gerritReview) error {
+	if gerrit != nil {
+		return nil
+	}
+	return checkGitHubPermissions(ctx, cfg, ghClient)
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gerrit"
+)
+
+func TestCommitAndPush_Gerrit(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		gerrit       *config.Gerrit
+		push         bool
+		topic        string
+		setReviewErr error
+		wantPushed   []string
+		wantVotes    int
+		wantErr      bool
+		wantSubjects []string
+	}{
+		{
+			name:         "commit only",
+			gerrit:       &config.Gerrit{Project: "example/repo"},
+			wantSubjects: []string{"feat: regenerate", "chore: quarantine"},
+		},
+		{
+			name:         "push with default branch and topic",
+			gerrit:       &config.Gerrit{Project: "example/repo"},
+			push:         true,
+			wantPushed:   []string{"refs/for/main%topic=librarian-test"},
+			wantSubjects: []string{"feat: regenerate", "chore: quarantine"},
+		},
+		{
+			name:         "push with run topic and votes",
+			gerrit:       &config.Gerrit{Project: "example/repo", Branch: "release", Labels: map[string]int{"Code-Review": 1}},
+			push:         true,
+			topic:        "librarian-release",
+			wantPushed:   []string{"refs/for/release%topic=librarian-release"},
+			wantVotes:    2,
+			wantSubjects: []string{"feat: regenerate", "chore: quarantine"},
+		},
+		{
+			name:         "configured topic",
+			gerrit:       &config.Gerrit{Project: "example/repo", Topic: "configured"},
+			push:         true,
+			topic:        "librarian-release",
+			wantPushed:   []string{"refs/for/main%topic=configured"},
+			wantSubjects: []string{"feat: regenerate", "chore: quarantine"},
+		},
+		{
+			name:         "vote fails",
+			gerrit:       &config.Gerrit{Project: "example/repo", Labels: map[string]int{"Code-Review": 1}},
+			push:         true,
+			setReviewErr: errors.New("forbidden"),
+			wantPushed:   []string{"refs/for/main%topic=librarian-test"},
+			wantErr:      true,
+			wantSubjects: []string{"feat: regenerate", "chore: quarantine"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			status := make(git.Status)
+			status["file.txt"] = &git.FileStatus{Worktree: git.Modified}
+			repo := &MockRepository{Dir: t.TempDir(), AddAllStatus: status}
+			client := &mockGerritClient{setReviewErr: test.setReviewErr}
+			info := &commitInfo{
+				cfg:           &config.Config{Commit: true, Push: test.push},
+				repo:          repo,
+				commitMessage: "feat: regenerate\n",
+				branch:        "librarian-test",
+				topic:         test.topic,
+				gerrit:        &gerritReview{config: test.gerrit, client: client},
+				followUpCommits: []*followUpCommit{
+					{message: "chore: quarantine", apply: func() error { return nil }},
+				},
+			}
+			pr, err := commitAndCreatePullRequest(context.Background(), info)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("commitAndCreatePullRequest() error = %v, wantErr %t", err, test.wantErr)
+			}
+			if pr != nil {
+				t.Errorf("commitAndCreatePullRequest() = %v, want no pull request", pr)
+			}
+			if diff := cmp.Diff(test.wantPushed, repo.PushedForReview); diff != "" {
+				t.Errorf("pushed refs mismatch (-want +got):\n%s", diff)
+			}
+			var subjects []string
+			for _, message := range repo.CommitMessages {
+				subject, _, _ := strings.Cut(message, "\n")
+				subjects = append(subjects, subject)
+				if gerrit.ChangeIDOf(message) == "" {
+					t.Errorf("commit message %q has no Change-Id", message)
+				}
+			}
+			if diff := cmp.Diff(test.wantSubjects, subjects); diff != "" {
+				t.Errorf("commit subjects mismatch (-want +got):\n%s", diff)
+			}
+			if len(client.votes) != test.wantVotes {
+				t.Errorf("votes = %v, want %d changes voted on", client.votes, test.wantVotes)
+			}
+		})
+	}
+}
+
+func TestCheckReviewPermissions(t *testing.T) {
+	cfg := &config.Config{CommandName: generateCmdName, Push: true}
+	if err := checkReviewPermissions(context.Background(), cfg, nil, &gerritReview{}); err != nil {
+		t.Errorf("checkReviewPermissions() with Gerrit error = %v, want nil", err)
+	}
+	err := checkReviewPermissions(context.Background(), cfg, nil, nil)
+	if got := failure.CategoryOf(err); got != failure.UserConfig {
+		t.Errorf("checkReviewPermissions() without GitHub error = %v, want category %q", err, failure.UserConfig)
+	}
+}
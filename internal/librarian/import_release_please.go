@@ -0,0 +1,241 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/versionfile"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	releasePleaseConfigFile   = "release-please-config.json"
+	releasePleaseManifestFile = ".release-please-manifest.json"
+)
+
+var cmdImportReleasePlease = &cli.Command{
+	Short:     "import-release-please converts a release-please setup to librarian",
+	UsageLine: "librarian import-release-please -repo=<dir> -image=<image> [flags]",
+	Long: `Converts the release-please configuration of a language repository into
+librarian state and config.
+
+"` + releasePleaseConfigFile + `" and "` + releasePleaseManifestFile + `" are read
+from the root of the repository specified by "-repo". Each release-please
+package becomes a library:
+
+- The library ID is the component of the package, falling back to the
+  package name without its npm scope and then to the last element of the
+  package path.
+- The source root of the library is the package path.
+- The version of the library is the version recorded in the manifest.
+- The tag format preserves the tags of release-please, so that release notes
+  continue from the last release.
+
+The libraries are written to ".librarian/state.yaml", with the image specified
+by "-image", which must not exist yet. Version files for the release types of
+the packages are written to ".librarian/config.yaml", unless it exists already.
+
+Release-please "extra-files" are not converted, and are reported instead so
+that they can be added as version files by hand.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		return importReleasePlease(ctx, cfg)
+	},
+}
+
+func init() {
+	cmdImportReleasePlease.Init()
+	fs := cmdImportReleasePlease.Flags
+	cfg := cmdImportReleasePlease.Config
+
+	addFlagAuditLog(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagImage(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+// releasePleaseConfig is the subset of release-please-config.json which is
+// converted. Options set at the top level are defaults for all packages.
+type releasePleaseConfig struct {
+	releasePleasePackage
+	Packages map[string]*releasePleasePackage `json:"packages"`
+}
+
+type releasePleasePackage struct {
+	PackageName           string            `json:"package-name"`
+	Component             string            `json:"component"`
+	ReleaseType           string            `json:"release-type"`
+	IncludeComponentInTag *bool             `json:"include-component-in-tag"`
+	IncludeVInTag         *bool             `json:"include-v-in-tag"`
+	TagSeparator          string            `json:"tag-separator"`
+	ExtraFiles            []json.RawMessage `json:"extra-files"`
+}
+
+// releasePleaseVersionFiles maps release-please release types to the version
+// files they update.
+var releasePleaseVersionFiles = map[string]*config.VersionFile{
+	"java":   {Path: "pom.xml", Kind: versionfile.KindPomXML},
+	"maven":  {Path: "pom.xml", Kind: versionfile.KindPomXML},
+	"node":   {Path: "package.json", Kind: versionfile.KindPackageJSON},
+	"python": {Path: "setup.py", Kind: versionfile.KindSetupPy},
+}
+
+func importReleasePlease(ctx context.Context, cfg *config.Config) error {
+	if cfg.Image == "" {
+		return errors.New("-image is required")
+	}
+	if isRemote(cfg.Repo) {
+		return errors.New("-repo must be a local directory")
+	}
+	rpConfig := &releasePleaseConfig{}
+	if err := readJSONFile(filepath.Join(cfg.Repo, releasePleaseConfigFile), rpConfig); err != nil {
+		return err
+	}
+	manifest := map[string]string{}
+	if err := readJSONFile(filepath.Join(cfg.Repo, releasePleaseManifestFile), &manifest); err != nil {
+		return err
+	}
+	state, librarianConfig, err := convertReleasePlease(rpConfig, manifest, cfg.Image)
+	if err != nil {
+		return err
+	}
+
+	statePath := filepath.Join(cfg.Repo, config.LibrarianDir, librarianStateFile)
+	if _, err := os.Stat(statePath); err == nil {
+		return fmt.Errorf("%s already exists", statePath)
+	}
+	if err := os.MkdirAll(filepath.Dir(statePath), 0755); err != nil {
+		return fmt.Errorf("failed to make directory: %w", err)
+	}
+	if err := saveLibrarianState(ctx, cfg.Repo, state); err != nil {
+		return err
+	}
+	slog.Info("Imported release-please packages", "libraries", len(state.Libraries), "path", statePath)
+
+	configPath := filepath.Join(cfg.Repo, config.LibrarianDir, librarianConfigFile)
+	if _, err := os.Stat(configPath); err == nil {
+		slog.Info("Keeping existing config", "path", configPath)
+		return nil
+	}
+	data, err := yaml.Marshal(librarianConfig)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(configPath, data, 0644)
+}
+
+// convertReleasePlease converts the release-please config and manifest to
+// librarian state and config.
+func convertReleasePlease(rpConfig *releasePleaseConfig, manifest map[string]string, image string) (*config.LibrarianState, *config.LibrarianConfig, error) {
+	if len(rpConfig.Packages) == 0 {
+		return nil, nil, fmt.Errorf("no packages found in %s", releasePleaseConfigFile)
+	}
+	state := &config.LibrarianState{Image: image}
+	librarianConfig := &config.LibrarianConfig{GlobalFilesAllowlist: []*config.GlobalFile{}}
+	paths := slices.Sorted(maps.Keys(rpConfig.Packages))
+	for _, packagePath := range paths {
+		pkg := rpConfig.Packages[packagePath]
+		if packagePath == "." {
+			return nil, nil, fmt.Errorf("package at the repository root is not supported, as libraries need a source root")
+		}
+		id := cmp.Or(pkg.Component, releasePleaseComponent(pkg.PackageName), path.Base(packagePath))
+		if state.LibraryByID(id) != nil {
+			return nil, nil, fmt.Errorf("packages %s and %s have the same library ID %q", state.LibraryByID(id).SourceRoots[0], packagePath, id)
+		}
+		library := &config.LibraryState{
+			ID:          id,
+			Version:     manifest[packagePath],
+			SourceRoots: []string{packagePath},
+			TagFormat:   releasePleaseTagFormat(&rpConfig.releasePleasePackage, pkg),
+		}
+		if len(pkg.ExtraFiles) > 0 || len(rpConfig.ExtraFiles) > 0 {
+			slog.Warn("release-please extra-files are not converted, add them as version files", "library", id)
+		}
+		releaseType := cmp.Or(pkg.ReleaseType, rpConfig.ReleaseType)
+		if versionFile, ok := releasePleaseVersionFiles[releaseType]; ok && !slices.Contains(librarianConfig.VersionFiles, versionFile) {
+			librarianConfig.VersionFiles = append(librarianConfig.VersionFiles, versionFile)
+		}
+		state.Libraries = append(state.Libraries, library)
+	}
+	if err := state.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid imported state: %w", err)
+	}
+	return state, librarianConfig, nil
+}
+
+// releasePleaseComponent returns the component release-please derives from a
+// package name, which excludes the scope of npm packages.
+func releasePleaseComponent(packageName string) string {
+	if strings.HasPrefix(packageName, "@") {
+		if _, name, ok := strings.Cut(packageName, "/"); ok {
+			return name
+		}
+	}
+	return packageName
+}
+
+// releasePleaseTagFormat returns the tag format matching the tags created by
+// release-please for pkg, with defaults from the top level of the config.
+func releasePleaseTagFormat(defaults, pkg *releasePleasePackage) string {
+	includeComponent := boolOr(true, pkg.IncludeComponentInTag, defaults.IncludeComponentInTag)
+	includeV := boolOr(true, pkg.IncludeVInTag, defaults.IncludeVInTag)
+	var format strings.Builder
+	if includeComponent {
+		format.WriteString("{id}")
+		format.WriteString(cmp.Or(pkg.TagSeparator, defaults.TagSeparator, "-"))
+	}
+	if includeV {
+		format.WriteString("v")
+	}
+	format.WriteString("{version}")
+	return format.String()
+}
+
+// boolOr returns the first non-nil value, or fallback if all are nil.
+func boolOr(fallback bool, values ...*bool) bool {
+	for _, value := range values {
+		if value != nil {
+			return *value
+		}
+	}
+	return fallback
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestImportReleasePlease(t *testing.T) {
+	repoDir := t.TempDir()
+	for name, content := range map[string]string{
+		releasePleaseConfigFile: `{
+  "release-type": "node",
+  "packages": {
+    "packages/google-cloud-secretmanager": {
+      "component": "secretmanager"
+    },
+    "packages/google-cloud-kms": {
+      "package-name": "@google-cloud/kms",
+      "include-v-in-tag": false
+    },
+    "handwritten/storage": {
+      "release-type": "python",
+      "include-component-in-tag": false,
+      "extra-files": ["storage/version.py"]
+    }
+  }
+}`,
+		releasePleaseManifestFile: `{
+  "packages/google-cloud-secretmanager": "1.2.3",
+  "packages/google-cloud-kms": "4.5.6",
+  "handwritten/storage": "2.0.0"
+}`,
+	} {
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := &config.Config{
+		Image: "gcr.io/test/node-generator:latest",
+		Repo:  repoDir,
+	}
+	if err := importReleasePlease(context.Background(), cfg); err != nil {
+		t.Fatalf("importReleasePlease() error = %v", err)
+	}
+
+	state, err := parseLibrarianState(filepath.Join(repoDir, config.LibrarianDir, librarianStateFile), "")
+	if err != nil {
+		t.Fatalf("parseLibrarianState() error = %v", err)
+	}
+	want := &config.LibrarianState{
+		Image: "gcr.io/test/node-generator:latest",
+		Libraries: []*config.LibraryState{
+			{
+				ID:            "storage",
+				Version:       "2.0.0",
+				APIs:          []*config.API{},
+				SourceRoots:   []string{"handwritten/storage"},
+				PreserveRegex: []string{},
+				RemoveRegex:   []string{},
+				TagFormat:     "v{version}",
+			},
+			{
+				ID:            "kms",
+				Version:       "4.5.6",
+				APIs:          []*config.API{},
+				SourceRoots:   []string{"packages/google-cloud-kms"},
+				PreserveRegex: []string{},
+				RemoveRegex:   []string{},
+				TagFormat:     "{id}-{version}",
+			},
+			{
+				ID:            "secretmanager",
+				Version:       "1.2.3",
+				APIs:          []*config.API{},
+				SourceRoots:   []string{"packages/google-cloud-secretmanager"},
+				PreserveRegex: []string{},
+				RemoveRegex:   []string{},
+				TagFormat:     "{id}-v{version}",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, state); diff != "" {
+		t.Errorf("state mismatch (-want +got):\n%s", diff)
+	}
+	librarianConfig, err := parseLibrarianConfig(filepath.Join(repoDir, config.LibrarianDir, librarianConfigFile))
+	if err != nil {
+		t.Fatalf("parseLibrarianConfig() error = %v", err)
+	}
+	wantVersionFiles := []*config.VersionFile{
+		{Path: "setup.py", Kind: "setup-py"},
+		{Path: "package.json", Kind: "package-json"},
+	}
+	if diff := cmp.Diff(wantVersionFiles, librarianConfig.VersionFiles); diff != "" {
+		t.Errorf("version files mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := importReleasePlease(context.Background(), cfg); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("importReleasePlease() on imported repository error = %v, want already exists", err)
+	}
+}
+
+func TestConvertReleasePlease_Errors(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		config     *releasePleaseConfig
+		manifest   map[string]string
+		wantErrMsg string
+	}{
+		{
+			name:       "no packages",
+			config:     &releasePleaseConfig{},
+			wantErrMsg: "no packages found",
+		},
+		{
+			name: "root package",
+			config: &releasePleaseConfig{
+				Packages: map[string]*releasePleasePackage{".": {}},
+			},
+			wantErrMsg: "repository root is not supported",
+		},
+		{
+			name: "duplicate library IDs",
+			config: &releasePleaseConfig{
+				Packages: map[string]*releasePleasePackage{
+					"a/storage": {},
+					"b/storage": {},
+				},
+			},
+			wantErrMsg: "have the same library ID",
+		},
+		{
+			name: "invalid version",
+			config: &releasePleaseConfig{
+				Packages: map[string]*releasePleasePackage{"storage": {}},
+			},
+			manifest:   map[string]string{"storage": "1.0.0-beta"},
+			wantErrMsg: "invalid version",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := convertReleasePlease(test.config, test.manifest, "gcr.io/test/image:v1")
+			if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+				t.Errorf("convertReleasePlease() error = %v, want error containing %q", err, test.wantErrMsg)
+			}
+		})
+	}
+}
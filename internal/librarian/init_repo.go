@@ -0,0 +1,258 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/versionfile"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	initRepoCommitMessage = "chore: initialize librarian"
+	librarianWorkflowFile = ".github/workflows/librarian.yaml"
+)
+
+var cmdInitRepo = &cli.Command{
+	Short:     "init-repo scaffolds a new language repository",
+	UsageLine: "librarian init-repo -repo=<dir> -language=<language> -image=<image> -library=<id> [flags]",
+	Long: `Scaffolds a new language repository to be managed by librarian.
+
+The repository is created in the directory specified by "-repo", which is
+initialized as a git repository if it is not one already. It must not contain
+a ".librarian" directory yet. The following files are created, with defaults
+for the language specified by "-language":
+
+- ".librarian/state.yaml", with the image specified by "-image" and a first
+  library with the ID specified by "-library", generated from the API
+  specified by "-api" if any.
+- ".librarian/config.yaml", with the version files of the language.
+- ".librarian/generator-input", for the generator configuration.
+- The source root of the first library.
+- "` + librarianWorkflowFile + `", a GitHub Actions workflow which runs
+  generation and release pull requests on a schedule.
+
+The files are committed in an initial commit.
+
+Supported languages are: ` + "go, java, node, python, rust and dotnet.",
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		return initRepo(ctx, cfg)
+	},
+}
+
+func init() {
+	cmdInitRepo.Init()
+	fs := cmdInitRepo.Flags
+	cfg := cmdInitRepo.Config
+
+	addFlagAPI(fs, cfg)
+	addFlagAuditLog(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagImage(fs, cfg)
+	addFlagLanguage(fs, cfg)
+	addFlagLibrary(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+// repoTemplate holds the defaults of a language repository.
+type repoTemplate struct {
+	// sourceRootPrefix is the directory which contains the source root of
+	// each library, whose name is the library ID.
+	sourceRootPrefix string
+	versionFiles     []*config.VersionFile
+}
+
+var repoTemplates = map[string]*repoTemplate{
+	"dotnet": {sourceRootPrefix: "apis"},
+	"go": {
+		versionFiles: []*config.VersionFile{
+			{Path: "internal/version.go", Kind: versionfile.KindGoVersion},
+		},
+	},
+	"java": {
+		versionFiles: []*config.VersionFile{
+			{Path: "pom.xml", Kind: versionfile.KindPomXML},
+		},
+	},
+	"node": {
+		sourceRootPrefix: "packages",
+		versionFiles: []*config.VersionFile{
+			{Path: "package.json", Kind: versionfile.KindPackageJSON},
+		},
+	},
+	"python": {
+		sourceRootPrefix: "packages",
+		versionFiles: []*config.VersionFile{
+			{Path: "setup.py", Kind: versionfile.KindSetupPy},
+		},
+	},
+	"rust": {sourceRootPrefix: "src/generated"},
+}
+
+func initRepo(ctx context.Context, cfg *config.Config) error {
+	template, ok := repoTemplates[cfg.Language]
+	if !ok {
+		return fmt.Errorf("unsupported language %q, supported languages are %s", cfg.Language, strings.Join(supportedRepoLanguages(), ", "))
+	}
+	if cfg.Image == "" {
+		return errors.New("-image is required")
+	}
+	if cfg.Library == "" {
+		return errors.New("-library is required")
+	}
+	if isRemote(cfg.Repo) {
+		return errors.New("-repo must be a local directory")
+	}
+	library := &config.LibraryState{
+		ID:          cfg.Library,
+		Version:     "0.0.0",
+		SourceRoots: []string{path.Join(template.sourceRootPrefix, cfg.Library)},
+	}
+	if cfg.API != "" {
+		library.APIs = []*config.API{{Path: cfg.API}}
+	}
+	state := &config.LibrarianState{
+		Image:     cfg.Image,
+		Libraries: []*config.LibraryState{library},
+	}
+	if err := state.Validate(); err != nil {
+		return fmt.Errorf("invalid repository settings: %w", err)
+	}
+	librarianConfig := &config.LibrarianConfig{
+		GlobalFilesAllowlist: []*config.GlobalFile{},
+		VersionFiles:         template.versionFiles,
+	}
+
+	repo, err := openOrInitRepo(cfg.Repo)
+	if err != nil {
+		return err
+	}
+	repoDir := repo.GetDir()
+	if _, err := os.Stat(filepath.Join(repoDir, config.LibrarianDir)); err == nil {
+		return fmt.Errorf("%s already exists in %s", config.LibrarianDir, repoDir)
+	}
+
+	slog.Info("Scaffolding language repository", "dir", repoDir, "language", cfg.Language)
+	for _, dir := range []string{config.GeneratorInputDir, library.SourceRoots[0]} {
+		if err := writeFile(filepath.Join(repoDir, dir, ".gitkeep"), ""); err != nil {
+			return err
+		}
+	}
+	if err := saveLibrarianState(ctx, repoDir, state); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(librarianConfig)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(repoDir, config.LibrarianDir, librarianConfigFile), string(data)); err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(repoDir, librarianWorkflowFile), librarianWorkflow); err != nil {
+		return err
+	}
+
+	if _, err := repo.AddAll(); err != nil {
+		return err
+	}
+	if err := repo.Commit(initRepoCommitMessage); err != nil {
+		return err
+	}
+	auditLogFromContext(ctx).recordCommit(repo, initRepoCommitMessage)
+	return nil
+}
+
+// openOrInitRepo opens the git repository in dir, which must be clean, or
+// initializes one if dir is not a git repository.
+func openOrInitRepo(dir string) (*gitrepo.LocalRepository, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return gitrepo.InitRepository(dir)
+	}
+	repo, err := gitrepo.NewRepository(&gitrepo.RepositoryOptions{Dir: dir})
+	if err != nil {
+		return nil, err
+	}
+	clean, err := repo.IsClean()
+	if err != nil {
+		return nil, err
+	}
+	if !clean {
+		return nil, fmt.Errorf("%s has uncommitted changes", dir)
+	}
+	return repo, nil
+}
+
+func supportedRepoLanguages() []string {
+	var languages []string
+	for language := range repoTemplates {
+		languages = append(languages, language)
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+func writeFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to make directory: %w", err)
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// librarianWorkflow is a GitHub Actions workflow which runs librarian in the
+// language repository on a schedule.
+const librarianWorkflow = `# Runs librarian to create generation and release pull requests.
+name: librarian
+on:
+  schedule:
+    - cron: "0 6 * * 1-5"
+  workflow_dispatch:
+permissions:
+  contents: write
+  pull-requests: write
+jobs:
+  librarian:
+    runs-on: ubuntu-latest
+    env:
+      LIBRARIAN_GITHUB_TOKEN: ${{ secrets.GITHUB_TOKEN }}
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-go@v5
+        with:
+          go-version: stable
+      - run: go install github.com/googleapis/librarian/cmd/librarian@latest
+      # Pull the language container ahead of time.
+      - run: librarian prewarm -repo=.
+      - run: librarian generate -repo=. -push
+      - run: librarian release init -repo=. -push
+`
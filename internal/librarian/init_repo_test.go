@@ -0,0 +1,146 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+func TestInitRepo(t *testing.T) {
+	// The author of the initial commit is read from the global git config.
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	if err := os.WriteFile(filepath.Join(home, ".gitconfig"), []byte("[user]\n\tname = Test\n\temail = test@example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repoDir := filepath.Join(t.TempDir(), "google-cloud-python")
+	cfg := &config.Config{
+		API:      "google/cloud/secretmanager/v1",
+		Image:    "gcr.io/test/python-generator:latest",
+		Language: "python",
+		Library:  "google-cloud-secret-manager",
+		Repo:     repoDir,
+	}
+	if err := initRepo(context.Background(), cfg); err != nil {
+		t.Fatalf("initRepo() error = %v", err)
+	}
+
+	state, err := parseLibrarianState(filepath.Join(repoDir, config.LibrarianDir, librarianStateFile), "")
+	if err != nil {
+		t.Fatalf("parseLibrarianState() error = %v", err)
+	}
+	wantState := &config.LibrarianState{
+		Image: "gcr.io/test/python-generator:latest",
+		Libraries: []*config.LibraryState{
+			{
+				ID:            "google-cloud-secret-manager",
+				Version:       "0.0.0",
+				APIs:          []*config.API{{Path: "google/cloud/secretmanager/v1"}},
+				SourceRoots:   []string{"packages/google-cloud-secret-manager"},
+				PreserveRegex: []string{},
+				RemoveRegex:   []string{},
+			},
+		},
+	}
+	if diff := cmp.Diff(wantState, state); diff != "" {
+		t.Errorf("state mismatch (-want +got):\n%s", diff)
+	}
+	librarianConfig, err := parseLibrarianConfig(filepath.Join(repoDir, config.LibrarianDir, librarianConfigFile))
+	if err != nil {
+		t.Fatalf("parseLibrarianConfig() error = %v", err)
+	}
+	wantVersionFiles := []*config.VersionFile{{Path: "setup.py", Kind: "setup-py"}}
+	if diff := cmp.Diff(wantVersionFiles, librarianConfig.VersionFiles); diff != "" {
+		t.Errorf("version files mismatch (-want +got):\n%s", diff)
+	}
+	for _, file := range []string{
+		".librarian/generator-input/.gitkeep",
+		"packages/google-cloud-secret-manager/.gitkeep",
+		librarianWorkflowFile,
+	} {
+		if _, err := os.Stat(filepath.Join(repoDir, file)); err != nil {
+			t.Errorf("%s was not created: %v", file, err)
+		}
+	}
+
+	repo, err := gitrepo.NewRepository(&gitrepo.RepositoryOptions{Dir: repoDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clean, err := repo.IsClean()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !clean {
+		t.Errorf("repository has uncommitted changes after initRepo()")
+	}
+
+	if err := initRepo(context.Background(), cfg); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("initRepo() on initialized repository error = %v, want already exists", err)
+	}
+}
+
+func TestInitRepo_InvalidSettings(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		cfg        *config.Config
+		wantErrMsg string
+	}{
+		{
+			name:       "unsupported language",
+			cfg:        &config.Config{Language: "cobol", Image: "gcr.io/test/image:v1", Library: "a"},
+			wantErrMsg: "unsupported language",
+		},
+		{
+			name:       "missing image",
+			cfg:        &config.Config{Language: "go", Library: "a"},
+			wantErrMsg: "-image is required",
+		},
+		{
+			name:       "missing library",
+			cfg:        &config.Config{Language: "go", Image: "gcr.io/test/image:v1"},
+			wantErrMsg: "-library is required",
+		},
+		{
+			name:       "remote repository",
+			cfg:        &config.Config{Language: "go", Image: "gcr.io/test/image:v1", Library: "a", Repo: "https://github.com/googleapis/google-cloud-go"},
+			wantErrMsg: "must be a local directory",
+		},
+		{
+			name:       "invalid library id",
+			cfg:        &config.Config{Language: "go", Image: "gcr.io/test/image:v1", Library: "a b"},
+			wantErrMsg: "invalid id",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if test.cfg.Repo == "" {
+				test.cfg.Repo = t.TempDir()
+			}
+			err := initRepo(context.Background(), test.cfg)
+			if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+				t.Errorf("initRepo() error = %v, want error containing %q", err, test.wantErrMsg)
+			}
+		})
+	}
+}
@@ -20,13 +20,21 @@ package librarian
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/url"
+	"os"
+	"time"
 
 	"github.com/googleapis/librarian/internal/docker"
 
 	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
 	"github.com/googleapis/librarian/internal/github"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/logging"
+	"github.com/googleapis/librarian/internal/metrics"
 )
 
 // CmdLibrarian is the top-level command for the Librarian CLI.
@@ -39,40 +47,165 @@ var CmdLibrarian = &cli.Command{
 func init() {
 	CmdLibrarian.Init()
 	CmdLibrarian.Commands = append(CmdLibrarian.Commands,
+		cmdBackfillChangelog,
+		cmdBlockLibrary,
+		cmdBundleDebug,
+		cmdCheckFreshness,
+		cmdClean,
+		cmdDiscoverAPIs,
+		cmdExplain,
+		cmdExport,
 		cmdGenerate,
+		cmdGenerateCI,
+		cmdImportReleasePlease,
+		cmdInitRepo,
+		cmdPrewarm,
+		cmdPreviewRelease,
+		cmdPrintConfig,
+		cmdPrintEffectiveConfig,
+		cmdPropose,
 		cmdRelease,
+		cmdRenameLibrary,
+		cmdSelfUpdate,
+		cmdServe,
+		cmdStats,
+		cmdStatus,
+		cmdSyncAPIs,
+		cmdSyncOwners,
+		cmdUnblockLibrary,
+		cmdVerifyReleases,
 		cmdVersion,
 	)
 }
 
 // Run executes the Librarian CLI with the given command line
 // arguments.
+//
+// Errors are classified with the failure package, so that the caller can
+// exit with the exit code of the failure category.
 func Run(ctx context.Context, arg ...string) error {
 	if err := CmdLibrarian.Parse(arg); err != nil {
-		return err
+		return failure.New(failure.UserConfig, err)
 	}
 	if len(arg) == 0 {
 		CmdLibrarian.Flags.Usage()
-		return fmt.Errorf("command not specified")
+		return failure.New(failure.UserConfig, fmt.Errorf("command not specified"))
 	}
 	cmd, arg, err := lookupCommand(CmdLibrarian, arg)
+	if err != nil {
+		return failure.New(failure.UserConfig, err)
+	}
+	layers, err := newFlagLayers()
 	if err != nil {
 		return err
 	}
-	if err := cmd.Parse(arg); err != nil {
+	if _, err := layers.parse(cmd, arg); err != nil {
 		// We expect that if cmd.Parse fails, it will already
 		// have printed out a command-specific usage error,
 		// so we don't need to display the general usage.
-		return err
+		return failure.New(failure.UserConfig, err)
 	}
+	logging.Setup(os.Stderr, cmd.Config.Verbosity, cmd.Config.LogCommands)
+	logging.AddSecret(cmd.Config.GitHubToken)
+	logging.AddSecret(cmd.Config.GerritPassword)
 	slog.Info("librarian", "arguments", arg)
+	if err := runCommand(ctx, cmd); err != nil {
+		if cmd.Config.ErrorFormat == config.ErrorFormatJSON {
+			writeErrorJSON(os.Stdout, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// runCommand validates the config of cmd, and runs cmd.
+func runCommand(ctx context.Context, cmd *cli.Command) error {
+	createdWorkRoot := cmd.Config.WorkRoot == ""
 	if err := cmd.Config.SetDefaults(); err != nil {
-		return fmt.Errorf("failed to initialize config: %w", err)
+		return failure.New(failure.UserConfig, fmt.Errorf("failed to initialize config: %w", err))
 	}
 	if _, err := cmd.Config.IsValid(); err != nil {
-		return fmt.Errorf("failed to validate config: %s", err)
+		return failure.New(failure.UserConfig, fmt.Errorf("failed to validate config: %s", err))
+	}
+	stopProfiling, err := startProfiling(cmd.Config)
+	if err != nil {
+		return err
+	}
+	defer stopProfiling()
+	if cmd.Config.MetricsDir != "" && cmd.Name() != statsCmdName {
+		ctx = metrics.NewContext(ctx, metrics.NewRun(cmd.Config.CommandName, cli.Version()))
+	}
+	start := now()
+	ctx = newArtifactContext(ctx, &artifactRun{})
+	audit, err := newAuditLog(cmd.Config, cmd.Flags, start)
+	if err != nil {
+		return failure.New(failure.UserConfig, fmt.Errorf("invalid -audit-log: %w", err))
+	}
+	ctx = newAuditContext(ctx, audit)
+	if err := cmd.Run(ctx, cmd.Config); err != nil {
+		flushAuditLog(ctx)
+		writeMetrics(ctx, cmd.Config.MetricsDir, err)
+		uploadRunArtifacts(ctx, cmd.Config, start)
+		if cmd.Config.ReportFailures {
+			reportRunFailure(ctx, cmd.Config, err)
+		}
+		return err
+	}
+	flushAuditLog(ctx)
+	writeMetrics(ctx, cmd.Config.MetricsDir, nil)
+	uploadRunArtifacts(ctx, cmd.Config, start)
+	if cmd.Config.CleanWorkRoot && createdWorkRoot && cmd.Config.WorkRoot != "" {
+		if cmd.Config.Profile != "" {
+			slog.Info("Keeping working directory with profiles", "dir", cmd.Config.WorkRoot)
+			return nil
+		}
+		slog.Info("Removing working directory", "dir", cmd.Config.WorkRoot)
+		if err := os.RemoveAll(cmd.Config.WorkRoot); err != nil {
+			slog.Warn("failed to remove working directory", "dir", cmd.Config.WorkRoot, "error", err)
+		}
+	}
+	return nil
+}
+
+// writeMetrics finishes the metrics of the run recorded in ctx, if any, with
+// the failure category of runErr, and writes them into dir. Problems while
+// writing are logged, so that they do not fail the run.
+func writeMetrics(ctx context.Context, dir string, runErr error) {
+	run := metrics.FromContext(ctx)
+	if run == nil {
+		return
+	}
+	category := ""
+	if runErr != nil {
+		category = string(failure.CategoryOf(runErr))
+	}
+	run.Finish(category)
+	if err := run.Write(dir); err != nil {
+		slog.Warn("failed to write metrics", "dir", dir, "error", err)
+	}
+}
+
+// writeErrorJSON writes the JSON report of err as a single line to w.
+func writeErrorJSON(w io.Writer, err error) {
+	data, jsonErr := failure.JSON(err)
+	if jsonErr != nil {
+		slog.Error("failed to encode error as JSON", "error", jsonErr)
+		return
+	}
+	fmt.Fprintf(w, "%s\n", data)
+}
+
+// reportRunFailure files a GitHub issue for the failed run. Problems while
+// reporting are logged, so that the original error is what gets returned.
+func reportRunFailure(ctx context.Context, cfg *config.Config, runErr error) {
+	ghClient, err := newFailureReportClient(cfg)
+	if err != nil {
+		slog.Error("failed to create GitHub client for reporting failure", "error", err)
+		return
+	}
+	if err := reportFailure(ctx, cfg, ghClient, runErr); err != nil {
+		slog.Error("failed to report failure", "error", err)
 	}
-	return cmd.Run(ctx, cmd.Config)
 }
 
 // lookupCommand recursively looks up the command specified by the given arguments.
@@ -95,6 +228,7 @@ func lookupCommand(cmd *cli.Command, args []string) (*cli.Command, []string, err
 
 // GitHubClient is an abstraction over the GitHub client.
 type GitHubClient interface {
+	CheckPermissions(ctx context.Context, permissions ...github.Permission) error
 	GetRawContent(ctx context.Context, path, ref string) ([]byte, error)
 	CreatePullRequest(ctx context.Context, repo *github.Repository, remoteBranch, title, body string) (*github.PullRequestMetadata, error)
 	AddLabelsToIssue(ctx context.Context, repo *github.Repository, number int, labels []string) error
@@ -102,8 +236,27 @@ type GitHubClient interface {
 	ReplaceLabels(ctx context.Context, number int, labels []string) error
 	SearchPullRequests(ctx context.Context, query string) ([]*github.PullRequest, error)
 	GetPullRequest(ctx context.Context, number int) (*github.PullRequest, error)
-	CreateRelease(ctx context.Context, tagName, name, body, commitish string) (*github.RepositoryRelease, error)
+	UpdatePullRequestBody(ctx context.Context, number int, body string) error
+	CreateRelease(ctx context.Context, tagName, name, body, commitish string, prerelease bool) (*github.RepositoryRelease, error)
+	GetReleaseByTag(ctx context.Context, tagName string) (*github.RepositoryRelease, error)
+	ListReleaseTags(ctx context.Context) ([]string, error)
+	ListReleaseAssets(ctx context.Context, releaseID int64) ([]string, error)
+	UploadReleaseAsset(ctx context.Context, releaseID int64, name string, content []byte) error
 	CreateIssueComment(ctx context.Context, number int, comment string) error
+	ListIssueComments(ctx context.Context, number int) ([]*github.IssueComment, error)
+	GetAuthenticatedUser(ctx context.Context) (string, error)
+	IsTeamMember(ctx context.Context, org, team, user string) (bool, error)
+	RequestReviewers(ctx context.Context, number int, users, teams []string) error
+	CreateIssue(ctx context.Context, title, body string, labels []string) (*github.Issue, error)
+	FindOpenIssueWithLabel(ctx context.Context, label string) (*github.Issue, error)
+	RequiresMergeQueue(ctx context.Context, branch string) (bool, error)
+	IsInMergeQueue(ctx context.Context, number int) (bool, error)
+	ListCommitsTouching(ctx context.Context, branch, path string, since time.Time) ([]string, error)
+}
+
+// GerritClient is an abstraction over the Gerrit client.
+type GerritClient interface {
+	SetReview(ctx context.Context, project, branch, changeID string, labels map[string]int, message string) error
 }
 
 // ContainerClient is an abstraction over the Docker client.
@@ -112,6 +265,8 @@ type ContainerClient interface {
 	Configure(ctx context.Context, request *docker.ConfigureRequest) (string, error)
 	Generate(ctx context.Context, request *docker.GenerateRequest) error
 	ReleaseInit(ctx context.Context, request *docker.ReleaseInitRequest) error
+	Prewarm(ctx context.Context, images ...string) error
+	Test(ctx context.Context, request *docker.TestRequest) error
 }
 
 func isURL(s string) bool {
@@ -122,3 +277,9 @@ func isURL(s string) bool {
 
 	return true
 }
+
+// isRemote reports whether the location of a repository is the URL of a
+// remote to clone, either an HTTPS or SSH URL, rather than a local directory.
+func isRemote(s string) bool {
+	return isURL(s) || gitrepo.IsSSHURL(s)
+}
@@ -15,20 +15,20 @@
 package librarian
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"testing"
 
-	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing/object"
-
 	"github.com/googleapis/librarian/internal/cli"
 	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
 	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/metrics"
 	"gopkg.in/yaml.v3"
 
 	"github.com/google/go-cmp/cmp"
@@ -42,6 +42,73 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestRunErrorCategory(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		args []string
+		want failure.Category
+	}{
+		{
+			name: "no command",
+			want: failure.UserConfig,
+		},
+		{
+			name: "unknown command",
+			args: []string{"unknown"},
+			want: failure.UserConfig,
+		},
+		{
+			name: "unknown flag",
+			args: []string{"version", "-unknown"},
+			want: failure.UserConfig,
+		},
+		{
+			name: "invalid config",
+			args: []string{"clean", "-keep-last=-1"},
+			want: failure.UserConfig,
+		},
+		{
+			name: "stats without metrics dir",
+			args: []string{"stats"},
+			want: failure.UserConfig,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := Run(t.Context(), test.args...)
+			if got := failure.CategoryOf(err); got != test.want {
+				t.Errorf("CategoryOf(Run()) = %q, want %q; error = %v", got, test.want, err)
+			}
+		})
+	}
+}
+
+func TestWriteErrorJSON(t *testing.T) {
+	var out bytes.Buffer
+	writeErrorJSON(&out, fmt.Errorf("failed to generate: %w", failure.New(failure.ContainerFailure, errors.New("exit status 1"))))
+	want := `{"category":"container-failure","exit_code":4,"message":"failed to generate: exit status 1"}` + "\n"
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("writeErrorJSON() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	dir := t.TempDir()
+	// Without a run in the context, nothing is written.
+	writeMetrics(t.Context(), dir, nil)
+	ctx := metrics.NewContext(t.Context(), metrics.NewRun("generate", "v0.1.0"))
+	writeMetrics(ctx, dir, failure.New(failure.ContainerFailure, errors.New("exit status 1")))
+	runs, err := metrics.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("len(runs) = %d, want 1", len(runs))
+	}
+	if got, want := runs[0].Failure, string(failure.ContainerFailure); got != want {
+		t.Errorf("Failure = %q, want %q", got, want)
+	}
+}
+
 func TestIsURL(t *testing.T) {
 	for _, test := range []struct {
 		name  string
@@ -103,6 +170,25 @@ func TestIsURL(t *testing.T) {
 	}
 }
 
+func TestIsRemote(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		want  bool
+	}{
+		{input: "https://github.com/googleapis/google-cloud-go", want: true},
+		{input: "git@github.com:googleapis/google-cloud-go.git", want: true},
+		{input: "ssh://git@github.com/googleapis/google-cloud-go.git", want: true},
+		{input: "/path/to/google-cloud-go"},
+		{input: "google-cloud-go"},
+	} {
+		t.Run(test.input, func(t *testing.T) {
+			if got := isRemote(test.input); got != test.want {
+				t.Errorf("isRemote(%q) = %t, want %t", test.input, got, test.want)
+			}
+		})
+	}
+}
+
 // newTestGitRepo creates a new git repository in a temporary directory.
 func newTestGitRepo(t *testing.T) gitrepo.Repository {
 	t.Helper()
@@ -182,57 +268,26 @@ func runGit(t *testing.T, dir string, args ...string) {
 // Each commit has a file path and a commit message.
 // Note that pathAndMessages should at least have one element. All tags are created
 // after the first commit.
-func setupRepoForGetCommits(t *testing.T, pathAndMessages []pathAndMessage, tags []string) *gitrepo.LocalRepository {
+func setupRepoForGetCommits(t *testing.T, pathAndMessages []pathAndMessage, tags []string) *gitrepo.MemoryRepository {
 	t.Helper()
-	dir := t.TempDir()
-	gitRepo, err := git.PlainInit(dir, false)
+	r, err := gitrepo.NewMemoryRepository("")
 	if err != nil {
-		t.Fatalf("git.PlainInit failed: %v", err)
+		t.Fatalf("gitrepo.NewMemoryRepository failed: %v", err)
 	}
-
-	createAndCommit := func(path, msg string) {
-		w, err := gitRepo.Worktree()
-		if err != nil {
-			t.Fatalf("Worktree() failed: %v", err)
-		}
-		fullPath := filepath.Join(dir, path)
-		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
-			t.Fatalf("os.MkdirAll failed: %v", err)
+	for i, pam := range pathAndMessages {
+		content := fmt.Sprintf("content-%d", i)
+		if _, err := r.CommitFiles(pam.message, map[string]string{pam.path: content}); err != nil {
+			t.Fatalf("CommitFiles failed: %v", err)
 		}
-		content := fmt.Sprintf("content-%d", rand.Intn(10000))
-		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
-			t.Fatalf("os.WriteFile failed: %v", err)
+		if i > 0 {
+			continue
 		}
-		if _, err := w.Add(path); err != nil {
-			t.Fatalf("w.Add failed: %v", err)
-		}
-		_, err = w.Commit(msg, &git.CommitOptions{
-			Author: &object.Signature{Name: "Test", Email: "test@example.com"},
-		})
-		if err != nil {
-			t.Fatalf("w.Commit failed: %v", err)
-		}
-	}
-
-	createAndCommit(pathAndMessages[0].path, pathAndMessages[0].message)
-	head, err := gitRepo.Head()
-	if err != nil {
-		t.Fatalf("repo.Head() failed: %v", err)
-	}
-	for _, tag := range tags {
-		if _, err := gitRepo.CreateTag(tag, head.Hash(), nil); err != nil {
-			t.Fatalf("CreateTag failed: %v", err)
+		for _, tag := range tags {
+			if err := r.CreateTag(tag); err != nil {
+				t.Fatalf("CreateTag failed: %v", err)
+			}
 		}
 	}
-
-	for _, pam := range pathAndMessages[1:] {
-		createAndCommit(pam.path, pam.message)
-	}
-
-	r, err := gitrepo.NewRepository(&gitrepo.RepositoryOptions{Dir: dir})
-	if err != nil {
-		t.Fatalf("gitrepo.NewRepository failed: %v", err)
-	}
 	return r
 }
 
@@ -0,0 +1,156 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/github"
+	"gopkg.in/yaml.v3"
+)
+
+// pullRequestBaseBranch is the branch which the pull requests of librarian
+// are merged into, see github.Client.CreatePullRequest.
+const pullRequestBaseBranch = "main"
+
+// mergeQueuePollInterval is how often the state of a pull request queued in a
+// merge queue is polled. It is a variable so that tests can shorten it.
+var mergeQueuePollInterval = 30 * time.Second
+
+// requiresMergeQueue reports whether the pull requests to
+// pullRequestBaseBranch are merged through a merge queue. A failure to read
+// the rulesets of the repository, e.g. for lack of permission, is logged, and
+// the branch is then assumed to have no merge queue.
+func requiresMergeQueue(ctx context.Context, ghClient GitHubClient) bool {
+	required, err := ghClient.RequiresMergeQueue(ctx, pullRequestBaseBranch)
+	if err != nil {
+		slog.Warn("failed to read the rulesets of the base branch, assuming no merge queue", "branch", pullRequestBaseBranch, "error", err)
+		return false
+	}
+	if required {
+		slog.Info("base branch requires a merge queue", "branch", pullRequestBaseBranch)
+	}
+	return required
+}
+
+// waitForMergeQueue returns pr once it is merged. A pull request queued in the
+// merge queue is polled until the queue merges it, for at most timeout. It is
+// an error if pr is closed, removed from the queue, or was never queued.
+func waitForMergeQueue(ctx context.Context, ghClient GitHubClient, pr *github.PullRequest, timeout time.Duration) (*github.PullRequest, error) {
+	number := pr.GetNumber()
+	deadline := time.Now().Add(timeout)
+	for waited := false; ; waited = true {
+		if pr.GetMerged() {
+			return pr, nil
+		}
+		if pr.GetState() == "closed" {
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("pull request %d is closed without being merged", number))
+		}
+		queued, err := ghClient.IsInMergeQueue(ctx, number)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check the merge queue of pull request %d: %w", number, err)
+		}
+		switch {
+		case !queued && waited:
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("pull request %d was removed from the merge queue without being merged", number))
+		case !queued:
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("pull request %d is neither merged nor queued in the merge queue", number))
+		case timeout == 0:
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("pull request %d is queued in the merge queue; set -merge-queue-timeout to wait for it", number))
+		case time.Now().After(deadline):
+			return nil, fmt.Errorf("pull request %d is still queued in the merge queue after %s", number, timeout)
+		}
+		slog.Info("waiting for the merge queue to merge pull request", "pr", number)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(mergeQueuePollInterval):
+		}
+		if pr, err = ghClient.GetPullRequest(ctx, number); err != nil {
+			return nil, fmt.Errorf("failed to get pull request %d: %w", number, err)
+		}
+	}
+}
+
+// resolveReleaseCommit returns the commit of the base branch which merged the
+// release pull request pr, which releases releases. A merge queue squashes or
+// rebases the pull requests which it merges, so the merge commit reported for
+// pr may be a commit of the temporary branch of the queue rather than of the
+// base branch. The commit is the first one of the base branch whose
+// state.yaml records releases with releaseID, as release init recorded them
+// in the state.yaml of pr. The merge commit of pr is returned as is if
+// releaseID is empty.
+func resolveReleaseCommit(ctx context.Context, ghClient GitHubClient, pr *github.PullRequest, releaseID string, releases []libraryRelease) (string, error) {
+	mergeCommit := pr.GetMergeCommitSHA()
+	if releaseID == "" {
+		slog.Warn("release has no ID, using the merge commit of the pull request", "pr", pr.GetNumber(), "commit", mergeCommit)
+		return mergeCommit, nil
+	}
+	statePath := path.Join(config.LibrarianDir, librarianStateFile)
+	// The commits of the base branch cannot predate the pull request.
+	commits, err := ghClient.ListCommitsTouching(ctx, pullRequestBaseBranch, statePath, pr.GetCreatedAt().Time)
+	if err != nil {
+		return "", fmt.Errorf("failed to list the commits of %s: %w", pullRequestBaseBranch, err)
+	}
+	// The commits are listed from the newest, and later commits keep the
+	// release ID until the next release.
+	for _, commit := range slices.Backward(commits) {
+		recorded, err := recordsRelease(ctx, ghClient, statePath, commit, releaseID, releases)
+		if err != nil {
+			return "", err
+		}
+		if recorded {
+			if commit != mergeCommit {
+				slog.Info("resolved the commit of the release in the merge queue", "pr", pr.GetNumber(), "release_id", releaseID, "commit", commit, "merge_commit", mergeCommit)
+			}
+			return commit, nil
+		}
+	}
+	return "", fmt.Errorf("no commit of %s records release %s of pull request %d", pullRequestBaseBranch, releaseID, pr.GetNumber())
+}
+
+// recordsRelease reports whether the state file at statePath, at commit,
+// records each of releases, that is its library at its version, or canary
+// version, with releaseID as the release ID. The pull requests of a release
+// split into several pull requests share its ID, so the ID alone does not
+// tell which of them a commit merged.
+func recordsRelease(ctx context.Context, ghClient GitHubClient, statePath, commit, releaseID string, releases []libraryRelease) (bool, error) {
+	content, err := ghClient.GetRawContent(ctx, statePath, commit)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s at %s: %w", statePath, commit, err)
+	}
+	var state config.LibrarianState
+	if err := yaml.Unmarshal(content, &state); err != nil {
+		return false, fmt.Errorf("failed to parse %s at %s: %w", statePath, commit, err)
+	}
+	if len(releases) == 0 {
+		return false, nil
+	}
+	for _, release := range releases {
+		library := state.LibraryByID(release.Library)
+		if library == nil || library.ReleaseID != releaseID ||
+			(library.Version != release.Version && library.CanaryVersion != release.Version) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
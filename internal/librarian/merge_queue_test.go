@@ -0,0 +1,264 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	gh "github.com/google/go-github/v69/github"
+	"github.com/googleapis/librarian/internal/github"
+)
+
+func TestRequiresMergeQueue(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name     string
+		ghClient *mockGitHubClient
+		want     bool
+	}{
+		{
+			name:     "merge queue",
+			ghClient: &mockGitHubClient{mergeQueue: true},
+			want:     true,
+		},
+		{
+			name:     "no merge queue",
+			ghClient: &mockGitHubClient{},
+		},
+		{
+			name:     "rulesets not readable",
+			ghClient: &mockGitHubClient{mergeQueue: true, mergeQueueErr: errors.New("forbidden")},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			if got := requiresMergeQueue(t.Context(), test.ghClient); got != test.want {
+				t.Errorf("requiresMergeQueue() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+// TestWaitForMergeQueue is not parallel, as it shortens
+// mergeQueuePollInterval.
+func TestWaitForMergeQueue(t *testing.T) {
+	interval := mergeQueuePollInterval
+	mergeQueuePollInterval = time.Millisecond
+	t.Cleanup(func() { mergeQueuePollInterval = interval })
+
+	open := &github.PullRequest{Number: gh.Ptr(7), State: gh.Ptr("open")}
+	merged := &github.PullRequest{Number: gh.Ptr(7), State: gh.Ptr("closed"), Merged: gh.Ptr(true)}
+	closed := &github.PullRequest{Number: gh.Ptr(7), State: gh.Ptr("closed")}
+	for _, test := range []struct {
+		name     string
+		pr       *github.PullRequest
+		ghClient *mockGitHubClient
+		timeout  time.Duration
+		wantErr  string
+	}{
+		{
+			name:     "already merged",
+			pr:       merged,
+			ghClient: &mockGitHubClient{},
+		},
+		{
+			name: "merged by the queue",
+			pr:   open,
+			ghClient: &mockGitHubClient{
+				inMergeQueue:       []bool{true},
+				polledPullRequests: []*github.PullRequest{open, merged},
+			},
+			timeout: time.Minute,
+		},
+		{
+			name:     "closed",
+			pr:       closed,
+			ghClient: &mockGitHubClient{},
+			wantErr:  "closed without being merged",
+		},
+		{
+			name:     "not queued",
+			pr:       open,
+			ghClient: &mockGitHubClient{inMergeQueue: []bool{false}},
+			timeout:  time.Minute,
+			wantErr:  "neither merged nor queued",
+		},
+		{
+			name: "removed from the queue",
+			pr:   open,
+			ghClient: &mockGitHubClient{
+				inMergeQueue: []bool{true, false},
+				pullRequest:  open,
+			},
+			timeout: time.Minute,
+			wantErr: "removed from the merge queue",
+		},
+		{
+			name:     "no timeout",
+			pr:       open,
+			ghClient: &mockGitHubClient{inMergeQueue: []bool{true}},
+			wantErr:  "set -merge-queue-timeout",
+		},
+		{
+			name: "timeout",
+			pr:   open,
+			ghClient: &mockGitHubClient{
+				inMergeQueue: []bool{true},
+				pullRequest:  open,
+			},
+			timeout: 10 * time.Millisecond,
+			wantErr: "still queued in the merge queue after 10ms",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := waitForMergeQueue(t.Context(), test.ghClient, test.pr, test.timeout)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("waitForMergeQueue() error = %v, want to contain %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.GetMerged() {
+				t.Errorf("waitForMergeQueue() returned unmerged pull request")
+			}
+		})
+	}
+}
+
+func TestResolveReleaseCommit(t *testing.T) {
+	t.Parallel()
+	state := func(releaseID string) []byte {
+		return []byte("libraries:\n  - id: a\n    version: 1.1.0\n    release_id: " + releaseID + "\n  - id: b\n    version: 2.0.0\n")
+	}
+	pr := &github.PullRequest{Number: gh.Ptr(7), MergeCommitSHA: gh.Ptr("merge")}
+	releaseA := []libraryRelease{{Library: "a", Version: "1.1.0"}}
+	for _, test := range []struct {
+		name      string
+		releaseID string
+		releases  []libraryRelease
+		ghClient  *mockGitHubClient
+		want      string
+		wantErr   string
+	}{
+		{
+			name:      "merge commit on the base branch",
+			releaseID: "release-2",
+			releases:  releaseA,
+			ghClient: &mockGitHubClient{
+				commitsTouching: []string{"merge", "old"},
+				rawContentByRef: map[string][]byte{"merge": state("release-2"), "old": state("release-1")},
+			},
+			want: "merge",
+		},
+		{
+			name:      "squashed by the queue",
+			releaseID: "release-2",
+			releases:  releaseA,
+			ghClient: &mockGitHubClient{
+				commitsTouching: []string{"next", "squashed", "old"},
+				rawContentByRef: map[string][]byte{
+					"next":     state("release-2"),
+					"squashed": state("release-2"),
+					"old":      state("release-1"),
+				},
+			},
+			want: "squashed",
+		},
+		{
+			name:      "split release",
+			releaseID: "release-2",
+			releases:  []libraryRelease{{Library: "b", Version: "2.1.0"}},
+			ghClient: &mockGitHubClient{
+				commitsTouching: []string{"part-2", "part-1"},
+				rawContentByRef: map[string][]byte{
+					"part-2": []byte("libraries:\n  - id: a\n    version: 1.1.0\n    release_id: release-2\n  - id: b\n    version: 2.1.0\n    release_id: release-2\n"),
+					"part-1": state("release-2"),
+				},
+			},
+			want: "part-2",
+		},
+		{
+			name:      "canary release",
+			releaseID: "release-2",
+			releases:  []libraryRelease{{Library: "a", Version: "1.2.0-canary.1"}},
+			ghClient: &mockGitHubClient{
+				commitsTouching: []string{"canary", "old"},
+				rawContentByRef: map[string][]byte{
+					"canary": []byte("libraries:\n  - id: a\n    version: 1.1.0\n    canary_version: 1.2.0-canary.1\n    release_id: release-2\n"),
+					"old":    state("release-1"),
+				},
+			},
+			want: "canary",
+		},
+		{
+			name:     "no release ID",
+			ghClient: &mockGitHubClient{},
+			want:     "merge",
+		},
+		{
+			name:      "not merged into the base branch",
+			releaseID: "release-2",
+			releases:  releaseA,
+			ghClient: &mockGitHubClient{
+				commitsTouching: []string{"old"},
+				rawContentByRef: map[string][]byte{"old": state("release-1")},
+			},
+			wantErr: "no commit of main records release release-2 of pull request 7",
+		},
+		{
+			name:      "release ID at other versions",
+			releaseID: "release-2",
+			releases:  []libraryRelease{{Library: "a", Version: "1.2.0"}},
+			ghClient: &mockGitHubClient{
+				commitsTouching: []string{"merge"},
+				rawContentByRef: map[string][]byte{"merge": state("release-2")},
+			},
+			wantErr: "no commit of main records release release-2 of pull request 7",
+		},
+		{
+			name:      "unreadable state",
+			releaseID: "release-2",
+			releases:  releaseA,
+			ghClient: &mockGitHubClient{
+				commitsTouching: []string{"old"},
+				rawErr:          errors.New("not found"),
+			},
+			wantErr: "failed to read .librarian/state.yaml at old",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := resolveReleaseCommit(t.Context(), test.ghClient, pr, test.releaseID, test.releases)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("resolveReleaseCommit() error = %v, want to contain %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("resolveReleaseCommit() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -17,8 +17,12 @@ package librarian
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/googleapis/librarian/internal/config"
@@ -30,6 +34,8 @@ import (
 // mockGitHubClient is a mock implementation of the GitHubClient interface for testing.
 type mockGitHubClient struct {
 	GitHubClient
+	checkPermissionsErr     error
+	checkedPermissions      []github.Permission
 	rawContent              []byte
 	rawErr                  error
 	createPullRequestCalls  int
@@ -39,6 +45,10 @@ type mockGitHubClient struct {
 	searchPullRequestsCalls int
 	getPullRequestCalls     int
 	createReleaseCalls      int
+	uploadReleaseAssetCalls int
+	requestReviewersCalls   int
+	createIssueCalls        int
+	createIssueCommentCalls int
 	createPullRequestErr    error
 	addLabelsToIssuesErr    error
 	getLabelsErr            error
@@ -46,19 +56,67 @@ type mockGitHubClient struct {
 	searchPullRequestsErr   error
 	getPullRequestErr       error
 	createReleaseErr        error
+	uploadReleaseAssetErr   error
+	listReleaseTagsErr      error
+	requestReviewersErr     error
+	createIssueErr          error
+	createIssueCommentErr   error
+	findOpenIssueErr        error
 	createdPR               *github.PullRequestMetadata
 	labels                  []string
+	addedLabels             []string
 	pullRequests            []*github.PullRequest
 	pullRequest             *github.PullRequest
 	createdRelease          *github.RepositoryRelease
+	requestedUsers          []string
+	requestedTeams          []string
+	openIssue               *github.Issue
+	createdIssueTitle       string
+	createdIssueBody        string
+	createdIssueLabels      []string
+	issueComment            string
+	uploadedAssetName       string
+	uploadedAssetNames      []string
+	releaseAssets           []string
+	listReleaseAssetsErr    error
+	createdPullRequestBody  string
+	releaseTags             []string
+	createdReleaseTags      []string
+	createdPrereleaseTags   []string
+	createdReleaseCommitish string
+	uploadedAsset           []byte
+	updatedBodies           []string
+	updatePullRequestErr    error
+	getReleaseByTagCalls    int
+	issueComments           []*github.IssueComment
+	listIssueCommentsErr    error
+	authenticatedUser       string
+	teamMembers             map[string][]string
+	// mergeQueue and mergeQueueErr are returned by RequiresMergeQueue.
+	mergeQueue    bool
+	mergeQueueErr error
+	// inMergeQueue are returned, in order, by IsInMergeQueue, which keeps
+	// returning the last one.
+	inMergeQueue []bool
+	// polledPullRequests are returned, in order, by GetPullRequest before
+	// pullRequest.
+	polledPullRequests []*github.PullRequest
+	// rawContentByRef, if set, is returned by GetRawContent by ref.
+	rawContentByRef map[string][]byte
+	// commitsTouching is returned by ListCommitsTouching.
+	commitsTouching []string
 }
 
 func (m *mockGitHubClient) GetRawContent(ctx context.Context, path, ref string) ([]byte, error) {
+	if m.rawContentByRef != nil {
+		return m.rawContentByRef[ref], m.rawErr
+	}
 	return m.rawContent, m.rawErr
 }
 
 func (m *mockGitHubClient) CreatePullRequest(ctx context.Context, repo *github.Repository, remoteBranch, title, body string) (*github.PullRequestMetadata, error) {
 	m.createPullRequestCalls++
+	m.createdPullRequestBody = body
 	if m.createPullRequestErr != nil {
 		return nil, m.createPullRequestErr
 	}
@@ -67,6 +125,7 @@ func (m *mockGitHubClient) CreatePullRequest(ctx context.Context, repo *github.R
 
 func (m *mockGitHubClient) AddLabelsToIssue(ctx context.Context, repo *github.Repository, number int, labels []string) error {
 	m.addLabelsToIssuesCalls++
+	m.addedLabels = append(m.addedLabels, labels...)
 	return m.addLabelsToIssuesErr
 }
 
@@ -87,14 +146,127 @@ func (m *mockGitHubClient) SearchPullRequests(ctx context.Context, query string)
 
 func (m *mockGitHubClient) GetPullRequest(ctx context.Context, number int) (*github.PullRequest, error) {
 	m.getPullRequestCalls++
+	if len(m.polledPullRequests) > 0 {
+		pr := m.polledPullRequests[0]
+		m.polledPullRequests = m.polledPullRequests[1:]
+		return pr, m.getPullRequestErr
+	}
 	return m.pullRequest, m.getPullRequestErr
 }
 
-func (m *mockGitHubClient) CreateRelease(ctx context.Context, tagName, releaseName, body, commitish string) (*github.RepositoryRelease, error) {
+func (m *mockGitHubClient) CreateRelease(ctx context.Context, tagName, releaseName, body, commitish string, prerelease bool) (*github.RepositoryRelease, error) {
 	m.createReleaseCalls++
+	m.createdReleaseTags = append(m.createdReleaseTags, tagName)
+	m.createdReleaseCommitish = commitish
+	if prerelease {
+		m.createdPrereleaseTags = append(m.createdPrereleaseTags, tagName)
+	}
 	return m.createdRelease, m.createReleaseErr
 }
 
+func (m *mockGitHubClient) UpdatePullRequestBody(ctx context.Context, number int, body string) error {
+	m.updatedBodies = append(m.updatedBodies, body)
+	return m.updatePullRequestErr
+}
+
+func (m *mockGitHubClient) GetReleaseByTag(ctx context.Context, tagName string) (*github.RepositoryRelease, error) {
+	m.getReleaseByTagCalls++
+	return m.createdRelease, nil
+}
+
+func (m *mockGitHubClient) ListReleaseTags(ctx context.Context) ([]string, error) {
+	return m.releaseTags, m.listReleaseTagsErr
+}
+
+func (m *mockGitHubClient) ListReleaseAssets(ctx context.Context, releaseID int64) ([]string, error) {
+	return m.releaseAssets, m.listReleaseAssetsErr
+}
+
+func (m *mockGitHubClient) UploadReleaseAsset(ctx context.Context, releaseID int64, name string, content []byte) error {
+	m.uploadReleaseAssetCalls++
+	m.uploadedAssetName = name
+	m.uploadedAssetNames = append(m.uploadedAssetNames, name)
+	m.uploadedAsset = content
+	return m.uploadReleaseAssetErr
+}
+
+func (m *mockGitHubClient) RequestReviewers(ctx context.Context, number int, users, teams []string) error {
+	m.requestReviewersCalls++
+	m.requestedUsers = users
+	m.requestedTeams = teams
+	return m.requestReviewersErr
+}
+
+func (m *mockGitHubClient) CreateIssue(ctx context.Context, title, body string, labels []string) (*github.Issue, error) {
+	m.createIssueCalls++
+	m.createdIssueTitle = title
+	m.createdIssueBody = body
+	m.createdIssueLabels = labels
+	return &github.Issue{}, m.createIssueErr
+}
+
+func (m *mockGitHubClient) FindOpenIssueWithLabel(ctx context.Context, label string) (*github.Issue, error) {
+	return m.openIssue, m.findOpenIssueErr
+}
+
+func (m *mockGitHubClient) CreateIssueComment(ctx context.Context, number int, comment string) error {
+	m.createIssueCommentCalls++
+	m.issueComment = comment
+	return m.createIssueCommentErr
+}
+
+func (m *mockGitHubClient) ListIssueComments(ctx context.Context, number int) ([]*github.IssueComment, error) {
+	return m.issueComments, m.listIssueCommentsErr
+}
+
+func (m *mockGitHubClient) GetAuthenticatedUser(ctx context.Context) (string, error) {
+	return m.authenticatedUser, nil
+}
+
+// IsTeamMember reports whether user is listed in teamMembers under
+// "{org}/{team}".
+func (m *mockGitHubClient) IsTeamMember(ctx context.Context, org, team, user string) (bool, error) {
+	return slices.Contains(m.teamMembers[org+"/"+team], user), nil
+}
+
+func (m *mockGitHubClient) RequiresMergeQueue(ctx context.Context, branch string) (bool, error) {
+	return m.mergeQueue, m.mergeQueueErr
+}
+
+func (m *mockGitHubClient) IsInMergeQueue(ctx context.Context, number int) (bool, error) {
+	if len(m.inMergeQueue) == 0 {
+		return false, nil
+	}
+	queued := m.inMergeQueue[0]
+	if len(m.inMergeQueue) > 1 {
+		m.inMergeQueue = m.inMergeQueue[1:]
+	}
+	return queued, nil
+}
+
+func (m *mockGitHubClient) ListCommitsTouching(ctx context.Context, branch, path string, since time.Time) ([]string, error) {
+	return m.commitsTouching, nil
+}
+
+// mockGerritClient is a mock implementation of the GerritClient interface for
+// testing.
+type mockGerritClient struct {
+	// votes are the labels voted on, by Change-Id.
+	votes        map[string]map[string]int
+	setReviewErr error
+}
+
+func (m *mockGerritClient) SetReview(ctx context.Context, project, branch, changeID string, labels map[string]int, message string) error {
+	if m.setReviewErr != nil {
+		return m.setReviewErr
+	}
+	if m.votes == nil {
+		m.votes = make(map[string]map[string]int)
+	}
+	m.votes[changeID] = labels
+	return nil
+}
+
 // mockContainerClient is a mock implementation of the ContainerClient interface for testing.
 type mockContainerClient struct {
 	ContainerClient
@@ -102,10 +274,14 @@ type mockContainerClient struct {
 	buildCalls     int
 	configureCalls int
 	initCalls      int
+	prewarmCalls   int
+	testCalls      int
 	generateErr    error
 	buildErr       error
 	configureErr   error
 	initErr        error
+	prewarmErr     error
+	testErr        error
 	// Set this value if you want an error when
 	// generate a library with a specific id.
 	failGenerateForID string
@@ -124,10 +300,44 @@ type mockContainerClient struct {
 	// Set this value if you want the configure-response
 	// has library source roots and remove regex.
 	configureLibraryPaths []string
+	// Set this value if you want the build of a variant of the build
+	// matrix to fail.
+	failBuildVariant string
+	// buildVariants and testVariants record the variants of the build and
+	// test requests.
+	buildVariants []string
+	testVariants  []string
+	// buildResults is written as the results of the build container, if not
+	// empty.
+	buildResults string
+}
+
+func (m *mockGitHubClient) CheckPermissions(ctx context.Context, permissions ...github.Permission) error {
+	m.checkedPermissions = permissions
+	return m.checkPermissionsErr
+}
+
+func (m *mockContainerClient) Prewarm(ctx context.Context, images ...string) error {
+	m.prewarmCalls++
+	return m.prewarmErr
 }
 
 func (m *mockContainerClient) Build(ctx context.Context, request *docker.BuildRequest) error {
 	m.buildCalls++
+	if request.Variant != nil {
+		m.buildVariants = append(m.buildVariants, request.Variant.Name)
+		if request.Variant.Name == m.failBuildVariant {
+			return errors.New("simulated build failure of variant")
+		}
+	}
+	if m.buildResults != "" {
+		if err := os.MkdirAll(filepath.Join(request.RepoDir, config.LibrarianDir), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(request.RepoDir, config.LibrarianDir, config.ContainerResults), []byte(m.buildResults), 0644); err != nil {
+			return err
+		}
+	}
 	if m.noBuildResponse {
 		return m.buildErr
 	}
@@ -146,6 +356,14 @@ func (m *mockContainerClient) Build(ctx context.Context, request *docker.BuildRe
 	return m.buildErr
 }
 
+func (m *mockContainerClient) Test(ctx context.Context, request *docker.TestRequest) error {
+	m.testCalls++
+	if request.Variant != nil {
+		m.testVariants = append(m.testVariants, request.Variant.Name)
+	}
+	return m.testErr
+}
+
 func (m *mockContainerClient) Configure(ctx context.Context, request *docker.ConfigureRequest) (string, error) {
 	m.configureCalls++
 
@@ -260,18 +478,26 @@ func (m *mockContainerClient) ReleaseInit(ctx context.Context, request *docker.R
 
 type MockRepository struct {
 	gitrepo.Repository
-	Dir                                  string
-	IsCleanValue                         bool
-	IsCleanError                         error
-	AddAllStatus                         git.Status
-	AddAllError                          error
-	CommitError                          error
-	RemotesValue                         []*git.Remote
-	RemotesError                         error
-	CommitCalls                          int
+	Dir            string
+	IsCleanValue   bool
+	IsCleanError   error
+	AddAllStatus   git.Status
+	AddAllError    error
+	CommitError    error
+	RemotesValue   []*git.Remote
+	RemotesError   error
+	CommitCalls    int
+	CommitMessages []string
+	// CommitHook, if set, is called on each commit, e.g. to inspect the
+	// committed files.
+	CommitHook                           func()
 	GetCommitsForPathsSinceTagValue      []*gitrepo.Commit
 	GetCommitsForPathsSinceTagValueByTag map[string][]*gitrepo.Commit
 	GetCommitsForPathsSinceTagError      error
+	TagCommitTimeValueByTag              map[string]time.Time
+	CommitTimeValueByHash                map[string]time.Time
+	TagsValue                            []string
+	TagsError                            error
 	GetCommitsForPathsSinceLastGenValue  []*gitrepo.Commit
 	GetCommitsForPathsSinceLastGenByPath map[string][]*gitrepo.Commit
 	GetCommitsForPathsSinceLastGenError  error
@@ -279,7 +505,18 @@ type MockRepository struct {
 	ChangedFilesInCommitValueByHash      map[string][]string
 	ChangedFilesInCommitError            error
 	CreateBranchAndCheckoutError         error
+	CheckoutCommitError                  error
+	CheckedOutCommits                    []string
+	CreatedBranches                      []string
+	HeadHashValue                        string
+	FilesAtHead                          map[string]string
+	FilesAtCommit                        map[string]map[string]string
+	StatusError                          error
+	HeadHashError                        error
 	PushError                            error
+	PushedForReview                      []string
+	WrittenBundles                       []string
+	FormattedPatches                     []string
 }
 
 func (m *MockRepository) IsClean() (bool, error) {
@@ -296,8 +533,31 @@ func (m *MockRepository) AddAll() (git.Status, error) {
 	return m.AddAllStatus, nil
 }
 
+func (m *MockRepository) Status() (git.Status, error) {
+	if m.StatusError != nil {
+		return git.Status{}, m.StatusError
+	}
+	return m.AddAllStatus, nil
+}
+
+func (m *MockRepository) ReadFileAtHead(path string) ([]byte, error) {
+	return []byte(m.FilesAtHead[path]), nil
+}
+
+func (m *MockRepository) ReadFileAtCommit(commitHash, path string) ([]byte, error) {
+	content, ok := m.FilesAtCommit[commitHash][path]
+	if !ok {
+		return nil, fmt.Errorf("%s at %s: %w", path, commitHash, os.ErrNotExist)
+	}
+	return []byte(content), nil
+}
+
 func (m *MockRepository) Commit(msg string) error {
 	m.CommitCalls++
+	m.CommitMessages = append(m.CommitMessages, msg)
+	if m.CommitHook != nil {
+		m.CommitHook()
+	}
 	return m.CommitError
 }
 
@@ -324,6 +584,25 @@ func (m *MockRepository) GetCommitsForPathsSinceTag(paths []string, tagName stri
 	return m.GetCommitsForPathsSinceTagValue, nil
 }
 
+func (m *MockRepository) TagCommitTime(tagName string) (time.Time, error) {
+	if t, ok := m.TagCommitTimeValueByTag[tagName]; ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("tag %s not found", tagName)
+}
+
+func (m *MockRepository) CommitTime(hash string) (time.Time, error) {
+	commitTime, ok := m.CommitTimeValueByHash[hash]
+	if !ok {
+		return time.Time{}, fmt.Errorf("commit %s not found", hash)
+	}
+	return commitTime, nil
+}
+
+func (m *MockRepository) Tags() ([]string, error) {
+	return m.TagsValue, m.TagsError
+}
+
 func (m *MockRepository) GetCommitsForPathsSinceCommit(paths []string, sinceCommit string) ([]*gitrepo.Commit, error) {
 	if m.GetCommitsForPathsSinceLastGenError != nil {
 		return nil, m.GetCommitsForPathsSinceLastGenError
@@ -354,15 +633,43 @@ func (m *MockRepository) ChangedFilesInCommit(hash string) ([]string, error) {
 }
 
 func (m *MockRepository) CreateBranchAndCheckout(name string) error {
+	m.CreatedBranches = append(m.CreatedBranches, name)
 	if m.CreateBranchAndCheckoutError != nil {
 		return m.CreateBranchAndCheckoutError
 	}
 	return nil
 }
 
+func (m *MockRepository) HeadHash() (string, error) {
+	return m.HeadHashValue, m.HeadHashError
+}
+
+func (m *MockRepository) CheckoutCommit(hash string) error {
+	m.CheckedOutCommits = append(m.CheckedOutCommits, hash)
+	return m.CheckoutCommitError
+}
+
 func (m *MockRepository) Push(name string) error {
 	if m.PushError != nil {
 		return m.PushError
 	}
 	return nil
 }
+
+func (m *MockRepository) PushForReview(name, ref string) error {
+	if m.PushError != nil {
+		return m.PushError
+	}
+	m.PushedForReview = append(m.PushedForReview, ref)
+	return nil
+}
+
+func (m *MockRepository) WriteBundle(path, base, branchName string) error {
+	m.WrittenBundles = append(m.WrittenBundles, path)
+	return nil
+}
+
+func (m *MockRepository) FormatPatches(dir, base string) error {
+	m.FormattedPatches = append(m.FormattedPatches, dir)
+	return nil
+}
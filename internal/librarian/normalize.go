@@ -0,0 +1,141 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"github.com/googleapis/librarian/internal/config"
+)
+
+// normalizeOutput normalizes the generated files in dir as configured by
+// normalization, so that generating again from unchanged inputs produces
+// identical files. Nothing is done if normalization is nil.
+func normalizeOutput(dir string, normalization *config.Normalization) error {
+	if normalization == nil {
+		return nil
+	}
+	var stripPatterns, sortLines []*regexp.Regexp
+	for _, pattern := range normalization.StripPatterns {
+		re, err := regexp.Compile("(?m)" + pattern)
+		if err != nil {
+			return fmt.Errorf("invalid normalization strip pattern %q: %w", pattern, err)
+		}
+		stripPatterns = append(stripPatterns, re)
+	}
+	for _, path := range normalization.SortLines {
+		re, err := regexp.Compile(path)
+		if err != nil {
+			return fmt.Errorf("invalid normalization sort lines path %q: %w", path, err)
+		}
+		sortLines = append(sortLines, re)
+	}
+
+	var normalized int
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		changed, err := normalizeFile(path, filepath.ToSlash(rel), normalization, stripPatterns, sortLines)
+		if err != nil {
+			return fmt.Errorf("failed to normalize %s: %w", rel, err)
+		}
+		if changed {
+			normalized++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info("Normalized generated files", "dir", dir, "files", normalized)
+	return nil
+}
+
+// normalizeFile normalizes the file at path, whose path relative to the
+// output directory is rel, and reports whether it changed.
+func normalizeFile(path, rel string, normalization *config.Normalization, stripPatterns, sortLines []*regexp.Regexp) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	changed := false
+	if normalization.Permissions {
+		perm := fs.FileMode(0644)
+		if info.Mode().Perm()&0100 != 0 {
+			perm = 0755
+		}
+		if info.Mode().Perm() != perm {
+			if err := os.Chmod(path, perm); err != nil {
+				return false, err
+			}
+			changed = true
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	if bytes.IndexByte(content, 0) >= 0 {
+		// Binary files are kept as they are.
+		return changed, nil
+	}
+	normalized := content
+	if normalization.LineEndings == config.LineEndingsLF {
+		normalized = bytes.ReplaceAll(normalized, []byte("\r\n"), []byte("\n"))
+	}
+	for _, re := range stripPatterns {
+		normalized = re.ReplaceAll(normalized, nil)
+	}
+	if slices.ContainsFunc(sortLines, func(re *regexp.Regexp) bool { return re.MatchString(rel) }) {
+		normalized = sortFileLines(normalized)
+	}
+	if bytes.Equal(normalized, content) {
+		return changed, nil
+	}
+	// WriteFile keeps the permissions of the existing file.
+	if err := os.WriteFile(path, normalized, info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sortFileLines sorts the lines of content, keeping its final newline, if
+// any.
+func sortFileLines(content []byte) []byte {
+	trimmed, hasFinalNewline := bytes.CutSuffix(content, []byte("\n"))
+	lines := bytes.Split(trimmed, []byte("\n"))
+	slices.SortFunc(lines, bytes.Compare)
+	sorted := bytes.Join(lines, []byte("\n"))
+	if hasFinalNewline {
+		sorted = append(sorted, '\n')
+	}
+	return sorted
+}
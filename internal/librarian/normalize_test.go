@@ -0,0 +1,118 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestNormalizeOutput(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a/client.go":        "// Generated on 2025-01-02.\r\npackage a\r\n",
+		"a/files.txt":        "b.go\nc.go\na.go\n",
+		"a/other.txt":        "b\na\n",
+		"a/binary.bin":       "x\x00\r\n",
+		"a/scripts/gen.sh":   "echo\n",
+		"a/unchanged/doc.md": "# Doc\n",
+	}
+	for name, content := range files {
+		if err := writeFile(filepath.Join(dir, name), content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(dir, "a/scripts/gen.sh"), 0775); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(dir, "a/files.txt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	normalization := &config.Normalization{
+		LineEndings:   config.LineEndingsLF,
+		StripPatterns: []string{`^// Generated on .*\n`},
+		SortLines:     []string{`/files\.txt$`},
+		Permissions:   true,
+	}
+	if err := normalizeOutput(dir, normalization); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"a/client.go":        "package a\n",
+		"a/files.txt":        "a.go\nb.go\nc.go\n",
+		"a/other.txt":        "b\na\n",
+		"a/binary.bin":       "x\x00\r\n",
+		"a/scripts/gen.sh":   "echo\n",
+		"a/unchanged/doc.md": "# Doc\n",
+	}
+	for name, wantContent := range want {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(wantContent, string(got)); diff != "" {
+			t.Errorf("content of %s mismatch (-want +got):\n%s", name, diff)
+		}
+	}
+	for name, wantPerm := range map[string]os.FileMode{
+		"a/scripts/gen.sh": 0755,
+		"a/files.txt":      0644,
+	} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != wantPerm {
+			t.Errorf("permissions of %s = %o, want %o", name, got, wantPerm)
+		}
+	}
+}
+
+func TestNormalizeOutput_NotConfigured(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+	if err := writeFile(path, "a\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := normalizeOutput(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "a\r\n" {
+		t.Errorf("content = %q, want it unchanged", got)
+	}
+}
+
+func TestSortFileLines(t *testing.T) {
+	for _, test := range []struct {
+		content string
+		want    string
+	}{
+		{content: "b\na\n", want: "a\nb\n"},
+		{content: "b\na", want: "a\nb"},
+		{content: "", want: ""},
+	} {
+		if got := string(sortFileLines([]byte(test.content))); got != test.want {
+			t.Errorf("sortFileLines(%q) = %q, want %q", test.content, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+const (
+	// offlineOutputDir is the directory, relative to the work root, where
+	// offline runs write their commits for manual transfer.
+	offlineOutputDir = "offline-output"
+	// offlinePatchesDir is the directory of the patches of the commits,
+	// relative to offlineOutputDir.
+	offlinePatchesDir = "patches"
+)
+
+// writeOfflineOutput writes the commits of branch on top of base in repo to
+// the offline output directory of workRoot, in place of pushing them: as a
+// git bundle named after the branch, which "git fetch" reads, and as patches,
+// which "git am" applies.
+func writeOfflineOutput(ctx context.Context, workRoot string, repo gitrepo.Repository, base, branch string) error {
+	outputDir := filepath.Join(workRoot, offlineOutputDir)
+	bundle := filepath.Join(outputDir, branch+gitrepo.BundleSuffix)
+	if err := repo.WriteBundle(bundle, base, branch); err != nil {
+		return err
+	}
+	patches := filepath.Join(outputDir, offlinePatchesDir)
+	if err := repo.FormatPatches(patches, base); err != nil {
+		return err
+	}
+	slog.Info("Wrote changes for manual transfer", "bundle", bundle, "patches", patches)
+	auditLogFromContext(ctx).record(auditOfflineOutput, map[string]string{
+		"branch":  branch,
+		"bundle":  bundle,
+		"patches": patches,
+	})
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+func TestCommitAndPush_Offline(t *testing.T) {
+	workRoot := t.TempDir()
+	status := make(git.Status)
+	status["file.txt"] = &git.FileStatus{Worktree: git.Modified}
+	repo := &MockRepository{
+		Dir:           t.TempDir(),
+		AddAllStatus:  status,
+		HeadHashValue: "1234abcd",
+		PushError:     errors.New("pushed offline"),
+	}
+	err := commitAndPush(context.Background(), &commitInfo{
+		cfg:           &config.Config{Offline: true, WorkRoot: workRoot},
+		repo:          repo,
+		commitMessage: "chore: regenerate",
+		branch:        "librarian-test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.CommitCalls != 1 {
+		t.Errorf("CommitCalls = %d, want 1", repo.CommitCalls)
+	}
+	outputDir := filepath.Join(workRoot, offlineOutputDir)
+	if diff := cmp.Diff([]string{filepath.Join(outputDir, "librarian-test.bundle")}, repo.WrittenBundles); diff != "" {
+		t.Errorf("bundles mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{filepath.Join(outputDir, offlinePatchesDir)}, repo.FormattedPatches); diff != "" {
+		t.Errorf("patches mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckOfflineInputs(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		cfg     *config.Config
+		wantErr bool
+	}{
+		{
+			name: "local inputs",
+			cfg:  &config.Config{CommandName: generateCmdName, Repo: "/tmp/repo", APISource: "/tmp/googleapis.bundle"},
+		},
+		{
+			name: "release without API source",
+			cfg:  &config.Config{CommandName: "release init", Repo: "/tmp/repo.bundle"},
+		},
+		{
+			name:    "remote repository",
+			cfg:     &config.Config{CommandName: generateCmdName, Repo: "https://github.com/googleapis/google-cloud-go", APISource: "/tmp/googleapis"},
+			wantErr: true,
+		},
+		{
+			name:    "remote API source",
+			cfg:     &config.Config{CommandName: generateCmdName, Repo: "/tmp/repo", APISource: defaultAPISource},
+			wantErr: true,
+		},
+		{
+			name:    "missing API source",
+			cfg:     &config.Config{CommandName: generateCmdName, Repo: "/tmp/repo"},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkOfflineInputs(test.cfg)
+			if test.wantErr {
+				if failure.CategoryOf(err) != failure.UserConfig {
+					t.Errorf("checkOfflineInputs() error = %v, want a %s error", err, failure.UserConfig)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("checkOfflineInputs() error = %v", err)
+			}
+		})
+	}
+}
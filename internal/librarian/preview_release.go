@@ -0,0 +1,174 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+var cmdPreviewRelease = &cli.Command{
+	Short:     "preview-release reports which libraries would be released now",
+	UsageLine: "librarian preview-release [flags]",
+	Long: `Computes which libraries "librarian release init" would release right now, their
+next versions and their release notes, and writes them as a Markdown report to
+standard output, e.g. to be posted as a comment on a pull request or an issue.
+
+The libraries are selected like "librarian release init" does: a library is released
+if it has releasable changes since its last release, the members of a release group
+are released together, and libraries whose release violates the release policy are
+skipped. With "-library", only the library and the other members of its release
+group are considered.
+
+The command is read-only: it does not run the language container, and does not
+change the repository, create branches or pull requests.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newPreviewReleaseRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return runner.run(ctx, os.Stdout)
+	},
+}
+
+func init() {
+	cmdPreviewRelease.Init()
+	fs := cmdPreviewRelease.Flags
+	cfg := cmdPreviewRelease.Config
+
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLibrary(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+type previewReleaseRunner struct {
+	cfg             *config.Config
+	repo            gitrepo.Repository
+	state           *config.LibrarianState
+	librarianConfig *config.LibrarianConfig
+	releaseNotes    *releaseNotesFormat
+}
+
+func newPreviewReleaseRunner(cfg *config.Config) (*previewReleaseRunner, error) {
+	runner, err := newCommandRunner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &previewReleaseRunner{
+		cfg:             runner.cfg,
+		repo:            runner.repo,
+		state:           runner.state,
+		librarianConfig: runner.librarianConfig,
+		releaseNotes:    runner.releaseNotes,
+	}, nil
+}
+
+func (r *previewReleaseRunner) run(ctx context.Context, w io.Writer) error {
+	report, err := r.report(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, report)
+	return err
+}
+
+// report returns the Markdown report of the libraries which would be
+// released now. The state is not changed.
+func (r *previewReleaseRunner) report(ctx context.Context) (string, error) {
+	var out bytes.Buffer
+	out.WriteString("## Release preview\n\n")
+	plan, err := planRelease(ctx, r.cfg, r.repo, r.state, r.librarianConfig)
+	if err != nil {
+		return "", err
+	}
+	if plan.Blocked != "" {
+		fmt.Fprintf(&out, "No libraries would be released: %s.\n", plan.Blocked)
+		return out.String(), nil
+	}
+
+	// The release notes are derived from the versions before the release,
+	// like when the release is tagged.
+	notesState := copyLibrarianState(r.state)
+	var released []*config.LibraryState
+	changes := make(map[string]int)
+	for _, library := range plan.Libraries {
+		if library.Release {
+			l := notesState.LibraryByID(library.ID)
+			l.ReleaseTriggered = true
+			released = append(released, l)
+			changes[library.ID] = library.Changes
+		}
+	}
+	if len(released) == 0 {
+		out.WriteString("No libraries would be released.\n")
+		return out.String(), nil
+	}
+	groupChanges, err := releaseGroupChangeLevels(r.repo, notesState)
+	if err != nil {
+		return "", err
+	}
+	var notes bytes.Buffer
+	fmt.Fprintf(&out, "Libraries which would be released: %d\n\n", len(released))
+	out.WriteString("| Library | Version | Next version | Changes | Breaking |\n")
+	out.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, library := range released {
+		libraryNotes, release, err := formatLibraryReleaseNotes(r.repo, r.librarianConfig, r.releaseNotes, library, r.cfg.GitHubHost(), max(groupChanges[library.ReleaseGroup], dependencyChangeLevel(notesState, library)), "")
+		if err != nil {
+			return "", fmt.Errorf("failed to format release notes for library %s: %w", library.ID, err)
+		}
+		breaking := "no"
+		if release.Breaking {
+			breaking = "yes"
+		}
+		previous := release.PreviousVersion
+		if previous == "" {
+			previous = "-"
+		}
+		id := library.ID
+		if library.ReleaseGroup != "" {
+			id = fmt.Sprintf("%s (release group %s)", library.ID, library.ReleaseGroup)
+		}
+		fmt.Fprintf(&out, "| %s | %s | %s | %d | %s |\n", id, previous, release.Version, changes[library.ID], breaking)
+		fmt.Fprintf(&notes, "\n<details><summary>%s: %s</summary>\n\n%s\n\n</details>\n", library.ID, release.Version, libraryNotes)
+	}
+	out.Write(notes.Bytes())
+	return out.String(), nil
+}
+
+// copyLibrarianState returns a copy of state whose libraries can be updated
+// without changing state.
+func copyLibrarianState(state *config.LibrarianState) *config.LibrarianState {
+	c := *state
+	c.Libraries = make([]*config.LibraryState, len(state.Libraries))
+	for i, library := range state.Libraries {
+		l := *library
+		c.Libraries[i] = &l
+	}
+	return &c
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+func TestPreviewReleaseRunner(t *testing.T) {
+	today := time.Now().Format("2006-01-02")
+	hash1 := plumbing.NewHash("1234567890abcdef")
+	hash2 := plumbing.NewHash("fedcba0987654321")
+	repo := &MockRepository{
+		RemotesValue: []*git.Remote{git.NewRemote(nil, &gitconfig.RemoteConfig{Name: "origin", URLs: []string{"https://github.com/owner/repo.git"}})},
+		GetCommitsForPathsSinceTagValueByTag: map[string][]*gitrepo.Commit{
+			"a-1.0.0": {
+				{Message: "feat!: remove a method", Hash: hash1},
+			},
+			"b-2.3.0": {
+				{Message: "fix: a bug fix", Hash: hash2},
+			},
+		},
+		ChangedFilesInCommitValueByHash: map[string][]string{
+			hash1.String(): {"a/a.go"},
+			hash2.String(): {"b/b.go"},
+		},
+	}
+	for _, test := range []struct {
+		name    string
+		library string
+		want    string
+	}{
+		{
+			name: "all libraries",
+			want: fmt.Sprintf(`## Release preview
+
+Libraries which would be released: 2
+
+| Library | Version | Next version | Changes | Breaking |
+| --- | --- | --- | --- | --- |
+| a | 1.0.0 | 2.0.0 | 1 | yes |
+| b | 2.3.0 | 2.3.1 | 1 | no |
+
+<details><summary>a: 2.0.0</summary>
+
+## [2.0.0](https://github.com/owner/repo/compare/a-1.0.0...a-2.0.0) (%[1]s)
+
+### ⚠ BREAKING CHANGES
+* remove a method ([1234567](https://github.com/owner/repo/commit/1234567890abcdef000000000000000000000000))
+
+### Features
+* remove a method ([1234567](https://github.com/owner/repo/commit/1234567890abcdef000000000000000000000000))
+
+</details>
+
+<details><summary>b: 2.3.1</summary>
+
+## [2.3.1](https://github.com/owner/repo/compare/b-2.3.0...b-2.3.1) (%[1]s)
+
+### Bug Fixes
+* a bug fix ([fedcba0](https://github.com/owner/repo/commit/fedcba0987654321000000000000000000000000))
+
+</details>
+`, today),
+		},
+		{
+			name:    "library without changes",
+			library: "c",
+			want:    "## Release preview\n\nNo libraries would be released.\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			state := &config.LibrarianState{
+				Libraries: []*config.LibraryState{
+					{ID: "a", Version: "1.0.0", SourceRoots: []string{"a"}},
+					{ID: "b", Version: "2.3.0", SourceRoots: []string{"b"}},
+					{ID: "c", Version: "0.1.0", SourceRoots: []string{"c"}},
+				},
+			}
+			r := &previewReleaseRunner{
+				cfg:   &config.Config{Library: test.library},
+				repo:  repo,
+				state: state,
+			}
+			var out bytes.Buffer
+			if err := r.run(context.Background(), &out); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, out.String()); diff != "" {
+				t.Errorf("run() mismatch (-want +got):\n%s", diff)
+			}
+			for _, library := range state.Libraries {
+				if library.ReleaseTriggered || len(library.Changes) > 0 {
+					t.Errorf("run() changed the state of library %s", library.ID)
+				}
+			}
+		})
+	}
+}
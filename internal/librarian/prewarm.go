@@ -0,0 +1,63 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+var cmdPrewarm = &cli.Command{
+	Short:     "prewarm pulls the container images used by the pipeline",
+	UsageLine: "librarian prewarm [flags]",
+	Long: `Pulls the container images referenced by the language repository up front.
+
+Images are pulled in parallel, and images pinned to a digest are verified
+against the digest. The "generate" and "release init" commands run the same
+prewarm phase before running any container, so this command is mainly useful
+to populate the image cache of a build environment ahead of time.
+
+If "-registry-mirror" is specified, images are pulled through the mirror,
+which replaces the registry host of each image.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newCommandRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return runner.containerClient.Prewarm(ctx)
+	},
+}
+
+func init() {
+	cmdPrewarm.Init()
+	fs := cmdPrewarm.Flags
+	cfg := cmdPrewarm.Config
+
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagImage(fs, cfg)
+	addFlagImagePlatform(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagNativeImage(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagRegistryMirror(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
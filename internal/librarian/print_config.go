@@ -0,0 +1,134 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+var cmdPrintConfig = &cli.Command{
+	Short:     "print-config prints the effective flags of a command and their origins",
+	UsageLine: "librarian print-config [flags] <command> [flags of the command]",
+	Long: `Prints the effective value of each flag of a command, and where it comes from,
+without running the command.
+
+The value of a flag comes from the first of these layers which sets it:
+  - flag: the command line, e.g. "-registry-mirror=mirror.gcr.io";
+  - env: the environment variable named after the flag, e.g.
+    LIBRARIAN_REGISTRY_MIRROR for -registry-mirror; empty variables are ignored;
+  - file: the flags file, whose path is LIBRARIAN_FLAGS_FILE, or
+    librarian/flags.yaml in the user config directory, e.g.
+    ~/.config/librarian/flags.yaml on Linux;
+  - default: the default of the flag.
+
+The flags file sets flags by name, for all the commands which have them under
+"flags", or for a single command under "commands", which takes precedence:
+
+  flags:
+    registry-mirror: mirror.gcr.io
+  commands:
+    generate:
+      max-concurrency: "4"
+
+For example, "librarian print-config generate -api=google/cloud/foo/v1" prints
+the flags with which "librarian generate -api=google/cloud/foo/v1" would run.
+Subcommands are named with their parent, e.g. "librarian print-config release
+tag-and-release".
+
+Credentials, such as LIBRARIAN_GITHUB_TOKEN, are not flags, and are not printed.`,
+}
+
+func init() {
+	cmdPrintConfig.Init()
+	// The arguments after the flags of print-config, which name the command
+	// to print, are only known once they are parsed.
+	cmdPrintConfig.Run = func(ctx context.Context, cfg *config.Config) error {
+		layers, err := newFlagLayers()
+		if err != nil {
+			return err
+		}
+		return printConfig(os.Stdout, cfg, layers, cmdPrintConfig.Flags.Args())
+	}
+	fs := cmdPrintConfig.Flags
+	cfg := cmdPrintConfig.Config
+
+	addFlagErrorFormat(fs, cfg)
+	addFlagOutputFormat(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+}
+
+// flagValue is the effective value of a flag.
+type flagValue struct {
+	Flag   string     `json:"flag"`
+	Value  string     `json:"value"`
+	Source flagSource `json:"source"`
+	// Detail is the environment variable, or the path of the flags file, of
+	// the value.
+	Detail string `json:"detail,omitempty"`
+}
+
+// printConfig writes the effective values of the flags of the command named
+// by args, followed by its flags, resolved from layers, to w.
+func printConfig(w io.Writer, cfg *config.Config, layers *flagLayers, args []string) error {
+	if len(args) == 0 {
+		return failure.New(failure.UserConfig, errors.New("command not specified"))
+	}
+	cmd, args, err := lookupCommand(CmdLibrarian, args)
+	if err != nil {
+		return failure.New(failure.UserConfig, err)
+	}
+	if cmd == cmdPrintConfig {
+		return failure.New(failure.UserConfig, errors.New("cannot print the config of print-config"))
+	}
+	origins, err := layers.parse(cmd, args)
+	if err != nil {
+		return failure.New(failure.UserConfig, err)
+	}
+	var values []*flagValue
+	cmd.Flags.VisitAll(func(f *flag.Flag) {
+		origin := origins[f.Name]
+		values = append(values, &flagValue{
+			Flag:   "-" + f.Name,
+			Value:  f.Value.String(),
+			Source: origin.Source,
+			Detail: origin.Detail,
+		})
+	})
+	if cfg.OutputFormat == config.OutputFormatJSON {
+		data, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FLAG\tVALUE\tORIGIN")
+	for _, v := range values {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Flag, v.Value, flagOrigin{Source: v.Source, Detail: v.Detail})
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+// This test is not parallel, as it parses the flags of cmdStats, which can
+// only be parsed once.
+func TestPrintConfig(t *testing.T) {
+	saved := *cmdStats.Config
+	t.Cleanup(func() { *cmdStats.Config = saved })
+	layers := &flagLayers{
+		lookupEnv: func(name string) (string, bool) {
+			if name == "LIBRARIAN_METRICS_DIR" {
+				return "/env/metrics", true
+			}
+			return "", false
+		},
+		file:     &flagsFile{Flags: map[string]string{"v": "true"}},
+		filePath: "/flags.yaml",
+	}
+
+	var out bytes.Buffer
+	cfg := &config.Config{OutputFormat: config.OutputFormatJSON}
+	if err := printConfig(&out, cfg, layers, []string{"stats", "-log-commands"}); err != nil {
+		t.Fatal(err)
+	}
+	var got []*flagValue
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []*flagValue{
+		{Flag: "-error-format", Value: cmdStats.Flags.Lookup("error-format").DefValue, Source: flagSourceDefault},
+		{Flag: "-log-commands", Value: "true", Source: flagSourceFlag},
+		{Flag: "-metrics-dir", Value: "/env/metrics", Source: flagSourceEnv, Detail: "LIBRARIAN_METRICS_DIR"},
+		{Flag: "-v", Source: flagSourceFile, Detail: "/flags.yaml"},
+		{Flag: "-vv", Source: flagSourceDefault},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("printConfig() mismatch (-want +got):\n%s", diff)
+	}
+
+	if cmdStats.Config.Verbosity != 1 {
+		t.Errorf("printConfig() verbosity = %d, want 1", cmdStats.Config.Verbosity)
+	}
+
+	for _, args := range [][]string{nil, {"unknown"}, {"print-config"}} {
+		if err := printConfig(&out, &config.Config{}, layers, args); failure.CategoryOf(err) != failure.UserConfig {
+			t.Errorf("printConfig(%q) error = %v, want %q error", args, err, failure.UserConfig)
+		}
+	}
+}
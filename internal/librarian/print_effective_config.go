@@ -0,0 +1,83 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+var cmdPrintEffectiveConfig = &cli.Command{
+	Short:     "print-effective-config prints the resolved config.yaml",
+	UsageLine: "librarian print-effective-config [flags]",
+	Long: `Prints the effective ".librarian/config.yaml" of the language repository.
+
+If the config extends a base config through the "extends" field, the chain of
+base configs is loaded and merged, and the result is printed as YAML. This
+is useful to debug config inheritance.
+
+Unlike other commands, a local repository does not need to be clean, so that
+changes to the config can be inspected before they are committed.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		return printEffectiveConfig(os.Stdout, cfg)
+	},
+}
+
+func init() {
+	cmdPrintEffectiveConfig.Init()
+	fs := cmdPrintEffectiveConfig.Flags
+	cfg := cmdPrintEffectiveConfig.Config
+
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+func printEffectiveConfig(w io.Writer, cfg *config.Config) error {
+	repoDir := cfg.Repo
+	if isRemote(cfg.Repo) {
+		repo, err := cloneOrOpenRepo(cfg.WorkRoot, cfg.Repo, cfg.CI, cfg.GitHubToken, repoSSHOptions(cfg), gitTuning(cfg))
+		if err != nil {
+			return err
+		}
+		repoDir = repo.GetDir()
+	}
+	lc, err := parseLibrarianConfig(filepath.Join(repoDir, config.LibrarianDir, librarianConfigFile))
+	if err != nil {
+		return err
+	}
+	if lc == nil {
+		return fmt.Errorf("no %s found in %s", librarianConfigFile, config.LibrarianDir)
+	}
+	data, err := yaml.Marshal(lc)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
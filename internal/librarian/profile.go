@@ -0,0 +1,120 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"slices"
+
+	"github.com/googleapis/librarian/internal/config"
+)
+
+// The names of the files into which profiles are written, in the work root.
+const (
+	cpuProfileFile = "cpu.pprof"
+	memProfileFile = "mem.pprof"
+	traceFile      = "trace.out"
+)
+
+// startProfiling starts collecting the profiles of the librarian process
+// listed in cfg.Profile, and returns a function which stops collecting them
+// and writes them into the work root. Problems while writing the profiles are
+// logged, so that they do not fail the run.
+func startProfiling(cfg *config.Config) (func(), error) {
+	profiles := cfg.Profiles()
+	if len(profiles) == 0 {
+		return func() {}, nil
+	}
+	var stops []func()
+	stop := func() {
+		for _, stop := range slices.Backward(stops) {
+			stop()
+		}
+	}
+	if slices.Contains(profiles, config.ProfileCPU) {
+		f, err := createProfileFile(cfg.WorkRoot, cpuProfileFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		stops = append(stops, func() {
+			pprof.StopCPUProfile()
+			closeProfileFile(f)
+		})
+	}
+	if slices.Contains(profiles, config.ProfileTrace) {
+		f, err := createProfileFile(cfg.WorkRoot, traceFile)
+		if err != nil {
+			stop()
+			return nil, err
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			stop()
+			return nil, fmt.Errorf("failed to start trace: %w", err)
+		}
+		stops = append(stops, func() {
+			trace.Stop()
+			closeProfileFile(f)
+		})
+	}
+	if slices.Contains(profiles, config.ProfileMem) {
+		stops = append(stops, func() {
+			writeMemProfile(cfg.WorkRoot)
+		})
+	}
+	return stop, nil
+}
+
+func createProfileFile(dir, name string) (*os.File, error) {
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create profile: %w", err)
+	}
+	return f, nil
+}
+
+func closeProfileFile(f *os.File) {
+	if err := f.Close(); err != nil {
+		slog.Warn("failed to write profile", "file", f.Name(), "error", err)
+		return
+	}
+	slog.Info("Wrote profile", "file", f.Name())
+}
+
+// writeMemProfile writes a heap profile, of the allocations since the start of
+// the process, into dir.
+func writeMemProfile(dir string) {
+	f, err := createProfileFile(dir, memProfileFile)
+	if err != nil {
+		slog.Warn("failed to write profile", "error", err)
+		return
+	}
+	// Get up-to-date statistics.
+	runtime.GC()
+	if err := pprof.Lookup("allocs").WriteTo(f, 0); err != nil {
+		slog.Warn("failed to write profile", "file", f.Name(), "error", err)
+	}
+	closeProfileFile(f)
+}
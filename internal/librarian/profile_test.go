@@ -0,0 +1,78 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestStartProfiling(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		profile   string
+		wantFiles []string
+	}{
+		{
+			name: "no profiles",
+		},
+		{
+			name:      "all profiles",
+			profile:   "cpu,mem,trace",
+			wantFiles: []string{cpuProfileFile, memProfileFile, traceFile},
+		},
+		{
+			name:      "mem only",
+			profile:   "mem",
+			wantFiles: []string{memProfileFile},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			workRoot := t.TempDir()
+			stop, err := startProfiling(&config.Config{Profile: test.profile, WorkRoot: workRoot})
+			if err != nil {
+				t.Fatalf("startProfiling() error = %v", err)
+			}
+			stop()
+			entries, err := os.ReadDir(workRoot)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(test.wantFiles) {
+				t.Errorf("startProfiling() wrote %d files, want %d", len(entries), len(test.wantFiles))
+			}
+			for _, name := range test.wantFiles {
+				info, err := os.Stat(filepath.Join(workRoot, name))
+				if err != nil {
+					t.Errorf("profile %s not written: %v", name, err)
+					continue
+				}
+				if info.Size() == 0 {
+					t.Errorf("profile %s is empty", name)
+				}
+			}
+		})
+	}
+}
+
+func TestStartProfilingError(t *testing.T) {
+	_, err := startProfiling(&config.Config{Profile: "cpu", WorkRoot: filepath.Join(t.TempDir(), "missing")})
+	if err == nil {
+		t.Error("startProfiling() error = nil, want error")
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"cmp"
+	"context"
+	"errors"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+const (
+	proposeCmdName = "propose"
+)
+
+var cmdPropose = &cli.Command{
+	Short:     "propose generates the client library of an API and raises a pull request",
+	UsageLine: "librarian propose -api-path=<api-path> -repo=<repo> [flags]",
+	Long: `Generates the client library of a single API and proposes it as a pull request to the
+language repository, in one step. It is meant for API producers who want a pull request with
+the client library of their new or changed API, without knowing the generation pipeline.
+
+The command clones the language repository of "-repo" and the API source, generates and
+builds the library of "-api-path", commits the changes to a new branch, pushes it, and creates
+a pull request. It is equivalent to:
+
+  librarian generate -api=<api-path> -library=<library> -build -push -repo=<repo>
+
+The library defaults to the library of the API in ".librarian/state.yaml", or, for a new
+API, to a new library whose ID is derived from the API path, e.g. "google-cloud-foo-v1" for
+"google/cloud/foo/v1". Specify "-library" to onboard the API with another ID.
+
+A GitHub token with write access to the language repository is required in the
+LIBRARIAN_GITHUB_TOKEN environment variable.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newProposeRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return runner.run(ctx)
+	},
+}
+
+func init() {
+	cmdPropose.Init()
+	fs := cmdPropose.Flags
+	cfg := cmdPropose.Config
+
+	addFlagAPIPath(fs, cfg)
+	addFlagAPIRef(fs, cfg)
+	addFlagAPISource(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagImage(fs, cfg)
+	addFlagLibrary(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+
+	// The generated library is always built, and proposed as a pull request.
+	cfg.Build = true
+	cfg.Push = true
+}
+
+// newProposeRunner returns the generate runner of the library of -api-path.
+func newProposeRunner(cfg *config.Config) (*generateRunner, error) {
+	if cfg.API == "" {
+		return nil, failure.New(failure.UserConfig, errors.New("-api-path is required"))
+	}
+	// propose runs the generate command, which sets up the API source and
+	// the generation of the library by its name.
+	cfg.CommandName = generateCmdName
+	runner, err := newGenerateRunner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Library == "" {
+		cfg.Library = cmp.Or(findLibraryIDByAPIPath(runner.state, cfg.API), libraryIDForAPI(cfg.API))
+	}
+	return runner, nil
+}
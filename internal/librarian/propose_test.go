@@ -0,0 +1,89 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"testing"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+func TestProposeDefaults(t *testing.T) {
+	if !cmdPropose.Config.Build || !cmdPropose.Config.Push {
+		t.Errorf("propose Build = %t, Push = %t, want both true", cmdPropose.Config.Build, cmdPropose.Config.Push)
+	}
+}
+
+func TestNewProposeRunner(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name        string
+		api         string
+		library     string
+		wantLibrary string
+		wantErr     bool
+	}{
+		{
+			name:        "existing API",
+			api:         "some/api",
+			wantLibrary: "some-library",
+		},
+		{
+			name:        "new API",
+			api:         "google/cloud/foo/v1",
+			wantLibrary: "google-cloud-foo-v1",
+		},
+		{
+			name:        "library specified",
+			api:         "google/cloud/foo/v1",
+			library:     "foo",
+			wantLibrary: "foo",
+		},
+		{
+			name:    "missing API path",
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := &config.Config{
+				API:         test.api,
+				Library:     test.library,
+				APISource:   newTestGitRepo(t).GetDir(),
+				Repo:        newTestGitRepo(t).GetDir(),
+				WorkRoot:    t.TempDir(),
+				Image:       "gcr.io/test/test-image",
+				CommandName: proposeCmdName,
+			}
+			_, err := newProposeRunner(cfg)
+			if test.wantErr {
+				if failure.CategoryOf(err) != failure.UserConfig {
+					t.Fatalf("newProposeRunner() error = %v, want a %s failure", err, failure.UserConfig)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Library != test.wantLibrary {
+				t.Errorf("newProposeRunner() library = %q, want %q", cfg.Library, test.wantLibrary)
+			}
+			if cfg.CommandName != generateCmdName {
+				t.Errorf("newProposeRunner() command = %q, want %q", cfg.CommandName, generateCmdName)
+			}
+		})
+	}
+}
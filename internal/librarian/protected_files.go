@@ -0,0 +1,123 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// protectedFileChanges returns the files in report which are protected files
+// of their library.
+func protectedFileChanges(lc *config.LibrarianConfig, report *generationReport) []*changedFile {
+	var changes []*changedFile
+	for _, library := range report.Libraries {
+		for _, file := range library.Files {
+			if lc.IsProtected(library.ID, file.Path) {
+				changes = append(changes, file)
+			}
+		}
+	}
+	return changes
+}
+
+// guardProtectedFiles checks that generation did not change protected files.
+// Depending on the configuration, changes to protected files either fail the
+// run, or are reverted in the working tree and returned as a follow-up commit,
+// so that they are reviewed separately from the rest of the generated code.
+func guardProtectedFiles(cfg *config.Config, lc *config.LibrarianConfig, repo gitrepo.Repository, report *generationReport) (*followUpCommit, error) {
+	changes := protectedFileChanges(lc, report)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	var paths []string
+	for _, change := range changes {
+		paths = append(paths, fmt.Sprintf("%s (%s)", change.Path, change.Change))
+	}
+	if lc.ProtectedFilesAction() == config.ProtectedFilesFail {
+		return nil, fmt.Errorf("generation changed protected files: %s", strings.Join(paths, ", "))
+	}
+	if !cfg.Commit && !cfg.Push {
+		slog.Warn("Generation changed protected files, which are left in the working tree", "files", paths)
+		return nil, nil
+	}
+
+	slog.Warn("Generation changed protected files, quarantining them into a separate commit", "files", paths)
+	apply, err := setAsideChanges(repo, changes)
+	if err != nil {
+		return nil, err
+	}
+	message := fmt.Sprintf("chore: quarantine generated changes to protected files\n\nGeneration changed the following protected files, review before merging:\n\n* %s\n",
+		strings.Join(paths, "\n* "))
+	return &followUpCommit{message: message, apply: apply}, nil
+}
+
+// setAsideChanges reverts the changes to files in the working tree. It
+// returns a function which applies the changes again.
+func setAsideChanges(repo gitrepo.Repository, changes []*changedFile) (apply func() error, err error) {
+	repoDir := repo.GetDir()
+	// generated holds the content written by generation, nil for deleted
+	// files.
+	generated := make(map[string][]byte)
+	for _, change := range changes {
+		path := filepath.Join(repoDir, change.Path)
+		if change.Change != fileChangeDeleted {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			generated[change.Path] = content
+		}
+		if err := restoreFileAtHead(repo, change); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", change.Path, err)
+		}
+	}
+	apply = func() error {
+		for _, change := range changes {
+			path := filepath.Join(repoDir, change.Path)
+			content, ok := generated[change.Path]
+			if !ok {
+				if err := os.Remove(path); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := writeFile(path, string(content)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return apply, nil
+}
+
+// restoreFileAtHead reverts the change to a file in the working tree.
+func restoreFileAtHead(repo gitrepo.Repository, change *changedFile) error {
+	path := filepath.Join(repo.GetDir(), change.Path)
+	if change.Change == fileChangeAdded {
+		return os.Remove(path)
+	}
+	content, err := repo.ReadFileAtHead(change.Path)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, string(content))
+}
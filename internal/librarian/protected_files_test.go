@@ -0,0 +1,189 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	gogitConfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/github"
+)
+
+func TestGuardProtectedFiles(t *testing.T) {
+	report := &generationReport{
+		Libraries: []*libraryGenerationReport{
+			{
+				ID: "a",
+				Files: []*changedFile{
+					{Path: "a/gen.go", Change: fileChangeModified, Kind: fileKindGenerated},
+					{Path: "a/handwritten/modified.go", Change: fileChangeModified, Kind: fileKindGenerated},
+					{Path: "a/handwritten/deleted.go", Change: fileChangeDeleted, Kind: fileKindGenerated},
+					{Path: "a/handwritten/added.go", Change: fileChangeAdded, Kind: fileKindGenerated},
+				},
+			},
+		},
+	}
+	protectedFiles := func(onChange string) *config.LibrarianConfig {
+		return &config.LibrarianConfig{
+			ProtectedFiles: &config.ProtectedFiles{
+				OnChange: onChange,
+				Libraries: []*config.LibraryProtectedFiles{
+					{ID: "a", Paths: []string{"a/handwritten"}},
+				},
+			},
+		}
+	}
+	setup := func(t *testing.T) *MockRepository {
+		repoDir := t.TempDir()
+		for path, content := range map[string]string{
+			"a/gen.go":                  "generated",
+			"a/handwritten/modified.go": "generated",
+			"a/handwritten/added.go":    "generated",
+		} {
+			if err := writeFile(filepath.Join(repoDir, path), content); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return &MockRepository{
+			Dir: repoDir,
+			FilesAtHead: map[string]string{
+				"a/handwritten/modified.go": "handwritten",
+				"a/handwritten/deleted.go":  "handwritten",
+			},
+		}
+	}
+
+	t.Run("no protected files", func(t *testing.T) {
+		repo := setup(t)
+		quarantine, err := guardProtectedFiles(&config.Config{Push: true}, &config.LibrarianConfig{}, repo, report)
+		if err != nil || quarantine != nil {
+			t.Errorf("guardProtectedFiles() = %v, %v, want nil, nil", quarantine, err)
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		repo := setup(t)
+		_, err := guardProtectedFiles(&config.Config{Push: true}, protectedFiles(""), repo, report)
+		if err == nil {
+			t.Fatal("guardProtectedFiles() error = nil, want error")
+		}
+		for _, path := range []string{"a/handwritten/modified.go", "a/handwritten/deleted.go", "a/handwritten/added.go"} {
+			if !strings.Contains(err.Error(), path) {
+				t.Errorf("guardProtectedFiles() error = %v, want it to list %s", err, path)
+			}
+		}
+		if strings.Contains(err.Error(), "a/gen.go") {
+			t.Errorf("guardProtectedFiles() error = %v, lists unprotected file", err)
+		}
+	})
+
+	t.Run("quarantine without commit", func(t *testing.T) {
+		repo := setup(t)
+		quarantine, err := guardProtectedFiles(&config.Config{}, protectedFiles(config.ProtectedFilesQuarantine), repo, report)
+		if err != nil || quarantine != nil {
+			t.Errorf("guardProtectedFiles() = %v, %v, want nil, nil", quarantine, err)
+		}
+	})
+
+	t.Run("quarantine", func(t *testing.T) {
+		repo := setup(t)
+		quarantine, err := guardProtectedFiles(&config.Config{Commit: true}, protectedFiles(config.ProtectedFilesQuarantine), repo, report)
+		if err != nil {
+			t.Fatalf("guardProtectedFiles() error = %v", err)
+		}
+		assertFiles(t, repo.Dir, map[string]string{
+			"a/gen.go":                  "generated",
+			"a/handwritten/modified.go": "handwritten",
+			"a/handwritten/deleted.go":  "handwritten",
+			"a/handwritten/added.go":    "",
+		})
+
+		if err := quarantine.apply(); err != nil {
+			t.Fatalf("apply() error = %v", err)
+		}
+		assertFiles(t, repo.Dir, map[string]string{
+			"a/gen.go":                  "generated",
+			"a/handwritten/modified.go": "generated",
+			"a/handwritten/deleted.go":  "",
+			"a/handwritten/added.go":    "generated",
+		})
+		if !strings.Contains(quarantine.message, "a/handwritten/modified.go (modified)") {
+			t.Errorf("quarantine message = %q, want it to list the protected files", quarantine.message)
+		}
+	})
+}
+
+func TestCommitAndCreatePullRequest_FollowUpCommits(t *testing.T) {
+	remote := git.NewRemote(memory.NewStorage(), &gogitConfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{"https://github.com/googleapis/librarian.git"},
+	})
+	repo := &MockRepository{
+		Dir:          t.TempDir(),
+		AddAllStatus: git.Status{"file.txt": &git.FileStatus{Worktree: git.Modified}},
+		RemotesValue: []*git.Remote{remote},
+	}
+	var applied []string
+	info := &commitInfo{
+		cfg:           &config.Config{Push: true},
+		state:         &config.LibrarianState{},
+		repo:          repo,
+		ghClient:      &mockGitHubClient{createdPR: &github.PullRequestMetadata{Number: 1}},
+		commitMessage: "feat: generate",
+		followUpCommits: []*followUpCommit{
+			{message: "chore: first", apply: func() error { applied = append(applied, "first"); return nil }},
+			{message: "chore: second", apply: func() error { applied = append(applied, "second"); return nil }},
+		},
+	}
+	if _, err := commitAndCreatePullRequest(context.Background(), info); err != nil {
+		t.Fatalf("commitAndCreatePullRequest() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"first", "second"}, applied); diff != "" {
+		t.Errorf("applied follow-up commits mismatch (-want +got):\n%s", diff)
+	}
+	if repo.CommitCalls != 3 {
+		t.Errorf("CommitCalls = %d, want 3", repo.CommitCalls)
+	}
+}
+
+// assertFiles checks the content of files in dir. An empty content means that
+// the file must not exist.
+func assertFiles(t *testing.T, dir string, want map[string]string) {
+	t.Helper()
+	for path, content := range want {
+		got, err := os.ReadFile(filepath.Join(dir, path))
+		if content == "" {
+			if !os.IsNotExist(err) {
+				t.Errorf("%s exists, want it removed", path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("failed to read %s: %v", path, err)
+			continue
+		}
+		if diff := cmp.Diff(content, string(got)); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", path, diff)
+		}
+	}
+}
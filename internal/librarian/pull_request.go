@@ -15,9 +15,13 @@
 package librarian
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
+
+	"github.com/googleapis/librarian/internal/github"
 )
 
 // A PullRequestContent builds up the content of a pull request.
@@ -64,3 +68,53 @@ func formatListAsMarkdown(title string, list []string) string {
 	builder.WriteString("\n\n")
 	return builder.String()
 }
+
+// applyPullRequestConfig adds the labels configured for the kind of the pull
+// request in info, and requests reviews from the configured reviewers. Labels
+// the pull request already has are not added again, so that refreshing a pull
+// request does not duplicate them. Failing to label the pull request or to
+// request reviews does not fail the command.
+func applyPullRequestConfig(ctx context.Context, info *commitInfo, repo *github.Repository, pr *github.PullRequestMetadata) {
+	if pr == nil {
+		return
+	}
+	if labels := info.librarianConfig.PullRequestLabels(info.kind, info.libraryIDs); len(labels) > 0 {
+		existing, err := info.ghClient.GetLabels(ctx, pr.Number)
+		if err != nil {
+			slog.Warn("failed to get labels of pull request", "pr", pr.Number, "err", err)
+		} else {
+			var missing []string
+			for _, label := range labels {
+				if !slices.Contains(existing, label) {
+					missing = append(missing, label)
+				}
+			}
+			if len(missing) > 0 {
+				if err := info.ghClient.AddLabelsToIssue(ctx, repo, pr.Number, missing); err != nil {
+					slog.Warn("failed to label pull request", "pr", pr.Number, "labels", missing, "err", err)
+				}
+			}
+		}
+	}
+	users, teams := splitReviewers(info.librarianConfig.PullRequestReviewers(info.kind))
+	if len(users) == 0 && len(teams) == 0 {
+		return
+	}
+	if err := info.ghClient.RequestReviewers(ctx, pr.Number, users, teams); err != nil {
+		slog.Warn("failed to request reviews from configured reviewers", "pr", pr.Number, "err", err)
+	}
+}
+
+// splitReviewers splits reviewers into individual users and team slugs as
+// expected by the GitHub API.
+func splitReviewers(reviewers []string) (users, teams []string) {
+	for _, reviewer := range reviewers {
+		name := strings.TrimPrefix(reviewer, "@")
+		if _, team, ok := strings.Cut(name, "/"); ok {
+			teams = append(teams, team)
+			continue
+		}
+		users = append(users, name)
+	}
+	return users, teams
+}
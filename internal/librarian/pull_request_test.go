@@ -15,9 +15,14 @@
 package librarian
 
 import (
+	"context"
 	"errors"
 	"slices"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/github"
 )
 
 func TestAddErrorToPullRequest(t *testing.T) {
@@ -64,3 +69,81 @@ func TestFormatListAsMarkdownNonEmptyList(t *testing.T) {
 		t.Errorf("got %q, want %q", got, want)
 	}
 }
+
+func TestApplyPullRequestConfig(t *testing.T) {
+	librarianConfig := &config.LibrarianConfig{
+		PullRequests: &config.PullRequests{
+			Labels:       []string{"automated"},
+			Reviewers:    []string{"@octocat"},
+			LibraryLabel: "api: {id}",
+			Kinds: []*config.PullRequestKind{
+				{Kind: config.PullRequestKindRelease, Labels: []string{"release"}, Reviewers: []string{"@googleapis/releasers"}},
+			},
+		},
+	}
+	for _, test := range []struct {
+		name          string
+		kind          string
+		existing      []string
+		wantAdded     []string
+		wantUsers     []string
+		wantTeams     []string
+		wantReviewers int
+	}{
+		{
+			name:          "generate",
+			kind:          config.PullRequestKindGenerate,
+			wantAdded:     []string{"automated", "api: a"},
+			wantUsers:     []string{"octocat"},
+			wantReviewers: 1,
+		},
+		{
+			name:          "release",
+			kind:          config.PullRequestKindRelease,
+			wantAdded:     []string{"automated", "release", "api: a"},
+			wantUsers:     []string{"octocat"},
+			wantTeams:     []string{"releasers"},
+			wantReviewers: 1,
+		},
+		{
+			name:          "refreshed pull request",
+			kind:          config.PullRequestKindRelease,
+			existing:      []string{"automated", "api: a"},
+			wantAdded:     []string{"release"},
+			wantUsers:     []string{"octocat"},
+			wantTeams:     []string{"releasers"},
+			wantReviewers: 1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			client := &mockGitHubClient{labels: test.existing}
+			info := &commitInfo{
+				ghClient:        client,
+				libraryIDs:      []string{"a"},
+				kind:            test.kind,
+				librarianConfig: librarianConfig,
+			}
+			applyPullRequestConfig(context.Background(), info, &github.Repository{Owner: "o", Name: "r"}, &github.PullRequestMetadata{Number: 7})
+			if diff := cmp.Diff(test.wantAdded, client.addedLabels); diff != "" {
+				t.Errorf("added labels mismatch (-want +got):\n%s", diff)
+			}
+			if client.requestReviewersCalls != test.wantReviewers {
+				t.Errorf("requestReviewersCalls = %d, want %d", client.requestReviewersCalls, test.wantReviewers)
+			}
+			if diff := cmp.Diff(test.wantUsers, client.requestedUsers); diff != "" {
+				t.Errorf("requested users mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.wantTeams, client.requestedTeams); diff != "" {
+				t.Errorf("requested teams mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestApplyPullRequestConfig_NotConfigured(t *testing.T) {
+	client := &mockGitHubClient{}
+	applyPullRequestConfig(context.Background(), &commitInfo{ghClient: client, kind: config.PullRequestKindGenerate}, &github.Repository{}, &github.PullRequestMetadata{Number: 7})
+	if client.getLabelsCalls != 0 || client.addLabelsToIssuesCalls != 0 || client.requestReviewersCalls != 0 {
+		t.Errorf("applyPullRequestConfig() called GitHub, want no calls")
+	}
+}
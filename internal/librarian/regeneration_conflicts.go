@@ -0,0 +1,234 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"gopkg.in/yaml.v3"
+)
+
+// regenerationConflict is a file which regeneration changed, and which was
+// also edited manually since the last generation of its library.
+type regenerationConflict struct {
+	path     string
+	strategy string
+}
+
+// resolveRegenerationConflicts resolves the conflicts between the changes
+// made by regeneration to the working tree of repo and manual edits, according
+// to the conflict resolution rules of lc. A file changed by regeneration has
+// manual edits if its content at HEAD differs from its content in the last
+// generation commit of its library, i.e. the commit which recorded the current
+// last_generated_commit of the library in state.yaml. That commit is also the
+// base of three-way merges.
+//
+// It returns the resolved conflicts. Conflicts resolved with the "fail"
+// strategy, and merges with overlapping changes, fail the run. Without
+// conflict resolution rules, conflicts are only looked for with review, i.e.
+// -review, which reviews those resolved with the default "prefer-generated"
+// strategy.
+func resolveRegenerationConflicts(lc *config.LibrarianConfig, repo gitrepo.Repository, state *config.LibrarianState, libraryIDs []string, review bool) ([]*regenerationConflict, error) {
+	if !review && (lc == nil || len(lc.ConflictResolution) == 0) {
+		return nil, nil
+	}
+	status, err := repo.Status()
+	if err != nil {
+		return nil, err
+	}
+	var history *stateHistory
+	// bases holds the last generation commit of each library, or an empty
+	// string if it is unknown.
+	bases := make(map[string]string)
+	var conflicts []*regenerationConflict
+	var unresolved []string
+	for _, file := range sortedStatusFiles(status) {
+		change, err := newChangedFile(repo, file, status.File(file))
+		if err != nil {
+			return nil, err
+		}
+		// Added files have no manual edits, as they do not exist at HEAD.
+		if change == nil || change.Change == fileChangeAdded {
+			continue
+		}
+		id := libraryForFile(state, libraryIDs, file)
+		if id == "" {
+			continue
+		}
+		base, ok := bases[id]
+		if !ok {
+			if history == nil {
+				if history, err = loadStateHistory(repo); err != nil {
+					return nil, fmt.Errorf("failed to load history of state.yaml: %w", err)
+				}
+			}
+			base = history.lastGenerationCommit(id)
+			bases[id] = base
+		}
+		if base == "" {
+			continue
+		}
+		baseContent, err := readFileAtCommit(repo, base, file)
+		if err != nil {
+			return nil, err
+		}
+		manual, err := repo.ReadFileAtHead(file)
+		if err != nil {
+			return nil, err
+		}
+		if baseContent != nil && bytes.Equal(baseContent, manual) {
+			continue
+		}
+
+		strategy := lc.ConflictStrategy(file)
+		switch strategy {
+		case config.ConflictPreferManual:
+			if err := restoreFileAtHead(repo, change); err != nil {
+				return nil, fmt.Errorf("failed to restore %s: %w", file, err)
+			}
+		case config.ConflictFail:
+			unresolved = append(unresolved, file)
+			continue
+		case config.ConflictThreeWayMerge:
+			if err := mergeRegeneratedFile(repo, change, baseContent, manual); err != nil {
+				if !errors.Is(err, gitrepo.ErrMergeConflict) {
+					return nil, fmt.Errorf("failed to merge %s: %w", file, err)
+				}
+				unresolved = append(unresolved, fmt.Sprintf("%s (%v)", file, err))
+				continue
+			}
+		}
+		slog.Info("Resolved conflict between regeneration and manual edits", "path", file, "strategy", strategy)
+		conflicts = append(conflicts, &regenerationConflict{path: file, strategy: strategy})
+	}
+	if len(unresolved) > 0 {
+		return nil, failure.New(failure.GitConflict,
+			fmt.Errorf("regeneration conflicts with manual edits of: %s", strings.Join(unresolved, ", ")))
+	}
+	return conflicts, nil
+}
+
+// mergeRegeneratedFile merges the manual edits of a file since base into the
+// content written by regeneration, in the working tree.
+func mergeRegeneratedFile(repo gitrepo.Repository, change *changedFile, base, manual []byte) error {
+	if change.Change == fileChangeDeleted {
+		return fmt.Errorf("file deleted by regeneration: %w", gitrepo.ErrMergeConflict)
+	}
+	path := filepath.Join(repo.GetDir(), change.Path)
+	generated, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	merged, err := gitrepo.MergeFile(manual, base, generated)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, string(merged))
+}
+
+// readFileAtCommit returns the content of the file at path in the given
+// commit, or nil if the file does not exist in it.
+func readFileAtCommit(repo gitrepo.Repository, commit, path string) ([]byte, error) {
+	content, err := repo.ReadFileAtCommit(commit, path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return content, err
+}
+
+// stateHistory holds the state.yaml of the language repository in the commits
+// which changed it, newest first.
+type stateHistory struct {
+	head    *config.LibrarianState
+	commits []string
+	states  []*config.LibrarianState
+}
+
+func loadStateHistory(repo gitrepo.Repository) (*stateHistory, error) {
+	statePath := path.Join(config.LibrarianDir, librarianStateFile)
+	history := &stateHistory{}
+	var err error
+	if history.head, err = parseStateAt(repo.ReadFileAtHead, statePath); err != nil || history.head == nil {
+		return history, err
+	}
+	commits, err := repo.GetCommitsForPathsSinceCommit([]string{statePath}, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, commit := range commits {
+		hash := commit.Hash.String()
+		state, err := parseStateAt(func(path string) ([]byte, error) {
+			return repo.ReadFileAtCommit(hash, path)
+		}, statePath)
+		if err != nil {
+			return nil, err
+		}
+		if state == nil {
+			break
+		}
+		history.commits = append(history.commits, hash)
+		history.states = append(history.states, state)
+	}
+	return history, nil
+}
+
+// parseStateAt parses the state.yaml read with readFile, or returns nil if it
+// does not exist.
+func parseStateAt(readFile func(path string) ([]byte, error), path string) (*config.LibrarianState, error) {
+	data, err := readFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := &config.LibrarianState{}
+	if err := yaml.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("unmarshaling librarian state: %w", err)
+	}
+	return state, nil
+}
+
+// lastGenerationCommit returns the oldest commit of the most recent run of
+// commits in which the library with the given ID has the
+// last_generated_commit it has at HEAD, or an empty string if it is unknown.
+func (h *stateHistory) lastGenerationCommit(id string) string {
+	if h.head == nil {
+		return ""
+	}
+	library := h.head.LibraryByID(id)
+	if library == nil || library.LastGeneratedCommit == "" {
+		return ""
+	}
+	var base string
+	for i, state := range h.states {
+		l := state.LibraryByID(id)
+		if l == nil || l.LastGeneratedCommit != library.LastGeneratedCommit {
+			break
+		}
+		base = h.commits[i]
+	}
+	return base
+}
@@ -0,0 +1,168 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// newConflictTestRepo creates a repository in which library "a" was
+// generated, after which "a/edited.go" was edited manually. The working tree
+// holds the changes of a regeneration to "a/edited.go" and "a/untouched.go".
+func newConflictTestRepo(t *testing.T, regenerated string) gitrepo.Repository {
+	t.Helper()
+	dir := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, dir, "init")
+	runGit(t, dir, "config", "user.email", "test@example.com")
+	runGit(t, dir, "config", "user.name", "Test User")
+	write("README.md", "test")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "initial commit")
+
+	write(".librarian/state.yaml", "image: gen:v1\nlibraries:\n  - id: a\n    last_generated_commit: abc123\n    source_roots: [a]\n")
+	write("a/edited.go", "1\n2\n3\n4\n5\n")
+	write("a/untouched.go", "1\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "chore: generate a")
+
+	write("a/edited.go", "one\n2\n3\n4\n5\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "fix: edit a manually")
+
+	write("a/edited.go", regenerated)
+	write("a/untouched.go", "2\n")
+	repo, err := gitrepo.NewRepository(&gitrepo.RepositoryOptions{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestResolveRegenerationConflicts(t *testing.T) {
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{{ID: "a", SourceRoots: []string{"a"}}},
+	}
+	for _, test := range []struct {
+		name          string
+		strategy      string
+		regenerated   string
+		want          string
+		wantConflicts []*regenerationConflict
+		wantErr       bool
+	}{
+		{
+			name:          "prefer generated",
+			strategy:      config.ConflictPreferGenerated,
+			regenerated:   "1\n2\n3\n4\nfive\n",
+			want:          "1\n2\n3\n4\nfive\n",
+			wantConflicts: []*regenerationConflict{{path: "a/edited.go", strategy: config.ConflictPreferGenerated}},
+		},
+		{
+			name:          "prefer manual",
+			strategy:      config.ConflictPreferManual,
+			regenerated:   "1\n2\n3\n4\nfive\n",
+			want:          "one\n2\n3\n4\n5\n",
+			wantConflicts: []*regenerationConflict{{path: "a/edited.go", strategy: config.ConflictPreferManual}},
+		},
+		{
+			name:          "three-way merge",
+			strategy:      config.ConflictThreeWayMerge,
+			regenerated:   "1\n2\n3\n4\nfive\n",
+			want:          "one\n2\n3\n4\nfive\n",
+			wantConflicts: []*regenerationConflict{{path: "a/edited.go", strategy: config.ConflictThreeWayMerge}},
+		},
+		{
+			name:        "three-way merge with overlapping changes",
+			strategy:    config.ConflictThreeWayMerge,
+			regenerated: "uno\n2\n3\n4\n5\n",
+			wantErr:     true,
+		},
+		{
+			name:        "fail",
+			strategy:    config.ConflictFail,
+			regenerated: "1\n2\n3\n4\nfive\n",
+			wantErr:     true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repo := newConflictTestRepo(t, test.regenerated)
+			lc := &config.LibrarianConfig{
+				ConflictResolution: []*config.ConflictResolution{{Path: "^a/", Strategy: test.strategy}},
+			}
+			got, err := resolveRegenerationConflicts(lc, repo, state, []string{"a"}, false)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("resolveRegenerationConflicts() should fail")
+				}
+				if !strings.Contains(err.Error(), "a/edited.go") {
+					t.Errorf("resolveRegenerationConflicts() error = %v, want it to name a/edited.go", err)
+				}
+				if got := failure.CategoryOf(err); got != failure.GitConflict {
+					t.Errorf("CategoryOf() = %q, want %q", got, failure.GitConflict)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.wantConflicts, got, cmp.AllowUnexported(regenerationConflict{})); diff != "" {
+				t.Errorf("resolveRegenerationConflicts() mismatch (-want +got):\n%s", diff)
+			}
+			content, err := os.ReadFile(filepath.Join(repo.GetDir(), "a/edited.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(content)); diff != "" {
+				t.Errorf("a/edited.go mismatch (-want +got):\n%s", diff)
+			}
+			untouched, err := os.ReadFile(filepath.Join(repo.GetDir(), "a/untouched.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(untouched) != "2\n" {
+				t.Errorf("a/untouched.go = %q, want the generated content", untouched)
+			}
+		})
+	}
+}
+
+func TestResolveRegenerationConflicts_NoRules(t *testing.T) {
+	repo := &MockRepository{StatusError: os.ErrPermission}
+	got, err := resolveRegenerationConflicts(&config.LibrarianConfig{}, repo, &config.LibrarianState{}, []string{"a"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("resolveRegenerationConflicts() = %v, want nil", got)
+	}
+}
@@ -28,7 +28,14 @@ var cmdRelease = &cli.Command{
 func init() {
 	cmdRelease.Init()
 	cmdRelease.Commands = append(cmdRelease.Commands,
+		cmdApproveRelease,
+		cmdHandleComment,
 		cmdInit,
+		cmdPlanRelease,
+		cmdPromoteRelease,
+		cmdReconcileMirrors,
+		cmdRefreshReleasePR,
+		cmdSignArtifacts,
 		cmdTagAndRelease,
 	)
 }
@@ -0,0 +1,359 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/github"
+	"gopkg.in/yaml.v3"
+)
+
+const approveReleaseCmdName = "approve-release"
+
+// The markers surrounding the release approval in a comment on a release pull
+// request. Like the release status, it is in an HTML comment, so that it is
+// not rendered.
+const (
+	releaseApprovalBegin = "<!-- BEGIN LIBRARIAN RELEASE APPROVAL"
+	releaseApprovalEnd   = "END LIBRARIAN RELEASE APPROVAL -->"
+)
+
+// releaseApprovalNote is added to the body of the release pull requests when
+// the release policy requires approval.
+const releaseApprovalNote = "This release is pending approval. It is tagged once an approver of the release runs `librarian release approve-release` on this pull request.\n"
+
+// cmdApproveRelease is the command for the `release approve-release`
+// subcommand.
+var cmdApproveRelease = &cli.Command{
+	Short:     "approve-release signs off the release of a release pull request.",
+	UsageLine: "librarian release approve-release [arguments]",
+	Long: `Signs off the release of a release pull request, which tag-and-release requires
+before tagging when "require_approval" is set in the "release_policy" of
+".librarian/config.yaml".
+
+The release pull request is the open pull request whose body contains the
+marker written by "librarian release init", or the one specified with -pr,
+which may also be merged. The approval is a comment on the pull request, which
+records the libraries and versions of the release. A release changed after its
+approval, e.g. by "librarian release refresh-release-pr", must be approved
+again.
+
+The owner of the GitHub token approves the release. They must be listed in the
+"approvers" of the release policy, directly or through a team, or in the
+"owners" of every library of the release in ".librarian/state.yaml".
+tag-and-release checks the author of the approval again, so that a comment
+written by anyone else is ignored.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newApproveReleaseRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return runner.run(ctx)
+	},
+}
+
+func init() {
+	cmdApproveRelease.Init()
+	fs := cmdApproveRelease.Flags
+	cfg := cmdApproveRelease.Config
+
+	addFlagAuditLog(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagPR(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+type approveReleaseRunner struct {
+	cfg             *config.Config
+	state           *config.LibrarianState
+	librarianConfig *config.LibrarianConfig
+	ghClient        GitHubClient
+}
+
+func newApproveReleaseRunner(cfg *config.Config) (*approveReleaseRunner, error) {
+	runner, err := newCommandRunner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &approveReleaseRunner{
+		cfg:             runner.cfg,
+		state:           runner.state,
+		librarianConfig: runner.librarianConfig,
+		ghClient:        runner.ghClient,
+	}, nil
+}
+
+func (r *approveReleaseRunner) run(ctx context.Context) error {
+	if err := checkGitHubPermissions(ctx, r.cfg, r.ghClient); err != nil {
+		return err
+	}
+	pr, err := r.findPullRequest(ctx)
+	if err != nil {
+		return err
+	}
+	if pr == nil {
+		return failure.New(failure.UserConfig, errors.New("no open release pull request to approve, specify one with -pr"))
+	}
+	releases := parsePullRequestBody(pr.GetBody())
+	if len(releases) == 0 {
+		return failure.New(failure.UserConfig, fmt.Errorf("no release details found in pull request %d", pr.GetNumber()))
+	}
+	approver, err := r.ghClient.GetAuthenticatedUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the user of the GitHub token: %w", err)
+	}
+	if err := checkApprover(ctx, r.ghClient, r.librarianConfig, r.state, approver, releases); err != nil {
+		return err
+	}
+	releaseID := ""
+	if status, err := parseReleaseStatus(pr.GetBody()); err == nil && status != nil {
+		releaseID = status.ReleaseID
+	}
+	comment, err := newReleaseApproval(releaseID, releases).format(approver)
+	if err != nil {
+		return err
+	}
+	if err := r.ghClient.CreateIssueComment(ctx, pr.GetNumber(), comment); err != nil {
+		return fmt.Errorf("failed to comment the approval on pull request %d: %w", pr.GetNumber(), err)
+	}
+	auditLogFromContext(ctx).record(auditReleaseApproved, map[string]string{
+		"number":     fmt.Sprint(pr.GetNumber()),
+		"release_id": releaseID,
+		"approver":   approver,
+	})
+	slog.Info("Approved release", "pr", pr.GetNumber(), "approver", approver, "libraries", len(releases))
+	return nil
+}
+
+// findPullRequest returns the release pull request to approve: the one
+// specified with -pr, which is open or merged, or else the only open release
+// pull request, or nil if there is none.
+func (r *approveReleaseRunner) findPullRequest(ctx context.Context) (*github.PullRequest, error) {
+	if r.cfg.PullRequest == "" {
+		return findReleasePullRequest(ctx, r.cfg, r.ghClient)
+	}
+	number, err := parsePullRequestNumber(r.cfg.PullRequest)
+	if err != nil {
+		return nil, failure.New(failure.UserConfig, err)
+	}
+	pr, err := r.ghClient.GetPullRequest(ctx, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull request %d: %w", number, err)
+	}
+	if pr.GetState() != "open" && !pr.GetMerged() {
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("pull request %d is closed without being merged", number))
+	}
+	return pr, nil
+}
+
+// approvalNote returns releaseApprovalNote if the release policy requires
+// approval, and "" otherwise.
+func approvalNote(librarianConfig *config.LibrarianConfig) string {
+	if librarianConfig == nil || !librarianConfig.ReleasePolicy.RequiresApproval() {
+		return ""
+	}
+	return releaseApprovalNote
+}
+
+// releaseApproval is the sign-off of a release, recorded in a comment on its
+// release pull request.
+type releaseApproval struct {
+	ReleaseID string             `yaml:"release_id,omitempty"`
+	Libraries []*approvedLibrary `yaml:"libraries"`
+}
+
+// approvedLibrary is a library of an approved release.
+type approvedLibrary struct {
+	ID      string `yaml:"id"`
+	Version string `yaml:"version"`
+}
+
+// newReleaseApproval returns the approval of the given releases.
+func newReleaseApproval(releaseID string, releases []libraryRelease) *releaseApproval {
+	approval := &releaseApproval{ReleaseID: releaseID}
+	for _, release := range releases {
+		approval.Libraries = append(approval.Libraries, &approvedLibrary{ID: release.Library, Version: release.Version})
+	}
+	return approval
+}
+
+// format returns the comment which records the approval by approver.
+func (a *releaseApproval) format(approver string) (string, error) {
+	data, err := yaml.Marshal(a)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal release approval: %w", err)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s%s\n", releaseApprovalBegin, data, releaseApprovalEnd)
+	fmt.Fprintf(&b, "Release approved by @%s:\n", approver)
+	for _, library := range a.Libraries {
+		fmt.Fprintf(&b, "- %s %s\n", library.ID, library.Version)
+	}
+	return b.String(), nil
+}
+
+// parseReleaseApproval returns the release approval in the body of a
+// comment, or nil if the body does not contain any.
+func parseReleaseApproval(body string) (*releaseApproval, error) {
+	_, rest, found := strings.Cut(body, releaseApprovalBegin)
+	if !found {
+		return nil, nil
+	}
+	data, _, found := strings.Cut(rest, releaseApprovalEnd)
+	if !found {
+		return nil, fmt.Errorf("release approval is not terminated by %q", releaseApprovalEnd)
+	}
+	approval := &releaseApproval{}
+	if err := yaml.Unmarshal([]byte(data), approval); err != nil {
+		return nil, fmt.Errorf("failed to parse release approval: %w", err)
+	}
+	return approval, nil
+}
+
+// covers reports whether the approval is for exactly the given releases.
+func (a *releaseApproval) covers(releases []libraryRelease) bool {
+	if len(a.Libraries) != len(releases) {
+		return false
+	}
+	for _, release := range releases {
+		if !slices.ContainsFunc(a.Libraries, func(library *approvedLibrary) bool {
+			return library.ID == release.Library && library.Version == release.Version
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkReleaseApproved returns an error unless a comment on the pull request
+// number approves exactly the given releases, and was written by an approver
+// of the release, see checkApprover.
+func checkReleaseApproved(ctx context.Context, ghClient GitHubClient, librarianConfig *config.LibrarianConfig, state *config.LibrarianState, number int, releases []libraryRelease) error {
+	comments, err := ghClient.ListIssueComments(ctx, number)
+	if err != nil {
+		return fmt.Errorf("failed to list comments of pull request %d: %w", number, err)
+	}
+	for _, comment := range slices.Backward(comments) {
+		approval, err := parseReleaseApproval(comment.GetBody())
+		if err != nil {
+			slog.Warn("ignoring invalid release approval", "pr", number, "comment", comment.GetID(), "error", err)
+			continue
+		}
+		if approval == nil || !approval.covers(releases) {
+			continue
+		}
+		author := comment.GetUser().GetLogin()
+		if err := checkApprover(ctx, ghClient, librarianConfig, state, author, releases); err != nil {
+			slog.Warn("ignoring release approval", "pr", number, "comment", comment.GetID(), "error", err)
+			continue
+		}
+		slog.Info("Release is approved", "pr", number, "approver", author)
+		return nil
+	}
+	return failure.New(failure.UserConfig, fmt.Errorf("the release of pull request %d is not approved, run `librarian release %s -pr=<url>`", number, approveReleaseCmdName))
+}
+
+// checkApprover returns an error unless user may approve the given releases:
+// they are one of the approvers of the release policy, or an owner of every
+// released library. Approvers and owners are users, e.g. "@octocat", or teams,
+// e.g. "@googleapis/release-team".
+func checkApprover(ctx context.Context, ghClient GitHubClient, librarianConfig *config.LibrarianConfig, state *config.LibrarianState, user string, releases []libraryRelease) error {
+	ok, err := isListedUser(ctx, ghClient, releaseApprovers(librarianConfig), user)
+	if err != nil || ok {
+		return err
+	}
+	var libraryIDs []string
+	for _, release := range releases {
+		libraryIDs = append(libraryIDs, release.Library)
+	}
+	unowned, err := unownedLibraries(ctx, ghClient, state, user, libraryIDs)
+	if err != nil {
+		return err
+	}
+	if len(unowned) > 0 {
+		return failure.New(failure.UserConfig, fmt.Errorf("%s may not approve the release: not a release approver, nor an owner of %s", user, strings.Join(unowned, ", ")))
+	}
+	return nil
+}
+
+// releaseApprovers returns the approvers of the release policy, if any.
+func releaseApprovers(librarianConfig *config.LibrarianConfig) []string {
+	if librarianConfig == nil || librarianConfig.ReleasePolicy == nil {
+		return nil
+	}
+	return librarianConfig.ReleasePolicy.Approvers
+}
+
+// unownedLibraries returns the IDs of the libraries in libraryIDs which user
+// does not own, including those not found in state.
+func unownedLibraries(ctx context.Context, ghClient GitHubClient, state *config.LibrarianState, user string, libraryIDs []string) ([]string, error) {
+	var unowned []string
+	for _, id := range libraryIDs {
+		library := state.LibraryByID(id)
+		if library == nil {
+			unowned = append(unowned, id)
+			continue
+		}
+		ok, err := isListedUser(ctx, ghClient, library.Owners, user)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			unowned = append(unowned, id)
+		}
+	}
+	return unowned, nil
+}
+
+// isListedUser reports whether user is one of the listed users, or a member
+// of one of the listed teams.
+func isListedUser(ctx context.Context, ghClient GitHubClient, list []string, user string) (bool, error) {
+	for _, entry := range list {
+		name := strings.TrimPrefix(entry, "@")
+		org, team, isTeam := strings.Cut(name, "/")
+		if !isTeam {
+			if strings.EqualFold(name, user) {
+				return true, nil
+			}
+			continue
+		}
+		member, err := ghClient.IsTeamMember(ctx, org, team, user)
+		if err != nil {
+			return false, fmt.Errorf("failed to check membership of team %s: %w", entry, err)
+		}
+		if member {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	gh "github.com/google/go-github/v69/github"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/github"
+)
+
+func TestReleaseApproval_FormatAndParse(t *testing.T) {
+	releases := []libraryRelease{
+		{Library: "a", Version: "1.2.0"},
+		{Library: "b", Version: "2.0.0"},
+	}
+	comment, err := newReleaseApproval("release-1", releases).format("octocat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(comment, "Release approved by @octocat") {
+		t.Errorf("comment %q does not name the approver", comment)
+	}
+	got, err := parseReleaseApproval("LGTM\n" + comment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &releaseApproval{
+		ReleaseID: "release-1",
+		Libraries: []*approvedLibrary{{ID: "a", Version: "1.2.0"}, {ID: "b", Version: "2.0.0"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parseReleaseApproval() mismatch (-want +got):\n%s", diff)
+	}
+	if !got.covers([]libraryRelease{releases[1], releases[0]}) {
+		t.Errorf("approval does not cover its releases")
+	}
+	if got.covers([]libraryRelease{releases[0], {Library: "b", Version: "2.0.1"}}) {
+		t.Errorf("approval covers a changed version")
+	}
+	if got.covers(releases[:1]) {
+		t.Errorf("approval covers a different set of libraries")
+	}
+
+	if approval, err := parseReleaseApproval("LGTM"); err != nil || approval != nil {
+		t.Errorf("parseReleaseApproval() = %v, %v, want nil, nil", approval, err)
+	}
+	if _, err := parseReleaseApproval(releaseApprovalBegin + "\nlibraries: []\n"); err == nil {
+		t.Errorf("parseReleaseApproval() of an unterminated approval succeeded")
+	}
+}
+
+func TestCheckApprover(t *testing.T) {
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "a", Owners: []string{"@alice"}},
+			{ID: "b", Owners: []string{"@alice", "@googleapis/b-team"}},
+		},
+	}
+	librarianConfig := &config.LibrarianConfig{
+		ReleasePolicy: &config.ReleasePolicy{
+			Approvers:       []string{"@releaser", "@googleapis/release-team"},
+			RequireApproval: true,
+		},
+	}
+	ghClient := &mockGitHubClient{
+		teamMembers: map[string][]string{
+			"googleapis/release-team": {"carol"},
+			"googleapis/b-team":       {"bob"},
+		},
+	}
+	both := []libraryRelease{{Library: "a", Version: "1.0.0"}, {Library: "b", Version: "1.0.0"}}
+	for _, test := range []struct {
+		name     string
+		user     string
+		releases []libraryRelease
+		wantErr  bool
+	}{
+		{name: "approver", user: "releaser", releases: both},
+		{name: "approver team member", user: "carol", releases: both},
+		{name: "owner of every library", user: "alice", releases: both},
+		{name: "owner through a team", user: "bob", releases: both[1:]},
+		{name: "owner of some libraries", user: "bob", releases: both, wantErr: true},
+		{name: "unknown library", user: "alice", releases: []libraryRelease{{Library: "c", Version: "1.0.0"}}, wantErr: true},
+		{name: "stranger", user: "mallory", releases: both, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkApprover(context.Background(), ghClient, librarianConfig, state, test.user, test.releases)
+			if test.wantErr {
+				if failure.CategoryOf(err) != failure.UserConfig {
+					t.Errorf("checkApprover() error = %v, want a %s error", err, failure.UserConfig)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("checkApprover() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestApproveReleaseRunner(t *testing.T) {
+	body := releasePullRequestMarker + "\n<details><summary>a: 1.2.0</summary>notes</details>\n" +
+		releaseStatusBegin + "\nrelease_id: release-1\nlibraries:\n  - id: a\n    version: 1.2.0\n    status: pr-opened\n" + releaseStatusEnd + "\n"
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{{ID: "a", Owners: []string{"@alice"}}},
+	}
+	for _, test := range []struct {
+		name    string
+		user    string
+		wantErr bool
+	}{
+		{name: "owner", user: "alice"},
+		{name: "stranger", user: "mallory", wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ghClient := &mockGitHubClient{
+				authenticatedUser: test.user,
+				pullRequest:       &github.PullRequest{Number: gh.Ptr(7), State: gh.Ptr("open"), Body: &body},
+			}
+			r := &approveReleaseRunner{
+				cfg:      &config.Config{CommandName: approveReleaseCmdName, PullRequest: "github.com/googleapis/repo/pulls/7"},
+				state:    state,
+				ghClient: ghClient,
+			}
+			err := r.run(context.Background())
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("run() succeeded, want an error")
+				}
+				if ghClient.createIssueCommentCalls != 0 {
+					t.Errorf("an unauthorized user commented an approval")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			approval, err := parseReleaseApproval(ghClient.issueComment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := &releaseApproval{ReleaseID: "release-1", Libraries: []*approvedLibrary{{ID: "a", Version: "1.2.0"}}}
+			if diff := cmp.Diff(want, approval); diff != "" {
+				t.Errorf("approval mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProcessPullRequest_RequiresApproval(t *testing.T) {
+	body := "<details><summary>a: 1.2.0</summary>notes</details>"
+	pr := &github.PullRequest{
+		Body:           &body,
+		Number:         gh.Ptr(123),
+		MergeCommitSHA: gh.Ptr("abcdef"),
+		Labels:         []*gh.Label{{Name: gh.Ptr(releasePendingLabel)}},
+	}
+	approval := func(version string) string {
+		comment, err := newReleaseApproval("", []libraryRelease{{Library: "a", Version: version}}).format("alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return comment
+	}
+	comment := func(author, body string) *github.IssueComment {
+		return &github.IssueComment{Body: gh.Ptr(body), User: &gh.User{Login: gh.Ptr(author)}}
+	}
+	for _, test := range []struct {
+		name     string
+		comments []*github.IssueComment
+		wantErr  bool
+	}{
+		{name: "no approval", comments: []*github.IssueComment{comment("alice", "LGTM")}, wantErr: true},
+		{name: "approved", comments: []*github.IssueComment{comment("alice", approval("1.2.0"))}},
+		{name: "approval of another version", comments: []*github.IssueComment{comment("alice", approval("1.1.0"))}, wantErr: true},
+		{name: "approval by a stranger", comments: []*github.IssueComment{comment("mallory", approval("1.2.0"))}, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ghClient := &mockGitHubClient{issueComments: test.comments}
+			r := &tagAndReleaseRunner{
+				cfg:      &config.Config{},
+				ghClient: ghClient,
+				state: &config.LibrarianState{
+					Libraries: []*config.LibraryState{{ID: "a", Version: "1.2.0", Owners: []string{"@alice"}}},
+				},
+				librarianConfig: &config.LibrarianConfig{
+					ReleasePolicy: &config.ReleasePolicy{RequireApproval: true},
+				},
+			}
+			err := r.processPullRequest(context.Background(), pr)
+			if test.wantErr {
+				if failure.CategoryOf(err) != failure.UserConfig {
+					t.Errorf("processPullRequest() error = %v, want a %s error", err, failure.UserConfig)
+				}
+				if ghClient.createReleaseCalls != 0 || ghClient.replaceLabelsCalls != 0 {
+					t.Errorf("an unapproved release was tagged")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ghClient.createReleaseCalls != 1 {
+				t.Errorf("createReleaseCalls = %d, want 1", ghClient.createReleaseCalls)
+			}
+		})
+	}
+}
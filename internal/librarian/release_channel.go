@@ -0,0 +1,227 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/conventionalcommits"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// updateCanaryLibraries updates libraries, a single library or the members of
+// a release group, for a canary release. If any of them has changes since its
+// last canary release, or since its last stable release if that is newer, all
+// of them are released with the next canary version, see nextCanaryVersion,
+// or with libraryVersion if it is not empty.
+//
+// The canary version is set as both the Version and the CanaryVersion of the
+// libraries, so that the container and the release pull request use it. The
+// stable version is restored in the state by saveChannelVersions.
+func updateCanaryLibraries(repo gitrepo.Repository, librarianConfig *config.LibrarianConfig, libraries []*config.LibraryState, libraryVersion string) error {
+	highestChange := semver.None
+	hasChanges := false
+	for _, library := range libraries {
+		commits, err := GetConventionalCommitsSinceLastRelease(repo, library)
+		if err != nil {
+			return fmt.Errorf("failed to fetch conventional commits for library, %s: %w", library.ID, err)
+		}
+		highestChange = max(highestChange, getHighestChange(commits))
+		if commits, err = commitsSinceCanaryRelease(repo, librarianConfig, library, commits); err != nil {
+			return err
+		}
+		library.Changes = coerceLibraryChanges(commits)
+		hasChanges = hasChanges || len(library.Changes) > 0
+	}
+	if !hasChanges {
+		slog.Info("Skip canary release since no eligible change is found", "library", libraries[0].ID)
+		return nil
+	}
+	for _, library := range libraries {
+		nextVersion := libraryVersion
+		if nextVersion == "" {
+			var err error
+			nextVersion, err = nextCanaryVersion(library.Version, highestChange, library.CanaryVersion, librarianConfig.CanaryPrerelease())
+			if err != nil {
+				return fmt.Errorf("failed to derive canary version of library %s: %w", library.ID, err)
+			}
+		}
+		library.Version = nextVersion
+		library.CanaryVersion = nextVersion
+		library.ReleaseTriggered = true
+	}
+	return nil
+}
+
+// commitsSinceCanaryRelease returns the commits of library since its last
+// canary release, if it is newer than its last stable release. Otherwise, it
+// returns commits, the commits since the last stable release.
+func commitsSinceCanaryRelease(repo gitrepo.Repository, librarianConfig *config.LibrarianConfig, library *config.LibraryState, commits []*conventionalcommits.ConventionalCommit) ([]*conventionalcommits.ConventionalCommit, error) {
+	if library.CanaryVersion == "" {
+		return commits, nil
+	}
+	stable, err := semver.Parse(library.Version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version of library %s: %w", library.ID, err)
+	}
+	canary, err := semver.Parse(library.CanaryVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid canary version of library %s: %w", library.ID, err)
+	}
+	if canary.Compare(stable) <= 0 {
+		return commits, nil
+	}
+	tag := formatCanaryTag(librarianConfig, library, library.CanaryVersion)
+	since, err := repo.GetCommitsForPathsSinceTag(library.SourceRoots, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits for library %s: %w", library.ID, err)
+	}
+	return convertToConventionalCommits(repo, library, since)
+}
+
+// nextCanaryVersion returns the canary version which follows canary, the last
+// canary version, for the given highest change since stable, the last stable
+// version.
+//
+// The canary versions are prereleases of the next stable version, e.g.
+// 1.5.0-beta.1 after 1.4.2 for a new feature. The prerelease number is
+// incremented while the next stable version stays the same, e.g. 1.5.0-beta.2
+// after 1.5.0-beta.1, and a canary version is never lower than the last one.
+func nextCanaryVersion(stable string, highestChange semver.ChangeLevel, canary, prerelease string) (string, error) {
+	// Any change makes a new canary release, which is at least a patch.
+	target, err := semver.DeriveNext(max(highestChange, semver.Patch), stable)
+	if err != nil {
+		return "", err
+	}
+	next, err := semver.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	number := 1
+	if canary != "" {
+		last, err := semver.Parse(canary)
+		if err != nil {
+			return "", err
+		}
+		lastBase := &semver.Version{Major: last.Major, Minor: last.Minor, Patch: last.Patch}
+		switch lastBase.Compare(next) {
+		case 1:
+			next = lastBase
+			fallthrough
+		case 0:
+			if last.Prerelease == prerelease && last.PrereleaseNumber != "" {
+				n, err := strconv.Atoi(last.PrereleaseNumber)
+				if err != nil {
+					return "", err
+				}
+				number = n + 1
+			}
+		}
+	}
+	return fmt.Sprintf("%s-%s.%d", next, prerelease, number), nil
+}
+
+// promoteLibrary updates library to release its canary version as a stable
+// version, e.g. 1.5.0 for 1.5.0-beta.3. The release includes all the changes
+// since the last stable release. Libraries without a canary release since
+// their last stable release are skipped.
+func promoteLibrary(repo gitrepo.Repository, library *config.LibraryState) error {
+	if library.CanaryVersion == "" {
+		slog.Info("Skip promoting library without a canary release", "library", library.ID)
+		return nil
+	}
+	canary, err := semver.Parse(library.CanaryVersion)
+	if err != nil {
+		return fmt.Errorf("invalid canary version of library %s: %w", library.ID, err)
+	}
+	stable, err := semver.Parse(library.Version)
+	if err != nil {
+		return fmt.Errorf("invalid version of library %s: %w", library.ID, err)
+	}
+	promoted := &semver.Version{Major: canary.Major, Minor: canary.Minor, Patch: canary.Patch}
+	if promoted.Compare(stable) <= 0 {
+		slog.Info("Skip promoting library since its canary release is older than its stable release", "library", library.ID, "canary_version", library.CanaryVersion)
+		return nil
+	}
+	commits, err := GetConventionalCommitsSinceLastRelease(repo, library)
+	if err != nil {
+		return fmt.Errorf("failed to fetch conventional commits for library, %s: %w", library.ID, err)
+	}
+	library.Changes = coerceLibraryChanges(commits)
+	library.Version = promoted.String()
+	library.CanaryVersion = ""
+	library.ReleaseTriggered = true
+	library.PreviousReleaseTag = ""
+	return nil
+}
+
+// formatCanaryTag returns the git tag of the canary release of library with
+// the given version.
+func formatCanaryTag(librarianConfig *config.LibrarianConfig, library *config.LibraryState, version string) string {
+	r := strings.NewReplacer("{id}", library.ID, "{version}", version)
+	return r.Replace(librarianConfig.CanaryTagFormat())
+}
+
+// isCanaryRelease reports whether version of library is released on the
+// canary channel: it is the canary version of the library in the state, or a
+// prerelease with the canary prerelease identifier.
+func isCanaryRelease(librarianConfig *config.LibrarianConfig, library *config.LibraryState, version string) bool {
+	if version == library.CanaryVersion {
+		return true
+	}
+	v, err := semver.Parse(strings.TrimPrefix(version, "v"))
+	return err == nil && v.Prerelease == librarianConfig.CanaryPrerelease()
+}
+
+// saveChannelVersions writes the versions of the libraries released on the
+// canary channel, or promoted from it, to the state.yaml of the repository.
+// A canary release sets the canary_version of the libraries, and keeps their
+// stable version from stableVersions. A promotion sets the version of the
+// libraries, and clears their canary_version.
+func saveChannelVersions(ctx context.Context, repoDir string, released *config.LibrarianState, stableVersions map[string]string, promote bool) error {
+	path := filepath.Join(repoDir, config.LibrarianDir, librarianStateFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	state := &config.LibrarianState{}
+	if err := yaml.Unmarshal(data, state); err != nil {
+		return fmt.Errorf("unmarshaling librarian state: %w", err)
+	}
+	for _, library := range released.Libraries {
+		saved := state.LibraryByID(library.ID)
+		if !library.ReleaseTriggered || saved == nil {
+			continue
+		}
+		if promote {
+			saved.Version = library.Version
+			saved.CanaryVersion = ""
+			saved.PreviousReleaseTag = ""
+			continue
+		}
+		saved.Version = stableVersions[library.ID]
+		saved.CanaryVersion = library.CanaryVersion
+	}
+	return saveLibrarianState(ctx, repoDir, state)
+}
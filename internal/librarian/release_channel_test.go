@@ -0,0 +1,301 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/semver"
+	"gopkg.in/yaml.v3"
+)
+
+func TestNextCanaryVersion(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name          string
+		stable        string
+		highestChange semver.ChangeLevel
+		canary        string
+		want          string
+	}{
+		{
+			name:          "first canary",
+			stable:        "1.4.2",
+			highestChange: semver.Minor,
+			want:          "1.5.0-beta.1",
+		},
+		{
+			name:          "no version bump",
+			stable:        "1.4.2",
+			highestChange: semver.None,
+			want:          "1.4.3-beta.1",
+		},
+		{
+			name:          "next canary",
+			stable:        "1.4.2",
+			highestChange: semver.Minor,
+			canary:        "1.5.0-beta.2",
+			want:          "1.5.0-beta.3",
+		},
+		{
+			name:          "never lower than the last canary",
+			stable:        "1.4.2",
+			highestChange: semver.Patch,
+			canary:        "1.5.0-beta.2",
+			want:          "1.5.0-beta.3",
+		},
+		{
+			name:          "breaking change after canary",
+			stable:        "1.4.2",
+			highestChange: semver.Major,
+			canary:        "1.5.0-beta.2",
+			want:          "2.0.0-beta.1",
+		},
+		{
+			name:          "canary older than stable",
+			stable:        "1.5.0",
+			highestChange: semver.Patch,
+			canary:        "1.5.0-beta.2",
+			want:          "1.5.1-beta.1",
+		},
+		{
+			name:          "other prerelease",
+			stable:        "1.4.2",
+			highestChange: semver.Minor,
+			canary:        "1.5.0-alpha.4",
+			want:          "1.5.0-beta.1",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := nextCanaryVersion(test.stable, test.highestChange, test.canary, "beta")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("nextCanaryVersion() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUpdateCanaryLibraries(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name           string
+		canaryVersion  string
+		commitsByTag   map[string][]*gitrepo.Commit
+		libraryVersion string
+		want           string
+		wantChanges    int
+	}{
+		{
+			name: "first canary",
+			commitsByTag: map[string][]*gitrepo.Commit{
+				"a-1.4.2": {{Message: "feat: add a feature"}, {Message: "fix: fix a bug"}},
+			},
+			want:        "1.5.0-beta.1",
+			wantChanges: 2,
+		},
+		{
+			name:          "changes since the last canary",
+			canaryVersion: "1.5.0-beta.1",
+			commitsByTag: map[string][]*gitrepo.Commit{
+				"a-1.4.2":               {{Message: "feat: add a feature"}, {Message: "fix: fix a bug"}},
+				"a-canary-1.5.0-beta.1": {{Message: "fix: fix a bug"}},
+			},
+			want:        "1.5.0-beta.2",
+			wantChanges: 1,
+		},
+		{
+			name:          "no changes since the last canary",
+			canaryVersion: "1.5.0-beta.1",
+			commitsByTag: map[string][]*gitrepo.Commit{
+				"a-1.4.2": {{Message: "feat: add a feature"}},
+			},
+			want: "1.4.2",
+		},
+		{
+			name: "override",
+			commitsByTag: map[string][]*gitrepo.Commit{
+				"a-1.4.2": {{Message: "fix: fix a bug"}},
+			},
+			libraryVersion: "2.0.0-rc.1",
+			want:           "2.0.0-rc.1",
+			wantChanges:    1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			library := &config.LibraryState{ID: "a", Version: "1.4.2", CanaryVersion: test.canaryVersion, SourceRoots: []string{"a"}}
+			repo := &MockRepository{
+				GetCommitsForPathsSinceTagValueByTag: test.commitsByTag,
+				ChangedFilesInCommitValue:            []string{"a/a.go"},
+			}
+			if err := updateCanaryLibraries(repo, nil, []*config.LibraryState{library}, test.libraryVersion); err != nil {
+				t.Fatal(err)
+			}
+			if library.Version != test.want {
+				t.Errorf("Version = %q, want %q", library.Version, test.want)
+			}
+			if len(library.Changes) != test.wantChanges {
+				t.Errorf("got %d changes, want %d", len(library.Changes), test.wantChanges)
+			}
+			if released := test.wantChanges > 0; library.ReleaseTriggered != released {
+				t.Errorf("ReleaseTriggered = %t, want %t", library.ReleaseTriggered, released)
+			}
+			if library.ReleaseTriggered && library.CanaryVersion != test.want {
+				t.Errorf("CanaryVersion = %q, want %q", library.CanaryVersion, test.want)
+			}
+		})
+	}
+}
+
+func TestPromoteLibrary(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name          string
+		library       *config.LibraryState
+		wantVersion   string
+		wantTriggered bool
+	}{
+		{
+			name:          "promoted",
+			library:       &config.LibraryState{ID: "a", Version: "1.4.2", CanaryVersion: "1.5.0-beta.3", PreviousReleaseTag: "old-1.4.2"},
+			wantVersion:   "1.5.0",
+			wantTriggered: true,
+		},
+		{
+			name:        "no canary",
+			library:     &config.LibraryState{ID: "a", Version: "1.4.2"},
+			wantVersion: "1.4.2",
+		},
+		{
+			name:        "canary older than stable",
+			library:     &config.LibraryState{ID: "a", Version: "1.5.0", CanaryVersion: "1.5.0-beta.3"},
+			wantVersion: "1.5.0",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repo := &MockRepository{
+				GetCommitsForPathsSinceTagValue: []*gitrepo.Commit{{Message: "feat: add a feature"}},
+				ChangedFilesInCommitValue:       []string{"a/a.go"},
+			}
+			if err := promoteLibrary(repo, test.library); err != nil {
+				t.Fatal(err)
+			}
+			if test.library.Version != test.wantVersion {
+				t.Errorf("Version = %q, want %q", test.library.Version, test.wantVersion)
+			}
+			if test.library.ReleaseTriggered != test.wantTriggered {
+				t.Errorf("ReleaseTriggered = %t, want %t", test.library.ReleaseTriggered, test.wantTriggered)
+			}
+			if test.wantTriggered && (test.library.CanaryVersion != "" || test.library.PreviousReleaseTag != "" || len(test.library.Changes) != 1) {
+				t.Errorf("promoteLibrary() = %+v, want no canary version nor previous tag, and 1 change", test.library)
+			}
+		})
+	}
+}
+
+func TestIsCanaryRelease(t *testing.T) {
+	t.Parallel()
+	librarianConfig := &config.LibrarianConfig{Canary: &config.Canary{Prerelease: "canary"}}
+	library := &config.LibraryState{ID: "a", Version: "1.4.2", CanaryVersion: "1.5.0-rc.1"}
+	for _, test := range []struct {
+		version string
+		want    bool
+	}{
+		{version: "1.5.0-rc.1", want: true},
+		{version: "1.6.0-canary.2", want: true},
+		{version: "v1.6.0-canary.2", want: true},
+		{version: "1.6.0-alpha.1"},
+		{version: "1.6.0"},
+	} {
+		if got := isCanaryRelease(librarianConfig, library, test.version); got != test.want {
+			t.Errorf("isCanaryRelease(%q) = %t, want %t", test.version, got, test.want)
+		}
+	}
+}
+
+func TestFormatCanaryTag(t *testing.T) {
+	t.Parallel()
+	library := &config.LibraryState{ID: "a"}
+	if got, want := formatCanaryTag(nil, library, "1.5.0-beta.1"), "a-canary-1.5.0-beta.1"; got != want {
+		t.Errorf("formatCanaryTag() = %q, want %q", got, want)
+	}
+	librarianConfig := &config.LibrarianConfig{Canary: &config.Canary{TagFormat: "canary/{id}/v{version}"}}
+	if got, want := formatCanaryTag(librarianConfig, library, "1.5.0-beta.1"), "canary/a/v1.5.0-beta.1"; got != want {
+		t.Errorf("formatCanaryTag() = %q, want %q", got, want)
+	}
+}
+
+func TestSaveChannelVersions(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name     string
+		released *config.LibraryState
+		promote  bool
+		want     *config.LibraryState
+	}{
+		{
+			name:     "canary",
+			released: &config.LibraryState{ID: "a", Version: "1.5.0-beta.2", CanaryVersion: "1.5.0-beta.2", ReleaseTriggered: true},
+			want:     &config.LibraryState{ID: "a", Version: "1.4.2", CanaryVersion: "1.5.0-beta.2"},
+		},
+		{
+			name:     "promote",
+			released: &config.LibraryState{ID: "a", Version: "1.5.0", ReleaseTriggered: true},
+			promote:  true,
+			want:     &config.LibraryState{ID: "a", Version: "1.5.0"},
+		},
+		{
+			name:     "not released",
+			released: &config.LibraryState{ID: "a", Version: "1.5.0-beta.2", CanaryVersion: "1.5.0-beta.2"},
+			want:     &config.LibraryState{ID: "a", Version: "1.4.2", CanaryVersion: "1.5.0-beta.1"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoDir := t.TempDir()
+			path := filepath.Join(repoDir, config.LibrarianDir, librarianStateFile)
+			if err := writeFile(path, "image: gcr.io/test/image:v1\nlibraries:\n  - id: a\n    version: 1.4.2\n    canary_version: 1.5.0-beta.1\n"); err != nil {
+				t.Fatal(err)
+			}
+			released := &config.LibrarianState{Libraries: []*config.LibraryState{test.released}}
+			if err := saveChannelVersions(context.Background(), repoDir, released, map[string]string{"a": "1.4.2"}, test.promote); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := &config.LibrarianState{}
+			if err := yaml.Unmarshal(data, got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got.Libraries[0], cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("state mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
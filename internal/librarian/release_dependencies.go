@@ -0,0 +1,170 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/semver"
+)
+
+// releaseUnits partitions libraries into the units in which they are
+// released, like groupByReleaseGroup, ordered so that every unit comes after
+// the units of the libraries it depends on.
+func releaseUnits(libraries []*config.LibraryState) ([][]*config.LibraryState, error) {
+	units, err := orderReleaseUnits(groupByReleaseGroup(libraries))
+	if err != nil {
+		return nil, failure.New(failure.UserConfig, err)
+	}
+	return units, nil
+}
+
+// orderReleaseUnits orders units topologically by the dependencies of their
+// libraries, keeping the order of units which do not depend on each other.
+// Dependencies on libraries which are not in any of the units are ignored. It
+// fails if the units depend on each other in a cycle, which the release
+// groups may form even if the libraries do not.
+func orderReleaseUnits(units [][]*config.LibraryState) ([][]*config.LibraryState, error) {
+	unitOf := make(map[string]int)
+	for i, unit := range units {
+		for _, library := range unit {
+			unitOf[library.ID] = i
+		}
+	}
+	ordered := make([][]*config.LibraryState, 0, len(units))
+	done := make([]bool, len(units))
+	ready := func(i int) bool {
+		for _, library := range units[i] {
+			for _, id := range library.DependsOn {
+				if j, ok := unitOf[id]; ok && j != i && !done[j] {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	for len(ordered) < len(units) {
+		next := -1
+		for i := range units {
+			if !done[i] && ready(i) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			var ids []string
+			for i, unit := range units {
+				if !done[i] {
+					ids = append(ids, unit[0].ID)
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle between the releases of libraries %s", strings.Join(ids, ", "))
+		}
+		done[next] = true
+		ordered = append(ordered, units[next])
+	}
+	return ordered, nil
+}
+
+// propagateDependencyReleases releases unit, a single library or the members
+// of a release group, if any of its libraries depends on a library released
+// by the run, so that the language container updates the manifests of the
+// libraries to the new versions of their dependencies. The libraries which
+// are not released already get a patch release, or libraryVersion if it is not
+// empty, and every dependency update is recorded as a change. The units of
+// the dependencies must have been updated before.
+func propagateDependencyReleases(state *config.LibrarianState, unit []*config.LibraryState, libraryVersion string) error {
+	updated := false
+	for _, library := range unit {
+		for _, id := range library.DependsOn {
+			dependency := state.LibraryByID(id)
+			if dependency == nil || !dependency.ReleaseTriggered {
+				continue
+			}
+			library.Changes = append(library.Changes, &config.Change{
+				Type:    "fix",
+				Subject: fmt.Sprintf("update dependency %s to %s", dependency.ID, dependency.Version),
+			})
+			updated = true
+		}
+	}
+	if !updated {
+		return nil
+	}
+	for _, library := range unit {
+		if library.ReleaseTriggered {
+			continue
+		}
+		version := libraryVersion
+		if version == "" {
+			var err error
+			if version, err = semver.DeriveNext(semver.Patch, library.Version); err != nil {
+				return fmt.Errorf("failed to get next version for library %s: %w", library.ID, err)
+			}
+		}
+		slog.Info("Releasing library for the release of its dependencies", "library", library.ID, "version", version)
+		library.Version = version
+		library.ReleaseTriggered = true
+		library.PreviousReleaseTag = ""
+	}
+	return nil
+}
+
+// dependencyChangeLevel returns the change of library due to the dependencies
+// released in state: a patch if any member of its release group depends on a
+// released library, and none otherwise.
+func dependencyChangeLevel(state *config.LibrarianState, library *config.LibraryState) semver.ChangeLevel {
+	for _, member := range releaseGroupMembers(state, library) {
+		for _, id := range member.DependsOn {
+			if dependency := state.LibraryByID(id); dependency != nil && dependency.ReleaseTriggered {
+				return semver.Patch
+			}
+		}
+	}
+	return semver.None
+}
+
+// sortReleasesByDependencies orders releases like releaseUnits orders their
+// libraries in state, so that every library is released after its
+// dependencies.
+func sortReleasesByDependencies(state *config.LibrarianState, releases []libraryRelease) ([]libraryRelease, error) {
+	var libraries []*config.LibraryState
+	byID := make(map[string][]libraryRelease)
+	for _, release := range releases {
+		if _, ok := byID[release.Library]; !ok {
+			library := state.LibraryByID(release.Library)
+			if library == nil {
+				library = &config.LibraryState{ID: release.Library}
+			}
+			libraries = append(libraries, library)
+		}
+		byID[release.Library] = append(byID[release.Library], release)
+	}
+	units, err := releaseUnits(libraries)
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]libraryRelease, 0, len(releases))
+	for _, unit := range units {
+		for _, library := range unit {
+			sorted = append(sorted, byID[library.ID]...)
+		}
+	}
+	return sorted, nil
+}
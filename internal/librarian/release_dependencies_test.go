@@ -0,0 +1,193 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/semver"
+)
+
+func TestReleaseUnits(t *testing.T) {
+	t.Parallel()
+	app := &config.LibraryState{ID: "app", DependsOn: []string{"ext", "util"}}
+	ext := &config.LibraryState{ID: "ext", ReleaseGroup: "core", DependsOn: []string{"core"}}
+	other := &config.LibraryState{ID: "other"}
+	core := &config.LibraryState{ID: "core", ReleaseGroup: "core", DependsOn: []string{"util"}}
+	util := &config.LibraryState{ID: "util"}
+	got, err := releaseUnits([]*config.LibraryState{app, ext, other, core, util})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]*config.LibraryState{{other}, {util}, {ext, core}, {app}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("releaseUnits() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReleaseUnits_Cycle(t *testing.T) {
+	t.Parallel()
+	// The libraries do not depend on each other in a cycle, but the release
+	// group does with b.
+	libraries := []*config.LibraryState{
+		{ID: "a1", ReleaseGroup: "a", DependsOn: []string{"b"}},
+		{ID: "b", DependsOn: []string{"a2"}},
+		{ID: "a2", ReleaseGroup: "a"},
+	}
+	_, err := releaseUnits(libraries)
+	if failure.CategoryOf(err) != failure.UserConfig || !strings.Contains(err.Error(), "dependency cycle") {
+		t.Errorf("releaseUnits() error = %v, want %q error about a dependency cycle", err, failure.UserConfig)
+	}
+}
+
+func TestPropagateDependencyReleases(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name           string
+		libraries      []*config.LibraryState
+		libraryVersion string
+		want           []*config.LibraryState
+	}{
+		{
+			name: "patch release of dependent",
+			libraries: []*config.LibraryState{
+				{ID: "dep", Version: "1.3.0", ReleaseTriggered: true},
+				{ID: "lib", Version: "2.0.1", DependsOn: []string{"dep"}},
+			},
+			want: []*config.LibraryState{
+				{ID: "dep", Version: "1.3.0", ReleaseTriggered: true},
+				{
+					ID:               "lib",
+					Version:          "2.0.2",
+					DependsOn:        []string{"dep"},
+					ReleaseTriggered: true,
+					Changes:          []*config.Change{{Type: "fix", Subject: "update dependency dep to 1.3.0"}},
+				},
+			},
+		},
+		{
+			name: "released dependent keeps its version",
+			libraries: []*config.LibraryState{
+				{ID: "dep", Version: "1.3.0", ReleaseTriggered: true},
+				{ID: "lib", Version: "2.1.0", DependsOn: []string{"dep"}, ReleaseTriggered: true},
+			},
+			want: []*config.LibraryState{
+				{ID: "dep", Version: "1.3.0", ReleaseTriggered: true},
+				{
+					ID:               "lib",
+					Version:          "2.1.0",
+					DependsOn:        []string{"dep"},
+					ReleaseTriggered: true,
+					Changes:          []*config.Change{{Type: "fix", Subject: "update dependency dep to 1.3.0"}},
+				},
+			},
+		},
+		{
+			name: "unreleased dependency",
+			libraries: []*config.LibraryState{
+				{ID: "dep", Version: "1.2.0"},
+				{ID: "lib", Version: "2.0.1", DependsOn: []string{"dep"}},
+			},
+			want: []*config.LibraryState{
+				{ID: "dep", Version: "1.2.0"},
+				{ID: "lib", Version: "2.0.1", DependsOn: []string{"dep"}},
+			},
+		},
+		{
+			name: "library version",
+			libraries: []*config.LibraryState{
+				{ID: "dep", Version: "1.3.0", ReleaseTriggered: true},
+				{ID: "lib", Version: "2.0.1", DependsOn: []string{"dep"}},
+			},
+			libraryVersion: "3.0.0",
+			want: []*config.LibraryState{
+				{ID: "dep", Version: "1.3.0", ReleaseTriggered: true},
+				{
+					ID:               "lib",
+					Version:          "3.0.0",
+					DependsOn:        []string{"dep"},
+					ReleaseTriggered: true,
+					Changes:          []*config.Change{{Type: "fix", Subject: "update dependency dep to 1.3.0"}},
+				},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			state := &config.LibrarianState{Libraries: test.libraries}
+			if err := propagateDependencyReleases(state, test.libraries[1:], test.libraryVersion); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, state.Libraries); diff != "" {
+				t.Errorf("libraries mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPropagateDependencyReleases_ReleaseGroup(t *testing.T) {
+	t.Parallel()
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "dep", Version: "1.3.0", ReleaseTriggered: true},
+			{ID: "core", Version: "2.0.1", ReleaseGroup: "g", DependsOn: []string{"dep"}},
+			{ID: "ext", Version: "0.4.0", ReleaseGroup: "g"},
+		},
+	}
+	if err := propagateDependencyReleases(state, state.Libraries[1:], ""); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]string{"core": "2.0.2", "ext": "0.4.1"} {
+		if got := state.LibraryByID(id); !got.ReleaseTriggered || got.Version != want {
+			t.Errorf("library %s released = %t at %s, want true at %s", id, got.ReleaseTriggered, got.Version, want)
+		}
+	}
+	if got := dependencyChangeLevel(state, state.LibraryByID("ext")); got != semver.Patch {
+		t.Errorf("dependencyChangeLevel() = %v, want %v", got, semver.Patch)
+	}
+	if got := dependencyChangeLevel(state, state.LibraryByID("dep")); got != semver.None {
+		t.Errorf("dependencyChangeLevel() = %v, want %v", got, semver.None)
+	}
+}
+
+func TestSortReleasesByDependencies(t *testing.T) {
+	t.Parallel()
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "app", DependsOn: []string{"core"}},
+			{ID: "core"},
+		},
+	}
+	releases := []libraryRelease{
+		{Library: "app", Version: "1.0.1"},
+		{Library: "unknown", Version: "0.1.0"},
+		{Library: "core", Version: "2.1.0"},
+	}
+	got, err := sortReleasesByDependencies(state, releases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []libraryRelease{
+		{Library: "unknown", Version: "0.1.0"},
+		{Library: "core", Version: "2.1.0"},
+		{Library: "app", Version: "1.0.1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("sortReleasesByDependencies() mismatch (-want +got):\n%s", diff)
+	}
+}
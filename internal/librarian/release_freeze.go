@@ -0,0 +1,227 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+var freezeFeedHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// activeFreeze returns the freeze of policy which is active at t, from its
+// freezes and its freeze feed, or nil. With offline, the feed is not
+// fetched.
+func activeFreeze(ctx context.Context, policy *config.ReleasePolicy, t time.Time, offline bool) (*config.ReleaseFreeze, error) {
+	if policy == nil {
+		return nil, nil
+	}
+	freezes := policy.Freezes
+	if policy.FreezeFeed != "" {
+		if offline {
+			slog.Warn("Skipping release freeze feed in offline mode", "url", policy.FreezeFeed)
+		} else {
+			feed, err := fetchFreezeFeed(ctx, policy.FreezeFeed)
+			if err != nil {
+				return nil, failure.New(failure.TransientInfra, fmt.Errorf("failed to fetch release freeze feed %s: %w", policy.FreezeFeed, err))
+			}
+			freezes = append(freezes[:len(freezes):len(freezes)], feed...)
+		}
+	}
+	for _, freeze := range freezes {
+		if freeze.IsActiveAt(t) {
+			return freeze, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkReleaseFreeze returns the violation of policy by releasing at t
+// during a freeze, or nil. A freeze overridden by the bug override does not
+// block the release, and is returned as overridden.
+func checkReleaseFreeze(ctx context.Context, policy *config.ReleasePolicy, t time.Time, cfg *config.Config) (violation *policyViolation, overridden *config.ReleaseFreeze, err error) {
+	freeze, err := activeFreeze(ctx, policy, t, cfg.Offline)
+	if err != nil || freeze == nil {
+		return nil, nil, err
+	}
+	if cfg.OverrideFreeze != "" {
+		slog.Warn("Releasing during a release freeze", "freeze", freezeDescription(freeze), "override", cfg.OverrideFreeze)
+		return nil, freeze, nil
+	}
+	return &policyViolation{
+		rule:   "freezes",
+		reason: fmt.Sprintf("releases are frozen %s; override with -override-freeze=<bug>", freezeDescription(freeze)),
+	}, nil, nil
+}
+
+// freezeDescription describes the window and reason of freeze, e.g. "from
+// 2025-12-20 to 2026-01-05 (holidays)".
+func freezeDescription(freeze *config.ReleaseFreeze) string {
+	description := fmt.Sprintf("from %s to %s", freeze.Start, freeze.End)
+	if freeze.Reason != "" {
+		description += fmt.Sprintf(" (%s)", freeze.Reason)
+	}
+	return description
+}
+
+// freezeOverrideNote returns the note of the release pull request of a
+// release during freeze, overridden by the bug override.
+func freezeOverrideNote(freeze *config.ReleaseFreeze, override string) string {
+	if freeze == nil {
+		return ""
+	}
+	return fmt.Sprintf("Released during the release freeze %s, overridden for %s.\n", freezeDescription(freeze), override)
+}
+
+// fetchFreezeFeed returns the freezes of the iCalendar or JSON feed at url.
+func fetchFreezeFeed(ctx context.Context, url string) ([]*config.ReleaseFreeze, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := freezeFeedHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseFreezeFeed(data)
+}
+
+// parseFreezeFeed parses a feed of freezes: an iCalendar, whose events are
+// the freezes, or JSON, either a list of freezes or an object with the list
+// in "freezes", each with a "start", "end" and "reason" like in the release
+// policy.
+func parseFreezeFeed(data []byte) ([]*config.ReleaseFreeze, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\ufeff")))
+	var freezes []*config.ReleaseFreeze
+	switch {
+	case bytes.HasPrefix(data, []byte("BEGIN:VCALENDAR")):
+		var err error
+		if freezes, err = parseICalFreezes(string(data)); err != nil {
+			return nil, err
+		}
+	case bytes.HasPrefix(data, []byte("[")):
+		if err := json.Unmarshal(data, &freezes); err != nil {
+			return nil, fmt.Errorf("invalid JSON feed: %w", err)
+		}
+	case bytes.HasPrefix(data, []byte("{")):
+		var feed struct {
+			Freezes []*config.ReleaseFreeze `json:"freezes"`
+		}
+		if err := json.Unmarshal(data, &feed); err != nil {
+			return nil, fmt.Errorf("invalid JSON feed: %w", err)
+		}
+		freezes = feed.Freezes
+	default:
+		return nil, errors.New("feed is neither an iCalendar nor JSON")
+	}
+	for i, freeze := range freezes {
+		if _, _, err := freeze.Window(); err != nil {
+			return nil, fmt.Errorf("invalid freeze at index %d: %w", i, err)
+		}
+	}
+	return freezes, nil
+}
+
+// parseICalFreezes returns the events of the iCalendar as freezes. Events on
+// whole days end the day before their exclusive DTEND, and times are
+// converted to UTC.
+func parseICalFreezes(calendar string) ([]*config.ReleaseFreeze, error) {
+	// Long lines are folded with a line break followed by a space or tab.
+	calendar = strings.ReplaceAll(calendar, "\r\n", "\n")
+	calendar = strings.NewReplacer("\n ", "", "\n\t", "").Replace(calendar)
+	var freezes []*config.ReleaseFreeze
+	var event *config.ReleaseFreeze
+	for _, line := range strings.Split(calendar, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, params, _ := strings.Cut(name, ";")
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			event = &config.ReleaseFreeze{}
+		case event == nil:
+		case name == "END" && value == "VEVENT":
+			if event.End == "" {
+				// An event without an end lasts its start day.
+				event.End = event.Start
+			}
+			freezes = append(freezes, event)
+			event = nil
+		case name == "SUMMARY":
+			event.Reason = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\\`, `\`).Replace(value)
+		case name == "DTSTART", name == "DTEND":
+			t, err := parseICalTime(params, value, name == "DTEND")
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %w", name, value, err)
+			}
+			if name == "DTSTART" {
+				event.Start = t
+			} else {
+				event.End = t
+			}
+		}
+	}
+	return freezes, nil
+}
+
+// parseICalTime returns the time of a DTSTART or DTEND with the given
+// parameters, e.g. "VALUE=DATE" or "TZID=Europe/Paris", as a day or a time of
+// a freeze. The days of DTEND are exclusive, and returned as the day before.
+func parseICalTime(params, value string, end bool) (string, error) {
+	if len(value) == len("20060102") {
+		t, err := time.Parse("20060102", value)
+		if err != nil {
+			return "", err
+		}
+		if end {
+			t = t.AddDate(0, 0, -1)
+		}
+		return t.Format(time.DateOnly), nil
+	}
+	location := time.UTC
+	for _, param := range strings.Split(params, ";") {
+		if tzid, ok := strings.CutPrefix(param, "TZID="); ok {
+			var err error
+			if location, err = time.LoadLocation(tzid); err != nil {
+				return "", err
+			}
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", strings.TrimSuffix(value, "Z"), location)
+	if err != nil {
+		return "", err
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
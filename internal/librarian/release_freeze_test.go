@@ -0,0 +1,205 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestParseFreezeFeed(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name       string
+		feed       string
+		want       []*config.ReleaseFreeze
+		wantErrMsg string
+	}{
+		{
+			name: "icalendar",
+			feed: strings.ReplaceAll(`BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+DTSTART;VALUE=DATE:20251220
+DTEND;VALUE=DATE:20260106
+SUMMARY:Holiday freeze\, all
+  repositories
+END:VEVENT
+BEGIN:VEVENT
+DTSTART;TZID=America/Los_Angeles:20251127T090000
+DTEND:20251128T170000Z
+SUMMARY:Thanksgiving
+END:VEVENT
+BEGIN:VEVENT
+DTSTART;VALUE=DATE:20250704
+END:VEVENT
+END:VCALENDAR
+`, "\n", "\r\n"),
+			want: []*config.ReleaseFreeze{
+				{Start: "2025-12-20", End: "2026-01-05", Reason: "Holiday freeze, all repositories"},
+				{Start: "2025-11-27T17:00:00Z", End: "2025-11-28T17:00:00Z", Reason: "Thanksgiving"},
+				{Start: "2025-07-04", End: "2025-07-04"},
+			},
+		},
+		{
+			name: "json list",
+			feed: `[{"start": "2025-12-20", "end": "2026-01-05", "reason": "holidays"}]`,
+			want: []*config.ReleaseFreeze{{Start: "2025-12-20", End: "2026-01-05", Reason: "holidays"}},
+		},
+		{
+			name: "json object",
+			feed: `{"freezes": [{"start": "2025-12-20", "end": "2026-01-05"}]}`,
+			want: []*config.ReleaseFreeze{{Start: "2025-12-20", End: "2026-01-05"}},
+		},
+		{
+			name:       "invalid freeze",
+			feed:       `[{"start": "2025-12-20"}]`,
+			wantErrMsg: "invalid freeze at index 0",
+		},
+		{
+			name:       "unknown format",
+			feed:       "start,end\n2025-12-20,2026-01-05\n",
+			wantErrMsg: "neither an iCalendar nor JSON",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseFreezeFeed([]byte(test.feed))
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Errorf("parseFreezeFeed() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("parseFreezeFeed() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckReleaseFreeze(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/freezes.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[{"start": "2025-11-27", "end": "2025-11-28", "reason": "thanksgiving"}]`))
+	}))
+	t.Cleanup(server.Close)
+	holidays := &config.ReleaseFreeze{Start: "2025-12-20", End: "2026-01-05", Reason: "holidays"}
+	for _, test := range []struct {
+		name          string
+		policy        *config.ReleasePolicy
+		cfg           *config.Config
+		t             time.Time
+		wantViolation string
+		wantOverride  *config.ReleaseFreeze
+		wantErrMsg    string
+	}{
+		{
+			name:   "no policy",
+			cfg:    &config.Config{},
+			t:      time.Date(2025, 12, 24, 0, 0, 0, 0, time.UTC),
+			policy: nil,
+		},
+		{
+			name:          "frozen",
+			policy:        &config.ReleasePolicy{Freezes: []*config.ReleaseFreeze{holidays}},
+			cfg:           &config.Config{},
+			t:             time.Date(2025, 12, 24, 0, 0, 0, 0, time.UTC),
+			wantViolation: "freezes: releases are frozen from 2025-12-20 to 2026-01-05 (holidays); override with -override-freeze=<bug>",
+		},
+		{
+			name:         "overridden",
+			policy:       &config.ReleasePolicy{Freezes: []*config.ReleaseFreeze{holidays}},
+			cfg:          &config.Config{OverrideFreeze: "b/12345"},
+			t:            time.Date(2025, 12, 24, 0, 0, 0, 0, time.UTC),
+			wantOverride: holidays,
+		},
+		{
+			name:   "not frozen",
+			policy: &config.ReleasePolicy{Freezes: []*config.ReleaseFreeze{holidays}},
+			cfg:    &config.Config{},
+			t:      time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "frozen by feed",
+			policy:        &config.ReleasePolicy{Freezes: []*config.ReleaseFreeze{holidays}, FreezeFeed: server.URL + "/freezes.json"},
+			cfg:           &config.Config{},
+			t:             time.Date(2025, 11, 28, 12, 0, 0, 0, time.UTC),
+			wantViolation: "freezes: releases are frozen from 2025-11-27 to 2025-11-28 (thanksgiving); override with -override-freeze=<bug>",
+		},
+		{
+			name:   "feed offline",
+			policy: &config.ReleasePolicy{FreezeFeed: server.URL + "/freezes.json"},
+			cfg:    &config.Config{Offline: true},
+			t:      time.Date(2025, 11, 28, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "feed not found",
+			policy:     &config.ReleasePolicy{FreezeFeed: server.URL + "/missing.json"},
+			cfg:        &config.Config{},
+			t:          time.Date(2025, 11, 28, 12, 0, 0, 0, time.UTC),
+			wantErrMsg: "failed to fetch release freeze feed",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			violation, overridden, err := checkReleaseFreeze(t.Context(), test.policy, test.t, test.cfg)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Errorf("checkReleaseFreeze() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if violation != nil {
+				got = violation.String()
+			}
+			if got != test.wantViolation {
+				t.Errorf("checkReleaseFreeze() violation = %q, want %q", got, test.wantViolation)
+			}
+			if diff := cmp.Diff(test.wantOverride, overridden); diff != "" {
+				t.Errorf("checkReleaseFreeze() overridden mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFreezeOverrideNote(t *testing.T) {
+	t.Parallel()
+	freeze := &config.ReleaseFreeze{Start: "2025-12-20", End: "2026-01-05", Reason: "holidays"}
+	want := "Released during the release freeze from 2025-12-20 to 2026-01-05 (holidays), overridden for b/12345.\n"
+	if got := freezeOverrideNote(freeze, "b/12345"); got != want {
+		t.Errorf("freezeOverrideNote() = %q, want %q", got, want)
+	}
+	if got := freezeOverrideNote(nil, ""); got != "" {
+		t.Errorf("freezeOverrideNote(nil) = %q, want empty", got)
+	}
+}
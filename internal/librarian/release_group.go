@@ -0,0 +1,133 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/semver"
+)
+
+// releaseGroupMembers returns the libraries in the release group of library,
+// in the order of the state, or only library if it is not in a release group.
+func releaseGroupMembers(state *config.LibrarianState, library *config.LibraryState) []*config.LibraryState {
+	if library.ReleaseGroup == "" {
+		return []*config.LibraryState{library}
+	}
+	var members []*config.LibraryState
+	for _, l := range state.Libraries {
+		if l.ReleaseGroup == library.ReleaseGroup {
+			members = append(members, l)
+		}
+	}
+	return members
+}
+
+// groupByReleaseGroup partitions libraries into the units in which they are
+// released: the libraries of a release group together, at the position of the
+// first of them, and every other library on its own.
+func groupByReleaseGroup(libraries []*config.LibraryState) [][]*config.LibraryState {
+	var units [][]*config.LibraryState
+	groupIndex := make(map[string]int)
+	for _, library := range libraries {
+		if library.ReleaseGroup == "" {
+			units = append(units, []*config.LibraryState{library})
+			continue
+		}
+		i, ok := groupIndex[library.ReleaseGroup]
+		if !ok {
+			i = len(units)
+			groupIndex[library.ReleaseGroup] = i
+			units = append(units, nil)
+		}
+		units[i] = append(units[i], library)
+	}
+	return units
+}
+
+// updateReleaseGroup updates the members of a release group for a release,
+// like updateLibrary does for a single library. If any member has changes to
+// release, all members are released, and the version of each is bumped by the
+// highest change among all members, or set to libraryVersion if it is not
+// empty.
+func updateReleaseGroup(repo gitrepo.Repository, members []*config.LibraryState, libraryVersion string) error {
+	highestChange := semver.None
+	hasChanges := false
+	for _, library := range members {
+		commits, err := GetConventionalCommitsSinceLastRelease(repo, library)
+		if err != nil {
+			return fmt.Errorf("failed to fetch conventional commits for library, %s: %w", library.ID, err)
+		}
+		library.Changes = coerceLibraryChanges(commits)
+		hasChanges = hasChanges || len(library.Changes) > 0
+		highestChange = max(highestChange, getHighestChange(commits))
+	}
+	if !hasChanges {
+		slog.Info("Skip releasing release group since no eligible change is found", "release_group", members[0].ReleaseGroup)
+		return nil
+	}
+	for _, library := range members {
+		nextVersion := libraryVersion
+		if nextVersion == "" {
+			var err error
+			if nextVersion, err = semver.DeriveNext(highestChange, library.Version); err != nil {
+				return fmt.Errorf("failed to derive next version of library %s: %w", library.ID, err)
+			}
+		}
+		library.Version = nextVersion
+		library.ReleaseTriggered = true
+		library.PreviousReleaseTag = ""
+	}
+	return nil
+}
+
+// releaseGroupChangeLevels returns the highest change since the last release
+// among the released members of each release group in state.
+func releaseGroupChangeLevels(repo gitrepo.Repository, state *config.LibrarianState) (map[string]semver.ChangeLevel, error) {
+	levels := make(map[string]semver.ChangeLevel)
+	for _, library := range state.Libraries {
+		if library.ReleaseGroup == "" || !library.ReleaseTriggered {
+			continue
+		}
+		commits, err := GetConventionalCommitsSinceLastRelease(repo, library)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get conventional commits for library %s: %w", library.ID, err)
+		}
+		levels[library.ReleaseGroup] = max(levels[library.ReleaseGroup], getHighestChange(commits))
+	}
+	return levels, nil
+}
+
+// checkReleaseGroupsComplete checks that the released libraries include all
+// members of the release groups of any of them, so that a release group is
+// never partially released.
+func checkReleaseGroupsComplete(state *config.LibrarianState, libraryIDs []string) error {
+	for _, id := range libraryIDs {
+		library := state.LibraryByID(id)
+		if library == nil {
+			continue
+		}
+		for _, member := range releaseGroupMembers(state, library) {
+			if !slices.Contains(libraryIDs, member.ID) {
+				return fmt.Errorf("library %s is released without %s, which is in the same release group %s", id, member.ID, library.ReleaseGroup)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+func TestGroupByReleaseGroup(t *testing.T) {
+	t.Parallel()
+	a := &config.LibraryState{ID: "a", ReleaseGroup: "g"}
+	b := &config.LibraryState{ID: "b"}
+	c := &config.LibraryState{ID: "c", ReleaseGroup: "g"}
+	d := &config.LibraryState{ID: "d", ReleaseGroup: "h"}
+	got := groupByReleaseGroup([]*config.LibraryState{a, b, c, d})
+	want := [][]*config.LibraryState{{a, c}, {b}, {d}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("groupByReleaseGroup() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestInitRunnerLibrariesToRelease(t *testing.T) {
+	t.Parallel()
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "core", ReleaseGroup: "core"},
+			{ID: "other"},
+			{ID: "ext", ReleaseGroup: "core"},
+		},
+	}
+	for _, test := range []struct {
+		library string
+		want    []string
+	}{
+		{want: []string{"core", "other", "ext"}},
+		{library: "ext", want: []string{"core", "ext"}},
+		{library: "other", want: []string{"other"}},
+		{library: "unknown"},
+	} {
+		t.Run(test.library, func(t *testing.T) {
+			r := &initRunner{cfg: &config.Config{Library: test.library}, state: state}
+			var got []string
+			for _, library := range r.librariesToRelease() {
+				got = append(got, library.ID)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("librariesToRelease() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUpdateReleaseGroup(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name           string
+		commitsByTag   map[string][]*gitrepo.Commit
+		libraryVersion string
+		wantVersions   []string
+		wantTriggered  bool
+	}{
+		{
+			name: "highest change of the group",
+			commitsByTag: map[string][]*gitrepo.Commit{
+				"core-1.2.0": {{Message: "feat: add a feature"}},
+			},
+			wantVersions:  []string{"1.3.0", "0.4.1"},
+			wantTriggered: true,
+		},
+		{
+			name: "override",
+			commitsByTag: map[string][]*gitrepo.Commit{
+				"ext-0.4.0": {{Message: "fix: fix a bug"}},
+			},
+			libraryVersion: "2.0.0",
+			wantVersions:   []string{"2.0.0", "2.0.0"},
+			wantTriggered:  true,
+		},
+		{
+			name:         "no changes",
+			wantVersions: []string{"1.2.0", "0.4.0"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			members := []*config.LibraryState{
+				{ID: "core", Version: "1.2.0", SourceRoots: []string{"core"}, ReleaseGroup: "core"},
+				{ID: "ext", Version: "0.4.0", SourceRoots: []string{"ext"}, ReleaseGroup: "core"},
+			}
+			repo := &MockRepository{
+				GetCommitsForPathsSinceTagValueByTag: test.commitsByTag,
+				ChangedFilesInCommitValue:            []string{"core/a.go", "ext/a.go"},
+			}
+			if err := updateReleaseGroup(repo, members, test.libraryVersion); err != nil {
+				t.Fatalf("updateReleaseGroup() error = %v", err)
+			}
+			var gotVersions []string
+			for _, library := range members {
+				gotVersions = append(gotVersions, library.Version)
+				if library.ReleaseTriggered != test.wantTriggered {
+					t.Errorf("%s: ReleaseTriggered = %t, want %t", library.ID, library.ReleaseTriggered, test.wantTriggered)
+				}
+			}
+			if diff := cmp.Diff(test.wantVersions, gotVersions); diff != "" {
+				t.Errorf("versions mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
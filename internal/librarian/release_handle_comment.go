@@ -0,0 +1,380 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/github"
+	"github.com/googleapis/librarian/internal/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// slashCommandPrefix starts the lines of the comments on release pull
+// requests which handle-comment runs.
+const slashCommandPrefix = "/librarian"
+
+// The commands which handle-comment runs.
+const (
+	slashCommandExclude    = "exclude"
+	slashCommandRegenerate = "regenerate"
+	slashCommandSetVersion = "set-version"
+)
+
+// slashCommandUsage lists the commands which handle-comment runs, for the
+// replies to invalid commands.
+const slashCommandUsage = "The supported commands are:\n" +
+	"- `/librarian regenerate` initiates the release again from the current HEAD.\n" +
+	"- `/librarian exclude <library>` removes a library from the release.\n" +
+	"- `/librarian set-version <library> <version>` releases a library at the given version.\n"
+
+// The markers surrounding the release overrides in the body of a release pull
+// request. Like the release status, it is in an HTML comment, so that it is
+// not rendered.
+const (
+	releaseOverridesBegin = "<!-- BEGIN LIBRARIAN RELEASE OVERRIDES"
+	releaseOverridesEnd   = "END LIBRARIAN RELEASE OVERRIDES -->"
+)
+
+// cmdHandleComment is the command for the `release handle-comment`
+// subcommand.
+var cmdHandleComment = &cli.Command{
+	Short:     "handle-comment runs the librarian commands in a comment on a release pull request.",
+	UsageLine: "librarian release handle-comment -pr=<url> -comment=<body> -comment-author=<login> [arguments]",
+	Long: `Runs the librarian command in a comment on a release pull request, which
+updates the release pull request accordingly. It is meant to be run by a
+GitHub Actions workflow triggered by the "issue_comment" event, with the
+pull request, body and author of the comment specified with -pr, -comment and
+-comment-author.
+
+The command is the first line of the comment starting with "/librarian":
+
+  /librarian regenerate
+      Initiates the release again from the current HEAD, like
+      "librarian release refresh-release-pr".
+  /librarian exclude <library>
+      Removes the library from the release. Members of release groups cannot
+      be excluded on their own.
+  /librarian set-version <library> <version>
+      Releases the library, or its release group, at the given version.
+
+Comments without a command are ignored. The author of the comment must be an
+approver of the "release_policy" of ".librarian/config.yaml", or an owner of
+the library which the command changes, or of every library in the release to
+regenerate it.
+
+The exclusions and versions are kept in the body of the release pull request,
+so that later refreshes, including by "librarian release refresh-release-pr",
+keep them. Once the release is initiated again, the commit is force-pushed to
+the branch of the release pull request, and the outcome of the command is
+replied in a comment.
+
+Changes sent for review to Gerrit are not supported.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newInitRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return runner.handleComment(ctx)
+	},
+}
+
+func init() {
+	cmdHandleComment.Init()
+	fs := cmdHandleComment.Flags
+	cfg := cmdHandleComment.Config
+
+	addFlagAuditLog(fs, cfg)
+	addFlagCleanWorkRoot(fs, cfg)
+	addFlagComment(fs, cfg)
+	addFlagCommentAuthor(fs, cfg)
+	addFlagCommit(fs, cfg)
+	addFlagContainerBackend(fs, cfg)
+	addFlagContainerLogLevel(fs, cfg)
+	addFlagContainerMounts(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGeneratorSource(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagImage(fs, cfg)
+	addFlagImageLocal(fs, cfg)
+	addFlagImagePlatform(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagNativeImage(fs, cfg)
+	addFlagPR(fs, cfg)
+	addFlagPush(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+// slashCommand is a librarian command in a comment on a release pull request.
+type slashCommand struct {
+	name    string
+	library string
+	version string
+}
+
+// parseSlashCommand returns the command on the first line of comment which
+// starts with slashCommandPrefix, or nil if there is none.
+func parseSlashCommand(comment string) (*slashCommand, error) {
+	for _, line := range strings.Split(comment, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != slashCommandPrefix {
+			continue
+		}
+		if len(fields) == 1 {
+			return nil, errors.New("missing command after /librarian")
+		}
+		command := &slashCommand{name: fields[1]}
+		args := fields[2:]
+		var want int
+		switch command.name {
+		case slashCommandRegenerate:
+			want = 0
+		case slashCommandExclude:
+			want = 1
+		case slashCommandSetVersion:
+			want = 2
+		default:
+			return nil, fmt.Errorf("unknown command %q", command.name)
+		}
+		if len(args) != want {
+			return nil, fmt.Errorf("%s takes %d arguments, got %d", command.name, want, len(args))
+		}
+		if want > 0 {
+			command.library = args[0]
+		}
+		if want > 1 {
+			command.version = args[1]
+			if _, err := semver.Parse(command.version); err != nil {
+				return nil, fmt.Errorf("invalid version %q: %w", command.version, err)
+			}
+		}
+		return command, nil
+	}
+	return nil, nil
+}
+
+// String returns the command as written in a comment.
+func (c *slashCommand) String() string {
+	return strings.Join(slices.DeleteFunc([]string{slashCommandPrefix, c.name, c.library, c.version}, func(s string) bool {
+		return s == ""
+	}), " ")
+}
+
+// apply records the exclusion or the version of the command in overrides.
+func (c *slashCommand) apply(state *config.LibrarianState, overrides *releaseOverrides) error {
+	if c.name == slashCommandRegenerate {
+		return nil
+	}
+	library := state.LibraryByID(c.library)
+	if library == nil {
+		return fmt.Errorf("library %q not found in state", c.library)
+	}
+	switch c.name {
+	case slashCommandExclude:
+		if library.ReleaseGroup != "" {
+			return fmt.Errorf("library %s is released with the other members of release group %s, and cannot be excluded on its own", library.ID, library.ReleaseGroup)
+		}
+		if !slices.Contains(overrides.Excluded, library.ID) {
+			overrides.Excluded = append(overrides.Excluded, library.ID)
+			slices.Sort(overrides.Excluded)
+		}
+		delete(overrides.Versions, library.ID)
+	case slashCommandSetVersion:
+		overrides.Excluded = slices.DeleteFunc(overrides.Excluded, func(id string) bool {
+			return id == library.ID
+		})
+		if overrides.Versions == nil {
+			overrides.Versions = make(map[string]string)
+		}
+		overrides.Versions[library.ID] = c.version
+	}
+	return nil
+}
+
+// done returns the reply to the command once it updated the release pull
+// request.
+func (c *slashCommand) done() string {
+	switch c.name {
+	case slashCommandExclude:
+		return fmt.Sprintf("Excluded %s from the release, and updated this pull request.\n", c.library)
+	case slashCommandSetVersion:
+		return fmt.Sprintf("Set the version of %s to %s, and updated this pull request.\n", c.library, c.version)
+	default:
+		return "Initiated the release again, and updated this pull request.\n"
+	}
+}
+
+// releaseOverrides are the changes to a release made with comments on its
+// release pull request, which are kept in its body so that later refreshes
+// of the release keep them.
+type releaseOverrides struct {
+	// Excluded are the IDs of the libraries which are not released.
+	Excluded []string `yaml:"excluded,omitempty"`
+	// Versions are the versions of the released libraries, by library ID.
+	Versions map[string]string `yaml:"versions,omitempty"`
+}
+
+// excludes reports whether the library with the given ID is excluded from the
+// release. A nil releaseOverrides excludes no library.
+func (o *releaseOverrides) excludes(id string) bool {
+	return o != nil && slices.Contains(o.Excluded, id)
+}
+
+// version returns the version set for any of libraries, a single library or
+// the members of a release group, or "" if there is none.
+func (o *releaseOverrides) version(libraries []*config.LibraryState) string {
+	if o == nil {
+		return ""
+	}
+	for _, library := range libraries {
+		if version, ok := o.Versions[library.ID]; ok {
+			return version
+		}
+	}
+	return ""
+}
+
+// format returns the overrides as a block for the body of a release pull
+// request, or "" if there are none.
+func (o *releaseOverrides) format() (string, error) {
+	if o == nil || (len(o.Excluded) == 0 && len(o.Versions) == 0) {
+		return "", nil
+	}
+	data, err := yaml.Marshal(o)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal release overrides: %w", err)
+	}
+	return fmt.Sprintf("%s\n%s%s\n", releaseOverridesBegin, data, releaseOverridesEnd), nil
+}
+
+// parseReleaseOverrides returns the release overrides in the body of a
+// release pull request, which are empty if the body does not contain any.
+func parseReleaseOverrides(body string) (*releaseOverrides, error) {
+	overrides := &releaseOverrides{}
+	_, rest, found := strings.Cut(body, releaseOverridesBegin)
+	if !found {
+		return overrides, nil
+	}
+	data, _, found := strings.Cut(rest, releaseOverridesEnd)
+	if !found {
+		return nil, fmt.Errorf("release overrides are not terminated by %q", releaseOverridesEnd)
+	}
+	if err := yaml.Unmarshal([]byte(data), overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse release overrides: %w", err)
+	}
+	return overrides, nil
+}
+
+// handleComment runs the command in the comment specified with -comment on
+// the release pull request specified with -pr, and replies with its outcome.
+func (r *initRunner) handleComment(ctx context.Context) error {
+	command, parseErr := parseSlashCommand(r.cfg.Comment)
+	if command == nil && parseErr == nil {
+		slog.Info("Comment contains no librarian command")
+		return nil
+	}
+	if r.gerrit != nil {
+		return failure.New(failure.UserConfig, errors.New("handle-comment does not support changes sent for review to Gerrit"))
+	}
+	if r.cfg.PullRequest == "" || r.cfg.CommentAuthor == "" {
+		return failure.New(failure.UserConfig, errors.New("handle-comment requires the pull request and the author of the comment, specified with -pr and -comment-author"))
+	}
+	pr, err := findReleasePullRequest(ctx, r.cfg, r.ghClient)
+	if err != nil {
+		return err
+	}
+	var reply string
+	if parseErr != nil {
+		err = failure.New(failure.UserConfig, parseErr)
+		reply = fmt.Sprintf("Could not run the librarian command: %v.\n\n%s", parseErr, slashCommandUsage)
+	} else if reply, err = r.runSlashCommand(ctx, pr, command); err != nil {
+		reply = fmt.Sprintf("Failed to run `%s`: %v\n", command, err)
+	}
+	if commentErr := r.ghClient.CreateIssueComment(ctx, pr.GetNumber(), reply); commentErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to reply on pull request %d: %w", pr.GetNumber(), commentErr))
+	}
+	return err
+}
+
+// runSlashCommand runs command, by its author, on the release pull request
+// pr, and returns the reply to the command.
+func (r *initRunner) runSlashCommand(ctx context.Context, pr *github.PullRequest, command *slashCommand) (string, error) {
+	overrides, err := parseReleaseOverrides(pr.GetBody())
+	if err != nil {
+		return "", failure.New(failure.UserConfig, err)
+	}
+	var libraryIDs []string
+	if command.library != "" {
+		libraryIDs = append(libraryIDs, command.library)
+	} else {
+		for _, release := range parsePullRequestBody(pr.GetBody()) {
+			libraryIDs = append(libraryIDs, release.Library)
+		}
+	}
+	if err := checkCommentAuthor(ctx, r.ghClient, r.librarianConfig, r.state, r.cfg.CommentAuthor, libraryIDs); err != nil {
+		return "", err
+	}
+	if err := command.apply(r.state, overrides); err != nil {
+		return "", failure.New(failure.UserConfig, err)
+	}
+	slog.Info("Running librarian command", "command", command.String(), "author", r.cfg.CommentAuthor, "pr", pr.GetNumber())
+	r.refresh = pr
+	r.overrides = overrides
+	if err := r.run(ctx); err != nil {
+		return "", err
+	}
+	auditLogFromContext(ctx).record(auditCommentHandled, map[string]string{
+		"number":  fmt.Sprint(pr.GetNumber()),
+		"command": command.String(),
+		"author":  r.cfg.CommentAuthor,
+	})
+	return command.done(), nil
+}
+
+// checkCommentAuthor returns an error unless user may run commands changing
+// the libraries with the given IDs: user must be an approver of the release
+// policy, or an owner of all the libraries. Only approvers may run commands
+// changing no known library.
+func checkCommentAuthor(ctx context.Context, ghClient GitHubClient, librarianConfig *config.LibrarianConfig, state *config.LibrarianState, user string, libraryIDs []string) error {
+	ok, err := isListedUser(ctx, ghClient, releaseApprovers(librarianConfig), user)
+	if err != nil || ok {
+		return err
+	}
+	if len(libraryIDs) == 0 {
+		return failure.New(failure.UserConfig, fmt.Errorf("%s may not run librarian commands: not a release approver", user))
+	}
+	unowned, err := unownedLibraries(ctx, ghClient, state, user, libraryIDs)
+	if err != nil {
+		return err
+	}
+	if len(unowned) > 0 {
+		return failure.New(failure.UserConfig, fmt.Errorf("%s may not run librarian commands on this release: not a release approver, nor an owner of %s", user, strings.Join(unowned, ", ")))
+	}
+	return nil
+}
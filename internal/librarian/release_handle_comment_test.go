@@ -0,0 +1,283 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+func TestParseSlashCommand(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name       string
+		comment    string
+		want       *slashCommand
+		wantErrMsg string
+	}{
+		{
+			name:    "no command",
+			comment: "LGTM, thanks!",
+		},
+		{
+			name:    "regenerate",
+			comment: "Changes landed.\n/librarian regenerate\n",
+			want:    &slashCommand{name: slashCommandRegenerate},
+		},
+		{
+			name:    "exclude",
+			comment: "  /librarian exclude secretmanager  ",
+			want:    &slashCommand{name: slashCommandExclude, library: "secretmanager"},
+		},
+		{
+			name:    "set-version",
+			comment: "/librarian set-version secretmanager 2.0.0",
+			want:    &slashCommand{name: slashCommandSetVersion, library: "secretmanager", version: "2.0.0"},
+		},
+		{
+			name:       "missing command",
+			comment:    "/librarian",
+			wantErrMsg: "missing command",
+		},
+		{
+			name:       "unknown command",
+			comment:    "/librarian release-everything",
+			wantErrMsg: `unknown command "release-everything"`,
+		},
+		{
+			name:       "missing library",
+			comment:    "/librarian exclude",
+			wantErrMsg: "exclude takes 1 arguments, got 0",
+		},
+		{
+			name:       "invalid version",
+			comment:    "/librarian set-version secretmanager two",
+			wantErrMsg: `invalid version "two"`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseSlashCommand(test.comment)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("parseSlashCommand() error = %v, want %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got, cmp.AllowUnexported(slashCommand{})); diff != "" {
+				t.Errorf("parseSlashCommand() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSlashCommandApply(t *testing.T) {
+	t.Parallel()
+	state := &config.LibrarianState{Libraries: []*config.LibraryState{
+		{ID: "secretmanager"},
+		{ID: "storage"},
+		{ID: "pubsub", ReleaseGroup: "messaging"},
+	}}
+	for _, test := range []struct {
+		name       string
+		command    *slashCommand
+		overrides  *releaseOverrides
+		want       *releaseOverrides
+		wantErrMsg string
+	}{
+		{
+			name:      "regenerate",
+			command:   &slashCommand{name: slashCommandRegenerate},
+			overrides: &releaseOverrides{Excluded: []string{"storage"}},
+			want:      &releaseOverrides{Excluded: []string{"storage"}},
+		},
+		{
+			name:      "exclude",
+			command:   &slashCommand{name: slashCommandExclude, library: "secretmanager"},
+			overrides: &releaseOverrides{Excluded: []string{"storage"}, Versions: map[string]string{"secretmanager": "2.0.0"}},
+			want:      &releaseOverrides{Excluded: []string{"secretmanager", "storage"}, Versions: map[string]string{}},
+		},
+		{
+			name:      "set-version of excluded library",
+			command:   &slashCommand{name: slashCommandSetVersion, library: "storage", version: "2.0.0"},
+			overrides: &releaseOverrides{Excluded: []string{"storage"}},
+			want:      &releaseOverrides{Excluded: []string{}, Versions: map[string]string{"storage": "2.0.0"}},
+		},
+		{
+			name:       "unknown library",
+			command:    &slashCommand{name: slashCommandExclude, library: "unknown"},
+			overrides:  &releaseOverrides{},
+			wantErrMsg: `library "unknown" not found`,
+		},
+		{
+			name:       "release group member",
+			command:    &slashCommand{name: slashCommandExclude, library: "pubsub"},
+			overrides:  &releaseOverrides{},
+			wantErrMsg: "release group messaging",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			err := test.command.apply(state, test.overrides)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("apply() error = %v, want %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, test.overrides); diff != "" {
+				t.Errorf("apply() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReleaseOverrides(t *testing.T) {
+	t.Parallel()
+	overrides := &releaseOverrides{Excluded: []string{"storage"}, Versions: map[string]string{"secretmanager": "2.0.0"}}
+	block, err := overrides.format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := parseReleaseOverrides(releasePullRequestMarker + "\n" + block + "release status\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(overrides, got); diff != "" {
+		t.Errorf("parseReleaseOverrides() mismatch (-want +got):\n%s", diff)
+	}
+	if empty, err := (&releaseOverrides{}).format(); err != nil || empty != "" {
+		t.Errorf("format() of no overrides = %q, %v, want empty", empty, err)
+	}
+	if _, err := parseReleaseOverrides(releaseOverridesBegin + "\nexcluded: []\n"); err == nil {
+		t.Error("parseReleaseOverrides() of unterminated overrides succeeded, want error")
+	}
+
+	r := &initRunner{
+		cfg: &config.Config{},
+		state: &config.LibrarianState{Libraries: []*config.LibraryState{
+			{ID: "secretmanager"},
+			{ID: "storage"},
+		}},
+		overrides: overrides,
+	}
+	var ids []string
+	for _, library := range r.librariesToRelease() {
+		ids = append(ids, library.ID)
+	}
+	if diff := cmp.Diff([]string{"secretmanager"}, ids); diff != "" {
+		t.Errorf("librariesToRelease() mismatch (-want +got):\n%s", diff)
+	}
+	if got := r.libraryVersion(r.state.Libraries[:1]); got != "2.0.0" {
+		t.Errorf("libraryVersion() = %q, want %q", got, "2.0.0")
+	}
+	r.cfg.LibraryVersion = "3.0.0"
+	if got := r.libraryVersion(r.state.Libraries[:1]); got != "3.0.0" {
+		t.Errorf("libraryVersion() with -library-version = %q, want %q", got, "3.0.0")
+	}
+}
+
+func TestHandleComment(t *testing.T) {
+	t.Parallel()
+	body := releasePullRequestMarker + "\n<details><summary>secretmanager: 1.1.0</summary>\n\nnotes\n</details>"
+	for _, test := range []struct {
+		name        string
+		comment     string
+		author      string
+		wantReply   string
+		wantErrMsg  string
+		wantNoCalls bool
+	}{
+		{
+			name:        "no command",
+			comment:     "LGTM",
+			author:      "octocat",
+			wantNoCalls: true,
+		},
+		{
+			name:       "invalid command",
+			comment:    "/librarian exclude",
+			author:     "owner",
+			wantReply:  "Could not run the librarian command: exclude takes 1 arguments",
+			wantErrMsg: "exclude takes 1 arguments",
+		},
+		{
+			name:       "not an owner",
+			comment:    "/librarian exclude secretmanager",
+			author:     "octocat",
+			wantReply:  "Failed to run `/librarian exclude secretmanager`: octocat may not run librarian commands",
+			wantErrMsg: "nor an owner of secretmanager",
+		},
+		{
+			name:       "regenerate by a non-approver",
+			comment:    "/librarian regenerate",
+			author:     "octocat",
+			wantReply:  "Failed to run `/librarian regenerate`",
+			wantErrMsg: "nor an owner of secretmanager",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			ghClient := &mockGitHubClient{pullRequest: newTestPullRequest(5, "open", body)}
+			r := &initRunner{
+				cfg: &config.Config{
+					Comment:       test.comment,
+					CommentAuthor: test.author,
+					PullRequest:   "github.com/googleapis/librarian/pulls/5",
+				},
+				state: &config.LibrarianState{Libraries: []*config.LibraryState{
+					{ID: "secretmanager", Owners: []string{"@owner"}},
+				}},
+				ghClient: ghClient,
+			}
+			err := r.handleComment(context.Background())
+			if test.wantNoCalls {
+				if err != nil || ghClient.getPullRequestCalls != 0 || ghClient.createIssueCommentCalls != 0 {
+					t.Errorf("handleComment() = %v with %d calls, want no error nor calls", err, ghClient.getPullRequestCalls+ghClient.createIssueCommentCalls)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+				t.Fatalf("handleComment() error = %v, want %q", err, test.wantErrMsg)
+			}
+			if got := failure.CategoryOf(err); got != failure.UserConfig {
+				t.Errorf("failure.CategoryOf() = %v, want %v", got, failure.UserConfig)
+			}
+			if !strings.Contains(ghClient.issueComment, test.wantReply) {
+				t.Errorf("handleComment() replied %q, want %q", ghClient.issueComment, test.wantReply)
+			}
+		})
+	}
+}
+
+func TestHandleComment_MissingFlags(t *testing.T) {
+	t.Parallel()
+	r := &initRunner{cfg: &config.Config{Comment: "/librarian regenerate"}}
+	err := r.handleComment(context.Background())
+	if got := failure.CategoryOf(err); got != failure.UserConfig {
+		t.Errorf("handleComment() error = %v, want a user config error", err)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/googleapis/librarian/internal/conventionalcommits"
 
@@ -27,11 +28,14 @@ import (
 
 	"github.com/googleapis/librarian/internal/cli"
 	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/github"
 	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/metrics"
 )
 
 const (
-	KeyClNum = "PiperOrigin-RevId"
+	KeyClNum = gitrepo.TrailerPiperOriginRevID
 )
 
 // cmdInit is the command for the `release init` subcommand.
@@ -40,7 +44,14 @@ var cmdInit = &cli.Command{
 	UsageLine: "librarian release init [arguments]",
 	Long: `The release init command is the primary entry point for initiating a release.
 It orchestrates the process of parsing commits, determining new versions, generating
-a changelog, and creating a release pull request.`,
+a changelog, and creating a release pull request.
+
+With -channel=canary, the libraries are released with prerelease versions, e.g.
+1.5.0-beta.3, which are recorded as their canary_version in state.yaml, leaving
+their stable version unchanged. Canary releases are proposed on
+librarian-canary-* branches, and tagged with the canary tag format of
+config.yaml. Use "librarian release promote-release" to release the canary
+versions as stable versions.`,
 	Run: func(ctx context.Context, cfg *config.Config) error {
 		runner, err := newInitRunner(cfg)
 		if err != nil {
@@ -55,12 +66,45 @@ func init() {
 	fs := cmdInit.Flags
 	cfg := cmdInit.Config
 
+	addFlagArtifactsInclude(fs, cfg)
+	addFlagArtifactsRetention(fs, cfg)
+	addFlagArtifactsURL(fs, cfg)
+	addFlagAuditLog(fs, cfg)
+	addFlagChannel(fs, cfg)
+	addFlagCleanWorkRoot(fs, cfg)
 	addFlagCommit(fs, cfg)
+	addFlagContainerBackend(fs, cfg)
+	addFlagContainerLogLevel(fs, cfg)
+	addFlagContainerMounts(fs, cfg)
+	addFlagContainerRecord(fs, cfg)
+	addFlagContainerReplay(fs, cfg)
+	addFlagDebugShell(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGeneratorSource(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagProfile(fs, cfg)
 	addFlagPush(fs, cfg)
 	addFlagImage(fs, cfg)
+	addFlagImageArchive(fs, cfg)
+	addFlagImageLocal(fs, cfg)
+	addFlagImagePlatform(fs, cfg)
 	addFlagLibrary(fs, cfg)
 	addFlagLibraryVersion(fs, cfg)
+	addFlagMaxReleaseFiles(fs, cfg)
+	addFlagMaxReleaseLibraries(fs, cfg)
+	addFlagMetricsDir(fs, cfg)
+	addFlagNativeImage(fs, cfg)
+	addFlagOffline(fs, cfg)
+	addFlagOverrideFreeze(fs, cfg)
+	addFlagRegistryMirror(fs, cfg)
+	addFlagReportFailures(fs, cfg)
 	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
 	addFlagWorkRoot(fs, cfg)
 }
 
@@ -70,10 +114,28 @@ type initRunner struct {
 	state           *config.LibrarianState
 	librarianConfig *config.LibrarianConfig
 	ghClient        GitHubClient
+	gerrit          *gerritReview
 	containerClient ContainerClient
 	workRoot        string
 	partialRepo     string
 	image           string
+	// promote is set by the promote-release command, which releases the
+	// canary versions of the libraries as stable versions.
+	promote bool
+	// refresh is the open release pull request which the refresh-release-pr
+	// command updates in place, if any.
+	refresh *github.PullRequest
+	// overrides are the exclusions and versions set with comments on the
+	// refreshed release pull request, if any.
+	overrides *releaseOverrides
+	// frozen is the active release freeze overridden with -override-freeze,
+	// if any.
+	frozen *config.ReleaseFreeze
+	// releaseNotes is the format of the release notes of the repository.
+	releaseNotes *releaseNotesFormat
+	// previousState is a copy of the state before the libraries are updated
+	// for the release, from which their release notes are derived.
+	previousState *config.LibrarianState
 }
 
 func newInitRunner(cfg *config.Config) (*initRunner, error) {
@@ -90,23 +152,102 @@ func newInitRunner(cfg *config.Config) (*initRunner, error) {
 		librarianConfig: runner.librarianConfig,
 		image:           runner.image,
 		ghClient:        runner.ghClient,
+		gerrit:          runner.gerrit,
 		containerClient: runner.containerClient,
+		releaseNotes:    runner.releaseNotes,
 	}, nil
 }
 
 func (r *initRunner) run(ctx context.Context) error {
+	if err := checkReviewPermissions(ctx, r.cfg, r.ghClient, r.gerrit); err != nil {
+		return err
+	}
 	outputDir := filepath.Join(r.workRoot, "output")
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output dir: %s", outputDir)
 	}
+	// The directories are made before the image is pulled, so that an
+	// invalid work root fails fast.
+	if err := os.MkdirAll(r.partialRepo, 0755); err != nil {
+		return fmt.Errorf("failed to make directory: %w", err)
+	}
 	slog.Info("Initiating a release", "dir", outputDir)
+	if violation := checkReleaseDay(r.releasePolicy(), now()); violation != nil {
+		return releasePolicyError([]*policyViolation{violation})
+	}
+	violation, frozen, err := checkReleaseFreeze(ctx, r.releasePolicy(), now(), r.cfg)
+	if err != nil {
+		return err
+	}
+	if violation != nil {
+		return releasePolicyError([]*policyViolation{violation})
+	}
+	r.frozen = frozen
+	run := metrics.FromContext(ctx)
+	stopPrewarm := run.StartPhase("prewarm")
+	if err := r.containerClient.Prewarm(ctx); err != nil {
+		return fmt.Errorf("failed to prewarm container image: %w", err)
+	}
+	stopPrewarm()
+	stopInit := run.StartPhase("release-init")
 	if err := r.runInitCommand(ctx, outputDir); err != nil {
 		return err
 	}
+	stopInit()
 
 	// TODO: https://github.com/googleapis/librarian/issues/1697
 	// Add commit message after this issue is resolved.
-	if err := commitAndPush(ctx, r.cfg, r.repo, r.ghClient, ""); err != nil {
+	var releasedLibraryIDs []string
+	for _, library := range r.state.Libraries {
+		if library.ReleaseTriggered {
+			releasedLibraryIDs = append(releasedLibraryIDs, library.ID)
+		}
+	}
+	run.AddLibraries(len(releasedLibraryIDs), 0)
+	releaseID := newReleaseID(now())
+	artifactRunFromContext(ctx).setID(releaseID)
+	artifactRunFromContext(ctx).addLibraries(r.state)
+	var body string
+	if r.cfg.Commit || r.cfg.Push {
+		status, err := r.repo.AddAll()
+		if err != nil {
+			return err
+		}
+		if groups := planReleaseGroups(r.cfg, r.state, releasedLibraryIDs, status); len(groups) > 1 {
+			if r.refresh != nil {
+				return failure.New(failure.UserConfig, fmt.Errorf("the release of %d libraries is split into %d pull requests, which cannot refresh pull request %d in place", len(releasedLibraryIDs), len(groups), r.refresh.GetNumber()))
+			}
+			if err := r.commitReleaseGroups(ctx, groups, status, releaseID); err != nil {
+				return fmt.Errorf("failed to commit and push: %w", err)
+			}
+			return nil
+		}
+		if len(releasedLibraryIDs) > 0 {
+			if err := recordRelease(ctx, r.repo.GetDir(), r.state, releasedLibraryIDs, releaseID, r.canary()); err != nil {
+				return err
+			}
+			if body, err = r.releasePullRequestBody(releaseID, releasedLibraryIDs, ""); err != nil {
+				return err
+			}
+		}
+	}
+	commitInfo := &commitInfo{
+		cfg:             r.cfg,
+		state:           r.state,
+		repo:            r.repo,
+		ghClient:        r.ghClient,
+		gerrit:          r.gerrit,
+		libraryIDs:      releasedLibraryIDs,
+		kind:            config.PullRequestKindRelease,
+		librarianConfig: r.librarianConfig,
+		branch:          fmt.Sprintf("%s-%s", r.branchPrefix(), formatTimestamp(now())),
+		body:            body,
+	}
+	if r.refresh != nil {
+		commitInfo.branch = r.refresh.GetHead().GetRef()
+		commitInfo.update = r.refresh.GetNumber()
+	}
+	if err := commitAndPush(ctx, commitInfo); err != nil {
 		return fmt.Errorf("failed to commit and push: %w", err)
 	}
 
@@ -115,31 +256,18 @@ func (r *initRunner) run(ctx context.Context) error {
 
 func (r *initRunner) runInitCommand(ctx context.Context, outputDir string) error {
 	dst := r.partialRepo
-	if err := os.MkdirAll(dst, 0755); err != nil {
-		return fmt.Errorf("failed to make directory: %w", err)
-	}
 	src := r.repo.GetDir()
 
-	for _, library := range r.state.Libraries {
-		if r.cfg.Library != "" {
-			if r.cfg.Library != library.ID {
-				continue
-			}
-			// Only update one library with the given library ID.
-			if err := updateLibrary(r.repo, library, r.cfg.LibraryVersion); err != nil {
-				return err
-			}
-			if err := copyLibrary(dst, src, library); err != nil {
-				return err
-			}
-
-			break
-		}
-
-		// Update all libraries.
-		if err := updateLibrary(r.repo, library, r.cfg.LibraryVersion); err != nil {
-			return err
-		}
+	r.previousState = copyLibrarianState(r.state)
+	libraries := r.librariesToRelease()
+	stableVersions := make(map[string]string)
+	for _, library := range libraries {
+		stableVersions[library.ID] = library.Version
+	}
+	if err := r.planLibraries(libraries); err != nil {
+		return err
+	}
+	for _, library := range libraries {
 		if err := copyLibrary(dst, src, library); err != nil {
 			return err
 		}
@@ -153,6 +281,14 @@ func (r *initRunner) runInitCommand(ctx context.Context, outputDir string) error
 		return fmt.Errorf("failed to copy global allowlist  from %s to %s: %w", src, dst, err)
 	}
 
+	// The repository is not necessarily on GitHub, in which case only the
+	// bugs of the configured bug trackers are linked.
+	ghRepo, err := github.FetchGitHubRepoFromHostRemote(r.repo, r.cfg.GitHubHost())
+	if err != nil {
+		slog.Debug("Not linking issues of the repository", "err", err)
+	}
+	(&bugLinker{librarianConfig: r.librarianConfig, repo: ghRepo}).linkChanges(libraries)
+
 	initRequest := &docker.ReleaseInitRequest{
 		Cfg:             r.cfg,
 		State:           r.state,
@@ -167,26 +303,199 @@ func (r *initRunner) runInitCommand(ctx context.Context, outputDir string) error
 		return err
 	}
 
+	for _, library := range libraries {
+		if err := copyLibraryFiles(r.state, r.repo.GetDir(), library.ID, outputDir); err != nil {
+			return err
+		}
+	}
+
 	for _, library := range r.state.Libraries {
-		if r.cfg.Library != "" {
-			if r.cfg.Library != library.ID {
-				continue
-			}
-			// Only copy one library to repository.
-			if err := copyLibraryFiles(r.state, r.repo.GetDir(), r.cfg.Library, outputDir); err != nil {
-				return err
+		if !library.ReleaseTriggered {
+			continue
+		}
+		if err := updateVersionFiles(r.librarianConfig, r.repo.GetDir(), library); err != nil {
+			return err
+		}
+	}
+
+	if err := copyGlobalAllowlist(r.librarianConfig, r.repo.GetDir(), outputDir, false); err != nil {
+		return err
+	}
+	if r.promote || r.cfg.Channel == config.ChannelCanary {
+		return saveChannelVersions(ctx, r.repo.GetDir(), r.state, stableVersions, r.promote)
+	}
+	return nil
+}
+
+// releasePullRequestBody returns the body of the pull request releasing the
+// libraries with the given IDs: the marker of release pull requests, intro,
+// the notes on its approval, freeze override and overrides, the release notes
+// and metadata of the libraries from FormatReleaseNotes, and the release
+// status. The release notes are left out if the repository is not on GitHub,
+// as they link to it.
+func (r *initRunner) releasePullRequestBody(releaseID string, libraryIDs []string, intro string) (string, error) {
+	var notes string
+	if _, err := github.FetchGitHubRepoFromHostRemote(r.repo, r.cfg.GitHubHost()); err != nil {
+		slog.Debug("Not adding release notes to the pull request", "err", err)
+	} else {
+		notesState := copyLibrarianState(r.previousState)
+		versions := make(map[string]string)
+		for _, library := range notesState.Libraries {
+			library.ReleaseTriggered = slices.Contains(libraryIDs, library.ID)
+			if released := r.state.LibraryByID(library.ID); library.ReleaseTriggered && released != nil {
+				versions[library.ID] = released.Version
 			}
+		}
+		if notes, err = FormatReleaseNotes(r.repo, r.librarianConfig, r.releaseNotes, notesState, r.cfg.GitHubHost(), versions); err != nil {
+			return "", err
+		}
+		notes += "\n"
+	}
+	status, err := newReleaseStatus(releaseID, r.state, libraryIDs).format()
+	if err != nil {
+		return "", err
+	}
+	overrides, err := r.overrides.format()
+	if err != nil {
+		return "", err
+	}
+	return releasePullRequestMarker + "\n" + intro + approvalNote(r.librarianConfig) + freezeOverrideNote(r.frozen, r.cfg.OverrideFreeze) + overrides + notes + status, nil
+}
 
-			break
+// branchPrefix returns the prefix of the names of the release branches, which
+// tells canary releases apart.
+func (r *initRunner) branchPrefix() string {
+	if r.cfg.Channel == config.ChannelCanary {
+		return "librarian-canary"
+	}
+	return "librarian"
+}
+
+// canary reports whether the libraries are released on the canary channel,
+// rather than promoted from it or released as stable versions.
+func (r *initRunner) canary() bool {
+	return !r.promote && r.cfg.Channel == config.ChannelCanary
+}
+
+// releasePolicy returns the release policy of the repository, or nil.
+func (r *initRunner) releasePolicy() *config.ReleasePolicy {
+	if r.librarianConfig == nil {
+		return nil
+	}
+	return r.librarianConfig.ReleasePolicy
+}
+
+// librariesToRelease returns the libraries to consider for the release: the
+// library specified with the -library flag along with the other libraries in
+// its release group, or all libraries if the flag is not specified, except the
+// libraries excluded with comments on the release pull request.
+func (r *initRunner) librariesToRelease() []*config.LibraryState {
+	libraries := r.state.Libraries
+	if r.cfg.Library != "" {
+		library := r.state.LibraryByID(r.cfg.Library)
+		if library == nil {
+			return nil
 		}
+		libraries = releaseGroupMembers(r.state, library)
+	}
+	if r.overrides == nil {
+		return libraries
+	}
+	return slices.DeleteFunc(slices.Clone(libraries), func(library *config.LibraryState) bool {
+		return r.overrides.excludes(library.ID)
+	})
+}
 
-		// Copy all libraries to repository.
-		if err := copyLibraryFiles(r.state, r.repo.GetDir(), library.ID, outputDir); err != nil {
+// planLibraries updates libraries for the release with updateLibraries, a
+// release unit at a time, so that every library is updated after the
+// libraries it depends on.
+func (r *initRunner) planLibraries(libraries []*config.LibraryState) error {
+	units, err := releaseUnits(libraries)
+	if err != nil {
+		return err
+	}
+	for _, unit := range units {
+		if err := r.updateLibraries(unit); err != nil {
 			return err
 		}
 	}
+	return nil
+}
+
+// libraryVersion returns the version to release libraries, a single library
+// or the members of a release group, at: the one specified with the
+// -library-version flag, or else the one set with a comment on the release
+// pull request, or "" to derive it from their changes.
+func (r *initRunner) libraryVersion(libraries []*config.LibraryState) string {
+	if r.cfg.LibraryVersion != "" {
+		return r.cfg.LibraryVersion
+	}
+	return r.overrides.version(libraries)
+}
 
-	return copyGlobalAllowlist(r.librarianConfig, r.repo.GetDir(), outputDir, false)
+// updateLibraries updates libraries, a single library or the members of a
+// release group, for the release with updateLibrary or updateReleaseGroup, and
+// with propagateDependencyReleases, and reverts the update if releasing any of
+// them violates the release policy. Canary releases and promotions update the
+// libraries with updateCanaryLibraries and promoteLibrary instead, and do not
+// propagate releases to dependent libraries.
+// A violation fails the run if the libraries are released on their own with
+// the -library flag, and skips the libraries otherwise.
+func (r *initRunner) updateLibraries(libraries []*config.LibraryState) error {
+	previous := make([]config.LibraryState, len(libraries))
+	for i, library := range libraries {
+		previous[i] = *library
+	}
+	version := r.libraryVersion(libraries)
+	var err error
+	switch {
+	case r.promote:
+		for _, library := range libraries {
+			if err = promoteLibrary(r.repo, library); err != nil {
+				break
+			}
+		}
+	case r.cfg.Channel == config.ChannelCanary:
+		err = updateCanaryLibraries(r.repo, r.librarianConfig, libraries, version)
+	case len(libraries) == 1 && libraries[0].ReleaseGroup == "":
+		err = updateLibrary(r.repo, libraries[0], version)
+	default:
+		err = updateReleaseGroup(r.repo, libraries, version)
+	}
+	if err != nil {
+		return err
+	}
+	if !r.promote && r.cfg.Channel != config.ChannelCanary {
+		if err := propagateDependencyReleases(r.state, libraries, version); err != nil {
+			return err
+		}
+	}
+	var violations []*policyViolation
+	for i, library := range libraries {
+		if !library.ReleaseTriggered {
+			continue
+		}
+		var lastReleaseTag string
+		if previous[i].Version != "" {
+			lastReleaseTag = previousReleaseTag(&previous[i])
+		}
+		libraryViolations, err := checkLibraryReleasePolicy(r.releasePolicy(), r.repo, library.ID, lastReleaseTag, len(library.Changes), now())
+		if err != nil {
+			return err
+		}
+		violations = append(violations, libraryViolations...)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	if r.cfg.Library != "" {
+		return releasePolicyError(violations)
+	}
+	logPolicyViolations(violations)
+	for i, library := range libraries {
+		*library = previous[i]
+	}
+	return nil
 }
 
 // updateLibrary updates the given library in the following way:
@@ -195,7 +504,8 @@ func (r *initRunner) runInitCommand(ctx context.Context, outputDir string) error
 //
 // 2. Override the library version if libraryVersion is not empty.
 //
-// 3. Set the library's release trigger to true.
+// 3. Set the library's release trigger to true, and clear the tag of the
+// previous release recorded when the library was renamed.
 func updateLibrary(repo gitrepo.Repository, library *config.LibraryState, libraryVersion string) error {
 	commits, err := GetConventionalCommitsSinceLastRelease(repo, library)
 	if err != nil {
@@ -215,6 +525,8 @@ func updateLibrary(repo gitrepo.Repository, library *config.LibraryState, librar
 
 	library.Version = nextVersion
 	library.ReleaseTriggered = true
+	// The tag of this release follows the tag format again.
+	library.PreviousReleaseTag = ""
 
 	return nil
 }
@@ -23,7 +23,10 @@ import (
 	"testing"
 
 	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
 
+	"github.com/googleapis/librarian/internal/github"
 	"github.com/googleapis/librarian/internal/gitrepo"
 
 	"github.com/google/go-cmp/cmp"
@@ -175,6 +178,26 @@ func TestInitRun(t *testing.T) {
 				"dir2/file2.txt": "",
 			},
 		},
+		{
+			name: "release blocked by release policy",
+			runner: &initRunner{
+				workRoot:        t.TempDir(),
+				containerClient: &mockContainerClient{},
+				cfg:             &config.Config{},
+				state:           &config.LibrarianState{},
+				repo: &MockRepository{
+					Dir: t.TempDir(),
+				},
+				librarianConfig: &config.LibrarianConfig{
+					ReleasePolicy: &config.ReleasePolicy{
+						BlockedDays: []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+					},
+				},
+				partialRepo: t.TempDir(),
+			},
+			wantErr:    true,
+			wantErrMsg: "release blocked by release policy: blocked_days",
+		},
 		{
 			name: "run release init command for all libraries",
 			runner: &initRunner{
@@ -301,6 +324,7 @@ func TestInitRun(t *testing.T) {
 			runner: &initRunner{
 				workRoot:        os.TempDir(),
 				containerClient: &mockContainerClient{},
+				ghClient:        &mockGitHubClient{},
 				cfg: &config.Config{
 					Push: true,
 				},
@@ -541,7 +565,7 @@ func TestUpdateLibrary(t *testing.T) {
 			if err != nil {
 				t.Errorf("failed to run getChangesOf(): %q", err.Error())
 			}
-			if diff := cmp.Diff(test.want, test.library, cmpopts.IgnoreFields(config.Change{}, "CommitHash")); diff != "" {
+			if diff := cmp.Diff(test.want, test.library, cmpopts.IgnoreFields(config.Change{}, "CommitHash", "Author")); diff != "" {
 				t.Errorf("state mismatch (-want +got):\n%s", diff)
 			}
 		})
@@ -754,3 +778,120 @@ func TestCopyGlobalAllowlist(t *testing.T) {
 		})
 	}
 }
+
+func TestInitRun_PullRequestBody(t *testing.T) {
+	hash1 := plumbing.NewHash("1234567890abcdef")
+	hash2 := plumbing.NewHash("fedcba0987654321")
+	for _, test := range []struct {
+		name  string
+		state *config.LibrarianState
+		want  []string
+		// wantMetadata are the versions of the release metadata, by library.
+		wantMetadata map[string]string
+	}{
+		{
+			name: "single library",
+			state: &config.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*config.LibraryState{
+					{ID: "a", Version: "1.0.0", SourceRoots: []string{"a"}},
+				},
+			},
+			want: []string{
+				releasePullRequestMarker,
+				"<details><summary>a: 1.1.0</summary>",
+				"### Features\n* new feature",
+				releaseStatusBegin,
+			},
+			wantMetadata: map[string]string{"a": "1.1.0"},
+		},
+		{
+			name: "release group",
+			state: &config.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*config.LibraryState{
+					{ID: "a", Version: "1.0.0", SourceRoots: []string{"a"}, ReleaseGroup: "core"},
+					{ID: "b", Version: "2.0.0", SourceRoots: []string{"b"}, ReleaseGroup: "core"},
+				},
+			},
+			want: []string{
+				"Release group core, released together: a 1.1.0, b 2.1.0",
+				"<details><summary>a: 1.1.0</summary>",
+				"<details><summary>b: 2.1.0</summary>",
+				"release_group: core",
+			},
+			wantMetadata: map[string]string{"a": "1.1.0", "b": "2.1.0"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repoDir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(repoDir, config.LibrarianDir), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := saveLibrarianState(context.Background(), repoDir, test.state); err != nil {
+				t.Fatal(err)
+			}
+			for _, library := range test.state.Libraries {
+				path := filepath.Join(repoDir, library.ID, "file.txt")
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(library.ID), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			repo := &MockRepository{
+				Dir:          repoDir,
+				AddAllStatus: git.Status{"a/file.txt": &git.FileStatus{Worktree: git.Modified}},
+				RemotesValue: []*git.Remote{git.NewRemote(nil, &gitconfig.RemoteConfig{Name: "origin", URLs: []string{"https://github.com/owner/repo.git"}})},
+				GetCommitsForPathsSinceTagValueByTag: map[string][]*gitrepo.Commit{
+					"a-1.0.0": {{Message: "feat: new feature", Hash: hash1}},
+					"b-2.0.0": {{Message: "fix: a bug fix", Hash: hash2}},
+				},
+				ChangedFilesInCommitValueByHash: map[string][]string{
+					hash1.String(): {"a/file.txt"},
+					hash2.String(): {"b/file.txt"},
+				},
+			}
+			ghClient := &mockGitHubClient{
+				createdPR: &github.PullRequestMetadata{Number: 1, Repo: &github.Repository{Owner: "owner", Name: "repo"}},
+			}
+			r := &initRunner{
+				cfg:             &config.Config{Push: true},
+				state:           test.state,
+				repo:            repo,
+				ghClient:        ghClient,
+				containerClient: &mockContainerClient{},
+				librarianConfig: &config.LibrarianConfig{},
+				workRoot:        t.TempDir(),
+				partialRepo:     t.TempDir(),
+			}
+			if err := r.run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			body := ghClient.createdPullRequestBody
+			if !strings.HasPrefix(body, releasePullRequestMarker) {
+				t.Errorf("body does not start with the release pull request marker:\n%s", body)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("body does not contain %q:\n%s", want, body)
+				}
+			}
+			metadata, err := parseReleaseMetadata(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if metadata == nil {
+				t.Fatalf("body has no release metadata:\n%s", body)
+			}
+			got := make(map[string]string)
+			for _, library := range metadata.Libraries {
+				got[library.ID] = library.Version
+			}
+			if diff := cmp.Diff(test.wantMetadata, got); diff != "" {
+				t.Errorf("release metadata mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
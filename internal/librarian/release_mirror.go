@@ -0,0 +1,220 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// cmdReconcileMirrors is the command for the `release reconcile-mirrors`
+// subcommand.
+var cmdReconcileMirrors = &cli.Command{
+	Short:     "reconcile-mirrors pushes the commits and tags missing from the mirrors.",
+	UsageLine: "librarian release reconcile-mirrors [flags]",
+	Long: `Pushes the commits and tags of the repository which are missing from its mirrors.
+
+The mirrors are the secondary remotes listed under "mirrors" in
+.librarian/config.yaml, to which "librarian release tag-and-release" pushes the
+tags and the commit of each release. A mirror which cannot be pushed to does not
+fail the release, so that a host being down does not block it; this command
+pushes what such failures missed.
+
+For each mirror, the tags of the repository which the mirror does not have are
+pushed, if the mirror receives tags, and the HEAD commit of the repository is
+pushed to the branch of the mirror, if the mirror receives commits and its
+branch is not at that commit. Neither is force-pushed: a mirror whose branch
+diverged is reported as failed. The tags are fetched from origin first.
+
+Only the mirror specified with "-mirror" is reconciled, if any. With
+"-dry-run", what would be pushed is only reported.
+
+The password of a mirror is read from the environment variable named by its
+"password_env", and its SSH key from its "ssh_key_file", or "-ssh-key".`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newCommandRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return reconcileMirrors(ctx, runner.cfg, runner.repo, runner.librarianConfig)
+	},
+}
+
+func init() {
+	cmdReconcileMirrors.Init()
+	fs := cmdReconcileMirrors.Flags
+	cfg := cmdReconcileMirrors.Config
+
+	addFlagAuditLog(fs, cfg)
+	addFlagDryRun(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagMirror(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+// mirrorRelease pushes the release tags, and the released commit, to the
+// mirrors of lc. Each mirror is pushed to independently: the failure of one
+// is logged and recorded in the audit log, and does not stop the others. The
+// returned error joins the failures.
+func mirrorRelease(ctx context.Context, cfg *config.Config, repo gitrepo.Repository, lc *config.LibrarianConfig, commitish string, tags []string) error {
+	if lc == nil || len(lc.Mirrors) == 0 {
+		return nil
+	}
+	// The tags of GitHub releases are created on origin.
+	if err := repo.FetchTags(tags); err != nil {
+		return err
+	}
+	var errs []error
+	for _, mirror := range lc.Mirrors {
+		var refSpecs []string
+		if mirror.Pushes(config.MirrorTags) {
+			for _, tag := range tags {
+				refSpecs = append(refSpecs, tagRefSpec(tag))
+			}
+		}
+		if mirror.Pushes(config.MirrorCommits) && commitish != "" {
+			refSpecs = append(refSpecs, fmt.Sprintf("%s:refs/heads/%s", commitish, mirror.TargetBranch()))
+		}
+		if err := pushToMirror(ctx, cfg, repo, mirror, refSpecs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reconcileMirrors pushes the tags and the HEAD commit of repo which are
+// missing from the mirrors of lc, or from the mirror of -mirror.
+func reconcileMirrors(ctx context.Context, cfg *config.Config, repo gitrepo.Repository, lc *config.LibrarianConfig) error {
+	var mirrors []*config.Mirror
+	if lc != nil {
+		mirrors = lc.Mirrors
+	}
+	if cfg.Mirror != "" {
+		i := slices.IndexFunc(mirrors, func(m *config.Mirror) bool { return m.Name == cfg.Mirror })
+		if i == -1 {
+			return failure.New(failure.UserConfig, fmt.Errorf("mirror %q not found in config.yaml", cfg.Mirror))
+		}
+		mirrors = mirrors[i : i+1]
+	}
+	if len(mirrors) == 0 {
+		slog.Info("No mirrors configured, nothing to reconcile")
+		return nil
+	}
+	if err := repo.FetchTags(nil); err != nil {
+		return err
+	}
+	tags, err := repo.Tags()
+	if err != nil {
+		return err
+	}
+	head, err := repo.HeadHash()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, mirror := range mirrors {
+		refs, err := repo.MirrorRefs(gitMirror(cfg, mirror))
+		if err != nil {
+			slog.Warn("failed to list the refs of mirror", "mirror", mirror.Name, "error", err)
+			errs = append(errs, err)
+			continue
+		}
+		var refSpecs []string
+		if mirror.Pushes(config.MirrorTags) {
+			for _, tag := range tags {
+				if _, ok := refs["refs/tags/"+tag]; !ok {
+					refSpecs = append(refSpecs, tagRefSpec(tag))
+				}
+			}
+		}
+		if branch := "refs/heads/" + mirror.TargetBranch(); mirror.Pushes(config.MirrorCommits) && refs[branch] != head {
+			refSpecs = append(refSpecs, fmt.Sprintf("%s:%s", head, branch))
+		}
+		if len(refSpecs) == 0 {
+			slog.Info("Mirror is up to date", "mirror", mirror.Name)
+			continue
+		}
+		if cfg.DryRun {
+			slog.Info("Would push to mirror", "mirror", mirror.Name, "refspecs", refSpecs)
+			continue
+		}
+		if err := pushToMirror(ctx, cfg, repo, mirror, refSpecs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to reconcile %d of %d mirrors: %w", len(errs), len(mirrors), errors.Join(errs...))
+	}
+	return nil
+}
+
+// pushToMirror pushes refSpecs to mirror, and records the push, or its
+// failure, in the audit log.
+func pushToMirror(ctx context.Context, cfg *config.Config, repo gitrepo.Repository, mirror *config.Mirror, refSpecs []string) error {
+	if len(refSpecs) == 0 {
+		return nil
+	}
+	details := map[string]string{
+		"mirror":   mirror.Name,
+		"refspecs": strings.Join(refSpecs, " "),
+	}
+	if err := repo.PushToMirror(gitMirror(cfg, mirror), refSpecs); err != nil {
+		slog.Warn("failed to push to mirror", "mirror", mirror.Name, "error", err)
+		details["error"] = err.Error()
+		auditLogFromContext(ctx).record(auditMirrorPushFailed, details)
+		return err
+	}
+	auditLogFromContext(ctx).record(auditMirrorPushed, details)
+	return nil
+}
+
+// gitMirror returns the remote of mirror, with its credentials.
+func gitMirror(cfg *config.Config, mirror *config.Mirror) *gitrepo.Mirror {
+	m := &gitrepo.Mirror{
+		Name:     mirror.Name,
+		URL:      mirror.URL,
+		Username: mirror.Username,
+	}
+	if mirror.PasswordEnv != "" {
+		m.Password = os.Getenv(mirror.PasswordEnv)
+	}
+	if mirror.SSHKeyFile != "" {
+		m.SSH = &gitrepo.SSHOptions{KeyFile: mirror.SSHKeyFile, KnownHostsFile: cfg.SSHKnownHosts}
+	}
+	return m
+}
+
+// tagRefSpec returns the refspec which pushes the tag with the given name.
+func tagRefSpec(tag string) string {
+	return fmt.Sprintf("refs/tags/%s:refs/tags/%s", tag, tag)
+}
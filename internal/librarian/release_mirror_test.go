@@ -0,0 +1,151 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// newMirroredTestRepo returns a test repository whose origin is a local bare
+// repository, with its HEAD pushed to origin, and the directory of origin.
+func newMirroredTestRepo(t *testing.T) (gitrepo.Repository, string) {
+	t.Helper()
+	repo := newTestGitRepo(t)
+	origin := newBareTestRepo(t)
+	runGit(t, repo.GetDir(), "remote", "set-url", "origin", origin)
+	runGit(t, repo.GetDir(), "push", "origin", "HEAD:refs/heads/main")
+	return repo, origin
+}
+
+func newBareTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	runGit(t, dir, "init", "--bare")
+	return dir
+}
+
+// mirrorRefs returns the refs of the mirror at url.
+func mirrorRefs(t *testing.T, repo gitrepo.Repository, url string) map[string]string {
+	t.Helper()
+	refs, err := repo.MirrorRefs(&gitrepo.Mirror{Name: "test", URL: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return refs
+}
+
+func TestMirrorRelease(t *testing.T) {
+	t.Parallel()
+	repo, origin := newMirroredTestRepo(t)
+	head, err := repo.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The tag is created on origin, as GitHub does for releases.
+	runGit(t, origin, "tag", "some-library-v1.0.0", head)
+
+	all := newBareTestRepo(t)
+	tagsOnly := newBareTestRepo(t)
+	lc := &config.LibrarianConfig{
+		Mirrors: []*config.Mirror{
+			{Name: "unreachable", URL: filepath.Join(t.TempDir(), "missing")},
+			{Name: "all", URL: all, Branch: "release"},
+			{Name: "tags-only", URL: tagsOnly, Push: []string{config.MirrorTags}},
+		},
+	}
+	err = mirrorRelease(t.Context(), &config.Config{}, repo, lc, head, []string{"some-library-v1.0.0"})
+	if err == nil || !strings.Contains(err.Error(), "unreachable") {
+		t.Errorf("mirrorRelease() error = %v, want error of mirror unreachable", err)
+	}
+	want := map[string]string{
+		"refs/tags/some-library-v1.0.0": head,
+		"refs/heads/release":            head,
+	}
+	if diff := cmp.Diff(want, mirrorRefs(t, repo, all)); diff != "" {
+		t.Errorf("refs of mirror all mismatch (-want +got):\n%s", diff)
+	}
+	want = map[string]string{
+		"refs/tags/some-library-v1.0.0": head,
+	}
+	if diff := cmp.Diff(want, mirrorRefs(t, repo, tagsOnly)); diff != "" {
+		t.Errorf("refs of mirror tags-only mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := mirrorRelease(t.Context(), &config.Config{}, repo, nil, head, []string{"some-library-v1.0.0"}); err != nil {
+		t.Errorf("mirrorRelease() without mirrors error = %v", err)
+	}
+}
+
+func TestReconcileMirrors(t *testing.T) {
+	t.Parallel()
+	repo, origin := newMirroredTestRepo(t)
+	head, err := repo.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, origin, "tag", "some-library-v1.0.0", head)
+	runGit(t, origin, "tag", "some-library-v1.1.0", head)
+	mirror := newBareTestRepo(t)
+	lc := &config.LibrarianConfig{
+		Mirrors: []*config.Mirror{
+			{Name: "mirror", URL: mirror},
+			{Name: "unreachable", URL: filepath.Join(t.TempDir(), "missing")},
+		},
+	}
+	// The mirror missed the push of some-library-v1.1.0.
+	if err := repo.FetchTags(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.PushToMirror(&gitrepo.Mirror{Name: "mirror", URL: mirror}, []string{tagRefSpec("some-library-v1.0.0")}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{Mirror: "mirror", DryRun: true}
+	if err := reconcileMirrors(t.Context(), cfg, repo, lc); err != nil {
+		t.Fatalf("reconcileMirrors() with -dry-run error = %v", err)
+	}
+	if got := mirrorRefs(t, repo, mirror); len(got) != 1 {
+		t.Errorf("reconcileMirrors() with -dry-run pushed, mirror refs = %v", got)
+	}
+
+	cfg.DryRun = false
+	if err := reconcileMirrors(t.Context(), cfg, repo, lc); err != nil {
+		t.Fatalf("reconcileMirrors() error = %v", err)
+	}
+	want := map[string]string{
+		"refs/tags/some-library-v1.0.0": head,
+		"refs/tags/some-library-v1.1.0": head,
+		"refs/heads/main":               head,
+	}
+	if diff := cmp.Diff(want, mirrorRefs(t, repo, mirror)); diff != "" {
+		t.Errorf("refs of mirror mismatch (-want +got):\n%s", diff)
+	}
+
+	cfg.Mirror = ""
+	if err := reconcileMirrors(t.Context(), cfg, repo, lc); err == nil || !strings.Contains(err.Error(), "failed to reconcile 1 of 2 mirrors") {
+		t.Errorf("reconcileMirrors() error = %v, want failure of mirror unreachable", err)
+	}
+	cfg.Mirror = "unknown"
+	if err := reconcileMirrors(t.Context(), cfg, repo, lc); failure.CategoryOf(err) != failure.UserConfig {
+		t.Errorf("reconcileMirrors() of an unknown mirror error = %v, want %q error", err, failure.UserConfig)
+	}
+}
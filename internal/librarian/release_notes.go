@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"slices"
 	"strings"
 	"time"
 
@@ -26,6 +27,8 @@ import (
 	"github.com/googleapis/librarian/internal/conventionalcommits"
 	"github.com/googleapis/librarian/internal/github"
 	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/semver"
+	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -53,115 +56,244 @@ var (
 		"docs",
 	}
 
-	releaseNotesTemplate = template.Must(template.New("releaseNotes").Funcs(template.FuncMap{
-		"shortSHA": func(sha string) string {
-			if len(sha) < 7 {
-				return sha
-			}
-			return sha[:7]
-		},
-	}).Parse(`## [{{.NewVersion}}]({{"https://github.com/"}}{{.Repo.Owner}}/{{.Repo.Name}}/compare/{{.PreviousTag}}...{{.NewTag}}) ({{.Date}})
-{{- range .Sections -}}
-{{- if .Commits -}}
-{{- if .Heading}}
+	// releaseNotesTemplate is the default template of the release notes of a
+	// library, which releaseNotesTemplateFile replaces.
+	releaseNotesTemplate = template.Must(newReleaseNotesTemplate("releaseNotes").Parse(`{{if .PreviousTag}}## [{{.NewVersion}}]({{.Repo.URL}}/compare/{{.PreviousTag}}...{{.NewTag}}){{else}}## {{.NewVersion}}{{end}} ({{.Date}})
+{{- range .Sections}}
 
 ### {{.Heading}}
-{{end}}
+{{- range .Commits}}
+* {{.Description}} ([{{shortSHA .SHA}}]({{$.Repo.URL}}/commit/{{.SHA}}))
+{{- with index .Footers "Source-Link"}} ([source]({{.}})){{end}}
+{{- with index .Footers "PiperOrigin-RevId"}} (PiperOrigin-RevId: {{.}}){{end}}
+{{- with $.Bugs.Links .Bugs}} ({{range $i, $bug := .}}{{if $i}}, {{end}}{{if $bug.URL}}[{{$bug.ID}}]({{$bug.URL}}){{else}}{{$bug.ID}}{{end}}{{end}}){{end}}
+{{- end}}
+{{- end}}`))
+)
 
-{{- range .Commits -}}
-* {{.Description}} ([{{shortSHA .SHA}}]({{"https://github.com/"}}{{$.Repo.Owner}}/{{$.Repo.Name}}/commit/{{.SHA}}))
-{{- end -}}
-{{- end -}}
-{{- end -}}`))
+// breakingChangesHeading is the heading of the release notes section listing
+// breaking changes, ahead of the sections by commit type.
+const breakingChangesHeading = "⚠ BREAKING CHANGES"
+
+// The markers surrounding the machine-readable release metadata in the body of
+// a release pull request. The metadata is in an HTML comment, so that it is
+// not rendered.
+const (
+	releaseMetadataBegin = "<!-- BEGIN LIBRARIAN RELEASE METADATA"
+	releaseMetadataEnd   = "END LIBRARIAN RELEASE METADATA -->"
 )
 
+// releaseMetadata is the machine-readable summary of a release pull request,
+// for consumption by automation.
+type releaseMetadata struct {
+	Libraries []*libraryReleaseMetadata `yaml:"libraries"`
+}
+
+// libraryReleaseMetadata describes the release of a single library.
+type libraryReleaseMetadata struct {
+	ID              string `yaml:"id"`
+	Version         string `yaml:"version"`
+	PreviousVersion string `yaml:"previous_version"`
+	Tag             string `yaml:"tag"`
+	Breaking        bool   `yaml:"breaking"`
+	ReleaseGroup    string `yaml:"release_group,omitempty"`
+}
+
 // FormatReleaseNotes generates the body for a release pull request.
-func FormatReleaseNotes(repo gitrepo.Repository, state *config.LibrarianState) (string, error) {
+//
+// The release notes of each library are in a collapsible section, and are
+// followed by the release metadata in YAML, which can be read back with
+// parseReleaseMetadata. The libraries of a release group are presented
+// together, under a line listing them, and their versions are bumped by the
+// highest change among them. The links in the release notes point to the
+// GitHub instance at host, e.g. github.com, and the referenced bugs link to
+// the bug trackers of librarianConfig. The release notes of each library are
+// rendered in format, or in the default format if it is nil.
+//
+// The libraries in state are at their versions before the release. A library
+// is released at its version in versions, keyed by library ID, if any, e.g.
+// the version planned by release init, and otherwise at the version derived
+// from its changes.
+func FormatReleaseNotes(repo gitrepo.Repository, librarianConfig *config.LibrarianConfig, format *releaseNotesFormat, state *config.LibrarianState, host string, versions map[string]string) (string, error) {
 	var body bytes.Buffer
 
 	librarianVersion := cli.Version()
 	fmt.Fprintf(&body, "Librarian Version: %s\n", librarianVersion)
 	fmt.Fprintf(&body, "Language Image: %s\n\n", state.Image)
 
+	groupChanges, err := releaseGroupChangeLevels(repo, state)
+	if err != nil {
+		return "", fmt.Errorf("failed to format release notes: %w", err)
+	}
+	var released []*config.LibraryState
 	for _, library := range state.Libraries {
-		if !library.ReleaseTriggered {
-			continue
+		if library.ReleaseTriggered {
+			released = append(released, library)
 		}
+	}
+	metadata := &releaseMetadata{}
+	units, err := releaseUnits(released)
+	if err != nil {
+		return "", err
+	}
+	for _, unit := range units {
+		var sections bytes.Buffer
+		var members []string
+		for _, library := range unit {
+			notes, release, err := formatLibraryReleaseNotes(repo, librarianConfig, format, library, host, max(groupChanges[library.ReleaseGroup], dependencyChangeLevel(state, library)), versions[library.ID])
+			if err != nil {
+				return "", fmt.Errorf("failed to format release notes for library %s: %w", library.ID, err)
+			}
+			fmt.Fprintf(&sections, "<details><summary>%s: %s</summary>\n\n", library.ID, release.Version)
 
-		notes, newVersion, err := formatLibraryReleaseNotes(repo, library)
+			sections.WriteString(notes)
+			sections.WriteString("\n\n</details>")
+
+			sections.WriteString("\n")
+			metadata.Libraries = append(metadata.Libraries, release)
+			members = append(members, fmt.Sprintf("%s %s", library.ID, release.Version))
+		}
+		if group := unit[0].ReleaseGroup; group != "" {
+			fmt.Fprintf(&body, "Release group %s, released together: %s\n\n", group, strings.Join(members, ", "))
+		}
+		body.Write(sections.Bytes())
+	}
+	if len(metadata.Libraries) > 0 {
+		data, err := yaml.Marshal(metadata)
 		if err != nil {
-			return "", fmt.Errorf("failed to format release notes for library %s: %w", library.ID, err)
+			return "", fmt.Errorf("failed to marshal release metadata: %w", err)
 		}
-		fmt.Fprintf(&body, "<details><summary>%s: %s</summary>\n\n", library.ID, newVersion)
-
-		body.WriteString(notes)
-		body.WriteString("\n\n</details>")
-
-		body.WriteString("\n")
+		fmt.Fprintf(&body, "\n%s\n%s%s\n", releaseMetadataBegin, data, releaseMetadataEnd)
 	}
 	return body.String(), nil
 }
 
+// parseReleaseMetadata returns the release metadata in the body of a release
+// pull request, or nil if the body does not contain any.
+func parseReleaseMetadata(body string) (*releaseMetadata, error) {
+	_, rest, found := strings.Cut(body, releaseMetadataBegin)
+	if !found {
+		return nil, nil
+	}
+	data, _, found := strings.Cut(rest, releaseMetadataEnd)
+	if !found {
+		return nil, fmt.Errorf("release metadata is not terminated by %q", releaseMetadataEnd)
+	}
+	metadata := &releaseMetadata{}
+	if err := yaml.Unmarshal([]byte(data), metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse release metadata: %w", err)
+	}
+	return metadata, nil
+}
+
 // formatLibraryReleaseNotes generates release notes in Markdown format for a single library.
-// It returns the generated release notes and the new version string.
-func formatLibraryReleaseNotes(repo gitrepo.Repository, library *config.LibraryState) (string, string, error) {
-	ghRepo, err := github.FetchGitHubRepoFromRemote(repo)
+// It returns the generated release notes and the metadata of the release. The
+// version of the library is bumped by its highest change since its last
+// release, or by groupChange, the highest change in its release group or due
+// to the release of its dependencies, if it is higher, unless version is set.
+func formatLibraryReleaseNotes(repo gitrepo.Repository, librarianConfig *config.LibrarianConfig, format *releaseNotesFormat, library *config.LibraryState, host string, groupChange semver.ChangeLevel, version string) (string, *libraryReleaseMetadata, error) {
+	ghRepo, err := github.FetchGitHubRepoFromHostRemote(repo, host)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to fetch github repo from remote: %w", err)
+		return "", nil, fmt.Errorf("failed to fetch github repo from remote: %w", err)
 	}
-	previousTag := formatTag(library, "")
+	previousTag := previousReleaseTag(library)
 	commits, err := GetConventionalCommitsSinceLastRelease(repo, library)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to get conventional commits for library %s: %w", library.ID, err)
+		return "", nil, fmt.Errorf("failed to get conventional commits for library %s: %w", library.ID, err)
 	}
-	newVersion, err := NextVersion(commits, library.Version, "")
-	if err != nil {
-		return "", "", fmt.Errorf("failed to get next version for library %s: %w", library.ID, err)
+	newVersion := version
+	if newVersion == "" {
+		if newVersion, err = semver.DeriveNext(max(getHighestChange(commits), groupChange), library.Version); err != nil {
+			return "", nil, fmt.Errorf("failed to get next version for library %s: %w", library.ID, err)
+		}
 	}
 	newTag := formatTag(library, newVersion)
 
+	var out bytes.Buffer
+	data := &releaseNotesData{
+		NewVersion:  newVersion,
+		PreviousTag: previousTag,
+		NewTag:      newTag,
+		Repo:        ghRepo,
+		Date:        format.formatDate(time.Now()),
+		Sections:    format.sectionsOf(commits),
+		Bugs:        &bugLinker{librarianConfig: librarianConfig, repo: ghRepo},
+	}
+	if err := format.execute(&out, data); err != nil {
+		// This should not happen, as the templates are validated when they
+		// are loaded.
+		return "", nil, fmt.Errorf("error executing template: %v", err)
+	}
+
+	release := &libraryReleaseMetadata{
+		ID:              library.ID,
+		Version:         newVersion,
+		PreviousVersion: library.Version,
+		Tag:             newTag,
+		Breaking:        slices.ContainsFunc(commits, func(c *conventionalcommits.ConventionalCommit) bool { return c.IsBreaking }),
+		ReleaseGroup:    library.ReleaseGroup,
+	}
+	return strings.TrimSpace(out.String()), release, nil
+}
+
+// releaseNotesData is the data of releaseNotesTemplate, and of the templates
+// of the language repositories which replace it.
+type releaseNotesData struct {
+	NewVersion string
+	// PreviousTag is the tag of the previous release, if any. Without it, the
+	// heading does not link to the comparison of the two releases.
+	PreviousTag string
+	NewTag      string
+	Repo        *github.Repository
+	Date        string
+	Sections    []releaseNoteSection
+	// Bugs links the bugs referenced by the commits.
+	Bugs *bugLinker
+}
+
+// releaseNoteSection is a section of the release notes, listing the commits
+// under a heading.
+type releaseNoteSection struct {
+	Heading string
+	Commits []*conventionalcommits.ConventionalCommit
+}
+
+// releaseNoteSectionsWith groups commits into the sections of the release
+// notes: the breaking changes, if any, first, under breakingHeading, then the
+// commits by type in the order of sections. Commits of other types are left
+// out.
+func releaseNoteSectionsWith(commits []*conventionalcommits.ConventionalCommit, breakingHeading string, sections []*releaseNotesSectionConfig) []releaseNoteSection {
 	commitsByType := make(map[string][]*conventionalcommits.ConventionalCommit)
+	var breakingChanges []*conventionalcommits.ConventionalCommit
 	for _, commit := range commits {
 		commitsByType[commit.Type] = append(commitsByType[commit.Type], commit)
+		if commit.IsBreaking {
+			breakingChanges = append(breakingChanges, commit)
+		}
 	}
 
-	type releaseNoteSection struct {
-		Heading string
-		Commits []*conventionalcommits.ConventionalCommit
+	var noteSections []releaseNoteSection
+	if len(breakingChanges) > 0 {
+		noteSections = append(noteSections, releaseNoteSection{
+			Heading: breakingHeading,
+			Commits: breakingChanges,
+		})
 	}
-	var sections []releaseNoteSection
-	// Group commits by type, according to commitTypeOrder, to be used in the release notes.
-	for _, ct := range commitTypeOrder {
-		displayName, headingOK := commitTypeToHeading[ct]
-		typedCommits, commitsOK := commitsByType[ct]
-		if headingOK && commitsOK {
-			sections = append(sections, releaseNoteSection{
-				Heading: displayName,
+	for _, section := range sections {
+		if typedCommits, ok := commitsByType[section.Type]; ok {
+			noteSections = append(noteSections, releaseNoteSection{
+				Heading: section.Heading,
 				Commits: typedCommits,
 			})
 		}
 	}
+	return noteSections
+}
 
-	var out bytes.Buffer
-	data := struct {
-		NewVersion  string
-		PreviousTag string
-		NewTag      string
-		Repo        *github.Repository
-		Date        string
-		Sections    []releaseNoteSection
-	}{
-		NewVersion:  newVersion,
-		PreviousTag: previousTag,
-		NewTag:      newTag,
-		Repo:        ghRepo,
-		Date:        time.Now().Format("2006-01-02"),
-		Sections:    sections,
-	}
-	if err := releaseNotesTemplate.Execute(&out, data); err != nil {
-		// This should not happen, as the template is valid and the data is structured correctly.
-		return "", "", fmt.Errorf("error executing template: %v", err)
+// shortSHA abbreviates a commit hash to 7 characters.
+func shortSHA(sha string) string {
+	if len(sha) < 7 {
+		return sha
 	}
-
-	return strings.TrimSpace(out.String()), newVersion, nil
+	return sha[:7]
 }
@@ -0,0 +1,206 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/conventionalcommits"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/github"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// releaseNotesTemplateFile is the template of the release notes of a
+	// library, in the .librarian/templates directory of the language
+	// repository, which replaces releaseNotesTemplate.
+	releaseNotesTemplateFile = "templates/release-notes.md.tmpl"
+	// releaseNotesConfigFile configures the sections and the dates of the
+	// release notes, in the .librarian/templates directory of the language
+	// repository.
+	releaseNotesConfigFile = "templates/release-notes.yaml"
+	// defaultReleaseNotesDateFormat is the layout of the dates of releases.
+	defaultReleaseNotesDateFormat = "2006-01-02"
+)
+
+// releaseNotesConfig is the content of releaseNotesConfigFile. Empty fields
+// keep their defaults.
+type releaseNotesConfig struct {
+	// BreakingHeading is the heading of the section listing the breaking
+	// changes, e.g. "💥 Breaking changes".
+	BreakingHeading string `yaml:"breaking_heading,omitempty"`
+	// Sections are the sections of the commits by type, in the order in which
+	// they appear. Commits of other types are left out of the release notes.
+	Sections []*releaseNotesSectionConfig `yaml:"sections,omitempty"`
+	// DateFormat is the Go layout of the dates of releases, e.g. "02.01.2006".
+	DateFormat string `yaml:"date_format,omitempty"`
+}
+
+// releaseNotesSectionConfig is the section of the commits of a type.
+type releaseNotesSectionConfig struct {
+	// Type is the conventional commit type, e.g. "feat".
+	Type string `yaml:"type"`
+	// Heading is the heading of the section, e.g. "✨ Features".
+	Heading string `yaml:"heading"`
+}
+
+// releaseNotesFormat is how the release notes and changelogs of a language
+// repository are rendered. The zero value of a field means its default.
+type releaseNotesFormat struct {
+	template        *template.Template
+	breakingHeading string
+	sections        []*releaseNotesSectionConfig
+	dateFormat      string
+}
+
+// loadReleaseNotesFormat loads the format of the release notes of the language
+// repository at repoDir from its .librarian/templates directory, and validates
+// it. The defaults are used for the files which do not exist.
+func loadReleaseNotesFormat(repoDir string) (*releaseNotesFormat, error) {
+	format := &releaseNotesFormat{}
+	librarianDir := filepath.Join(repoDir, config.LibrarianDir)
+	data, err := os.ReadFile(filepath.Join(librarianDir, releaseNotesConfigFile))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		notesConfig, err := parseReleaseNotesConfig(data)
+		if err != nil {
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("invalid %s: %w", releaseNotesConfigFile, err))
+		}
+		format.breakingHeading = notesConfig.BreakingHeading
+		format.sections = notesConfig.Sections
+		format.dateFormat = notesConfig.DateFormat
+	}
+	data, err = os.ReadFile(filepath.Join(librarianDir, releaseNotesTemplateFile))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if format.template, err = newReleaseNotesTemplate(releaseNotesTemplateFile).Parse(string(data)); err != nil {
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("invalid %s: %w", releaseNotesTemplateFile, err))
+		}
+		// Executing the template with sample data reports references to
+		// unknown fields now, rather than when a release is made.
+		if err := format.template.Execute(io.Discard, sampleReleaseNotesData(format)); err != nil {
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("invalid %s: %w", releaseNotesTemplateFile, err))
+		}
+	}
+	return format, nil
+}
+
+// parseReleaseNotesConfig parses and validates the content of
+// releaseNotesConfigFile.
+func parseReleaseNotesConfig(data []byte) (*releaseNotesConfig, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	notesConfig := &releaseNotesConfig{}
+	if err := decoder.Decode(notesConfig); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	types := make(map[string]bool)
+	for i, section := range notesConfig.Sections {
+		if section.Type == "" || section.Heading == "" {
+			return nil, fmt.Errorf("section at index %d requires a type and a heading", i)
+		}
+		if types[section.Type] {
+			return nil, fmt.Errorf("duplicate section of type %q", section.Type)
+		}
+		types[section.Type] = true
+	}
+	// A layout without any date element formats every date as itself.
+	if notesConfig.DateFormat != "" && time.Date(2001, 3, 4, 0, 0, 0, 0, time.UTC).Format(notesConfig.DateFormat) == notesConfig.DateFormat {
+		return nil, fmt.Errorf("date_format %q contains no date", notesConfig.DateFormat)
+	}
+	return notesConfig, nil
+}
+
+// newReleaseNotesTemplate returns an empty template of release notes, with the
+// functions available to the templates.
+func newReleaseNotesTemplate(name string) *template.Template {
+	return template.New(name).Funcs(template.FuncMap{
+		"shortSHA": shortSHA,
+	})
+}
+
+// sampleReleaseNotesData returns the data of the release notes of a release
+// with every kind of section, to validate templates with.
+func sampleReleaseNotesData(format *releaseNotesFormat) *releaseNotesData {
+	commit := &conventionalcommits.ConventionalCommit{
+		Type:        "feat",
+		Description: "add a feature",
+		SHA:         "1234567890abcdef1234567890abcdef12345678",
+		Footers:     map[string]string{"Source-Link": "https://example.com/commit"},
+		IsBreaking:  true,
+	}
+	repo := &github.Repository{Owner: "owner", Name: "repo"}
+	return &releaseNotesData{
+		NewVersion:  "1.1.0",
+		PreviousTag: "library-v1.0.0",
+		NewTag:      "library-v1.1.0",
+		Repo:        repo,
+		Date:        format.formatDate(time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)),
+		Sections:    format.sectionsOf([]*conventionalcommits.ConventionalCommit{commit}),
+		Bugs:        &bugLinker{repo: repo},
+	}
+}
+
+// execute renders the release notes of data.
+func (f *releaseNotesFormat) execute(w io.Writer, data *releaseNotesData) error {
+	if f == nil || f.template == nil {
+		return releaseNotesTemplate.Execute(w, data)
+	}
+	return f.template.Execute(w, data)
+}
+
+// formatDate formats the date of a release.
+func (f *releaseNotesFormat) formatDate(date time.Time) string {
+	if f == nil || f.dateFormat == "" {
+		return date.Format(defaultReleaseNotesDateFormat)
+	}
+	return date.Format(f.dateFormat)
+}
+
+// sectionsOf groups commits into the sections of the release notes: the
+// breaking changes, if any, first, then the commits by type in the order of
+// the configured sections, or of commitTypeOrder by default.
+func (f *releaseNotesFormat) sectionsOf(commits []*conventionalcommits.ConventionalCommit) []releaseNoteSection {
+	breakingHeading := breakingChangesHeading
+	var sections []*releaseNotesSectionConfig
+	if f != nil {
+		if f.breakingHeading != "" {
+			breakingHeading = f.breakingHeading
+		}
+		sections = f.sections
+	}
+	if len(sections) == 0 {
+		for _, commitType := range commitTypeOrder {
+			sections = append(sections, &releaseNotesSectionConfig{Type: commitType, Heading: commitTypeToHeading[commitType]})
+		}
+	}
+	return releaseNoteSectionsWith(commits, breakingHeading, sections)
+}
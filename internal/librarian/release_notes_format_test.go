@@ -0,0 +1,207 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/conventionalcommits"
+	"github.com/googleapis/librarian/internal/github"
+)
+
+func writeReleaseNotesFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	repoDir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(repoDir, config.LibrarianDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repoDir
+}
+
+func TestLoadReleaseNotesFormat(t *testing.T) {
+	t.Parallel()
+	commits := []*conventionalcommits.ConventionalCommit{
+		{Type: "fix", Description: "fix a bug", SHA: "1111111111111111111111111111111111111111"},
+		{Type: "feat", Description: "add a feature", SHA: "2222222222222222222222222222222222222222", IsBreaking: true},
+		{Type: "docs", Description: "document it", SHA: "3333333333333333333333333333333333333333"},
+	}
+	repo := &github.Repository{Owner: "owner", Name: "repo"}
+	for _, test := range []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "defaults",
+			want: `## [1.1.0](https://github.com/owner/repo/compare/lib-v1.0.0...lib-v1.1.0) (2025-03-04)
+
+### ⚠ BREAKING CHANGES
+* add a feature ([2222222](https://github.com/owner/repo/commit/2222222222222222222222222222222222222222))
+
+### Features
+* add a feature ([2222222](https://github.com/owner/repo/commit/2222222222222222222222222222222222222222))
+
+### Bug Fixes
+* fix a bug ([1111111](https://github.com/owner/repo/commit/1111111111111111111111111111111111111111))
+
+### Documentation
+* document it ([3333333](https://github.com/owner/repo/commit/3333333333333333333333333333333333333333))`,
+		},
+		{
+			name: "custom sections and date",
+			files: map[string]string{
+				releaseNotesConfigFile: `breaking_heading: "💥 Breaking"
+date_format: "02.01.2006"
+sections:
+  - type: fix
+    heading: "🐛 Fixes"
+  - type: feat
+    heading: "✨ Features"
+`,
+			},
+			want: `## [1.1.0](https://github.com/owner/repo/compare/lib-v1.0.0...lib-v1.1.0) (04.03.2025)
+
+### 💥 Breaking
+* add a feature ([2222222](https://github.com/owner/repo/commit/2222222222222222222222222222222222222222))
+
+### 🐛 Fixes
+* fix a bug ([1111111](https://github.com/owner/repo/commit/1111111111111111111111111111111111111111))
+
+### ✨ Features
+* add a feature ([2222222](https://github.com/owner/repo/commit/2222222222222222222222222222222222222222))`,
+		},
+		{
+			name: "custom template",
+			files: map[string]string{
+				releaseNotesTemplateFile: `# {{.NewVersion}} - {{.Date}}
+{{- range .Sections}}
+{{.Heading}}:{{range .Commits}} {{shortSHA .SHA}}{{end}}
+{{- end}}`,
+			},
+			want: `# 1.1.0 - 2025-03-04
+⚠ BREAKING CHANGES: 2222222
+Features: 2222222
+Bug Fixes: 1111111
+Documentation: 3333333`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			format, err := loadReleaseNotesFormat(writeReleaseNotesFiles(t, test.files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data := &releaseNotesData{
+				NewVersion:  "1.1.0",
+				PreviousTag: "lib-v1.0.0",
+				NewTag:      "lib-v1.1.0",
+				Repo:        repo,
+				Date:        format.formatDate(time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)),
+				Sections:    format.sectionsOf(commits),
+				Bugs:        &bugLinker{repo: repo},
+			}
+			var got bytes.Buffer
+			if err := format.execute(&got, data); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got.String()); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadReleaseNotesFormat_Errors(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name:    "unknown field",
+			files:   map[string]string{releaseNotesConfigFile: "headings: []\n"},
+			wantErr: "field headings not found",
+		},
+		{
+			name: "duplicate section",
+			files: map[string]string{releaseNotesConfigFile: `sections:
+  - type: feat
+    heading: Features
+  - type: feat
+    heading: New
+`},
+			wantErr: `duplicate section of type "feat"`,
+		},
+		{
+			name:    "section without heading",
+			files:   map[string]string{releaseNotesConfigFile: "sections:\n  - type: feat\n"},
+			wantErr: "requires a type and a heading",
+		},
+		{
+			name:    "date format without date",
+			files:   map[string]string{releaseNotesConfigFile: "date_format: release\n"},
+			wantErr: `date_format "release" contains no date`,
+		},
+		{
+			name:    "template syntax",
+			files:   map[string]string{releaseNotesTemplateFile: "{{.NewVersion"},
+			wantErr: releaseNotesTemplateFile,
+		},
+		{
+			name:    "template unknown field",
+			files:   map[string]string{releaseNotesTemplateFile: "{{.Version}}"},
+			wantErr: "can't evaluate field Version",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := loadReleaseNotesFormat(writeReleaseNotesFiles(t, test.files))
+			if err == nil {
+				t.Fatalf("loadReleaseNotesFormat() error = nil, want %q", test.wantErr)
+			}
+			if !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("loadReleaseNotesFormat() error = %q, want to contain %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestReleaseNotesFormat_Nil(t *testing.T) {
+	t.Parallel()
+	var format *releaseNotesFormat
+	date := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)
+	if got, want := format.formatDate(date), "2025-03-04"; got != want {
+		t.Errorf("formatDate() = %q, want %q", got, want)
+	}
+	commits := []*conventionalcommits.ConventionalCommit{{Type: "perf", Description: "speed up"}}
+	want := []releaseNoteSection{{Heading: "Performance Improvements", Commits: commits}}
+	if diff := cmp.Diff(want, format.sectionsOf(commits)); diff != "" {
+		t.Errorf("sectionsOf() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/googleapis/librarian/internal/cli"
 	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/github"
 	"github.com/googleapis/librarian/internal/gitrepo"
 )
 
@@ -40,7 +41,9 @@ func TestFormatReleaseNotes(t *testing.T) {
 	for _, test := range []struct {
 		name            string
 		state           *config.LibrarianState
+		librarianConfig *config.LibrarianConfig
 		repo            gitrepo.Repository
+		host            string
 		wantReleaseNote string
 		wantErr         bool
 		wantErrPhrase   string
@@ -89,6 +92,113 @@ Language Image: go:1.21
 * a bug fix ([fedcba0](https://github.com/owner/repo/commit/fedcba0987654321000000000000000000000000))
 
 </details>
+
+<!-- BEGIN LIBRARIAN RELEASE METADATA
+libraries:
+    - id: my-library
+      version: 1.1.0
+      previous_version: 1.0.0
+      tag: my-library-1.1.0
+      breaking: false
+END LIBRARIAN RELEASE METADATA -->
+`,
+				librarianVersion, today),
+		},
+		{
+			name: "bug links",
+			state: &config.LibrarianState{
+				Image: "go:1.21",
+				Libraries: []*config.LibraryState{
+					{
+						ID:               "my-library",
+						Version:          "1.0.0",
+						ReleaseTriggered: true,
+					},
+				},
+			},
+			librarianConfig: &config.LibrarianConfig{
+				BugTrackers: []*config.BugTracker{{Prefix: "b/", URL: "https://issuetracker.google.com/issues/{id}"}},
+			},
+			repo: &MockRepository{
+				RemotesValue: []*git.Remote{git.NewRemote(nil, &gitconfig.RemoteConfig{Name: "origin", URLs: []string{"https://github.com/owner/repo.git"}})},
+				GetCommitsForPathsSinceTagValueByTag: map[string][]*gitrepo.Commit{
+					"my-library-1.0.0": {
+						{Message: "fix: a bug fix (#12)\n\nFixes b/345 and JIRA b/x.", Hash: hash1},
+					},
+				},
+				ChangedFilesInCommitValueByHash: map[string][]string{
+					hash1.String(): {"path/to/file"},
+				},
+			},
+			wantReleaseNote: fmt.Sprintf(`Librarian Version: %s
+Language Image: go:1.21
+
+<details><summary>my-library: 1.0.1</summary>
+
+## [1.0.1](https://github.com/owner/repo/compare/my-library-1.0.0...my-library-1.0.1) (%s)
+
+### Bug Fixes
+* a bug fix (#12) ([1234567](https://github.com/owner/repo/commit/1234567890abcdef000000000000000000000000)) ([#12](https://github.com/owner/repo/issues/12), [b/345](https://issuetracker.google.com/issues/345))
+
+</details>
+
+<!-- BEGIN LIBRARIAN RELEASE METADATA
+libraries:
+    - id: my-library
+      version: 1.0.1
+      previous_version: 1.0.0
+      tag: my-library-1.0.1
+      breaking: false
+END LIBRARIAN RELEASE METADATA -->
+`,
+				librarianVersion, today),
+		},
+		{
+			name: "github enterprise server",
+			state: &config.LibrarianState{
+				Image: "go:1.21",
+				Libraries: []*config.LibraryState{
+					{
+						ID:               "my-library",
+						Version:          "1.0.0",
+						ReleaseTriggered: true,
+					},
+				},
+			},
+			repo: &MockRepository{
+				RemotesValue: []*git.Remote{git.NewRemote(nil, &gitconfig.RemoteConfig{Name: "origin", URLs: []string{"https://github.example.com/owner/repo.git"}})},
+				GetCommitsForPathsSinceTagValueByTag: map[string][]*gitrepo.Commit{
+					"my-library-1.0.0": {
+						{Message: "fix: a bug fix", Hash: hash2},
+					},
+				},
+				ChangedFilesInCommitValueByHash: map[string][]string{
+					hash2.String(): {
+						"path/to/file",
+					},
+				},
+			},
+			host: "github.example.com",
+			wantReleaseNote: fmt.Sprintf(`Librarian Version: %s
+Language Image: go:1.21
+
+<details><summary>my-library: 1.0.1</summary>
+
+## [1.0.1](https://github.example.com/owner/repo/compare/my-library-1.0.0...my-library-1.0.1) (%s)
+
+### Bug Fixes
+* a bug fix ([fedcba0](https://github.example.com/owner/repo/commit/fedcba0987654321000000000000000000000000))
+
+</details>
+
+<!-- BEGIN LIBRARIAN RELEASE METADATA
+libraries:
+    - id: my-library
+      version: 1.0.1
+      previous_version: 1.0.0
+      tag: my-library-1.0.1
+      breaking: false
+END LIBRARIAN RELEASE METADATA -->
 `,
 				librarianVersion, today),
 		},
@@ -143,6 +253,20 @@ Language Image: go:1.21
 * fix for b ([fedcba0](https://github.com/owner/repo/commit/fedcba0987654321000000000000000000000000))
 
 </details>
+
+<!-- BEGIN LIBRARIAN RELEASE METADATA
+libraries:
+    - id: lib-a
+      version: 1.1.0
+      previous_version: 1.0.0
+      tag: lib-a-1.1.0
+      breaking: false
+    - id: lib-b
+      version: 2.0.1
+      previous_version: 2.0.0
+      tag: lib-b-2.0.1
+      breaking: false
+END LIBRARIAN RELEASE METADATA -->
 `,
 				librarianVersion, today, today),
 		},
@@ -182,6 +306,67 @@ Language Image: go:1.21
 * new feature ([1234567](https://github.com/owner/repo/commit/1234567890abcdef000000000000000000000000))
 
 </details>
+
+<!-- BEGIN LIBRARIAN RELEASE METADATA
+libraries:
+    - id: my-library
+      version: 1.1.0
+      previous_version: 1.0.0
+      tag: my-library-1.1.0
+      breaking: false
+END LIBRARIAN RELEASE METADATA -->
+`,
+				librarianVersion, today),
+		},
+		{
+			name: "release with breaking changes and commit origins",
+			state: &config.LibrarianState{
+				Image: "go:1.21",
+				Libraries: []*config.LibraryState{
+					{
+						ID:               "my-library",
+						Version:          "1.0.0",
+						ReleaseTriggered: true,
+					},
+				},
+			},
+			repo: &MockRepository{
+				RemotesValue: []*git.Remote{git.NewRemote(nil, &gitconfig.RemoteConfig{Name: "origin", URLs: []string{"https://github.com/owner/repo.git"}})},
+				GetCommitsForPathsSinceTagValueByTag: map[string][]*gitrepo.Commit{
+					"my-library-1.0.0": {
+						{Message: "feat!: remove a method\n\nPiperOrigin-RevId: 12345\nSource-Link: https://github.com/googleapis/googleapis/commit/abcdef", Hash: hash1},
+						{Message: "feat: new feature", Hash: hash2},
+					},
+				},
+				ChangedFilesInCommitValueByHash: map[string][]string{
+					hash1.String(): {"path/to/file"},
+					hash2.String(): {"path/to/file"},
+				},
+			},
+			wantReleaseNote: fmt.Sprintf(`Librarian Version: %s
+Language Image: go:1.21
+
+<details><summary>my-library: 2.0.0</summary>
+
+## [2.0.0](https://github.com/owner/repo/compare/my-library-1.0.0...my-library-2.0.0) (%s)
+
+### ⚠ BREAKING CHANGES
+* remove a method ([1234567](https://github.com/owner/repo/commit/1234567890abcdef000000000000000000000000)) ([source](https://github.com/googleapis/googleapis/commit/abcdef)) (PiperOrigin-RevId: 12345)
+
+### Features
+* remove a method ([1234567](https://github.com/owner/repo/commit/1234567890abcdef000000000000000000000000)) ([source](https://github.com/googleapis/googleapis/commit/abcdef)) (PiperOrigin-RevId: 12345)
+* new feature ([fedcba0](https://github.com/owner/repo/commit/fedcba0987654321000000000000000000000000))
+
+</details>
+
+<!-- BEGIN LIBRARIAN RELEASE METADATA
+libraries:
+    - id: my-library
+      version: 2.0.0
+      previous_version: 1.0.0
+      tag: my-library-2.0.0
+      breaking: true
+END LIBRARIAN RELEASE METADATA -->
 `,
 				librarianVersion, today),
 		},
@@ -194,6 +379,67 @@ Language Image: go:1.21
 			repo:            &MockRepository{},
 			wantReleaseNote: fmt.Sprintf("Librarian Version: %s\nLanguage Image: go:1.21\n\n", librarianVersion),
 		},
+		{
+			name: "release group",
+			state: &config.LibrarianState{
+				Image: "go:1.21",
+				Libraries: []*config.LibraryState{
+					{ID: "core", Version: "1.0.0", ReleaseGroup: "core", ReleaseTriggered: true},
+					{ID: "other", Version: "1.0.0"},
+					{ID: "ext", Version: "2.0.0", ReleaseGroup: "core", ReleaseTriggered: true},
+				},
+			},
+			repo: &MockRepository{
+				RemotesValue: []*git.Remote{git.NewRemote(nil, &gitconfig.RemoteConfig{Name: "origin", URLs: []string{"https://github.com/owner/repo.git"}})},
+				GetCommitsForPathsSinceTagValueByTag: map[string][]*gitrepo.Commit{
+					"core-1.0.0": {{Message: "feat: new feature", Hash: hash1}},
+					"ext-2.0.0":  {{Message: "fix: a bug fix", Hash: hash2}},
+				},
+				ChangedFilesInCommitValueByHash: map[string][]string{
+					hash1.String(): {"core/file"},
+					hash2.String(): {"ext/file"},
+				},
+			},
+			wantReleaseNote: fmt.Sprintf(`Librarian Version: %s
+Language Image: go:1.21
+
+Release group core, released together: core 1.1.0, ext 2.1.0
+
+<details><summary>core: 1.1.0</summary>
+
+## [1.1.0](https://github.com/owner/repo/compare/core-1.0.0...core-1.1.0) (%s)
+
+### Features
+* new feature ([1234567](https://github.com/owner/repo/commit/1234567890abcdef000000000000000000000000))
+
+</details>
+<details><summary>ext: 2.1.0</summary>
+
+## [2.1.0](https://github.com/owner/repo/compare/ext-2.0.0...ext-2.1.0) (%s)
+
+### Bug Fixes
+* a bug fix ([fedcba0](https://github.com/owner/repo/commit/fedcba0987654321000000000000000000000000))
+
+</details>
+
+<!-- BEGIN LIBRARIAN RELEASE METADATA
+libraries:
+    - id: core
+      version: 1.1.0
+      previous_version: 1.0.0
+      tag: core-1.1.0
+      breaking: false
+      release_group: core
+    - id: ext
+      version: 2.1.0
+      previous_version: 2.0.0
+      tag: ext-2.1.0
+      breaking: false
+      release_group: core
+END LIBRARIAN RELEASE METADATA -->
+`,
+				librarianVersion, today, today),
+		},
 		{
 			name: "error getting commits",
 			state: &config.LibrarianState{
@@ -216,7 +462,11 @@ Language Image: go:1.21
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
-			got, err := FormatReleaseNotes(test.repo, test.state)
+			host := test.host
+			if host == "" {
+				host = github.DefaultHost
+			}
+			got, err := FormatReleaseNotes(test.repo, test.librarianConfig, nil, test.state, host, nil)
 			if test.wantErr {
 				if err == nil {
 					t.Errorf("%s should return error", test.name)
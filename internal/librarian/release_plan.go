@@ -0,0 +1,326 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/conventionalcommits"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/semver"
+)
+
+var cmdPlanRelease = &cli.Command{
+	Short:     "plan-release reports what would be released, and why",
+	UsageLine: "librarian release plan-release [flags]",
+	Long: `Computes which libraries "librarian release init" would release right now, and
+reports for every library the releasable commits, the proposed version bump and
+the reasons why it is released or not.
+
+The plan follows the dependencies between the libraries: the members of a
+release group are released together, a library is released when a library it
+depends on is, and libraries whose release violates the release policy are
+skipped. During a release freeze, nothing is released unless the freeze is
+overridden with "-override-freeze". With "-library", only the library and the
+other members of its release group are considered.
+
+The commits of each library are those since its last release. With "-since",
+the commits since the given commit hash or tag are considered for every library
+instead, e.g. to preview the release of a range of commits.
+
+With "-output-format=json", the plan is printed as JSON instead, for dashboards
+and other tools. The command is read-only: it does not run the language
+container, and does not change the repository.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newCommandRunner(cfg)
+		if err != nil {
+			return err
+		}
+		plan, err := planRelease(ctx, runner.cfg, runner.repo, runner.state, runner.librarianConfig)
+		if err != nil {
+			return err
+		}
+		return plan.write(os.Stdout, cfg.OutputFormat)
+	},
+}
+
+func init() {
+	cmdPlanRelease.Init()
+	fs := cmdPlanRelease.Flags
+	cfg := cmdPlanRelease.Config
+
+	addFlagChannel(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLibrary(fs, cfg)
+	addFlagLibraryVersion(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagOutputFormat(fs, cfg)
+	addFlagOverrideFreeze(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSince(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+// releasePlan is what "librarian release init" would release now.
+type releasePlan struct {
+	// Since is the commit hash or tag since which the commits of every
+	// library are considered, or empty if they are considered since the last
+	// release of each library.
+	Since string `json:"since,omitempty"`
+	// Blocked is why no library would be released at all, if none would.
+	Blocked string `json:"blocked,omitempty"`
+	// Freeze describes the active release freeze overridden with
+	// -override-freeze, if any.
+	Freeze    string                `json:"freeze,omitempty"`
+	Libraries []*libraryReleasePlan `json:"libraries"`
+}
+
+// libraryReleasePlan is the planned release of a library.
+type libraryReleasePlan struct {
+	ID           string `json:"id"`
+	ReleaseGroup string `json:"release_group,omitempty"`
+	Release      bool   `json:"release"`
+	Version      string `json:"version,omitempty"`
+	NextVersion  string `json:"next_version,omitempty"`
+	// Bump is the change level of the release: "major", "minor", "patch" or
+	// "none".
+	Bump    string           `json:"bump"`
+	Commits []*releaseCommit `json:"commits"`
+	// Changes is the number of changes in the release notes, which include
+	// the updates of the released dependencies.
+	Changes int      `json:"changes"`
+	Reasons []string `json:"reasons"`
+}
+
+// releaseCommit is a releasable commit of a library.
+type releaseCommit struct {
+	SHA      string `json:"sha"`
+	Type     string `json:"type"`
+	Subject  string `json:"subject"`
+	Breaking bool   `json:"breaking,omitempty"`
+	// Bump is the change level of the commit on its own.
+	Bump string `json:"bump"`
+}
+
+// sinceRepository is a repository whose commits since the last release of
+// any library are the commits since a given commit hash or tag.
+type sinceRepository struct {
+	gitrepo.Repository
+	since string
+}
+
+// GetCommitsForPathsSinceTag returns the commits since the commit hash or tag
+// of r, regardless of tagName.
+func (r *sinceRepository) GetCommitsForPathsSinceTag(paths []string, _ string) ([]*gitrepo.Commit, error) {
+	tags, err := r.Tags()
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(tags, r.since) {
+		return r.Repository.GetCommitsForPathsSinceTag(paths, r.since)
+	}
+	return r.GetCommitsForPathsSinceCommit(paths, r.since)
+}
+
+// planRelease returns what "librarian release init" would release now, with
+// the -channel, -library, -library-version and -override-freeze of cfg, and
+// the commits since the -since of cfg, if any. The state is not changed.
+func planRelease(ctx context.Context, cfg *config.Config, repo gitrepo.Repository, state *config.LibrarianState, librarianConfig *config.LibrarianConfig) (*releasePlan, error) {
+	if cfg.Since != "" {
+		repo = &sinceRepository{Repository: repo, since: cfg.Since}
+	}
+	planner := &initRunner{
+		cfg:             cfg,
+		repo:            repo,
+		state:           copyLibrarianState(state),
+		librarianConfig: librarianConfig,
+	}
+	plan := &releasePlan{Since: cfg.Since}
+	libraries := planner.librariesToRelease()
+	commits := make(map[string][]*conventionalcommits.ConventionalCommit)
+	for _, library := range libraries {
+		libraryCommits, err := GetConventionalCommitsSinceLastRelease(repo, library)
+		if err != nil {
+			return nil, err
+		}
+		commits[library.ID] = libraryCommits
+	}
+	freezeViolation, frozen, err := checkReleaseFreeze(ctx, planner.releasePolicy(), now(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	if frozen != nil {
+		plan.Freeze = fmt.Sprintf("%s, overridden for %s", freezeDescription(frozen), cfg.OverrideFreeze)
+	}
+	if violation := checkReleaseDay(planner.releasePolicy(), now()); violation != nil {
+		plan.Blocked = violation.reason
+	} else if freezeViolation != nil {
+		plan.Blocked = freezeViolation.reason
+	} else if err := planner.planLibraries(libraries); err != nil {
+		return nil, err
+	}
+
+	for _, library := range libraries {
+		previous := state.LibraryByID(library.ID)
+		p := &libraryReleasePlan{
+			ID:           library.ID,
+			ReleaseGroup: library.ReleaseGroup,
+			Release:      library.ReleaseTriggered,
+			Version:      previous.Version,
+			Bump:         semver.None.String(),
+		}
+		for _, commit := range commits[library.ID] {
+			p.Commits = append(p.Commits, &releaseCommit{
+				SHA:      commit.SHA,
+				Type:     commit.Type,
+				Subject:  commit.Description,
+				Breaking: commit.IsBreaking,
+				Bump:     getHighestChange([]*conventionalcommits.ConventionalCommit{commit}).String(),
+			})
+		}
+		since := cfg.Since
+		if since == "" {
+			since = "the last release"
+			if previous.Version == "" {
+				since = "the first commit"
+			}
+		}
+		switch {
+		case plan.Blocked != "":
+			p.Reasons = append(p.Reasons, plan.Blocked)
+		case library.ReleaseTriggered:
+			p.NextVersion = library.Version
+			p.Changes = len(library.Changes)
+			p.Bump = planner.releaseChangeLevel(library, commits).String()
+			p.Reasons = planner.releaseReasons(library, commits, since)
+		case len(commits[library.ID]) > 0:
+			p.Reasons = append(p.Reasons, fmt.Sprintf("%d releasable commits since %s, skipped by the release policy", len(commits[library.ID]), since))
+		default:
+			p.Reasons = append(p.Reasons, "no releasable commits since "+since)
+		}
+		plan.Libraries = append(plan.Libraries, p)
+	}
+	return plan, nil
+}
+
+// releaseChangeLevel returns the change level of the planned release of
+// library: the highest of its commits and of the commits of the other
+// members of its release group, or a patch for the release of its
+// dependencies.
+func (r *initRunner) releaseChangeLevel(library *config.LibraryState, commits map[string][]*conventionalcommits.ConventionalCommit) semver.ChangeLevel {
+	level := dependencyChangeLevel(r.state, library)
+	for _, member := range releaseGroupMembers(r.state, library) {
+		level = max(level, getHighestChange(commits[member.ID]))
+	}
+	return level
+}
+
+// releaseReasons returns why library is released in the plan of r.
+func (r *initRunner) releaseReasons(library *config.LibraryState, commits map[string][]*conventionalcommits.ConventionalCommit, since string) []string {
+	var reasons []string
+	if n := len(commits[library.ID]); n > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d releasable commits since %s", n, since))
+	}
+	if library.ReleaseGroup != "" {
+		var changed, dependents []string
+		for _, member := range releaseGroupMembers(r.state, library) {
+			if member.ID == library.ID {
+				continue
+			}
+			if len(commits[member.ID]) > 0 {
+				changed = append(changed, member.ID)
+			}
+			if len(releasedDependencies(r.state, member)) > 0 {
+				dependents = append(dependents, member.ID)
+			}
+		}
+		if len(changed) > 0 {
+			reasons = append(reasons, fmt.Sprintf("released with release group %s, for the commits of %s", library.ReleaseGroup, strings.Join(changed, ", ")))
+		}
+		if len(dependents) > 0 {
+			reasons = append(reasons, fmt.Sprintf("released with release group %s, for the released dependencies of %s", library.ReleaseGroup, strings.Join(dependents, ", ")))
+		}
+	}
+	for _, dependency := range releasedDependencies(r.state, library) {
+		reasons = append(reasons, fmt.Sprintf("depends on %s, released as %s", dependency.ID, dependency.Version))
+	}
+	if version := r.libraryVersion(releaseGroupMembers(r.state, library)); version != "" {
+		reasons = append(reasons, "version set to "+version)
+	}
+	return reasons
+}
+
+// releasedDependencies returns the libraries in state which library depends
+// on and which are released.
+func releasedDependencies(state *config.LibrarianState, library *config.LibraryState) []*config.LibraryState {
+	var released []*config.LibraryState
+	for _, id := range library.DependsOn {
+		if dependency := state.LibraryByID(id); dependency != nil && dependency.ReleaseTriggered {
+			released = append(released, dependency)
+		}
+	}
+	return released
+}
+
+// write writes the plan to w in the given -output-format.
+func (p *releasePlan) write(w io.Writer, format string) error {
+	if format == config.OutputFormatJSON {
+		data, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+	if p.Blocked != "" {
+		if _, err := fmt.Fprintf(w, "No libraries would be released: %s.\n\n", p.Blocked); err != nil {
+			return err
+		}
+	}
+	if p.Freeze != "" {
+		if _, err := fmt.Fprintf(w, "Releases are frozen %s.\n\n", p.Freeze); err != nil {
+			return err
+		}
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LIBRARY\tVERSION\tNEXT VERSION\tBUMP\tCOMMITS\tREASONS")
+	released := 0
+	for _, library := range p.Libraries {
+		next := "-"
+		if library.Release {
+			next = library.NextVersion
+			released++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", library.ID, library.Version, next, library.Bump, len(library.Commits), strings.Join(library.Reasons, "; "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d of %d libraries would be released\n", released, len(p.Libraries))
+	return err
+}
@@ -0,0 +1,164 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+func TestPlanRelease(t *testing.T) {
+	hash1 := plumbing.NewHash("1234567890abcdef")
+	hash2 := plumbing.NewHash("fedcba0987654321")
+	repo := &MockRepository{
+		TagsValue: []string{"a-1.0.0", "b-1.0.0", "release-2025"},
+		GetCommitsForPathsSinceTagValueByTag: map[string][]*gitrepo.Commit{
+			"a-1.0.0":      {{Message: "feat: add a method", Hash: hash1}},
+			"release-2025": {{Message: "fix: a bug fix", Hash: hash2}},
+		},
+		ChangedFilesInCommitValueByHash: map[string][]string{
+			hash1.String(): {"a/a.go"},
+			hash2.String(): {"a/a.go"},
+		},
+	}
+	newState := func() *config.LibrarianState {
+		return &config.LibrarianState{
+			Libraries: []*config.LibraryState{
+				{ID: "a", Version: "1.0.0", SourceRoots: []string{"a"}},
+				{ID: "b", Version: "1.0.0", SourceRoots: []string{"b"}, ReleaseGroup: "g"},
+				{ID: "c", Version: "2.0.0", SourceRoots: []string{"c"}, ReleaseGroup: "g", DependsOn: []string{"a"}},
+				{ID: "d", Version: "0.1.0", SourceRoots: []string{"d"}},
+			},
+		}
+	}
+	for _, test := range []struct {
+		name    string
+		library string
+		since   string
+		want    []*libraryReleasePlan
+	}{
+		{
+			name: "since last release",
+			want: []*libraryReleasePlan{
+				{
+					ID:          "a",
+					Release:     true,
+					Version:     "1.0.0",
+					NextVersion: "1.1.0",
+					Bump:        "minor",
+					Commits:     []*releaseCommit{{SHA: hash1.String(), Type: "feat", Subject: "add a method", Bump: "minor"}},
+					Changes:     1,
+					Reasons:     []string{"1 releasable commits since the last release"},
+				},
+				{
+					ID:           "b",
+					ReleaseGroup: "g",
+					Release:      true,
+					Version:      "1.0.0",
+					NextVersion:  "1.0.1",
+					Bump:         "patch",
+					Reasons:      []string{"released with release group g, for the released dependencies of c"},
+				},
+				{
+					ID:           "c",
+					ReleaseGroup: "g",
+					Release:      true,
+					Version:      "2.0.0",
+					NextVersion:  "2.0.1",
+					Bump:         "patch",
+					Changes:      1,
+					Reasons:      []string{"depends on a, released as 1.1.0"},
+				},
+				{
+					ID:      "d",
+					Version: "0.1.0",
+					Bump:    "none",
+					Reasons: []string{"no releasable commits since the last release"},
+				},
+			},
+		},
+		{
+			name:    "since tag",
+			library: "a",
+			since:   "release-2025",
+			want: []*libraryReleasePlan{
+				{
+					ID:          "a",
+					Release:     true,
+					Version:     "1.0.0",
+					NextVersion: "1.0.1",
+					Bump:        "patch",
+					Commits:     []*releaseCommit{{SHA: hash2.String(), Type: "fix", Subject: "a bug fix", Bump: "patch"}},
+					Changes:     1,
+					Reasons:     []string{"1 releasable commits since release-2025"},
+				},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			state := newState()
+			plan, err := planRelease(t.Context(), &config.Config{Library: test.library, Since: test.since}, repo, state, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, plan.Libraries); diff != "" {
+				t.Errorf("planRelease() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(newState(), state); diff != "" {
+				t.Errorf("planRelease() changed the state (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReleasePlanWrite(t *testing.T) {
+	plan := &releasePlan{
+		Libraries: []*libraryReleasePlan{
+			{ID: "a", Release: true, Version: "1.0.0", NextVersion: "1.1.0", Bump: "minor", Commits: []*releaseCommit{{SHA: "abc", Type: "feat", Subject: "add a method", Bump: "minor"}}, Reasons: []string{"1 releasable commits since the last release"}},
+			{ID: "b", Version: "0.1.0", Bump: "none", Reasons: []string{"no releasable commits since the last release"}},
+		},
+	}
+	var text bytes.Buffer
+	if err := plan.write(&text, config.OutputFormatText); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"a        1.0.0    1.1.0         minor  1        1 releasable commits since the last release",
+		"b        0.1.0    -             none   0        no releasable commits since the last release",
+		"1 of 2 libraries would be released",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("write() = %q, want to contain %q", text.String(), want)
+		}
+	}
+	var data bytes.Buffer
+	if err := plan.write(&data, config.OutputFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	got := &releasePlan{}
+	if err := json.Unmarshal(data.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(plan, got); diff != "" {
+		t.Errorf("write() JSON mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -30,7 +30,7 @@ const defaultTagFormat = "{id}-{version}"
 // GetConventionalCommitsSinceLastRelease returns all conventional commits for the given library since the
 // version specified in the state file.
 func GetConventionalCommitsSinceLastRelease(repo gitrepo.Repository, library *config.LibraryState) ([]*conventionalcommits.ConventionalCommit, error) {
-	tag := formatTag(library, "")
+	tag := previousReleaseTag(library)
 	commits, err := repo.GetCommitsForPathsSinceTag(library.SourceRoots, tag)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get commits for library %s: %w", library.ID, err)
@@ -76,6 +76,9 @@ func convertToConventionalCommits(repo gitrepo.Repository, library *config.Libra
 		if parsedCommits == nil {
 			continue
 		}
+		for _, parsed := range parsedCommits {
+			parsed.Author = commit.Author
+		}
 		conventionalCommits = append(conventionalCommits, parsedCommits...)
 	}
 	return conventionalCommits, nil
@@ -113,6 +116,16 @@ func formatTag(library *config.LibraryState, versionOverride string) string {
 	return r.Replace(tagFormat)
 }
 
+// previousReleaseTag returns the git tag of the last release of the library.
+// This is library.PreviousReleaseTag if the library was renamed since, and
+// the tag of library.Version otherwise.
+func previousReleaseTag(library *config.LibraryState) string {
+	if library.PreviousReleaseTag != "" {
+		return library.PreviousReleaseTag
+	}
+	return formatTag(library, "")
+}
+
 // NextVersion calculates the next semantic version based on a slice of conventional commits.
 // If overrideNextVersion is not empty, it is returned as the next version.
 func NextVersion(commits []*conventionalcommits.ConventionalCommit, currentVersion, overrideNextVersion string) (string, error) {
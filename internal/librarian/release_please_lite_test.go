@@ -177,6 +177,32 @@ func TestGetConventionalCommitsSinceLastRelease(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "get commits for renamed library",
+			repo: repoWithCommits,
+			library: &config.LibraryState{
+				ID:                  "renamed-foo",
+				Version:             "1.0.0",
+				TagFormat:           "{id}-v{version}",
+				SourceRoots:         []string{"foo"},
+				ReleaseExcludePaths: []string{"foo/README.md"},
+				PreviousReleaseTag:  "foo-v1.0.0",
+			},
+			want: []*conventionalcommits.ConventionalCommit{
+				{
+					Type:        "feat",
+					Scope:       "foo",
+					Description: "another feature for foo",
+					Footers:     make(map[string]string),
+				},
+				{
+					Type:        "fix",
+					Scope:       "foo",
+					Description: "a fix for foo",
+					Footers:     make(map[string]string),
+				},
+			},
+		},
 		{
 			name: "GetCommitsForPathsSinceTag error",
 			repo: &MockRepository{
@@ -225,7 +251,7 @@ func TestGetConventionalCommitsSinceLastRelease(t *testing.T) {
 			if err != nil {
 				t.Fatalf("GetConventionalCommitsSinceLastRelease() failed: %v", err)
 			}
-			if diff := cmp.Diff(test.want, got, cmpopts.IgnoreFields(conventionalcommits.ConventionalCommit{}, "SHA", "Body", "IsBreaking")); diff != "" {
+			if diff := cmp.Diff(test.want, got, cmpopts.IgnoreFields(conventionalcommits.ConventionalCommit{}, "SHA", "Body", "IsBreaking", "Author")); diff != "" {
 				t.Errorf("GetConventionalCommitsSinceLastRelease() mismatch (-want +got):\n%s", diff)
 			}
 		})
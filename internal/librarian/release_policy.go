@@ -0,0 +1,108 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// now returns the current time. It is a variable so it can be replaced during
+// testing.
+var now = time.Now
+
+// policyViolation is a violation of a rule of the release policy.
+type policyViolation struct {
+	// libraryID is the ID of the library whose release violates the rule,
+	// or empty if the rule applies to the whole release.
+	libraryID string
+	// rule is the name of the rule in the release policy, e.g. "min_changes".
+	rule string
+	// reason explains the violation.
+	reason string
+}
+
+// String returns a description of the violation.
+func (v *policyViolation) String() string {
+	if v.libraryID == "" {
+		return fmt.Sprintf("%s: %s", v.rule, v.reason)
+	}
+	return fmt.Sprintf("%s: %s: %s", v.libraryID, v.rule, v.reason)
+}
+
+// checkReleaseDay returns the violation of policy by releasing at t, or nil.
+func checkReleaseDay(policy *config.ReleasePolicy, t time.Time) *policyViolation {
+	if !policy.IsBlockedOn(t) {
+		return nil
+	}
+	return &policyViolation{
+		rule:   "blocked_days",
+		reason: fmt.Sprintf("releases are blocked on %s", t.UTC().Weekday()),
+	}
+}
+
+// checkLibraryReleasePolicy returns the violations of policy by releasing
+// the library with the given ID and number of changes at t. lastReleaseTag
+// is the tag of the last release of the library, or empty if the library
+// has never been released.
+func checkLibraryReleasePolicy(policy *config.ReleasePolicy, repo gitrepo.Repository, libraryID, lastReleaseTag string, changes int, t time.Time) ([]*policyViolation, error) {
+	if policy == nil {
+		return nil, nil
+	}
+	var violations []*policyViolation
+	if changes < policy.MinChanges {
+		violations = append(violations, &policyViolation{
+			libraryID: libraryID,
+			rule:      "min_changes",
+			reason:    fmt.Sprintf("%d releasable changes, at least %d required", changes, policy.MinChanges),
+		})
+	}
+	if minInterval := policy.MinIntervalDuration(); minInterval > 0 && lastReleaseTag != "" {
+		released, err := repo.TagCommitTime(lastReleaseTag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get time of last release of library %s: %w", libraryID, err)
+		}
+		if elapsed := t.Sub(released); elapsed < minInterval {
+			violations = append(violations, &policyViolation{
+				libraryID: libraryID,
+				rule:      "min_interval",
+				reason:    fmt.Sprintf("last released %s ago at %s, at least %s required", elapsed.Round(time.Minute), lastReleaseTag, minInterval),
+			})
+		}
+	}
+	return violations, nil
+}
+
+// releasePolicyError returns the error reporting violations.
+func releasePolicyError(violations []*policyViolation) error {
+	var reasons []string
+	for _, v := range violations {
+		reasons = append(reasons, v.String())
+	}
+	return failure.New(failure.UserConfig, fmt.Errorf("release blocked by release policy: %s", strings.Join(reasons, "; ")))
+}
+
+// logPolicyViolations logs violations which cause libraries to be skipped.
+func logPolicyViolations(violations []*policyViolation) {
+	for _, v := range violations {
+		slog.Warn("Skipping release blocked by release policy", "library", v.libraryID, "rule", v.rule, "reason", v.reason)
+	}
+}
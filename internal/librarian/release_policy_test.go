@@ -0,0 +1,185 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+func TestCheckReleaseDay(t *testing.T) {
+	t.Parallel()
+	friday := time.Date(2025, 1, 3, 12, 0, 0, 0, time.UTC)
+	policy := &config.ReleasePolicy{BlockedDays: []string{"Friday"}}
+	if got := checkReleaseDay(nil, friday); got != nil {
+		t.Errorf("checkReleaseDay(nil) = %v, want nil", got)
+	}
+	if got := checkReleaseDay(policy, friday.Add(24*time.Hour)); got != nil {
+		t.Errorf("checkReleaseDay() on Saturday = %v, want nil", got)
+	}
+	got := checkReleaseDay(policy, friday)
+	if got == nil {
+		t.Fatal("checkReleaseDay() on Friday = nil, want violation")
+	}
+	if want := "blocked_days: releases are blocked on Friday"; got.String() != want {
+		t.Errorf("checkReleaseDay() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckLibraryReleasePolicy(t *testing.T) {
+	t.Parallel()
+	releaseTime := time.Date(2025, 1, 3, 12, 0, 0, 0, time.UTC)
+	repo := &MockRepository{
+		TagCommitTimeValueByTag: map[string]time.Time{"foo-1.0.0": releaseTime},
+	}
+	for _, test := range []struct {
+		name           string
+		policy         *config.ReleasePolicy
+		lastReleaseTag string
+		changes        int
+		t              time.Time
+		want           []string
+		wantErr        bool
+	}{
+		{
+			name:           "no policy",
+			lastReleaseTag: "foo-1.0.0",
+			changes:        1,
+			t:              releaseTime,
+		},
+		{
+			name:           "satisfied",
+			policy:         &config.ReleasePolicy{MinChanges: 2, MinInterval: "24h"},
+			lastReleaseTag: "foo-1.0.0",
+			changes:        2,
+			t:              releaseTime.Add(25 * time.Hour),
+		},
+		{
+			name:           "too few changes",
+			policy:         &config.ReleasePolicy{MinChanges: 2},
+			lastReleaseTag: "foo-1.0.0",
+			changes:        1,
+			t:              releaseTime,
+			want:           []string{"foo: min_changes: 1 releasable changes, at least 2 required"},
+		},
+		{
+			name:           "released too recently",
+			policy:         &config.ReleasePolicy{MinChanges: 2, MinInterval: "24h"},
+			lastReleaseTag: "foo-1.0.0",
+			changes:        1,
+			t:              releaseTime.Add(3 * time.Hour),
+			want: []string{
+				"foo: min_changes: 1 releasable changes, at least 2 required",
+				"foo: min_interval: last released 3h0m0s ago at foo-1.0.0, at least 24h0m0s required",
+			},
+		},
+		{
+			name:    "never released",
+			policy:  &config.ReleasePolicy{MinInterval: "24h"},
+			changes: 1,
+			t:       releaseTime,
+		},
+		{
+			name:           "missing tag",
+			policy:         &config.ReleasePolicy{MinInterval: "24h"},
+			lastReleaseTag: "foo-0.9.0",
+			changes:        1,
+			t:              releaseTime,
+			wantErr:        true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			violations, err := checkLibraryReleasePolicy(test.policy, repo, "foo", test.lastReleaseTag, test.changes, test.t)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("checkLibraryReleasePolicy() error = %v, wantErr %v", err, test.wantErr)
+			}
+			var got []string
+			for _, v := range violations {
+				got = append(got, v.String())
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("checkLibraryReleasePolicy() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInitRunnerUpdateLibrariesPolicy(t *testing.T) {
+	t.Parallel()
+	newRunner := func(library string) *initRunner {
+		return &initRunner{
+			cfg: &config.Config{Library: library},
+			repo: &MockRepository{
+				GetCommitsForPathsSinceTagValue: []*gitrepo.Commit{
+					{Message: "fix: a bug"},
+				},
+				ChangedFilesInCommitValue: []string{"foo/foo.go"},
+				TagCommitTimeValueByTag:   map[string]time.Time{"foo-1.0.0": time.Now().Add(-time.Hour)},
+			},
+			librarianConfig: &config.LibrarianConfig{
+				ReleasePolicy: &config.ReleasePolicy{MinInterval: "24h"},
+			},
+		}
+	}
+	newLibrary := func() *config.LibraryState {
+		return &config.LibraryState{ID: "foo", Version: "1.0.0", SourceRoots: []string{"foo"}}
+	}
+
+	library := newLibrary()
+	runner := newRunner("")
+	runner.librarianConfig = nil
+	if err := runner.updateLibraries([]*config.LibraryState{library}); err != nil {
+		t.Fatalf("updateLibraries() error = %v", err)
+	}
+	if !library.ReleaseTriggered {
+		t.Fatal("updateLibraries() did not trigger a release without release policy")
+	}
+
+	library = newLibrary()
+	if err := newRunner("").updateLibraries([]*config.LibraryState{library}); err != nil {
+		t.Fatalf("updateLibraries() error = %v", err)
+	}
+	if diff := cmp.Diff(newLibrary(), library); diff != "" {
+		t.Errorf("updateLibraries() did not skip the library (-want +got):\n%s", diff)
+	}
+
+	err := newRunner("foo").updateLibraries([]*config.LibraryState{newLibrary()})
+	if failure.CategoryOf(err) != failure.UserConfig {
+		t.Errorf("updateLibraries() error = %v, want %q error", err, failure.UserConfig)
+	}
+	// A violation by a member of a release group skips the whole group.
+	newGroup := func() []*config.LibraryState {
+		foo := newLibrary()
+		foo.ReleaseGroup = "group"
+		return []*config.LibraryState{
+			foo,
+			{ID: "bar", Version: "0.1.0", SourceRoots: []string{"bar"}, ReleaseGroup: "group"},
+		}
+	}
+	group := newGroup()
+	runner = newRunner("")
+	runner.repo.(*MockRepository).TagCommitTimeValueByTag["bar-0.1.0"] = time.Now().Add(-48 * time.Hour)
+	if err := runner.updateLibraries(group); err != nil {
+		t.Fatalf("updateLibraries() error = %v", err)
+	}
+	if diff := cmp.Diff(newGroup(), group); diff != "" {
+		t.Errorf("updateLibraries() did not skip the release group (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+// cmdPromoteRelease is the command for the `release promote-release`
+// subcommand.
+var cmdPromoteRelease = &cli.Command{
+	Short:     "promote-release releases canary versions as stable versions.",
+	UsageLine: "librarian release promote-release [arguments]",
+	Long: `Creates a release pull request which promotes the canary versions of the
+libraries to stable versions, e.g. 1.5.0 for 1.5.0-beta.3.
+
+Only the libraries with a canary release since their last stable release, as
+recorded by their canary_version in state.yaml, are released. Their changes are
+all the changes since their last stable release. Their canary_version is
+cleared in state.yaml once promoted.
+
+The release is otherwise like one of "librarian release init", and is tagged
+by "librarian release tag-and-release" once merged.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newInitRunner(cfg)
+		if err != nil {
+			return err
+		}
+		runner.promote = true
+		return runner.run(ctx)
+	},
+}
+
+func init() {
+	cmdPromoteRelease.Init()
+	fs := cmdPromoteRelease.Flags
+	cfg := cmdPromoteRelease.Config
+
+	addFlagAuditLog(fs, cfg)
+	addFlagCleanWorkRoot(fs, cfg)
+	addFlagCommit(fs, cfg)
+	addFlagContainerBackend(fs, cfg)
+	addFlagContainerLogLevel(fs, cfg)
+	addFlagContainerMounts(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGeneratorSource(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagImage(fs, cfg)
+	addFlagImageLocal(fs, cfg)
+	addFlagImagePlatform(fs, cfg)
+	addFlagLibrary(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagNativeImage(fs, cfg)
+	addFlagOverrideFreeze(fs, cfg)
+	addFlagPush(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
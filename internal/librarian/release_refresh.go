@@ -0,0 +1,175 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/github"
+)
+
+// releasePullRequestMarker marks the body of the release pull requests
+// created by release init, which refresh-release-pr can refresh in place.
+// Like the release status, it is an HTML comment, so that it is not rendered.
+const releasePullRequestMarker = "<!-- LIBRARIAN RELEASE PULL REQUEST -->"
+
+// cmdRefreshReleasePR is the command for the `release refresh-release-pr`
+// subcommand.
+var cmdRefreshReleasePR = &cli.Command{
+	Short:     "refresh-release-pr updates the open release pull request in place.",
+	UsageLine: "librarian release refresh-release-pr [arguments]",
+	Long: `Updates the open release pull request in place to include the changes which
+landed since it was opened, rather than opening another release pull request.
+
+The release is initiated again from the current HEAD like "librarian release
+init" does. Its commit is force-pushed to the branch of the open release pull
+request, and the body of the pull request is replaced, including its release
+status. The exclusions and versions set with "librarian release handle-comment"
+are kept. The release pull request is the open pull request whose body
+contains the marker written by "librarian release init", or the one specified
+with -pr.
+
+Releases split into multiple pull requests, pull requests queued in the merge
+queue of the base branch, and changes sent for review to Gerrit cannot be
+refreshed.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newInitRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return runner.refreshPullRequest(ctx)
+	},
+}
+
+func init() {
+	cmdRefreshReleasePR.Init()
+	fs := cmdRefreshReleasePR.Flags
+	cfg := cmdRefreshReleasePR.Config
+
+	addFlagAuditLog(fs, cfg)
+	addFlagCleanWorkRoot(fs, cfg)
+	addFlagCommit(fs, cfg)
+	addFlagContainerBackend(fs, cfg)
+	addFlagContainerLogLevel(fs, cfg)
+	addFlagContainerMounts(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGeneratorSource(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagImage(fs, cfg)
+	addFlagImageLocal(fs, cfg)
+	addFlagImagePlatform(fs, cfg)
+	addFlagLibrary(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagNativeImage(fs, cfg)
+	addFlagOverrideFreeze(fs, cfg)
+	addFlagPR(fs, cfg)
+	addFlagPush(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+// refreshPullRequest initiates the release again, and updates the open
+// release pull request with it, if there is one.
+func (r *initRunner) refreshPullRequest(ctx context.Context) error {
+	if r.gerrit != nil {
+		return failure.New(failure.UserConfig, errors.New("refresh-release-pr does not support changes sent for review to Gerrit"))
+	}
+	pr, err := findReleasePullRequest(ctx, r.cfg, r.ghClient)
+	if err != nil {
+		return err
+	}
+	if pr == nil {
+		slog.Info("No open release pull request to refresh; run release init to open one")
+		return nil
+	}
+	// Pushing to a pull request in the merge queue removes it from the queue.
+	if requiresMergeQueue(ctx, r.ghClient) {
+		queued, err := r.ghClient.IsInMergeQueue(ctx, pr.GetNumber())
+		if err != nil {
+			return fmt.Errorf("failed to check the merge queue of pull request %d: %w", pr.GetNumber(), err)
+		}
+		if queued {
+			return failure.New(failure.UserConfig, fmt.Errorf("pull request %d is queued in the merge queue, and refreshing it would remove it from the queue", pr.GetNumber()))
+		}
+	}
+	overrides, err := parseReleaseOverrides(pr.GetBody())
+	if err != nil {
+		return failure.New(failure.UserConfig, err)
+	}
+	slog.Info("Refreshing release pull request", "number", pr.GetNumber(), "branch", pr.GetHead().GetRef())
+	r.refresh = pr
+	r.overrides = overrides
+	return r.run(ctx)
+}
+
+// findReleasePullRequest returns the open release pull request specified with
+// the -pr flag, or else the only open pull request whose body contains
+// releasePullRequestMarker, or nil if there is none.
+func findReleasePullRequest(ctx context.Context, cfg *config.Config, ghClient GitHubClient) (*github.PullRequest, error) {
+	if cfg.PullRequest != "" {
+		number, err := parsePullRequestNumber(cfg.PullRequest)
+		if err != nil {
+			return nil, failure.New(failure.UserConfig, err)
+		}
+		pr, err := ghClient.GetPullRequest(ctx, number)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pull request %d: %w", number, err)
+		}
+		if !isOpenReleasePullRequest(pr) {
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("pull request %d is not an open release pull request", number))
+		}
+		return pr, nil
+	}
+	query := fmt.Sprintf("is:pr is:open in:body %q", strings.Trim(releasePullRequestMarker, "<!-> "))
+	prs, err := ghClient.SearchPullRequests(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search pull requests: %w", err)
+	}
+	var found []*github.PullRequest
+	for _, pr := range prs {
+		if isOpenReleasePullRequest(pr) {
+			found = append(found, pr)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, nil
+	case 1:
+		return found[0], nil
+	}
+	var numbers []string
+	for _, pr := range found {
+		numbers = append(numbers, fmt.Sprint(pr.GetNumber()))
+	}
+	return nil, failure.New(failure.UserConfig, fmt.Errorf("found %d open release pull requests (%s), specify one with -pr", len(found), strings.Join(numbers, ", ")))
+}
+
+// isOpenReleasePullRequest reports whether pr is open, and was created by
+// release init.
+func isOpenReleasePullRequest(pr *github.PullRequest) bool {
+	return pr.GetState() == "open" && strings.Contains(pr.GetBody(), releasePullRequestMarker)
+}
@@ -0,0 +1,146 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	gh "github.com/google/go-github/v69/github"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/github"
+)
+
+func newTestPullRequest(number int, state, body string) *github.PullRequest {
+	return &github.PullRequest{
+		Number: gh.Ptr(number),
+		State:  gh.Ptr(state),
+		Body:   gh.Ptr(body),
+		Head:   &gh.PullRequestBranch{Ref: gh.Ptr("librarian-20250101T000000Z")},
+	}
+}
+
+func TestFindReleasePullRequest(t *testing.T) {
+	t.Parallel()
+	releaseBody := releasePullRequestMarker + "\nrelease status"
+	for _, test := range []struct {
+		name         string
+		pr           string
+		ghClient     *mockGitHubClient
+		wantNumber   int
+		wantErrMsg   string
+		wantCategory failure.Category
+	}{
+		{
+			name: "found",
+			ghClient: &mockGitHubClient{pullRequests: []*github.PullRequest{
+				newTestPullRequest(1, "open", "not a release"),
+				newTestPullRequest(2, "open", releaseBody),
+				newTestPullRequest(3, "closed", releaseBody),
+			}},
+			wantNumber: 2,
+		},
+		{
+			name:     "none",
+			ghClient: &mockGitHubClient{},
+		},
+		{
+			name: "ambiguous",
+			ghClient: &mockGitHubClient{pullRequests: []*github.PullRequest{
+				newTestPullRequest(2, "open", releaseBody),
+				newTestPullRequest(4, "open", releaseBody),
+			}},
+			wantErrMsg:   "found 2 open release pull requests (2, 4)",
+			wantCategory: failure.UserConfig,
+		},
+		{
+			name:         "search error",
+			ghClient:     &mockGitHubClient{searchPullRequestsErr: errors.New("search error")},
+			wantErrMsg:   "search error",
+			wantCategory: failure.Unknown,
+		},
+		{
+			name:       "specified",
+			pr:         "github.com/googleapis/librarian/pulls/5",
+			ghClient:   &mockGitHubClient{pullRequest: newTestPullRequest(5, "open", releaseBody)},
+			wantNumber: 5,
+		},
+		{
+			name:         "specified is not a release pull request",
+			pr:           "github.com/googleapis/librarian/pulls/5",
+			ghClient:     &mockGitHubClient{pullRequest: newTestPullRequest(5, "open", "not a release")},
+			wantErrMsg:   "pull request 5 is not an open release pull request",
+			wantCategory: failure.UserConfig,
+		},
+		{
+			name:         "invalid -pr",
+			pr:           "5",
+			ghClient:     &mockGitHubClient{},
+			wantErrMsg:   "invalid pull request format",
+			wantCategory: failure.UserConfig,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			pr, err := findReleasePullRequest(context.Background(), &config.Config{PullRequest: test.pr}, test.ghClient)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("findReleasePullRequest() error = %v, want %q", err, test.wantErrMsg)
+				}
+				if got := failure.CategoryOf(err); got != test.wantCategory {
+					t.Errorf("failure.CategoryOf() = %v, want %v", got, test.wantCategory)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pr.GetNumber(); got != test.wantNumber {
+				t.Errorf("findReleasePullRequest() = %d, want %d", got, test.wantNumber)
+			}
+		})
+	}
+}
+
+func TestRefreshPullRequest_Gerrit(t *testing.T) {
+	t.Parallel()
+	r := &initRunner{cfg: &config.Config{}, gerrit: &gerritReview{}}
+	err := r.refreshPullRequest(context.Background())
+	if got := failure.CategoryOf(err); got != failure.UserConfig {
+		t.Errorf("refreshPullRequest() error = %v, want a user config error", err)
+	}
+}
+
+func TestRefreshPullRequest_MergeQueue(t *testing.T) {
+	t.Parallel()
+	r := &initRunner{
+		cfg: &config.Config{},
+		ghClient: &mockGitHubClient{
+			pullRequests: []*github.PullRequest{newTestPullRequest(2, "open", releasePullRequestMarker)},
+			mergeQueue:   true,
+			inMergeQueue: []bool{true},
+		},
+	}
+	err := r.refreshPullRequest(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "queued in the merge queue") {
+		t.Fatalf("refreshPullRequest() error = %v, want the pull request to be queued", err)
+	}
+	if got := failure.CategoryOf(err); got != failure.UserConfig {
+		t.Errorf("refreshPullRequest() error category = %v, want %v", got, failure.UserConfig)
+	}
+}
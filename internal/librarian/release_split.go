@@ -0,0 +1,276 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/github"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"gopkg.in/yaml.v3"
+)
+
+// releaseGroup is a set of libraries released by a single pull request, when
+// a release is split into multiple pull requests.
+type releaseGroup struct {
+	libraryIDs []string
+	// files are the paths, relative to the repository root, of the files
+	// changed for the libraries in the group.
+	files []string
+}
+
+// planReleaseGroups partitions the released libraries into groups which
+// respect the configured limits of libraries and changed files per pull
+// request. Files which do not belong to a released library, such as global
+// files, are added to the first group, except state.yaml, which each group
+// records its own libraries in, see recordRelease.
+//
+// The libraries of a release group in the state are never split across pull
+// requests. A library, or release group, which changes more files than the
+// limit on its own is released in a group of its own.
+func planReleaseGroups(cfg *config.Config, state *config.LibrarianState, libraryIDs []string, status git.Status) []*releaseGroup {
+	filesByLibrary := make(map[string][]string)
+	var shared []string
+	statePath := path.Join(config.LibrarianDir, librarianStateFile)
+	for _, file := range sortedStatusFiles(status) {
+		if file == statePath {
+			continue
+		}
+		id := libraryForFile(state, libraryIDs, file)
+		if id == "" {
+			shared = append(shared, file)
+			continue
+		}
+		filesByLibrary[id] = append(filesByLibrary[id], file)
+	}
+
+	var groups []*releaseGroup
+	current := &releaseGroup{files: shared}
+	for _, ids := range releaseUnitIDs(state, libraryIDs) {
+		var files []string
+		for _, id := range ids {
+			files = append(files, filesByLibrary[id]...)
+		}
+		tooManyLibraries := cfg.MaxReleaseLibraries > 0 && len(current.libraryIDs)+len(ids) > cfg.MaxReleaseLibraries
+		tooManyFiles := cfg.MaxReleaseFiles > 0 && len(current.files)+len(files) > cfg.MaxReleaseFiles
+		if len(current.libraryIDs) > 0 && (tooManyLibraries || tooManyFiles) {
+			groups = append(groups, current)
+			current = &releaseGroup{}
+		}
+		if cfg.MaxReleaseFiles > 0 && len(files) > cfg.MaxReleaseFiles {
+			slog.Warn("Library changes more files than a release pull request should", "library", strings.Join(ids, ", "), "files", len(files), "limit", cfg.MaxReleaseFiles)
+		}
+		current.libraryIDs = append(current.libraryIDs, ids...)
+		current.files = append(current.files, files...)
+	}
+	return append(groups, current)
+}
+
+// releaseUnitIDs partitions libraryIDs into the IDs of libraries which must be
+// released in the same pull request, according to their release groups.
+func releaseUnitIDs(state *config.LibrarianState, libraryIDs []string) [][]string {
+	var libraries []*config.LibraryState
+	for _, id := range libraryIDs {
+		library := state.LibraryByID(id)
+		if library == nil {
+			library = &config.LibraryState{ID: id}
+		}
+		libraries = append(libraries, library)
+	}
+	var units [][]string
+	for _, unit := range groupByReleaseGroup(libraries) {
+		var ids []string
+		for _, library := range unit {
+			ids = append(ids, library.ID)
+		}
+		units = append(units, ids)
+	}
+	return units
+}
+
+func sortedStatusFiles(status git.Status) []string {
+	var files []string
+	for file := range status {
+		files = append(files, file)
+	}
+	slices.Sort(files)
+	return files
+}
+
+// libraryForFile returns the ID of the library among libraryIDs with a source
+// root containing file, or an empty string if there is none.
+func libraryForFile(state *config.LibrarianState, libraryIDs []string, file string) string {
+	for _, id := range libraryIDs {
+		library := state.LibraryByID(id)
+		if library == nil {
+			continue
+		}
+		for _, root := range library.SourceRoots {
+			if file == root || strings.HasPrefix(file, strings.TrimSuffix(root, "/")+"/") {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// commitReleaseGroups commits the changes of each release group on its own
+// branch created from the current HEAD, and creates a pull request for each.
+// The state.yaml of each pull request records the release of the libraries of
+// its group only, with the release ID shared by all groups, and the pull
+// requests are cross-linked with comments.
+func (r *initRunner) commitReleaseGroups(ctx context.Context, groups []*releaseGroup, status git.Status, releaseID string) error {
+	repoDir := r.repo.GetDir()
+	base, err := r.repo.HeadHash()
+	if err != nil {
+		return err
+	}
+	timestamp := strings.TrimPrefix(releaseID, "release-")
+	slog.Info("Splitting release into multiple pull requests", "release_id", releaseID, "pull_requests", len(groups))
+
+	// Keep a copy of the changed files, as the working tree is reset for
+	// each group.
+	snapshot := filepath.Join(r.workRoot, "release-split")
+	for _, file := range sortedStatusFiles(status) {
+		if isDeleted(status, file) {
+			continue
+		}
+		if err := copyFile(filepath.Join(snapshot, file), filepath.Join(repoDir, file)); err != nil {
+			return fmt.Errorf("failed to save release changes: %w", err)
+		}
+	}
+
+	var prs []*github.PullRequestMetadata
+	for i, group := range groups {
+		if err := r.repo.CheckoutCommit(base); err != nil {
+			return fmt.Errorf("failed to reset to %s: %w", base, err)
+		}
+		for _, file := range group.files {
+			dst := filepath.Join(repoDir, file)
+			if isDeleted(status, file) {
+				if err := os.Remove(dst); err != nil && !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+				continue
+			}
+			if err := copyFile(dst, filepath.Join(snapshot, file)); err != nil {
+				return fmt.Errorf("failed to restore release changes: %w", err)
+			}
+		}
+		if err := recordRelease(ctx, repoDir, r.state, group.libraryIDs, releaseID, r.canary()); err != nil {
+			return err
+		}
+		part := fmt.Sprintf("part %d of %d", i+1, len(groups))
+		commitMessage := gitrepo.FormatMessage(
+			fmt.Sprintf("chore: release %s\n\nThis is %s of the release, for libraries: %s.", releaseID, part, strings.Join(group.libraryIDs, ", ")),
+			[]gitrepo.Trailer{
+				{Key: gitrepo.TrailerLibraryIDs, Value: strings.Join(group.libraryIDs, ",")},
+				{Key: gitrepo.TrailerReleaseID, Value: releaseID},
+			})
+		body, err := r.releasePullRequestBody(releaseID, group.libraryIDs, fmt.Sprintf("This is %s of release %s.\n\n", part, releaseID))
+		if err != nil {
+			return err
+		}
+		pr, err := commitAndCreatePullRequest(ctx, &commitInfo{
+			cfg:             r.cfg,
+			state:           r.state,
+			repo:            r.repo,
+			ghClient:        r.ghClient,
+			gerrit:          r.gerrit,
+			libraryIDs:      group.libraryIDs,
+			kind:            config.PullRequestKindRelease,
+			librarianConfig: r.librarianConfig,
+			branch:          fmt.Sprintf("%s-%s-%d", r.branchPrefix(), timestamp, i+1),
+			topic:           fmt.Sprintf("librarian-%s", timestamp),
+			title:           fmt.Sprintf("Librarian release %s (%s)", releaseID, part),
+			commitMessage:   commitMessage,
+			body:            body,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create pull request for %s: %w", part, err)
+		}
+		if pr != nil {
+			prs = append(prs, pr)
+		}
+	}
+	return linkReleasePullRequests(ctx, r.ghClient, releaseID, prs)
+}
+
+func isDeleted(status git.Status, file string) bool {
+	fileStatus := status.File(file)
+	return fileStatus.Worktree == git.Deleted || fileStatus.Staging == git.Deleted
+}
+
+// recordRelease records the release of the libraries with the given IDs in
+// the state.yaml of the repository: their versions in released, the state
+// updated for the release, and releaseID. A canary release only records the
+// canary versions, keeping the stable versions. The other libraries are left
+// as they are in state.yaml, so that each pull request of a split release
+// only releases its own libraries.
+func recordRelease(ctx context.Context, repoDir string, released *config.LibrarianState, libraryIDs []string, releaseID string, canary bool) error {
+	path := filepath.Join(repoDir, config.LibrarianDir, librarianStateFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	state := &config.LibrarianState{}
+	if err := yaml.Unmarshal(data, state); err != nil {
+		return fmt.Errorf("unmarshaling librarian state: %w", err)
+	}
+	for _, id := range libraryIDs {
+		saved := state.LibraryByID(id)
+		if saved == nil {
+			continue
+		}
+		if library := released.LibraryByID(id); library != nil {
+			saved.CanaryVersion = library.CanaryVersion
+			if !canary {
+				saved.Version = library.Version
+				saved.PreviousReleaseTag = library.PreviousReleaseTag
+			}
+		}
+		saved.ReleaseID = releaseID
+	}
+	return saveLibrarianState(ctx, repoDir, state)
+}
+
+// linkReleasePullRequests comments on each of the pull requests of a split
+// release with links to all of them.
+func linkReleasePullRequests(ctx context.Context, ghClient GitHubClient, releaseID string, prs []*github.PullRequestMetadata) error {
+	if len(prs) < 2 {
+		return nil
+	}
+	var comment strings.Builder
+	fmt.Fprintf(&comment, "Release %s is split into %d pull requests:\n\n", releaseID, len(prs))
+	for _, pr := range prs {
+		fmt.Fprintf(&comment, "* %s/pull/%d\n", pr.Repo.URL(), pr.Number)
+	}
+	for _, pr := range prs {
+		if err := ghClient.CreateIssueComment(ctx, pr.Number, comment.String()); err != nil {
+			return fmt.Errorf("failed to link pull request %d: %w", pr.Number, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gogitConfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/github"
+)
+
+func TestPlanReleaseGroups(t *testing.T) {
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "a", SourceRoots: []string{"a"}},
+			{ID: "b", SourceRoots: []string{"b"}},
+			{ID: "c", SourceRoots: []string{"c"}},
+			{ID: "unreleased", SourceRoots: []string{"unreleased"}},
+		},
+	}
+	status := git.Status{
+		"CHANGELOG.md":          &git.FileStatus{Worktree: git.Modified},
+		".librarian/state.yaml": &git.FileStatus{Worktree: git.Modified},
+		"a/1.txt":               &git.FileStatus{Worktree: git.Modified},
+		"a/2.txt":               &git.FileStatus{Worktree: git.Modified},
+		"b/1.txt":               &git.FileStatus{Worktree: git.Modified},
+		"c/1.txt":               &git.FileStatus{Worktree: git.Modified},
+		"c/2.txt":               &git.FileStatus{Worktree: git.Modified},
+		"c/3.txt":               &git.FileStatus{Worktree: git.Modified},
+		"c/4.txt":               &git.FileStatus{Worktree: git.Modified},
+		"unreleased/1.txt":      &git.FileStatus{Worktree: git.Modified},
+	}
+	libraryIDs := []string{"a", "b", "c"}
+	for _, test := range []struct {
+		name string
+		cfg  *config.Config
+		want []*releaseGroup
+	}{
+		{
+			name: "no limits",
+			cfg:  &config.Config{},
+			want: []*releaseGroup{
+				{
+					libraryIDs: []string{"a", "b", "c"},
+					files:      []string{"CHANGELOG.md", "unreleased/1.txt", "a/1.txt", "a/2.txt", "b/1.txt", "c/1.txt", "c/2.txt", "c/3.txt", "c/4.txt"},
+				},
+			},
+		},
+		{
+			name: "library limit",
+			cfg:  &config.Config{MaxReleaseLibraries: 2},
+			want: []*releaseGroup{
+				{
+					libraryIDs: []string{"a", "b"},
+					files:      []string{"CHANGELOG.md", "unreleased/1.txt", "a/1.txt", "a/2.txt", "b/1.txt"},
+				},
+				{
+					libraryIDs: []string{"c"},
+					files:      []string{"c/1.txt", "c/2.txt", "c/3.txt", "c/4.txt"},
+				},
+			},
+		},
+		{
+			name: "file limit with oversized library",
+			cfg:  &config.Config{MaxReleaseFiles: 3},
+			want: []*releaseGroup{
+				{
+					libraryIDs: []string{"a"},
+					files:      []string{"CHANGELOG.md", "unreleased/1.txt", "a/1.txt", "a/2.txt"},
+				},
+				{
+					libraryIDs: []string{"b"},
+					files:      []string{"b/1.txt"},
+				},
+				{
+					libraryIDs: []string{"c"},
+					files:      []string{"c/1.txt", "c/2.txt", "c/3.txt", "c/4.txt"},
+				},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := planReleaseGroups(test.cfg, state, libraryIDs, status)
+			if diff := cmp.Diff(test.want, got, cmp.AllowUnexported(releaseGroup{})); diff != "" {
+				t.Errorf("planReleaseGroups() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPlanReleaseGroupsKeepsReleaseGroupsTogether(t *testing.T) {
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "core", SourceRoots: []string{"core"}, ReleaseGroup: "core"},
+			{ID: "other", SourceRoots: []string{"other"}},
+			{ID: "ext", SourceRoots: []string{"ext"}, ReleaseGroup: "core"},
+		},
+	}
+	status := git.Status{
+		"core/1.txt":  &git.FileStatus{Worktree: git.Modified},
+		"ext/1.txt":   &git.FileStatus{Worktree: git.Modified},
+		"other/1.txt": &git.FileStatus{Worktree: git.Modified},
+	}
+	got := planReleaseGroups(&config.Config{MaxReleaseLibraries: 1}, state, []string{"core", "other", "ext"}, status)
+	want := []*releaseGroup{
+		{libraryIDs: []string{"core", "ext"}, files: []string{"core/1.txt", "ext/1.txt"}},
+		{libraryIDs: []string{"other"}, files: []string{"other/1.txt"}},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(releaseGroup{})); diff != "" {
+		t.Errorf("planReleaseGroups() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCommitReleaseGroups(t *testing.T) {
+	repoDir := t.TempDir()
+	base := &config.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*config.LibraryState{
+			{ID: "a", Version: "1.0.0", SourceRoots: []string{"a"}},
+			{ID: "b", Version: "2.0.0", SourceRoots: []string{"b"}},
+		},
+	}
+	state := copyLibrarianState(base)
+	state.Libraries[0].Version = "1.1.0"
+	state.Libraries[1].Version = "2.1.0"
+	if err := os.MkdirAll(filepath.Join(repoDir, config.LibrarianDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveLibrarianState(context.Background(), repoDir, base); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"a/1.txt", "b/1.txt"} {
+		path := filepath.Join(repoDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status := git.Status{
+		"a/1.txt": &git.FileStatus{Worktree: git.Modified},
+		"b/1.txt": &git.FileStatus{Worktree: git.Untracked},
+		"b/2.txt": &git.FileStatus{Worktree: git.Deleted},
+	}
+	remote := git.NewRemote(memory.NewStorage(), &gogitConfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{"https://github.com/googleapis/librarian.git"},
+	})
+	repo := &MockRepository{
+		Dir:           repoDir,
+		AddAllStatus:  status,
+		RemotesValue:  []*git.Remote{remote},
+		HeadHashValue: "1234abcd",
+	}
+	// committed are the states committed for each pull request. After each
+	// commit, the state is reset to base, like checking out the base commit.
+	var committed []*config.LibrarianState
+	repo.CommitHook = func() {
+		got, err := parseLibrarianState(filepath.Join(repoDir, config.LibrarianDir, librarianStateFile), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		committed = append(committed, got)
+		if err := saveLibrarianState(context.Background(), repoDir, base); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ghClient := &mockGitHubClient{
+		createdPR: &github.PullRequestMetadata{Number: 123, Repo: &github.Repository{Owner: "googleapis", Name: "librarian"}},
+	}
+	r := &initRunner{
+		cfg:           &config.Config{Push: true, MaxReleaseLibraries: 1},
+		state:         state,
+		previousState: base,
+		repo:          repo,
+		ghClient:      ghClient,
+		workRoot:      t.TempDir(),
+	}
+	groups := planReleaseGroups(r.cfg, state, []string{"a", "b"}, status)
+	releaseID := newReleaseID(time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC))
+	if err := r.commitReleaseGroups(context.Background(), groups, status, releaseID); err != nil {
+		t.Fatalf("commitReleaseGroups() error = %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"1234abcd", "1234abcd"}, repo.CheckedOutCommits); diff != "" {
+		t.Errorf("CheckedOutCommits mismatch (-want +got):\n%s", diff)
+	}
+	if len(repo.CreatedBranches) != 2 || !strings.HasSuffix(repo.CreatedBranches[1], "-2") {
+		t.Errorf("CreatedBranches = %v, want two numbered branches", repo.CreatedBranches)
+	}
+	if ghClient.createPullRequestCalls != 2 {
+		t.Errorf("createPullRequestCalls = %d, want 2", ghClient.createPullRequestCalls)
+	}
+	if ghClient.createIssueCommentCalls != 2 {
+		t.Errorf("createIssueCommentCalls = %d, want 2", ghClient.createIssueCommentCalls)
+	}
+	if !strings.Contains(ghClient.issueComment, "split into 2 pull requests") {
+		t.Errorf("issueComment = %q, want links to the split pull requests", ghClient.issueComment)
+	}
+
+	body := ghClient.createdPullRequestBody
+	for _, want := range []string{releasePullRequestMarker, "part 2 of 2", "<details><summary>b: 2.1.0</summary>", releaseStatusBegin} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body of the last pull request does not contain %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "<summary>a:") {
+		t.Errorf("body of the last pull request contains the release notes of library a:\n%s", body)
+	}
+
+	type recorded struct{ Version, ReleaseID string }
+	want := []map[string]recorded{
+		{"a": {"1.1.0", releaseID}, "b": {"2.0.0", ""}},
+		{"a": {"1.0.0", ""}, "b": {"2.1.0", releaseID}},
+	}
+	var got []map[string]recorded
+	for _, state := range committed {
+		libraries := make(map[string]recorded)
+		for _, library := range state.Libraries {
+			libraries[library.ID] = recorded{library.Version, library.ReleaseID}
+		}
+		got = append(got, libraries)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("committed states mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRecordRelease(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		canary bool
+		want   *config.LibraryState
+	}{
+		{
+			name: "stable",
+			want: &config.LibraryState{ID: "a", Version: "1.1.0", SourceRoots: []string{"a"}, ReleaseID: "release-1"},
+		},
+		{
+			name:   "canary",
+			canary: true,
+			want:   &config.LibraryState{ID: "a", Version: "1.0.0", SourceRoots: []string{"a"}, CanaryVersion: "1.1.0-canary.1", PreviousReleaseTag: "old-a-1.0.0", ReleaseID: "release-1"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repoDir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(repoDir, config.LibrarianDir), 0755); err != nil {
+				t.Fatal(err)
+			}
+			saved := &config.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*config.LibraryState{
+					{ID: "a", Version: "1.0.0", SourceRoots: []string{"a"}, PreviousReleaseTag: "old-a-1.0.0"},
+					{ID: "b", Version: "2.0.0", SourceRoots: []string{"b"}},
+				},
+			}
+			if err := saveLibrarianState(context.Background(), repoDir, saved); err != nil {
+				t.Fatal(err)
+			}
+			released := &config.LibrarianState{
+				Libraries: []*config.LibraryState{
+					{ID: "a", Version: "1.1.0"},
+					{ID: "b", Version: "2.1.0"},
+				},
+			}
+			if test.canary {
+				released.Libraries[0] = &config.LibraryState{ID: "a", Version: "1.1.0-canary.1", CanaryVersion: "1.1.0-canary.1", PreviousReleaseTag: "old-a-1.0.0"}
+			}
+			if err := recordRelease(context.Background(), repoDir, released, []string{"a"}, "release-1", test.canary); err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseLibrarianState(filepath.Join(repoDir, config.LibrarianDir, librarianStateFile), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got.LibraryByID("a"), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("recorded library a mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(&config.LibraryState{ID: "b", Version: "2.0.0", SourceRoots: []string{"b"}}, got.LibraryByID("b"), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("library b, which is not released, mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// The statuses of the release of a library, in the order of the release
+// lifecycle.
+const (
+	// releaseStatusPROpened is the status of a library whose release pull
+	// request is opened by release init.
+	releaseStatusPROpened = "pr-opened"
+	// releaseStatusMerged is the status of a library whose release pull
+	// request is merged and picked up by tag-and-release.
+	releaseStatusMerged = "merged"
+	// releaseStatusTagged is the status of a library whose tag and GitHub
+	// release are created.
+	releaseStatusTagged = "tagged"
+	// releaseStatusPublished is the status of a library whose release is
+	// complete, including its assets.
+	releaseStatusPublished = "published"
+)
+
+var releaseStatuses = []string{releaseStatusPROpened, releaseStatusMerged, releaseStatusTagged, releaseStatusPublished}
+
+// The markers surrounding the release status in the body of a release pull
+// request. Like the release metadata, it is in an HTML comment, so that it is
+// not rendered.
+const (
+	releaseStatusBegin = "<!-- BEGIN LIBRARIAN RELEASE STATUS"
+	releaseStatusEnd   = "END LIBRARIAN RELEASE STATUS -->"
+)
+
+// releaseStatus tracks the lifecycle of a release, identified by its release
+// ID, which is also recorded as the release_id of its libraries in
+// state.yaml. It is kept in the body of the release pull request, where
+// tag-and-release updates it after each step, so that re-running
+// tag-and-release after a partial failure skips the libraries which are
+// already tagged or published.
+type releaseStatus struct {
+	ReleaseID string                  `yaml:"release_id,omitempty"`
+	Libraries []*libraryReleaseStatus `yaml:"libraries"`
+}
+
+// libraryReleaseStatus is the status of a single library in a release.
+type libraryReleaseStatus struct {
+	ID      string `yaml:"id"`
+	Version string `yaml:"version"`
+	Status  string `yaml:"status"`
+}
+
+// newReleaseID returns the ID of a release initiated at t.
+func newReleaseID(t time.Time) string {
+	return fmt.Sprintf("release-%s", formatTimestamp(t))
+}
+
+// newReleaseStatus returns the status of a release whose pull request is
+// opened for the given libraries.
+func newReleaseStatus(releaseID string, state *config.LibrarianState, libraryIDs []string) *releaseStatus {
+	status := &releaseStatus{ReleaseID: releaseID}
+	for _, id := range libraryIDs {
+		var version string
+		if library := state.LibraryByID(id); library != nil {
+			version = library.Version
+		}
+		status.Libraries = append(status.Libraries, &libraryReleaseStatus{ID: id, Version: version, Status: releaseStatusPROpened})
+	}
+	return status
+}
+
+// reached reports whether the library with the given ID has reached the
+// given status in the release.
+func (s *releaseStatus) reached(id, status string) bool {
+	for _, library := range s.Libraries {
+		if library.ID == id {
+			return slices.Index(releaseStatuses, library.Status) >= slices.Index(releaseStatuses, status)
+		}
+	}
+	return false
+}
+
+// advance moves the library with the given ID forward to status, adding the
+// library to the release if needed. It reports whether the status changed.
+func (s *releaseStatus) advance(id, version, status string) bool {
+	if s.reached(id, status) {
+		return false
+	}
+	for _, library := range s.Libraries {
+		if library.ID == id {
+			library.Status = status
+			return true
+		}
+	}
+	s.Libraries = append(s.Libraries, &libraryReleaseStatus{ID: id, Version: version, Status: status})
+	return true
+}
+
+// format returns the release status as a block for the body of a release
+// pull request.
+func (s *releaseStatus) format() (string, error) {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal release status: %w", err)
+	}
+	return fmt.Sprintf("%s\n%s%s\n", releaseStatusBegin, data, releaseStatusEnd), nil
+}
+
+// parseReleaseStatus returns the release status in the body of a release
+// pull request, or nil if the body does not contain any.
+func parseReleaseStatus(body string) (*releaseStatus, error) {
+	_, rest, found := strings.Cut(body, releaseStatusBegin)
+	if !found {
+		return nil, nil
+	}
+	data, _, found := strings.Cut(rest, releaseStatusEnd)
+	if !found {
+		return nil, fmt.Errorf("release status is not terminated by %q", releaseStatusEnd)
+	}
+	status := &releaseStatus{}
+	if err := yaml.Unmarshal([]byte(data), status); err != nil {
+		return nil, fmt.Errorf("failed to parse release status: %w", err)
+	}
+	return status, nil
+}
+
+// replaceReleaseStatus returns body with its release status replaced by
+// status, or appended if body does not contain any.
+func replaceReleaseStatus(body string, status *releaseStatus) (string, error) {
+	block, err := status.format()
+	if err != nil {
+		return "", err
+	}
+	before, rest, found := strings.Cut(body, releaseStatusBegin)
+	if !found {
+		if body != "" && !strings.HasSuffix(body, "\n") {
+			body += "\n"
+		}
+		return body + "\n" + block, nil
+	}
+	_, after, found := strings.Cut(rest, releaseStatusEnd)
+	if !found {
+		return "", fmt.Errorf("release status is not terminated by %q", releaseStatusEnd)
+	}
+	return before + strings.TrimSuffix(block, "\n") + after, nil
+}
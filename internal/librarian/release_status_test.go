@@ -0,0 +1,112 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestReleaseStatus(t *testing.T) {
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "a", Version: "1.1.0"},
+			{ID: "b", Version: "2.0.0"},
+		},
+	}
+	releaseID := newReleaseID(time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC))
+	status := newReleaseStatus(releaseID, state, []string{"a", "b"})
+	if !status.reached("a", releaseStatusPROpened) || status.reached("a", releaseStatusMerged) {
+		t.Errorf("newReleaseStatus() = %+v, want libraries with an opened pull request", status.Libraries[0])
+	}
+	if !status.advance("a", "1.1.0", releaseStatusTagged) {
+		t.Error("advance() to tagged = false, want true")
+	}
+	if status.advance("a", "1.1.0", releaseStatusMerged) {
+		t.Error("advance() back to merged = true, want false")
+	}
+	if !status.advance("c", "3.0.0", releaseStatusMerged) {
+		t.Error("advance() of a new library = false, want true")
+	}
+
+	body, err := replaceReleaseStatus("release notes", status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(body, "release notes\n\n"+releaseStatusBegin) {
+		t.Errorf("replaceReleaseStatus() = %q, want the status appended", body)
+	}
+	status.advance("b", "2.0.0", releaseStatusPublished)
+	body, err = replaceReleaseStatus(body+"footer\n", status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(body, releaseStatusBegin) != 1 || !strings.HasSuffix(body, releaseStatusEnd+"\nfooter\n") {
+		t.Errorf("replaceReleaseStatus() = %q, want the status replaced in place", body)
+	}
+	got, err := parseReleaseStatus(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &releaseStatus{
+		ReleaseID: "release-20250610T120000Z",
+		Libraries: []*libraryReleaseStatus{
+			{ID: "a", Version: "1.1.0", Status: releaseStatusTagged},
+			{ID: "b", Version: "2.0.0", Status: releaseStatusPublished},
+			{ID: "c", Version: "3.0.0", Status: releaseStatusMerged},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parseReleaseStatus() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseReleaseStatus(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		body    string
+		want    *releaseStatus
+		wantErr bool
+	}{
+		{
+			name: "no status",
+			body: "release notes",
+		},
+		{
+			name:    "unterminated",
+			body:    releaseStatusBegin + "\nlibraries: []\n",
+			wantErr: true,
+		},
+		{
+			name: "status",
+			body: "notes\n" + releaseStatusBegin + "\nlibraries:\n  - id: a\n    version: 1.0.0\n    status: merged\n" + releaseStatusEnd,
+			want: &releaseStatus{Libraries: []*libraryReleaseStatus{{ID: "a", Version: "1.0.0", Status: releaseStatusMerged}}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseReleaseStatus(test.body)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseReleaseStatus() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("parseReleaseStatus() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// removedAPILabelPrefix is the prefix of the label identifying the issue
+// filed for a library whose APIs were removed. The rest of the label is the
+// library ID.
+const removedAPILabelPrefix = "librarian-removed-api:"
+
+// removedAPI is an API of a library which no longer exists in the API source,
+// e.g. because it was deprecated and deleted.
+type removedAPI struct {
+	Library string `json:"library"`
+	Path    string `json:"path"`
+	// LastGeneratedCommit is the commit of the API source which the library
+	// was last generated from, at which the API still existed.
+	LastGeneratedCommit string `json:"last_generated_commit"`
+	// RemovedIn is the commit of the API source which removed the API.
+	RemovedIn string `json:"removed_in"`
+}
+
+// findRemovedAPIs returns the APIs of library which were removed from the API
+// source since the library was last generated: those which do not exist in
+// apiRoot, and which a commit of sourceRepo since the last generated commit
+// of the library removed. An API which does not exist in the API source
+// without having been removed from it is not reported, as some generators do
+// not need the API definitions.
+func findRemovedAPIs(sourceRepo gitrepo.Repository, apiRoot string, library *config.LibraryState) ([]*removedAPI, error) {
+	if library.LastGeneratedCommit == "" {
+		return nil, nil
+	}
+	var removed []*removedAPI
+	for _, api := range library.APIs {
+		_, err := os.Stat(filepath.Join(apiRoot, api.Path))
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		commits, err := sourceRepo.GetCommitsForPathsSinceCommit([]string{api.Path}, library.LastGeneratedCommit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the commit which removed API %s: %w", api.Path, err)
+		}
+		if len(commits) == 0 {
+			continue
+		}
+		removed = append(removed, &removedAPI{
+			Library:             library.ID,
+			Path:                api.Path,
+			LastGeneratedCommit: library.LastGeneratedCommit,
+			RemovedIn:           commits[0].Hash.String(),
+		})
+	}
+	return removed, nil
+}
+
+// removedAPIPaths returns the paths of the removed APIs, separated by commas.
+func removedAPIPaths(removed []*removedAPI) string {
+	var paths []string
+	for _, api := range removed {
+		paths = append(paths, api.Path)
+	}
+	return strings.Join(paths, ", ")
+}
+
+// removedAPIsError is the error of generating a library whose APIs were
+// removed, which suggests how to keep generating it.
+func removedAPIsError(library *config.LibraryState, removed []*removedAPI) error {
+	return failure.New(failure.UserConfig, fmt.Errorf("APIs of library %s were removed from the API source: %s; generate it from the last commit with the APIs with -api-ref=%s, or remove the library",
+		library.ID, removedAPIPaths(removed), library.LastGeneratedCommit))
+}
+
+// blockRemovedAPIs blocks the generation of library if any of its APIs were
+// removed from the API source, so that the following runs skip it until it is
+// removed from the repository or unblocked. It returns the removed APIs, if
+// any.
+func blockRemovedAPIs(sourceRepo gitrepo.Repository, apiRoot string, library *config.LibraryState) ([]*removedAPI, error) {
+	removed, err := findRemovedAPIs(sourceRepo, apiRoot, library)
+	if err != nil || len(removed) == 0 {
+		return nil, err
+	}
+	library.Blocked = &config.GenerationBlock{
+		Reason: fmt.Sprintf("APIs removed from the API source: %s", removedAPIPaths(removed)),
+		Since:  now().Format(time.DateOnly),
+	}
+	slog.Warn("Blocked library whose APIs were removed from the API source", "library", library.ID, "apis", removedAPIPaths(removed))
+	return removed, nil
+}
+
+// removedAPIsMarkdown formats the removed APIs for the generation report.
+func removedAPIsMarkdown(removed []*removedAPI) string {
+	var b strings.Builder
+	b.WriteString("These APIs no longer exist in the API source, and the generation of their libraries is now blocked. ")
+	b.WriteString("Remove the libraries, or keep them blocked and generate them on their own from the last commit with the APIs, ")
+	b.WriteString("with `librarian generate -library=<id> -api-ref=<commit>`.\n\n")
+	for _, api := range removed {
+		fmt.Fprintf(&b, "* %s: `%s`, removed in %s, last generated from %s\n", api.Library, api.Path, api.RemovedIn, api.LastGeneratedCommit)
+	}
+	return b.String()
+}
+
+// fileRemovedAPIsIssue files a GitHub issue for library, whose APIs were
+// removed, unless an open issue for it exists.
+func fileRemovedAPIsIssue(ctx context.Context, ghClient GitHubClient, library *config.LibraryState, removed []*removedAPI) error {
+	label := removedAPILabelPrefix + library.ID
+	issue, err := ghClient.FindOpenIssueWithLabel(ctx, label)
+	if err != nil {
+		return fmt.Errorf("failed to search for existing removed API issue: %w", err)
+	}
+	if issue != nil {
+		slog.Info("Removed APIs already reported", "library", library.ID, "issue", issue.GetNumber())
+		return nil
+	}
+	title := fmt.Sprintf("APIs of %s were removed from the API source", library.ID)
+	var body strings.Builder
+	fmt.Fprintf(&body, "The APIs of library `%s` no longer exist in the API source, so its generation is now blocked:\n\n", library.ID)
+	for _, api := range removed {
+		fmt.Fprintf(&body, "* `%s`, removed in %s\n", api.Path, api.RemovedIn)
+	}
+	body.WriteString("\nRemove the library from the repository if the APIs were deleted for good. ")
+	fmt.Fprintf(&body, "To keep the library, generate it on its own from the last commit with the APIs, "+
+		"with `librarian generate -library=%s -api-ref=%s`.\n", library.ID, library.LastGeneratedCommit)
+	if len(library.Owners) > 0 {
+		fmt.Fprintf(&body, "\nOwners: %s\n", strings.Join(library.Owners, " "))
+	}
+	if _, err := ghClient.CreateIssue(ctx, title, body.String(), []string{label}); err != nil {
+		return fmt.Errorf("failed to create removed API issue: %w", err)
+	}
+	slog.Info("Filed removed API issue", "library", library.ID)
+	return nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/github"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+const (
+	lastGeneratedCommit = "1111111111111111111111111111111111111111"
+	removalCommit       = "3333333333333333333333333333333333333333"
+)
+
+// newRemovedAPISource returns an API source with the API google/kept/v1, from
+// which google/gone/v1 was removed by removalCommit.
+func newRemovedAPISource(t *testing.T) *MockRepository {
+	t.Helper()
+	dir := t.TempDir()
+	if err := writeFile(filepath.Join(dir, "google/kept/v1/kept.proto"), "syntax = \"proto3\";\n\npackage google.kept.v1;\n"); err != nil {
+		t.Fatal(err)
+	}
+	return &MockRepository{
+		Dir:           dir,
+		HeadHashValue: "4444444444444444444444444444444444444444",
+		GetCommitsForPathsSinceLastGenByPath: map[string][]*gitrepo.Commit{
+			"google/gone/v1": {{Hash: plumbing.NewHash(removalCommit), Message: "chore: remove gone v1"}},
+		},
+	}
+}
+
+func TestFindRemovedAPIs(t *testing.T) {
+	t.Parallel()
+	sourceRepo := newRemovedAPISource(t)
+	for _, test := range []struct {
+		name    string
+		library *config.LibraryState
+		want    []*removedAPI
+	}{
+		{
+			name: "removed",
+			library: &config.LibraryState{
+				ID:                  "lib",
+				LastGeneratedCommit: lastGeneratedCommit,
+				APIs:                []*config.API{{Path: "google/kept/v1"}, {Path: "google/gone/v1"}},
+			},
+			want: []*removedAPI{{Library: "lib", Path: "google/gone/v1", LastGeneratedCommit: lastGeneratedCommit, RemovedIn: removalCommit}},
+		},
+		{
+			name: "never in the API source",
+			library: &config.LibraryState{
+				ID:                  "lib",
+				LastGeneratedCommit: lastGeneratedCommit,
+				APIs:                []*config.API{{Path: "google/other/v1"}},
+			},
+		},
+		{
+			name: "never generated",
+			library: &config.LibraryState{
+				ID:   "lib",
+				APIs: []*config.API{{Path: "google/gone/v1"}},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := findRemovedAPIs(sourceRepo, sourceRepo.Dir, test.library)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("findRemovedAPIs() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerateRun_RemovedAPIs(t *testing.T) {
+	t.Parallel()
+	sourceRepo := newRemovedAPISource(t)
+	state := &config.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*config.LibraryState{
+			{
+				ID:                  "kept",
+				LastGeneratedCommit: lastGeneratedCommit,
+				APIs:                []*config.API{{Path: "google/kept/v1"}},
+				SourceRoots:         []string{"src/a"},
+			},
+			{
+				ID:                  "gone",
+				LastGeneratedCommit: lastGeneratedCommit,
+				APIs:                []*config.API{{Path: "google/gone/v1"}},
+				SourceRoots:         []string{"src/b"},
+			},
+		},
+	}
+	container := &mockContainerClient{wantLibraryGen: true}
+	ghClient := &mockGitHubClient{}
+	workRoot := t.TempDir()
+	r := &generateRunner{
+		cfg:             &config.Config{APISource: sourceRepo.Dir, FileIssues: true},
+		repo:            newTestGitRepo(t),
+		sourceRepo:      sourceRepo,
+		state:           state,
+		containerClient: container,
+		ghClient:        ghClient,
+		workRoot:        workRoot,
+	}
+	if err := r.run(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if container.generateCalls != 1 {
+		t.Errorf("run() made %d generate calls, want 1", container.generateCalls)
+	}
+	gone := state.LibraryByID("gone")
+	if gone.Blocked == nil || gone.Blocked.Reason != "APIs removed from the API source: google/gone/v1" {
+		t.Errorf("run() blocked library gone with %+v, want blocked for its removed API", gone.Blocked)
+	}
+	report, err := readGenerationReport(filepath.Join(workRoot, generationReportJSONFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*removedAPI{{Library: "gone", Path: "google/gone/v1", LastGeneratedCommit: lastGeneratedCommit, RemovedIn: removalCommit}}
+	if diff := cmp.Diff(want, report.RemovedAPIs); diff != "" {
+		t.Errorf("removed APIs mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"gone"}, report.Blocked); diff != "" {
+		t.Errorf("blocked libraries mismatch (-want +got):\n%s", diff)
+	}
+	if ghClient.createIssueCalls != 1 || !strings.Contains(ghClient.createdIssueBody, "-library=gone -api-ref="+lastGeneratedCommit) {
+		t.Errorf("run() created %d issues with body %q, want 1 suggesting to pin the library", ghClient.createIssueCalls, ghClient.createdIssueBody)
+	}
+}
+
+func TestGenerateSingleLibrary_RemovedAPIs(t *testing.T) {
+	t.Parallel()
+	sourceRepo := newRemovedAPISource(t)
+	r := &generateRunner{
+		cfg:        &config.Config{Library: "gone"},
+		repo:       newTestGitRepo(t),
+		sourceRepo: sourceRepo,
+		state: &config.LibrarianState{
+			Libraries: []*config.LibraryState{{
+				ID:                  "gone",
+				LastGeneratedCommit: lastGeneratedCommit,
+				APIs:                []*config.API{{Path: "google/gone/v1"}},
+			}},
+		},
+		containerClient: &mockContainerClient{},
+	}
+	err := r.generateSingleLibrary(t.Context(), "gone", t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "-api-ref="+lastGeneratedCommit) {
+		t.Errorf("generateSingleLibrary() error = %v, want to suggest -api-ref=%s", err, lastGeneratedCommit)
+	}
+}
+
+func TestFileRemovedAPIsIssue_Exists(t *testing.T) {
+	t.Parallel()
+	ghClient := &mockGitHubClient{openIssue: &github.Issue{}}
+	library := &config.LibraryState{ID: "gone", LastGeneratedCommit: lastGeneratedCommit}
+	removed := []*removedAPI{{Library: "gone", Path: "google/gone/v1", RemovedIn: removalCommit}}
+	if err := fileRemovedAPIsIssue(t.Context(), ghClient, library, removed); err != nil {
+		t.Fatal(err)
+	}
+	if ghClient.createIssueCalls != 0 {
+		t.Errorf("fileRemovedAPIsIssue() created %d issues, want none", ghClient.createIssueCalls)
+	}
+}
@@ -0,0 +1,358 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+const (
+	renameLibraryCmdName = "rename-library"
+
+	changelogFile = "CHANGELOG.md"
+)
+
+var cmdRenameLibrary = &cli.Command{
+	Short:     "rename-library changes the ID or source roots of a library",
+	UsageLine: "librarian rename-library -library=<id> [-new-library-id=<id>] [-new-source-roots=<paths>] [flags]",
+	Long: `Renames a library, or moves its source roots, in the language repository.
+
+The library specified with "-library" is renamed to "-new-library-id", and its
+source roots are moved to "-new-source-roots", a comma-separated list in the
+same order as the "source_roots" in ".librarian/state.yaml". At least one of
+the two flags must be specified.
+
+The following are updated:
+- The library in ".librarian/state.yaml", including the paths in
+  "release_exclude_paths", "preserve_regex" and "remove_regex".
+- The files of the source roots, which are moved. The moves are committed
+  as a whole, like "git mv", so that git detects them as renames and the
+  history of the files is preserved.
+- The paths of the source roots in ".github/CODEOWNERS" and in the
+  "CHANGELOG.md" at the root of the repository.
+- If the library has been released and the tag of its last release changes
+  with the new ID, the tag is recorded as "previous_release_tag", so that the
+  next release finds the changes since the last release.
+
+If the "-commit" or "-push" flags are specified, the changes are committed and,
+with "-push", a pull request is created.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newRenameLibraryRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return runner.run(ctx)
+	},
+}
+
+func init() {
+	cmdRenameLibrary.Init()
+	fs := cmdRenameLibrary.Flags
+	cfg := cmdRenameLibrary.Config
+
+	addFlagAuditLog(fs, cfg)
+	addFlagCommit(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLibrary(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagNewLibraryID(fs, cfg)
+	addFlagNewSourceRoots(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagPush(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+type renameLibraryRunner struct {
+	cfg             *config.Config
+	repo            gitrepo.Repository
+	state           *config.LibrarianState
+	librarianConfig *config.LibrarianConfig
+	ghClient        GitHubClient
+	gerrit          *gerritReview
+}
+
+func newRenameLibraryRunner(cfg *config.Config) (*renameLibraryRunner, error) {
+	runner, err := newCommandRunner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &renameLibraryRunner{
+		cfg:             runner.cfg,
+		repo:            runner.repo,
+		state:           runner.state,
+		librarianConfig: runner.librarianConfig,
+		ghClient:        runner.ghClient,
+		gerrit:          runner.gerrit,
+	}, nil
+}
+
+func (r *renameLibraryRunner) run(ctx context.Context) error {
+	if err := checkReviewPermissions(ctx, r.cfg, r.ghClient, r.gerrit); err != nil {
+		return err
+	}
+	library := r.state.LibraryByID(r.cfg.Library)
+	if library == nil {
+		return failure.New(failure.UserConfig, fmt.Errorf("library %q not found in state", r.cfg.Library))
+	}
+	renamed, err := renameLibraryState(r.state, library, r.cfg.NewLibraryID, splitList(r.cfg.NewSourceRoots))
+	if err != nil {
+		return failure.New(failure.UserConfig, err)
+	}
+	moves := sourceRootMoves(library, renamed)
+
+	repoDir := r.repo.GetDir()
+	for _, move := range moves {
+		if err := moveSourceRoot(repoDir, move.from, move.to); err != nil {
+			return err
+		}
+	}
+	for _, path := range []string{codeOwnersFile, changelogFile} {
+		if err := rewritePathsInFile(filepath.Join(repoDir, path), moves); err != nil {
+			return err
+		}
+	}
+	*library = *renamed
+	if err := saveLibrarianState(ctx, repoDir, r.state); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	if renamed.ID != r.cfg.Library {
+		warnLibraryIDInConfig(r.librarianConfig, r.cfg.Library)
+	}
+	slog.Info("Renamed library", "library", r.cfg.Library, "id", renamed.ID, "source_roots", renamed.SourceRoots)
+
+	return commitAndPush(ctx, &commitInfo{
+		cfg:           r.cfg,
+		state:         r.state,
+		repo:          r.repo,
+		ghClient:      r.ghClient,
+		gerrit:        r.gerrit,
+		commitMessage: fmt.Sprintf("chore: rename library %s to %s", r.cfg.Library, renamed.ID),
+		libraryIDs:    []string{renamed.ID},
+	})
+}
+
+// renameLibraryState returns a copy of library with the given ID and source
+// roots. An empty newID or newSourceRoots keeps the current value. The paths
+// of the library which are within its source roots are moved along, and the
+// tag of the last release is recorded if it changes with the ID.
+func renameLibraryState(state *config.LibrarianState, library *config.LibraryState, newID string, newSourceRoots []string) (*config.LibraryState, error) {
+	if newID == "" && len(newSourceRoots) == 0 {
+		return nil, errors.New("one of -new-library-id and -new-source-roots is required")
+	}
+	renamed := *library
+	if newID != "" && newID != library.ID {
+		if state.LibraryByID(newID) != nil {
+			return nil, fmt.Errorf("library %q already exists", newID)
+		}
+		renamed.ID = newID
+	}
+	if len(newSourceRoots) > 0 {
+		if len(newSourceRoots) != len(library.SourceRoots) {
+			return nil, fmt.Errorf("got %d new source roots, library %s has %d", len(newSourceRoots), library.ID, len(library.SourceRoots))
+		}
+		renamed.SourceRoots = newSourceRoots
+		moves := sourceRootMoves(library, &renamed)
+		renamed.ReleaseExcludePaths = rewritePathList(library.ReleaseExcludePaths, moves)
+		renamed.PreserveRegex = rewritePathList(library.PreserveRegex, moves)
+		renamed.RemoveRegex = rewritePathList(library.RemoveRegex, moves)
+	}
+	if library.Version != "" && library.PreviousReleaseTag == "" {
+		if tag := formatTag(library, ""); tag != formatTag(&renamed, "") {
+			renamed.PreviousReleaseTag = tag
+		}
+	}
+	if err := renamed.Validate(); err != nil {
+		return nil, err
+	}
+	return &renamed, nil
+}
+
+// pathMove is the move of a source root of a library.
+type pathMove struct {
+	from, to string
+}
+
+// sourceRootMoves returns the source roots of library which are moved in
+// renamed.
+func sourceRootMoves(library, renamed *config.LibraryState) []*pathMove {
+	var moves []*pathMove
+	for i, root := range library.SourceRoots {
+		if root != renamed.SourceRoots[i] {
+			moves = append(moves, &pathMove{from: root, to: renamed.SourceRoots[i]})
+		}
+	}
+	return moves
+}
+
+// moveSourceRoot moves the directory from to the directory to, relative to
+// repoDir. A missing directory is skipped, as the library may not have been
+// generated yet.
+func moveSourceRoot(repoDir, from, to string) error {
+	src := filepath.Join(repoDir, from)
+	dst := filepath.Join(repoDir, to)
+	if _, err := os.Stat(src); errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Source root does not exist, skipping move", "path", from)
+		return nil
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return failure.New(failure.UserConfig, fmt.Errorf("cannot move %s to %s: destination already exists", from, to))
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to make directory: %w", err)
+	}
+	slog.Info("Moving source root", "from", from, "to", to)
+	if err := os.Rename(src, dst); err != nil {
+		return fmt.Errorf("failed to move %s to %s: %w", from, to, err)
+	}
+	return nil
+}
+
+// rewritePathsInFile rewrites the paths of moves in the file at path, if it
+// exists.
+func rewritePathsInFile(path string, moves []*pathMove) error {
+	if len(moves) == 0 {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	content := rewritePaths(string(data), moves)
+	if content == string(data) {
+		return nil
+	}
+	slog.Info("Updating paths of moved source roots", "path", path)
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// rewritePathList returns paths with the paths of moves rewritten.
+func rewritePathList(paths []string, moves []*pathMove) []string {
+	if paths == nil {
+		return nil
+	}
+	rewritten := make([]string, 0, len(paths))
+	for _, path := range paths {
+		rewritten = append(rewritten, rewritePaths(path, moves))
+	}
+	return rewritten
+}
+
+// rewritePaths replaces each reference to a moved path in content, including
+// references to files within it, such as "/src/a/" or "src/a/README.md" for
+// the path "src/a". Paths which merely share a prefix, such as "src/ab" or
+// "other/src/a", are left untouched. Each position is rewritten at most once,
+// so that moves can be chained or swapped.
+func rewritePaths(content string, moves []*pathMove) string {
+	// Prefer the longest path when moved paths are nested.
+	moves = slices.Clone(moves)
+	slices.SortFunc(moves, func(a, b *pathMove) int {
+		return len(b.from) - len(a.from)
+	})
+	var builder strings.Builder
+	for i := 0; i < len(content); {
+		if atPathStart(content, i) {
+			if move := matchPath(content[i:], moves); move != nil {
+				builder.WriteString(move.to)
+				i += len(move.from)
+				continue
+			}
+		}
+		builder.WriteByte(content[i])
+		i++
+	}
+	return builder.String()
+}
+
+// atPathStart reports whether a repository relative path can start at
+// position i of content, optionally after a leading slash.
+func atPathStart(content string, i int) bool {
+	if i > 0 && content[i-1] == '/' {
+		i--
+	}
+	return i == 0 || !isPathByte(content[i-1])
+}
+
+// matchPath returns the move whose path content starts with, or nil.
+func matchPath(content string, moves []*pathMove) *pathMove {
+	for _, move := range moves {
+		if !strings.HasPrefix(content, move.from) {
+			continue
+		}
+		rest := content[len(move.from):]
+		if rest == "" || rest[0] == '/' || !isPathByte(rest[0]) {
+			return move
+		}
+	}
+	return nil
+}
+
+func isPathByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '.' || c == '_' || c == '-' || c == '/'
+}
+
+// warnLibraryIDInConfig warns about references to the library ID in the
+// librarian config, which need to be updated by hand.
+func warnLibraryIDInConfig(librarianConfig *config.LibrarianConfig, id string) {
+	if librarianConfig == nil {
+		return
+	}
+	for _, env := range librarianConfig.Environment {
+		if slices.Contains(env.Libraries, id) {
+			slog.Warn("Environment variable in config.yaml refers to the old library ID", "variable", env.Name, "library", id)
+		}
+	}
+	if librarianConfig.ProtectedFiles != nil {
+		for _, protected := range librarianConfig.ProtectedFiles.Libraries {
+			if protected.ID == id {
+				slog.Warn("Protected files in config.yaml refer to the old library ID", "library", id)
+			}
+		}
+	}
+}
+
+// splitList splits a comma-separated list, ignoring surrounding whitespace
+// and empty elements.
+func splitList(list string) []string {
+	var elements []string
+	for _, element := range strings.Split(list, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
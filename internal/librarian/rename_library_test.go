@@ -0,0 +1,286 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+func TestRenameLibraryState(t *testing.T) {
+	t.Parallel()
+	newLibrary := func() *config.LibraryState {
+		return &config.LibraryState{
+			ID:                  "foo",
+			Version:             "1.2.3",
+			SourceRoots:         []string{"foo", "tests/foo"},
+			ReleaseExcludePaths: []string{"foo/README.md"},
+			PreserveRegex:       []string{"foo/handwritten/.*"},
+		}
+	}
+	for _, test := range []struct {
+		name           string
+		library        *config.LibraryState
+		newID          string
+		newSourceRoots []string
+		want           *config.LibraryState
+		wantErr        bool
+	}{
+		{
+			name:    "rename",
+			library: newLibrary(),
+			newID:   "bar",
+			want: &config.LibraryState{
+				ID:                  "bar",
+				Version:             "1.2.3",
+				SourceRoots:         []string{"foo", "tests/foo"},
+				ReleaseExcludePaths: []string{"foo/README.md"},
+				PreserveRegex:       []string{"foo/handwritten/.*"},
+				PreviousReleaseTag:  "foo-1.2.3",
+			},
+		},
+		{
+			name:           "move",
+			library:        newLibrary(),
+			newSourceRoots: []string{"libs/foo", "tests/foo"},
+			want: &config.LibraryState{
+				ID:                  "foo",
+				Version:             "1.2.3",
+				SourceRoots:         []string{"libs/foo", "tests/foo"},
+				ReleaseExcludePaths: []string{"libs/foo/README.md"},
+				PreserveRegex:       []string{"libs/foo/handwritten/.*"},
+			},
+		},
+		{
+			name: "rename with tag format without id",
+			library: &config.LibraryState{
+				ID:          "foo",
+				Version:     "1.2.3",
+				SourceRoots: []string{"foo"},
+				TagFormat:   "v{version}",
+			},
+			newID: "bar",
+			want: &config.LibraryState{
+				ID:          "bar",
+				Version:     "1.2.3",
+				SourceRoots: []string{"foo"},
+				TagFormat:   "v{version}",
+			},
+		},
+		{
+			name: "rename unreleased library",
+			library: &config.LibraryState{
+				ID:          "foo",
+				SourceRoots: []string{"foo"},
+			},
+			newID: "bar",
+			want: &config.LibraryState{
+				ID:          "bar",
+				SourceRoots: []string{"foo"},
+			},
+		},
+		{
+			name: "rename again keeps previous release tag",
+			library: &config.LibraryState{
+				ID:                 "bar",
+				Version:            "1.2.3",
+				SourceRoots:        []string{"foo"},
+				PreviousReleaseTag: "foo-1.2.3",
+			},
+			newID: "baz",
+			want: &config.LibraryState{
+				ID:                 "baz",
+				Version:            "1.2.3",
+				SourceRoots:        []string{"foo"},
+				PreviousReleaseTag: "foo-1.2.3",
+			},
+		},
+		{
+			name:    "nothing to rename",
+			library: newLibrary(),
+			wantErr: true,
+		},
+		{
+			name:    "existing id",
+			library: newLibrary(),
+			newID:   "other",
+			wantErr: true,
+		},
+		{
+			name:    "invalid id",
+			library: newLibrary(),
+			newID:   "foo bar",
+			wantErr: true,
+		},
+		{
+			name:           "wrong number of source roots",
+			library:        newLibrary(),
+			newSourceRoots: []string{"libs/foo"},
+			wantErr:        true,
+		},
+		{
+			name:           "invalid source root",
+			library:        newLibrary(),
+			newSourceRoots: []string{"../foo", "tests/foo"},
+			wantErr:        true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			state := &config.LibrarianState{
+				Libraries: []*config.LibraryState{
+					test.library,
+					{ID: "other", SourceRoots: []string{"other"}},
+				},
+			}
+			got, err := renameLibraryState(state, test.library, test.newID, test.newSourceRoots)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("renameLibraryState() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("renameLibraryState() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRewritePaths(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		content string
+		moves   []*pathMove
+		want    string
+	}{
+		{
+			name:    "codeowners entries",
+			content: "/src/a/ @octocat\nsrc/a/*.go @octocat\n/src/ab/ @octocat\n",
+			moves:   []*pathMove{{from: "src/a", to: "libs/a"}},
+			want:    "/libs/a/ @octocat\nlibs/a/*.go @octocat\n/src/ab/ @octocat\n",
+		},
+		{
+			name:    "changelog links",
+			content: "- [a](src/a/CHANGELOG.md)\n- [b](other/src/a/CHANGELOG.md)\n",
+			moves:   []*pathMove{{from: "src/a", to: "libs/a"}},
+			want:    "- [a](libs/a/CHANGELOG.md)\n- [b](other/src/a/CHANGELOG.md)\n",
+		},
+		{
+			name:    "swapped paths",
+			content: "a b",
+			moves:   []*pathMove{{from: "a", to: "b"}, {from: "b", to: "a"}},
+			want:    "b a",
+		},
+		{
+			name:    "nested paths",
+			content: "src/a src/a/v2",
+			moves:   []*pathMove{{from: "src/a", to: "libs/a"}, {from: "src/a/v2", to: "libs/a-v2"}},
+			want:    "libs/a libs/a-v2",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := rewritePaths(test.content, test.moves)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("rewritePaths() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenameLibraryRun(t *testing.T) {
+	t.Parallel()
+	repo := newTestGitRepo(t)
+	repoDir := repo.GetDir()
+	for path, content := range map[string]string{
+		"src/a/a.go":      "package a",
+		codeOwnersFile:    "/src/a/ @octocat\n",
+		"CHANGELOG.md":    "- [some-library](src/a/CHANGELOG.md)\n",
+		"src/b/README.md": "unrelated",
+	} {
+		fullPath := filepath.Join(repoDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "add library")
+	state := &config.LibrarianState{
+		Image: "some/image:v1.2.3",
+		Libraries: []*config.LibraryState{
+			{
+				ID:          "some-library",
+				Version:     "1.0.0",
+				SourceRoots: []string{"src/a"},
+			},
+		},
+	}
+	r := &renameLibraryRunner{
+		cfg: &config.Config{
+			Library:        "some-library",
+			NewLibraryID:   "renamed-library",
+			NewSourceRoots: "libs/renamed",
+		},
+		repo:     repo,
+		state:    state,
+		ghClient: &mockGitHubClient{},
+	}
+	if err := r.run(context.Background()); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(repoDir, "src/a")); !os.IsNotExist(err) {
+		t.Errorf("src/a was not moved")
+	}
+	for path, want := range map[string]string{
+		"libs/renamed/a.go": "package a",
+		codeOwnersFile:      "/libs/renamed/ @octocat\n",
+		"CHANGELOG.md":      "- [some-library](libs/renamed/CHANGELOG.md)\n",
+		"src/b/README.md":   "unrelated",
+	} {
+		got, err := os.ReadFile(filepath.Join(repoDir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", path, diff)
+		}
+	}
+	got, err := parseLibrarianState(filepath.Join(repoDir, config.LibrarianDir, librarianStateFile), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &config.LibraryState{
+		ID:                 "renamed-library",
+		Version:            "1.0.0",
+		SourceRoots:        []string{"libs/renamed"},
+		PreviousReleaseTag: "some-library-1.0.0",
+	}
+	if diff := cmp.Diff(want, got.LibraryByID("renamed-library"), cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("state mismatch (-want +got):\n%s", diff)
+	}
+
+	r.cfg.Library = "unknown"
+	if err := r.run(context.Background()); failure.CategoryOf(err) != failure.UserConfig {
+		t.Errorf("run() error = %v, want %q error", err, failure.UserConfig)
+	}
+}
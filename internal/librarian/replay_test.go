@@ -0,0 +1,102 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunGenerateReplay runs the generate command end to end, replaying the
+// container run recorded in testdata/replay/generate instead of running Docker.
+func TestRunGenerateReplay(t *testing.T) {
+	const (
+		initialRepoStateDir = "../../testdata/e2e/generate/repo_init"
+		localAPISource      = "../../testdata/e2e/generate/api_root"
+		recording           = "testdata/replay/generate"
+	)
+	for _, test := range []struct {
+		name       string
+		modifyAPI  bool
+		wantErrMsg string
+	}{
+		{
+			name: "replayed",
+		},
+		{
+			name:       "inputs changed",
+			modifyAPI:  true,
+			wantErrMsg: "does not match recording",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repo := newReplayTestRepo(t, initialRepoStateDir)
+			apiSource := newReplayTestRepo(t, localAPISource)
+			if test.modifyAPI {
+				path := filepath.Join(apiSource, "google/cloud/pubsub/v1/pubsub_v1.yaml")
+				if err := os.WriteFile(path, []byte("type: google.api.Service\ntitle: Modified\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				runGit(t, apiSource, "commit", "-am", "modify API")
+			}
+
+			err := Run(t.Context(),
+				"generate",
+				"-api=google/cloud/pubsub/v1",
+				"-output="+t.TempDir(),
+				"-repo="+repo,
+				"-api-source="+apiSource,
+				"-container-replay="+recording,
+			)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("Run() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			got, err := os.ReadFile(filepath.Join(repo, "google-cloud-pubsub/v1/client.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "Generated by the test generator.\n"; string(got) != want {
+				t.Errorf("generated file = %q, want %q", got, want)
+			}
+			if _, err := os.Stat(filepath.Join(repo, "google-cloud-pubsub/v1/example.txt")); !os.IsNotExist(err) {
+				t.Errorf("removed file still exists, err = %v", err)
+			}
+		})
+	}
+}
+
+// newReplayTestRepo creates a git repository with a copy of the files in src.
+func newReplayTestRepo(t *testing.T, src string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS(src)); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "init")
+	runGit(t, dir, "config", "user.email", "test@example.com")
+	runGit(t, dir, "config", "user.name", "Test User")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "initial commit")
+	runGit(t, dir, "remote", "add", "origin", "https://github.com/googleapis/librarian.git")
+	return dir
+}
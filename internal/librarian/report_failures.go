@@ -0,0 +1,103 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+// failureLabelPrefix is the prefix of the label identifying the issue filed
+// for a failure. The rest of the label is the failure fingerprint.
+const failureLabelPrefix = "librarian-failure:"
+
+// volatileErrorText matches parts of an error message that change between
+// runs of the same failure, such as commit hashes, temporary directory names
+// and line numbers.
+var volatileErrorText = regexp.MustCompile(`[0-9a-fA-F]{7,}|[0-9]+`)
+
+// failureFingerprint returns a short identifier for a failure, which is
+// stable across repeated runs of the same command failing in the same way.
+func failureFingerprint(cmdName string, err error) string {
+	normalized := volatileErrorText.ReplaceAllString(err.Error(), "#")
+	sum := sha256.Sum256([]byte(cmdName + "\n" + normalized))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// reportFailure files a GitHub issue describing the failure of the command.
+// If an open issue for the same failure already exists, a comment is added to
+// it instead.
+func reportFailure(ctx context.Context, cfg *config.Config, ghClient GitHubClient, runErr error) error {
+	fingerprint := failureFingerprint(cfg.CommandName, runErr)
+	label := failureLabelPrefix + fingerprint
+	logs := "not available"
+	if cfg.LogURL != "" {
+		logs = cfg.LogURL
+	}
+
+	issue, err := ghClient.FindOpenIssueWithLabel(ctx, label)
+	if err != nil {
+		return fmt.Errorf("failed to search for existing failure issue: %w", err)
+	}
+	if issue != nil {
+		slog.Info("Failure already reported, adding a comment", "issue", issue.GetNumber())
+		comment := fmt.Sprintf("The failure occurred again.\n\nLibrarian version: %s\nLogs: %s\n", cli.Version(), logs)
+		if err := ghClient.CreateIssueComment(ctx, issue.GetNumber(), comment); err != nil {
+			return fmt.Errorf("failed to comment on failure issue: %w", err)
+		}
+		return nil
+	}
+
+	title := fmt.Sprintf("librarian %s failed", cfg.CommandName)
+	var body strings.Builder
+	fmt.Fprintf(&body, "The automated `librarian %s` run failed with the following error:\n\n", cfg.CommandName)
+	fmt.Fprintf(&body, "```\n%s\n```\n\n", runErr)
+	fmt.Fprintf(&body, "Failure category: %s\n", failure.CategoryOf(runErr))
+	fmt.Fprintf(&body, "Librarian version: %s\n", cli.Version())
+	fmt.Fprintf(&body, "Logs: %s\n", logs)
+	fmt.Fprintf(&body, "Fingerprint: %s\n", fingerprint)
+	if _, err := ghClient.CreateIssue(ctx, title, body.String(), []string{label}); err != nil {
+		return fmt.Errorf("failed to create failure issue: %w", err)
+	}
+	return nil
+}
+
+// newFailureReportClient creates the GitHub client for the language
+// repository that failures are reported to.
+func newFailureReportClient(cfg *config.Config) (GitHubClient, error) {
+	var languageRepo gitrepo.Repository
+	if !isRemote(cfg.Repo) {
+		var err error
+		languageRepo, err = gitrepo.NewRepository(&gitrepo.RepositoryOptions{Dir: cfg.Repo})
+		if err != nil {
+			return nil, err
+		}
+	}
+	ghRepo, err := gitHubRepository(cfg, languageRepo)
+	if err != nil {
+		return nil, err
+	}
+	return newGitHubClient(cfg, ghRepo)
+}
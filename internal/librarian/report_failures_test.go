@@ -0,0 +1,122 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/github"
+)
+
+func TestFailureFingerprint(t *testing.T) {
+	t.Parallel()
+	a := failureFingerprint("generate", errors.New("failed to generate library at commit 1a2b3c4d5e in /tmp/librarian-123"))
+	b := failureFingerprint("generate", errors.New("failed to generate library at commit 9f8e7d6c5b in /tmp/librarian-456"))
+	if a != b {
+		t.Errorf("fingerprints of the same failure differ: %s, %s", a, b)
+	}
+	c := failureFingerprint("generate", errors.New("failed to build library"))
+	if a == c {
+		t.Errorf("fingerprints of different failures are equal: %s", a)
+	}
+	d := failureFingerprint("init", errors.New("failed to build library"))
+	if c == d {
+		t.Errorf("fingerprints of different commands are equal: %s", c)
+	}
+}
+
+func TestReportFailure(t *testing.T) {
+	t.Parallel()
+	runErr := errors.New("failed to build library")
+	cfg := &config.Config{
+		CommandName: "generate",
+		LogURL:      "https://example.com/logs/1",
+	}
+	issueNumber := 7
+	wantLabel := failureLabelPrefix + failureFingerprint("generate", runErr)
+	for _, test := range []struct {
+		name             string
+		client           *mockGitHubClient
+		wantIssueCalls   int
+		wantCommentCalls int
+		wantErrMsg       string
+	}{
+		{
+			name:           "new failure",
+			client:         &mockGitHubClient{},
+			wantIssueCalls: 1,
+		},
+		{
+			name: "recurring failure",
+			client: &mockGitHubClient{
+				openIssue: &github.Issue{Number: &issueNumber},
+			},
+			wantCommentCalls: 1,
+		},
+		{
+			name: "search fails",
+			client: &mockGitHubClient{
+				findOpenIssueErr: errors.New("search failed"),
+			},
+			wantErrMsg: "failed to search for existing failure issue",
+		},
+		{
+			name: "create issue fails",
+			client: &mockGitHubClient{
+				createIssueErr: errors.New("create failed"),
+			},
+			wantIssueCalls: 1,
+			wantErrMsg:     "failed to create failure issue",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			err := reportFailure(context.Background(), cfg, test.client, runErr)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("reportFailure() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reportFailure() error = %v", err)
+			}
+			if test.client.createIssueCalls != test.wantIssueCalls {
+				t.Errorf("createIssueCalls = %d, want %d", test.client.createIssueCalls, test.wantIssueCalls)
+			}
+			if test.client.createIssueCommentCalls != test.wantCommentCalls {
+				t.Errorf("createIssueCommentCalls = %d, want %d", test.client.createIssueCommentCalls, test.wantCommentCalls)
+			}
+			if test.wantIssueCalls > 0 {
+				if diff := cmp.Diff([]string{wantLabel}, test.client.createdIssueLabels); diff != "" {
+					t.Errorf("issue labels mismatch (-want +got):\n%s", diff)
+				}
+				for _, want := range []string{runErr.Error(), cfg.LogURL} {
+					if !strings.Contains(test.client.createdIssueBody, want) {
+						t.Errorf("issue body %q does not contain %q", test.client.createdIssueBody, want)
+					}
+				}
+			}
+			if test.wantCommentCalls > 0 && !strings.Contains(test.client.issueComment, cfg.LogURL) {
+				t.Errorf("comment %q does not contain %q", test.client.issueComment, cfg.LogURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"gopkg.in/yaml.v3"
+)
+
+// reviewDecisionsFile is the file of the .librarian directory in which the
+// decisions of -review are recorded.
+const reviewDecisionsFile = "review-decisions.yaml"
+
+const (
+	// reviewAccept keeps a regenerated change.
+	reviewAccept = "accept"
+	// reviewReject reverts a regenerated change, keeping the file as it is
+	// at HEAD.
+	reviewReject = "reject"
+)
+
+// reviewDecisions is the content of reviewDecisionsFile.
+type reviewDecisions struct {
+	Decisions []*reviewDecision `yaml:"decisions"`
+}
+
+// reviewDecision is the decision taken for a regenerated change of a file.
+// It applies to the following runs as long as regeneration produces the same
+// content for the file.
+type reviewDecision struct {
+	Path     string `yaml:"path"`
+	Decision string `yaml:"decision"`
+	// GeneratedSHA256 is the SHA-256 of the content written by regeneration,
+	// or empty if regeneration deleted the file.
+	GeneratedSHA256 string `yaml:"generated_sha256,omitempty"`
+}
+
+// reviewItem is a regenerated change which needs a review.
+type reviewItem struct {
+	change *changedFile
+	// reason tells why the change needs a review.
+	reason string
+}
+
+// terminalReview prompts for the decisions of -review.
+type terminalReview struct {
+	in  *bufio.Reader
+	out io.Writer
+	// all is the decision for all the remaining changes, once taken.
+	all string
+}
+
+// newTerminalReview returns the review of -review, which reads the decisions
+// from the terminal.
+func newTerminalReview() (*terminalReview, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil, failure.New(failure.UserConfig, errors.New("-review requires an interactive terminal"))
+	}
+	return &terminalReview{in: bufio.NewReader(os.Stdin), out: os.Stderr}, nil
+}
+
+// decide shows the diff of item and asks whether to accept it.
+func (t *terminalReview) decide(item *reviewItem, diff string) (string, error) {
+	if t.all != "" {
+		return t.all, nil
+	}
+	fmt.Fprintf(t.out, "\n%s (%s, %s)\n%s", item.change.Path, item.change.Change, item.reason, diff)
+	for {
+		fmt.Fprint(t.out, "Accept this change? [y]es, [n]o, [a]ccept all remaining, [r]eject all remaining: ")
+		answer, err := t.in.ReadString('\n')
+		if err != nil && (answer == "" || !errors.Is(err, io.EOF)) {
+			return "", fmt.Errorf("review aborted: %w", err)
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return reviewAccept, nil
+		case "n", "no":
+			return reviewReject, nil
+		case "a":
+			t.all = reviewAccept
+			return reviewAccept, nil
+		case "r":
+			t.all = reviewReject
+			return reviewReject, nil
+		}
+		if errors.Is(err, io.EOF) {
+			return "", errors.New("review aborted: no decision")
+		}
+	}
+}
+
+// reviewItems returns the changes in report which need a review: those of
+// handwritten files, of files with manual edits which regeneration
+// overwrote or merged, as resolved in conflicts, and of files with recorded
+// decisions.
+func reviewItems(report *generationReport, conflicts []*regenerationConflict, decisions *reviewDecisions) []*reviewItem {
+	strategies := make(map[string]string)
+	for _, conflict := range conflicts {
+		if conflict.strategy != config.ConflictPreferManual {
+			strategies[conflict.path] = conflict.strategy
+		}
+	}
+	var items []*reviewItem
+	for _, library := range report.Libraries {
+		for _, file := range library.Files {
+			switch {
+			case strategies[file.Path] != "":
+				items = append(items, &reviewItem{change: file, reason: "edited manually, " + strategies[file.Path]})
+			case file.Kind == fileKindHandwritten:
+				items = append(items, &reviewItem{change: file, reason: "handwritten"})
+			case decisions.has(file.Path):
+				items = append(items, &reviewItem{change: file, reason: "reviewed before"})
+			}
+		}
+	}
+	return items
+}
+
+// reviewChanges applies the recorded decisions to the regenerated changes
+// which need a review, and with -review, prompts for the decisions of the
+// others and records them. Rejected changes are reverted in the working tree
+// and removed from report. It returns the paths of the rejected changes.
+func (r *generateRunner) reviewChanges(report *generationReport, conflicts []*regenerationConflict) ([]string, error) {
+	repoDir := r.repo.GetDir()
+	decisions, err := loadReviewDecisions(repoDir)
+	if err != nil {
+		return nil, err
+	}
+	items := reviewItems(report, conflicts, decisions)
+	var rejected []string
+	var recorded bool
+	for _, item := range items {
+		generated, err := readGenerated(repoDir, item.change)
+		if err != nil {
+			return nil, err
+		}
+		sum := generatedSHA256(generated)
+		decision := decisions.find(item.change.Path, sum)
+		switch {
+		case decision != "":
+			slog.Info("Applying recorded review decision", "path", item.change.Path, "decision", decision)
+		case r.review != nil:
+			head, err := readFileAtHead(r.repo, item.change.Path)
+			if err != nil {
+				return nil, err
+			}
+			diff, err := gitrepo.DiffFile(item.change.Path, head, generated)
+			if err != nil {
+				return nil, err
+			}
+			if decision, err = r.review.decide(item, diff); err != nil {
+				return nil, err
+			}
+			decisions.record(item.change.Path, sum, decision)
+			recorded = true
+		default:
+			continue
+		}
+		if decision == reviewReject {
+			if err := restoreFileAtHead(r.repo, item.change); err != nil {
+				return nil, fmt.Errorf("failed to restore %s: %w", item.change.Path, err)
+			}
+			rejected = append(rejected, item.change.Path)
+		}
+	}
+	if recorded {
+		if err := decisions.save(repoDir); err != nil {
+			return nil, err
+		}
+	}
+	report.removeFiles(rejected)
+	return rejected, nil
+}
+
+// readGenerated returns the content which regeneration wrote for change, or
+// nil if it deleted the file.
+func readGenerated(repoDir string, change *changedFile) ([]byte, error) {
+	if change.Change == fileChangeDeleted {
+		return nil, nil
+	}
+	return os.ReadFile(filepath.Join(repoDir, change.Path))
+}
+
+// readFileAtHead returns the content of the file at path at HEAD, or nil if
+// it does not exist at HEAD.
+func readFileAtHead(repo gitrepo.Repository, path string) ([]byte, error) {
+	content, err := repo.ReadFileAtHead(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return content, err
+}
+
+// generatedSHA256 returns the hex-encoded SHA-256 of content, or an empty
+// string if it is nil, i.e. the file was deleted.
+func generatedSHA256(content []byte) string {
+	if content == nil {
+		return ""
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func loadReviewDecisions(repoDir string) (*reviewDecisions, error) {
+	decisions := &reviewDecisions{}
+	data, err := os.ReadFile(filepath.Join(repoDir, config.LibrarianDir, reviewDecisionsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return decisions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, decisions); err != nil {
+		return nil, failure.New(failure.UserConfig, fmt.Errorf("failed to parse %s: %w", reviewDecisionsFile, err))
+	}
+	return decisions, nil
+}
+
+// find returns the decision recorded for the regenerated content of path
+// with the given SHA-256, or an empty string if there is none.
+func (d *reviewDecisions) find(path, sum string) string {
+	for _, decision := range d.Decisions {
+		if decision.Path == path && decision.GeneratedSHA256 == sum {
+			return decision.Decision
+		}
+	}
+	return ""
+}
+
+// has reports whether a decision is recorded for path.
+func (d *reviewDecisions) has(path string) bool {
+	return slices.ContainsFunc(d.Decisions, func(r *reviewDecision) bool { return r.Path == path })
+}
+
+// record records the decision for the regenerated content of path, replacing
+// the decisions recorded for earlier contents of path.
+func (d *reviewDecisions) record(path, sum, decision string) {
+	d.Decisions = slices.DeleteFunc(d.Decisions, func(r *reviewDecision) bool { return r.Path == path })
+	d.Decisions = append(d.Decisions, &reviewDecision{Path: path, Decision: decision, GeneratedSHA256: sum})
+	slices.SortFunc(d.Decisions, func(a, b *reviewDecision) int { return strings.Compare(a.Path, b.Path) })
+}
+
+func (d *reviewDecisions) save(repoDir string) error {
+	data, err := yaml.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(repoDir, config.LibrarianDir, reviewDecisionsFile), string(data))
+}
+
+// removeFiles removes the files with the given paths from the report, e.g.
+// because their changes were reverted.
+func (r *generationReport) removeFiles(paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	for _, library := range r.Libraries {
+		library.Files = slices.DeleteFunc(library.Files, func(file *changedFile) bool {
+			if !slices.Contains(paths, file.Path) {
+				return false
+			}
+			library.LinesDelta -= file.LinesDelta
+			return true
+		})
+		library.CodegenOnly = !slices.ContainsFunc(library.Files, func(file *changedFile) bool { return file.Kind == fileKindHandwritten })
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+// newReviewReport returns a report of the regeneration of src/a/hand.go, a
+// handwritten file, and src/a/gen.go, after writing their regenerated
+// content into the working tree of repoDir.
+func newReviewReport(t *testing.T, repoDir, hand string) *generationReport {
+	t.Helper()
+	for path, content := range map[string]string{"src/a/hand.go": hand, "src/a/gen.go": "package a // regenerated\n"} {
+		if err := writeFile(filepath.Join(repoDir, path), content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &generationReport{
+		Libraries: []*libraryGenerationReport{{
+			ID:         "some-library",
+			LinesDelta: 2,
+			Files: []*changedFile{
+				{Path: "src/a/gen.go", Change: fileChangeModified, Kind: fileKindGenerated, LinesDelta: 1},
+				{Path: "src/a/hand.go", Change: fileChangeModified, Kind: fileKindHandwritten, LinesDelta: 1},
+			},
+		}},
+	}
+}
+
+func TestReviewChanges(t *testing.T) {
+	t.Parallel()
+	repo := newTestGitRepo(t)
+	repoDir := repo.GetDir()
+	for path, content := range map[string]string{"src/a/hand.go": "package a\n", "src/a/gen.go": "package a\n"} {
+		if err := writeFile(filepath.Join(repoDir, path), content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "add files")
+
+	var output bytes.Buffer
+	r := &generateRunner{
+		repo:   repo,
+		review: &terminalReview{in: bufio.NewReader(strings.NewReader("maybe\nn\n")), out: &output},
+	}
+	report := newReviewReport(t, repoDir, "package a // regenerated\n")
+	rejected, err := r.reviewChanges(report, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"src/a/hand.go"}, rejected); diff != "" {
+		t.Errorf("reviewChanges() rejected mismatch (-want +got):\n%s", diff)
+	}
+	for _, want := range []string{"src/a/hand.go (modified, handwritten)", "+package a // regenerated"} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("reviewChanges() output = %q, want to contain %q", output.String(), want)
+		}
+	}
+	if got := strings.Count(output.String(), "Accept this change?"); got != 2 {
+		t.Errorf("reviewChanges() prompted %d times, want 2 for an invalid answer", got)
+	}
+	got, err := os.ReadFile(filepath.Join(repoDir, "src/a/hand.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "package a\n" {
+		t.Errorf("src/a/hand.go = %q, want the content at HEAD", got)
+	}
+	wantLibrary := &libraryGenerationReport{
+		ID:          "some-library",
+		CodegenOnly: true,
+		LinesDelta:  1,
+		Files:       []*changedFile{{Path: "src/a/gen.go", Change: fileChangeModified, Kind: fileKindGenerated, LinesDelta: 1}},
+	}
+	if diff := cmp.Diff(wantLibrary, report.Libraries[0]); diff != "" {
+		t.Errorf("report mismatch (-want +got):\n%s", diff)
+	}
+
+	// The following run applies the recorded decision without -review.
+	r.review = nil
+	report = newReviewReport(t, repoDir, "package a // regenerated\n")
+	rejected, err = r.reviewChanges(report, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"src/a/hand.go"}, rejected); diff != "" {
+		t.Errorf("reviewChanges() with recorded decision rejected mismatch (-want +got):\n%s", diff)
+	}
+
+	// The decision does not apply to other regenerated content.
+	report = newReviewReport(t, repoDir, "package a // regenerated again\n")
+	rejected, err = r.reviewChanges(report, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejected) != 0 {
+		t.Errorf("reviewChanges() with other content rejected %v, want none", rejected)
+	}
+}
+
+func TestReviewChanges_Conflicts(t *testing.T) {
+	t.Parallel()
+	repo := newTestGitRepo(t)
+	r := &generateRunner{
+		repo:   repo,
+		review: &terminalReview{in: bufio.NewReader(strings.NewReader("a\n")), out: &bytes.Buffer{}},
+	}
+	report := newReviewReport(t, repo.GetDir(), "package a // regenerated\n")
+	conflicts := []*regenerationConflict{{path: "src/a/gen.go", strategy: config.ConflictPreferGenerated}}
+	rejected, err := r.reviewChanges(report, conflicts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejected) != 0 {
+		t.Errorf("reviewChanges() rejected %v, want none", rejected)
+	}
+	decisions, err := loadReviewDecisions(repo.GetDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, decision := range decisions.Decisions {
+		got = append(got, decision.Path+" "+decision.Decision)
+	}
+	want := []string{"src/a/gen.go accept", "src/a/hand.go accept"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("recorded decisions mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTerminalReview_Aborted(t *testing.T) {
+	t.Parallel()
+	review := &terminalReview{in: bufio.NewReader(strings.NewReader("maybe")), out: &bytes.Buffer{}}
+	item := &reviewItem{change: &changedFile{Path: "a.go", Change: fileChangeModified}, reason: "handwritten"}
+	if _, err := review.decide(item, ""); err == nil || !strings.Contains(err.Error(), "review aborted") {
+		t.Errorf("decide() error = %v, want review aborted", err)
+	}
+}
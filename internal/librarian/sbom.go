@@ -0,0 +1,87 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/sbom"
+)
+
+// sbomLibrary returns the SBOM description of library, generated by image
+// from the API source at the given commit, with the dependencies reported by
+// the language container.
+func sbomLibrary(library *config.LibraryState, image, apiSource, apiSourceCommit string, dependencies []*config.Dependency) *sbom.Library {
+	l := &sbom.Library{
+		ID:              library.ID,
+		Version:         library.Version,
+		Image:           image,
+		APISource:       apiSource,
+		APISourceCommit: apiSourceCommit,
+	}
+	for _, api := range library.APIs {
+		l.APIs = append(l.APIs, api.Path)
+	}
+	for _, dependency := range dependencies {
+		l.Packages = append(l.Packages, &sbom.Package{
+			Name:    dependency.Name,
+			Version: dependency.Version,
+			PURL:    dependency.PURL,
+		})
+	}
+	return l
+}
+
+// marshalSBOM returns the SBOM of libraries as JSON.
+func marshalSBOM(libraries []*sbom.Library) ([]byte, error) {
+	return sbom.New(cli.Version(), now(), libraries).Marshal()
+}
+
+// writeSBOM writes the SBOM of libraries into dir.
+func writeSBOM(dir string, libraries []*sbom.Library) error {
+	data, err := marshalSBOM(libraries)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, sbom.FileName)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write SBOM: %w", err)
+	}
+	slog.Info("Wrote SBOM", "path", path)
+	return nil
+}
+
+// mergeDependencies returns dependencies with additional appended, skipping
+// those already listed with the same name and version.
+func mergeDependencies(dependencies, additional []*config.Dependency) []*config.Dependency {
+	for _, dependency := range additional {
+		found := false
+		for _, existing := range dependencies {
+			if existing.Name == dependency.Name && existing.Version == dependency.Version {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dependencies = append(dependencies, dependency)
+		}
+	}
+	return dependencies
+}
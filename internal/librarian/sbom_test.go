@@ -0,0 +1,111 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/sbom"
+)
+
+func TestSBOMLibrary(t *testing.T) {
+	t.Parallel()
+	library := &config.LibraryState{
+		ID:      "foo",
+		Version: "1.2.3",
+		APIs:    []*config.API{{Path: "google/cloud/foo/v1"}, {Path: "google/cloud/foo/v2"}},
+	}
+	dependencies := []*config.Dependency{
+		{Name: "google.golang.org/grpc", Version: "v1.2.3", PURL: "pkg:golang/google.golang.org/grpc@v1.2.3"},
+	}
+	got := sbomLibrary(library, "some/image:v1", "https://github.com/googleapis/googleapis", "abc123", dependencies)
+	want := &sbom.Library{
+		ID:              "foo",
+		Version:         "1.2.3",
+		Image:           "some/image:v1",
+		APISource:       "https://github.com/googleapis/googleapis",
+		APISourceCommit: "abc123",
+		APIs:            []string{"google/cloud/foo/v1", "google/cloud/foo/v2"},
+		Packages: []*sbom.Package{
+			{Name: "google.golang.org/grpc", Version: "v1.2.3", PURL: "pkg:golang/google.golang.org/grpc@v1.2.3"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("sbomLibrary() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeDependencies(t *testing.T) {
+	t.Parallel()
+	got := mergeDependencies(
+		[]*config.Dependency{{Name: "a", Version: "v1"}},
+		[]*config.Dependency{{Name: "a", Version: "v1"}, {Name: "a", Version: "v2"}, {Name: "b", Version: "v1"}},
+	)
+	want := []*config.Dependency{{Name: "a", Version: "v1"}, {Name: "a", Version: "v2"}, {Name: "b", Version: "v1"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mergeDependencies() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateRunnerWriteSBOM(t *testing.T) {
+	t.Parallel()
+	workRoot := t.TempDir()
+	r := &generateRunner{
+		cfg:       &config.Config{APISource: "https://github.com/googleapis/googleapis"},
+		apiSource: &apiSourceProvenance{Commit: "abc123", Dirty: true},
+		state: &config.LibrarianState{
+			Libraries: []*config.LibraryState{
+				{ID: "foo", APIs: []*config.API{{Path: "google/cloud/foo/v1"}}},
+				{ID: "bar", APIs: []*config.API{{Path: "google/cloud/bar/v1"}}},
+			},
+		},
+		workRoot:     workRoot,
+		image:        "some/image:v1",
+		dependencies: make(map[string][]*config.Dependency),
+	}
+	r.recordDependencies("foo", &config.LibraryState{
+		Dependencies: []*config.Dependency{{Name: "google.golang.org/grpc", Version: "v1.2.3"}},
+	})
+	r.recordDependencies("foo", nil)
+	if err := r.writeSBOM([]string{"foo"}); err != nil {
+		t.Fatalf("writeSBOM() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(workRoot, sbom.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got sbom.BOM
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []*sbom.Dependency{
+		{
+			Ref: "library:foo",
+			DependsOn: []string{
+				"image:some/image:v1",
+				"api:google/cloud/foo/v1@abc123+dirty",
+				"package:google.golang.org/grpc@v1.2.3",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got.Dependencies); diff != "" {
+		t.Errorf("writeSBOM() dependencies mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+const (
+	// scaffoldTemplatesDir is the directory of the templates of the files of
+	// new libraries in the language repository, relative to the librarian
+	// directory.
+	scaffoldTemplatesDir = "templates/new-library"
+	// configureTemplatesDir is the directory, relative to the librarian
+	// directory, where the configure container may write the templates of
+	// the files of the new library. It is removed after scaffolding.
+	configureTemplatesDir = "configure-templates"
+	// scaffoldTemplateSuffix is removed from the names of the templates.
+	scaffoldTemplateSuffix = ".tmpl"
+)
+
+// scaffoldData is the data of the templates of the files of a new library.
+type scaffoldData struct {
+	*config.LibraryState
+	// SourceRoot is the first source root of the library, where its files are
+	// scaffolded.
+	SourceRoot string
+	// Year is the current year, e.g. for copyright headers.
+	Year int
+}
+
+// scaffoldLibrary renders the templates of new libraries into the first
+// source root of library, and returns the paths of the written files relative
+// to repoDir.
+//
+// The templates are the files in .librarian/templates/new-library of the
+// language repository, and those written by the configure container into
+// .librarian/configure-templates; a template of the language repository
+// takes precedence over the template of the container at the same path. The
+// contents and paths of the templates are Go templates of scaffoldData, and
+// the .tmpl suffix is removed from their names. Existing files are left
+// untouched. The scaffolded files are added to the preserve_regex of the
+// library, so that regenerating the library keeps them.
+func scaffoldLibrary(repoDir string, library *config.LibraryState) ([]string, error) {
+	librarianDir := filepath.Join(repoDir, config.LibrarianDir)
+	containerDir := filepath.Join(librarianDir, configureTemplatesDir)
+	defer func() {
+		if err := os.RemoveAll(containerDir); err != nil {
+			slog.Warn("failed to remove configure templates", "dir", containerDir, "error", err)
+		}
+	}()
+	templates := make(map[string]string)
+	for _, dir := range []string{containerDir, filepath.Join(librarianDir, scaffoldTemplatesDir)} {
+		if err := collectScaffoldTemplates(dir, templates); err != nil {
+			return nil, err
+		}
+	}
+	if len(templates) == 0 {
+		return nil, nil
+	}
+	if len(library.SourceRoots) == 0 {
+		slog.Warn("library has no source root, skipping scaffolding", "id", library.ID)
+		return nil, nil
+	}
+	data := &scaffoldData{
+		LibraryState: library,
+		SourceRoot:   library.SourceRoots[0],
+		Year:         now().Year(),
+	}
+	var written []string
+	for _, name := range slices.Sorted(maps.Keys(templates)) {
+		target, err := renderScaffoldTemplate(name, name, data)
+		if err != nil {
+			return nil, err
+		}
+		target = path.Join(data.SourceRoot, strings.TrimSuffix(target, scaffoldTemplateSuffix))
+		if !filepath.IsLocal(target) {
+			return nil, failure.New(failure.UserConfig, fmt.Errorf("template %s renders to %s, which is outside the language repository", name, target))
+		}
+		dst := filepath.Join(repoDir, target)
+		if _, err := os.Stat(dst); err == nil {
+			slog.Info("Skip scaffolding existing file", "path", target)
+			continue
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		src := templates[name]
+		content, err := os.ReadFile(src)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		rendered, err := renderScaffoldTemplate(name, string(content), data)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(src)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(dst, []byte(rendered), info.Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("failed to scaffold %s: %w", target, err)
+		}
+		slog.Info("Scaffolded file", "library", library.ID, "path", target)
+		written = append(written, target)
+		preserved, err := matchesAny(library.PreserveRegex, target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid preserve_regex of library %s: %w", library.ID, err)
+		}
+		if !preserved {
+			library.PreserveRegex = append(library.PreserveRegex, "^"+regexp.QuoteMeta(target)+"$")
+		}
+	}
+	return written, nil
+}
+
+// collectScaffoldTemplates adds the templates in dir to templates, keyed by
+// their slash-separated paths relative to dir. A missing dir has no
+// templates.
+func collectScaffoldTemplates(dir string, templates map[string]string) error {
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		templates[filepath.ToSlash(rel)] = p
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read templates in %s: %w", dir, err)
+	}
+	return nil
+}
+
+// renderScaffoldTemplate renders text, the content or path of the template
+// name, with data.
+func renderScaffoldTemplate(name, text string, data *scaffoldData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", failure.New(failure.UserConfig, fmt.Errorf("invalid template %s: %w", name, err))
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", failure.New(failure.UserConfig, fmt.Errorf("failed to render template %s: %w", name, err))
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+func TestScaffoldLibrary(t *testing.T) {
+	repoDir := t.TempDir()
+	librarianDir := filepath.Join(repoDir, config.LibrarianDir)
+	for path, content := range map[string]string{
+		filepath.Join(scaffoldTemplatesDir, "README.md.tmpl"):  "# {{.ID}}\n\n{{range .APIs}}- {{.Path}}\n{{end}}",
+		filepath.Join(scaffoldTemplatesDir, "{{.ID}}.owners"):  "{{range .Owners}}{{.}}\n{{end}}",
+		filepath.Join(configureTemplatesDir, "README.md.tmpl"): "overridden by the language repository",
+		filepath.Join(configureTemplatesDir, "BUILD.bazel"):    "# {{.SourceRoot}}, {{.Year}}\n",
+		filepath.Join(configureTemplatesDir, "CHANGELOG.md"):   "existing files are kept",
+	} {
+		if err := writeFile(filepath.Join(librarianDir, path), content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeFile(filepath.Join(repoDir, "packages/a/CHANGELOG.md"), "# Changelog\n"); err != nil {
+		t.Fatal(err)
+	}
+	library := &config.LibraryState{
+		ID:            "a",
+		APIs:          []*config.API{{Path: "google/a/v1"}},
+		Owners:        []string{"@octocat"},
+		SourceRoots:   []string{"packages/a"},
+		PreserveRegex: []string{"^packages/a/README\\.md$"},
+	}
+	written, err := scaffoldLibrary(repoDir, library)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"packages/a/BUILD.bazel", "packages/a/README.md", "packages/a/a.owners"}, written); diff != "" {
+		t.Errorf("written files mismatch (-want +got):\n%s", diff)
+	}
+	for path, want := range map[string]string{
+		"packages/a/README.md":    "# a\n\n- google/a/v1\n",
+		"packages/a/a.owners":     "@octocat\n",
+		"packages/a/BUILD.bazel":  fmt.Sprintf("# packages/a, %d\n", now().Year()),
+		"packages/a/CHANGELOG.md": "# Changelog\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(repoDir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", path, diff)
+		}
+	}
+	wantPreserve := []string{"^packages/a/README\\.md$", "^packages/a/BUILD\\.bazel$", "^packages/a/a\\.owners$"}
+	if diff := cmp.Diff(wantPreserve, library.PreserveRegex); diff != "" {
+		t.Errorf("preserve_regex mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := os.Stat(filepath.Join(librarianDir, configureTemplatesDir)); !os.IsNotExist(err) {
+		t.Errorf("configure templates are not removed, stat error = %v", err)
+	}
+}
+
+func TestScaffoldLibrary_Invalid(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		template string
+		content  string
+	}{
+		{name: "unknown field", template: "README.md", content: "{{.Unknown}}"},
+		{name: "syntax error", template: "README.md", content: "{{.ID"},
+		{name: "outside the repository", template: "{{.ID}}", content: ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repoDir := t.TempDir()
+			path := filepath.Join(repoDir, config.LibrarianDir, scaffoldTemplatesDir, test.template)
+			if err := writeFile(path, test.content); err != nil {
+				t.Fatal(err)
+			}
+			library := &config.LibraryState{ID: "../../..", SourceRoots: []string{"packages/a"}}
+			if _, err := scaffoldLibrary(repoDir, library); failure.CategoryOf(err) != failure.UserConfig {
+				t.Errorf("scaffoldLibrary() error = %v, want a %s error", err, failure.UserConfig)
+			}
+		})
+	}
+}
+
+func TestScaffoldLibrary_NoTemplates(t *testing.T) {
+	library := &config.LibraryState{ID: "a", SourceRoots: []string{"packages/a"}}
+	written, err := scaffoldLibrary(t.TempDir(), library)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 0 || len(library.PreserveRegex) != 0 {
+		t.Errorf("scaffoldLibrary() without templates = %v, preserve_regex %v, want nothing", written, library.PreserveRegex)
+	}
+}
@@ -0,0 +1,365 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/semver"
+)
+
+var cmdSelfUpdate = &cli.Command{
+	Short:     "self-update replaces the librarian binary with a release",
+	UsageLine: "librarian self-update -release-manifest=<url> [flags] [version]",
+	Long: `Replaces the running librarian binary with the binary of a release, the latest
+one by default, for the operating system and architecture of the machine.
+
+The releases and their binaries are listed in the release manifest at
+-release-manifest, a JSON file:
+
+  {
+    "latest": "v0.5.0",
+    "releases": [
+      {
+        "version": "v0.5.0",
+        "binaries": [
+          {
+            "os": "linux",
+            "arch": "amd64",
+            "url": "librarian-v0.5.0-linux-amd64",
+            "sha256": "<hex-encoded SHA-256 checksum>",
+            "signature": "<base64-encoded Ed25519 signature>"
+          }
+        ]
+      }
+    ]
+  }
+
+The URLs of the binaries are relative to the manifest. The SHA-256 checksum of
+the downloaded binary is always verified. With -release-public-key, its
+Ed25519 signature is verified too, and a binary without a signature is
+rejected. With -dry-run, the binary is downloaded and verified, but not
+installed.`,
+}
+
+func init() {
+	cmdSelfUpdate.Init()
+	// The version to update to is the argument after the flags, which is only
+	// known once they are parsed.
+	cmdSelfUpdate.Run = func(ctx context.Context, cfg *config.Config) error {
+		var version string
+		switch args := cmdSelfUpdate.Flags.Args(); len(args) {
+		case 0:
+		case 1:
+			version = args[0]
+		default:
+			return failure.New(failure.UserConfig, fmt.Errorf("expected at most one version, got %q", args))
+		}
+		executable, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		if executable, err = filepath.EvalSymlinks(executable); err != nil {
+			return err
+		}
+		return selfUpdate(ctx, os.Stdout, cfg, cli.Version(), version, executable)
+	}
+	fs := cmdSelfUpdate.Flags
+	cfg := cmdSelfUpdate.Config
+
+	addFlagDryRun(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagReleaseManifest(fs, cfg)
+	addFlagReleasePublicKey(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+}
+
+// selfUpdateHTTPClient downloads the binaries of librarian, which take longer
+// than configs.
+var selfUpdateHTTPClient = &http.Client{Timeout: 10 * time.Minute}
+
+// releaseManifest lists the releases of librarian and their binaries.
+type releaseManifest struct {
+	// Latest is the version of the latest release.
+	Latest   string             `json:"latest"`
+	Releases []*manifestRelease `json:"releases"`
+}
+
+// manifestRelease is a release of librarian.
+type manifestRelease struct {
+	Version  string            `json:"version"`
+	Binaries []*manifestBinary `json:"binaries"`
+}
+
+// manifestBinary is the binary of a release for an operating system and an
+// architecture, with the values of runtime.GOOS and runtime.GOARCH.
+type manifestBinary struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// URL is the URL of the binary, relative to the manifest.
+	URL string `json:"url"`
+	// SHA256 is the hex-encoded SHA-256 checksum of the binary.
+	SHA256 string `json:"sha256"`
+	// Signature is the base64-encoded Ed25519 signature of the binary.
+	Signature string `json:"signature,omitempty"`
+}
+
+// release returns the release with the given version, or nil.
+func (m *releaseManifest) release(version string) *manifestRelease {
+	for _, release := range m.Releases {
+		if sameVersion(release.Version, version) {
+			return release
+		}
+	}
+	return nil
+}
+
+// binary returns the binary of the release for goos and goarch, or nil.
+func (r *manifestRelease) binary(goos, goarch string) *manifestBinary {
+	for _, binary := range r.Binaries {
+		if binary.OS == goos && binary.Arch == goarch {
+			return binary
+		}
+	}
+	return nil
+}
+
+// loadReleaseManifest reads the release manifest at location, a URL or a
+// path.
+func loadReleaseManifest(location string) (*releaseManifest, error) {
+	if location == "" {
+		return nil, failure.New(failure.UserConfig, errors.New("no release manifest, specify it with -release-manifest"))
+	}
+	data, err := readConfigLocation(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read release manifest %s: %w", location, err)
+	}
+	manifest := &releaseManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse release manifest %s: %w", location, err)
+	}
+	if _, err := parseLibrarianVersion(manifest.Latest); err != nil {
+		return nil, fmt.Errorf("invalid latest version of release manifest %s: %w", location, err)
+	}
+	return manifest, nil
+}
+
+// parseLibrarianVersion parses a version of librarian, with or without its
+// "v" prefix.
+func parseLibrarianVersion(version string) (*semver.Version, error) {
+	return semver.Parse(strings.TrimPrefix(version, "v"))
+}
+
+// sameVersion reports whether a and b are the same version of librarian,
+// regardless of their "v" prefixes.
+func sameVersion(a, b string) bool {
+	return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
+
+// compareLibrarianVersions compares running, the version of the running
+// librarian, with version. It reports false if running is not the version of
+// a release, e.g. the pseudo-version of a build from source.
+func compareLibrarianVersions(running, version string) (int, bool) {
+	runningVersion, err := parseLibrarianVersion(running)
+	if err != nil || strings.HasPrefix(running, "0.0.0-") {
+		return 0, false
+	}
+	other, err := parseLibrarianVersion(version)
+	if err != nil {
+		return 0, false
+	}
+	return runningVersion.Compare(other), true
+}
+
+// checkLibrarianVersion returns an error if running, the version of the
+// running librarian, is older than pinned, the librarian_version of
+// config.yaml. A newer release, or a build which is not a release, is only
+// warned about.
+func checkLibrarianVersion(pinned, running string) error {
+	if pinned == "" {
+		return nil
+	}
+	c, ok := compareLibrarianVersions(running, pinned)
+	switch {
+	case !ok:
+		slog.Warn("cannot check the pinned librarian version against a build which is not a release", "pinned", pinned, "running", running)
+	case c < 0:
+		return failure.New(failure.UserConfig, fmt.Errorf("librarian %s is older than librarian_version %s of config.yaml; run librarian self-update %s", running, pinned, pinned))
+	case c > 0:
+		slog.Warn("librarian is newer than the pinned librarian version", "pinned", pinned, "running", running)
+	}
+	return nil
+}
+
+// checkForUpdate writes running, the version of the running librarian, to w,
+// and whether the release manifest at cfg.ReleaseManifest has a newer
+// release.
+func checkForUpdate(w io.Writer, cfg *config.Config, running string) error {
+	fmt.Fprintln(w, running)
+	manifest, err := loadReleaseManifest(cfg.ReleaseManifest)
+	if err != nil {
+		return err
+	}
+	c, ok := compareLibrarianVersions(running, manifest.Latest)
+	switch {
+	case !ok:
+		fmt.Fprintf(w, "This build is not a release; the latest release is %s.\n", manifest.Latest)
+	case c < 0:
+		fmt.Fprintf(w, "A newer version of librarian is available: %s. Run \"librarian self-update\" to update.\n", manifest.Latest)
+	default:
+		fmt.Fprintln(w, "librarian is up to date.")
+	}
+	return nil
+}
+
+// selfUpdate replaces executable, the binary of the running librarian of
+// version running, with the binary of version, or of the latest release if
+// version is empty, from the release manifest of cfg. It writes what it does
+// to w.
+func selfUpdate(ctx context.Context, w io.Writer, cfg *config.Config, running, version, executable string) error {
+	manifest, err := loadReleaseManifest(cfg.ReleaseManifest)
+	if err != nil {
+		return err
+	}
+	if version == "" {
+		version = manifest.Latest
+		if c, ok := compareLibrarianVersions(running, version); ok && c >= 0 {
+			fmt.Fprintf(w, "librarian %s is up to date.\n", running)
+			return nil
+		}
+	}
+	release := manifest.release(version)
+	if release == nil {
+		return failure.New(failure.UserConfig, fmt.Errorf("release %s not found in release manifest %s", version, cfg.ReleaseManifest))
+	}
+	binary := release.binary(runtime.GOOS, runtime.GOARCH)
+	if binary == nil {
+		return failure.New(failure.UserConfig, fmt.Errorf("release %s has no binary for %s/%s", release.Version, runtime.GOOS, runtime.GOARCH))
+	}
+	location, err := resolveConfigLocation(cfg.ReleaseManifest, binary.URL)
+	if err != nil {
+		return err
+	}
+	slog.Info("Downloading librarian", "version", release.Version, "url", location)
+	data, err := downloadBinary(ctx, location)
+	if err != nil {
+		return fmt.Errorf("failed to download librarian %s: %w", release.Version, err)
+	}
+	if err := verifyBinary(data, binary, cfg.ReleasePublicKey); err != nil {
+		return fmt.Errorf("failed to verify librarian %s: %w", release.Version, err)
+	}
+	if cfg.DryRun {
+		fmt.Fprintf(w, "Downloaded and verified librarian %s; not installing it with -dry-run.\n", release.Version)
+		return nil
+	}
+	if err := replaceExecutable(executable, data); err != nil {
+		return fmt.Errorf("failed to install librarian %s: %w", release.Version, err)
+	}
+	fmt.Fprintf(w, "Updated librarian from %s to %s.\n", running, release.Version)
+	return nil
+}
+
+// downloadBinary returns the content of the binary at location, a URL or a
+// path.
+func downloadBinary(ctx context.Context, location string) ([]byte, error) {
+	if !isURL(location) {
+		return os.ReadFile(location)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := selfUpdateHTTPClient.Do(req)
+	if err != nil {
+		return nil, failure.New(failure.TransientInfra, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// verifyBinary checks data, the content of binary, against its checksum and,
+// if publicKey is not empty, against its signature.
+func verifyBinary(data []byte, binary *manifestBinary, publicKey string) error {
+	sum := sha256.Sum256(data)
+	want, err := hex.DecodeString(binary.SHA256)
+	if err != nil || len(want) != sha256.Size {
+		return fmt.Errorf("invalid checksum %q in release manifest", binary.SHA256)
+	}
+	if !bytes.Equal(sum[:], want) {
+		return fmt.Errorf("checksum mismatch: got %x, want %s", sum, binary.SHA256)
+	}
+	if publicKey == "" {
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return failure.New(failure.UserConfig, errors.New("invalid -release-public-key"))
+	}
+	if binary.Signature == "" {
+		return errors.New("binary is not signed")
+	}
+	signature, err := base64.StdEncoding.DecodeString(binary.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature in release manifest: %w", err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), data, signature) {
+		return errors.New("signature does not match -release-public-key")
+	}
+	return nil
+}
+
+// replaceExecutable atomically replaces the file at path with an executable
+// with the given content. The new file is written next to path and renamed
+// over it, so that an interrupted update leaves the old binary in place.
+func replaceExecutable(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
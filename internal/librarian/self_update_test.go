@@ -0,0 +1,302 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/googleapis/librarian/internal/config"
+)
+
+// serveReleaseManifest serves a release manifest whose latest release, v0.5.0,
+// has a binary of the given content for the platform of the test, signed with
+// key if it is not nil. The server responds with served for the binary, so
+// that tests can corrupt the download. It returns the URL of the manifest.
+func serveReleaseManifest(t *testing.T, content, served []byte, key ed25519.PrivateKey) string {
+	t.Helper()
+	sum := sha256.Sum256(content)
+	binary := &manifestBinary{
+		OS:     runtime.GOOS,
+		Arch:   runtime.GOARCH,
+		URL:    "binaries/librarian",
+		SHA256: hex.EncodeToString(sum[:]),
+	}
+	if key != nil {
+		binary.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, content))
+	}
+	manifest, err := json.Marshal(&releaseManifest{
+		Latest:   "v0.5.0",
+		Releases: []*manifestRelease{{Version: "v0.5.0", Binaries: []*manifestBinary{binary}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/releases/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(manifest)
+	})
+	mux.HandleFunc("/releases/binaries/librarian", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(served)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server.URL + "/releases/manifest.json"
+}
+
+func TestSelfUpdate(t *testing.T) {
+	t.Parallel()
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("new librarian")
+	for _, test := range []struct {
+		name        string
+		running     string
+		version     string
+		signedWith  ed25519.PrivateKey
+		publicKey   string
+		corrupt     bool
+		dryRun      bool
+		wantContent string
+		wantOutput  string
+		wantErr     string
+	}{
+		{
+			name:        "update to latest",
+			running:     "v0.4.0",
+			wantContent: "new librarian",
+			wantOutput:  "Updated librarian from v0.4.0 to v0.5.0.",
+		},
+		{
+			name:        "update to version",
+			running:     "v0.6.0",
+			version:     "0.5.0",
+			wantContent: "new librarian",
+			wantOutput:  "Updated librarian from v0.6.0 to v0.5.0.",
+		},
+		{
+			name:        "up to date",
+			running:     "v0.5.0",
+			wantContent: "old librarian",
+			wantOutput:  "librarian v0.5.0 is up to date.",
+		},
+		{
+			name:        "build from source",
+			running:     "0.0.0-123456789000-20251006115905",
+			wantContent: "new librarian",
+			wantOutput:  "Updated librarian",
+		},
+		{
+			name:        "dry run",
+			running:     "v0.4.0",
+			dryRun:      true,
+			wantContent: "old librarian",
+			wantOutput:  "not installing it with -dry-run",
+		},
+		{
+			name:        "signed",
+			running:     "v0.4.0",
+			signedWith:  privateKey,
+			publicKey:   base64.StdEncoding.EncodeToString(publicKey),
+			wantContent: "new librarian",
+			wantOutput:  "Updated librarian",
+		},
+		{
+			name:        "unsigned",
+			running:     "v0.4.0",
+			publicKey:   base64.StdEncoding.EncodeToString(publicKey),
+			wantContent: "old librarian",
+			wantErr:     "binary is not signed",
+		},
+		{
+			name:        "signed with other key",
+			running:     "v0.4.0",
+			signedWith:  otherKey,
+			publicKey:   base64.StdEncoding.EncodeToString(publicKey),
+			wantContent: "old librarian",
+			wantErr:     "signature does not match",
+		},
+		{
+			name:        "checksum mismatch",
+			running:     "v0.4.0",
+			corrupt:     true,
+			wantContent: "old librarian",
+			wantErr:     "checksum mismatch",
+		},
+		{
+			name:        "unknown version",
+			running:     "v0.4.0",
+			version:     "v0.3.0",
+			wantContent: "old librarian",
+			wantErr:     "release v0.3.0 not found",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			served := content
+			if test.corrupt {
+				served = []byte("corrupt librarian")
+			}
+			manifestURL := serveReleaseManifest(t, content, served, test.signedWith)
+			executable := filepath.Join(t.TempDir(), "librarian")
+			if err := os.WriteFile(executable, []byte("old librarian"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			cfg := &config.Config{
+				DryRun:           test.dryRun,
+				ReleaseManifest:  manifestURL,
+				ReleasePublicKey: test.publicKey,
+			}
+			var output bytes.Buffer
+			err := selfUpdate(context.Background(), &output, cfg, test.running, test.version, executable)
+			switch {
+			case test.wantErr != "" && err == nil:
+				t.Fatalf("selfUpdate() error = nil, want %q", test.wantErr)
+			case test.wantErr != "" && !strings.Contains(err.Error(), test.wantErr):
+				t.Fatalf("selfUpdate() error = %q, want to contain %q", err, test.wantErr)
+			case test.wantErr == "" && err != nil:
+				t.Fatal(err)
+			}
+			if !strings.Contains(output.String(), test.wantOutput) {
+				t.Errorf("selfUpdate() output = %q, want to contain %q", output.String(), test.wantOutput)
+			}
+			got, err := os.ReadFile(executable)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.wantContent {
+				t.Errorf("executable = %q, want %q", got, test.wantContent)
+			}
+			entries, err := os.ReadDir(filepath.Dir(executable))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("left %d files next to the executable, want 1", len(entries))
+			}
+		})
+	}
+}
+
+func TestLoadReleaseManifest_Errors(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	for _, test := range []struct {
+		name     string
+		location string
+		wantErr  string
+	}{
+		{
+			name:    "no manifest",
+			wantErr: "no release manifest",
+		},
+		{
+			name:     "not JSON",
+			location: write("invalid.json", "releases: []"),
+			wantErr:  "failed to parse release manifest",
+		},
+		{
+			name:     "invalid latest",
+			location: write("latest.json", `{"latest": "newest"}`),
+			wantErr:  "invalid latest version",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := loadReleaseManifest(test.location)
+			if err == nil {
+				t.Fatalf("loadReleaseManifest() error = nil, want %q", test.wantErr)
+			}
+			if !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("loadReleaseManifest() error = %q, want to contain %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckForUpdate(t *testing.T) {
+	t.Parallel()
+	manifestURL := serveReleaseManifest(t, []byte("librarian"), []byte("librarian"), nil)
+	for _, test := range []struct {
+		running string
+		want    string
+	}{
+		{running: "v0.4.0", want: "v0.4.0\nA newer version of librarian is available: v0.5.0."},
+		{running: "v0.5.0", want: "v0.5.0\nlibrarian is up to date."},
+		{running: "not available", want: "not available\nThis build is not a release; the latest release is v0.5.0."},
+	} {
+		t.Run(test.running, func(t *testing.T) {
+			t.Parallel()
+			var output bytes.Buffer
+			if err := checkForUpdate(&output, &config.Config{ReleaseManifest: manifestURL}, test.running); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(output.String(), test.want) {
+				t.Errorf("checkForUpdate() output = %q, want prefix %q", output.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestCheckLibrarianVersion(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		pinned  string
+		running string
+		wantErr bool
+	}{
+		{name: "not pinned", running: "v0.4.0"},
+		{name: "same", pinned: "v0.5.0", running: "v0.5.0"},
+		{name: "same without prefix", pinned: "0.5.0", running: "v0.5.0"},
+		{name: "newer", pinned: "v0.5.0", running: "v0.6.0"},
+		{name: "older", pinned: "v0.5.0", running: "v0.4.0", wantErr: true},
+		{name: "build from source", pinned: "v0.5.0", running: "0.0.0-123456789000-20251006115905"},
+		{name: "not available", pinned: "v0.5.0", running: "not available"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			err := checkLibrarianVersion(test.pinned, test.running)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Errorf("checkLibrarianVersion(%q, %q) error = %v, want error %t", test.pinned, test.running, err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,632 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/logging"
+	"google.golang.org/api/idtoken"
+)
+
+var cmdServe = &cli.Command{
+	Short:     "serve runs librarian commands on request",
+	UsageLine: "librarian serve [flags]",
+	Long: `Runs librarian as a long-running service which accepts work requests,
+queues them and runs them, so that it can be the execution engine of a hosted
+pipeline.
+
+A work request names a repository and the librarian command to run on it:
+
+	{"id": "nightly-go", "repo": "https://github.com/googleapis/google-cloud-go", "args": ["generate", "-push"]}
+
+Work requests are accepted as JSON by "POST /requests", and as the data of
+Pub/Sub push messages by "POST /pubsub", whose ID defaults to the ID of the
+message. A request with the ID of a known request is not queued again, so that
+redelivered messages run once. The status of a request is returned by
+"GET /requests/{id}".
+
+The service listens on "localhost:8080" by default, and authenticates every
+request. "/requests" requires the shared secret of the LIBRARIAN_SERVE_TOKEN
+environment variable as bearer token, and is disabled if it is not set.
+"/pubsub" requires the OIDC token of a Pub/Sub push subscription with the
+audience "-pubsub-audience", and of the service account
+"-pubsub-service-account" if set, and is disabled if "-pubsub-audience" is not
+set.
+
+Work requests may only run on the repositories of "-allowed-repos", and may not
+set the flags which are the choice of the operator of the service, such as
+"-image" or "-github-api-url", see serverFlags.
+
+The requests of a repository run one at a time, in the order they were
+received. At most "-max-concurrency" requests run at the same time across all
+repositories, so that the quotas of GitHub and of the container registry are
+shared by all of them. Each request runs as a separate librarian process, with
+"-repo" set to the repository of the request, and the environment of the
+service, such as LIBRARIAN_GITHUB_TOKEN, except LIBRARIAN_SERVE_TOKEN.
+
+On interrupt, the service stops accepting requests, waits for the running
+requests to finish and cancels the queued ones.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		s := newWorkServer(ctx, cfg.MaxConcurrency, runWorkProcess)
+		s.access = newWorkAccess(cfg)
+		return s.listenAndServe(ctx, cfg.Listen)
+	},
+}
+
+func init() {
+	cmdServe.Init()
+	fs := cmdServe.Flags
+	cfg := cmdServe.Config
+
+	addFlagAllowedRepos(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagListen(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagMaxConcurrency(fs, cfg)
+	addFlagPubSubAudience(fs, cfg)
+	addFlagPubSubServiceAccount(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+}
+
+// The states of work requests.
+const (
+	workQueued    = "queued"
+	workRunning   = "running"
+	workSucceeded = "succeeded"
+	workFailed    = "failed"
+	workCanceled  = "canceled"
+)
+
+// maxFinishedWork is the number of finished work requests whose status is
+// kept.
+const maxFinishedWork = 1000
+
+// maxWorkRequestBytes is the maximum size of the body of a work request or
+// Pub/Sub message.
+const maxWorkRequestBytes = 1 << 20
+
+// serveTokenEnvVar is the environment variable of the shared secret of the
+// work requests, see config.Config.ServeToken.
+const serveTokenEnvVar = "LIBRARIAN_SERVE_TOKEN"
+
+// serverFlags are the flags which work requests may not set, as they select
+// what runs, e.g. the image of the language container, or where credentials
+// are sent, e.g. the GitHub instance. They are the choice of the operator of
+// the service, not of whoever sends work requests.
+var serverFlags = []string{
+	"api-source-ssh-key",
+	"container-backend",
+	"container-mounts",
+	"container-record",
+	"container-replay",
+	"generator-source",
+	"github-api-url",
+	"github-upload-url",
+	"host-mount",
+	"image",
+	"image-archive",
+	"image-local",
+	"registry-mirror",
+	"ssh-key",
+	"ssh-known-hosts",
+	"work-root",
+}
+
+// validateIDToken validates an OIDC token for audience. It is a variable so
+// that tests can replace it.
+var validateIDToken = idtoken.Validate
+
+// errServerClosed is returned when a work request is received while the
+// server shuts down.
+var errServerClosed = errors.New("server is shutting down")
+
+// workRequest is a request to run a librarian command on a repository.
+type workRequest struct {
+	// ID identifies the request. An ID is assigned if empty.
+	ID string `json:"id,omitempty"`
+	// Repo is the repository to run the command on, as accepted by -repo.
+	Repo string `json:"repo"`
+	// Args are the command and its flags, e.g. ["release", "init", "-push"],
+	// without -repo.
+	Args []string `json:"args"`
+}
+
+// workStatus is the status of a work request.
+type workStatus struct {
+	workRequest
+	State string `json:"state"`
+	// Category is the failure category of a failed request.
+	Category failure.Category `json:"category,omitempty"`
+	Error    string           `json:"error,omitempty"`
+	Queued   time.Time        `json:"queued"`
+	Started  *time.Time       `json:"started,omitempty"`
+	Finished *time.Time       `json:"finished,omitempty"`
+}
+
+// workAccess is who may send work requests to a workServer, and which
+// repositories they may run on.
+type workAccess struct {
+	// token is the shared secret of "/requests", which is disabled if it is
+	// empty.
+	token string
+	// pubSubAudience is the audience of the OIDC tokens of "/pubsub", which
+	// is disabled if it is empty.
+	pubSubAudience string
+	// pubSubServiceAccount is the service account of the OIDC tokens of
+	// "/pubsub", or empty if any is accepted.
+	pubSubServiceAccount string
+	// repos are the repositories which work requests may run on.
+	repos []string
+}
+
+// newWorkAccess returns the workAccess configured by cfg.
+func newWorkAccess(cfg *config.Config) *workAccess {
+	access := &workAccess{
+		token:                cfg.ServeToken,
+		pubSubAudience:       cfg.PubSubAudience,
+		pubSubServiceAccount: cfg.PubSubServiceAccount,
+		repos:                cfg.AllowedRepoList(),
+	}
+	if access.token == "" {
+		slog.Warn("POST /requests is disabled, " + serveTokenEnvVar + " is not set")
+	}
+	if access.pubSubAudience == "" {
+		slog.Warn("POST /pubsub is disabled, -pubsub-audience is not set")
+	}
+	return access
+}
+
+// workServer queues work requests per repository and runs them. The requests
+// of a repository run in order, one at a time, and at most a fixed number of
+// requests run at the same time across all repositories.
+type workServer struct {
+	ctx context.Context
+	run func(ctx context.Context, req *workRequest) error
+	// access is checked for every request. No request is accepted if it is
+	// nil.
+	access *workAccess
+	// slots has a value for each running request, or is nil if the number of
+	// running requests is not limited.
+	slots chan struct{}
+
+	mu sync.Mutex
+	// queues are the queued requests by repository.
+	queues map[string][]*workStatus
+	// draining are the repositories whose queue is being run.
+	draining map[string]bool
+	statuses map[string]*workStatus
+	// finished are the IDs of the finished requests, oldest first.
+	finished []string
+	nextID   int
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// newWorkServer returns a workServer which runs each request with run, at
+// most maxConcurrency at a time, or without limit if maxConcurrency is 0. No
+// new requests are started once ctx is done.
+func newWorkServer(ctx context.Context, maxConcurrency int, run func(ctx context.Context, req *workRequest) error) *workServer {
+	s := &workServer{
+		ctx:      ctx,
+		run:      run,
+		queues:   make(map[string][]*workStatus),
+		draining: make(map[string]bool),
+		statuses: make(map[string]*workStatus),
+	}
+	if maxConcurrency > 0 {
+		s.slots = make(chan struct{}, maxConcurrency)
+	}
+	return s
+}
+
+// listenAndServe accepts work requests on addr until ctx is done, and then
+// waits for the running requests to finish.
+func (s *workServer) listenAndServe(ctx context.Context, addr string) error {
+	server := &http.Server{Addr: addr, Handler: s.handler()}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+	slog.Info("Serving work requests", "address", addr)
+	select {
+	case err := <-errs:
+		return failure.New(failure.UserConfig, fmt.Errorf("failed to serve on %s: %w", addr, err))
+	case <-ctx.Done():
+	}
+	slog.Info("Shutting down, waiting for running work requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("failed to shut down server", "err", err)
+	}
+	s.close()
+	return nil
+}
+
+// handler returns the HTTP handler of the work requests.
+func (s *workServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /requests", s.requireToken(s.handleRequest))
+	mux.HandleFunc("POST /pubsub", s.requirePubSubToken(s.handlePubSub))
+	mux.HandleFunc("GET /requests/{id}", s.requireToken(s.handleStatus))
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// requireToken returns handler, which only handles the requests which carry
+// the shared secret of the server as bearer token.
+func (s *workServer) requireToken(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.access == nil || s.access.token == "" {
+			http.Error(w, "work requests are disabled, "+serveTokenEnvVar+" is not set", http.StatusForbidden)
+			return
+		}
+		token, ok := bearerToken(r)
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.access.token)) != 1 {
+			http.Error(w, "invalid or missing bearer token", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// requirePubSubToken returns handler, which only handles the requests which
+// carry the OIDC token of the Pub/Sub push subscription of the server.
+func (s *workServer) requirePubSubToken(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.access == nil || s.access.pubSubAudience == "" {
+			http.Error(w, "Pub/Sub messages are disabled, -pubsub-audience is not set", http.StatusForbidden)
+			return
+		}
+		token, ok := bearerToken(r)
+		if !ok {
+			http.Error(w, "missing bearer token", http.StatusUnauthorized)
+			return
+		}
+		payload, err := validateIDToken(r.Context(), token, s.access.pubSubAudience)
+		if err != nil {
+			slog.Warn("rejecting Pub/Sub message with invalid token", "err", err)
+			http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		if account := s.access.pubSubServiceAccount; account != "" &&
+			(payload.Claims["email"] != account || payload.Claims["email_verified"] != true) {
+			slog.Warn("rejecting Pub/Sub message of another service account", "email", payload.Claims["email"])
+			http.Error(w, "token of another service account", http.StatusForbidden)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// bearerToken returns the bearer token of the Authorization header of r.
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token, ok && token != ""
+}
+
+func (s *workServer) handleRequest(w http.ResponseWriter, r *http.Request) {
+	req := &workRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWorkRequestBytes)).Decode(req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid work request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := validateWorkRequest(req, s.access.repos); err != nil {
+		http.Error(w, fmt.Sprintf("invalid work request: %v", err), http.StatusBadRequest)
+		return
+	}
+	status, err := s.enqueue(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeWorkStatus(w, http.StatusAccepted, status)
+}
+
+// handlePubSub accepts a work request from a Pub/Sub push subscription.
+// Invalid work requests are acknowledged, so that they are not redelivered.
+func (s *workServer) handlePubSub(w http.ResponseWriter, r *http.Request) {
+	var envelope struct {
+		Message struct {
+			Data      []byte `json:"data"`
+			MessageID string `json:"messageId"`
+		} `json:"message"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWorkRequestBytes)).Decode(&envelope); err != nil {
+		http.Error(w, fmt.Sprintf("invalid Pub/Sub message: %v", err), http.StatusBadRequest)
+		return
+	}
+	req := &workRequest{}
+	if err := json.Unmarshal(envelope.Message.Data, req); err != nil {
+		slog.Warn("ignoring invalid work request", "message", envelope.Message.MessageID, "err", err)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err := validateWorkRequest(req, s.access.repos); err != nil {
+		slog.Warn("ignoring invalid work request", "message", envelope.Message.MessageID, "err", err)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	req.ID = cmp.Or(req.ID, envelope.Message.MessageID)
+	if _, err := s.enqueue(req); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *workServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := s.status(r.PathValue("id"))
+	if status == nil {
+		http.Error(w, "unknown work request", http.StatusNotFound)
+		return
+	}
+	writeWorkStatus(w, http.StatusOK, status)
+}
+
+func writeWorkStatus(w http.ResponseWriter, code int, status *workStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		slog.Warn("failed to write work status", "id", status.ID, "err", err)
+	}
+}
+
+// validateWorkRequest checks that req runs a librarian command which operates
+// on a repository of repos, without setting any of serverFlags.
+func validateWorkRequest(req *workRequest, repos []string) error {
+	if req.Repo == "" {
+		return errors.New("missing repo")
+	}
+	if !slices.Contains(repos, req.Repo) {
+		return fmt.Errorf("repo %s is not allowed, see -allowed-repos", req.Repo)
+	}
+	if len(req.Args) == 0 {
+		return errors.New("missing command")
+	}
+	cmd, args := CmdLibrarian, req.Args
+	for len(cmd.Commands) > 0 {
+		if len(args) == 0 {
+			return fmt.Errorf("missing subcommand of %s", cmd.Name())
+		}
+		sub, err := cmd.Lookup(args[0])
+		if err != nil {
+			return err
+		}
+		cmd, args = sub, args[1:]
+	}
+	if cmd.Flags.Lookup("repo") == nil {
+		return fmt.Errorf("command %q does not operate on a repository", cmd.Name())
+	}
+	for _, arg := range args {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		switch {
+		case !strings.HasPrefix(arg, "-"):
+		case name == "repo":
+			return errors.New("-repo is set from repo, and must not be in args")
+		case slices.Contains(serverFlags, name):
+			return fmt.Errorf("-%s is set by the server, and must not be in args", name)
+		}
+	}
+	return nil
+}
+
+// enqueue queues req after the other requests of its repository. A request
+// with the ID of a known request is not queued again, and the status of the
+// known request is returned instead.
+func (s *workServer) enqueue(req *workRequest) (*workStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.ctx.Err() != nil {
+		return nil, errServerClosed
+	}
+	if req.ID == "" {
+		s.nextID++
+		req.ID = fmt.Sprintf("request-%d", s.nextID)
+	}
+	if status, ok := s.statuses[req.ID]; ok {
+		return status.snapshot(), nil
+	}
+	status := &workStatus{workRequest: *req, State: workQueued, Queued: now()}
+	s.statuses[req.ID] = status
+	s.queues[req.Repo] = append(s.queues[req.Repo], status)
+	slog.Info("Queued work request", "id", req.ID, "repo", req.Repo, "args", req.Args)
+	if !s.draining[req.Repo] {
+		s.draining[req.Repo] = true
+		s.wg.Add(1)
+		go s.drain(req.Repo)
+	}
+	return status.snapshot(), nil
+}
+
+// drain runs the queued requests of repo in order until its queue is empty.
+func (s *workServer) drain(repo string) {
+	defer s.wg.Done()
+	for {
+		status := s.dequeue(repo)
+		if status == nil {
+			return
+		}
+		if !s.acquire() {
+			s.finish(status, errServerClosed, workCanceled)
+			continue
+		}
+		s.start(status)
+		// Running requests are not canceled on shutdown, but allowed to
+		// finish.
+		err := s.run(context.WithoutCancel(s.ctx), &status.workRequest)
+		s.release()
+		state := workSucceeded
+		if err != nil {
+			state = workFailed
+		}
+		s.finish(status, err, state)
+	}
+}
+
+// dequeue returns the next queued request of repo, or nil if there is none.
+func (s *workServer) dequeue(repo string) *workStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queue := s.queues[repo]
+	if len(queue) == 0 {
+		delete(s.queues, repo)
+		delete(s.draining, repo)
+		return nil
+	}
+	s.queues[repo] = queue[1:]
+	return queue[0]
+}
+
+// acquire waits for a slot to run a request, and reports whether one was
+// acquired before the server was closed.
+func (s *workServer) acquire() bool {
+	if s.slots == nil {
+		return s.ctx.Err() == nil && !s.isClosed()
+	}
+	select {
+	case s.slots <- struct{}{}:
+		if s.isClosed() {
+			<-s.slots
+			return false
+		}
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+func (s *workServer) release() {
+	if s.slots != nil {
+		<-s.slots
+	}
+}
+
+func (s *workServer) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *workServer) start(status *workStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	started := now()
+	status.State = workRunning
+	status.Started = &started
+	slog.Info("Running work request", "id", status.ID, "repo", status.Repo)
+}
+
+func (s *workServer) finish(status *workStatus, err error, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finished := now()
+	status.State = state
+	status.Finished = &finished
+	if err != nil {
+		status.Category = failure.CategoryOf(err)
+		status.Error = err.Error()
+		slog.Warn("Work request did not succeed", "id", status.ID, "repo", status.Repo, "state", state, "err", err)
+	} else {
+		slog.Info("Work request succeeded", "id", status.ID, "repo", status.Repo)
+	}
+	s.finished = append(s.finished, status.ID)
+	if len(s.finished) > maxFinishedWork {
+		delete(s.statuses, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
+
+// status returns a copy of the status of the request with the given ID, or
+// nil if it is unknown.
+func (s *workServer) status(id string) *workStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, ok := s.statuses[id]
+	if !ok {
+		return nil
+	}
+	return status.snapshot()
+}
+
+// snapshot returns a copy of status, which can be read without holding the
+// lock of the server.
+func (status *workStatus) snapshot() *workStatus {
+	c := *status
+	return &c
+}
+
+// close stops running queued requests, and waits for the running ones to
+// finish.
+func (s *workServer) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.wait()
+}
+
+// wait waits until the queues of all repositories are empty.
+func (s *workServer) wait() {
+	s.wg.Wait()
+}
+
+// runWorkProcess runs req as a separate librarian process, with the
+// environment of the server except its shared secret. The exit code of the
+// process is classified with the failure category it stands for.
+func runWorkProcess(ctx context.Context, req *workRequest) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find librarian executable: %w", err)
+	}
+	args := append(slices.Clone(req.Args), "-repo", req.Repo)
+	cmd := exec.CommandContext(ctx, executable, args...)
+	cmd.Env = slices.DeleteFunc(os.Environ(), func(variable string) bool {
+		return strings.HasPrefix(variable, serveTokenEnvVar+"=")
+	})
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	logging.LogCommand(cmd)
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return failure.New(failure.CategoryOfExitCode(exitErr.ExitCode()), fmt.Errorf("librarian %s: %w", strings.Join(req.Args, " "), err))
+		}
+		return fmt.Errorf("failed to run librarian %s: %w", strings.Join(req.Args, " "), err)
+	}
+	return nil
+}
@@ -0,0 +1,449 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"google.golang.org/api/idtoken"
+)
+
+func TestValidateWorkRequest(t *testing.T) {
+	repos := []string{"https://github.com/googleapis/google-cloud-go", "r"}
+	for _, test := range []struct {
+		name    string
+		req     *workRequest
+		wantErr string
+	}{
+		{
+			name: "generate",
+			req:  &workRequest{Repo: "https://github.com/googleapis/google-cloud-go", Args: []string{"generate", "-push"}},
+		},
+		{
+			name: "release init",
+			req:  &workRequest{Repo: "https://github.com/googleapis/google-cloud-go", Args: []string{"release", "init"}},
+		},
+		{
+			name:    "missing repo",
+			req:     &workRequest{Args: []string{"generate"}},
+			wantErr: "missing repo",
+		},
+		{
+			name:    "repo not allowed",
+			req:     &workRequest{Repo: "https://github.example.com/attacker/repo", Args: []string{"generate"}},
+			wantErr: "is not allowed",
+		},
+		{
+			name:    "missing command",
+			req:     &workRequest{Repo: "r"},
+			wantErr: "missing command",
+		},
+		{
+			name:    "missing subcommand",
+			req:     &workRequest{Repo: "r", Args: []string{"release"}},
+			wantErr: "missing subcommand of release",
+		},
+		{
+			name:    "unknown command",
+			req:     &workRequest{Repo: "r", Args: []string{"publish"}},
+			wantErr: "invalid command",
+		},
+		{
+			name:    "command without repository",
+			req:     &workRequest{Repo: "r", Args: []string{"serve"}},
+			wantErr: "does not operate on a repository",
+		},
+		{
+			name:    "repo in args",
+			req:     &workRequest{Repo: "r", Args: []string{"generate", "--repo=other"}},
+			wantErr: "must not be in args",
+		},
+		{
+			name:    "image in args",
+			req:     &workRequest{Repo: "r", Args: []string{"generate", "-image", "attacker/image"}},
+			wantErr: "-image is set by the server",
+		},
+		{
+			name:    "GitHub API URL in args",
+			req:     &workRequest{Repo: "r", Args: []string{"release", "tag-and-release", "--github-api-url=https://github.example.com"}},
+			wantErr: "-github-api-url is set by the server",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateWorkRequest(test.req, repos)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateWorkRequest() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("validateWorkRequest() error = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestWorkServer_Scheduling(t *testing.T) {
+	var mu sync.Mutex
+	running := 0
+	maxRunning := 0
+	runningByRepo := make(map[string]int)
+	var order []string
+	run := func(ctx context.Context, req *workRequest) error {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		runningByRepo[req.Repo]++
+		if runningByRepo[req.Repo] > 1 {
+			t.Errorf("requests of %s run at the same time", req.Repo)
+		}
+		order = append(order, req.ID)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		runningByRepo[req.Repo]--
+		mu.Unlock()
+		if req.ID == "b-1" {
+			return failure.New(failure.ContainerFailure, errors.New("boom"))
+		}
+		return nil
+	}
+	s := newWorkServer(context.Background(), 2, run)
+	for _, id := range []string{"a-1", "b-1", "a-2", "c-1", "a-3", "d-1"} {
+		repo, _, _ := strings.Cut(id, "-")
+		if _, err := s.enqueue(&workRequest{ID: id, Repo: repo, Args: []string{"generate"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.wait()
+
+	if maxRunning > 2 {
+		t.Errorf("max running requests = %d, want at most 2", maxRunning)
+	}
+	var aOrder []string
+	for _, id := range order {
+		if strings.HasPrefix(id, "a-") {
+			aOrder = append(aOrder, id)
+		}
+	}
+	if diff := cmp.Diff([]string{"a-1", "a-2", "a-3"}, aOrder); diff != "" {
+		t.Errorf("order of requests of a mismatch (-want +got):\n%s", diff)
+	}
+	if got := s.status("a-3").State; got != workSucceeded {
+		t.Errorf("state of a-3 = %q, want %q", got, workSucceeded)
+	}
+	failed := s.status("b-1")
+	if failed.State != workFailed || failed.Category != failure.ContainerFailure {
+		t.Errorf("status of b-1 = %q, %q, want %q, %q", failed.State, failed.Category, workFailed, failure.ContainerFailure)
+	}
+}
+
+func TestWorkServer_Closed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newWorkServer(ctx, 1, func(ctx context.Context, req *workRequest) error { return nil })
+	cancel()
+	if _, err := s.enqueue(&workRequest{Repo: "a", Args: []string{"generate"}}); !errors.Is(err, errServerClosed) {
+		t.Errorf("enqueue() error = %v, want %v", err, errServerClosed)
+	}
+}
+
+func TestWorkServer_CancelsQueuedOnClose(t *testing.T) {
+	started := make(chan struct{})
+	proceed := make(chan struct{})
+	s := newWorkServer(context.Background(), 1, func(ctx context.Context, req *workRequest) error {
+		if req.ID == "first" {
+			close(started)
+			<-proceed
+		}
+		return nil
+	})
+	for _, id := range []string{"first", "second"} {
+		if _, err := s.enqueue(&workRequest{ID: id, Repo: "a", Args: []string{"generate"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-started
+	closed := make(chan struct{})
+	go func() {
+		s.close()
+		close(closed)
+	}()
+	// Wait for close to mark the server as closed before the running request
+	// finishes.
+	for !s.isClosed() {
+		time.Sleep(time.Millisecond)
+	}
+	close(proceed)
+	<-closed
+	if got := s.status("first").State; got != workSucceeded {
+		t.Errorf("state of first = %q, want %q", got, workSucceeded)
+	}
+	if got := s.status("second").State; got != workCanceled {
+		t.Errorf("state of second = %q, want %q", got, workCanceled)
+	}
+}
+
+// useMockIDTokenValidator makes the OIDC tokens valid if they are "valid",
+// for the email "pubsub@example.iam.gserviceaccount.com".
+func useMockIDTokenValidator(t *testing.T) {
+	t.Helper()
+	saved := validateIDToken
+	t.Cleanup(func() { validateIDToken = saved })
+	validateIDToken = func(ctx context.Context, token, audience string) (*idtoken.Payload, error) {
+		if token != "valid" || audience != "https://librarian.example.com/pubsub" {
+			return nil, errors.New("invalid token")
+		}
+		return &idtoken.Payload{Audience: audience, Claims: map[string]any{
+			"email":          "pubsub@example.iam.gserviceaccount.com",
+			"email_verified": true,
+		}}, nil
+	}
+}
+
+func TestWorkServer_HTTP(t *testing.T) {
+	useMockIDTokenValidator(t)
+	var mu sync.Mutex
+	var ran []string
+	s := newWorkServer(context.Background(), 0, func(ctx context.Context, req *workRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, fmt.Sprintf("%s %s", req.Repo, strings.Join(req.Args, " ")))
+		return nil
+	})
+	s.access = &workAccess{
+		token:                "secret",
+		pubSubAudience:       "https://librarian.example.com/pubsub",
+		pubSubServiceAccount: "pubsub@example.iam.gserviceaccount.com",
+		repos:                []string{"r1", "r2"},
+	}
+	server := httptest.NewServer(s.handler())
+	defer server.Close()
+
+	send := func(method, path, token, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	post := func(path, body string) *http.Response {
+		t.Helper()
+		token := "secret"
+		if path == "/pubsub" {
+			token = "valid"
+		}
+		resp := send(http.MethodPost, path, token, body)
+		resp.Body.Close()
+		return resp
+	}
+	pubSubMessage := func(id, data string) string {
+		return fmt.Sprintf(`{"message": {"messageId": %q, "data": %q}}`, id, base64.StdEncoding.EncodeToString([]byte(data)))
+	}
+
+	if resp := post("/requests", `{"id": "nightly", "repo": "r1", "args": ["generate"]}`); resp.StatusCode != http.StatusAccepted {
+		t.Errorf("POST /requests status = %d, want %d", resp.StatusCode, http.StatusAccepted)
+	}
+	if resp := post("/requests", `{"repo": "r1", "args": ["version"]}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST /requests of invalid request status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	for range 2 {
+		// The redelivered message does not run again.
+		if resp := post("/pubsub", pubSubMessage("m1", `{"repo": "r2", "args": ["release", "init"]}`)); resp.StatusCode != http.StatusNoContent {
+			t.Errorf("POST /pubsub status = %d, want %d", resp.StatusCode, http.StatusNoContent)
+		}
+	}
+	if resp := post("/pubsub", pubSubMessage("m2", `{"repo": "r2"}`)); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("POST /pubsub of invalid request status = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+	s.wait()
+
+	resp := send(http.MethodGet, "/requests/m1", "secret", "")
+	defer resp.Body.Close()
+	status := &workStatus{}
+	if err := json.NewDecoder(resp.Body).Decode(status); err != nil {
+		t.Fatal(err)
+	}
+	if status.State != workSucceeded || status.Repo != "r2" {
+		t.Errorf("status of m1 = %q of %q, want %q of %q", status.State, status.Repo, workSucceeded, "r2")
+	}
+	if resp := send(http.MethodGet, "/requests/m2", "secret", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /requests/m2 status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	if diff := cmp.Diff([]string{"r1 generate", "r2 release init"}, ran, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Errorf("ran mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWorkServer_Authentication(t *testing.T) {
+	useMockIDTokenValidator(t)
+	request := `{"repo": "r1", "args": ["generate"]}`
+	pubSubMessage := fmt.Sprintf(`{"message": {"messageId": "m1", "data": %q}}`, base64.StdEncoding.EncodeToString([]byte(request)))
+	for _, test := range []struct {
+		name   string
+		access *workAccess
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		{
+			name:   "request",
+			access: &workAccess{token: "secret", repos: []string{"r1"}},
+			path:   "/requests",
+			token:  "secret",
+			body:   request,
+			want:   http.StatusAccepted,
+		},
+		{
+			name:   "request without token",
+			access: &workAccess{token: "secret", repos: []string{"r1"}},
+			path:   "/requests",
+			body:   request,
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "request with wrong token",
+			access: &workAccess{token: "secret", repos: []string{"r1"}},
+			path:   "/requests",
+			token:  "guess",
+			body:   request,
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "requests disabled",
+			access: &workAccess{repos: []string{"r1"}},
+			path:   "/requests",
+			body:   request,
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "request too large",
+			access: &workAccess{token: "secret", repos: []string{"r1"}},
+			path:   "/requests",
+			token:  "secret",
+			body:   `{"repo": "r1", "args": ["generate"], "id": "` + strings.Repeat("x", maxWorkRequestBytes) + `"}`,
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "Pub/Sub message",
+			access: &workAccess{pubSubAudience: "https://librarian.example.com/pubsub", repos: []string{"r1"}},
+			path:   "/pubsub",
+			token:  "valid",
+			body:   pubSubMessage,
+			want:   http.StatusNoContent,
+		},
+		{
+			name:   "Pub/Sub message with invalid token",
+			access: &workAccess{pubSubAudience: "https://librarian.example.com/pubsub", repos: []string{"r1"}},
+			path:   "/pubsub",
+			token:  "forged",
+			body:   pubSubMessage,
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "Pub/Sub message with the shared secret",
+			access: &workAccess{token: "secret", pubSubAudience: "https://librarian.example.com/pubsub", repos: []string{"r1"}},
+			path:   "/pubsub",
+			token:  "secret",
+			body:   pubSubMessage,
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name: "Pub/Sub message of another service account",
+			access: &workAccess{
+				pubSubAudience:       "https://librarian.example.com/pubsub",
+				pubSubServiceAccount: "librarian@example.iam.gserviceaccount.com",
+				repos:                []string{"r1"},
+			},
+			path:  "/pubsub",
+			token: "valid",
+			body:  pubSubMessage,
+			want:  http.StatusForbidden,
+		},
+		{
+			name:   "Pub/Sub disabled",
+			access: &workAccess{token: "secret", repos: []string{"r1"}},
+			path:   "/pubsub",
+			token:  "valid",
+			body:   pubSubMessage,
+			want:   http.StatusForbidden,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s := newWorkServer(context.Background(), 0, func(ctx context.Context, req *workRequest) error { return nil })
+			s.access = test.access
+			server := httptest.NewServer(s.handler())
+			defer server.Close()
+			req, err := http.NewRequest(http.MethodPost, server.URL+test.path, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			s.wait()
+			if resp.StatusCode != test.want {
+				t.Errorf("POST %s status = %d, want %d", test.path, resp.StatusCode, test.want)
+			}
+		})
+	}
+}
+
+func TestNewWorkAccess(t *testing.T) {
+	cfg := &config.Config{
+		AllowedRepos:         "r1,r2",
+		PubSubAudience:       "https://librarian.example.com/pubsub",
+		PubSubServiceAccount: "pubsub@example.iam.gserviceaccount.com",
+		ServeToken:           "secret",
+	}
+	want := &workAccess{
+		token:                "secret",
+		pubSubAudience:       "https://librarian.example.com/pubsub",
+		pubSubServiceAccount: "pubsub@example.iam.gserviceaccount.com",
+		repos:                []string{"r1", "r2"},
+	}
+	if diff := cmp.Diff(want, newWorkAccess(cfg), cmp.AllowUnexported(workAccess{})); diff != "" {
+		t.Errorf("newWorkAccess() mismatch (-want +got):\n%s", diff)
+	}
+}
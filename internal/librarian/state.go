@@ -15,6 +15,8 @@
 package librarian
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -37,13 +39,17 @@ const (
 
 // Utility functions for saving and loading pipeline state and config from various places.
 
-func loadRepoState(repo *gitrepo.LocalRepository, source string) (*config.LibrarianState, error) {
+// loadRepoState loads the state of repo. If libraryID is not empty, only the
+// library with that ID is prepared for use, e.g. its service configs are
+// looked up in source, which keeps runs on a single library of a large
+// repository fast.
+func loadRepoState(repo *gitrepo.LocalRepository, source, libraryID string) (*config.LibrarianState, error) {
 	if repo == nil {
 		slog.Info("repo is nil, skipping state loading")
 		return nil, nil
 	}
 	path := filepath.Join(repo.Dir, config.LibrarianDir, librarianStateFile)
-	return parseLibrarianState(path, source)
+	return parseLibrarianStateFor(path, source, libraryID)
 }
 
 func loadLibrarianConfig(repo *gitrepo.LocalRepository) (*config.LibrarianConfig, error) {
@@ -56,6 +62,12 @@ func loadLibrarianConfig(repo *gitrepo.LocalRepository) (*config.LibrarianConfig
 }
 
 func parseLibrarianState(path, source string) (*config.LibrarianState, error) {
+	return parseLibrarianStateFor(path, source, "")
+}
+
+// parseLibrarianStateFor is like parseLibrarianState, but prepares only the
+// library with the given ID for use if libraryID is not empty.
+func parseLibrarianStateFor(path, source, libraryID string) (*config.LibrarianState, error) {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -64,7 +76,7 @@ func parseLibrarianState(path, source string) (*config.LibrarianState, error) {
 	if err := yaml.Unmarshal(bytes, &s); err != nil {
 		return nil, fmt.Errorf("unmarshaling librarian state: %w", err)
 	}
-	if err := populateServiceConfigIfEmpty(&s, source); err != nil {
+	if err := populateServiceConfigIfEmpty(&s, source, libraryID); err != nil {
 		return nil, fmt.Errorf("populating service config: %w", err)
 	}
 	if err := s.Validate(); err != nil {
@@ -82,22 +94,35 @@ func parseLibrarianConfig(path string) (*config.LibrarianConfig, error) {
 		}
 		return nil, err
 	}
-	var lc config.LibrarianConfig
+	var lc *config.LibrarianConfig
 	if err := yaml.Unmarshal(bytes, &lc); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal global config: %w", err)
 	}
+	if lc == nil {
+		lc = &config.LibrarianConfig{}
+	}
+	lc, err = resolveConfigExtends(lc, path, []string{path})
+	if err != nil {
+		return nil, err
+	}
 	if err := lc.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid global config: %w", err)
 	}
-	return &lc, nil
+	return lc, nil
 }
 
-func populateServiceConfigIfEmpty(state *config.LibrarianState, source string) error {
+// populateServiceConfigIfEmpty looks up the service configs of the APIs of the
+// libraries in state which have none in source. Only the library with the
+// given ID is considered if libraryID is not empty.
+func populateServiceConfigIfEmpty(state *config.LibrarianState, source, libraryID string) error {
 	if source == "" {
 		slog.Info("source not specified, skipping service config population")
 		return nil
 	}
 	for i, library := range state.Libraries {
+		if libraryID != "" && library.ID != libraryID {
+			continue
+		}
 		for j, api := range library.APIs {
 			if api.ServiceConfig != "" {
 				// Do not change API if the service config has already been set.
@@ -150,13 +175,169 @@ func findServiceConfigIn(path string) (string, error) {
 	return "", nil
 }
 
-func saveLibrarianState(repoDir string, state *config.LibrarianState) error {
+// serviceConfig is the part of a service config which librarian reads.
+type serviceConfig struct {
+	Type string `yaml:"type"`
+	APIs []struct {
+		Name string `yaml:"name"`
+	} `yaml:"apis"`
+}
+
+// serviceConfigAPIPath returns the path of the API of the service config at
+// path: the directory of the service config relative to source if it is in
+// source, and the package of its first API otherwise, e.g.
+// "google/cloud/foo/v1" for "google.cloud.foo.v1.FooService".
+func serviceConfigAPIPath(path, source string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var sc serviceConfig
+	if err := yaml.Unmarshal(data, &sc); err != nil {
+		return "", fmt.Errorf("failed to parse service config %s: %w", path, err)
+	}
+	if sc.Type != serviceConfigValue {
+		return "", fmt.Errorf("%s is not a service config, want %s: %s", path, serviceConfigType, serviceConfigValue)
+	}
+	if source != "" {
+		if rel, err := filepath.Rel(source, filepath.Dir(path)); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel), nil
+		}
+	}
+	if len(sc.APIs) == 0 {
+		return "", fmt.Errorf("service config %s is outside of the API source and has no APIs to derive the API path from", path)
+	}
+	name := sc.APIs[0].Name
+	i := strings.LastIndex(name, ".")
+	if i <= 0 {
+		return "", fmt.Errorf("invalid API name %q in service config %s", name, path)
+	}
+	return strings.ReplaceAll(name[:i], ".", "/"), nil
+}
+
+// saveLibrarianState writes state to the state file of the repository at
+// repoDir. An existing state file is updated with minimal changes, see
+// updateStateYAML, so that the diff of a run on a few libraries of a large
+// repository stays small.
+func saveLibrarianState(ctx context.Context, repoDir string, state *config.LibrarianState) error {
 	path := filepath.Join(repoDir, config.LibrarianDir, librarianStateFile)
 	bytes, err := yaml.Marshal(state)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, bytes, 0644)
+	if original, err := os.ReadFile(path); err == nil {
+		if updated, ok := updateStateYAML(original, state); ok {
+			bytes = updated
+		}
+	}
+	if err := os.WriteFile(path, bytes, 0644); err != nil {
+		return err
+	}
+	auditLogFromContext(ctx).record(auditStateModified, map[string]string{"path": path})
+	return nil
+}
+
+// updateStateYAML returns original, the content of a state file, updated to
+// state: the entries of the libraries which did not change are kept as they
+// are, including their comments, and only the entries of the added or changed
+// libraries are written, with the indentation of the existing entries. It
+// reports false if the state file cannot be updated this way, e.g. if a field
+// other than the libraries changed, in which case it is written anew.
+func updateStateYAML(original []byte, state *config.LibrarianState) ([]byte, bool) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(original, &doc); err != nil || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, false
+	}
+	var previous config.LibrarianState
+	if err := doc.Decode(&previous); err != nil {
+		return nil, false
+	}
+	previousRest, rest := previous, *state
+	previousRest.Libraries, rest.Libraries = nil, nil
+	if !sameYAML(&previousRest, &rest) {
+		return nil, false
+	}
+
+	root := doc.Content[0]
+	var libraries *yaml.Node
+	lines := strings.SplitAfter(string(original), "\n")
+	// end is the line following the libraries, counting from 1.
+	end := len(lines) + 1
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "libraries" {
+			continue
+		}
+		libraries = root.Content[i+1]
+		if i+2 < len(root.Content) {
+			end = root.Content[i+2].Line
+		}
+	}
+	if libraries == nil || libraries.Kind != yaml.SequenceNode || len(libraries.Content) == 0 {
+		return nil, false
+	}
+	first := libraries.Content[0]
+	indent := first.Column - 3
+	if indent < 0 || first.Line > len(lines) {
+		return nil, false
+	}
+	entries := make(map[string]string)
+	previousLibraries := make(map[string]*config.LibraryState)
+	for i, item := range libraries.Content {
+		line := lines[item.Line-1]
+		if item.Kind != yaml.MappingNode || item.Column-3 != indent || len(line) < indent+2 || strings.TrimSpace(line[:indent]) != "" || !strings.HasPrefix(line[indent:], "- ") {
+			return nil, false
+		}
+		next := end
+		if i+1 < len(libraries.Content) {
+			next = libraries.Content[i+1].Line
+		}
+		library := &config.LibraryState{}
+		if err := item.Decode(library); err != nil {
+			return nil, false
+		}
+		entries[library.ID] = strings.Join(lines[item.Line-1:next-1], "")
+		previousLibraries[library.ID] = library
+	}
+
+	var out strings.Builder
+	out.WriteString(strings.Join(lines[:first.Line-1], ""))
+	for _, library := range state.Libraries {
+		if previous, ok := previousLibraries[library.ID]; ok && sameYAML(previous, library) {
+			out.WriteString(entries[library.ID])
+			continue
+		}
+		entry, err := yaml.Marshal([]*config.LibraryState{library})
+		if err != nil {
+			return nil, false
+		}
+		for _, line := range strings.SplitAfter(string(entry), "\n") {
+			if line != "" {
+				out.WriteString(strings.Repeat(" ", indent) + line)
+			}
+		}
+	}
+	out.WriteString(strings.Join(lines[end-1:], ""))
+
+	// The result must describe the state exactly, whatever the layout of the
+	// original.
+	var updated config.LibrarianState
+	if err := yaml.Unmarshal([]byte(out.String()), &updated); err != nil || !sameYAML(&updated, state) {
+		return nil, false
+	}
+	return []byte(out.String()), true
+}
+
+// sameYAML reports whether a and b are written the same in YAML.
+func sameYAML(a, b any) bool {
+	x, err := yaml.Marshal(a)
+	if err != nil {
+		return false
+	}
+	y, err := yaml.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(x, y)
 }
 
 // readLibraryState reads the library state from a container response, if it exists.
@@ -15,6 +15,7 @@
 package librarian
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -108,6 +109,76 @@ libraries:
 	}
 }
 
+func TestServiceConfigAPIPath(t *testing.T) {
+	t.Parallel()
+	source := t.TempDir()
+	other := t.TempDir()
+	const serviceConfig = `type: google.api.Service
+name: foo.googleapis.com
+apis:
+- name: google.cloud.foo.v1.FooService
+`
+	for _, path := range []string{
+		filepath.Join(source, "google/cloud/foo/v1/foo_v1.yaml"),
+		filepath.Join(other, "foo_v1.yaml"),
+		filepath.Join(other, "no_apis.yaml"),
+		filepath.Join(other, "not_service.yaml"),
+	} {
+		content := serviceConfig
+		switch filepath.Base(path) {
+		case "no_apis.yaml":
+			content = "type: google.api.Service\n"
+		case "not_service.yaml":
+			content = "type: other\n"
+		}
+		if err := writeFile(path, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, test := range []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "in API source",
+			path: filepath.Join(source, "google/cloud/foo/v1/foo_v1.yaml"),
+			want: "google/cloud/foo/v1",
+		},
+		{
+			name: "outside API source",
+			path: filepath.Join(other, "foo_v1.yaml"),
+			want: "google/cloud/foo/v1",
+		},
+		{
+			name:    "outside API source without APIs",
+			path:    filepath.Join(other, "no_apis.yaml"),
+			wantErr: true,
+		},
+		{
+			name:    "not a service config",
+			path:    filepath.Join(other, "not_service.yaml"),
+			wantErr: true,
+		},
+		{
+			name:    "missing",
+			path:    filepath.Join(other, "missing.yaml"),
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := serviceConfigAPIPath(test.path, source)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("serviceConfigAPIPath() error = %v, wantErr %t", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("serviceConfigAPIPath() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
 func TestFindServiceConfigIn(t *testing.T) {
 	for _, test := range []struct {
 		name    string
@@ -212,12 +283,30 @@ func TestParseGlobalConfig(t *testing.T) {
 
 func TestPopulateServiceConfig(t *testing.T) {
 	for _, test := range []struct {
-		name    string
-		state   *config.LibrarianState
-		path    string
-		want    *config.LibrarianState
-		wantErr bool
+		name      string
+		state     *config.LibrarianState
+		path      string
+		libraryID string
+		want      *config.LibrarianState
+		wantErr   bool
 	}{
+		{
+			name: "populate service config of one library",
+			state: &config.LibrarianState{
+				Libraries: []*config.LibraryState{
+					{ID: "example-id", APIs: []*config.API{{Path: "example/api"}}},
+					{ID: "other-id", APIs: []*config.API{{Path: "non-existed/example/api"}}},
+				},
+			},
+			path:      filepath.Join("..", "..", "testdata", "populate_service_config"),
+			libraryID: "example-id",
+			want: &config.LibrarianState{
+				Libraries: []*config.LibraryState{
+					{ID: "example-id", APIs: []*config.API{{Path: "example/api", ServiceConfig: "example_api_config.yaml"}}},
+					{ID: "other-id", APIs: []*config.API{{Path: "non-existed/example/api"}}},
+				},
+			},
+		},
 		{
 			name: "populate service config",
 			state: &config.LibrarianState{
@@ -275,7 +364,7 @@ func TestPopulateServiceConfig(t *testing.T) {
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			err := populateServiceConfigIfEmpty(test.state, test.path)
+			err := populateServiceConfigIfEmpty(test.state, test.path, test.libraryID)
 			if test.wantErr {
 				if err == nil {
 					t.Errorf("findServiceConfigIn() should return error")
@@ -317,7 +406,7 @@ func TestSaveLibrarianState(t *testing.T) {
 			},
 		},
 	}
-	if err := saveLibrarianState(tmpDir, state); err != nil {
+	if err := saveLibrarianState(context.Background(), tmpDir, state); err != nil {
 		t.Fatalf("saveLibrarianState() failed: %v", err)
 	}
 
@@ -423,3 +512,70 @@ func TestReadLibraryState(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateStateYAML(t *testing.T) {
+	t.Parallel()
+	original := `# The state of the repository.
+image: gcr.io/test/image:v1.2.3
+libraries:
+  # The first library.
+  - id: a
+    version: 1.0.0
+    source_roots: [a]
+  - id: b
+    version: 2.0.0
+    source_roots:
+      - b
+  - id: c
+    version: 3.0.0
+    source_roots:
+      - c
+`
+	state := &config.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*config.LibraryState{
+			{ID: "a", Version: "1.0.0", SourceRoots: []string{"a"}},
+			{ID: "b", Version: "2.1.0", SourceRoots: []string{"b"}},
+			{ID: "d", Version: "0.1.0", SourceRoots: []string{"d"}},
+		},
+	}
+	got, ok := updateStateYAML([]byte(original), state)
+	if !ok {
+		t.Fatal("updateStateYAML() = false, want true")
+	}
+	want := `# The state of the repository.
+image: gcr.io/test/image:v1.2.3
+libraries:
+  # The first library.
+  - id: a
+    version: 1.0.0
+    source_roots: [a]
+  - id: b
+    version: 2.1.0
+    last_generated_commit: ""
+    apis: []
+    source_roots:
+      - b
+    preserve_regex: []
+    remove_regex: []
+  - id: d
+    version: 0.1.0
+    last_generated_commit: ""
+    apis: []
+    source_roots:
+      - d
+    preserve_regex: []
+    remove_regex: []
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("updateStateYAML() mismatch (-want +got):\n%s", diff)
+	}
+
+	state.Image = "gcr.io/test/image:v1.2.4"
+	if _, ok := updateStateYAML([]byte(original), state); ok {
+		t.Error("updateStateYAML() with a new image = true, want false")
+	}
+	if _, ok := updateStateYAML([]byte("libraries: [{id: a}]\n"), state); ok {
+		t.Error("updateStateYAML() of flow style libraries = true, want false")
+	}
+}
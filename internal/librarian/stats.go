@@ -0,0 +1,86 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/metrics"
+)
+
+const statsCmdName = "stats"
+
+var cmdStats = &cli.Command{
+	Short:     "stats summarizes the metrics of past runs",
+	UsageLine: "librarian stats -metrics-dir=<path> [flags]",
+	Long: `Summarizes the metrics written by past runs of librarian into the directory
+specified with "-metrics-dir", to show trends over time.
+
+Runs of "generate" and "release init" write their metrics into that directory
+when "-metrics-dir" is specified, one JSON file per run. The metrics include
+the duration of the run and of its phases (e.g. "prewarm", "generate",
+"build" and "commit"), the number of libraries processed and failed, and the
+size of the diff to the language repository. The directory may be shared by
+several machines, e.g. a mounted Cloud Storage bucket.
+
+For each command and day (UTC), the number of runs, failed runs, libraries,
+failed libraries and changed files is printed, along with the mean duration
+of the runs and of their phases, in seconds.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		return stats(os.Stdout, cfg)
+	},
+}
+
+func init() {
+	cmdStats.Init()
+	fs := cmdStats.Flags
+	cfg := cmdStats.Config
+
+	addFlagErrorFormat(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagMetricsDir(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+}
+
+func stats(w io.Writer, cfg *config.Config) error {
+	if cfg.MetricsDir == "" {
+		return failure.New(failure.UserConfig, errors.New("-metrics-dir is required"))
+	}
+	runs, err := metrics.Load(cfg.MetricsDir)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMMAND\tDAY\tRUNS\tFAILED\tLIBRARIES\tFAILED LIBRARIES\tFILES CHANGED\tMEAN DURATION\tMEAN PHASES")
+	for _, summary := range metrics.Summarize(runs) {
+		var phases []string
+		for _, phase := range summary.MeanPhases {
+			phases = append(phases, fmt.Sprintf("%s=%.1f", phase.Name, phase.DurationSeconds))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%.1f\t%s\n",
+			summary.Command, summary.Day, summary.Runs, summary.FailedRuns, summary.Libraries,
+			summary.FailedLibraries, summary.FilesChanged, summary.MeanDurationSeconds, strings.Join(phases, " "))
+	}
+	return tw.Flush()
+}
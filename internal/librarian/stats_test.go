@@ -0,0 +1,64 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/metrics"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, run := range []*metrics.Run{
+		{
+			Command:         "generate",
+			Start:           start,
+			DurationSeconds: 10,
+			Phases:          []*metrics.Phase{{Name: "prewarm", DurationSeconds: 2}, {Name: "generate", DurationSeconds: 5}},
+			Libraries:       2,
+			FilesChanged:    7,
+		},
+		{
+			Command:         "generate",
+			Start:           start.Add(time.Hour),
+			DurationSeconds: 20,
+			Phases:          []*metrics.Phase{{Name: "prewarm", DurationSeconds: 4}},
+			Libraries:       2,
+			FailedLibraries: 2,
+			Failure:         "container-failure",
+		},
+	} {
+		if err := run.Write(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out bytes.Buffer
+	if err := stats(&out, &config.Config{MetricsDir: dir}); err != nil {
+		t.Fatalf("stats() error = %v", err)
+	}
+	want := `COMMAND   DAY         RUNS  FAILED  LIBRARIES  FAILED LIBRARIES  FILES CHANGED  MEAN DURATION  MEAN PHASES
+generate  2025-01-02  2     1       4          2                 7              15.0           prewarm=3.0 generate=5.0
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("stats() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+var cmdStatus = &cli.Command{
+	Short:     "status reports the state of the libraries of the language repository",
+	UsageLine: "librarian status [flags]",
+	Long: `Reports the state of each library in ".librarian/state.yaml": its version, the
+API source commit it was last generated from, the image which runs its
+language container commands, and whether its generation is blocked.
+
+Libraries are pinned to another image than the one of the repository with the
+"image" of the library in state.yaml, e.g. to keep them on an older generator
+during a migration. It is a full image reference, or a tag of the image of the
+repository prefixed with a colon, e.g. ":1.2.0". The pinned libraries are
+marked, and counted at the end of the report.
+
+Libraries whose generation is blocked with the "block-library" command are
+listed with the reason and bug of the block, and counted at the end of the
+report too.
+
+The active freeze of the release policy in ".librarian/config.yaml", during
+which no release pull request is created and no release is tagged, is reported
+at the end of the report as well.
+
+With "-output-format=json", the state of every library is printed as a JSON
+array instead, with the ID, version, last generated commit and image, whether
+the image is pinned, the block of its generation and the active release freeze,
+if any.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		repo, err := cloneOrOpenRepo(cfg.WorkRoot, cfg.Repo, cfg.CI, cfg.GitHubToken, repoSSHOptions(cfg), gitTuning(cfg))
+		if err != nil {
+			return err
+		}
+		state, err := loadRepoState(repo, "", "")
+		if err != nil {
+			return err
+		}
+		librarianConfig, err := loadLibrarianConfig(repo)
+		if err != nil {
+			return err
+		}
+		var policy *config.ReleasePolicy
+		if librarianConfig != nil {
+			policy = librarianConfig.ReleasePolicy
+		}
+		freeze, err := activeFreeze(ctx, policy, now(), cfg.Offline)
+		if err != nil {
+			// The state is still reported without the freezes of the feed.
+			slog.Warn("Failed to determine the active release freeze", "err", err)
+		}
+		return status(os.Stdout, cfg, state, freeze)
+	},
+}
+
+func init() {
+	cmdStatus.Init()
+	fs := cmdStatus.Flags
+	cfg := cmdStatus.Config
+
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagOutputFormat(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+// libraryStatus is the state of a library reported by status.
+type libraryStatus struct {
+	ID                  string `json:"id"`
+	Version             string `json:"version,omitempty"`
+	LastGeneratedCommit string `json:"last_generated_commit,omitempty"`
+	Image               string `json:"image"`
+	// Pinned reports whether the library is pinned to another image than the
+	// image of the repository.
+	Pinned bool `json:"pinned"`
+	// Blocked is why the generation of the library is blocked, if it is.
+	Blocked *config.GenerationBlock `json:"blocked,omitempty"`
+	// ReleaseFreeze is the active freeze of the releases of the repository,
+	// if any.
+	ReleaseFreeze *config.ReleaseFreeze `json:"release_freeze,omitempty"`
+}
+
+// status writes the state of the libraries of state, and the active release
+// freeze, if any, to w, in the -output-format of cfg.
+func status(w io.Writer, cfg *config.Config, state *config.LibrarianState, freeze *config.ReleaseFreeze) error {
+	var statuses []*libraryStatus
+	pinned, blocked := 0, 0
+	for _, library := range state.Libraries {
+		s := &libraryStatus{
+			ID:                  library.ID,
+			Version:             library.Version,
+			LastGeneratedCommit: library.LastGeneratedCommit,
+			Image:               state.LibraryImage(library),
+			Blocked:             library.Blocked,
+			ReleaseFreeze:       freeze,
+		}
+		s.Pinned = s.Image != state.Image
+		if s.Pinned {
+			pinned++
+		}
+		if s.Blocked != nil {
+			blocked++
+		}
+		statuses = append(statuses, s)
+	}
+	if cfg.OutputFormat == config.OutputFormatJSON {
+		data, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LIBRARY\tVERSION\tLAST GENERATED\tIMAGE\tBLOCKED")
+	for _, s := range statuses {
+		image := "default"
+		if s.Pinned {
+			image = s.Image + " (pinned)"
+		}
+		block := ""
+		if s.Blocked != nil {
+			block = blockDescription(s.Blocked)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.ID, s.Version, shortSHA(s.LastGeneratedCommit), image, block)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "\n%d of %d libraries on non-default images; default image: %s\n", pinned, len(statuses), state.Image); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%d of %d libraries blocked from generation\n", blocked, len(statuses)); err != nil {
+		return err
+	}
+	if freeze != nil {
+		if _, err := fmt.Fprintf(w, "Releases are frozen %s\n", freezeDescription(freeze)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+	state := &config.LibrarianState{
+		Image: "gcr.io/test/image:v2.0.0",
+		Libraries: []*config.LibraryState{
+			{ID: "secretmanager", Version: "1.2.0", LastGeneratedCommit: "0123456789abcdef0123456789abcdef01234567"},
+			{ID: "storage", Version: "3.0.0", Image: ":v1.9.0"},
+			{ID: "pubsub", Version: "2.1.0", Image: "gcr.io/test/image:v2.0.0", Blocked: &config.GenerationBlock{Reason: "generator bug", Bug: "b/123"}},
+		},
+	}
+
+	var text bytes.Buffer
+	if err := status(&text, &config.Config{}, state, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"secretmanager  1.2.0    0123456         default                            \n",
+		"storage        3.0.0                    gcr.io/test/image:v1.9.0 (pinned)  \n",
+		"pubsub         2.1.0                    default                            generator bug (b/123)\n",
+		"1 of 3 libraries on non-default images; default image: gcr.io/test/image:v2.0.0\n",
+		"1 of 3 libraries blocked from generation\n",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("status() = %q, want it to contain %q", text.String(), want)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := status(&out, &config.Config{OutputFormat: config.OutputFormatJSON}, state, nil); err != nil {
+		t.Fatal(err)
+	}
+	var got []*libraryStatus
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []*libraryStatus{
+		{ID: "secretmanager", Version: "1.2.0", LastGeneratedCommit: "0123456789abcdef0123456789abcdef01234567", Image: "gcr.io/test/image:v2.0.0"},
+		{ID: "storage", Version: "3.0.0", Image: "gcr.io/test/image:v1.9.0", Pinned: true},
+		{ID: "pubsub", Version: "2.1.0", Image: "gcr.io/test/image:v2.0.0", Blocked: &config.GenerationBlock{Reason: "generator bug", Bug: "b/123"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("status() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStatus_ReleaseFreeze(t *testing.T) {
+	t.Parallel()
+	state := &config.LibrarianState{
+		Image:     "gcr.io/test/image:v2.0.0",
+		Libraries: []*config.LibraryState{{ID: "storage", Version: "3.0.0"}},
+	}
+	freeze := &config.ReleaseFreeze{Start: "2025-12-20", End: "2026-01-05", Reason: "holidays"}
+	var text bytes.Buffer
+	if err := status(&text, &config.Config{}, state, freeze); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Releases are frozen from 2025-12-20 to 2026-01-05 (holidays)\n"; !strings.Contains(text.String(), want) {
+		t.Errorf("status() = %q, want it to contain %q", text.String(), want)
+	}
+	var out bytes.Buffer
+	if err := status(&out, &config.Config{OutputFormat: config.OutputFormatJSON}, state, freeze); err != nil {
+		t.Fatal(err)
+	}
+	var got []*libraryStatus
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(freeze, got[0].ReleaseFreeze); diff != "" {
+		t.Errorf("status() release freeze mismatch (-want +got):\n%s", diff)
+	}
+}
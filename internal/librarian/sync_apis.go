@@ -0,0 +1,286 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+const (
+	syncAPIsCmdName = "sync-apis"
+
+	apiSnapshotProvenanceFile = "provenance.json"
+)
+
+var cmdSyncAPIs = &cli.Command{
+	Short:     "sync-apis updates the snapshot of API definitions in the language repository",
+	UsageLine: "librarian sync-apis [flags]",
+	Long: `Copies the API definitions of every library from the API source repository into a
+snapshot directory of the language repository, like a vendored copy of the protos.
+
+The snapshot holds the directories of the APIs listed in ".librarian/state.yaml", and the
+proto files which they import, directly or indirectly, from the API source repository.
+It is written to "third_party/googleapis", or to the "api_snapshot" path configured in
+".librarian/config.yaml", replacing the previous snapshot. A "provenance.json" file in
+the snapshot records the API source repository and the commit it was copied from, so
+that builds can run offline and generation can be reproduced.
+
+By default, the HEAD of the API source is copied. To pin the snapshot to an exact commit,
+tag or branch, specify it with "-api-ref".
+
+If the "-commit" or "-push" flags are specified, the changes are committed and,
+with "-push", a pull request is created.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newSyncAPIsRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return runner.run(ctx)
+	},
+}
+
+func init() {
+	cmdSyncAPIs.Init()
+	fs := cmdSyncAPIs.Flags
+	cfg := cmdSyncAPIs.Config
+
+	addFlagAPIRef(fs, cfg)
+	addFlagAPISource(fs, cfg)
+	addFlagAPISourceSSHKey(fs, cfg)
+	addFlagAuditLog(fs, cfg)
+	addFlagCommit(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagPush(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+type syncAPIsRunner struct {
+	cfg             *config.Config
+	repo            gitrepo.Repository
+	sourceRepo      gitrepo.Repository
+	apiSource       *apiSourceProvenance
+	state           *config.LibrarianState
+	librarianConfig *config.LibrarianConfig
+	ghClient        GitHubClient
+	gerrit          *gerritReview
+}
+
+func newSyncAPIsRunner(cfg *config.Config) (*syncAPIsRunner, error) {
+	runner, err := newCommandRunner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &syncAPIsRunner{
+		cfg:             runner.cfg,
+		repo:            runner.repo,
+		sourceRepo:      runner.sourceRepo,
+		apiSource:       runner.apiSource,
+		state:           runner.state,
+		librarianConfig: runner.librarianConfig,
+		ghClient:        runner.ghClient,
+		gerrit:          runner.gerrit,
+	}, nil
+}
+
+// apiSnapshotProvenance records where a snapshot of API definitions was
+// copied from.
+type apiSnapshotProvenance struct {
+	// Source is the URL of the API source repository. It is empty for a local
+	// API source.
+	Source string `json:"source,omitempty"`
+	// Commit is the commit of the API source repository which was copied.
+	Commit string `json:"commit"`
+	// Ref is the ref specified with -api-ref which Commit was resolved from.
+	Ref string `json:"ref,omitempty"`
+	// APIs are the paths of the copied APIs.
+	APIs []string `json:"apis"`
+	// Imports are the proto files outside of the APIs which the APIs import,
+	// directly or indirectly.
+	Imports []string `json:"imports,omitempty"`
+}
+
+func (r *syncAPIsRunner) run(ctx context.Context) error {
+	if err := checkReviewPermissions(ctx, r.cfg, r.ghClient, r.gerrit); err != nil {
+		return err
+	}
+	apiPaths := stateAPIPaths(r.state)
+	if len(apiPaths) == 0 {
+		slog.Info("No APIs configured in state, skipping API snapshot")
+		return nil
+	}
+	sourceDir := r.sourceRepo.GetDir()
+	if err := validateAPIPaths(sourceDir, apiPaths); err != nil {
+		return err
+	}
+	commit, err := r.sourceRepo.HeadHash()
+	if err != nil {
+		return err
+	}
+	files, imports, err := apiSnapshotFiles(sourceDir, apiPaths)
+	if err != nil {
+		return err
+	}
+
+	snapshotPath := r.librarianConfig.APISnapshotPath()
+	snapshotDir := filepath.Join(r.repo.GetDir(), snapshotPath)
+	slog.Info("Updating API snapshot", "dir", snapshotDir, "commit", commit, "apis", len(apiPaths), "files", len(files))
+	// Removed APIs and imports must not linger in the snapshot.
+	if err := os.RemoveAll(snapshotDir); err != nil {
+		return fmt.Errorf("failed to remove previous API snapshot: %w", err)
+	}
+	for _, file := range files {
+		if err := copyFile(filepath.Join(snapshotDir, file), filepath.Join(sourceDir, file)); err != nil {
+			return fmt.Errorf("failed to copy %s to API snapshot: %w", file, err)
+		}
+	}
+	provenance := &apiSnapshotProvenance{
+		Commit:  commit,
+		APIs:    apiPaths,
+		Imports: imports,
+	}
+	if isRemote(r.cfg.APISource) {
+		provenance.Source = r.cfg.APISource
+	}
+	if r.apiSource != nil {
+		provenance.Ref = r.apiSource.Ref
+	}
+	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
+		return fmt.Errorf("failed to make directory: %w", err)
+	}
+	if err := writeAPISnapshotProvenance(snapshotDir, provenance); err != nil {
+		return err
+	}
+
+	return commitAndPush(ctx, &commitInfo{
+		cfg:      r.cfg,
+		state:    r.state,
+		repo:     r.repo,
+		ghClient: r.ghClient,
+		gerrit:   r.gerrit,
+		commitMessage: fmt.Sprintf("chore: sync API snapshot to %s\n\nCopied %d APIs and %d imported proto files at %s into %s.\n",
+			shortSHA(commit), len(apiPaths), len(imports), commit, snapshotPath),
+	})
+}
+
+// stateAPIPaths returns the sorted paths of the APIs of all libraries.
+func stateAPIPaths(state *config.LibrarianState) []string {
+	var paths []string
+	for _, library := range state.Libraries {
+		for _, api := range library.APIs {
+			paths = append(paths, api.Path)
+		}
+	}
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// protoImportRegex matches the import statements of a proto file.
+var protoImportRegex = regexp.MustCompile(`(?m)^\s*import\s+(?:public\s+|weak\s+)?"([^"]+)"\s*;`)
+
+// apiSnapshotFiles returns the files of the snapshot of the APIs in the API
+// source at sourceDir: the files in the directories of the APIs, and the
+// proto files which they import, directly or indirectly. Imports which are
+// not in the API source, such as the well-known types of protobuf, are
+// skipped. The paths are sorted and relative to sourceDir, and the imports
+// outside of the API directories are also returned on their own.
+func apiSnapshotFiles(sourceDir string, apiPaths []string) (files, imports []string, err error) {
+	seen := make(map[string]bool)
+	var protos []string
+	for _, apiPath := range apiPaths {
+		root := filepath.Join(sourceDir, apiPath)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(sourceDir, path)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if !seen[rel] {
+				seen[rel] = true
+				files = append(files, rel)
+				if filepath.Ext(rel) == ".proto" {
+					protos = append(protos, rel)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	for len(protos) > 0 {
+		proto := protos[0]
+		protos = protos[1:]
+		content, err := os.ReadFile(filepath.Join(sourceDir, proto))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, match := range protoImportRegex.FindAllStringSubmatch(string(content), -1) {
+			imported := match[1]
+			if seen[imported] {
+				continue
+			}
+			seen[imported] = true
+			if _, err := os.Stat(filepath.Join(sourceDir, imported)); err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				return nil, nil, err
+			}
+			files = append(files, imported)
+			imports = append(imports, imported)
+			protos = append(protos, imported)
+		}
+	}
+	slices.Sort(files)
+	slices.Sort(imports)
+	return files, imports, nil
+}
+
+func writeAPISnapshotProvenance(snapshotDir string, provenance *apiSnapshotProvenance) error {
+	data, err := json.MarshalIndent(provenance, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(snapshotDir, apiSnapshotProvenanceFile)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write API snapshot provenance: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+// setupAPISource writes an API source with the secretmanager API, which
+// imports common protos, and an unrelated API.
+func setupAPISource(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for path, content := range map[string]string{
+		"google/cloud/secretmanager/v1/service.proto": `syntax = "proto3";
+import "google/api/annotations.proto";
+import public "google/cloud/secretmanager/v1/resources.proto";
+import "google/protobuf/empty.proto";
+`,
+		"google/cloud/secretmanager/v1/resources.proto":            `import "google/api/resource.proto";`,
+		"google/cloud/secretmanager/v1/secretmanager_v1.yaml":      "type: google.api.Service",
+		"google/cloud/secretmanager/v1/BUILD.bazel":                "",
+		"google/api/annotations.proto":                             `import "google/api/http.proto";`,
+		"google/api/http.proto":                                    "",
+		"google/api/resource.proto":                                `import "google/protobuf/descriptor.proto";`,
+		"google/api/client.proto":                                  "",
+		"google/cloud/functions/v2/functions.proto":                `import "google/api/client.proto";`,
+		"google/cloud/secretmanager/logging/v1/secret_event.proto": "",
+	} {
+		if err := writeFile(filepath.Join(dir, path), content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestAPISnapshotFiles(t *testing.T) {
+	sourceDir := setupAPISource(t)
+	files, imports, err := apiSnapshotFiles(sourceDir, []string{"google/cloud/secretmanager/v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFiles := []string{
+		"google/api/annotations.proto",
+		"google/api/http.proto",
+		"google/api/resource.proto",
+		"google/cloud/secretmanager/v1/BUILD.bazel",
+		"google/cloud/secretmanager/v1/resources.proto",
+		"google/cloud/secretmanager/v1/secretmanager_v1.yaml",
+		"google/cloud/secretmanager/v1/service.proto",
+	}
+	if diff := cmp.Diff(wantFiles, files); diff != "" {
+		t.Errorf("apiSnapshotFiles() files mismatch (-want +got):\n%s", diff)
+	}
+	wantImports := []string{
+		"google/api/annotations.proto",
+		"google/api/http.proto",
+		"google/api/resource.proto",
+	}
+	if diff := cmp.Diff(wantImports, imports); diff != "" {
+		t.Errorf("apiSnapshotFiles() imports mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSyncAPIsRunner(t *testing.T) {
+	sourceDir := setupAPISource(t)
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "secretmanager", APIs: []*config.API{{Path: "google/cloud/secretmanager/v1"}}},
+			{ID: "functions", APIs: []*config.API{{Path: "google/cloud/functions/v2"}}},
+		},
+	}
+	for _, test := range []struct {
+		name           string
+		config         *config.LibrarianConfig
+		apiSource      *apiSourceProvenance
+		state          *config.LibrarianState
+		wantDir        string
+		wantProvenance *apiSnapshotProvenance
+		wantErr        bool
+	}{
+		{
+			name:    "default path",
+			state:   state,
+			wantDir: config.DefaultAPISnapshotPath,
+			wantProvenance: &apiSnapshotProvenance{
+				Source:  "https://github.com/googleapis/googleapis",
+				Commit:  "1234567890abcdef",
+				APIs:    []string{"google/cloud/functions/v2", "google/cloud/secretmanager/v1"},
+				Imports: []string{"google/api/annotations.proto", "google/api/client.proto", "google/api/http.proto", "google/api/resource.proto"},
+			},
+		},
+		{
+			name:      "configured path and ref",
+			config:    &config.LibrarianConfig{APISnapshot: &config.APISnapshot{Path: "protos"}},
+			apiSource: &apiSourceProvenance{Commit: "1234567890abcdef", Ref: "v1.0.0"},
+			state: &config.LibrarianState{
+				Libraries: []*config.LibraryState{state.Libraries[1]},
+			},
+			wantDir: "protos",
+			wantProvenance: &apiSnapshotProvenance{
+				Source:  "https://github.com/googleapis/googleapis",
+				Commit:  "1234567890abcdef",
+				Ref:     "v1.0.0",
+				APIs:    []string{"google/cloud/functions/v2"},
+				Imports: []string{"google/api/client.proto"},
+			},
+		},
+		{
+			name: "missing API",
+			state: &config.LibrarianState{
+				Libraries: []*config.LibraryState{{ID: "missing", APIs: []*config.API{{Path: "google/cloud/missing/v1"}}}},
+			},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repoDir := t.TempDir()
+			stale := filepath.Join(repoDir, test.wantDir, "google/cloud/removed/v1/removed.proto")
+			if err := writeFile(stale, ""); err != nil {
+				t.Fatal(err)
+			}
+			r := &syncAPIsRunner{
+				cfg:             &config.Config{APISource: "https://github.com/googleapis/googleapis"},
+				repo:            &MockRepository{Dir: repoDir},
+				sourceRepo:      &MockRepository{Dir: sourceDir, HeadHashValue: "1234567890abcdef"},
+				apiSource:       test.apiSource,
+				state:           test.state,
+				librarianConfig: test.config,
+			}
+			err := r.run(context.Background())
+			if (err != nil) != test.wantErr {
+				t.Fatalf("run() error = %v, wantErr %t", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if _, err := os.Stat(stale); !os.IsNotExist(err) {
+				t.Errorf("stale file of the previous snapshot was not removed: %v", err)
+			}
+			snapshotDir := filepath.Join(repoDir, test.wantDir)
+			for _, api := range test.wantProvenance.APIs {
+				if _, err := os.Stat(filepath.Join(snapshotDir, api)); err != nil {
+					t.Errorf("API %s is missing from the snapshot: %v", api, err)
+				}
+			}
+			data, err := os.ReadFile(filepath.Join(snapshotDir, apiSnapshotProvenanceFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := &apiSnapshotProvenance{}
+			if err := json.Unmarshal(data, got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.wantProvenance, got); diff != "" {
+				t.Errorf("provenance mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/github"
+	"github.com/googleapis/librarian/internal/gitrepo"
+)
+
+const (
+	syncOwnersCmdName = "sync-owners"
+
+	codeOwnersFile       = ".github/CODEOWNERS"
+	codeOwnersBeginBlock = "# BEGIN LIBRARIAN MANAGED OWNERS"
+	codeOwnersEndBlock   = "# END LIBRARIAN MANAGED OWNERS"
+)
+
+var cmdSyncOwners = &cli.Command{
+	Short:     "sync-owners regenerates CODEOWNERS entries from library owners",
+	UsageLine: "librarian sync-owners [flags]",
+	Long: `Regenerates the CODEOWNERS entries for every library in the language repository.
+
+The owners of each library are read from the "owners" field in ".librarian/state.yaml"
+and written for each of the library's source roots into a block in ".github/CODEOWNERS"
+which is managed by librarian. Content outside of the managed block is left untouched.
+
+If the "-commit" or "-push" flags are specified, the changes are committed and,
+with "-push", a pull request is created.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newSyncOwnersRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return runner.run(ctx)
+	},
+}
+
+func init() {
+	cmdSyncOwners.Init()
+	fs := cmdSyncOwners.Flags
+	cfg := cmdSyncOwners.Config
+
+	addFlagAuditLog(fs, cfg)
+	addFlagCommit(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagPush(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+type syncOwnersRunner struct {
+	cfg      *config.Config
+	repo     gitrepo.Repository
+	state    *config.LibrarianState
+	ghClient GitHubClient
+	gerrit   *gerritReview
+}
+
+func newSyncOwnersRunner(cfg *config.Config) (*syncOwnersRunner, error) {
+	runner, err := newCommandRunner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &syncOwnersRunner{
+		cfg:      runner.cfg,
+		repo:     runner.repo,
+		state:    runner.state,
+		ghClient: runner.ghClient,
+		gerrit:   runner.gerrit,
+	}, nil
+}
+
+func (r *syncOwnersRunner) run(ctx context.Context) error {
+	if err := checkReviewPermissions(ctx, r.cfg, r.ghClient, r.gerrit); err != nil {
+		return err
+	}
+	path := filepath.Join(r.repo.GetDir(), codeOwnersFile)
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", codeOwnersFile, err)
+	}
+	content := updateCodeOwners(string(existing), r.state)
+	if content == string(existing) {
+		slog.Info("CODEOWNERS is up to date", "path", path)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to make directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", codeOwnersFile, err)
+	}
+	slog.Info("Updated CODEOWNERS", "path", path)
+
+	return commitAndPush(ctx, &commitInfo{
+		cfg:           r.cfg,
+		state:         r.state,
+		repo:          r.repo,
+		ghClient:      r.ghClient,
+		gerrit:        r.gerrit,
+		commitMessage: "chore: sync CODEOWNERS with library owners",
+	})
+}
+
+// updateCodeOwners returns the content of a CODEOWNERS file with the managed
+// block replaced by entries derived from the library owners in state. If the
+// existing content has no managed block, one is appended.
+func updateCodeOwners(existing string, state *config.LibrarianState) string {
+	block := formatCodeOwnersBlock(state)
+	begin := strings.Index(existing, codeOwnersBeginBlock)
+	end := strings.Index(existing, codeOwnersEndBlock)
+	if begin >= 0 && end > begin {
+		end += len(codeOwnersEndBlock)
+		if end < len(existing) && existing[end] == '\n' {
+			end++
+		}
+		return existing[:begin] + block + existing[end:]
+	}
+	if existing != "" && !strings.HasSuffix(existing, "\n") {
+		existing += "\n"
+	}
+	if existing != "" {
+		existing += "\n"
+	}
+	return existing + block
+}
+
+// formatCodeOwnersBlock formats the managed CODEOWNERS block. Each source root
+// of a library with owners gets a single entry.
+func formatCodeOwnersBlock(state *config.LibrarianState) string {
+	var builder strings.Builder
+	builder.WriteString(codeOwnersBeginBlock)
+	builder.WriteString("\n")
+	for _, library := range state.Libraries {
+		if len(library.Owners) == 0 {
+			continue
+		}
+		owners := strings.Join(library.Owners, " ")
+		for _, root := range library.SourceRoots {
+			fmt.Fprintf(&builder, "/%s/ %s\n", filepath.ToSlash(root), owners)
+		}
+	}
+	builder.WriteString(codeOwnersEndBlock)
+	builder.WriteString("\n")
+	return builder.String()
+}
+
+// ownersForLibraries returns the de-duplicated owners of the given libraries,
+// split into individual users and team slugs as expected by the GitHub API.
+func ownersForLibraries(state *config.LibrarianState, libraryIDs []string) (users, teams []string) {
+	for _, id := range libraryIDs {
+		library := findLibraryByID(state, id)
+		if library == nil {
+			continue
+		}
+		for _, owner := range library.Owners {
+			name := strings.TrimPrefix(owner, "@")
+			if _, team, ok := strings.Cut(name, "/"); ok {
+				if !slices.Contains(teams, team) {
+					teams = append(teams, team)
+				}
+				continue
+			}
+			if !slices.Contains(users, name) {
+				users = append(users, name)
+			}
+		}
+	}
+	return users, teams
+}
+
+// requestOwnerReviews requests reviews on the pull request from the owners of
+// the libraries in info. Failing to request reviews does not fail the
+// command, as the pull request has already been created.
+func requestOwnerReviews(ctx context.Context, info *commitInfo, pr *github.PullRequestMetadata) {
+	if pr == nil || info.state == nil {
+		return
+	}
+	users, teams := ownersForLibraries(info.state, info.libraryIDs)
+	if len(users) == 0 && len(teams) == 0 {
+		return
+	}
+	if err := info.ghClient.RequestReviewers(ctx, pr.Number, users, teams); err != nil {
+		slog.Warn("failed to request reviews from library owners", "pr", pr.Number, "err", err)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/github"
+)
+
+func TestUpdateCodeOwners(t *testing.T) {
+	t.Parallel()
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{
+				ID:          "a",
+				SourceRoots: []string{"src/a", "test/a"},
+				Owners:      []string{"@googleapis/team-a", "@octocat"},
+			},
+			{
+				ID:          "b",
+				SourceRoots: []string{"src/b"},
+			},
+		},
+	}
+	block := `# BEGIN LIBRARIAN MANAGED OWNERS
+/src/a/ @googleapis/team-a @octocat
+/test/a/ @googleapis/team-a @octocat
+# END LIBRARIAN MANAGED OWNERS
+`
+	for _, test := range []struct {
+		name     string
+		existing string
+		want     string
+	}{
+		{
+			name:     "empty file",
+			existing: "",
+			want:     block,
+		},
+		{
+			name:     "no managed block",
+			existing: "* @googleapis/admins",
+			want:     "* @googleapis/admins\n\n" + block,
+		},
+		{
+			name: "replace managed block",
+			existing: `* @googleapis/admins
+# BEGIN LIBRARIAN MANAGED OWNERS
+/src/old/ @someone
+# END LIBRARIAN MANAGED OWNERS
+/docs/ @writers
+`,
+			want: "* @googleapis/admins\n" + block + "/docs/ @writers\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := updateCodeOwners(test.existing, state)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("updateCodeOwners() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOwnersForLibraries(t *testing.T) {
+	t.Parallel()
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "a", Owners: []string{"@googleapis/team-a", "@octocat"}},
+			{ID: "b", Owners: []string{"@googleapis/team-a", "@hubot"}},
+			{ID: "c", Owners: []string{"@monalisa"}},
+		},
+	}
+	for _, test := range []struct {
+		name       string
+		libraryIDs []string
+		wantUsers  []string
+		wantTeams  []string
+	}{
+		{
+			name:       "single library",
+			libraryIDs: []string{"c"},
+			wantUsers:  []string{"monalisa"},
+		},
+		{
+			name:       "de-duplicates owners",
+			libraryIDs: []string{"a", "b"},
+			wantUsers:  []string{"octocat", "hubot"},
+			wantTeams:  []string{"team-a"},
+		},
+		{
+			name:       "unknown library",
+			libraryIDs: []string{"unknown"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			gotUsers, gotTeams := ownersForLibraries(state, test.libraryIDs)
+			if diff := cmp.Diff(test.wantUsers, gotUsers); diff != "" {
+				t.Errorf("ownersForLibraries() users mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.wantTeams, gotTeams); diff != "" {
+				t.Errorf("ownersForLibraries() teams mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRequestOwnerReviews(t *testing.T) {
+	t.Parallel()
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "a", Owners: []string{"@googleapis/team-a", "@octocat"}},
+			{ID: "b"},
+		},
+	}
+	for _, test := range []struct {
+		name       string
+		libraryIDs []string
+		pr         *github.PullRequestMetadata
+		wantCalls  int
+		wantUsers  []string
+		wantTeams  []string
+	}{
+		{
+			name:       "requests reviews",
+			libraryIDs: []string{"a"},
+			pr:         &github.PullRequestMetadata{Number: 1},
+			wantCalls:  1,
+			wantUsers:  []string{"octocat"},
+			wantTeams:  []string{"team-a"},
+		},
+		{
+			name:       "no owners",
+			libraryIDs: []string{"b"},
+			pr:         &github.PullRequestMetadata{Number: 1},
+		},
+		{
+			name:       "no pull request",
+			libraryIDs: []string{"a"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			client := &mockGitHubClient{}
+			requestOwnerReviews(context.Background(), &commitInfo{
+				state:      state,
+				ghClient:   client,
+				libraryIDs: test.libraryIDs,
+			}, test.pr)
+			if client.requestReviewersCalls != test.wantCalls {
+				t.Errorf("requestReviewersCalls = %d, want %d", client.requestReviewersCalls, test.wantCalls)
+			}
+			if diff := cmp.Diff(test.wantUsers, client.requestedUsers); diff != "" {
+				t.Errorf("requested users mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.wantTeams, client.requestedTeams); diff != "" {
+				t.Errorf("requested teams mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSyncOwnersRun(t *testing.T) {
+	t.Parallel()
+	repo := newTestGitRepo(t)
+	r := &syncOwnersRunner{
+		cfg:  &config.Config{},
+		repo: repo,
+		state: &config.LibrarianState{
+			Libraries: []*config.LibraryState{
+				{
+					ID:          "some-library",
+					SourceRoots: []string{"src/a"},
+					Owners:      []string{"@octocat"},
+				},
+			},
+		},
+		ghClient: &mockGitHubClient{},
+	}
+	if err := r.run(context.Background()); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(repo.GetDir(), codeOwnersFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# BEGIN LIBRARIAN MANAGED OWNERS
+/src/a/ @octocat
+# END LIBRARIAN MANAGED OWNERS
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("CODEOWNERS mismatch (-want +got):\n%s", diff)
+	}
+}
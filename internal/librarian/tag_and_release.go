@@ -19,6 +19,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"regexp"
 	"slices"
 	"strconv"
@@ -27,8 +29,10 @@ import (
 
 	"github.com/googleapis/librarian/internal/cli"
 	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
 	"github.com/googleapis/librarian/internal/github"
 	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/sbom"
 )
 
 const (
@@ -40,14 +44,47 @@ const (
 
 var (
 	detailsRegex = regexp.MustCompile(`(?s)<details><summary>(.*?)</summary>(.*?)</details>`)
-	summaryRegex = regexp.MustCompile(`(.*?): (v?\d+\.\d+\.\d+)`)
+	summaryRegex = regexp.MustCompile(`(.*?): (v?\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?)`)
 )
 
 // cmdTagAndRelease is the command for the `release tag-and-release` subcommand.
 var cmdTagAndRelease = &cli.Command{
 	Short:     "tag-and-release tags and creates a GitHub release for a merged pull request.",
 	UsageLine: "librarian release tag-and-release [arguments]",
-	Long:      "Tags and creates a GitHub release for a merged pull request.",
+	Long: `Tags and creates a GitHub release for a merged pull request.
+
+The status of each library in the release (pr-opened, merged, tagged or
+published) is tracked in the body of the release pull request, under the
+release ID which "librarian release init" recorded in state.yaml. It is updated
+after each library, so that re-running the command after a partial failure
+skips the libraries which are already released. Libraries whose GitHub release
+already exists are not released again either.
+
+Canary releases, whose versions are prereleases made by "librarian release init
+-channel=canary", are tagged with the canary tag format of config.yaml and
+published as GitHub prereleases.
+
+When "require_approval" is set in the "release_policy" of config.yaml, a
+release is only tagged once "librarian release approve-release" signed it off
+on the release pull request. An unapproved pull request keeps its pending
+label, so that it is processed again after its approval.
+
+When the base branch requires a merge queue, a pull request specified with -pr
+which is still queued is waited for, for at most -merge-queue-timeout, and the
+release is tagged at the commit which the queue merged, the first commit of the
+base branch whose state.yaml records the release ID, rather than at the merge
+commit reported for the pull request.
+
+With -release-artifacts, the built artifacts of each released library are
+attached to its GitHub release, together with their signatures. When
+"artifact_signing" is set in config.yaml, the artifacts must have been signed
+by "librarian release sign-artifacts" after the build. The assets which a
+previous run attached are not uploaded again.
+
+The tags of the release, and its merge commit, are then pushed to the mirrors
+listed under "mirrors" in config.yaml, if any. A mirror which cannot be pushed
+to does not fail the release; "librarian release reconcile-mirrors" pushes what
+it missed.`,
 	Run: func(ctx context.Context, cfg *config.Config) error {
 		runner, err := newTagAndReleaseRunner(cfg)
 		if err != nil {
@@ -62,15 +99,36 @@ func init() {
 	fs := cmdTagAndRelease.Flags
 	cfg := cmdTagAndRelease.Config
 
+	addFlagAttachSBOM(fs, cfg)
+	addFlagAuditLog(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagMergeQueueTimeout(fs, cfg)
+	addFlagOverrideFreeze(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagReleaseArtifacts(fs, cfg)
+	addFlagReportFailures(fs, cfg)
 	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
 	addFlagPR(fs, cfg)
+	addFlagVerbosity(fs, cfg)
 }
 
 type tagAndReleaseRunner struct {
-	cfg      *config.Config
-	ghClient GitHubClient
-	repo     gitrepo.Repository
-	state    *config.LibrarianState
+	cfg             *config.Config
+	ghClient        GitHubClient
+	repo            gitrepo.Repository
+	state           *config.LibrarianState
+	librarianConfig *config.LibrarianConfig
+	// mergeQueue reports whether the base branch requires a merge queue.
+	mergeQueue bool
+	// releaseTags are the tags of the existing GitHub releases, or nil if
+	// they are not fetched yet.
+	releaseTags []string
 }
 
 func newTagAndReleaseRunner(cfg *config.Config) (*tagAndReleaseRunner, error) {
@@ -82,15 +140,31 @@ func newTagAndReleaseRunner(cfg *config.Config) (*tagAndReleaseRunner, error) {
 		return nil, fmt.Errorf("`LIBRARIAN_GITHUB_TOKEN` must be set")
 	}
 	return &tagAndReleaseRunner{
-		cfg:      cfg,
-		repo:     runner.repo,
-		state:    runner.state,
-		ghClient: runner.ghClient,
+		cfg:             cfg,
+		repo:            runner.repo,
+		state:           runner.state,
+		librarianConfig: runner.librarianConfig,
+		ghClient:        runner.ghClient,
 	}, nil
 }
 
 func (r *tagAndReleaseRunner) run(ctx context.Context) error {
+	if err := checkGitHubPermissions(ctx, r.cfg, r.ghClient); err != nil {
+		return err
+	}
+	var policy *config.ReleasePolicy
+	if r.librarianConfig != nil {
+		policy = r.librarianConfig.ReleasePolicy
+	}
+	violation, _, err := checkReleaseFreeze(ctx, policy, now(), r.cfg)
+	if err != nil {
+		return err
+	}
+	if violation != nil {
+		return releasePolicyError([]*policyViolation{violation})
+	}
 	slog.Info("running tag-and-release command")
+	r.mergeQueue = requiresMergeQueue(ctx, r.ghClient)
 	prs, err := r.determinePullRequestsToProcess(ctx)
 	if err != nil {
 		return err
@@ -121,18 +195,19 @@ func (r *tagAndReleaseRunner) determinePullRequestsToProcess(ctx context.Context
 	slog.Info("determining pull requests to process")
 	if r.cfg.PullRequest != "" {
 		slog.Info("processing a single pull request", "pr", r.cfg.PullRequest)
-		ss := strings.Split(r.cfg.PullRequest, "/")
-		if len(ss) != pullRequestSegments {
-			return nil, fmt.Errorf("invalid pull request format: %s", r.cfg.PullRequest)
-		}
-		prNum, err := strconv.Atoi(ss[pullRequestSegments-1])
+		prNum, err := parsePullRequestNumber(r.cfg.PullRequest)
 		if err != nil {
-			return nil, fmt.Errorf("invalid pull request number: %s", ss[pullRequestSegments-1])
+			return nil, err
 		}
 		pr, err := r.ghClient.GetPullRequest(ctx, prNum)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get pull request %d: %w", prNum, err)
 		}
+		if r.mergeQueue && !pr.GetMerged() {
+			if pr, err = waitForMergeQueue(ctx, r.ghClient, pr, r.cfg.MergeQueueTimeout); err != nil {
+				return nil, err
+			}
+		}
 		return []*github.PullRequest{pr}, nil
 	}
 
@@ -146,6 +221,20 @@ func (r *tagAndReleaseRunner) determinePullRequestsToProcess(ctx context.Context
 	return prs, nil
 }
 
+// parsePullRequestNumber returns the number of the pull request specified with
+// the -pr flag, https://github.com/{owner}/{repo}/pull/{number}.
+func parsePullRequestNumber(pr string) (int, error) {
+	ss := strings.Split(pr, "/")
+	if len(ss) != pullRequestSegments {
+		return 0, fmt.Errorf("invalid pull request format: %s", pr)
+	}
+	number, err := strconv.Atoi(ss[pullRequestSegments-1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid pull request number: %s", ss[pullRequestSegments-1])
+	}
+	return number, nil
+}
+
 func (r *tagAndReleaseRunner) processPullRequest(ctx context.Context, p *github.PullRequest) error {
 	slog.Info("processing pull request", "pr", p.GetNumber())
 	releases := parsePullRequestBody(p.GetBody())
@@ -153,26 +242,243 @@ func (r *tagAndReleaseRunner) processPullRequest(ctx context.Context, p *github.
 		slog.Warn("no release details found in pull request body, skipping")
 		return nil
 	}
+	// The libraries are tagged and released after their dependencies.
+	releases, err := sortReleasesByDependencies(r.state, releases)
+	if err != nil {
+		return err
+	}
+	var libraryIDs []string
 	for _, release := range releases {
-		slog.Info("creating release", "library", release.Library, "version", release.Version)
-		commitish := p.GetMergeCommitSHA()
-
+		libraryIDs = append(libraryIDs, release.Library)
+	}
+	// The libraries of a release group are tagged together, or not at all.
+	if err := checkReleaseGroupsComplete(r.state, libraryIDs); err != nil {
+		return err
+	}
+	if r.librarianConfig != nil && r.librarianConfig.ReleasePolicy.RequiresApproval() {
+		if err := checkReleaseApproved(ctx, r.ghClient, r.librarianConfig, r.state, p.GetNumber(), releases); err != nil {
+			return err
+		}
+	}
+	body := p.GetBody()
+	status, err := parseReleaseStatus(body)
+	if err != nil {
+		slog.Warn("failed to parse release status, tracking it anew", "pr", p.GetNumber(), "error", err)
+	}
+	if status == nil {
+		status = &releaseStatus{ReleaseID: r.releaseID(libraryIDs)}
+	}
+	merged := false
+	for _, release := range releases {
+		if status.advance(release.Library, release.Version, releaseStatusMerged) {
+			merged = true
+		}
+	}
+	if merged {
+		body = r.saveReleaseStatus(ctx, p.GetNumber(), body, status)
+	}
+	releaseTags, err := r.listReleaseTags(ctx)
+	if err != nil {
+		return err
+	}
+	commitish := p.GetMergeCommitSHA()
+	if r.mergeQueue {
+		if commitish, err = resolveReleaseCommit(ctx, r.ghClient, p, status.ReleaseID, releases); err != nil {
+			return err
+		}
+	}
+	var tags []string
+	for _, release := range releases {
+		if status.reached(release.Library, releaseStatusPublished) {
+			slog.Info("library is already released, skipping", "library", release.Library, "version", release.Version)
+			continue
+		}
 		lib := r.state.LibraryByID(release.Library)
 		if lib == nil {
 			return fmt.Errorf("library %s not found", release.Library)
 		}
 
-		// Create the release.
+		// Create the release, unless a previous run did.
 		tagName := formatTag(lib, release.Version)
-		releaseName := fmt.Sprintf("%s %s", release.Library, release.Version)
-		if _, err := r.ghClient.CreateRelease(ctx, tagName, releaseName, release.Body, commitish); err != nil {
-			return fmt.Errorf("failed to create release: %w", err)
+		canary := isCanaryRelease(r.librarianConfig, lib, release.Version)
+		if canary {
+			tagName = formatCanaryTag(r.librarianConfig, lib, release.Version)
 		}
-
+		tags = append(tags, tagName)
+		var created *github.RepositoryRelease
+		if status.reached(release.Library, releaseStatusTagged) || slices.Contains(releaseTags, tagName) {
+			slog.Info("release already exists", "library", release.Library, "tag", tagName)
+		} else {
+			slog.Info("creating release", "library", release.Library, "version", release.Version)
+			releaseName := fmt.Sprintf("%s %s", release.Library, release.Version)
+			if created, err = r.ghClient.CreateRelease(ctx, tagName, releaseName, release.Body, commitish, canary); err != nil {
+				return fmt.Errorf("failed to create release: %w", err)
+			}
+			auditLogFromContext(ctx).record(auditReleaseCreated, map[string]string{
+				"library":   release.Library,
+				"version":   release.Version,
+				"tag":       tagName,
+				"commitish": commitish,
+			})
+		}
+		if r.cfg.AttachSBOM || r.cfg.ReleaseArtifacts != "" {
+			status.advance(release.Library, release.Version, releaseStatusTagged)
+			body = r.saveReleaseStatus(ctx, p.GetNumber(), body, status)
+			if created == nil {
+				if created, err = r.ghClient.GetReleaseByTag(ctx, tagName); err != nil {
+					return fmt.Errorf("failed to get release %s: %w", tagName, err)
+				}
+			}
+			if r.cfg.AttachSBOM {
+				if err := r.attachSBOM(ctx, created, lib, release.Version); err != nil {
+					return err
+				}
+			}
+			if r.cfg.ReleaseArtifacts != "" {
+				if err := r.attachArtifacts(ctx, created, lib); err != nil {
+					return err
+				}
+			}
+		}
+		status.advance(release.Library, release.Version, releaseStatusPublished)
+		body = r.saveReleaseStatus(ctx, p.GetNumber(), body, status)
+	}
+	// A mirror failing does not fail the release, which is done on GitHub:
+	// the reconcile-mirrors command pushes what it missed.
+	if err := mirrorRelease(ctx, r.cfg, r.repo, r.librarianConfig, commitish, tags); err != nil {
+		slog.Warn("failed to mirror release, run librarian release reconcile-mirrors", "pr", p.GetNumber(), "error", err)
 	}
 	return r.replacePendingLabel(ctx, p)
 }
 
+// releaseID returns the release ID recorded in the state for the given
+// libraries, or an empty string.
+func (r *tagAndReleaseRunner) releaseID(libraryIDs []string) string {
+	for _, id := range libraryIDs {
+		if library := r.state.LibraryByID(id); library != nil && library.ReleaseID != "" {
+			return library.ReleaseID
+		}
+	}
+	return ""
+}
+
+// listReleaseTags returns the tags of the existing GitHub releases, which are
+// fetched once per run.
+func (r *tagAndReleaseRunner) listReleaseTags(ctx context.Context) ([]string, error) {
+	if r.releaseTags == nil {
+		tags, err := r.ghClient.ListReleaseTags(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GitHub releases: %w", err)
+		}
+		r.releaseTags = append([]string{}, tags...)
+	}
+	return r.releaseTags, nil
+}
+
+// saveReleaseStatus persists status in body, the body of the pull request
+// with the given number, and returns the updated body. A failure is only
+// logged: a re-run still skips the libraries whose GitHub release exists.
+func (r *tagAndReleaseRunner) saveReleaseStatus(ctx context.Context, number int, body string, status *releaseStatus) string {
+	updated, err := replaceReleaseStatus(body, status)
+	if err == nil {
+		err = r.ghClient.UpdatePullRequestBody(ctx, number, updated)
+	}
+	if err != nil {
+		slog.Warn("failed to save release status", "pr", number, "error", err)
+		return body
+	}
+	return updated
+}
+
+// attachSBOM uploads the SBOM of library at version to the GitHub release,
+// unless a previous run attached it.
+func (r *tagAndReleaseRunner) attachSBOM(ctx context.Context, release *github.RepositoryRelease, library *config.LibraryState, version string) error {
+	if release == nil {
+		return fmt.Errorf("cannot attach SBOM of %s: release was not returned", library.ID)
+	}
+	attached, err := r.releaseAssets(ctx, release)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(attached, sbom.FileName) {
+		slog.Info("SBOM is already attached", "library", library.ID)
+		return nil
+	}
+	l := sbomLibrary(library, r.state.Image, defaultAPISource, library.LastGeneratedCommit, nil)
+	l.Version = version
+	data, err := marshalSBOM([]*sbom.Library{l})
+	if err != nil {
+		return err
+	}
+	if err := r.ghClient.UploadReleaseAsset(ctx, release.GetID(), sbom.FileName, data); err != nil {
+		return fmt.Errorf("failed to attach SBOM to release of %s: %w", library.ID, err)
+	}
+	return nil
+}
+
+// attachArtifacts uploads the artifacts which the build of library placed in
+// its directory of -release-artifacts to the GitHub release, together with
+// their signatures. When "artifact_signing" is configured in config.yaml,
+// every artifact must have been signed by "librarian release sign-artifacts",
+// so that no artifact is published without its signature. The assets which a
+// previous run attached, e.g. before failing on another one, are skipped, as
+// GitHub rejects an asset whose name exists.
+func (r *tagAndReleaseRunner) attachArtifacts(ctx context.Context, release *github.RepositoryRelease, library *config.LibraryState) error {
+	if release == nil {
+		return fmt.Errorf("cannot attach artifacts of %s: release was not returned", library.ID)
+	}
+	dir := filepath.Join(r.cfg.ReleaseArtifacts, library.ID)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		slog.Info("no release artifacts to attach", "library", library.ID, "dir", dir)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read release artifacts of %s: %w", library.ID, err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	if r.librarianConfig != nil && r.librarianConfig.ArtifactSigning != nil {
+		suffix := signatureSuffix(r.librarianConfig.ArtifactSigning.Method)
+		for _, name := range names {
+			if !isArtifactSignature(name) && !slices.Contains(names, name+suffix) {
+				return failure.New(failure.UserConfig, fmt.Errorf("artifact %s of %s is not signed, run librarian release sign-artifacts after the build", name, library.ID))
+			}
+		}
+	}
+	attached, err := r.releaseAssets(ctx, release)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if slices.Contains(attached, name) {
+			slog.Info("release asset is already attached", "library", library.ID, "asset", name)
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if err := r.ghClient.UploadReleaseAsset(ctx, release.GetID(), name, data); err != nil {
+			return fmt.Errorf("failed to attach %s to release of %s: %w", name, library.ID, err)
+		}
+	}
+	return nil
+}
+
+// releaseAssets returns the names of the assets attached to release.
+func (r *tagAndReleaseRunner) releaseAssets(ctx context.Context, release *github.RepositoryRelease) ([]string, error) {
+	attached, err := r.ghClient.ListReleaseAssets(ctx, release.GetID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the assets of release %s: %w", release.GetTagName(), err)
+	}
+	return attached, nil
+}
+
 // libraryRelease holds the parsed information from a pull request body.
 type libraryRelease struct {
 	// Body contains the release notes.
@@ -206,7 +512,31 @@ func parsePullRequestBody(body string) []libraryRelease {
 		slog.Warn("failed to parse pull request body", "match", strings.Join(match, "\n"))
 	}
 
-	return parsedBodies
+	metadata, err := parseReleaseMetadata(body)
+	if err != nil {
+		slog.Warn("failed to parse release metadata, using release notes", "error", err)
+		return parsedBodies
+	}
+	if metadata == nil {
+		return parsedBodies
+	}
+	// The release metadata is authoritative for which libraries are released
+	// at which versions, as it does not depend on the format of the notes.
+	var releases []libraryRelease
+	for _, library := range metadata.Libraries {
+		release := libraryRelease{
+			Library: library.ID,
+			Version: library.Version,
+		}
+		for _, parsed := range parsedBodies {
+			if parsed.Library == library.ID {
+				release.Body = parsed.Body
+				break
+			}
+		}
+		releases = append(releases, release)
+	}
+	return releases
 }
 
 // replacePendingLabel is a helper function that replaces the `release:pending` label with `release:done`.
@@ -24,6 +24,7 @@ import (
 	gh "github.com/google/go-github/v69/github"
 	"github.com/googleapis/librarian/internal/config"
 	"github.com/googleapis/librarian/internal/github"
+	"github.com/googleapis/librarian/internal/sbom"
 )
 
 func TestNewTagAndReleaseRunner(t *testing.T) {
@@ -66,10 +67,12 @@ func TestNewTagAndReleaseRunner(t *testing.T) {
 
 func TestDeterminePullRequestsToProcess(t *testing.T) {
 	pr123 := &github.PullRequest{}
+	mergedPR := &github.PullRequest{Number: gh.Ptr(123), Merged: gh.Ptr(true)}
 	for _, test := range []struct {
 		name       string
 		cfg        *config.Config
 		ghClient   GitHubClient
+		mergeQueue bool
 		want       []*github.PullRequest
 		wantErrMsg string
 	}{
@@ -84,6 +87,29 @@ func TestDeterminePullRequestsToProcess(t *testing.T) {
 			},
 			want: []*github.PullRequest{pr123},
 		},
+		{
+			name: "merged pull request with merge queue",
+			cfg: &config.Config{
+				PullRequest: "github.com/googleapis/librarian/pulls/123",
+			},
+			ghClient: &mockGitHubClient{
+				pullRequest: mergedPR,
+			},
+			mergeQueue: true,
+			want:       []*github.PullRequest{mergedPR},
+		},
+		{
+			name: "queued pull request without timeout",
+			cfg: &config.Config{
+				PullRequest: "github.com/googleapis/librarian/pulls/123",
+			},
+			ghClient: &mockGitHubClient{
+				pullRequest:  &github.PullRequest{Number: gh.Ptr(123), State: gh.Ptr("open")},
+				inMergeQueue: []bool{true},
+			},
+			mergeQueue: true,
+			wantErrMsg: "set -merge-queue-timeout",
+		},
 		{
 			name: "invalid pull request format",
 			cfg: &config.Config{
@@ -132,8 +158,9 @@ func TestDeterminePullRequestsToProcess(t *testing.T) {
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			r := &tagAndReleaseRunner{
-				cfg:      test.cfg,
-				ghClient: test.ghClient,
+				cfg:        test.cfg,
+				ghClient:   test.ghClient,
+				mergeQueue: test.mergeQueue,
 			}
 			got, err := r.determinePullRequestsToProcess(context.Background())
 			if err != nil {
@@ -249,6 +276,21 @@ Language Image: image
 				},
 			},
 		},
+		{
+			name: "prerelease",
+			body: `<details><summary>google-cloud-storage: 1.3.0-beta.2</summary>
+
+canary notes
+
+</details>`,
+			want: []libraryRelease{
+				{
+					Version: "1.3.0-beta.2",
+					Library: "google-cloud-storage",
+					Body:    "canary notes",
+				},
+			},
+		},
 		{
 			name: "multiple libraries",
 			body: `
@@ -329,6 +371,51 @@ some content
 				},
 			},
 		},
+		{
+			name: "release metadata",
+			body: `
+<details><summary>library-one: 2.0.0 (breaking)</summary>
+
+### ⚠ BREAKING CHANGES
+
+</details>
+
+<!-- BEGIN LIBRARIAN RELEASE METADATA
+libraries:
+    - id: library-one
+      version: 2.0.0
+      previous_version: 1.0.0
+      tag: library-one-2.0.0
+      breaking: true
+END LIBRARIAN RELEASE METADATA -->`,
+			want: []libraryRelease{
+				{
+					Version: "2.0.0",
+					Library: "library-one",
+					Body:    "### ⚠ BREAKING CHANGES",
+				},
+			},
+		},
+		{
+			name: "malformed release metadata",
+			body: `
+<details><summary>library-one: 1.0.0</summary>
+
+notes
+
+</details>
+
+<!-- BEGIN LIBRARIAN RELEASE METADATA
+libraries: [
+END LIBRARIAN RELEASE METADATA -->`,
+			want: []libraryRelease{
+				{
+					Version: "1.0.0",
+					Library: "library-one",
+					Body:    "notes",
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -366,14 +453,28 @@ func TestProcessPullRequest(t *testing.T) {
 		},
 	}
 
+	withStatus := func(status string) *github.PullRequest {
+		body := prBody + "\n" + releaseStatusBegin + "\nrelease_id: release-1\nlibraries:\n  - id: google-cloud-storage\n    version: v1.2.3\n    status: " + status + "\n" + releaseStatusEnd + "\n"
+		return &github.PullRequest{
+			Body:           &body,
+			Number:         &prNumber,
+			MergeCommitSHA: &mergeCommitSHA,
+			Labels:         []*gh.Label{{Name: gh.Ptr(releasePendingLabel)}},
+		}
+	}
+
 	for _, test := range []struct {
 		name                   string
 		pr                     *github.PullRequest
 		ghClient               *mockGitHubClient
 		state                  *config.LibrarianState
+		attachSBOM             bool
 		wantErrMsg             string
 		wantCreateReleaseCalls int
 		wantReplaceLabelsCalls int
+		wantUploadAssetCalls   int
+		// wantStatus is the last release status saved in the pull request.
+		wantStatus string
 	}{
 		{
 			name:                   "happy path",
@@ -382,6 +483,34 @@ func TestProcessPullRequest(t *testing.T) {
 			state:                  state,
 			wantCreateReleaseCalls: 1,
 			wantReplaceLabelsCalls: 1,
+			wantStatus:             releaseStatusPublished,
+		},
+		{
+			name:                   "already published",
+			pr:                     withStatus(releaseStatusPublished),
+			ghClient:               &mockGitHubClient{},
+			state:                  state,
+			wantReplaceLabelsCalls: 1,
+		},
+		{
+			name:                   "release already exists on GitHub",
+			pr:                     withStatus(releaseStatusMerged),
+			ghClient:               &mockGitHubClient{releaseTags: []string{"google-cloud-storage-v1.2.3"}},
+			state:                  state,
+			wantReplaceLabelsCalls: 1,
+			wantStatus:             releaseStatusPublished,
+		},
+		{
+			name: "attach SBOM after tagging",
+			pr:   withStatus(releaseStatusTagged),
+			ghClient: &mockGitHubClient{
+				createdRelease: &github.RepositoryRelease{ID: gh.Ptr(int64(1))},
+			},
+			state:                  state,
+			attachSBOM:             true,
+			wantReplaceLabelsCalls: 1,
+			wantUploadAssetCalls:   1,
+			wantStatus:             releaseStatusPublished,
 		},
 		{
 			name:     "no release details",
@@ -395,6 +524,19 @@ func TestProcessPullRequest(t *testing.T) {
 			ghClient:   &mockGitHubClient{},
 			state:      &config.LibrarianState{},
 			wantErrMsg: "library google-cloud-storage not found",
+			wantStatus: releaseStatusMerged,
+		},
+		{
+			name:     "incomplete release group",
+			pr:       prWithRelease,
+			ghClient: &mockGitHubClient{},
+			state: &config.LibrarianState{
+				Libraries: []*config.LibraryState{
+					{ID: "google-cloud-storage", ReleaseGroup: "storage"},
+					{ID: "google-cloud-storage-control", ReleaseGroup: "storage"},
+				},
+			},
+			wantErrMsg: "released without google-cloud-storage-control",
 		},
 		{
 			name: "create release fails",
@@ -405,6 +547,7 @@ func TestProcessPullRequest(t *testing.T) {
 			state:                  state,
 			wantErrMsg:             "failed to create release",
 			wantCreateReleaseCalls: 1,
+			wantStatus:             releaseStatusMerged,
 		},
 		{
 			name: "replace labels fails",
@@ -416,10 +559,39 @@ func TestProcessPullRequest(t *testing.T) {
 			wantErrMsg:             "failed to replace labels",
 			wantCreateReleaseCalls: 1,
 			wantReplaceLabelsCalls: 1,
+			wantStatus:             releaseStatusPublished,
+		},
+		{
+			name: "attach SBOM",
+			pr:   prWithRelease,
+			ghClient: &mockGitHubClient{
+				createdRelease: &github.RepositoryRelease{ID: gh.Ptr(int64(1))},
+			},
+			state:                  state,
+			attachSBOM:             true,
+			wantCreateReleaseCalls: 1,
+			wantReplaceLabelsCalls: 1,
+			wantUploadAssetCalls:   1,
+			wantStatus:             releaseStatusPublished,
+		},
+		{
+			name: "attach SBOM fails",
+			pr:   prWithRelease,
+			ghClient: &mockGitHubClient{
+				createdRelease:        &github.RepositoryRelease{ID: gh.Ptr(int64(1))},
+				uploadReleaseAssetErr: errors.New("upload error"),
+			},
+			state:                  state,
+			attachSBOM:             true,
+			wantErrMsg:             "failed to attach SBOM",
+			wantCreateReleaseCalls: 1,
+			wantUploadAssetCalls:   1,
+			wantStatus:             releaseStatusTagged,
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			r := &tagAndReleaseRunner{
+				cfg:      &config.Config{AttachSBOM: test.attachSBOM},
 				ghClient: test.ghClient,
 				state:    test.state,
 			}
@@ -441,10 +613,92 @@ func TestProcessPullRequest(t *testing.T) {
 			if test.ghClient.replaceLabelsCalls != test.wantReplaceLabelsCalls {
 				t.Errorf("replaceLabelsCalls = %v, want %v", test.ghClient.replaceLabelsCalls, test.wantReplaceLabelsCalls)
 			}
+			if test.ghClient.uploadReleaseAssetCalls != test.wantUploadAssetCalls {
+				t.Errorf("uploadReleaseAssetCalls = %v, want %v", test.ghClient.uploadReleaseAssetCalls, test.wantUploadAssetCalls)
+			}
+			if test.wantUploadAssetCalls > 0 && test.ghClient.uploadedAssetName != sbom.FileName {
+				t.Errorf("uploadedAssetName = %q, want %q", test.ghClient.uploadedAssetName, sbom.FileName)
+			}
+			var gotStatus string
+			if n := len(test.ghClient.updatedBodies); n > 0 {
+				status, err := parseReleaseStatus(test.ghClient.updatedBodies[n-1])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.HasPrefix(test.ghClient.updatedBodies[n-1], prBody) {
+					t.Errorf("updated body %q does not keep the release notes", test.ghClient.updatedBodies[n-1])
+				}
+				gotStatus = status.Libraries[0].Status
+			}
+			if gotStatus != test.wantStatus {
+				t.Errorf("saved release status = %q, want %q", gotStatus, test.wantStatus)
+			}
 		})
 	}
 }
 
+func TestProcessPullRequest_Canary(t *testing.T) {
+	body := "<details><summary>a: 1.5.0-beta.2</summary>canary notes</details>\n<details><summary>b: 2.0.0</summary>stable notes</details>"
+	pr := &github.PullRequest{
+		Body:           &body,
+		Number:         gh.Ptr(123),
+		MergeCommitSHA: gh.Ptr("abcdef"),
+		Labels:         []*gh.Label{{Name: gh.Ptr(releasePendingLabel)}},
+	}
+	ghClient := &mockGitHubClient{}
+	r := &tagAndReleaseRunner{
+		cfg:      &config.Config{},
+		ghClient: ghClient,
+		state: &config.LibrarianState{
+			Libraries: []*config.LibraryState{
+				{ID: "a", Version: "1.4.2", CanaryVersion: "1.5.0-beta.2"},
+				{ID: "b", Version: "2.0.0"},
+			},
+		},
+	}
+	if err := r.processPullRequest(context.Background(), pr); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a-canary-1.5.0-beta.2", "b-2.0.0"}, ghClient.createdReleaseTags); diff != "" {
+		t.Errorf("created release tags mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"a-canary-1.5.0-beta.2"}, ghClient.createdPrereleaseTags); diff != "" {
+		t.Errorf("created prerelease tags mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestProcessPullRequest_MergeQueue(t *testing.T) {
+	body := "<details><summary>a: 1.2.0</summary>notes</details>\n" + releaseStatusBegin + "\nrelease_id: release-2\nlibraries:\n  - id: a\n    version: 1.2.0\n    status: pr-opened\n" + releaseStatusEnd + "\n"
+	pr := &github.PullRequest{
+		Body:           &body,
+		Number:         gh.Ptr(123),
+		MergeCommitSHA: gh.Ptr("queue-commit"),
+		Labels:         []*gh.Label{{Name: gh.Ptr(releasePendingLabel)}},
+	}
+	ghClient := &mockGitHubClient{
+		commitsTouching: []string{"later", "squashed", "before"},
+		rawContentByRef: map[string][]byte{
+			"later":    []byte("libraries:\n  - id: a\n    version: 1.2.0\n    release_id: release-2\n"),
+			"squashed": []byte("libraries:\n  - id: a\n    version: 1.2.0\n    release_id: release-2\n"),
+			"before":   []byte("libraries:\n  - id: a\n    release_id: release-1\n"),
+		},
+	}
+	r := &tagAndReleaseRunner{
+		cfg:        &config.Config{},
+		ghClient:   ghClient,
+		mergeQueue: true,
+		state: &config.LibrarianState{
+			Libraries: []*config.LibraryState{{ID: "a", Version: "1.2.0"}},
+		},
+	}
+	if err := r.processPullRequest(context.Background(), pr); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ghClient.createdReleaseCommitish, "squashed"; got != want {
+		t.Errorf("release created at %q, want %q", got, want)
+	}
+}
+
 func TestReplacePendingLabel(t *testing.T) {
 	prWithPending := &github.PullRequest{
 		Number: gh.Ptr(123),
@@ -0,0 +1,300 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/cli"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+	"github.com/googleapis/librarian/internal/gitrepo"
+	"github.com/googleapis/librarian/internal/semver"
+)
+
+// The kinds of drift between the state and the releases of a repository.
+const (
+	// driftMissingTag is a version in the state without a tag.
+	driftMissingTag = "missing-tag"
+	// driftMissingRelease is a tag of a version in the state without a
+	// GitHub release.
+	driftMissingRelease = "missing-release"
+	// driftVersionMismatch is a library with a tag of a later version than
+	// the version in the state.
+	driftVersionMismatch = "version-mismatch"
+	// driftUntrackedTag is a tag which matches no library in the state.
+	driftUntrackedTag = "untracked-tag"
+)
+
+var cmdVerifyReleases = &cli.Command{
+	Short:     "verify-releases checks the versions in the state against git tags and GitHub releases",
+	UsageLine: "librarian verify-releases [flags]",
+	Long: `Cross-checks the versions of the libraries in ".librarian/state.yaml" against
+the git tags and GitHub releases of the language repository, and reports:
+
+- missing-tag: the tag of the version of a library in the state does not
+  exist.
+- missing-release: the tag of the version of a library in the state has no
+  GitHub release.
+- version-mismatch: a library has a tag of a later version than the version
+  in the state.
+- untracked-tag: a tag does not match the tag format of any library in the
+  state.
+
+The command fails if any drift is found. With "-fix", the versions in the
+state are updated to the latest tags, and the missing GitHub releases of
+existing tags are created, without release notes. Missing and untracked tags
+cannot be fixed automatically, and are only reported. If the "-commit" or
+"-push" flags are specified, the updated state is committed and, with
+"-push", a pull request is created.`,
+	Run: func(ctx context.Context, cfg *config.Config) error {
+		runner, err := newVerifyReleasesRunner(cfg)
+		if err != nil {
+			return err
+		}
+		return runner.run(ctx, os.Stdout)
+	},
+}
+
+func init() {
+	cmdVerifyReleases.Init()
+	fs := cmdVerifyReleases.Flags
+	cfg := cmdVerifyReleases.Config
+
+	addFlagAuditLog(fs, cfg)
+	addFlagCommit(fs, cfg)
+	addFlagErrorFormat(fs, cfg)
+	addFlagFix(fs, cfg)
+	addFlagGitHubAPIURL(fs, cfg)
+	addFlagGitHubUploadURL(fs, cfg)
+	addFlagGitTuning(fs, cfg)
+	addFlagLogCommands(fs, cfg)
+	addFlagProfile(fs, cfg)
+	addFlagPush(fs, cfg)
+	addFlagRepo(fs, cfg)
+	addFlagSSHKey(fs, cfg)
+	addFlagSSHKnownHosts(fs, cfg)
+	addFlagVerbosity(fs, cfg)
+	addFlagWorkRoot(fs, cfg)
+}
+
+type verifyReleasesRunner struct {
+	cfg      *config.Config
+	repo     gitrepo.Repository
+	state    *config.LibrarianState
+	ghClient GitHubClient
+}
+
+func newVerifyReleasesRunner(cfg *config.Config) (*verifyReleasesRunner, error) {
+	runner, err := newCommandRunner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &verifyReleasesRunner{
+		cfg:      runner.cfg,
+		repo:     runner.repo,
+		state:    runner.state,
+		ghClient: runner.ghClient,
+	}, nil
+}
+
+// releaseDrift is a discrepancy between the state and the tags or GitHub
+// releases of the repository.
+type releaseDrift struct {
+	kind string
+	// libraryID is empty for untracked tags.
+	libraryID string
+	tag       string
+	// version is the version in the state, or the version of the latest tag
+	// for a version mismatch.
+	version string
+}
+
+// String returns a description of the drift.
+func (d *releaseDrift) String() string {
+	switch d.kind {
+	case driftMissingTag:
+		return fmt.Sprintf("%s: %s: tag %s of version %s in state does not exist", d.libraryID, d.kind, d.tag, d.version)
+	case driftMissingRelease:
+		return fmt.Sprintf("%s: %s: tag %s has no GitHub release", d.libraryID, d.kind, d.tag)
+	case driftVersionMismatch:
+		return fmt.Sprintf("%s: %s: latest tag %s has version %s, later than in state", d.libraryID, d.kind, d.tag, d.version)
+	default:
+		return fmt.Sprintf("%s: tag %s does not match any library in state", d.kind, d.tag)
+	}
+}
+
+func (r *verifyReleasesRunner) run(ctx context.Context, w io.Writer) error {
+	if err := checkGitHubPermissions(ctx, r.cfg, r.ghClient); err != nil {
+		return err
+	}
+	if r.ghClient == nil {
+		return errNotOnGitHub(r.cfg)
+	}
+	tags, err := r.repo.Tags()
+	if err != nil {
+		return err
+	}
+	releaseTags, err := r.ghClient.ListReleaseTags(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list GitHub releases: %w", err)
+	}
+	drifts := findReleaseDrifts(r.state, tags, releaseTags)
+	var remaining []*releaseDrift
+	var reconciled []string
+	for _, drift := range drifts {
+		fixed := false
+		if r.cfg.Fix {
+			if fixed, err = r.fix(ctx, drift); err != nil {
+				return err
+			}
+		}
+		status := ""
+		if fixed {
+			status = " (fixed)"
+			if drift.kind == driftVersionMismatch {
+				reconciled = append(reconciled, drift.libraryID)
+			}
+		} else {
+			remaining = append(remaining, drift)
+		}
+		if _, err := fmt.Fprintf(w, "%s%s\n", drift, status); err != nil {
+			return err
+		}
+	}
+	if len(reconciled) > 0 {
+		if err := saveLibrarianState(ctx, r.repo.GetDir(), r.state); err != nil {
+			return fmt.Errorf("failed to save state: %w", err)
+		}
+		if err := commitAndPush(ctx, &commitInfo{
+			cfg:           r.cfg,
+			state:         r.state,
+			repo:          r.repo,
+			ghClient:      r.ghClient,
+			commitMessage: "chore: update library versions to their latest release tags",
+			libraryIDs:    reconciled,
+		}); err != nil {
+			return err
+		}
+	}
+	if len(remaining) > 0 {
+		return failure.New(failure.UserConfig, fmt.Errorf("found %d release drifts between state and tags", len(remaining)))
+	}
+	slog.Info("State is consistent with tags and GitHub releases", "fixed", len(drifts))
+	return nil
+}
+
+// fix reconciles drift, and reports whether it could.
+func (r *verifyReleasesRunner) fix(ctx context.Context, drift *releaseDrift) (bool, error) {
+	switch drift.kind {
+	case driftVersionMismatch:
+		library := r.state.LibraryByID(drift.libraryID)
+		library.Version = drift.version
+		library.PreviousReleaseTag = ""
+		return true, nil
+	case driftMissingRelease:
+		// The commitish is unused as the tag already exists.
+		name := fmt.Sprintf("%s %s", drift.libraryID, drift.version)
+		if _, err := r.ghClient.CreateRelease(ctx, drift.tag, name, "", drift.tag, false); err != nil {
+			return false, fmt.Errorf("failed to create release of tag %s: %w", drift.tag, err)
+		}
+		auditLogFromContext(ctx).record(auditReleaseCreated, map[string]string{
+			"library": drift.libraryID,
+			"version": drift.version,
+			"tag":     drift.tag,
+		})
+		return true, nil
+	}
+	return false, nil
+}
+
+// findReleaseDrifts returns the drift between the libraries in state, the
+// tags of the repository and the tags of its GitHub releases.
+func findReleaseDrifts(state *config.LibrarianState, tags, releaseTags []string) []*releaseDrift {
+	tagSet := make(map[string]bool)
+	for _, tag := range tags {
+		tagSet[tag] = true
+	}
+	releaseSet := make(map[string]bool)
+	for _, tag := range releaseTags {
+		releaseSet[tag] = true
+	}
+	tracked := make(map[string]bool)
+	var drifts []*releaseDrift
+	for _, library := range state.Libraries {
+		tag := previousReleaseTag(library)
+		tracked[tag] = true
+		tagRegex := tagFormatRegexp(library)
+		var latestTag string
+		var latest *semver.Version
+		for _, tag := range tags {
+			matches := tagRegex.FindStringSubmatch(tag)
+			if matches == nil {
+				continue
+			}
+			tracked[tag] = true
+			version, err := semver.Parse(matches[1])
+			if err != nil {
+				continue
+			}
+			if latest == nil || version.Compare(latest) > 0 {
+				latestTag, latest = tag, version
+			}
+		}
+
+		released := library.Version != "" && library.Version != "0.0.0"
+		current, err := semver.Parse(library.Version)
+		switch {
+		case latest != nil && (!released || (err == nil && latest.Compare(current) > 0)):
+			// The tag of the version in the state is superseded.
+			drifts = append(drifts, &releaseDrift{kind: driftVersionMismatch, libraryID: library.ID, tag: latestTag, version: latest.String()})
+		case released && !tagSet[tag]:
+			drifts = append(drifts, &releaseDrift{kind: driftMissingTag, libraryID: library.ID, tag: tag, version: library.Version})
+		case released && !releaseSet[tag]:
+			drifts = append(drifts, &releaseDrift{kind: driftMissingRelease, libraryID: library.ID, tag: tag, version: library.Version})
+		}
+	}
+	for _, tag := range tags {
+		if !tracked[tag] {
+			drifts = append(drifts, &releaseDrift{kind: driftUntrackedTag, tag: tag})
+		}
+	}
+	return drifts
+}
+
+// tagFormatRegexp returns a regular expression matching the tags of library,
+// which captures the version.
+func tagFormatRegexp(library *config.LibraryState) *regexp.Regexp {
+	tagFormat := library.TagFormat
+	if tagFormat == "" {
+		tagFormat = defaultTagFormat
+	}
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for i, part := range strings.Split(tagFormat, "{version}") {
+		if i > 0 {
+			pattern.WriteString(`(\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?)`)
+		}
+		pattern.WriteString(strings.ReplaceAll(regexp.QuoteMeta(part), regexp.QuoteMeta("{id}"), regexp.QuoteMeta(library.ID)))
+	}
+	pattern.WriteString("$")
+	return regexp.MustCompile(pattern.String())
+}
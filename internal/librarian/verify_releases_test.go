@@ -0,0 +1,210 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/failure"
+)
+
+func TestFindReleaseDrifts(t *testing.T) {
+	t.Parallel()
+	state := &config.LibrarianState{
+		Libraries: []*config.LibraryState{
+			{ID: "in-sync", Version: "1.0.0"},
+			{ID: "no-tag", Version: "1.1.0"},
+			{ID: "no-release", Version: "2.0.0"},
+			{ID: "behind", Version: "1.0.0"},
+			{ID: "unreleased", Version: "0.0.0"},
+			{ID: "renamed", Version: "3.0.0", PreviousReleaseTag: "old-name-3.0.0"},
+			{ID: "custom", Version: "1.0.0-beta.2", TagFormat: "{id}/v{version}"},
+		},
+	}
+	tags := []string{
+		"in-sync-0.9.0",
+		"in-sync-1.0.0",
+		"no-tag-1.0.0",
+		"no-release-2.0.0",
+		"behind-1.0.0",
+		"behind-1.2.0",
+		"behind-1.10.0",
+		"old-name-3.0.0",
+		"custom/v1.0.0-beta.2",
+		"custom/v1.0.0-beta.10",
+		"v0.1.0",
+	}
+	releaseTags := []string{
+		"in-sync-1.0.0",
+		"no-tag-1.0.0",
+		"behind-1.0.0",
+		"old-name-3.0.0",
+		"custom/v1.0.0-beta.2",
+	}
+	got := findReleaseDrifts(state, tags, releaseTags)
+	want := []*releaseDrift{
+		{kind: driftMissingTag, libraryID: "no-tag", tag: "no-tag-1.1.0", version: "1.1.0"},
+		{kind: driftMissingRelease, libraryID: "no-release", tag: "no-release-2.0.0", version: "2.0.0"},
+		{kind: driftVersionMismatch, libraryID: "behind", tag: "behind-1.10.0", version: "1.10.0"},
+		{kind: driftVersionMismatch, libraryID: "custom", tag: "custom/v1.0.0-beta.10", version: "1.0.0-beta.10"},
+		{kind: driftUntrackedTag, tag: "v0.1.0"},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(releaseDrift{})); diff != "" {
+		t.Errorf("findReleaseDrifts() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTagFormatRegexp(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name        string
+		library     *config.LibraryState
+		tag         string
+		wantVersion string
+	}{
+		{
+			name:        "default format",
+			library:     &config.LibraryState{ID: "foo"},
+			tag:         "foo-1.2.3",
+			wantVersion: "1.2.3",
+		},
+		{
+			name:    "default format, other library",
+			library: &config.LibraryState{ID: "foo"},
+			tag:     "foo-bar-1.2.3",
+		},
+		{
+			name:        "custom format",
+			library:     &config.LibraryState{ID: "foo.bar", TagFormat: "{id}/v{version}"},
+			tag:         "foo.bar/v1.2.3-rc.1",
+			wantVersion: "1.2.3-rc.1",
+		},
+		{
+			name:    "custom format, escaped id",
+			library: &config.LibraryState{ID: "foo.bar", TagFormat: "{id}/v{version}"},
+			tag:     "fooxbar/v1.2.3",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			matches := tagFormatRegexp(test.library).FindStringSubmatch(test.tag)
+			got := ""
+			if matches != nil {
+				got = matches[1]
+			}
+			if got != test.wantVersion {
+				t.Errorf("tagFormatRegexp() matched version %q, want %q", got, test.wantVersion)
+			}
+		})
+	}
+}
+
+func TestVerifyReleasesRun(t *testing.T) {
+	t.Parallel()
+	newState := func() *config.LibrarianState {
+		return &config.LibrarianState{
+			Image: "some/image:v1",
+			Libraries: []*config.LibraryState{
+				{ID: "behind", Version: "1.0.0", SourceRoots: []string{"behind"}},
+				{ID: "no-release", Version: "2.0.0", SourceRoots: []string{"no-release"}},
+			},
+		}
+	}
+	tags := []string{"behind-1.0.0", "behind-1.1.0", "no-release-2.0.0"}
+	releaseTags := []string{"behind-1.0.0", "behind-1.1.0"}
+
+	for _, test := range []struct {
+		name               string
+		fix                bool
+		tags               []string
+		wantOut            string
+		wantErr            bool
+		wantVersion        string
+		wantCreatedRelease []string
+	}{
+		{
+			name:        "report",
+			tags:        tags,
+			wantOut:     "behind: version-mismatch: latest tag behind-1.1.0 has version 1.1.0, later than in state\nno-release: missing-release: tag no-release-2.0.0 has no GitHub release\n",
+			wantErr:     true,
+			wantVersion: "1.0.0",
+		},
+		{
+			name:               "fix",
+			fix:                true,
+			tags:               tags,
+			wantOut:            "behind: version-mismatch: latest tag behind-1.1.0 has version 1.1.0, later than in state (fixed)\nno-release: missing-release: tag no-release-2.0.0 has no GitHub release (fixed)\n",
+			wantVersion:        "1.1.0",
+			wantCreatedRelease: []string{"no-release-2.0.0"},
+		},
+		{
+			name:        "unfixable",
+			fix:         true,
+			tags:        []string{"behind-1.0.0"},
+			wantOut:     "no-release: missing-tag: tag no-release-2.0.0 of version 2.0.0 in state does not exist\n",
+			wantErr:     true,
+			wantVersion: "1.0.0",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repoDir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(repoDir, config.LibrarianDir), 0755); err != nil {
+				t.Fatal(err)
+			}
+			ghClient := &mockGitHubClient{releaseTags: releaseTags}
+			r := &verifyReleasesRunner{
+				cfg:      &config.Config{Fix: test.fix},
+				repo:     &MockRepository{Dir: repoDir, TagsValue: test.tags},
+				state:    newState(),
+				ghClient: ghClient,
+			}
+			var out bytes.Buffer
+			err := r.run(context.Background(), &out)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("run() error = nil, want error")
+				}
+				if got := failure.CategoryOf(err); got != failure.UserConfig {
+					t.Errorf("failure.CategoryOf() = %q, want %q", got, failure.UserConfig)
+				}
+			} else if err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			if diff := cmp.Diff(test.wantOut, out.String()); diff != "" {
+				t.Errorf("run() output mismatch (-want +got):\n%s", diff)
+			}
+			if got := r.state.LibraryByID("behind").Version; got != test.wantVersion {
+				t.Errorf("version = %q, want %q", got, test.wantVersion)
+			}
+			if diff := cmp.Diff(test.wantCreatedRelease, ghClient.createdReleaseTags); diff != "" {
+				t.Errorf("created releases mismatch (-want +got):\n%s", diff)
+			}
+			if test.wantVersion != "1.0.0" {
+				saved, err := parseLibrarianState(filepath.Join(repoDir, config.LibrarianDir, librarianStateFile), "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := saved.LibraryByID("behind").Version; got != test.wantVersion {
+					t.Errorf("saved version = %q, want %q", got, test.wantVersion)
+				}
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ package librarian
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/googleapis/librarian/internal/cli"
 	"github.com/googleapis/librarian/internal/config"
@@ -24,9 +25,15 @@ import (
 
 var cmdVersion = &cli.Command{
 	Short:     "version prints the version information",
-	UsageLine: "librarian version",
-	Long:      "Version prints version information for the librarian binary.",
+	UsageLine: "librarian version [flags]",
+	Long: `Version prints version information for the librarian binary.
+
+With -check, it also reports whether the release manifest at -release-manifest
+has a newer release, which "librarian self-update" installs.`,
 	Run: func(ctx context.Context, cfg *config.Config) error {
+		if cfg.Check {
+			return checkForUpdate(os.Stdout, cfg, cli.Version())
+		}
 		fmt.Println(cli.Version())
 		return nil
 	},
@@ -34,4 +41,9 @@ var cmdVersion = &cli.Command{
 
 func init() {
 	cmdVersion.Init()
+	fs := cmdVersion.Flags
+	cfg := cmdVersion.Config
+
+	addFlagCheck(fs, cfg)
+	addFlagReleaseManifest(fs, cfg)
 }
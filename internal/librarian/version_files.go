@@ -0,0 +1,68 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/googleapis/librarian/internal/config"
+	"github.com/googleapis/librarian/internal/versionfile"
+)
+
+// updateVersionFiles rewrites the version of the library in every version file
+// declared in the librarian config. Version files are looked up relative to
+// each source root of the library; source roots without the file are skipped.
+func updateVersionFiles(cfg *config.LibrarianConfig, repoDir string, library *config.LibraryState) error {
+	if cfg == nil || len(cfg.VersionFiles) == 0 {
+		return nil
+	}
+	for _, versionFile := range cfg.VersionFiles {
+		updater, err := versionfile.New(versionFile.Kind, versionFile.Pattern)
+		if err != nil {
+			return err
+		}
+		for _, root := range library.SourceRoots {
+			path := filepath.Join(repoDir, root, versionFile.Path)
+			if err := updateVersionFile(updater, path, library.Version); err != nil {
+				return fmt.Errorf("failed to update version in %s for library %s: %w", path, library.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func updateVersionFile(updater versionfile.Updater, path, version string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	updated, err := updater.Update(content, version)
+	if err != nil {
+		return err
+	}
+	slog.Info("Updating version file", "path", path, "version", version)
+	return os.WriteFile(path, updated, info.Mode().Perm())
+}
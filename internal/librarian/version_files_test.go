@@ -0,0 +1,113 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package librarian
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/config"
+)
+
+func TestUpdateVersionFiles(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name       string
+		cfg        *config.LibrarianConfig
+		files      map[string]string
+		want       map[string]string
+		wantErrMsg string
+	}{
+		{
+			name: "updates files in every source root",
+			cfg: &config.LibrarianConfig{
+				VersionFiles: []*config.VersionFile{
+					{Path: "internal/version.go", Kind: "go-version"},
+					{Path: "package.json", Kind: "package-json"},
+				},
+			},
+			files: map[string]string{
+				"src/a/internal/version.go": "package internal\n\nconst Version = \"1.0.0\"\n",
+				"src/b/package.json":        "{\n  \"version\": \"1.0.0\"\n}\n",
+			},
+			want: map[string]string{
+				"src/a/internal/version.go": "package internal\n\nconst Version = \"1.1.0\"\n",
+				"src/b/package.json":        "{\n  \"version\": \"1.1.0\"\n}\n",
+			},
+		},
+		{
+			name: "no config",
+			files: map[string]string{
+				"src/a/package.json": "{\n  \"version\": \"1.0.0\"\n}\n",
+			},
+			want: map[string]string{
+				"src/a/package.json": "{\n  \"version\": \"1.0.0\"\n}\n",
+			},
+		},
+		{
+			name: "version not found",
+			cfg: &config.LibrarianConfig{
+				VersionFiles: []*config.VersionFile{
+					{Path: "setup.py", Kind: "setup-py"},
+				},
+			},
+			files: map[string]string{
+				"src/a/setup.py": "setuptools.setup()\n",
+			},
+			wantErrMsg: "version not found",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoDir := t.TempDir()
+			for path, content := range test.files {
+				fullPath := filepath.Join(repoDir, path)
+				if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			library := &config.LibraryState{
+				ID:          "some-library",
+				Version:     "1.1.0",
+				SourceRoots: []string{"src/a", "src/b"},
+			}
+			err := updateVersionFiles(test.cfg, repoDir, library)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Errorf("updateVersionFiles() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("updateVersionFiles() error = %v", err)
+			}
+			for path, want := range test.want {
+				got, err := os.ReadFile(filepath.Join(repoDir, path))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(want, string(got)); diff != "" {
+					t.Errorf("%s mismatch (-want +got):\n%s", path, diff)
+				}
+			}
+		})
+	}
+}
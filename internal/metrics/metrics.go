@@ -0,0 +1,191 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics records metrics of librarian runs, such as the duration of
+// their phases, and persists them as a log of JSON files, one per run, so
+// that trends can be computed over time.
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const timestampFormat = "20060102T150405.000Z"
+
+// Run holds the metrics of a single run of a command. The methods of Run
+// do nothing on a nil Run, so that code can record metrics unconditionally.
+type Run struct {
+	// Command is the name of the command, e.g. "generate".
+	Command string `json:"command"`
+	// Version is the version of librarian.
+	Version string `json:"version"`
+	// Start is the time at which the run started.
+	Start time.Time `json:"start"`
+	// DurationSeconds is the duration of the run.
+	DurationSeconds float64 `json:"duration_seconds"`
+	// Phases are the durations of the phases of the run, in the order in
+	// which they first started.
+	Phases []*Phase `json:"phases,omitempty"`
+	// Libraries is the number of libraries processed by the run, including
+	// failed ones.
+	Libraries int `json:"libraries"`
+	// FailedLibraries is the number of libraries which failed.
+	FailedLibraries int `json:"failed_libraries"`
+	// FilesChanged is the number of files changed in the repository.
+	FilesChanged int `json:"files_changed"`
+	// LinesDelta is the net number of lines added to the repository.
+	LinesDelta int `json:"lines_delta"`
+	// Failure is the failure category of a failed run, and empty if the run
+	// succeeded.
+	Failure string `json:"failure,omitempty"`
+
+	// phasesMu guards Phases, which are timed concurrently when libraries
+	// are generated in parallel.
+	phasesMu sync.Mutex
+}
+
+// Phase is the total duration of a phase of a run. A phase which runs
+// several times, e.g. once per library, is summed.
+type Phase struct {
+	// Name is the name of the phase, e.g. "generate".
+	Name string `json:"name"`
+	// DurationSeconds is the total duration of the phase.
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// now is a variable so it can be replaced during testing.
+var now = time.Now
+
+// NewRun starts recording the metrics of a run of command.
+func NewRun(command, version string) *Run {
+	return &Run{
+		Command: command,
+		Version: version,
+		Start:   now().UTC(),
+	}
+}
+
+// StartPhase starts timing the phase with the given name, and returns a
+// function which stops it.
+func (r *Run) StartPhase(name string) func() {
+	if r == nil {
+		return func() {}
+	}
+	start := now()
+	return func() {
+		r.phasesMu.Lock()
+		defer r.phasesMu.Unlock()
+		r.phase(name).DurationSeconds += now().Sub(start).Seconds()
+	}
+}
+
+func (r *Run) phase(name string) *Phase {
+	for _, phase := range r.Phases {
+		if phase.Name == name {
+			return phase
+		}
+	}
+	phase := &Phase{Name: name}
+	r.Phases = append(r.Phases, phase)
+	return phase
+}
+
+// AddLibraries records processed libraries, of which failed failed.
+func (r *Run) AddLibraries(processed, failed int) {
+	if r == nil {
+		return
+	}
+	r.Libraries += processed
+	r.FailedLibraries += failed
+}
+
+// AddDiff records changes to the repository.
+func (r *Run) AddDiff(filesChanged, linesDelta int) {
+	if r == nil {
+		return
+	}
+	r.FilesChanged += filesChanged
+	r.LinesDelta += linesDelta
+}
+
+// Finish records the end of the run, with the failure category of a failed
+// run, or an empty category if it succeeded.
+func (r *Run) Finish(failure string) {
+	if r == nil {
+		return
+	}
+	r.DurationSeconds = now().Sub(r.Start).Seconds()
+	r.Failure = failure
+}
+
+// Write writes the run into dir, as a JSON file named after its start time
+// and command, so that runs written concurrently by several machines, e.g.
+// into a mounted bucket, do not conflict.
+func (r *Run) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create metrics directory: %w", err)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %w", err)
+	}
+	name := fmt.Sprintf("%s-%s.json", r.Start.UTC().Format(timestampFormat), strings.ReplaceAll(r.Command, " ", "-"))
+	if err := os.WriteFile(filepath.Join(dir, name), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return nil
+}
+
+// Load reads the runs written into dir, sorted by start time.
+func Load(dir string) ([]*Run, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var runs []*Run
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read metrics: %w", err)
+		}
+		run := &Run{}
+		if err := json.Unmarshal(data, run); err != nil {
+			return nil, fmt.Errorf("failed to parse metrics %s: %w", path, err)
+		}
+		runs = append(runs, run)
+	}
+	slices.SortStableFunc(runs, func(a, b *Run) int { return a.Start.Compare(b.Start) })
+	return runs, nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying run.
+func NewContext(ctx context.Context, run *Run) context.Context {
+	return context.WithValue(ctx, contextKey{}, run)
+}
+
+// FromContext returns the run carried by ctx, or nil if there is none.
+func FromContext(ctx context.Context) *Run {
+	run, _ := ctx.Value(contextKey{}).(*Run)
+	return run
+}
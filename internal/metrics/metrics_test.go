@@ -0,0 +1,151 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestRun(t *testing.T) {
+	clock := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	run := NewRun("generate", "v0.1.0")
+	stopPrewarm := run.StartPhase("prewarm")
+	clock = clock.Add(time.Second)
+	stopPrewarm()
+	for range 2 {
+		stop := run.StartPhase("generate")
+		clock = clock.Add(2 * time.Second)
+		stop()
+	}
+	run.AddLibraries(2, 1)
+	run.AddDiff(3, -4)
+	run.Finish("container-failure")
+
+	want := &Run{
+		Command:         "generate",
+		Version:         "v0.1.0",
+		Start:           time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		DurationSeconds: 5,
+		Phases: []*Phase{
+			{Name: "prewarm", DurationSeconds: 1},
+			{Name: "generate", DurationSeconds: 4},
+		},
+		Libraries:       2,
+		FailedLibraries: 1,
+		FilesChanged:    3,
+		LinesDelta:      -4,
+		Failure:         "container-failure",
+	}
+	if diff := cmp.Diff(want, run, cmpopts.IgnoreUnexported(Run{})); diff != "" {
+		t.Errorf("Run mismatch (-want +got):\n%s", diff)
+	}
+
+	dir := filepath.Join(t.TempDir(), "metrics")
+	if err := run.Write(dir); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "20250102T030405.000Z-generate.json")); err != nil {
+		t.Errorf("Write() did not write the expected file: %v", err)
+	}
+	got, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if diff := cmp.Diff([]*Run{want}, got, cmpopts.IgnoreUnexported(Run{})); diff != "" {
+		t.Errorf("Load() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNilRun(t *testing.T) {
+	var run *Run
+	run.StartPhase("generate")()
+	run.AddLibraries(1, 0)
+	run.AddDiff(1, 1)
+	run.Finish("")
+}
+
+func TestContext(t *testing.T) {
+	if got := FromContext(context.Background()); got != nil {
+		t.Errorf("FromContext() = %v, want nil", got)
+	}
+	run := &Run{Command: "generate"}
+	if got := FromContext(NewContext(context.Background(), run)); got != run {
+		t.Errorf("FromContext() = %v, want %v", got, run)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "run.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("Load() error = nil, want error")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	day1 := time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	runs := []*Run{
+		{
+			Command:         "generate",
+			Start:           day1,
+			DurationSeconds: 10,
+			Phases:          []*Phase{{Name: "prewarm", DurationSeconds: 2}, {Name: "generate", DurationSeconds: 6}},
+			Libraries:       3,
+			FilesChanged:    5,
+		},
+		{
+			Command:         "generate",
+			Start:           day1.Add(time.Hour),
+			DurationSeconds: 20,
+			Phases:          []*Phase{{Name: "generate", DurationSeconds: 10}},
+			Libraries:       3,
+			FailedLibraries: 1,
+			Failure:         "container-failure",
+		},
+		{Command: "init", Start: day1, DurationSeconds: 4, Libraries: 1},
+		{Command: "generate", Start: day2, DurationSeconds: 30, Libraries: 3},
+	}
+	want := []*Summary{
+		{
+			Command:             "generate",
+			Day:                 "2025-01-02",
+			Runs:                2,
+			FailedRuns:          1,
+			Libraries:           6,
+			FailedLibraries:     1,
+			FilesChanged:        5,
+			MeanDurationSeconds: 15,
+			MeanPhases:          []*Phase{{Name: "prewarm", DurationSeconds: 2}, {Name: "generate", DurationSeconds: 8}},
+		},
+		{Command: "generate", Day: "2025-01-03", Runs: 1, Libraries: 3, MeanDurationSeconds: 30},
+		{Command: "init", Day: "2025-01-02", Runs: 1, Libraries: 1, MeanDurationSeconds: 4},
+	}
+	if diff := cmp.Diff(want, Summarize(runs)); diff != "" {
+		t.Errorf("Summarize() mismatch (-want +got):\n%s", diff)
+	}
+}
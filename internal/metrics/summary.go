@@ -0,0 +1,90 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"cmp"
+	"slices"
+)
+
+const dayFormat = "2006-01-02"
+
+// Summary aggregates the runs of a command on a single day (UTC).
+type Summary struct {
+	// Command is the name of the command.
+	Command string
+	// Day is the day of the runs, e.g. "2025-01-02".
+	Day string
+	// Runs is the number of runs.
+	Runs int
+	// FailedRuns is the number of failed runs.
+	FailedRuns int
+	// Libraries is the total number of libraries processed.
+	Libraries int
+	// FailedLibraries is the total number of failed libraries.
+	FailedLibraries int
+	// FilesChanged is the total number of changed files.
+	FilesChanged int
+	// MeanDurationSeconds is the mean duration of the runs.
+	MeanDurationSeconds float64
+	// MeanPhases are the mean durations of the phases of the runs, over the
+	// runs which had the phase.
+	MeanPhases []*Phase
+}
+
+// Summarize aggregates runs by command and day, sorted by command, then day.
+func Summarize(runs []*Run) []*Summary {
+	type key struct{ command, day string }
+	var summaries []*Summary
+	byKey := make(map[key]*Summary)
+	phaseRuns := make(map[*Summary]map[string]int)
+	for _, run := range runs {
+		k := key{run.Command, run.Start.UTC().Format(dayFormat)}
+		summary, ok := byKey[k]
+		if !ok {
+			summary = &Summary{Command: k.command, Day: k.day}
+			byKey[k] = summary
+			phaseRuns[summary] = make(map[string]int)
+			summaries = append(summaries, summary)
+		}
+		summary.Runs++
+		if run.Failure != "" {
+			summary.FailedRuns++
+		}
+		summary.Libraries += run.Libraries
+		summary.FailedLibraries += run.FailedLibraries
+		summary.FilesChanged += run.FilesChanged
+		summary.MeanDurationSeconds += run.DurationSeconds
+		for _, phase := range run.Phases {
+			i := slices.IndexFunc(summary.MeanPhases, func(p *Phase) bool { return p.Name == phase.Name })
+			if i < 0 {
+				summary.MeanPhases = append(summary.MeanPhases, &Phase{Name: phase.Name})
+				i = len(summary.MeanPhases) - 1
+			}
+			summary.MeanPhases[i].DurationSeconds += phase.DurationSeconds
+			phaseRuns[summary][phase.Name]++
+		}
+	}
+	for _, summary := range summaries {
+		summary.MeanDurationSeconds /= float64(summary.Runs)
+		for _, phase := range summary.MeanPhases {
+			phase.DurationSeconds /= float64(phaseRuns[summary][phase.Name])
+		}
+	}
+	slices.SortStableFunc(summaries, func(a, b *Summary) int {
+		return cmp.Or(cmp.Compare(a.Command, b.Command), cmp.Compare(a.Day, b.Day))
+	})
+	return summaries
+}
@@ -0,0 +1,233 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sbom produces software bills of materials (SBOMs) of generated
+// libraries, in the CycloneDX JSON format.
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FileName is the name of the SBOM file written by librarian.
+const FileName = "sbom.cdx.json"
+
+const (
+	bomFormat   = "CycloneDX"
+	specVersion = "1.5"
+)
+
+// localImageProvenance is the provenance recorded for local, unreleased
+// generator images.
+const localImageProvenance = "local-dev"
+
+// Library describes a library and what it was generated from.
+type Library struct {
+	// ID is the ID of the library.
+	ID string
+	// Version is the version of the library.
+	Version string
+	// Image is the generator image, e.g. "gcr.io/foo/generator:v1.2.3".
+	Image string
+	// ImageLocal reports whether the generator image is a local, unreleased
+	// build, in which case its provenance is "local-dev".
+	ImageLocal bool
+	// APISource is the location of the API definition repository.
+	APISource string
+	// APISourceCommit is the commit of the API definition repository that
+	// the library was generated from.
+	APISourceCommit string
+	// APISourceDirty reports whether the library was generated from
+	// uncommitted changes on top of APISourceCommit.
+	APISourceDirty bool
+	// APIs are the paths of the proto sources of the library, relative to
+	// the root of the API definition repository.
+	APIs []string
+	// Packages are the dependencies of the library, as reported by the
+	// language container.
+	Packages []*Package
+}
+
+// Package is a dependency of a library.
+type Package struct {
+	// Name is the name of the package, e.g. "google.golang.org/grpc".
+	Name string `json:"name"`
+	// Version is the version of the package.
+	Version string `json:"version"`
+	// PURL is the package URL of the package, e.g.
+	// "pkg:golang/google.golang.org/grpc@v1.2.3". It is optional.
+	PURL string `json:"purl,omitempty"`
+}
+
+// BOM is a CycloneDX bill of materials.
+type BOM struct {
+	BOMFormat    string        `json:"bomFormat"`
+	SpecVersion  string        `json:"specVersion"`
+	Version      int           `json:"version"`
+	Metadata     *Metadata     `json:"metadata"`
+	Components   []*Component  `json:"components"`
+	Dependencies []*Dependency `json:"dependencies"`
+}
+
+// Metadata describes how the BOM was produced.
+type Metadata struct {
+	Timestamp string `json:"timestamp"`
+	Tools     *Tools `json:"tools"`
+}
+
+// Tools lists the tools which produced the BOM.
+type Tools struct {
+	Components []*Component `json:"components"`
+}
+
+// Component is a library, container image, proto source or package.
+type Component struct {
+	Type               string               `json:"type"`
+	BOMRef             string               `json:"bom-ref,omitempty"`
+	Name               string               `json:"name"`
+	Version            string               `json:"version,omitempty"`
+	PURL               string               `json:"purl,omitempty"`
+	ExternalReferences []*ExternalReference `json:"externalReferences,omitempty"`
+	Properties         []*Property          `json:"properties,omitempty"`
+}
+
+// ExternalReference is a reference to a resource outside of the BOM.
+type ExternalReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// Property is a name-value pair of a component.
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Dependency lists the components that the component Ref depends on.
+type Dependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// New returns the BOM of libraries, produced by librarian at the given
+// version and time. Components shared by libraries, such as the generator
+// image, are listed once.
+func New(librarianVersion string, timestamp time.Time, libraries []*Library) *BOM {
+	bom := &BOM{
+		BOMFormat:   bomFormat,
+		SpecVersion: specVersion,
+		Version:     1,
+		Metadata: &Metadata{
+			Timestamp: timestamp.UTC().Format(time.RFC3339),
+			Tools: &Tools{
+				Components: []*Component{{Type: "application", Name: "librarian", Version: librarianVersion}},
+			},
+		},
+		Components:   []*Component{},
+		Dependencies: []*Dependency{},
+	}
+	add := func(c *Component) string {
+		if !slices.ContainsFunc(bom.Components, func(o *Component) bool { return o.BOMRef == c.BOMRef }) {
+			bom.Components = append(bom.Components, c)
+		}
+		return c.BOMRef
+	}
+	for _, library := range libraries {
+		dependency := &Dependency{Ref: add(&Component{
+			Type:    "library",
+			BOMRef:  "library:" + library.ID,
+			Name:    library.ID,
+			Version: library.Version,
+		})}
+		if library.Image != "" {
+			dependency.DependsOn = append(dependency.DependsOn, add(imageComponent(library)))
+		}
+		for _, api := range library.APIs {
+			dependency.DependsOn = append(dependency.DependsOn, add(apiComponent(library, api)))
+		}
+		for _, pkg := range library.Packages {
+			dependency.DependsOn = append(dependency.DependsOn, add(packageComponent(pkg)))
+		}
+		if dependency.DependsOn == nil {
+			dependency.DependsOn = []string{}
+		}
+		bom.Dependencies = append(bom.Dependencies, dependency)
+	}
+	return bom
+}
+
+// Marshal returns the BOM as indented JSON.
+func (b *BOM) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SBOM: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+func imageComponent(library *Library) *Component {
+	image := library.Image
+	name, version := image, ""
+	if i := strings.LastIndex(image, "@"); i >= 0 {
+		name, version = image[:i], image[i+1:]
+	} else if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		name, version = image[:i], image[i+1:]
+	}
+	component := &Component{
+		Type:    "container",
+		BOMRef:  "image:" + image,
+		Name:    name,
+		Version: version,
+	}
+	if library.ImageLocal {
+		component.BOMRef += "+" + localImageProvenance
+		component.Properties = []*Property{{Name: "librarian:image_provenance", Value: localImageProvenance}}
+	}
+	return component
+}
+
+func apiComponent(library *Library, api string) *Component {
+	component := &Component{
+		Type:    "data",
+		BOMRef:  fmt.Sprintf("api:%s@%s", api, library.APISourceCommit),
+		Name:    api,
+		Version: library.APISourceCommit,
+	}
+	if library.APISource != "" {
+		component.ExternalReferences = []*ExternalReference{{Type: "vcs", URL: library.APISource}}
+	}
+	if library.APISourceDirty {
+		component.BOMRef += "+dirty"
+		component.Properties = []*Property{{Name: "librarian:api_source_dirty", Value: "true"}}
+	}
+	return component
+}
+
+func packageComponent(pkg *Package) *Component {
+	ref := pkg.PURL
+	if ref == "" {
+		ref = fmt.Sprintf("package:%s@%s", pkg.Name, pkg.Version)
+	}
+	return &Component{
+		Type:    "library",
+		BOMRef:  ref,
+		Name:    pkg.Name,
+		Version: pkg.Version,
+		PURL:    pkg.PURL,
+	}
+}
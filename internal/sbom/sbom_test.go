@@ -0,0 +1,158 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNew(t *testing.T) {
+	timestamp := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	libraries := []*Library{
+		{
+			ID:              "foo",
+			Version:         "1.2.3",
+			Image:           "gcr.io/generator:v1",
+			APISource:       "https://github.com/googleapis/googleapis",
+			APISourceCommit: "abc123",
+			APIs:            []string{"google/cloud/foo/v1"},
+			Packages: []*Package{
+				{Name: "google.golang.org/grpc", Version: "v1.2.3", PURL: "pkg:golang/google.golang.org/grpc@v1.2.3"},
+				{Name: "cloud.google.com/go/auth", Version: "v0.1.0"},
+			},
+		},
+		{
+			ID:              "bar",
+			Image:           "gcr.io/generator:v1",
+			APISourceCommit: "abc123",
+			APISourceDirty:  true,
+			APIs:            []string{"google/cloud/bar/v1"},
+		},
+	}
+	got := New("v0.1.0", timestamp, libraries)
+	want := &BOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: &Metadata{
+			Timestamp: "2025-01-02T03:04:05Z",
+			Tools: &Tools{
+				Components: []*Component{{Type: "application", Name: "librarian", Version: "v0.1.0"}},
+			},
+		},
+		Components: []*Component{
+			{Type: "library", BOMRef: "library:foo", Name: "foo", Version: "1.2.3"},
+			{Type: "container", BOMRef: "image:gcr.io/generator:v1", Name: "gcr.io/generator", Version: "v1"},
+			{
+				Type:               "data",
+				BOMRef:             "api:google/cloud/foo/v1@abc123",
+				Name:               "google/cloud/foo/v1",
+				Version:            "abc123",
+				ExternalReferences: []*ExternalReference{{Type: "vcs", URL: "https://github.com/googleapis/googleapis"}},
+			},
+			{
+				Type:    "library",
+				BOMRef:  "pkg:golang/google.golang.org/grpc@v1.2.3",
+				Name:    "google.golang.org/grpc",
+				Version: "v1.2.3",
+				PURL:    "pkg:golang/google.golang.org/grpc@v1.2.3",
+			},
+			{Type: "library", BOMRef: "package:cloud.google.com/go/auth@v0.1.0", Name: "cloud.google.com/go/auth", Version: "v0.1.0"},
+			{Type: "library", BOMRef: "library:bar", Name: "bar"},
+			{
+				Type:       "data",
+				BOMRef:     "api:google/cloud/bar/v1@abc123+dirty",
+				Name:       "google/cloud/bar/v1",
+				Version:    "abc123",
+				Properties: []*Property{{Name: "librarian:api_source_dirty", Value: "true"}},
+			},
+		},
+		Dependencies: []*Dependency{
+			{
+				Ref: "library:foo",
+				DependsOn: []string{
+					"image:gcr.io/generator:v1",
+					"api:google/cloud/foo/v1@abc123",
+					"pkg:golang/google.golang.org/grpc@v1.2.3",
+					"package:cloud.google.com/go/auth@v0.1.0",
+				},
+			},
+			{
+				Ref:       "library:bar",
+				DependsOn: []string{"image:gcr.io/generator:v1", "api:google/cloud/bar/v1@abc123+dirty"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("New() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestImageComponent(t *testing.T) {
+	for _, test := range []struct {
+		image       string
+		wantName    string
+		wantVersion string
+	}{
+		{image: "generator", wantName: "generator"},
+		{image: "generator:v1", wantName: "generator", wantVersion: "v1"},
+		{image: "localhost:5000/generator", wantName: "localhost:5000/generator"},
+		{image: "localhost:5000/generator:v1", wantName: "localhost:5000/generator", wantVersion: "v1"},
+		{image: "generator@sha256:abc", wantName: "generator", wantVersion: "sha256:abc"},
+	} {
+		t.Run(test.image, func(t *testing.T) {
+			got := imageComponent(&Library{Image: test.image})
+			if got.Name != test.wantName || got.Version != test.wantVersion {
+				t.Errorf("imageComponent(%q) = %q, %q, want %q, %q", test.image, got.Name, got.Version, test.wantName, test.wantVersion)
+			}
+		})
+	}
+}
+
+func TestImageComponent_Local(t *testing.T) {
+	got := imageComponent(&Library{Image: "generator:dev", ImageLocal: true})
+	want := &Component{
+		Type:       "container",
+		BOMRef:     "image:generator:dev+local-dev",
+		Name:       "generator",
+		Version:    "dev",
+		Properties: []*Property{{Name: "librarian:image_provenance", Value: "local-dev"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("imageComponent() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	data, err := New("v0.1.0", time.Now(), []*Library{{ID: "foo"}}).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["bomFormat"] != "CycloneDX" || got["specVersion"] != "1.5" {
+		t.Errorf("Marshal() = %s, want a CycloneDX 1.5 document", data)
+	}
+	deps := got["dependencies"].([]any)
+	if dependsOn := deps[0].(map[string]any)["dependsOn"]; dependsOn == nil {
+		t.Errorf("Marshal() dependsOn = null, want an empty list")
+	}
+}
@@ -15,6 +15,7 @@
 package semver
 
 import (
+	"cmp"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -91,6 +92,33 @@ func Parse(versionString string) (*Version, error) {
 	return v, nil
 }
 
+// Compare returns -1, 0 or 1 if v is lower than, equal to or higher than
+// other. A pre-release version is lower than the associated normal version,
+// and pre-release numbers are compared numerically.
+func (v *Version) Compare(other *Version) int {
+	if c := cmp.Compare(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	switch {
+	case v.Prerelease == "" && v.PrereleaseNumber == "" && (other.Prerelease != "" || other.PrereleaseNumber != ""):
+		return 1
+	case other.Prerelease == "" && other.PrereleaseNumber == "" && (v.Prerelease != "" || v.PrereleaseNumber != ""):
+		return -1
+	}
+	if c := strings.Compare(v.Prerelease, other.Prerelease); c != 0 {
+		return c
+	}
+	n, _ := strconv.Atoi(v.PrereleaseNumber)
+	otherN, _ := strconv.Atoi(other.PrereleaseNumber)
+	return cmp.Compare(n, otherN)
+}
+
 // String formats a Version struct into a string.
 func (v *Version) String() string {
 	version := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
@@ -162,6 +162,36 @@ func TestVersion_String(t *testing.T) {
 	}
 }
 
+func TestVersion_Compare(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.2.3", b: "1.2.3", want: 0},
+		{a: "1.2.3", b: "1.2.4", want: -1},
+		{a: "1.3.0", b: "1.2.9", want: 1},
+		{a: "2.0.0", b: "10.0.0", want: -1},
+		{a: "1.0.0-alpha", b: "1.0.0", want: -1},
+		{a: "1.0.0", b: "1.0.0-rc.1", want: 1},
+		{a: "1.0.0-alpha.2", b: "1.0.0-alpha.10", want: -1},
+		{a: "1.0.0-beta.1", b: "1.0.0-alpha.5", want: 1},
+	} {
+		t.Run(test.a+" vs "+test.b, func(t *testing.T) {
+			a, err := Parse(test.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := Parse(test.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := a.Compare(b); got != test.want {
+				t.Errorf("Compare() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
 func TestDeriveNext(t *testing.T) {
 	for _, test := range []struct {
 		name            string
@@ -0,0 +1,206 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package versionfile rewrites version strings in files commonly used by
+// language ecosystems to record the version of a library, such as
+// gradle.properties, pom.xml, setup.py, package.json and version.go.
+package versionfile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+)
+
+// The set of supported updater kinds.
+const (
+	// KindGeneric updates the version matched by a user-provided pattern.
+	KindGeneric = "generic"
+	// KindGoVersion updates a Go `Version` constant or variable.
+	KindGoVersion = "go-version"
+	// KindGradleProperties updates the `version` property of a
+	// gradle.properties file.
+	KindGradleProperties = "gradle-properties"
+	// KindPackageJSON updates the `version` field of a package.json file.
+	KindPackageJSON = "package-json"
+	// KindPomXML updates the project version of a Maven pom.xml file.
+	KindPomXML = "pom-xml"
+	// KindSetupPy updates the `version` argument of a setup.py file.
+	KindSetupPy = "setup-py"
+)
+
+// versionGroup is the name of the capture group containing the version in
+// regular expression based updaters.
+const versionGroup = "version"
+
+// ErrVersionNotFound is returned when a file does not contain a version that
+// the updater recognizes.
+var ErrVersionNotFound = errors.New("version not found")
+
+var builtinPatterns = map[string]string{
+	KindGoVersion:        `(?m)^\s*(?:const\s+|var\s+)?Version\s*=\s*"(?P<version>[^"]*)"`,
+	KindGradleProperties: `(?m)^\s*version\s*[=:]\s*(?P<version>\S+)\s*$`,
+	KindPackageJSON:      `(?m)^\s*"version"\s*:\s*"(?P<version>[^"]*)"`,
+	KindSetupPy:          `\bversion\s*=\s*["'](?P<version>[^"']*)["']`,
+}
+
+// Updater reads and rewrites the version recorded in the content of a file.
+type Updater interface {
+	// Version returns the version recorded in content.
+	Version(content []byte) (string, error)
+	// Update returns a copy of content with the version replaced by version.
+	// Everything else in content is preserved byte for byte.
+	Update(content []byte, version string) ([]byte, error)
+}
+
+// Kinds returns the supported updater kinds in sorted order.
+func Kinds() []string {
+	kinds := []string{KindGeneric, KindPomXML}
+	for kind := range builtinPatterns {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+	return kinds
+}
+
+// New returns the Updater for the given kind. The pattern is only used by
+// KindGeneric, and must be a regular expression with a capture group named
+// "version".
+func New(kind, pattern string) (Updater, error) {
+	switch kind {
+	case KindPomXML:
+		return &pomUpdater{}, nil
+	case KindGeneric:
+		if pattern == "" {
+			return nil, fmt.Errorf("pattern is required for %q updater", kind)
+		}
+		return newRegexpUpdater(pattern)
+	}
+	pattern, ok := builtinPatterns[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown version file kind %q", kind)
+	}
+	return newRegexpUpdater(pattern)
+}
+
+// regexpUpdater updates the first match of the "version" capture group of a
+// regular expression.
+type regexpUpdater struct {
+	re    *regexp.Regexp
+	group int
+}
+
+func newRegexpUpdater(pattern string) (*regexpUpdater, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	group := re.SubexpIndex(versionGroup)
+	if group < 0 {
+		return nil, fmt.Errorf("pattern %q has no capture group named %q", pattern, versionGroup)
+	}
+	return &regexpUpdater{re: re, group: group}, nil
+}
+
+// Version returns the text matched by the "version" capture group.
+func (u *regexpUpdater) Version(content []byte) (string, error) {
+	start, end, err := u.locate(content)
+	if err != nil {
+		return "", err
+	}
+	return string(content[start:end]), nil
+}
+
+// Update replaces the text matched by the "version" capture group.
+func (u *regexpUpdater) Update(content []byte, version string) ([]byte, error) {
+	start, end, err := u.locate(content)
+	if err != nil {
+		return nil, err
+	}
+	return splice(content, start, end, version), nil
+}
+
+func (u *regexpUpdater) locate(content []byte) (int, int, error) {
+	match := u.re.FindSubmatchIndex(content)
+	if match == nil || match[2*u.group] < 0 {
+		return 0, 0, ErrVersionNotFound
+	}
+	return match[2*u.group], match[2*u.group+1], nil
+}
+
+// pomUpdater updates the <version> element which is a direct child of the
+// <project> element, leaving dependency and parent versions untouched.
+type pomUpdater struct{}
+
+// Version returns the text of the project version element.
+func (u *pomUpdater) Version(content []byte) (string, error) {
+	start, end, err := u.locate(content)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimSpace(content[start:end])), nil
+}
+
+// Update replaces the text of the project version element.
+func (u *pomUpdater) Update(content []byte, version string) ([]byte, error) {
+	start, end, err := u.locate(content)
+	if err != nil {
+		return nil, err
+	}
+	return splice(content, start, end, version), nil
+}
+
+// locate returns the byte offsets of the text within the project version
+// element.
+func (u *pomUpdater) locate(content []byte) (int, int, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	var stack []string
+	for {
+		offset := int(decoder.InputOffset())
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return 0, 0, ErrVersionNotFound
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to parse pom.xml: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+		case xml.EndElement:
+			if len(stack) == 2 && stack[0] == "project" && stack[1] == "version" {
+				// An empty element, e.g. <version></version>.
+				return offset, offset, nil
+			}
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) == 2 && stack[0] == "project" && stack[1] == "version" {
+				return offset, int(decoder.InputOffset()), nil
+			}
+		}
+	}
+}
+
+func splice(content []byte, start, end int, replacement string) []byte {
+	result := make([]byte, 0, len(content)-(end-start)+len(replacement))
+	result = append(result, content[:start]...)
+	result = append(result, replacement...)
+	return append(result, content[end:]...)
+}
@@ -0,0 +1,262 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package versionfile
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		kind    string
+		pattern string
+		content string
+		want    string
+	}{
+		{
+			name: "gradle.properties",
+			kind: KindGradleProperties,
+			content: `# Project properties
+group=com.google.cloud
+version=1.2.3
+org.gradle.jvmargs=-Xmx2g
+`,
+			want: `# Project properties
+group=com.google.cloud
+version=1.3.0
+org.gradle.jvmargs=-Xmx2g
+`,
+		},
+		{
+			name: "pom.xml",
+			kind: KindPomXML,
+			content: `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <parent>
+    <groupId>com.google.cloud</groupId>
+    <artifactId>parent</artifactId>
+    <version>9.9.9</version>
+  </parent>
+  <artifactId>google-cloud-secretmanager</artifactId>
+  <version>1.2.3</version><!-- {x-version-update:google-cloud-secretmanager:current} -->
+  <dependencies>
+    <dependency>
+      <version>4.5.6</version>
+    </dependency>
+  </dependencies>
+</project>
+`,
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <parent>
+    <groupId>com.google.cloud</groupId>
+    <artifactId>parent</artifactId>
+    <version>9.9.9</version>
+  </parent>
+  <artifactId>google-cloud-secretmanager</artifactId>
+  <version>1.3.0</version><!-- {x-version-update:google-cloud-secretmanager:current} -->
+  <dependencies>
+    <dependency>
+      <version>4.5.6</version>
+    </dependency>
+  </dependencies>
+</project>
+`,
+		},
+		{
+			name: "setup.py",
+			kind: KindSetupPy,
+			content: `setuptools.setup(
+    name="google-cloud-secret-manager",
+    version="1.2.3",
+    python_requires=">=3.7",
+)
+`,
+			want: `setuptools.setup(
+    name="google-cloud-secret-manager",
+    version="1.3.0",
+    python_requires=">=3.7",
+)
+`,
+		},
+		{
+			name: "package.json",
+			kind: KindPackageJSON,
+			content: `{
+  "name": "@google-cloud/secret-manager",
+  "version": "1.2.3",
+  "dependencies": {
+    "google-gax": "^4.0.0"
+  }
+}
+`,
+			want: `{
+  "name": "@google-cloud/secret-manager",
+  "version": "1.3.0",
+  "dependencies": {
+    "google-gax": "^4.0.0"
+  }
+}
+`,
+		},
+		{
+			name: "version.go",
+			kind: KindGoVersion,
+			content: `package internal
+
+// Version is the current tagged release of the library.
+const Version = "1.2.3"
+`,
+			want: `package internal
+
+// Version is the current tagged release of the library.
+const Version = "1.3.0"
+`,
+		},
+		{
+			name:    "generic",
+			kind:    KindGeneric,
+			pattern: `__version__ = '(?P<version>[^']+)'`,
+			content: "__version__ = '1.2.3'\n",
+			want:    "__version__ = '1.3.0'\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			updater, err := New(test.kind, test.pattern)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			gotVersion, err := updater.Version([]byte(test.content))
+			if err != nil {
+				t.Fatalf("Version() error = %v", err)
+			}
+			if diff := cmp.Diff("1.2.3", gotVersion); diff != "" {
+				t.Errorf("Version() mismatch (-want +got):\n%s", diff)
+			}
+
+			updated, err := updater.Update([]byte(test.content), "1.3.0")
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if diff := cmp.Diff(test.want, string(updated)); diff != "" {
+				t.Errorf("Update() mismatch (-want +got):\n%s", diff)
+			}
+			gotVersion, err = updater.Version(updated)
+			if err != nil {
+				t.Fatalf("Version() error = %v", err)
+			}
+			if diff := cmp.Diff("1.3.0", gotVersion); diff != "" {
+				t.Errorf("Version() after update mismatch (-want +got):\n%s", diff)
+			}
+
+			restored, err := updater.Update(updated, "1.2.3")
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if diff := cmp.Diff(test.content, string(restored)); diff != "" {
+				t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestVersionNotFound(t *testing.T) {
+	for _, kind := range []string{
+		KindGoVersion,
+		KindGradleProperties,
+		KindPackageJSON,
+		KindPomXML,
+		KindSetupPy,
+	} {
+		t.Run(kind, func(t *testing.T) {
+			updater, err := New(kind, "")
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			content := []byte("<project><name>no version here</name></project>")
+			if _, err := updater.Update(content, "1.0.0"); !errors.Is(err, ErrVersionNotFound) {
+				t.Errorf("Update() error = %v, want %v", err, ErrVersionNotFound)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		kind       string
+		pattern    string
+		wantErrMsg string
+	}{
+		{
+			name: "builtin kind",
+			kind: KindSetupPy,
+		},
+		{
+			name:       "unknown kind",
+			kind:       "cargo-toml",
+			wantErrMsg: "unknown version file kind",
+		},
+		{
+			name:       "generic without pattern",
+			kind:       KindGeneric,
+			wantErrMsg: "pattern is required",
+		},
+		{
+			name:       "generic with invalid pattern",
+			kind:       KindGeneric,
+			pattern:    "(",
+			wantErrMsg: "invalid pattern",
+		},
+		{
+			name:       "generic without version group",
+			kind:       KindGeneric,
+			pattern:    `version = "(.*)"`,
+			wantErrMsg: "no capture group named",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := New(test.kind, test.pattern)
+			if test.wantErrMsg == "" {
+				if err != nil {
+					t.Errorf("New() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+				t.Errorf("New() error = %v, want error containing %q", err, test.wantErrMsg)
+			}
+		})
+	}
+}
+
+func TestKinds(t *testing.T) {
+	want := []string{
+		KindGeneric,
+		KindGoVersion,
+		KindGradleProperties,
+		KindPackageJSON,
+		KindPomXML,
+		KindSetupPy,
+	}
+	if diff := cmp.Diff(want, Kinds()); diff != "" {
+		t.Errorf("Kinds() mismatch (-want +got):\n%s", diff)
+	}
+}